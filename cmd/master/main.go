@@ -3,77 +3,103 @@ package main
 import (
 	"context"
 	"flag"
+	"fmt"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
 	"go.uber.org/zap"
-	"go.uber.org/zap/zapcore"
 
 	"github.com/fyerfyer/scheduler-refactor/config"
+	"github.com/fyerfyer/scheduler-refactor/master/admission"
 	"github.com/fyerfyer/scheduler-refactor/master/api"
+	"github.com/fyerfyer/scheduler-refactor/master/approvalmgr"
+	"github.com/fyerfyer/scheduler-refactor/master/archiver"
+	"github.com/fyerfyer/scheduler-refactor/master/authmgr"
+	"github.com/fyerfyer/scheduler-refactor/master/digest"
+	"github.com/fyerfyer/scheduler-refactor/master/election"
+	"github.com/fyerfyer/scheduler-refactor/master/eventhub"
+	"github.com/fyerfyer/scheduler-refactor/master/freezemgr"
+	"github.com/fyerfyer/scheduler-refactor/master/idempotency"
 	"github.com/fyerfyer/scheduler-refactor/master/jobmgr"
 	"github.com/fyerfyer/scheduler-refactor/master/logmgr"
+	"github.com/fyerfyer/scheduler-refactor/master/nsmgr"
+	"github.com/fyerfyer/scheduler-refactor/master/policymgr"
+	"github.com/fyerfyer/scheduler-refactor/master/rbacmgr"
+	"github.com/fyerfyer/scheduler-refactor/master/reconciler"
+	"github.com/fyerfyer/scheduler-refactor/master/replicator"
+	"github.com/fyerfyer/scheduler-refactor/master/semaphoremgr"
 	"github.com/fyerfyer/scheduler-refactor/master/workermgr"
 	"github.com/fyerfyer/scheduler-refactor/pkg/etcd"
-	"github.com/fyerfyer/scheduler-refactor/pkg/mongodb"
+	"github.com/fyerfyer/scheduler-refactor/pkg/healthcheck"
+	"github.com/fyerfyer/scheduler-refactor/pkg/logging"
+	"github.com/fyerfyer/scheduler-refactor/pkg/logstore"
+	"github.com/fyerfyer/scheduler-refactor/pkg/notify"
+	"github.com/fyerfyer/scheduler-refactor/pkg/selfcheck"
+	"github.com/fyerfyer/scheduler-refactor/pkg/version"
 )
 
-// initLogger 初始化日志
-func initLogger() *zap.Logger {
-	// 配置日志编码器
-	encoderConfig := zapcore.EncoderConfig{
-		TimeKey:        "time",
-		LevelKey:       "level",
-		NameKey:        "logger",
-		CallerKey:      "caller",
-		MessageKey:     "msg",
-		StacktraceKey:  "stacktrace",
-		LineEnding:     zapcore.DefaultLineEnding,
-		EncodeLevel:    zapcore.LowercaseLevelEncoder,
-		EncodeTime:     zapcore.ISO8601TimeEncoder,
-		EncodeDuration: zapcore.SecondsDurationEncoder,
-		EncodeCaller:   zapcore.ShortCallerEncoder,
-	}
-
-	// 创建生产环境配置
-	config := zap.Config{
-		Level:       zap.NewAtomicLevelAt(zap.InfoLevel),
-		Development: false,
-		Sampling: &zap.SamplingConfig{
-			Initial:    100,
-			Thereafter: 100,
-		},
-		Encoding:         "json",
-		EncoderConfig:    encoderConfig,
-		OutputPaths:      []string{"stderr"},
-		ErrorOutputPaths: []string{"stderr"},
-	}
-
-	// 构建日志
-	logger, err := config.Build()
-	if err != nil {
-		panic("failed to initialize logger: " + err.Error())
+// runHealthcheck 请求本机API端口的健康检查接口，返回进程退出码
+func runHealthcheck(configFile string) int {
+	if err := config.InitConfig(configFile, false); err != nil {
+		fmt.Fprintln(os.Stderr, "failed to initialize config:", err)
+		return 1
 	}
-
-	return logger
+	if err := healthcheck.Probe(config.GlobalConfig.ApiPort); err != nil {
+		fmt.Fprintln(os.Stderr, "unhealthy:", err)
+		return 1
+	}
+	return 0
 }
 
 func main() {
 	// 解析命令行参数
 	configFile := flag.String("config", "./master.json", "master config file path")
+	check := flag.Bool("check", false, "check config, etcd, log store and clock, then exit")
+	bootstrap := flag.Bool("init", false, "initialize log store schema and cluster record, then exit")
+	health := flag.Bool("healthcheck", false, "probe the local health endpoint and exit, for container HEALTHCHECK")
 	flag.Parse()
 
+	// 健康检查模式不输出启动日志，避免每次探测都刷屏
+	if *health {
+		os.Exit(runHealthcheck(*configFile))
+	}
+
+	// 初始化配置，日志级别和格式来自配置
+	if err := config.InitConfig(*configFile, false); err != nil {
+		fmt.Fprintln(os.Stderr, "failed to initialize config:", err)
+		os.Exit(1)
+	}
+
 	// 初始化日志
-	logger := initLogger()
-	defer logger.Sync()
+	loggers, err := logging.New(config.GlobalConfig)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "failed to initialize logger:", err)
+		os.Exit(1)
+	}
+	defer loggers.Sync()
+	logger := loggers.Logger()
 
-	logger.Info("master starting...")
+	buildInfo := version.Get()
+	logger.Info("master starting...",
+		zap.String("version", buildInfo.Version),
+		zap.String("commit", buildInfo.Commit),
+		zap.String("buildDate", buildInfo.BuildDate))
 
-	// 初始化配置
-	if err := config.InitConfig(*configFile, false); err != nil {
-		logger.Fatal("failed to initialize config", zap.Error(err))
+	// 自检和初始化模式只输出诊断报告，供部署流水线根据退出码判断
+	if *check || *bootstrap {
+		var report *selfcheck.Report
+		if *bootstrap {
+			report = selfcheck.Bootstrap()
+		} else {
+			report = selfcheck.Run(selfcheck.RoleMaster)
+		}
+		report.Print(os.Stdout)
+		if report.Failed() {
+			os.Exit(1)
+		}
+		return
 	}
 
 	// 初始化Etcd客户端
@@ -81,25 +107,146 @@ func main() {
 	if err != nil {
 		logger.Fatal("failed to connect to etcd", zap.Error(err))
 	}
+	etcdClient.SetLogger(loggers.Component(logging.ComponentEtcd))
 	defer etcdClient.Close()
 
-	// 初始化MongoDB客户端
-	mongoClient, err := mongodb.NewClient()
+	// 初始化日志存储
+	logStore, err := logstore.NewLogStore(logger)
 	if err != nil {
-		logger.Fatal("failed to connect to mongodb", zap.Error(err))
+		logger.Fatal("failed to connect to log store",
+			zap.String("backend", config.GlobalConfig.LogBackend),
+			zap.Error(err))
 	}
-	defer mongoClient.Close()
+	defer logStore.Close()
 
 	// 初始化组件
 	jobManager := jobmgr.NewJobManager(etcdClient, logger)
-	logManager := logmgr.NewLogManager(mongoClient, logger)
+	jobManager.StartCache()
+	logManager := logmgr.NewLogManager(logStore, logger)
 	workerManager := workermgr.NewWorkerManager(etcdClient, logger)
+	policyManager := policymgr.NewPolicyManager(etcdClient, logger)
+	approvalManager := approvalmgr.NewApprovalManager(etcdClient, jobManager, notify.NewNotifier(config.GlobalConfig.ApprovalWebhook), logger)
+	freezeManager := freezemgr.NewFreezeManager(etcdClient, logger)
+
+	// 清理旧版本写入任务锁目录的kill标记，避免阻塞任务锁
+	if _, err := jobManager.MigrateKillMarkers(); err != nil {
+		logger.Warn("failed to migrate legacy kill markers", zap.Error(err))
+	}
+
+	// 参与选主，只有leader执行集群级的日志清理和统计汇总
+	elector := election.NewElector(etcdClient, logger)
+	elector.Start()
+	logManager.SetLeader(elector)
 
 	// 启动日志清理器
-	logManager.StartLogCleaner(30) // 保留30天的日志
+	retentionDays := config.GlobalConfig.LogRetentionDays
+	if err := logManager.StartLogCleaner(retentionDays); err != nil {
+		logger.Fatal("invalid log clean schedule",
+			zap.String("schedule", config.GlobalConfig.LogCleanSchedule),
+			zap.Error(err))
+	}
+	logManager.StartStatsRollup(retentionDays) // 在原始日志过期前汇总为按天统计
 
 	// 创建API服务器
-	apiServer := api.NewServer(logger, jobManager, logManager, workerManager)
+	apiServer := api.NewServer(loggers.Component(logging.ComponentAPI), jobManager, logManager, workerManager, policyManager, approvalManager, freezeManager)
+	apiServer.SetNamespaceManager(nsmgr.NewNamespaceManager(etcdClient, logger))
+	apiServer.SetSemaphoreManager(semaphoremgr.NewSemaphoreManager(etcdClient, logger))
+
+	// 开启认证时，/api/v1接口必须携带API密钥或JWT
+	if config.GlobalConfig.AuthEnabled {
+		authManager := authmgr.NewAuthManager(etcdClient, logger)
+		authManager.SetJWTSecret(config.GlobalConfig.JWTSecret)
+		authManager.SetBootstrapKey(config.GlobalConfig.AdminAPIKey)
+		if config.GlobalConfig.AdminAPIKey == "" {
+			logger.Warn("authentication is enabled without adminApiKey, api keys must already exist in etcd")
+		}
+		if !authManager.JWTEnabled() {
+			logger.Info("jwtSecret is not set, only api keys are accepted")
+		}
+		apiServer.SetAuthManager(authManager)
+	}
+
+	// 每周摘要，配置了webhook时由leader定时发送
+	costPrices := logmgr.CostPrices{
+		CPUHour: config.GlobalConfig.CostPerCPUHour,
+		GBHour:  config.GlobalConfig.CostPerGBHour,
+	}
+	digestManager := digest.NewManager(etcdClient, jobManager, logManager,
+		notify.NewNotifier(config.GlobalConfig.DigestWebhook), costPrices, logger)
+	digestManager.SetLeader(elector)
+	if config.GlobalConfig.DigestWebhook != "" {
+		if err := digestManager.Start(config.GlobalConfig.DigestSchedule); err != nil {
+			logger.Fatal("invalid digest schedule",
+				zap.String("schedule", config.GlobalConfig.DigestSchedule),
+				zap.Error(err))
+		}
+	}
+	apiServer.SetDigest(digestManager)
+
+	// 开启角色控制时按viewer/operator/admin角色限制接口，角色分配保存在etcd中
+	// 未开启认证时身份来自可伪造的请求头，角色控制没有意义
+	var roleManager *rbacmgr.RoleManager
+	if config.GlobalConfig.RBACEnabled {
+		if !config.GlobalConfig.AuthEnabled {
+			logger.Fatal("rbacEnabled requires authEnabled, roles from request headers cannot be trusted")
+		}
+		roleManager = rbacmgr.NewRoleManager(etcdClient, logger)
+		apiServer.SetRoleManager(roleManager)
+	}
+
+	// 携带Idempotency-Key的POST请求在缓存时间内重试时返回首次的响应
+	if window := config.GlobalConfig.IdempotencyWindow; window > 0 {
+		apiServer.SetIdempotencyStore(idempotency.NewStore(etcdClient, time.Duration(window)*time.Second, logger))
+	}
+
+	// 配置了准入webhook时，保存任务前由webhook修改或拒绝任务定义
+	if webhook := config.GlobalConfig.AdmissionWebhook; webhook != "" {
+		admissionWebhook := admission.NewWebhook(webhook, time.Duration(config.GlobalConfig.AdmissionTimeout)*time.Millisecond, logger)
+		admissionWebhook.SetFailOpen(config.GlobalConfig.AdmissionFailOpen)
+		apiServer.SetAdmission(admissionWebhook)
+	}
+
+	// 由etcd监听汇集任务、执行和worker变化，通过WebSocket推送给面板
+	eventHub := eventhub.NewHub(etcdClient, workerManager, logger)
+	eventHub.Start()
+	apiServer.SetEventHub(eventHub)
+
+	// 由leader定时对账执行意图，为随worker丢失结果的执行补记日志
+	intentReconciler := reconciler.NewManager(etcdClient, logManager, logger)
+	intentReconciler.SetLeader(elector)
+	intentReconciler.Start()
+	apiServer.SetReconciler(intentReconciler)
+
+	// 配置了归档天数时，由leader定时归档长期禁用的任务
+	var jobArchiver *archiver.Manager
+	if config.GlobalConfig.ArchiveDisabledDays > 0 {
+		jobArchiver = archiver.NewManager(etcdClient, jobManager,
+			notify.NewNotifier(config.GlobalConfig.ArchiveWebhook),
+			config.GlobalConfig.ArchiveDisabledDays, config.GlobalConfig.ArchiveNoticeDays, logger)
+		jobArchiver.SetLeader(elector)
+		if err := jobArchiver.Start(config.GlobalConfig.ArchiveSchedule); err != nil {
+			logger.Fatal("invalid archive schedule",
+				zap.String("schedule", config.GlobalConfig.ArchiveSchedule),
+				zap.Error(err))
+		}
+	}
+
+	// 配置了备用集群时，由leader将任务定义复制到备用集群
+	var jobReplicator *replicator.Replicator
+	if len(config.GlobalConfig.DRStandbyEndpoints) > 0 {
+		standbyClient, err := etcd.NewClientFor(config.GlobalConfig.DRStandbyEndpoints)
+		if err != nil {
+			logger.Fatal("failed to connect to standby etcd", zap.Error(err))
+		}
+		standbyClient.SetLogger(loggers.Component(logging.ComponentEtcd))
+		defer standbyClient.Close()
+
+		interval := time.Duration(config.GlobalConfig.DRSyncInterval) * time.Second
+		jobReplicator = replicator.NewReplicator(etcdClient, standbyClient, interval, logger)
+		jobReplicator.SetLeader(elector)
+		jobReplicator.Start()
+		apiServer.SetReplicator(jobReplicator)
+	}
 
 	// 启动API服务器
 	go func() {
@@ -123,6 +270,19 @@ func main() {
 
 	// 优雅关闭
 	apiServer.Stop()
+	if jobReplicator != nil {
+		jobReplicator.Stop()
+	}
+	digestManager.Stop()
+	eventHub.Stop()
+	intentReconciler.Stop()
+	if roleManager != nil {
+		roleManager.Stop()
+	}
+	if jobArchiver != nil {
+		jobArchiver.Stop()
+	}
+	elector.Stop()
 	jobManager.Stop()
 	logManager.Stop()
 	workerManager.Stop()
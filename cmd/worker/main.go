@@ -2,45 +2,101 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
+	"fmt"
+	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
 	"go.uber.org/zap"
-	"go.uber.org/zap/zapcore"
 
+	"github.com/fyerfyer/scheduler-refactor/common"
 	"github.com/fyerfyer/scheduler-refactor/config"
 	"github.com/fyerfyer/scheduler-refactor/pkg/etcd"
-	"github.com/fyerfyer/scheduler-refactor/pkg/mongodb"
+	"github.com/fyerfyer/scheduler-refactor/pkg/healthcheck"
+	"github.com/fyerfyer/scheduler-refactor/pkg/logging"
+	"github.com/fyerfyer/scheduler-refactor/pkg/logstore"
+	"github.com/fyerfyer/scheduler-refactor/pkg/metrics"
+	"github.com/fyerfyer/scheduler-refactor/pkg/notify"
+	"github.com/fyerfyer/scheduler-refactor/pkg/selfcheck"
+	"github.com/fyerfyer/scheduler-refactor/pkg/version"
+	"github.com/fyerfyer/scheduler-refactor/worker/admin"
+	"github.com/fyerfyer/scheduler-refactor/worker/canary"
+	"github.com/fyerfyer/scheduler-refactor/worker/checkpoint"
+	"github.com/fyerfyer/scheduler-refactor/worker/cmdpolicy"
+	"github.com/fyerfyer/scheduler-refactor/worker/condition"
 	"github.com/fyerfyer/scheduler-refactor/worker/executor"
+	"github.com/fyerfyer/scheduler-refactor/worker/fleet"
+	"github.com/fyerfyer/scheduler-refactor/worker/intent"
+	"github.com/fyerfyer/scheduler-refactor/worker/jobkill"
+	"github.com/fyerfyer/scheduler-refactor/worker/joblock"
 	"github.com/fyerfyer/scheduler-refactor/worker/jobmgr"
+	"github.com/fyerfyer/scheduler-refactor/worker/killswitch"
 	"github.com/fyerfyer/scheduler-refactor/worker/logsink"
+	"github.com/fyerfyer/scheduler-refactor/worker/nsconfig"
+	"github.com/fyerfyer/scheduler-refactor/worker/placement"
+	"github.com/fyerfyer/scheduler-refactor/worker/plugin"
+	"github.com/fyerfyer/scheduler-refactor/worker/progress"
 	"github.com/fyerfyer/scheduler-refactor/worker/register"
+	"github.com/fyerfyer/scheduler-refactor/worker/remotecfg"
+	"github.com/fyerfyer/scheduler-refactor/worker/runstats"
+	"github.com/fyerfyer/scheduler-refactor/worker/schedsource"
 	"github.com/fyerfyer/scheduler-refactor/worker/scheduler"
+	"github.com/fyerfyer/scheduler-refactor/worker/tracer"
+	"github.com/fyerfyer/scheduler-refactor/worker/trigger"
 )
 
+// worker本地日志默认保留天数
+const defaultLogRetentionDays = 7
+
 // 全局组件
 type workerContext struct {
-	logger      *zap.Logger
-	etcdClient  *etcd.Client
-	mongoClient *mongodb.Client
-	executor    *executor.Executor
-	jobManager  *jobmgr.JobManager
-	register    *register.Register
-	scheduler   *scheduler.Scheduler
-	logSink     *logsink.LogSink
+	loggers    *logging.Loggers
+	logger     *zap.Logger
+	etcdClient *etcd.Client
+	logStore   logstore.LogStore
+	executor   *executor.Executor
+	jobManager *jobmgr.JobManager
+	register   *register.Register
+	scheduler  *scheduler.Scheduler
+	logSink    *logsink.LogSink
+	remoteCfg  *remotecfg.Watcher
+	cmdPolicy  *cmdpolicy.Watcher
+	nsConfig   *nsconfig.Watcher
+	killSwitch *killswitch.Watcher
+	fleet      *fleet.Watcher
+	schedSrc   *schedsource.Manager
+	canary     *canary.Watcher
+	tracer     *tracer.Tracer
+	placement  *placement.Publisher
+	trigger    *trigger.Watcher
+	jobKill    *jobkill.Watcher
+	admin      *admin.Server
+	metrics    *http.Server
+	notifier   notify.Notifier
+	runStats   *runstats.Collector
+	conditions *condition.Evaluator
+	plugins    *plugin.Registry
 }
 
 func main() {
 	var (
 		configFile string
+		check      bool
+		health     bool
 		err        error
 	)
 
 	// 解析命令行参数
 	flag.StringVar(&configFile, "config", "./worker.json", "worker config file path")
+	flag.BoolVar(&check, "check", false, "check config, etcd, log store, clock and sandbox, then exit")
+	flag.BoolVar(&health, "healthcheck", false, "probe the local admin health endpoint and exit, for container HEALTHCHECK")
 	flag.Parse()
 
 	// 加载配置
@@ -48,6 +104,20 @@ func main() {
 		panic(err)
 	}
 
+	// 健康检查请求管理接口，需要配置adminPort
+	if health {
+		if err = healthcheck.Probe(config.GlobalConfig.AdminPort); err != nil {
+			fmt.Fprintln(os.Stderr, "unhealthy:", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// 自检模式只输出诊断报告，供部署流水线根据退出码判断
+	if check {
+		os.Exit(runSelfCheck())
+	}
+
 	// 创建Worker上下文
 	wctx := &workerContext{}
 
@@ -63,13 +133,39 @@ func main() {
 	waitForExit(wctx)
 }
 
-// initLogger 初始化日志
-func initLogger() *zap.Logger {
-	// 配置zap logger
-	config := zap.NewProductionConfig()
-	config.EncoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
-	logger, _ := config.Build()
-	return logger
+// runSelfCheck 执行自检并输出报告，返回进程退出码
+func runSelfCheck() int {
+	report := selfcheck.Run(selfcheck.RoleWorker)
+	report.Run("sandbox", func() (string, error) {
+		sandbox, err := executor.ResolveSandbox(config.GlobalConfig.Sandbox, config.GlobalConfig.SandboxCommand)
+		if err != nil {
+			return "", err
+		}
+		if len(sandbox) == 0 {
+			return "disabled", nil
+		}
+		return strings.Join(sandbox, " "), nil
+	})
+	report.Run("plugins", func() (string, error) {
+		if config.GlobalConfig.PluginDir == "" {
+			return "disabled", nil
+		}
+		plugins, err := plugin.Discover(config.GlobalConfig.PluginDir, zap.NewNop())
+		if err != nil {
+			return "", err
+		}
+		types := make([]string, 0)
+		for _, manifest := range plugins.Manifests() {
+			types = append(types, manifest.Type)
+		}
+		return fmt.Sprintf("%d loaded %v", len(types), types), nil
+	})
+
+	report.Print(os.Stdout)
+	if report.Failed() {
+		return 1
+	}
+	return 0
 }
 
 // initWorker 初始化Worker组件
@@ -77,38 +173,186 @@ func initWorker(wctx *workerContext) error {
 	var err error
 
 	// 初始化日志
-	wctx.logger = initLogger()
+	if wctx.loggers, err = logging.New(config.GlobalConfig); err != nil {
+		return err
+	}
+	wctx.logger = wctx.loggers.Logger()
 
 	// 初始化etcd客户端
 	if wctx.etcdClient, err = etcd.NewClient(); err != nil {
 		wctx.logger.Error("failed to create etcd client", zap.Error(err))
 		return err
 	}
+	wctx.etcdClient.SetLogger(wctx.loggers.Component(logging.ComponentEtcd))
+
+	// worker的任务、配置、策略和停机开关监听共用一个底层watch
+	wctx.etcdClient.EnableWatchMux(common.CronRootDir)
 
-	// 初始化MongoDB客户端
-	if wctx.mongoClient, err = mongodb.NewClient(); err != nil {
-		wctx.logger.Error("failed to create mongodb client", zap.Error(err))
+	// 初始化日志存储
+	if wctx.logStore, err = logstore.NewLogStore(wctx.logger); err != nil {
+		wctx.logger.Error("failed to create log store",
+			zap.String("backend", config.GlobalConfig.LogBackend),
+			zap.Error(err))
 		return err
 	}
 
+	// 初始化命令策略监听器
+	wctx.cmdPolicy = cmdpolicy.NewWatcher(wctx.logger, wctx.etcdClient)
+
 	// 初始化执行器
-	wctx.executor = executor.NewExecutor(wctx.logger)
+	wctx.executor = executor.NewExecutor(wctx.loggers.Component(logging.ComponentExecutor))
+	wctx.executor.SetPolicy(wctx.cmdPolicy)
+
+	// 配置沙箱，沙箱不可用时拒绝启动，避免任务在沙箱外执行
+	sandbox, err := executor.ResolveSandbox(config.GlobalConfig.Sandbox, config.GlobalConfig.SandboxCommand)
+	if err != nil {
+		wctx.logger.Error("failed to configure job sandbox", zap.Error(err))
+		return err
+	}
+	if len(sandbox) > 0 {
+		wctx.executor.SetSandbox(sandbox)
+		wctx.logger.Info("job sandbox enabled", zap.Strings("template", sandbox))
+	}
+
+	// 发现执行器插件，设置了type的任务交给对应插件执行；插件目录不可读时拒绝启动
+	wctx.plugins, err = plugin.Discover(config.GlobalConfig.PluginDir, wctx.logger)
+	if err != nil {
+		wctx.logger.Error("failed to discover executor plugins", zap.Error(err))
+		return err
+	}
+	wctx.executor.SetPlugins(wctx.plugins)
+
+	// 运行中的任务可以通过进度文件上报进度
+	reporter, err := progress.NewReporter(wctx.logger, wctx.etcdClient, filepath.Join(os.TempDir(), "cron-progress"))
+	if err != nil {
+		wctx.logger.Warn("job progress reporting disabled", zap.Error(err))
+	} else {
+		wctx.executor.SetProgress(reporter)
+	}
+
+	// 开启检查点的任务失败后可以从检查点恢复
+	checkpoints, err := checkpoint.NewStore(wctx.logger, wctx.etcdClient, filepath.Join(os.TempDir(), "cron-checkpoint"))
+	if err != nil {
+		wctx.logger.Warn("job checkpoints disabled", zap.Error(err))
+	} else {
+		wctx.executor.SetCheckpoint(checkpoints)
+	}
+
+	// 任务失败通知，命名空间可以设置默认的通知路由
+	wctx.notifier = notify.NewNotifier(config.GlobalConfig.FailureWebhook)
+	wctx.nsConfig = nsconfig.NewWatcher(wctx.logger, wctx.etcdClient)
 
 	// 初始化任务管理器
 	wctx.jobManager = jobmgr.NewJobManager(wctx.etcdClient, wctx.logger)
 
+	// 初始化执行统计，随心跳上报，定期保存到etcd，重启后继续累计
+	wctx.runStats = runstats.NewPersistentCollector(wctx.logger, wctx.etcdClient)
+
 	// 初始化注册器
 	wctx.register = register.NewRegister(wctx.logger, wctx.etcdClient)
+	wctx.register.SetStats(wctx.runStats)
+	wctx.register.SetExecutors(wctx.plugins.Manifests())
 
 	// 初始化调度器
-	wctx.scheduler = scheduler.NewScheduler(wctx.logger, wctx.jobManager, wctx.etcdClient, wctx.executor)
+	wctx.scheduler = scheduler.NewScheduler(wctx.loggers.Component(logging.ComponentScheduler), wctx.jobManager, wctx.etcdClient, wctx.executor)
+	wctx.scheduler.SetTick(time.Duration(config.GlobalConfig.SchedulerTick) * time.Millisecond)
+	wctx.scheduler.SetExecutors(wctx.plugins)
+
+	// 启动命令前写入执行意图，意图绑定worker的锁租约，master据此发现随worker丢失的执行
+	wctx.executor.SetIntents(intent.NewRecorder(wctx.logger, wctx.etcdClient, wctx.scheduler.LockSession()))
+
+	// 执行前对任务的when条件求值，设置了条件的任务的执行结果记录到etcd供下次求值
+	wctx.conditions = condition.NewEvaluator(wctx.logger, wctx.etcdClient, wctx.executor.Running)
+	wctx.executor.SetConditions(wctx.conditions)
+
+	// 初始化灰度发布监听器
+	wctx.canary = canary.NewWatcher(wctx.logger, wctx.etcdClient)
+	wctx.scheduler.SetCanary(wctx.canary)
+
+	// 初始化调度决策追踪器，可通过管理接口随时开关
+	wctx.tracer = tracer.NewTracer(config.GlobalConfig.TraceScheduler)
+	wctx.scheduler.SetTracer(wctx.tracer)
+
+	// 初始化触发归属决策发布器，master据此说明任务最近一次触发由哪个worker执行
+	wctx.placement = placement.NewPublisher(wctx.logger, wctx.etcdClient)
+	wctx.scheduler.SetPlacementRecorder(wctx.placement)
+
+	// 初始化手动触发监听器
+	wctx.trigger = trigger.NewWatcher(wctx.logger, wctx.etcdClient)
+	wctx.scheduler.SetTriggers(wctx.trigger.Triggers())
+
+	// 初始化kill标记监听器，收到标记后终止本worker上运行中的执行
+	wctx.jobKill = jobkill.NewWatcher(wctx.logger, wctx.etcdClient, wctx.scheduler.RequestKill)
+
+	// 初始化抢锁守卫，统计抢锁情况并限制每秒抢锁次数
+	lockGuard := joblock.NewGuard(config.GlobalConfig.LockRateLimit)
+	wctx.scheduler.SetLockGuard(lockGuard)
+
+	if config.GlobalConfig.AdminPort > 0 {
+		wctx.admin = admin.NewServer(wctx.logger, wctx.tracer, lockGuard)
+		wctx.admin.SetStats(wctx.runStats)
+	}
 
 	// 初始化日志收集器
-	wctx.logSink = logsink.NewLogSink(wctx.mongoClient, wctx.logger)
+	wctx.logSink = logsink.NewLogSink(wctx.logStore, wctx.loggers.Component(logging.ComponentLogSink))
+	wctx.scheduler.SetSkipRecorder(wctx.logSink)
+	wctx.scheduler.SetResultHandler(&resultHandler{wctx: wctx})
+
+	// 初始化紧急停机开关监听器
+	wctx.killSwitch = killswitch.NewWatcher(wctx.logger, wctx.etcdClient, func(ks *common.KillSwitch) {
+		if ks == nil {
+			wctx.scheduler.Resume()
+			return
+		}
+		wctx.scheduler.Halt(time.Duration(ks.GracePeriod) * time.Second)
+	})
+
+	// 初始化蓝绿切换监听器
+	wctx.fleet = fleet.NewWatcher(wctx.logger, wctx.etcdClient)
+	wctx.scheduler.SetFleet(wctx.fleet)
+
+	// 设置了外部调度来源的任务由来源决定下次执行时间
+	wctx.schedSrc = schedsource.NewManager(wctx.logger)
+	wctx.scheduler.SetScheduleSources(wctx.schedSrc)
+
+	// 初始化远程配置监听器
+	wctx.remoteCfg = remotecfg.NewWatcher(wctx.logger, wctx.etcdClient)
+	wctx.remoteCfg.OnChange(func(settings *common.WorkerSettings) {
+		applyWorkerSettings(wctx, settings)
+	})
 
 	return nil
 }
 
+// applyWorkerSettings 应用master下发的配置，未下发的字段回退到本地配置
+func applyWorkerSettings(wctx *workerContext, settings *common.WorkerSettings) {
+	cfg := config.GlobalConfig
+
+	batchSize := cfg.LogBatchSize
+	if settings.LogBatchSize != nil {
+		batchSize = *settings.LogBatchSize
+	}
+	wctx.logSink.SetBatchSize(batchSize)
+
+	commitTimeout := cfg.LogCommitTimeout
+	if settings.LogCommitTimeout != nil {
+		commitTimeout = *settings.LogCommitTimeout
+	}
+	wctx.logSink.SetCommitTimeout(commitTimeout)
+
+	retentionDays := defaultLogRetentionDays
+	if settings.LogRetentionDays != nil {
+		retentionDays = *settings.LogRetentionDays
+	}
+	wctx.logSink.SetRetentionDays(retentionDays)
+
+	maxConcurrent := 0
+	if settings.MaxConcurrentJobs != nil {
+		maxConcurrent = *settings.MaxConcurrentJobs
+	}
+	wctx.scheduler.SetMaxConcurrentJobs(maxConcurrent)
+}
+
 // startWorker 启动Worker组件
 func startWorker(wctx *workerContext) {
 	// 启动Worker注册
@@ -118,40 +362,211 @@ func startWorker(wctx *workerContext) {
 	}
 	wctx.logger.Info("worker register started")
 
+	// 启动命令策略监听，必须在调度器之前加载规则
+	if err := wctx.cmdPolicy.Start(); err != nil {
+		wctx.logger.Error("failed to start command policy watcher", zap.Error(err))
+		return
+	}
+
+	// 启动命名空间设置监听，失败时使用全局的失败通知地址
+	if err := wctx.nsConfig.Start(); err != nil {
+		wctx.logger.Warn("failed to start namespace settings watcher, using global notify route", zap.Error(err))
+	}
+
+	// 启动紧急停机开关监听，必须在调度器之前加载开关状态
+	if err := wctx.killSwitch.Start(); err != nil {
+		wctx.logger.Error("failed to start kill switch watcher", zap.Error(err))
+		return
+	}
+
+	// 启动蓝绿切换监听，必须在调度器之前加载切换
+	if err := wctx.fleet.Start(); err != nil {
+		wctx.logger.Error("failed to start fleet switch watcher", zap.Error(err))
+		return
+	}
+
+	// 恢复上次保存的执行统计，必须在调度器之前
+	if err := wctx.runStats.Start(); err != nil {
+		wctx.logger.Error("failed to start run stats", zap.Error(err))
+	}
+
+	// 启动灰度发布监听，失败时所有触发执行当前定义
+	if err := wctx.canary.Start(); err != nil {
+		wctx.logger.Warn("failed to start canary watcher, running current job definitions", zap.Error(err))
+	}
+
+	// 启动手动触发监听，失败时只按cron表达式执行
+	if err := wctx.trigger.Start(); err != nil {
+		wctx.logger.Warn("failed to start job trigger watcher, manual triggers disabled", zap.Error(err))
+	}
+
 	// 启动任务调度器
+	wctx.placement.Start()
 	wctx.scheduler.Start()
+	wctx.jobKill.Start()
 	wctx.logger.Info("job scheduler started")
 
-	// 启动日志清理器
-	cleanCtx, _ := context.WithCancel(context.Background())
-	wctx.logSink.StartLogCleaner(cleanCtx, 7) // 默认保留7天日志
-	wctx.logger.Info("log cleaner started")
+	// 启动远程配置监听，先于清理器启动以便使用下发的保留天数
+	if err := wctx.remoteCfg.Start(); err != nil {
+		wctx.logger.Warn("failed to start worker settings watcher, using local config", zap.Error(err))
+	}
+
+	// 日志保留由master统一负责，只有显式开启时worker才自行清理
+	if config.GlobalConfig.WorkerLogCleanup {
+		if err := wctx.logSink.StartLogCleaner(context.Background(), defaultLogRetentionDays); err != nil {
+			wctx.logger.Error("failed to start log cleaner", zap.Error(err))
+		}
+	} else {
+		wctx.logger.Info("log cleanup is owned by master, worker cleaner disabled")
+	}
+
+	// 启动管理接口
+	if wctx.admin != nil {
+		go func() {
+			if err := wctx.admin.Start(); err != nil {
+				wctx.logger.Error("worker admin server error", zap.Error(err))
+			}
+		}()
+	}
 
-	// 注册执行结果处理器
-	go handleExecuteResults(wctx)
+	// 启动Prometheus指标接口
+	if config.GlobalConfig.MetricsPort > 0 {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", metrics.Handler())
+		wctx.metrics = &http.Server{
+			Addr:    fmt.Sprintf(":%d", config.GlobalConfig.MetricsPort),
+			Handler: mux,
+		}
+		go func() {
+			wctx.logger.Info("starting worker metrics server", zap.Int("port", config.GlobalConfig.MetricsPort))
+			if err := wctx.metrics.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				wctx.logger.Error("worker metrics server error", zap.Error(err))
+			}
+		}()
+	}
 
 	wctx.logger.Info("worker started successfully",
 		zap.String("workerId", config.GlobalConfig.WorkerID),
+		zap.String("version", version.Version),
+		zap.String("commit", version.Commit),
 		zap.Strings("etcdEndpoints", config.GlobalConfig.EtcdEndpoints))
 }
 
-// handleExecuteResults 处理任务执行结果
-func handleExecuteResults(wctx *workerContext) {
-	resultChan := wctx.executor.GetResultChan()
+// resultHandler 处理调度器交来的执行结果：写入日志、上报灰度结果、通知失败
+type resultHandler struct {
+	wctx *workerContext
+}
+
+// HandleResult 实现scheduler.ResultHandler接口
+func (h *resultHandler) HandleResult(result *common.JobExecuteResult, jobInfo *common.JobExecuteInfo) {
+	wctx := h.wctx
 
-	for result := range resultChan {
-		// 查找任务执行信息
-		jobInfo, exists := wctx.scheduler.GetExecutingJobs()[result.JobName]
-		if exists {
-			// 构建日志
-			jobLog := executor.BuildJobLog(result, jobInfo)
+	// 重复投递的手动触发没有启动命令，日志由确认执行的worker写入
+	if result.SkipReason == common.SkipReasonDuplicate {
+		return
+	}
 
-			// 发送到日志收集器
-			wctx.logSink.Append(jobLog)
+	// 构建日志并发送到日志收集器
+	jobLog := executor.BuildJobLog(result, jobInfo)
+	wctx.logSink.Append(jobLog)
+	wctx.runStats.Record(jobLog)
+	wctx.conditions.Record(result, jobInfo)
+
+	// 上报灰度执行结果，被跳过的执行不计入
+	if jobInfo.Canary && jobLog.Status != common.RunStatusSkipped {
+		wctx.canary.Report(result.JobName, jobLog.Status == common.RunStatusSuccess)
+	}
+
+	// 通知执行失败，试运行和开启了静默失败的任务只记录不通知
+	if jobLog.Status.IsFailure() && !jobInfo.Experiment && !jobInfo.Job.QuietFailures {
+		go notifyFailure(wctx, jobLog, jobInfo.Job.Notify)
+	}
+}
+
+// notifyFailure 按任务和命名空间的通知路由发送任务执行失败通知，附带脱敏后的输出末尾
+func notifyFailure(wctx *workerContext, jobLog *common.JobLog, jobRoute *common.NotifyRoute) {
+	severity, webhooks := common.ResolveNotifyRoute(jobRoute, wctx.nsConfig.NotifyRoute(jobLog.Namespace), jobLog.Status)
+	if severity == common.SeverityNone {
+		return
+	}
+
+	text := jobLog.Error
+	if tail := notify.OutputTail(jobLog.Output, config.GlobalConfig.NotifyOutputLines); tail != "" {
+		if text != "" {
+			text += "\n\n"
+		}
+		text += tail
+	}
+
+	msg := &notify.Message{
+		Event: "job_failed",
+		Title: "job " + jobLog.JobName + " " + string(jobLog.Status),
+		Text:  text,
+		Fields: map[string]string{
+			"jobName":   jobLog.JobName,
+			"runId":     jobLog.RunID,
+			"worker":    jobLog.WorkerIP,
+			"status":    string(jobLog.Status),
+			"exitCode":  strconv.Itoa(jobLog.ExitCode),
+			"namespace": jobLog.Namespace,
+			"severity":  severity,
+		},
+	}
+
+	// 任务和命名空间都没有设置地址时使用全局的失败通知地址
+	notifiers := []notify.Notifier{wctx.notifier}
+	if webhooks != nil {
+		notifiers = notifiers[:0]
+		for _, webhook := range webhooks {
+			notifiers = append(notifiers, notify.NewWebhookNotifier(webhook))
+		}
+	}
+	for _, notifier := range notifiers {
+		if err := notifier.Notify(msg); err != nil {
+			wctx.logger.Warn("failed to send job failure notification",
+				zap.String("jobName", jobLog.JobName),
+				zap.Error(err))
 		}
 	}
 }
 
+// 关闭各阶段的超时时间
+const (
+	killWaitTimeout   = 5 * time.Second  // 终止超时任务后等待其结果
+	logFlushTimeout   = 10 * time.Second // 写入剩余日志
+	deregisterTimeout = 5 * time.Second  // 删除注册信息
+)
+
+// shutdownStage 执行一个关闭阶段并记录耗时，超时后放弃等待，继续下一阶段
+func shutdownStage(logger *zap.Logger, name string, timeout time.Duration, fn func(ctx context.Context) error) {
+	start := time.Now()
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- fn(ctx)
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			logger.Warn("shutdown stage failed",
+				zap.String("stage", name),
+				zap.Duration("elapsed", time.Since(start)),
+				zap.Error(err))
+			return
+		}
+		logger.Info("shutdown stage finished",
+			zap.String("stage", name),
+			zap.Duration("elapsed", time.Since(start)))
+	case <-ctx.Done():
+		logger.Warn("shutdown stage timed out",
+			zap.String("stage", name),
+			zap.Duration("timeout", timeout))
+	}
+}
+
 // waitForExit 等待退出信号并优雅关闭
 func waitForExit(wctx *workerContext) {
 	// 创建接收信号的通道
@@ -162,33 +577,82 @@ func waitForExit(wctx *workerContext) {
 	sig := <-sigChan
 	wctx.logger.Info("received signal, starting graceful shutdown", zap.String("signal", sig.String()))
 
-	// 给清理操作设定一个超时时间
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
-	// 首先停止调度器
-	wctx.scheduler.Stop()
-	wctx.logger.Info("scheduler stopped")
+	shutdown(wctx)
+	wctx.logger.Info("worker shutdown complete")
+}
 
-	// 停止Worker注册
-	wctx.register.Stop()
-	wctx.logger.Info("worker register stopped")
+// shutdown 按顺序关闭worker：停止调度 → 等待执行结束 → 处理完结果 → 写入日志 → 注销，
+// 每个阶段有独立的超时时间，保证已经结束的执行都留下日志
+func shutdown(wctx *workerContext) {
+	logger := wctx.logger
+
+	// 停止调度，运行中的任务继续执行
+	shutdownStage(logger, "stop scheduling", time.Second, func(ctx context.Context) error {
+		wctx.scheduler.Drain()
+		wctx.remoteCfg.Stop()
+		wctx.cmdPolicy.Stop()
+		wctx.nsConfig.Stop()
+		wctx.trigger.Stop()
+		wctx.killSwitch.Stop()
+		wctx.fleet.Stop()
+		wctx.schedSrc.Stop()
+		if wctx.admin != nil {
+			wctx.admin.Stop()
+		}
+		if wctx.metrics != nil {
+			wctx.metrics.Shutdown(ctx)
+		}
+		return nil
+	})
+
+	// 等待运行中的任务结束，超过drainTimeout后终止，调度循环空闲时所有结果都已交给日志收集器
+	drainTimeout := time.Duration(config.GlobalConfig.DrainTimeout) * time.Second
+	shutdownStage(logger, "wait executions", drainTimeout+killWaitTimeout, func(ctx context.Context) error {
+		waitCtx, cancel := context.WithTimeout(ctx, drainTimeout)
+		defer cancel()
+		if wctx.scheduler.WaitIdle(waitCtx) {
+			return nil
+		}
 
-	// 确保日志收集器写入所有缓存日志
-	wctx.logSink.Stop()
-	wctx.logger.Info("log sink stopped")
+		logger.Warn("jobs still running after drain timeout, killing them",
+			zap.Duration("drainTimeout", drainTimeout))
+		wctx.scheduler.KillRunning()
+		if !wctx.scheduler.WaitIdle(ctx) {
+			return errors.New("killed jobs did not report results in time")
+		}
+		return nil
+	})
 
-	// 关闭MongoDB连接
-	if err := wctx.mongoClient.Close(); err != nil {
-		wctx.logger.Error("failed to close mongodb connection", zap.Error(err))
+	// 结果处理完毕后再停止调度循环和灰度上报
+	wctx.jobKill.Stop()
+	wctx.scheduler.Stop()
+	wctx.canary.Stop()
+	wctx.placement.Stop()
+
+	// 写入通道和批次中的剩余日志
+	shutdownStage(logger, "flush logs", logFlushTimeout, func(ctx context.Context) error {
+		wctx.logSink.Stop()
+		return nil
+	})
+
+	// 保存执行统计，重启后继续累计
+	shutdownStage(logger, "persist run stats", deregisterTimeout, func(ctx context.Context) error {
+		wctx.runStats.Stop()
+		return nil
+	})
+
+	// 删除注册信息，master立即感知worker下线
+	shutdownStage(logger, "deregister", deregisterTimeout, func(ctx context.Context) error {
+		return wctx.register.Deregister()
+	})
+
+	// 关闭日志存储连接
+	if err := wctx.logStore.Close(); err != nil {
+		logger.Error("failed to close log store", zap.Error(err))
 	}
 
 	// 关闭etcd连接
 	if err := wctx.etcdClient.Close(); err != nil {
-		wctx.logger.Error("failed to close etcd connection", zap.Error(err))
+		logger.Error("failed to close etcd connection", zap.Error(err))
 	}
-
-	// 等待超时或者所有清理工作完成
-	<-ctx.Done()
-	wctx.logger.Info("worker shutdown complete")
 }
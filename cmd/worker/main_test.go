@@ -2,7 +2,6 @@ package main
 
 import (
 	"encoding/json"
-	"github.com/fyerfyer/scheduler-refactor/worker/executor"
 	"os"
 	"path/filepath"
 	"testing"
@@ -10,6 +9,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
 
 	"github.com/fyerfyer/scheduler-refactor/common"
 	"github.com/fyerfyer/scheduler-refactor/config"
@@ -57,7 +57,7 @@ func TestInitWorker(t *testing.T) {
 
 	assert.NotNil(t, wctx.logger, "Logger should not be nil")
 	assert.NotNil(t, wctx.etcdClient, "ETCD client should not be nil")
-	assert.NotNil(t, wctx.mongoClient, "MongoDB client should not be nil")
+	assert.NotNil(t, wctx.logStore, "Log store should not be nil")
 	assert.NotNil(t, wctx.executor, "Executor should not be nil")
 	assert.NotNil(t, wctx.jobManager, "JobManager should not be nil")
 	assert.NotNil(t, wctx.register, "Register should not be nil")
@@ -116,9 +116,6 @@ func TestHandleExecuteResults(t *testing.T) {
 
 	wctx.scheduler.GetExecutingJobs()["test-job"] = jobExecuteInfo
 
-	// 不使用反射，直接创建一个执行结果并发送到日志收集器
-	go handleExecuteResults(wctx)
-
 	// 创建测试结果
 	result := &common.JobExecuteResult{
 		JobName:   "test-job",
@@ -129,9 +126,9 @@ func TestHandleExecuteResults(t *testing.T) {
 		ExitCode:  0,
 	}
 
-	// 创建日志并直接发送到logSink而不是通过执行器的结果通道
-	jobLog := executor.BuildJobLog(result, jobExecuteInfo)
-	wctx.logSink.Append(jobLog)
+	// 直接交给结果处理器，而不是通过执行器的结果通道
+	handler := &resultHandler{wctx: wctx}
+	handler.HandleResult(result, jobExecuteInfo)
 
 	time.Sleep(500 * time.Millisecond)
 }
@@ -140,7 +137,7 @@ func TestLogSinkCleanup(t *testing.T) {
 	err := setupConfig(t)
 	require.NoError(t, err, "Failed to setup config")
 
-	logger := initLogger()
+	logger := zaptest.NewLogger(t)
 	mongoClient, err := mongodb.NewClient()
 	require.NoError(t, err, "Failed to create MongoDB client")
 	defer mongoClient.Close()
@@ -187,11 +184,11 @@ func TestLogSinkCleanup(t *testing.T) {
 	logSink.CleanExpiredLogs(7)
 	time.Sleep(500 * time.Millisecond)
 
-	logs, err := mongoClient.FindJobLogs("test-old-job", 0, 10)
+	logs, err := mongoClient.FindJobLogs("test-old-job", nil, 0, 10)
 	require.NoError(t, err, "Failed to query logs")
 	assert.Equal(t, 0, len(logs), "Old logs should be deleted")
 
-	logs, err = mongoClient.FindJobLogs("test-recent-job", 0, 10)
+	logs, err = mongoClient.FindJobLogs("test-recent-job", nil, 0, 10)
 	require.NoError(t, err, "Failed to query logs")
 	assert.Equal(t, 1, len(logs), "Recent logs should not be deleted")
 	err = mongoClient.DropCollection()
@@ -202,8 +199,8 @@ func closeWorkerContext(wctx *workerContext) {
 	if wctx.etcdClient != nil {
 		wctx.etcdClient.Close()
 	}
-	if wctx.mongoClient != nil {
-		wctx.mongoClient.Close()
+	if wctx.logStore != nil {
+		wctx.logStore.Close()
 	}
 	if wctx.scheduler != nil {
 		wctx.scheduler.Stop()
@@ -228,8 +225,3 @@ func setupConfig(t *testing.T) error {
 	}
 	return nil
 }
-
-func TestInitLogger(t *testing.T) {
-	logger := initLogger()
-	assert.NotNil(t, logger, "Logger should not be nil")
-}
@@ -0,0 +1,35 @@
+package common
+
+import (
+	"fmt"
+	"time"
+)
+
+// APIKey 调用API的密钥。密钥明文只在创建时返回一次，etcd中只保存它的哈希
+type APIKey struct {
+	ID         string   `json:"id"`                   // 密钥ID，也是密钥明文的前缀
+	Name       string   `json:"name"`                 // 用途说明
+	User       string   `json:"user"`                 // 以该密钥调用时的用户名
+	Role       string   `json:"role,omitempty"`       // 以该密钥调用时的角色
+	Namespaces []string `json:"namespaces,omitempty"` // 以该密钥调用时可访问的命名空间
+	SecretHash string   `json:"secretHash,omitempty"` // 密钥的SHA-256哈希，不对外返回
+	CreatedBy  string   `json:"createdBy"`            // 创建人
+	CreatedAt  int64    `json:"createdAt"`            // 创建时间
+	ExpiresAt  int64    `json:"expiresAt,omitempty"`  // 过期时间，0表示不过期
+}
+
+// Validate 校验密钥信息
+func (k *APIKey) Validate() error {
+	if k.User == "" {
+		return fmt.Errorf("%w: user is required", ErrInvalidAPIKey)
+	}
+	if k.Role != "" && k.Role != RoleAdmin {
+		return fmt.Errorf("%w: role must be empty or %s", ErrInvalidAPIKey, RoleAdmin)
+	}
+	return nil
+}
+
+// Expired 密钥在now时是否已过期
+func (k *APIKey) Expired(now time.Time) bool {
+	return k.ExpiresAt > 0 && now.Unix() >= k.ExpiresAt
+}
@@ -0,0 +1,17 @@
+package common
+
+// Record 记录一次灰度执行结果，done表示已观察够执行次数，promote表示成功率达标应全量
+func (c *CanaryRelease) Record(succeeded bool) (done bool, promote bool) {
+	if succeeded {
+		c.Successes++
+	} else {
+		c.Failures++
+	}
+
+	total := c.Successes + c.Failures
+	if total < c.Runs {
+		return false, false
+	}
+
+	return true, float64(c.Successes)/float64(total) >= c.MinSuccessRate
+}
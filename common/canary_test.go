@@ -0,0 +1,26 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCanaryRecord(t *testing.T) {
+	canary := &CanaryRelease{Runs: 3, MinSuccessRate: 0.6}
+
+	done, _ := canary.Record(true)
+	assert.False(t, done, "Canary should keep running before enough runs")
+	done, _ = canary.Record(false)
+	assert.False(t, done)
+
+	done, promote := canary.Record(true)
+	assert.True(t, done)
+	assert.True(t, promote, "2 of 3 successful runs should meet 0.6")
+
+	canary = &CanaryRelease{Runs: 2, MinSuccessRate: 1.0}
+	canary.Record(true)
+	done, promote = canary.Record(false)
+	assert.True(t, done)
+	assert.False(t, promote, "Any failure should roll back when full success is required")
+}
@@ -0,0 +1,67 @@
+package common
+
+// 支持的API消息语言
+const (
+	LangEn = "en" // 英文，默认语言
+	LangZh = "zh" // 中文
+)
+
+// ApiErrorEntry 错误目录中的一项
+type ApiErrorEntry struct {
+	Code      int               `json:"code"`      // 数字错误码，与ApiResponse.Code一致
+	ErrorCode string            `json:"errorCode"` // 稳定的机器可读错误码
+	Messages  map[string]string `json:"messages"`  // 各语言的提示信息
+}
+
+// apiErrorCatalog 错误目录，新增API状态码时需要同时补充
+var apiErrorCatalog = []*ApiErrorEntry{
+	{ApiFailure, "FAILURE", map[string]string{LangEn: "Request failed", LangZh: "请求失败"}},
+	{ApiParamError, "INVALID_PARAMETER", map[string]string{LangEn: "Invalid parameter", LangZh: "参数错误"}},
+	{ApiJobNotExist, "JOB_NOT_FOUND", map[string]string{LangEn: "Job not found", LangZh: "任务不存在"}},
+	{ApiJobExecFail, "JOB_EXECUTION_FAILED", map[string]string{LangEn: "Job execution failed", LangZh: "任务执行失败"}},
+	{ApiPolicyDeny, "POLICY_DENIED", map[string]string{LangEn: "Command denied by policy", LangZh: "命令被策略拒绝"}},
+	{ApiPending, "PENDING_APPROVAL", map[string]string{LangEn: "Change submitted for approval", LangZh: "变更已提交，等待审批"}},
+	{ApiForbidden, "FORBIDDEN", map[string]string{LangEn: "Permission denied", LangZh: "无权限"}},
+	{ApiFrozen, "CHANGE_FROZEN", map[string]string{LangEn: "Changes are frozen", LangZh: "处于变更冻结窗口"}},
+	{ApiReadOnly, "READ_ONLY", map[string]string{LangEn: "Master is in read-only mode", LangZh: "master处于只读模式"}},
+	{ApiCompacted, "REVISION_COMPACTED", map[string]string{LangEn: "Revision has been compacted, reload full data", LangZh: "起始版本已被压缩，请重新拉取全量数据"}},
+	{ApiUnauthorized, "UNAUTHORIZED", map[string]string{LangEn: "Authentication required", LangZh: "未认证或凭证无效"}},
+	{ApiIdempotency, "IDEMPOTENCY_CONFLICT", map[string]string{LangEn: "Idempotency key conflicts with another request", LangZh: "幂等键已用于其他请求或请求仍在处理"}},
+	{ApiAdmission, "ADMISSION_DENIED", map[string]string{LangEn: "Job denied by admission webhook", LangZh: "任务被准入webhook拒绝"}},
+	{ApiNotFound, "NOT_FOUND", map[string]string{LangEn: "Resource not found", LangZh: "资源不存在"}},
+	{ApiSystemError, "SYSTEM_ERROR", map[string]string{LangEn: "System error", LangZh: "系统错误"}},
+	{ApiDbError, "DATABASE_ERROR", map[string]string{LangEn: "Database error", LangZh: "数据库错误"}},
+	{ApiEtcdError, "ETCD_ERROR", map[string]string{LangEn: "Etcd error", LangZh: "Etcd操作错误"}},
+}
+
+// apiErrorsByCode 按数字错误码索引的错误目录
+var apiErrorsByCode = func() map[int]*ApiErrorEntry {
+	index := make(map[int]*ApiErrorEntry, len(apiErrorCatalog))
+	for _, entry := range apiErrorCatalog {
+		index[entry.Code] = entry
+	}
+	return index
+}()
+
+// ApiErrorCatalog 返回完整的错误目录
+func ApiErrorCatalog() []*ApiErrorEntry {
+	return apiErrorCatalog
+}
+
+// ApiErrorCode 返回数字错误码对应的机器可读错误码，未登记时返回空串
+func ApiErrorCode(code int) string {
+	if entry, ok := apiErrorsByCode[code]; ok {
+		return entry.ErrorCode
+	}
+	return ""
+}
+
+// ApiMessage 返回错误码在指定语言下的提示信息，没有对应翻译时返回false
+func ApiMessage(code int, lang string) (string, bool) {
+	entry, ok := apiErrorsByCode[code]
+	if !ok {
+		return "", false
+	}
+	message, ok := entry.Messages[lang]
+	return message, ok
+}
@@ -0,0 +1,25 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApiErrorCatalog(t *testing.T) {
+	seen := make(map[string]bool)
+	for _, entry := range ApiErrorCatalog() {
+		assert.NotEmpty(t, entry.ErrorCode)
+		assert.False(t, seen[entry.ErrorCode], "Error code %s should be unique", entry.ErrorCode)
+		seen[entry.ErrorCode] = true
+		for _, lang := range []string{LangEn, LangZh} {
+			assert.NotEmpty(t, entry.Messages[lang], "Code %d should have a %s message", entry.Code, lang)
+		}
+	}
+
+	assert.Equal(t, "READ_ONLY", ApiErrorCode(ApiReadOnly))
+	assert.Equal(t, "NOT_FOUND", ApiErrorCode(ApiNotFound))
+	assert.Empty(t, ApiErrorCode(ApiSuccess))
+	_, ok := ApiMessage(ApiSuccess, LangZh)
+	assert.False(t, ok)
+}
@@ -2,15 +2,126 @@ package common
 
 // Etcd相关常量
 const (
+	// 所有数据的根目录
+	CronRootDir = "/cron/"
+
 	// 任务保存目录
 	JobSaveDir = "/cron/jobs/"
 
 	// 任务锁目录
 	JobLockDir = "/cron/lock/"
 
+	// 任务kill标记目录，key为任务名，标记绑定短租约，worker收到后终止运行中的执行
+	JobKillDir = "/cron/kill/"
+
+	// kill标记的租约时间(秒)
+	JobKillTTL = 5
+
 	// 服务注册目录
 	WorkerRegisterDir = "/cron/workers/"
 
+	// worker集中配置目录，global为全局配置，其余key为对应worker的配置
+	WorkerConfigDir = "/cron/config/"
+
+	// worker全局配置key
+	WorkerConfigGlobal = "global"
+
+	// 命令策略规则目录
+	PolicyDir = "/cron/policy/"
+
+	// 命令策略拒绝记录目录，key以拒绝时间开头，记录绑定租约到期自动删除
+	PolicyAuditDir = "/cron/policyaudit/"
+
+	// 待审批的任务变更目录
+	JobPendingDir = "/cron/pending/"
+
+	// 变更冻结窗口目录
+	FreezeDir = "/cron/freeze/"
+
+	// worker紧急停机开关目录，key为worker ID
+	KillSwitchDir = "/cron/killswitch/"
+
+	// worker蓝绿切换，所有worker共用一个切换
+	FleetSwitchKey = "/cron/fleet/switch"
+
+	// 灾备复制记录目录，位于备用集群，记录每个任务最近一次从主集群复制的信息
+	ReplicationDir = "/cron/replication/"
+
+	// 实验命令锁的后缀，与任务锁位于同一目录，保证实验命令与当前命令由不同worker执行
+	ExperimentLockSuffix = "#experiment"
+
+	// 任务灰度发布目录，key为任务名
+	CanaryDir = "/cron/canary/"
+
+	// 任务改名记录目录，key为改名前的任务名
+	JobRenamedDir = "/cron/renamed/"
+
+	// 任务检查点目录，key为任务名，保存失败执行留下的检查点供下次执行恢复
+	JobCheckpointDir = "/cron/checkpoint/"
+
+	// 运行中任务上报的进度目录，key为任务名，执行结束后删除
+	JobProgressDir = "/cron/progress/"
+
+	// 触发归属决策目录，key为任务名/worker ID，保存每个worker对任务最近一次触发的决策
+	JobPlacementDir = "/cron/placement/"
+
+	// 手动触发目录，key为任务名/执行ID，worker抢到后删除并立即执行
+	JobTriggerDir = "/cron/trigger/"
+
+	// 任务组集合点目录，key为任务组/计划时间/成员，成员全部抢到锁后一起开始执行
+	JobGangDir = "/cron/gang/"
+
+	// 互斥组锁目录，key为互斥组名，同组任务执行期间持有，保证同组任务不会同时执行
+	ExclusionLockDir = "/cron/exclusion/"
+
+	// 信号量定义目录，key为信号量名，值为信号量定义，限制集群内同时使用共享资源的执行数
+	SemaphoreDir = "/cron/semaphores/"
+
+	// 信号量槽位目录，key为信号量名/槽位序号，执行期间持有，槽位数不超过信号量的上限
+	SemaphoreSlotDir = "/cron/semaphore-slots/"
+
+	// 任务执行实例槽位目录，key为任务名/槽位序号，执行期间持有，限制任务在集群内同时执行的实例数
+	JobInstanceDir = "/cron/instance/"
+
+	// 归档任务目录，key为任务名，长期禁用的任务移到这里，不再下发给worker
+	JobArchiveDir = "/cron/archive/"
+
+	// 归档提醒记录key，保存已提醒即将归档的任务及其禁用时间，避免重复提醒
+	ArchiveNoticeKey = "/cron/archivenotice"
+
+	// 命名空间设置目录，key为命名空间
+	NamespaceDir = "/cron/namespaces/"
+
+	// API密钥目录，key为密钥ID，值为密钥信息，只保存密钥的哈希
+	APIKeyDir = "/cron/apikeys/"
+
+	// 执行意图目录，key为runId，worker启动命令前写入，结束后更新为结束状态，由master对账后删除
+	RunIntentDir = "/cron/intents/"
+
+	// 角色分配目录，key为用户名，值为分配的角色
+	RoleBindingDir = "/cron/roles/"
+
+	// 幂等记录目录，key为调用方、接口和幂等键的哈希，值为首次请求的响应，绑定租约到期自动删除
+	IdempotencyDir = "/cron/idempotency/"
+
+	// worker执行统计目录，key为worker ID，worker重启后从中恢复计数
+	WorkerRunStatsDir = "/cron/runstats/"
+
+	// 任务最近一次执行结果目录，key为任务名，只记录设置了when条件的任务，供条件中的lastRun变量使用
+	JobLastRunDir = "/cron/lastrun/"
+
+	// 每周摘要的发送记录key，保存上次发送时间和当时的任务列表
+	DigestStateKey = "/cron/digest/state"
+
+	// 集群初始化记录key，由master -init写入
+	BootstrapKey = "/cron/bootstrap"
+
+	// master选主key，持有者负责日志清理等集群级任务
+	MasterLeaderKey = "/cron/leader/master"
+
+	// master选主租约时间(秒)
+	MasterLeaderTTL = 10
+
 	// Etcd操作超时时间
 	EtcdDialTimeout = 5000 // 毫秒
 
@@ -24,24 +135,43 @@ const (
 	JobEventDelete            // 删除任务事件
 )
 
-// 任务执行结果状态
+// 任务变更动作
 const (
-	JobStatusSuccess = iota // 执行成功
-	JobStatusError          // 执行错误
-	JobStatusTimeout        // 执行超时
-	JobStatusKilled         // 被强制终止
+	ChangeActionSave   = "save"   // 保存任务
+	ChangeActionDelete = "delete" // 删除任务
+	ChangeActionRename = "rename" // 任务改名
+)
+
+// DefaultNamespace 未指定命名空间的任务所属的命名空间
+const DefaultNamespace = "default"
+
+// 用户角色
+const (
+	RoleViewer   = "viewer"   // 只读，只能调用查询接口
+	RoleOperator = "operator" // 操作员，可以保存、删除、触发和终止任务
+	RoleAdmin    = "admin"    // 管理员，同时也是审批人
 )
 
 // API响应状态码
 const (
-	ApiSuccess     = 0    // 成功
-	ApiFailure     = 1000 // 一般性错误
-	ApiParamError  = 1001 // 参数错误
-	ApiJobNotExist = 1002 // 任务不存在
-	ApiJobExecFail = 1003 // 任务执行失败
-	ApiSystemError = 2000 // 系统错误
-	ApiDbError     = 2001 // 数据库错误
-	ApiEtcdError   = 2002 // Etcd操作错误
+	ApiSuccess      = 0    // 成功
+	ApiFailure      = 1000 // 一般性错误
+	ApiParamError   = 1001 // 参数错误
+	ApiJobNotExist  = 1002 // 任务不存在
+	ApiJobExecFail  = 1003 // 任务执行失败
+	ApiPolicyDeny   = 1004 // 命令被策略拒绝
+	ApiPending      = 1005 // 变更已提交，等待审批
+	ApiForbidden    = 1006 // 无权限
+	ApiFrozen       = 1007 // 处于变更冻结窗口
+	ApiReadOnly     = 1008 // master处于只读模式
+	ApiCompacted    = 1009 // 监听的起始版本已被压缩，需要重新拉取全量数据
+	ApiUnauthorized = 1010 // 未认证或凭证无效
+	ApiIdempotency  = 1011 // 幂等键已用于不同的请求，或使用同一幂等键的请求仍在处理
+	ApiAdmission    = 1012 // 任务被准入webhook拒绝，或准入webhook不可用
+	ApiNotFound     = 1013 // 任务以外的资源不存在，如执行、API密钥、信号量
+	ApiSystemError  = 2000 // 系统错误
+	ApiDbError      = 2001 // 数据库错误
+	ApiEtcdError    = 2002 // Etcd操作错误
 )
 
 // 日志批处理相关
@@ -56,9 +186,38 @@ const (
 	DefaultPageSize   = 10  // 默认页大小
 	MaxPageSize       = 100 // 最大页大小
 	DefaultJobTimeout = 60  // 默认任务超时时间(秒)
+
+	DefaultKillGracePeriod = 30 // 紧急停机后终止运行中任务前的默认宽限时间(秒)
+
+	DefaultZoneFailoverDelay = 10 // 首选可用区的worker未接手时，其他可用区等待的默认时间(秒)
+
+	DefaultGangTimeout = 30 // 任务组成员等待其他成员抢到锁的默认时间(秒)
+
+	MaxExclusionGroups = 8 // 单个任务最多所属的互斥组数
+
+	MaxJobSemaphores  = 8    // 单个任务最多占用的信号量数
+	MaxSemaphoreLimit = 1024 // 信号量的最大槽位数
+
+	MaxJobAnnotations       = 32        // 单个任务最多的注解数
+	MaxJobDescriptionLength = 1024      // 任务说明的最大长度(字符)
+	MaxCheckpointSize       = 64 * 1024 // 检查点的最大字节数，超出时不保存
+
+	DefaultCanaryRuns           = 3   // 灰度发布默认观察的执行次数
+	DefaultCanaryMinSuccessRate = 1.0 // 灰度发布全量所需的默认成功率
+
+	DefaultLogCleanSchedule = "0 0 3 * * *" // 默认日志清理时间，每天3点
+
+	DefaultDigestSchedule = "0 0 9 * * 1" // 默认每周摘要发送时间，每周一9点
+	DefaultDigestDays     = 7             // 首次发送或预览时摘要覆盖的天数
+	DigestTopJobs         = 10            // 摘要中失败和超时排行列出的任务数
+
+	DefaultArchiveSchedule   = "0 0 4 * * *" // 默认归档检查时间，每天4点
+	DefaultArchiveNoticeDays = 3             // 默认提前几天提醒即将归档的任务
 )
 
 // MongoDB 相关
 const (
-	LogCollectionName = "job_logs" // 日志集合名
+	LogCollectionName       = "job_logs"          // 日志集合名
+	StatsCollectionName     = "job_stats_daily"   // 按天汇总的统计集合名
+	MigrationCollectionName = "schema_migrations" // schema迁移记录集合名
 )
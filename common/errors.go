@@ -15,6 +15,9 @@ var (
 	// ErrJobSaveConflict 任务保存冲突错误
 	ErrJobSaveConflict = errors.New("job save conflict")
 
+	// ErrJobExists 任务已存在错误
+	ErrJobExists = errors.New("job already exists")
+
 	// ErrInvalidCronExpr 无效的cron表达式错误
 	ErrInvalidCronExpr = errors.New("invalid cron expression")
 
@@ -23,6 +26,117 @@ var (
 
 	// ErrJobExecutionTimeout 任务执行超时错误
 	ErrJobExecutionTimeout = errors.New("job execution timeout")
+
+	// ErrWorkerSettingsNotFound worker配置不存在错误
+	ErrWorkerSettingsNotFound = errors.New("worker settings not found")
+
+	// ErrInvalidWorkerSettings worker配置取值非法错误
+	ErrInvalidWorkerSettings = errors.New("invalid worker settings")
+
+	// ErrInvalidPolicyRule 策略规则非法错误
+	ErrInvalidPolicyRule = errors.New("invalid policy rule")
+
+	// ErrPolicyRuleNotFound 策略规则不存在错误
+	ErrPolicyRuleNotFound = errors.New("policy rule not found")
+
+	// ErrPendingChangeNotFound 待审批变更不存在错误
+	ErrPendingChangeNotFound = errors.New("pending change not found")
+
+	// ErrSelfApproval 审批自己提交的变更错误
+	ErrSelfApproval = errors.New("cannot approve own change")
+
+	// ErrInvalidFreezeWindow 冻结窗口非法错误
+	ErrInvalidFreezeWindow = errors.New("invalid freeze window")
+
+	// ErrFreezeWindowNotFound 冻结窗口不存在错误
+	ErrFreezeWindowNotFound = errors.New("freeze window not found")
+
+	// ErrKillSwitchNotFound worker未开启紧急停机错误
+	ErrKillSwitchNotFound = errors.New("kill switch not engaged")
+
+	// ErrFleetSwitchNotFound 没有进行中的蓝绿切换错误
+	ErrFleetSwitchNotFound = errors.New("fleet switch not found")
+
+	// ErrInvalidFleetSwitch 蓝绿切换非法错误
+	ErrInvalidFleetSwitch = errors.New("invalid fleet switch")
+
+	// ErrCanaryNotFound 任务没有进行中的灰度发布错误
+	ErrCanaryNotFound = errors.New("canary release not found")
+
+	// ErrCommandDenied 命令被策略拒绝错误
+	ErrCommandDenied = errors.New("command denied by policy")
+
+	// ErrCheckpointNotFound 任务没有检查点错误
+	ErrCheckpointNotFound = errors.New("job checkpoint not found")
+
+	// ErrProgressNotFound 任务没有上报进度错误
+	ErrProgressNotFound = errors.New("job progress not found")
+
+	// ErrNamespaceSettingsNotFound 命名空间没有设置错误
+	ErrNamespaceSettingsNotFound = errors.New("namespace settings not found")
+
+	// ErrInvalidNamespaceSettings 命名空间设置非法错误
+	ErrInvalidNamespaceSettings = errors.New("invalid namespace settings")
+
+	// ErrJobRevisionNotFound 任务定义版本不存在错误
+	ErrJobRevisionNotFound = errors.New("job revision not found")
+
+	// ErrRunNotFound 执行不存在错误
+	ErrRunNotFound = errors.New("run not found")
+
+	// ErrRunNotReplayable 执行不能重放错误
+	ErrRunNotReplayable = errors.New("run cannot be replayed")
+
+	// ErrDuplicateRun 重复执行错误，手动触发已被其他worker确认执行
+	ErrDuplicateRun = errors.New("run already started by another delivery")
+
+	// ErrPlacementNotFound 调度决策不存在错误
+	ErrPlacementNotFound = errors.New("placement decisions not found")
+
+	// ErrSemaphoreNotFound 信号量不存在错误
+	ErrSemaphoreNotFound = errors.New("semaphore not found")
+
+	// ErrInvalidSemaphore 信号量非法错误
+	ErrInvalidSemaphore = errors.New("invalid semaphore")
+
+	// ErrUnauthenticated 未认证或凭证无效错误
+	ErrUnauthenticated = errors.New("missing or invalid credentials")
+
+	// ErrAPIKeyNotFound API密钥不存在错误
+	ErrAPIKeyNotFound = errors.New("api key not found")
+
+	// ErrInvalidAPIKey API密钥非法错误
+	ErrInvalidAPIKey = errors.New("invalid api key")
+
+	// ErrRoleBindingNotFound 用户没有分配角色错误
+	ErrRoleBindingNotFound = errors.New("role binding not found")
+
+	// ErrInvalidRoleBinding 角色分配非法错误
+	ErrInvalidRoleBinding = errors.New("invalid role binding")
+
+	// ErrIdempotencyConflict 幂等键已用于不同的请求错误
+	ErrIdempotencyConflict = errors.New("idempotency key was used for a different request")
+
+	// ErrIdempotencyInProgress 使用同一幂等键的请求仍在处理错误
+	ErrIdempotencyInProgress = errors.New("request with this idempotency key is still in progress")
+
+	// ErrArchivedJobNotFound 归档任务不存在错误
+	ErrArchivedJobNotFound = errors.New("archived job not found")
+
+	// ErrJobNotDisabled 只有禁用的任务才能归档错误
+	ErrJobNotDisabled = errors.New("only disabled jobs can be archived")
+
+	// ErrRevisionCompacted 监听的起始版本已被etcd压缩错误
+	ErrRevisionCompacted = errors.New("revision has been compacted")
+
+	// ErrFutureRevision 请求的版本比etcd当前版本新错误
+	ErrFutureRevision = errors.New("revision is newer than the current revision")
+
+	// ErrAdmissionDenied 任务被准入webhook拒绝错误
+	ErrAdmissionDenied = errors.New("job denied by admission webhook")
+
+	// ErrAdmissionUnavailable 准入webhook不可用或响应无效错误
+	ErrAdmissionUnavailable = errors.New("admission webhook unavailable")
 )
 
 // JobError 任务相关自定义错误
@@ -100,3 +214,29 @@ func NewMongoError(operation, collection string, err error) *MongoError {
 		Err:        err,
 	}
 }
+
+// SQLError SQL日志存储操作相关错误
+type SQLError struct {
+	Operation string
+	Table     string
+	Err       error
+}
+
+// Error 实现error接口
+func (e *SQLError) Error() string {
+	return fmt.Sprintf("SQL %s operation error, table=%s: %v", e.Operation, e.Table, e.Err)
+}
+
+// Unwrap 返回原始错误
+func (e *SQLError) Unwrap() error {
+	return e.Err
+}
+
+// NewSQLError 创建SQL错误
+func NewSQLError(operation, table string, err error) *SQLError {
+	return &SQLError{
+		Operation: operation,
+		Table:     table,
+		Err:       err,
+	}
+}
@@ -0,0 +1,119 @@
+package common
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+)
+
+// ExecutorShell 内置的shell执行器，任务未设置type时使用
+const ExecutorShell = "shell"
+
+// 执行器插件配置项的类型
+const (
+	ConfigFieldString = "string" // 字符串
+	ConfigFieldNumber = "number" // 数字
+	ConfigFieldBool   = "bool"   // 布尔值
+	ConfigFieldList   = "list"   // 数组
+	ConfigFieldObject = "object" // 对象
+)
+
+// executorTypePattern 插件类型名，小写字母开头，由小写字母、数字和-组成
+var executorTypePattern = regexp.MustCompile(`^[a-z][a-z0-9-]{0,31}$`)
+
+// ExecutorConfigField 插件声明的一个配置项
+type ExecutorConfigField struct {
+	Type        string `json:"type"`                  // 配置项类型: string/number/bool/list/object
+	Required    bool   `json:"required,omitempty"`    // 是否必填
+	Description string `json:"description,omitempty"` // 配置项说明
+}
+
+// ExecutorManifest 执行器插件的声明，由插件的describe命令输出，随worker心跳上报给master校验任务配置
+type ExecutorManifest struct {
+	Type            string                          `json:"type"`                      // 任务类型，对应任务的type字段
+	Version         string                          `json:"version,omitempty"`         // 插件版本
+	Description     string                          `json:"description,omitempty"`     // 插件说明
+	CommandRequired bool                            `json:"commandRequired,omitempty"` // 任务是否必须设置command，command原样传给插件
+	Config          map[string]*ExecutorConfigField `json:"config,omitempty"`          // 插件接受的配置项，任务的config只能包含这些配置项
+}
+
+// Validate 校验插件声明
+func (m *ExecutorManifest) Validate() error {
+	if !executorTypePattern.MatchString(m.Type) {
+		return fmt.Errorf("invalid executor type %q: must match %s", m.Type, executorTypePattern)
+	}
+	if m.Type == ExecutorShell {
+		return fmt.Errorf("executor type %q is reserved", ExecutorShell)
+	}
+	for name, field := range m.Config {
+		if name == "" || field == nil {
+			return fmt.Errorf("config field name and definition must not be empty")
+		}
+		switch field.Type {
+		case ConfigFieldString, ConfigFieldNumber, ConfigFieldBool, ConfigFieldList, ConfigFieldObject:
+		default:
+			return fmt.Errorf("config field %s has unsupported type %q", name, field.Type)
+		}
+	}
+	return nil
+}
+
+// ValidateJob 按插件声明校验任务的command和config：不允许未声明的配置项，必填项必须设置，类型必须一致
+func (m *ExecutorManifest) ValidateJob(job *Job) error {
+	if m.CommandRequired && job.Command == "" {
+		return fmt.Errorf("executor %s requires a command", m.Type)
+	}
+
+	names := make([]string, 0, len(job.Config))
+	for name := range job.Config {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		field, ok := m.Config[name]
+		if !ok {
+			return fmt.Errorf("executor %s does not accept config %s", m.Type, name)
+		}
+		if !configValueMatches(field.Type, job.Config[name]) {
+			return fmt.Errorf("config %s must be a %s", name, field.Type)
+		}
+	}
+
+	required := make([]string, 0)
+	for name, field := range m.Config {
+		if _, ok := job.Config[name]; field.Required && !ok {
+			required = append(required, name)
+		}
+	}
+	if len(required) > 0 {
+		sort.Strings(required)
+		return fmt.Errorf("executor %s requires config %v", m.Type, required)
+	}
+	return nil
+}
+
+// ExecutorType 任务使用的执行器类型，未设置时为shell
+func (j *Job) ExecutorType() string {
+	if j.Type == "" {
+		return ExecutorShell
+	}
+	return j.Type
+}
+
+// configValueMatches 判断JSON解析后的配置值是否为声明的类型
+func configValueMatches(fieldType string, value any) bool {
+	switch value.(type) {
+	case string:
+		return fieldType == ConfigFieldString
+	case float64:
+		return fieldType == ConfigFieldNumber
+	case bool:
+		return fieldType == ConfigFieldBool
+	case []any:
+		return fieldType == ConfigFieldList
+	case map[string]any:
+		return fieldType == ConfigFieldObject
+	default:
+		return false
+	}
+}
@@ -0,0 +1,54 @@
+package common
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecutorManifestValidate(t *testing.T) {
+	assert.NoError(t, (&ExecutorManifest{Type: "spark-submit", Config: map[string]*ExecutorConfigField{
+		"class": {Type: ConfigFieldString, Required: true},
+	}}).Validate())
+
+	assert.Error(t, (&ExecutorManifest{Type: "Ansible"}).Validate(), "Type names must be lowercase")
+	assert.Error(t, (&ExecutorManifest{Type: ExecutorShell}).Validate(), "shell is reserved for the built-in executor")
+	assert.Error(t, (&ExecutorManifest{Type: "ansible", Config: map[string]*ExecutorConfigField{
+		"playbook": {Type: "path"},
+	}}).Validate())
+}
+
+func TestExecutorManifestValidateJob(t *testing.T) {
+	manifest := &ExecutorManifest{
+		Type: "ansible",
+		Config: map[string]*ExecutorConfigField{
+			"playbook":  {Type: ConfigFieldString, Required: true},
+			"forks":     {Type: ConfigFieldNumber},
+			"check":     {Type: ConfigFieldBool},
+			"tags":      {Type: ConfigFieldList},
+			"extraVars": {Type: ConfigFieldObject},
+		},
+	}
+
+	// 任务从JSON解析，数字为float64
+	parse := func(config string) *Job {
+		job := &Job{Name: "deploy", Type: "ansible"}
+		require.NoError(t, json.Unmarshal([]byte(config), &job.Config))
+		return job
+	}
+
+	assert.NoError(t, manifest.ValidateJob(parse(`{"playbook":"site.yml","forks":5,"check":true,"tags":["web"],"extraVars":{"env":"prod"}}`)))
+	assert.ErrorContains(t, manifest.ValidateJob(parse(`{"forks":5}`)), "playbook")
+	assert.ErrorContains(t, manifest.ValidateJob(parse(`{"playbook":"site.yml","forks":"5"}`)), "forks must be a number")
+	assert.ErrorContains(t, manifest.ValidateJob(parse(`{"playbook":"site.yml","inventory":"hosts"}`)), "does not accept config inventory")
+
+	manifest.CommandRequired = true
+	assert.ErrorContains(t, manifest.ValidateJob(parse(`{"playbook":"site.yml"}`)), "requires a command")
+}
+
+func TestJobExecutorType(t *testing.T) {
+	assert.Equal(t, ExecutorShell, (&Job{}).ExecutorType())
+	assert.Equal(t, "ansible", (&Job{Type: "ansible"}).ExecutorType())
+}
@@ -0,0 +1,49 @@
+package common
+
+import (
+	"fmt"
+	"hash/fnv"
+)
+
+// FleetSwitch worker蓝绿切换，按任务名的哈希把任务分到100个桶，
+// 桶号小于Percent的任务只由To代的worker执行，其余任务只由From代的worker执行；
+// 不属于这两代的worker不受影响。把Percent改回0即可立即回滚
+type FleetSwitch struct {
+	From      string `json:"from"`      // 旧一代worker的代号
+	To        string `json:"to"`        // 新一代worker的代号
+	Percent   int    `json:"percent"`   // 切换到新一代的任务百分比，0-100
+	UpdatedBy string `json:"updatedBy"` // 操作人
+	UpdatedAt int64  `json:"updatedAt"` // 更新时间
+}
+
+// Validate 校验蓝绿切换
+func (f *FleetSwitch) Validate() error {
+	if f.From == "" || f.To == "" {
+		return fmt.Errorf("%w: from and to generations are required", ErrInvalidFleetSwitch)
+	}
+	if f.From == f.To {
+		return fmt.Errorf("%w: from and to generations must differ", ErrInvalidFleetSwitch)
+	}
+	if f.Percent < 0 || f.Percent > 100 {
+		return fmt.Errorf("%w: percent must be between 0 and 100", ErrInvalidFleetSwitch)
+	}
+	return nil
+}
+
+// Eligible 代号为generation的worker是否可以执行任务
+func (f *FleetSwitch) Eligible(generation, jobName string) bool {
+	switch generation {
+	case f.To:
+		return FleetBucket(jobName) < f.Percent
+	case f.From:
+		return FleetBucket(jobName) >= f.Percent
+	}
+	return true
+}
+
+// FleetBucket 任务所在的切换桶，0-99，同一个任务在所有worker上结果一致
+func FleetBucket(jobName string) int {
+	h := fnv.New32a()
+	h.Write([]byte(jobName))
+	return int(h.Sum32() % 100)
+}
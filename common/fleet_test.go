@@ -0,0 +1,40 @@
+package common
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFleetSwitch_Validate(t *testing.T) {
+	assert.NoError(t, (&FleetSwitch{From: "blue", To: "green", Percent: 30}).Validate())
+	assert.ErrorIs(t, (&FleetSwitch{From: "blue", Percent: 30}).Validate(), ErrInvalidFleetSwitch)
+	assert.ErrorIs(t, (&FleetSwitch{From: "blue", To: "blue"}).Validate(), ErrInvalidFleetSwitch)
+	assert.ErrorIs(t, (&FleetSwitch{From: "blue", To: "green", Percent: 101}).Validate(), ErrInvalidFleetSwitch)
+}
+
+func TestFleetSwitch_Eligible(t *testing.T) {
+	fs := &FleetSwitch{From: "blue", To: "green", Percent: 30}
+
+	moved := 0
+	for i := 0; i < 1000; i++ {
+		name := fmt.Sprintf("job-%d", i)
+		blue, green := fs.Eligible("blue", name), fs.Eligible("green", name)
+		assert.NotEqual(t, blue, green, "Each job should belong to exactly one generation")
+		assert.True(t, fs.Eligible("", name), "Workers outside the switch should not be affected")
+		if green {
+			moved++
+		}
+	}
+	assert.InDelta(t, 300, moved, 60, "About percent of the jobs should move to the new generation")
+
+	// 回滚后全部回到旧一代
+	fs.Percent = 0
+	assert.True(t, fs.Eligible("blue", "job-1"))
+	assert.False(t, fs.Eligible("green", "job-1"))
+
+	fs.Percent = 100
+	assert.False(t, fs.Eligible("blue", "job-1"))
+	assert.True(t, fs.Eligible("green", "job-1"))
+}
@@ -0,0 +1,26 @@
+package common
+
+import (
+	"sort"
+	"time"
+)
+
+// GangWait 抢到锁后等待同组其他任务的时间
+func (j *Job) GangWait() time.Duration {
+	if j.GangTimeout > 0 {
+		return time.Duration(j.GangTimeout) * time.Second
+	}
+	return DefaultGangTimeout * time.Second
+}
+
+// GangMembers 任务组中启用的任务名，按名称排序
+func GangMembers(jobs []*Job, gang string) []string {
+	members := make([]string, 0)
+	for _, job := range jobs {
+		if job.Gang == gang && !job.Disabled {
+			members = append(members, job.Name)
+		}
+	}
+	sort.Strings(members)
+	return members
+}
@@ -0,0 +1,24 @@
+package common
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGangMembers(t *testing.T) {
+	jobs := []*Job{
+		{Name: "load", Gang: "etl"},
+		{Name: "extract", Gang: "etl"},
+		{Name: "report", Gang: "etl", Disabled: true},
+		{Name: "backup"},
+	}
+
+	assert.Equal(t, []string{"extract", "load"}, GangMembers(jobs, "etl"), "Disabled jobs should not be waited for")
+	assert.Empty(t, GangMembers(jobs, "other"))
+
+	assert.Equal(t, DefaultGangTimeout*time.Second, jobs[0].GangWait())
+	jobs[0].GangTimeout = 5
+	assert.Equal(t, 5*time.Second, jobs[0].GangWait())
+}
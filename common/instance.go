@@ -0,0 +1,16 @@
+package common
+
+import "strconv"
+
+// InstanceSlotKey 任务第slot个执行实例槽位的key
+func InstanceSlotKey(jobName string, slot int) string {
+	return JobInstanceDir + jobName + "/" + strconv.Itoa(slot)
+}
+
+// FreeInstanceSlot 返回任务序号最小的空闲实例槽位，held为已被占用的槽位key，
+// maxInstances个槽位都被占用时返回空字符串
+func FreeInstanceSlot(jobName string, maxInstances int, held map[string]bool) string {
+	return freeSlot(maxInstances, held, func(slot int) string {
+		return InstanceSlotKey(jobName, slot)
+	})
+}
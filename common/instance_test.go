@@ -0,0 +1,16 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFreeInstanceSlot(t *testing.T) {
+	held := map[string]bool{InstanceSlotKey("report", 0): true}
+	assert.Equal(t, JobInstanceDir+"report/1", FreeInstanceSlot("report", 2, held))
+
+	held[InstanceSlotKey("report", 1)] = true
+	assert.Empty(t, FreeInstanceSlot("report", 2, held), "All instance slots are taken")
+	assert.Equal(t, JobInstanceDir+"report-daily/0", FreeInstanceSlot("report-daily", 1, held))
+}
@@ -0,0 +1,69 @@
+package common
+
+import "time"
+
+// 意图对账发现的丢失原因
+const (
+	IntentWorkerLost = "worker_lost" // 执行期间worker进程退出，没有留下执行结果
+	IntentResultLost = "result_lost" // 执行已结束，但日志没有写入日志存储
+)
+
+// RunIntent 执行意图记录。worker启动命令前写入，执行结束后更新结束状态；
+// 只有意图没有结束状态且worker的锁租约已过期，说明执行结果随worker一起丢失
+type RunIntent struct {
+	RunID       string    `json:"runId"`                 // 执行的唯一标识
+	JobName     string    `json:"jobName"`               // 任务名称
+	WorkerID    string    `json:"workerId"`              // 执行的worker
+	LeaseID     int64     `json:"leaseId"`               // worker锁租约，租约过期说明发起执行的worker进程已退出
+	Command     string    `json:"command"`               // 执行的命令
+	Namespace   string    `json:"namespace,omitempty"`   // 任务所属命名空间
+	Owner       string    `json:"owner,omitempty"`       // 任务负责人
+	JobRevision int64     `json:"jobRevision,omitempty"` // 执行所用任务定义的etcd版本
+	TriggeredBy string    `json:"triggeredBy,omitempty"` // 手动触发人
+	Canary      bool      `json:"canary,omitempty"`      // 是否为灰度执行
+	Experiment  bool      `json:"experiment,omitempty"`  // 是否为实验命令
+	PlanTime    int64     `json:"planTime"`              // 计划执行时间
+	StartTime   int64     `json:"startTime"`             // 启动命令的时间
+	Status      RunStatus `json:"status,omitempty"`      // 结束状态，为空表示尚未结束
+	ExitCode    int       `json:"exitCode,omitempty"`    // 退出码
+	EndTime     int64     `json:"endTime,omitempty"`     // 结束时间
+}
+
+// Finished 执行是否已结束
+func (i *RunIntent) Finished() bool {
+	return i.Status != ""
+}
+
+// LostLog 为结果丢失的执行构建日志。worker退出时记为失败，结束时间为now；
+// 结果没有写入日志存储时保留意图中的结束状态，输出已无法找回
+func (i *RunIntent) LostLog(reason string, now time.Time) *JobLog {
+	jobLog := &JobLog{
+		JobName:      i.JobName,
+		Command:      i.Command,
+		PlanTime:     i.PlanTime,
+		ScheduleTime: i.StartTime,
+		StartTime:    i.StartTime,
+		WorkerIP:     i.WorkerID,
+		Namespace:    NamespaceOf(i.Namespace),
+		Owner:        i.Owner,
+		Canary:       i.Canary,
+		Experiment:   i.Experiment,
+		RunID:        i.RunID,
+		TriggeredBy:  i.TriggeredBy,
+		JobRevision:  i.JobRevision,
+	}
+
+	if reason == IntentResultLost && i.Finished() {
+		jobLog.Status = i.Status
+		jobLog.ExitCode = i.ExitCode
+		jobLog.EndTime = i.EndTime
+		jobLog.Error = "execution finished but its log was lost, output is unavailable"
+		return jobLog
+	}
+
+	jobLog.Status = RunStatusFailed
+	jobLog.ExitCode = -1
+	jobLog.EndTime = now.Unix()
+	jobLog.Error = "worker " + i.WorkerID + " exited before reporting the result"
+	return jobLog
+}
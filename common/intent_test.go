@@ -0,0 +1,35 @@
+package common
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunIntentLostLog(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	intent := &RunIntent{
+		RunID:     "run-1",
+		JobName:   "backup",
+		WorkerID:  "worker-1",
+		PlanTime:  now.Add(-time.Hour).Unix(),
+		StartTime: now.Add(-time.Hour).Unix(),
+	}
+
+	jobLog := intent.LostLog(IntentWorkerLost, now)
+	assert.Equal(t, RunStatusFailed, jobLog.Status)
+	assert.Equal(t, -1, jobLog.ExitCode)
+	assert.Equal(t, now.Unix(), jobLog.EndTime)
+	assert.Equal(t, "worker-1", jobLog.WorkerIP)
+	assert.Equal(t, DefaultNamespace, jobLog.Namespace)
+	assert.Contains(t, jobLog.Error, "worker-1")
+
+	// 日志丢失时保留worker上报的结束状态
+	intent.Status = RunStatusSuccess
+	intent.EndTime = now.Add(-time.Minute).Unix()
+	jobLog = intent.LostLog(IntentResultLost, now)
+	assert.Equal(t, RunStatusSuccess, jobLog.Status)
+	assert.Equal(t, 0, jobLog.ExitCode)
+	assert.Equal(t, intent.EndTime, jobLog.EndTime)
+}
@@ -6,13 +6,117 @@ import (
 
 // Job 任务结构
 type Job struct {
-    Name      string `json:"name"`      // 任务名称
-    Command   string `json:"command"`   // shell命令
-    CronExpr  string `json:"cronExpr"`  // cron表达式
-    Timeout   int    `json:"timeout"`   // 任务超时时间(秒)，0表示不限制
-    Disabled  bool   `json:"disabled"`  // 是否禁用
-    CreatedAt int64  `json:"createdAt"` // 创建时间
-    UpdatedAt int64  `json:"updatedAt"` // 更新时间
+    Name           string       `json:"name"`                     // 任务名称
+    Command        string       `json:"command"`                  // shell命令，插件任务原样传给插件
+    Type           string       `json:"type,omitempty"`           // 执行器类型，为空时为shell，其他类型由worker上的执行器插件执行
+    Config         map[string]any `json:"config,omitempty"`       // 执行器插件的配置，按插件声明的配置项校验
+    CronExpr       string       `json:"cronExpr"`                 // cron表达式
+    Timezone       string       `json:"timezone,omitempty"`       // cron表达式和允许执行时间段所用的IANA时区（如Asia/Shanghai），为空时使用worker本地时区
+    Timeout        int          `json:"timeout"`                  // 任务超时时间(秒)，0表示不限制
+    AllowHighFrequency bool     `json:"allowHighFrequency,omitempty"` // 是否允许触发间隔低于minCronInterval
+    Disabled       bool         `json:"disabled"`                 // 是否禁用
+    DisabledAt     int64        `json:"disabledAt,omitempty"`     // 禁用时间，用于归档长期禁用的任务
+    Namespace      string       `json:"namespace"`                // 命名空间，为空时视为default
+    Owner          string       `json:"owner"`                    // 任务负责人
+    Description    string       `json:"description,omitempty"`    // 任务说明
+    RunbookURL     string       `json:"runbookUrl,omitempty"`     // 运行手册链接，随通知发送给值班人员
+    AllowedWindows []TimeWindow `json:"allowedWindows,omitempty"` // 每日允许执行的时间段，为空表示不限制
+    DeferToWindow  bool         `json:"deferToWindow,omitempty"`  // 窗口外的触发是否推迟到下一个窗口开始时执行
+    PreferredZone  string       `json:"preferredZone,omitempty"`  // 首选可用区，为空表示不限制
+    ZoneFailoverDelay int       `json:"zoneFailoverDelay,omitempty"` // 其他可用区的worker等待多久后接手(秒)，0使用默认值
+    ExperimentCommand string    `json:"experimentCommand,omitempty"` // 实验命令，每次触发在另一个worker上与当前命令并行执行，用于对比结果
+    Annotations    map[string]string `json:"annotations,omitempty"` // 外部工具附加的元数据（工单号、运行手册链接等），原样写入日志
+    Aliases        []string     `json:"aliases,omitempty"`        // 任务改名前使用过的名称，用于关联历史日志
+    Checkpoint     bool         `json:"checkpoint,omitempty"`     // 是否为任务保存检查点，失败后下次执行可以从检查点恢复
+    Preconditions  []Precondition `json:"preconditions,omitempty"` // 执行前检查的外部依赖，全部满足才执行
+    PreconditionRetries    int  `json:"preconditionRetries,omitempty"`    // 前置条件不满足时的重试次数，0表示直接跳过本次执行
+    PreconditionRetryDelay int  `json:"preconditionRetryDelay,omitempty"` // 重试间隔(秒)，0使用默认值
+    When           string       `json:"when,omitempty"`           // 触发时求值的条件表达式，结果为false时跳过本次执行，例如lastRun.failed
+    Schedule       *ScheduleSource `json:"schedule,omitempty"`     // 外部调度来源，设置后下次执行时间由来源决定，cron表达式作为来源不可用时的后备
+    Notify         *NotifyRoute `json:"notify,omitempty"`        // 失败通知路由，未设置的部分继承命名空间的设置
+    QuietFailures  bool         `json:"quietFailures,omitempty"` // 失败在预期内（如探索性任务），失败时不通知、不计入集群失败率，日志照常记录
+    Gang           string       `json:"gang,omitempty"`          // 所属任务组，同组任务的同一次触发全部抢到锁后才一起执行
+    GangTimeout    int          `json:"gangTimeout,omitempty"`   // 等待同组其他任务抢到锁的时间(秒)，0使用默认值
+    LockDuringRun  bool         `json:"lockDuringRun,omitempty"` // 是否在整个执行期间持有任务锁，保证同一时间只有一个worker在执行
+    ExclusionGroups []string    `json:"exclusionGroups,omitempty"` // 所属的互斥组，同组的不同任务在集群内不会同时执行
+    Semaphores     []string     `json:"semaphores,omitempty"`    // 执行期间占用的信号量，每个信号量占用一个槽位
+    MaxInstances   int          `json:"maxInstances,omitempty"`  // 集群内同时执行的最大实例数，0表示不限制
+    CreatedAt      int64        `json:"createdAt"`                // 创建时间
+    UpdatedAt      int64        `json:"updatedAt"`                // 更新时间
+    Revision       int64        `json:"-"`                        // 定义在etcd中的修改版本，worker加载任务时设置，不保存
+}
+
+// CanaryRelease 任务变更的灰度发布，新定义只在指定worker上执行，其余触发沿用当前定义，
+// 观察够执行次数后按成功率自动全量或回滚
+type CanaryRelease struct {
+    Job            *Job    `json:"job"`            // 灰度中的新定义
+    Worker         string  `json:"worker"`         // 执行新定义的worker
+    Runs           int     `json:"runs"`           // 需要观察的执行次数
+    MinSuccessRate float64 `json:"minSuccessRate"` // 全量所需的最低成功率
+    Successes      int     `json:"successes"`      // 已成功次数
+    Failures       int     `json:"failures"`       // 已失败次数
+    StartedBy      string  `json:"startedBy"`      // 发起人
+    StartedAt      int64   `json:"startedAt"`      // 发起时间
+    JobRevision    int64   `json:"jobRevision"`    // 发起时任务定义的修改版本，全量时任务已被修改则放弃
+}
+
+// PendingChange 待审批的任务变更
+type PendingChange struct {
+    JobName     string `json:"jobName"`       // 任务名称
+    Action      string `json:"action"`        // 变更动作: save/delete
+    Job         *Job   `json:"job,omitempty"` // 保存后的任务定义，删除时为空
+    NewName     string `json:"newName,omitempty"` // 改名后的任务名，仅改名时有值
+    RequestedBy string `json:"requestedBy"`   // 提交人
+    RequestedAt int64  `json:"requestedAt"`   // 提交时间
+}
+
+// JobProgress 运行中任务通过进度文件上报的进度
+type JobProgress struct {
+    JobName   string   `json:"jobName"`           // 任务名称
+    RunID     string   `json:"runId"`             // 执行的唯一标识
+    Worker    string   `json:"worker"`            // 执行的worker
+    Percent   *float64 `json:"percent,omitempty"` // 完成百分比，任务只上报消息时为空
+    Message   string   `json:"message"`           // 进度说明
+    StartedAt int64    `json:"startedAt"`         // 执行开始时间
+    UpdatedAt int64    `json:"updatedAt"`         // 最近一次上报时间
+}
+
+// JobCheckpoint 失败执行留下的检查点，下次执行时提供给任务
+type JobCheckpoint struct {
+    JobName string `json:"jobName"` // 任务名称
+    RunID   string `json:"runId"`   // 写入检查点的执行
+    Data    []byte `json:"data"`    // 检查点内容，由任务自行定义格式
+    SavedAt int64  `json:"savedAt"` // 保存时间
+}
+
+// JobRename 任务改名记录，保留在旧任务名下，便于按旧名称查找任务
+type JobRename struct {
+    OldName   string `json:"oldName"`   // 改名前的任务名
+    NewName   string `json:"newName"`   // 改名后的任务名
+    RenamedBy string `json:"renamedBy"` // 操作人
+    RenamedAt int64  `json:"renamedAt"` // 改名时间
+}
+
+// ArchivedJob 归档的任务，不在任务列表中，也不会被调度，恢复后重新成为禁用的任务
+type ArchivedJob struct {
+    Job        *Job   `json:"job"`        // 归档时的任务定义
+    ArchivedBy string `json:"archivedBy"` // 操作人，自动归档时为system
+    ArchivedAt int64  `json:"archivedAt"` // 归档时间
+    Reason     string `json:"reason"`     // 归档原因
+}
+
+// FreezeWindow 变更冻结窗口，窗口内拒绝通过API修改任务定义，任务照常执行
+type FreezeWindow struct {
+    Name      string `json:"name"`      // 窗口名称
+    Start     int64  `json:"start"`     // 开始时间(unix秒)
+    End       int64  `json:"end"`       // 结束时间(unix秒)
+    Reason    string `json:"reason"`    // 冻结原因
+    CreatedBy string `json:"createdBy"` // 创建人
+}
+
+// Contains 判断时间是否处于窗口内
+func (w *FreezeWindow) Contains(ts int64) bool {
+    return ts >= w.Start && ts < w.End
 }
 
 // JobEvent 任务变更事件
@@ -31,17 +135,30 @@ type JobExecuteInfo struct {
     CancelCtx  interface{}        // 任务command的上下文(用于取消任务)
     CancelFunc interface{}        // 用于取消command执行
     Result     *JobExecuteResult  // 任务执行结果
+    Canary     bool               // 是否执行的是灰度中的新定义
+    Experiment bool               // 是否执行的是实验命令
+    RunID      string             // 本次执行的唯一标识，注入到任务环境变量并写入日志
+    Attempt    int                // 第几次尝试，从1开始
+    TriggeredBy string            // 手动触发人，按cron表达式触发时为空
+    ReplayOf   string             // 重放的原执行的runId，不是重放时为空
+    TriggerToken int64            // 抢到手动触发时的fencing token，不是手动触发时为0
 }
 
 // JobExecuteResult 任务执行结果
 type JobExecuteResult struct {
     JobName    string    // 任务名称
+    RunID      string    // 执行的唯一标识，与JobExecuteInfo.RunID一致
     Output     string    // 命令输出
     Error      string    // 错误原因
     StartTime  time.Time // 启动时间
     EndTime    time.Time // 结束时间
     ExitCode   int       // 退出码
     IsTimeout  bool      // 是否超时
+    Status     RunStatus // 执行状态
+    SkipReason string    // 执行被跳过的原因
+    Experiment bool      // 是否为实验命令的执行
+    CPUTime    float64   // 用户态与内核态CPU时间之和(秒)
+    MaxRSS     int64     // 峰值内存(KB)
 }
 
 // JobLog 任务执行日志
@@ -56,7 +173,107 @@ type JobLog struct {
     EndTime      int64     `json:"endTime" bson:"endTime"`           // 任务执行结束时间
     ExitCode     int       `json:"exitCode" bson:"exitCode"`         // 退出码
     IsTimeout    bool      `json:"isTimeout" bson:"isTimeout"`       // 是否超时
+    Status       RunStatus `json:"status" bson:"status"`             // 执行状态
+    CPUTime      float64   `json:"cpuTime" bson:"cpuTime"`           // CPU时间(秒)
+    MaxRSS       int64     `json:"maxRss" bson:"maxRss"`             // 峰值内存(KB)
     WorkerIP     string    `json:"workerIp" bson:"workerIp"`         // 执行机器IP
+    Namespace    string    `json:"namespace" bson:"namespace"`       // 任务所属命名空间
+    Owner        string    `json:"owner" bson:"owner"`               // 任务负责人
+    SkipReason   string    `json:"skipReason,omitempty" bson:"skipReason,omitempty"` // 调度被跳过的原因
+    Canary       bool      `json:"canary,omitempty" bson:"canary,omitempty"`         // 是否为灰度新定义的执行
+    Experiment   bool      `json:"experiment,omitempty" bson:"experiment,omitempty"` // 是否为实验命令的执行，不计入任务统计
+    Annotations  map[string]string `json:"annotations,omitempty" bson:"annotations,omitempty"` // 执行时任务的注解
+    RunID        string    `json:"runId,omitempty" bson:"runId,omitempty"`           // 执行的唯一标识，与任务环境变量CRON_RUN_ID一致
+    QuietFailures bool     `json:"quietFailures,omitempty" bson:"quietFailures,omitempty"` // 执行时任务是否开启了静默失败
+    TriggeredBy  string    `json:"triggeredBy,omitempty" bson:"triggeredBy,omitempty"` // 手动触发人，按cron表达式触发时为空
+    JobRevision  int64     `json:"jobRevision,omitempty" bson:"jobRevision,omitempty"` // 执行所用任务定义的etcd修改版本，可用于按同一定义重新执行
+    ReplayOf     string    `json:"replayOf,omitempty" bson:"replayOf,omitempty"`       // 重放的原执行的runId，用于对比前后两次执行
+    ScheduleDelayMs int64  `json:"scheduleDelayMs,omitempty" bson:"scheduleDelayMs,omitempty"` // 计划时间到实际调度的延迟(毫秒)
+    StartDelayMs    int64  `json:"startDelayMs,omitempty" bson:"startDelayMs,omitempty"`       // 实际调度到开始执行的延迟(毫秒)
+}
+
+// Latencies 获取计划时间到实际调度、实际调度到开始执行的延迟(毫秒)，
+// 旧日志没有记录毫秒延迟时按秒级时间估算
+func (l *JobLog) Latencies() (scheduleDelay, startDelay int64) {
+    if l.ScheduleDelayMs != 0 || l.StartDelayMs != 0 {
+        return l.ScheduleDelayMs, l.StartDelayMs
+    }
+    if l.ScheduleTime > 0 && l.PlanTime > 0 {
+        scheduleDelay = max(l.ScheduleTime-l.PlanTime, 0) * 1000
+    }
+    if l.StartTime > 0 && l.ScheduleTime > 0 {
+        startDelay = max(l.StartTime-l.ScheduleTime, 0) * 1000
+    }
+    return scheduleDelay, startDelay
+}
+
+// LatencyStats 一组延迟的统计(毫秒)
+type LatencyStats struct {
+    Count   int     `json:"count"`   // 样本数
+    AvgMs   float64 `json:"avgMs"`   // 平均延迟
+    P50Ms   int64   `json:"p50Ms"`   // 延迟中位数
+    P95Ms   int64   `json:"p95Ms"`   // 延迟95分位
+    MaxMs   int64   `json:"maxMs"`   // 最大延迟
+    Buckets []int64 `json:"buckets"` // 落在每个延迟桶内的次数，桶的上界与metrics.LatencyBuckets一致，最后一个为溢出桶
+}
+
+// GetStatus 获取日志的执行状态，旧日志没有记录状态时根据退出码推断
+func (l *JobLog) GetStatus() RunStatus {
+    if l.Status != "" {
+        return l.Status
+    }
+    return InferRunStatus(l.ExitCode, l.IsTimeout)
+}
+
+// JobDailyStats 任务按天汇总的执行统计，原始日志过期后仍可用于长期统计
+type JobDailyStats struct {
+    JobName       string  `json:"jobName" bson:"jobName"`             // 任务名称
+    Day           int64   `json:"day" bson:"day"`                     // 统计日零点的时间戳
+    Namespace     string  `json:"namespace" bson:"namespace"`         // 任务所属命名空间
+    Owner         string  `json:"owner" bson:"owner"`                 // 任务负责人
+    TotalCount    int     `json:"totalCount" bson:"totalCount"`       // 执行次数，不含跳过
+    SuccessCount  int     `json:"successCount" bson:"successCount"`   // 成功次数
+    FailCount     int     `json:"failCount" bson:"failCount"`         // 失败次数，含超时和终止
+    TimeoutCount  int     `json:"timeoutCount" bson:"timeoutCount"`   // 超时次数
+    KilledCount   int     `json:"killedCount" bson:"killedCount"`     // 被终止次数
+    SkippedCount  int     `json:"skippedCount" bson:"skippedCount"`   // 跳过次数
+    TotalDuration int64   `json:"totalDuration" bson:"totalDuration"` // 总执行时长(秒)
+    AvgDuration   float64 `json:"avgDuration" bson:"avgDuration"`     // 平均执行时长(秒)
+    P50Duration   int64   `json:"p50Duration" bson:"p50Duration"`     // 执行时长中位数(秒)
+    P95Duration   int64   `json:"p95Duration" bson:"p95Duration"`     // 执行时长95分位(秒)
+    P99Duration   int64   `json:"p99Duration" bson:"p99Duration"`     // 执行时长99分位(秒)
+    MaxDuration   int64   `json:"maxDuration" bson:"maxDuration"`     // 最长执行时长(秒)
+}
+
+// Scope 调用方可读取的数据范围，nil表示不限制
+type Scope struct {
+    Namespaces []string `json:"namespaces"` // 可访问的命名空间
+    Owner      string   `json:"owner"`      // 调用方自己负责的任务不受命名空间限制
+}
+
+// Allows 判断调用方是否可以访问指定命名空间和负责人的数据
+func (s *Scope) Allows(namespace, owner string) bool {
+    if s == nil {
+        return true
+    }
+    if s.Owner != "" && s.Owner == owner {
+        return true
+    }
+    namespace = NamespaceOf(namespace)
+    for _, ns := range s.Namespaces {
+        if ns == namespace {
+            return true
+        }
+    }
+    return false
+}
+
+// NamespaceOf 返回规范化的命名空间，未设置时为默认命名空间
+func NamespaceOf(namespace string) string {
+    if namespace == "" {
+        return DefaultNamespace
+    }
+    return namespace
 }
 
 // WorkerInfo 工作节点信息
@@ -66,13 +283,85 @@ type WorkerInfo struct {
     CPUUsage  float64 `json:"cpuUsage"` // CPU使用率
     MemUsage  float64 `json:"memUsage"` // 内存使用率
     LastSeen  int64   `json:"lastSeen"` // 最后心跳时间
+    Version   string  `json:"version"`   // worker版本号
+    Commit    string  `json:"commit"`    // worker构建的git提交
+    BuildDate string  `json:"buildDate"` // worker构建时间
+    Zone      string  `json:"zone,omitempty"` // worker所在可用区
+    Generation string `json:"generation,omitempty"` // worker所属的代，用于蓝绿切换
+    Executors []*ExecutorManifest `json:"executors,omitempty"` // worker发现的执行器插件
+    Stats     *WorkerRunStats `json:"stats,omitempty"` // worker的执行统计
+}
+
+// WorkerRunStats worker的执行统计，随心跳上报，不依赖日志存储
+type WorkerRunStats struct {
+    Since       int64   `json:"since"`       // 开始统计的时间，统计持久化在etcd中，重启后保留
+    Executed    int64   `json:"executed"`    // 执行次数，不含被跳过的执行
+    Succeeded   int64   `json:"succeeded"`   // 成功次数
+    Failed      int64   `json:"failed"`      // 失败次数（失败、超时、被终止），不含静默失败
+    QuietFailed int64   `json:"quietFailed"` // 开启了静默失败的任务的失败次数
+    Skipped     int64   `json:"skipped"`     // 执行前被跳过的次数（如前置条件不满足）
+    AvgDuration float64 `json:"avgDuration"` // 平均执行时长(秒)
+}
+
+// WorkerSettings master下发给worker的可热更新配置，字段为nil表示不覆盖本地配置
+type WorkerSettings struct {
+    LogBatchSize      *int `json:"logBatchSize,omitempty"`      // 日志批处理大小
+    LogCommitTimeout  *int `json:"logCommitTimeout,omitempty"`  // 日志提交超时(毫秒)
+    LogRetentionDays  *int `json:"logRetentionDays,omitempty"`  // 日志保留天数
+    MaxConcurrentJobs *int `json:"maxConcurrentJobs,omitempty"` // 单个worker最大并发执行任务数，0表示不限制
+}
+
+// KillSwitch worker紧急停机开关，开启后worker拒绝所有新的执行，并在宽限时间后终止运行中的任务
+type KillSwitch struct {
+    Reason      string `json:"reason"`      // 停机原因
+    GracePeriod int    `json:"gracePeriod"` // 终止运行中任务前的宽限时间(秒)
+    CreatedBy   string `json:"createdBy"`   // 操作人
+    CreatedAt   int64  `json:"createdAt"`   // 开启时间
+}
+
+// JobLockInfo 任务锁的持有情况
+type JobLockInfo struct {
+    JobName string `json:"jobName"`           // 任务名称
+    Locked  bool   `json:"locked"`            // 锁是否存在
+    Holder  string `json:"holder,omitempty"`  // 持有锁的workerID
+    LeaseID int64  `json:"leaseId,omitempty"` // 锁绑定的租约ID
+    TTL     int64  `json:"ttl"`               // 租约剩余时间(秒)，不存在时为0
+}
+
+// Merge 用other中设置了的字段覆盖当前配置
+func (s *WorkerSettings) Merge(other *WorkerSettings) {
+    if other == nil {
+        return
+    }
+    if other.LogBatchSize != nil {
+        s.LogBatchSize = other.LogBatchSize
+    }
+    if other.LogCommitTimeout != nil {
+        s.LogCommitTimeout = other.LogCommitTimeout
+    }
+    if other.LogRetentionDays != nil {
+        s.LogRetentionDays = other.LogRetentionDays
+    }
+    if other.MaxConcurrentJobs != nil {
+        s.MaxConcurrentJobs = other.MaxConcurrentJobs
+    }
+}
+
+// BootstrapRecord 集群初始化记录
+type BootstrapRecord struct {
+    Version       string `json:"version"`       // 执行初始化的master版本
+    SchemaVersion int    `json:"schemaVersion"` // 初始化后的日志存储schema版本
+    InitializedBy string `json:"initializedBy"` // 执行初始化的主机
+    InitializedAt int64  `json:"initializedAt"` // 初始化时间
 }
 
 // ApiResponse API响应格式
 type ApiResponse struct {
-    Code    int         `json:"code"`    // 错误码，0-成功，非0-失败
-    Message string      `json:"message"` // 错误信息
-    Data    interface{} `json:"data"`    // 响应数据
+    Code      int         `json:"code"`                // 错误码，0-成功，非0-失败
+    Message   string      `json:"message"`             // 错误信息，按Accept-Language本地化
+    Data      interface{} `json:"data"`                // 响应数据
+    ErrorCode string      `json:"errorCode,omitempty"` // 稳定的机器可读错误码，例如JOB_NOT_FOUND
+    Detail    string      `json:"detail,omitempty"`    // 本地化后保留的原始错误详情
 }
 
 // JobListRequest 获取任务列表请求
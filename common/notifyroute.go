@@ -0,0 +1,79 @@
+package common
+
+import (
+	"fmt"
+	"net/url"
+)
+
+// 失败通知的级别
+const (
+	SeverityCritical = "critical" // 需要立即处理
+	SeverityWarning  = "warning"  // 需要关注
+	SeverityNone     = "none"     // 不通知
+)
+
+// MaxNotifyWebhooks 一条通知路由最多的webhook数
+const MaxNotifyWebhooks = 5
+
+// DefaultNotifySeverities 任务和命名空间都没有设置时各失败状态的通知级别
+var DefaultNotifySeverities = map[RunStatus]string{
+	RunStatusFailed:  SeverityCritical,
+	RunStatusTimeout: SeverityCritical,
+	RunStatusKilled:  SeverityWarning,
+}
+
+// NotifyRoute 任务失败通知的路由。命名空间上设置的路由是其中任务的默认值，
+// 任务上的路由逐项覆盖：设置了webhooks时替换命名空间的地址，severities按状态覆盖
+type NotifyRoute struct {
+	Webhooks   []string             `json:"webhooks,omitempty"`   // 接收通知的webhook地址，为空时继承
+	Severities map[RunStatus]string `json:"severities,omitempty"` // 各失败状态的通知级别，未设置的状态继承
+}
+
+// Validate 校验通知路由
+func (r *NotifyRoute) Validate() error {
+	if len(r.Webhooks) > MaxNotifyWebhooks {
+		return fmt.Errorf("at most %d notify webhooks are allowed", MaxNotifyWebhooks)
+	}
+	for _, webhook := range r.Webhooks {
+		if u, err := url.Parse(webhook); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("notify webhook must be an absolute http(s) URL: %s", webhook)
+		}
+	}
+	for status, severity := range r.Severities {
+		if !status.IsFailure() {
+			return fmt.Errorf("notify severity can only be set for failed, timeout or killed, got %q", status)
+		}
+		switch severity {
+		case SeverityCritical, SeverityWarning, SeverityNone:
+		default:
+			return fmt.Errorf("unsupported notify severity %q for %s", severity, status)
+		}
+	}
+	return nil
+}
+
+// NamespaceSettings 命名空间的设置，作为其中任务的默认值
+type NamespaceSettings struct {
+	Name      string       `json:"name"`             // 命名空间
+	Notify    *NotifyRoute `json:"notify,omitempty"` // 默认的失败通知路由
+	UpdatedBy string       `json:"updatedBy"`        // 最后修改人
+	UpdatedAt int64        `json:"updatedAt"`        // 最后修改时间
+}
+
+// ResolveNotifyRoute 按任务、命名空间、默认值的顺序确定失败状态的通知级别和webhook地址，
+// 任务和命名空间都没有设置webhooks时返回nil，由调用方使用全局的失败通知地址
+func ResolveNotifyRoute(job, namespace *NotifyRoute, status RunStatus) (severity string, webhooks []string) {
+	severity = DefaultNotifySeverities[status]
+	for _, route := range []*NotifyRoute{namespace, job} {
+		if route == nil {
+			continue
+		}
+		if value, ok := route.Severities[status]; ok {
+			severity = value
+		}
+		if len(route.Webhooks) > 0 {
+			webhooks = route.Webhooks
+		}
+	}
+	return severity, webhooks
+}
@@ -0,0 +1,42 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNotifyRouteValidate(t *testing.T) {
+	valid := &NotifyRoute{
+		Webhooks:   []string{"https://hooks.example.com/team"},
+		Severities: map[RunStatus]string{RunStatusKilled: SeverityNone},
+	}
+	assert.NoError(t, valid.Validate())
+
+	assert.Error(t, (&NotifyRoute{Webhooks: []string{"hooks.example.com"}}).Validate())
+	assert.Error(t, (&NotifyRoute{Severities: map[RunStatus]string{RunStatusSuccess: SeverityWarning}}).Validate())
+	assert.Error(t, (&NotifyRoute{Severities: map[RunStatus]string{RunStatusFailed: "page"}}).Validate())
+}
+
+func TestResolveNotifyRoute(t *testing.T) {
+	severity, webhooks := ResolveNotifyRoute(nil, nil, RunStatusKilled)
+	assert.Equal(t, SeverityWarning, severity)
+	assert.Nil(t, webhooks, "Without routes the global webhook should be used")
+
+	namespace := &NotifyRoute{
+		Webhooks:   []string{"https://hooks.example.com/team"},
+		Severities: map[RunStatus]string{RunStatusTimeout: SeverityWarning, RunStatusKilled: SeverityNone},
+	}
+	job := &NotifyRoute{Severities: map[RunStatus]string{RunStatusKilled: SeverityCritical}}
+
+	severity, webhooks = ResolveNotifyRoute(job, namespace, RunStatusTimeout)
+	assert.Equal(t, SeverityWarning, severity, "Job should inherit the namespace severity")
+	assert.Equal(t, namespace.Webhooks, webhooks, "Job without webhooks should inherit the namespace webhooks")
+
+	severity, _ = ResolveNotifyRoute(job, namespace, RunStatusKilled)
+	assert.Equal(t, SeverityCritical, severity, "Job severity should override the namespace")
+
+	job.Webhooks = []string{"https://hooks.example.com/oncall"}
+	_, webhooks = ResolveNotifyRoute(job, namespace, RunStatusFailed)
+	assert.Equal(t, job.Webhooks, webhooks)
+}
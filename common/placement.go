@@ -0,0 +1,97 @@
+package common
+
+import "sort"
+
+// 一个worker对一次触发的决策结果
+const (
+	PlacementWon       = "won"       // 抢到任务锁并执行
+	PlacementLost      = "lost"      // 发起抢锁，但锁已被其他worker持有
+	PlacementError     = "error"     // 发起抢锁时出错
+	PlacementThrottled = "throttled" // 通过检查，但超出抢锁预算没有抢锁
+	PlacementExcluded  = "excluded"  // 没有通过抢锁前的检查
+)
+
+// worker被排除的原因
+const (
+	PlacementReasonZone      = "zone"      // 不在首选可用区，等待故障转移或等待期间被新的触发取代
+	PlacementReasonWindow    = "window"    // 不在允许执行的时间窗口
+	PlacementReasonExecuting = "executing" // 本worker上一次执行尚未结束
+	PlacementReasonDraining  = "draining"  // worker正在关闭
+	PlacementReasonFleet     = "fleet"     // 蓝绿切换中任务属于另一代worker
+	PlacementReasonExecutor  = "executor"  // worker没有任务类型对应的执行器插件
+	PlacementReasonHalted    = "halted"    // 紧急停机开关开启
+	PlacementReasonOverload  = "overload"  // 达到并发上限
+	PlacementReasonGang      = "gang"      // 抢到锁，但任务组没有全部抢到锁，放弃执行
+	PlacementReasonExclusion = "exclusion" // 抢到锁，但同一互斥组的其他任务正在执行
+	PlacementReasonSemaphore = "semaphore" // 抢到锁，但占用的信号量没有空闲槽位
+	PlacementReasonInstances = "instances" // 抢到锁，但集群内同时执行的实例数达到上限
+)
+
+// PlacementDecision 一个worker对一次触发的调度决策，由worker写入etcd
+type PlacementDecision struct {
+	JobName   string `json:"jobName"`          // 任务名称
+	Worker    string `json:"worker"`           // worker ID
+	Zone      string `json:"zone,omitempty"`   // worker所在可用区
+	PlanTime  int64  `json:"planTime"`         // 触发的计划时间
+	Outcome   string `json:"outcome"`          // 决策结果
+	Reason    string `json:"reason,omitempty"` // 被排除的原因，只在结果为excluded时设置
+	Detail    string `json:"detail,omitempty"` // 补充说明
+	DecidedAt int64  `json:"decidedAt"`        // 决策时间
+}
+
+// Attempted 是否发起了抢锁
+func (d *PlacementDecision) Attempted() bool {
+	return d.Outcome == PlacementWon || d.Outcome == PlacementLost || d.Outcome == PlacementError
+}
+
+// JobPlacement 任务最近一次触发由哪个worker执行的说明
+type JobPlacement struct {
+	JobName   string               `json:"jobName"`          // 任务名称
+	PlanTime  int64                `json:"planTime"`         // 最近一次触发的计划时间，没有决策记录时为0
+	Winner    string               `json:"winner,omitempty"` // 抢到锁并执行的worker
+	Eligible  []string             `json:"eligible"`         // 通过抢锁前检查的worker
+	Attempted []string             `json:"attempted"`        // 发起抢锁的worker
+	Excluded  []*PlacementDecision `json:"excluded"`         // 被排除的worker及原因
+	Decisions []*PlacementDecision `json:"decisions"`        // 最近一次触发的全部决策
+}
+
+// ExplainPlacement 从各worker上报的决策中取出计划时间最新的一次触发，汇总参与和被排除的worker
+func ExplainPlacement(jobName string, decisions []*PlacementDecision) *JobPlacement {
+	placement := &JobPlacement{
+		JobName:   jobName,
+		Eligible:  []string{},
+		Attempted: []string{},
+		Excluded:  []*PlacementDecision{},
+		Decisions: []*PlacementDecision{},
+	}
+
+	for _, d := range decisions {
+		placement.PlanTime = max(placement.PlanTime, d.PlanTime)
+	}
+
+	for _, d := range decisions {
+		if d.PlanTime != placement.PlanTime {
+			continue
+		}
+		placement.Decisions = append(placement.Decisions, d)
+	}
+	sort.Slice(placement.Decisions, func(i, j int) bool {
+		return placement.Decisions[i].Worker < placement.Decisions[j].Worker
+	})
+
+	for _, d := range placement.Decisions {
+		if d.Outcome == PlacementExcluded {
+			placement.Excluded = append(placement.Excluded, d)
+			continue
+		}
+		placement.Eligible = append(placement.Eligible, d.Worker)
+		if d.Attempted() {
+			placement.Attempted = append(placement.Attempted, d.Worker)
+		}
+		if d.Outcome == PlacementWon {
+			placement.Winner = d.Worker
+		}
+	}
+
+	return placement
+}
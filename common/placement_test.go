@@ -0,0 +1,31 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExplainPlacement(t *testing.T) {
+	decisions := []*PlacementDecision{
+		{Worker: "w3", PlanTime: 100, Outcome: PlacementLost},
+		{Worker: "w1", PlanTime: 100, Outcome: PlacementWon},
+		{Worker: "w2", PlanTime: 100, Outcome: PlacementExcluded, Reason: PlacementReasonOverload},
+		{Worker: "w4", PlanTime: 100, Outcome: PlacementThrottled},
+		{Worker: "w5", PlanTime: 40, Outcome: PlacementWon},
+	}
+
+	placement := ExplainPlacement("job", decisions)
+	assert.Equal(t, int64(100), placement.PlanTime, "Only the most recent fire should be explained")
+	assert.Equal(t, "w1", placement.Winner)
+	assert.Equal(t, []string{"w1", "w3", "w4"}, placement.Eligible)
+	assert.Equal(t, []string{"w1", "w3"}, placement.Attempted, "Throttled workers should not count as lock attempts")
+	assert.Len(t, placement.Excluded, 1)
+	assert.Equal(t, PlacementReasonOverload, placement.Excluded[0].Reason)
+	assert.Len(t, placement.Decisions, 4)
+
+	placement = ExplainPlacement("job", nil)
+	assert.Zero(t, placement.PlanTime)
+	assert.Empty(t, placement.Winner)
+	assert.NotNil(t, placement.Eligible)
+}
@@ -0,0 +1,32 @@
+package common
+
+import (
+	"fmt"
+	"time"
+)
+
+// PolicyAuditTTL 命令策略拒绝记录的保留时间(秒)
+const PolicyAuditTTL = 7 * 24 * 3600
+
+// 命令被拒绝的环节
+const (
+	PolicyStageSave    = "save"    // master保存任务时拒绝
+	PolicyStageExecute = "execute" // worker执行前拦截
+)
+
+// PolicyRejection 命令被策略拒绝的审计记录，master和worker都会写入
+type PolicyRejection struct {
+	JobName string `json:"jobName"`          // 任务名称
+	Command string `json:"command"`          // 被拒绝的命令
+	Rule    string `json:"rule"`             // 命中的规则
+	Reason  string `json:"reason"`           // 拒绝原因
+	Stage   string `json:"stage"`            // 拒绝的环节，save或execute
+	RunID   string `json:"runId,omitempty"`  // 被拦截的执行，只有execute有
+	Worker  string `json:"worker,omitempty"` // 拦截执行的worker，只有execute有
+	Time    int64  `json:"time"`             // 拒绝时间
+}
+
+// PolicyAuditKey 拒绝记录在etcd中的key，按key排序即按时间排序
+func PolicyAuditKey(rejection *PolicyRejection, at time.Time) string {
+	return fmt.Sprintf("%s%020d-%s", PolicyAuditDir, at.UnixNano(), rejection.JobName)
+}
@@ -0,0 +1,53 @@
+package common
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+)
+
+// 前置条件类型
+const (
+	PreconditionTCP  = "tcp"  // 端口可以连接，target为host:port
+	PreconditionHTTP = "http" // URL返回200，target为http(s)地址
+	PreconditionFile = "file" // 文件存在，target为worker上的路径
+)
+
+// MaxPreconditions 每个任务最多的前置条件数
+const MaxPreconditions = 10
+
+// Precondition 任务执行前检查的外部依赖，不满足时本次执行被跳过或按任务配置重试
+type Precondition struct {
+	Type    string `json:"type"`              // 条件类型: tcp/http/file
+	Target  string `json:"target"`            // 检查目标
+	Timeout int    `json:"timeout,omitempty"` // 单次检查超时(秒)，0使用默认值
+}
+
+// Validate 校验前置条件
+func (p Precondition) Validate() error {
+	switch p.Type {
+	case PreconditionTCP:
+		if _, _, err := net.SplitHostPort(p.Target); err != nil {
+			return fmt.Errorf("tcp target must be host:port: %s", p.Target)
+		}
+	case PreconditionHTTP:
+		if u, err := url.Parse(p.Target); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("http target must be an absolute http(s) URL: %s", p.Target)
+		}
+	case PreconditionFile:
+		if p.Target == "" {
+			return fmt.Errorf("file target must not be empty")
+		}
+	default:
+		return fmt.Errorf("unsupported precondition type: %s", p.Type)
+	}
+	if p.Timeout < 0 {
+		return fmt.Errorf("precondition timeout must not be negative")
+	}
+	return nil
+}
+
+// String 格式化为type target，用于日志和错误信息
+func (p Precondition) String() string {
+	return p.Type + " " + p.Target
+}
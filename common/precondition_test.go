@@ -0,0 +1,19 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPreconditionValidate(t *testing.T) {
+	assert.NoError(t, Precondition{Type: PreconditionTCP, Target: "db:5432"}.Validate())
+	assert.NoError(t, Precondition{Type: PreconditionHTTP, Target: "https://api.example.com/healthz"}.Validate())
+	assert.NoError(t, Precondition{Type: PreconditionFile, Target: "/data/ready"}.Validate())
+
+	assert.Error(t, Precondition{Type: PreconditionTCP, Target: "db"}.Validate(), "TCP target needs a port")
+	assert.Error(t, Precondition{Type: PreconditionHTTP, Target: "api.example.com"}.Validate(), "HTTP target must be absolute")
+	assert.Error(t, Precondition{Type: PreconditionFile}.Validate())
+	assert.Error(t, Precondition{Type: "ping", Target: "db"}.Validate())
+	assert.Error(t, Precondition{Type: PreconditionFile, Target: "/data/ready", Timeout: -1}.Validate())
+}
@@ -0,0 +1,41 @@
+package common
+
+import "fmt"
+
+// roleRanks 角色的权限等级，高等级包含低等级的全部权限
+var roleRanks = map[string]int{
+	RoleViewer:   1,
+	RoleOperator: 2,
+	RoleAdmin:    3,
+}
+
+// RoleBinding 用户的角色分配，保存在etcd中，优先于凭证或请求头中的角色
+type RoleBinding struct {
+	User      string `json:"user"`      // 用户名
+	Role      string `json:"role"`      // 角色: viewer/operator/admin
+	UpdatedBy string `json:"updatedBy"` // 分配人
+	UpdatedAt int64  `json:"updatedAt"` // 分配时间
+}
+
+// Validate 校验角色分配
+func (b *RoleBinding) Validate() error {
+	if b.User == "" {
+		return fmt.Errorf("%w: user is required", ErrInvalidRoleBinding)
+	}
+	if !ValidRole(b.Role) {
+		return fmt.Errorf("%w: role must be %s, %s or %s", ErrInvalidRoleBinding, RoleViewer, RoleOperator, RoleAdmin)
+	}
+	return nil
+}
+
+// ValidRole 是否为已知角色
+func ValidRole(role string) bool {
+	_, ok := roleRanks[role]
+	return ok
+}
+
+// RoleAtLeast 角色的权限是否不低于required，未知角色没有任何权限
+func RoleAtLeast(role, required string) bool {
+	rank, ok := roleRanks[role]
+	return ok && rank >= roleRanks[required]
+}
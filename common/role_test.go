@@ -0,0 +1,18 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRoleAtLeast(t *testing.T) {
+	assert.True(t, RoleAtLeast(RoleAdmin, RoleOperator))
+	assert.True(t, RoleAtLeast(RoleOperator, RoleOperator))
+	assert.False(t, RoleAtLeast(RoleViewer, RoleOperator))
+	assert.False(t, RoleAtLeast("", RoleViewer), "Unknown roles should have no permissions")
+
+	assert.NoError(t, (&RoleBinding{User: "alice", Role: RoleOperator}).Validate())
+	assert.ErrorIs(t, (&RoleBinding{User: "alice", Role: "root"}).Validate(), ErrInvalidRoleBinding)
+	assert.ErrorIs(t, (&RoleBinding{Role: RoleViewer}).Validate(), ErrInvalidRoleBinding)
+}
@@ -0,0 +1,81 @@
+package common
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// 外部调度来源类型
+const (
+	ScheduleSourceHTTP    = "http"    // GET target，响应{"next": unix秒}
+	ScheduleSourceCommand = "command" // 在worker上执行target，标准输出为unix秒或RFC3339时间
+)
+
+// 外部调度来源不可用时的处理方式
+const (
+	ScheduleFallbackCron = "cron" // 按任务的cron表达式触发，默认值
+	ScheduleFallbackNone = "none" // 不触发，等待来源恢复
+)
+
+// 外部调度来源的默认参数
+const (
+	DefaultScheduleRefresh = 60 * time.Second // 重新查询下次执行时间的默认间隔
+	DefaultScheduleTimeout = 5 * time.Second  // 单次查询的默认超时时间
+)
+
+// ScheduleSource 外部调度来源，任务的下次执行时间由外部数据决定（例如ETL完成30分钟后），
+// 来源不可用且没有可用的缓存时按Fallback处理
+type ScheduleSource struct {
+	Type     string `json:"type"`               // 来源类型: http/command
+	Target   string `json:"target"`             // http为URL，command为在worker上执行的命令
+	Refresh  int    `json:"refresh,omitempty"`  // 重新查询的间隔(秒)，0使用默认值
+	Timeout  int    `json:"timeout,omitempty"`  // 单次查询超时(秒)，0使用默认值
+	Fallback string `json:"fallback,omitempty"` // 来源不可用时的处理方式: cron/none，为空时为cron
+}
+
+// Validate 校验外部调度来源
+func (s *ScheduleSource) Validate() error {
+	switch s.Type {
+	case ScheduleSourceHTTP:
+		if u, err := url.Parse(s.Target); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("http target must be an absolute http(s) URL: %s", s.Target)
+		}
+	case ScheduleSourceCommand:
+		if s.Target == "" {
+			return fmt.Errorf("command target must not be empty")
+		}
+	default:
+		return fmt.Errorf("unsupported schedule source type: %s", s.Type)
+	}
+	if s.Refresh < 0 || s.Timeout < 0 {
+		return fmt.Errorf("refresh and timeout must not be negative")
+	}
+	switch s.Fallback {
+	case "", ScheduleFallbackCron, ScheduleFallbackNone:
+	default:
+		return fmt.Errorf("unsupported schedule fallback: %s", s.Fallback)
+	}
+	return nil
+}
+
+// RefreshInterval 重新查询的间隔
+func (s *ScheduleSource) RefreshInterval() time.Duration {
+	if s.Refresh > 0 {
+		return time.Duration(s.Refresh) * time.Second
+	}
+	return DefaultScheduleRefresh
+}
+
+// QueryTimeout 单次查询的超时时间
+func (s *ScheduleSource) QueryTimeout() time.Duration {
+	if s.Timeout > 0 {
+		return time.Duration(s.Timeout) * time.Second
+	}
+	return DefaultScheduleTimeout
+}
+
+// FallbackToCron 来源不可用时是否按cron表达式触发
+func (s *ScheduleSource) FallbackToCron() bool {
+	return s.Fallback != ScheduleFallbackNone
+}
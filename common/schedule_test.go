@@ -0,0 +1,31 @@
+package common
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScheduleSourceValidate(t *testing.T) {
+	assert.NoError(t, (&ScheduleSource{Type: ScheduleSourceHTTP, Target: "http://etl:8080/next"}).Validate())
+	assert.NoError(t, (&ScheduleSource{Type: ScheduleSourceCommand, Target: "/opt/etl/next-run", Fallback: ScheduleFallbackNone}).Validate())
+
+	assert.Error(t, (&ScheduleSource{Type: ScheduleSourceHTTP, Target: "etl:8080/next"}).Validate(), "HTTP target must be absolute")
+	assert.Error(t, (&ScheduleSource{Type: ScheduleSourceCommand}).Validate())
+	assert.Error(t, (&ScheduleSource{Type: "grpc", Target: "etl:9000"}).Validate())
+	assert.Error(t, (&ScheduleSource{Type: ScheduleSourceCommand, Target: "x", Refresh: -1}).Validate())
+	assert.Error(t, (&ScheduleSource{Type: ScheduleSourceCommand, Target: "x", Fallback: "skip"}).Validate())
+}
+
+func TestScheduleSourceDefaults(t *testing.T) {
+	s := &ScheduleSource{Type: ScheduleSourceCommand, Target: "x"}
+	assert.Equal(t, DefaultScheduleRefresh, s.RefreshInterval())
+	assert.Equal(t, DefaultScheduleTimeout, s.QueryTimeout())
+	assert.True(t, s.FallbackToCron())
+
+	s = &ScheduleSource{Refresh: 10, Timeout: 2, Fallback: ScheduleFallbackNone}
+	assert.Equal(t, 10*time.Second, s.RefreshInterval())
+	assert.Equal(t, 2*time.Second, s.QueryTimeout())
+	assert.False(t, s.FallbackToCron())
+}
@@ -0,0 +1,55 @@
+package common
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Semaphore 计数信号量，限制集群内同时占用同一共享资源（如数据库连接）的执行数
+type Semaphore struct {
+	Name        string `json:"name"`                  // 信号量名称
+	Limit       int    `json:"limit"`                 // 槽位数，即最多同时占用的执行数
+	Description string `json:"description,omitempty"` // 说明
+	UpdatedBy   string `json:"updatedBy"`             // 最后修改人
+	UpdatedAt   int64  `json:"updatedAt"`             // 最后修改时间
+}
+
+// SemaphoreUsage 信号量及其当前占用情况
+type SemaphoreUsage struct {
+	*Semaphore
+	InUse   int      `json:"inUse"`   // 已占用的槽位数，调小上限后可能超过上限
+	Holders []string `json:"holders"` // 占用槽位的worker，一个worker可能占用多个槽位
+}
+
+// Validate 校验信号量
+func (s *Semaphore) Validate() error {
+	if s.Name == "" || strings.Contains(s.Name, "/") {
+		return fmt.Errorf("%w: name is required and must not contain '/'", ErrInvalidSemaphore)
+	}
+	if s.Limit < 1 || s.Limit > MaxSemaphoreLimit {
+		return fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidSemaphore, MaxSemaphoreLimit)
+	}
+	return nil
+}
+
+// SlotKey 信号量第slot个槽位的key
+func (s *Semaphore) SlotKey(slot int) string {
+	return SemaphoreSlotDir + s.Name + "/" + strconv.Itoa(slot)
+}
+
+// FreeSlot 返回序号最小的空闲槽位，held为已被占用的槽位key，没有空闲槽位时返回空字符串。
+// 上限调小后，超出上限的槽位在释放前仍被占用，但不会再被分配
+func (s *Semaphore) FreeSlot(held map[string]bool) string {
+	return freeSlot(s.Limit, held, s.SlotKey)
+}
+
+// freeSlot 返回前limit个槽位中序号最小且未被占用的槽位key，都被占用时返回空字符串
+func freeSlot(limit int, held map[string]bool, slotKey func(slot int) string) string {
+	for slot := 0; slot < limit; slot++ {
+		if key := slotKey(slot); !held[key] {
+			return key
+		}
+	}
+	return ""
+}
@@ -0,0 +1,32 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSemaphore_Validate(t *testing.T) {
+	assert.NoError(t, (&Semaphore{Name: "db-connections", Limit: 3}).Validate())
+
+	assert.ErrorIs(t, (&Semaphore{Name: "", Limit: 3}).Validate(), ErrInvalidSemaphore)
+	assert.ErrorIs(t, (&Semaphore{Name: "a/b", Limit: 3}).Validate(), ErrInvalidSemaphore)
+	assert.ErrorIs(t, (&Semaphore{Name: "db", Limit: 0}).Validate(), ErrInvalidSemaphore)
+	assert.ErrorIs(t, (&Semaphore{Name: "db", Limit: MaxSemaphoreLimit + 1}).Validate(), ErrInvalidSemaphore)
+}
+
+func TestSemaphore_FreeSlot(t *testing.T) {
+	sem := &Semaphore{Name: "db", Limit: 3}
+	assert.Equal(t, SemaphoreSlotDir+"db/0", sem.FreeSlot(nil))
+
+	held := map[string]bool{sem.SlotKey(0): true, sem.SlotKey(2): true}
+	assert.Equal(t, SemaphoreSlotDir+"db/1", sem.FreeSlot(held), "The lowest free slot should be chosen")
+
+	held[sem.SlotKey(1)] = true
+	assert.Empty(t, sem.FreeSlot(held), "A full semaphore should have no free slot")
+
+	// 上限调小后超出上限的槽位不再分配
+	sem.Limit = 2
+	held = map[string]bool{sem.SlotKey(2): true}
+	assert.Equal(t, SemaphoreSlotDir+"db/0", sem.FreeSlot(held))
+}
@@ -0,0 +1,57 @@
+package common
+
+// RunStatus 任务执行状态，日志、API响应、事件统一使用
+type RunStatus string
+
+// 任务执行状态
+const (
+	RunStatusPending RunStatus = "pending" // 等待执行
+	RunStatusRunning RunStatus = "running" // 正在执行
+	RunStatusSuccess RunStatus = "success" // 执行成功
+	RunStatusFailed  RunStatus = "failed"  // 执行失败（非0退出码或启动失败）
+	RunStatusTimeout RunStatus = "timeout" // 执行超时
+	RunStatusKilled  RunStatus = "killed"  // 被强制终止
+	RunStatusSkipped RunStatus = "skipped" // 本次调度被跳过
+)
+
+// 调度被跳过的原因，记录在跳过日志的skipReason字段中
+const (
+	SkipReasonExecuting     = "already_executing"   // 上一次执行尚未结束
+	SkipReasonOutsideWindow = "outside_window"      // 不在允许执行的时间窗口内
+	SkipReasonHalted        = "halted"              // worker紧急停机
+	SkipReasonOverload      = "overload"            // 达到最大并发数
+	SkipReasonLockError     = "lock_error"          // 获取任务锁出错（锁被其他worker持有不算跳过）
+	SkipReasonThrottled     = "lock_throttled"      // 超出worker的每秒抢锁预算
+	SkipReasonPrecondition  = "precondition_failed" // 执行前检查的外部依赖不满足
+	SkipReasonGangAborted   = "gang_aborted"        // 任务组没有在等待时间内全部抢到锁
+	SkipReasonExclusion     = "exclusion_busy"      // 同一互斥组的其他任务正在执行
+	SkipReasonSemaphore     = "semaphore_full"      // 占用的信号量没有空闲槽位
+	SkipReasonMaxInstances  = "max_instances"       // 集群内同时执行的实例数达到上限
+	SkipReasonDuplicate     = "duplicate_delivery"  // 手动触发被重新投递，同一次执行已由其他worker启动
+	SkipReasonCondition     = "condition_false"     // when条件为false或求值出错
+)
+
+// IsTerminal 判断是否为终止状态
+func (s RunStatus) IsTerminal() bool {
+	switch s {
+	case RunStatusSuccess, RunStatusFailed, RunStatusTimeout, RunStatusKilled, RunStatusSkipped:
+		return true
+	}
+	return false
+}
+
+// IsFailure 判断是否为失败状态（失败、超时、被终止）
+func (s RunStatus) IsFailure() bool {
+	return s == RunStatusFailed || s == RunStatusTimeout || s == RunStatusKilled
+}
+
+// InferRunStatus 根据退出码和超时标记推断执行状态，用于兼容没有记录状态的旧日志
+func InferRunStatus(exitCode int, isTimeout bool) RunStatus {
+	if isTimeout {
+		return RunStatusTimeout
+	}
+	if exitCode == 0 {
+		return RunStatusSuccess
+	}
+	return RunStatusFailed
+}
@@ -0,0 +1,11 @@
+package common
+
+import "time"
+
+// Location 任务cron表达式和允许执行时间段所用的时区，未设置时区时返回本地时区
+func (j *Job) Location() (*time.Location, error) {
+	if j.Timezone == "" {
+		return time.Local, nil
+	}
+	return time.LoadLocation(j.Timezone)
+}
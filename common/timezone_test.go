@@ -0,0 +1,21 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJob_Location(t *testing.T) {
+	loc, err := (&Job{}).Location()
+	require.NoError(t, err)
+	assert.Equal(t, "Local", loc.String(), "Jobs without a timezone should use the local timezone")
+
+	loc, err = (&Job{Timezone: "Asia/Shanghai"}).Location()
+	require.NoError(t, err)
+	assert.Equal(t, "Asia/Shanghai", loc.String())
+
+	_, err = (&Job{Timezone: "Mars/Olympus"}).Location()
+	assert.Error(t, err)
+}
@@ -0,0 +1,37 @@
+package common
+
+// JobTriggerTTL 手动触发记录的租约时间(秒)，期间没有worker接手时触发自动失效
+const JobTriggerTTL = 60
+
+// TriggerRetryInterval worker重新扫描触发记录的间隔(秒)，抢到触发的worker宕机后，
+// 触发在抢占租约过期后的下一次扫描时重新投递
+const TriggerRetryInterval = 10
+
+// JobTrigger 手动触发一次任务执行，worker抢到触发记录后立即执行，不受cron表达式影响。
+// 触发至少投递一次：worker抢占时在记录上写入自己的锁租约，启动命令前在同一个事务中删除记录并写入执行意图，
+// 抢占后宕机的触发会被重新投递，同一个runId只会启动一次
+type JobTrigger struct {
+	JobName     string `json:"jobName"`              // 任务名称
+	RunID       string `json:"runId"`                // 本次执行的唯一标识，可用于查询执行日志
+	TriggeredBy string `json:"triggeredBy"`          // 触发人
+	TriggeredAt int64  `json:"triggeredAt"`          // 触发时间
+	Revision    int64  `json:"revision,omitempty"`   // 指定执行的任务定义版本，为0时执行当前定义
+	Job         *Job   `json:"job,omitempty"`        // 指定版本的任务定义，本次执行的检查、占用的资源和命令都按该定义
+	ReplayOf    string `json:"replayOf,omitempty"`   // 重放的原执行的runId
+	ClaimedBy   string `json:"claimedBy,omitempty"`  // 抢到触发的worker，尚未被抢占时为空
+	ClaimLease  int64  `json:"claimLease,omitempty"` // 抢到触发的worker的锁租约，租约过期后触发重新投递
+	Attempt     int    `json:"attempt,omitempty"`    // 已投递的次数
+
+	ModRevision   int64 `json:"-"` // 读到的触发记录的修改版本
+	ClaimRevision int64 `json:"-"` // 抢占后触发记录的修改版本，作为fencing token
+}
+
+// TriggerKey 触发记录在etcd中的key，同一个任务可以同时有多个待执行的触发
+func TriggerKey(jobName, runID string) string {
+	return JobTriggerDir + jobName + "/" + runID
+}
+
+// Claimed 触发是否已被worker抢占
+func (t *JobTrigger) Claimed() bool {
+	return t.ClaimedBy != ""
+}
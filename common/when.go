@@ -0,0 +1,33 @@
+package common
+
+// WhenVariables when条件中可以使用的变量及说明，时间按任务时区计算
+var WhenVariables = map[string]string{
+	"now.hour":          "当前小时(0-23)",
+	"now.minute":        "当前分钟(0-59)",
+	"now.weekday":       "星期几(0-6，0为周日)",
+	"now.day":           "当月第几天(1-31)",
+	"now.month":         "月份(1-12)",
+	"now.unix":          "当前Unix时间(秒)",
+	"lastRun.exists":    "是否有上一次执行的记录",
+	"lastRun.status":    "上一次执行的状态，没有记录时为空串",
+	"lastRun.succeeded": "上一次执行是否成功",
+	"lastRun.failed":    "上一次执行是否失败（失败、超时或被终止）",
+	"lastRun.exitCode":  "上一次执行的退出码",
+	"lastRun.duration":  "上一次执行的时长(秒)",
+	"lastRun.age":       "距上一次执行结束的秒数，没有记录时为-1",
+	"worker.id":         "当前worker的ID",
+	"worker.zone":       "当前worker所在可用区",
+	"worker.running":    "当前worker上正在执行命令的任务数，不含本次执行",
+	"worker.load1":      "当前worker最近1分钟的系统负载，无法读取时为0",
+	"worker.cpus":       "当前worker的CPU核数",
+	"manual":            "是否为手动触发",
+}
+
+// LastRun 任务最近一次执行的结果，被跳过的执行不记录
+type LastRun struct {
+	RunID    string    `json:"runId"`    // 执行标识
+	Status   RunStatus `json:"status"`   // 执行状态
+	ExitCode int       `json:"exitCode"` // 退出码
+	Duration float64   `json:"duration"` // 执行时长(秒)
+	EndTime  int64     `json:"endTime"`  // 结束时间
+}
@@ -0,0 +1,105 @@
+package common
+
+import (
+	"fmt"
+	"time"
+)
+
+// minutesPerDay 一天的分钟数
+const minutesPerDay = 24 * 60
+
+// TimeWindow 每日允许执行的时间段，格式为HH:MM，左闭右开；End早于Start表示跨越午夜
+type TimeWindow struct {
+	Start string `json:"start"` // 开始时间，例如00:00
+	End   string `json:"end"`   // 结束时间，例如06:00
+}
+
+// Validate 校验时间段
+func (w TimeWindow) Validate() error {
+	start, err := parseClock(w.Start)
+	if err != nil {
+		return err
+	}
+	end, err := parseClock(w.End)
+	if err != nil {
+		return err
+	}
+	if start == end {
+		return fmt.Errorf("window start and end must differ: %s", w.Start)
+	}
+	return nil
+}
+
+// contains 判断一天中的某一分钟是否处于时间段内
+func (w TimeWindow) contains(minute int) bool {
+	start, err := parseClock(w.Start)
+	if err != nil {
+		return false
+	}
+	end, err := parseClock(w.End)
+	if err != nil {
+		return false
+	}
+
+	if start < end {
+		return minute >= start && minute < end
+	}
+	// 跨越午夜
+	return minute >= start || minute < end
+}
+
+// InWindows 判断时间是否处于任一时间段内，未配置时间段时总是允许
+func InWindows(windows []TimeWindow, t time.Time) bool {
+	if len(windows) == 0 {
+		return true
+	}
+
+	minute := t.Hour()*60 + t.Minute()
+	for _, w := range windows {
+		if w.contains(minute) {
+			return true
+		}
+	}
+	return false
+}
+
+// NextWindowStart 计算t之后最近的时间段开始时间，t已处于时间段内时返回t
+func NextWindowStart(windows []TimeWindow, t time.Time) time.Time {
+	if InWindows(windows, t) {
+		return t
+	}
+
+	dayStart := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	var next time.Time
+	for _, w := range windows {
+		start, err := parseClock(w.Start)
+		if err != nil {
+			continue
+		}
+
+		candidate := dayStart.Add(time.Duration(start) * time.Minute)
+		if !candidate.After(t) {
+			candidate = candidate.AddDate(0, 0, 1)
+		}
+		if next.IsZero() || candidate.Before(next) {
+			next = candidate
+		}
+	}
+
+	if next.IsZero() {
+		return t
+	}
+	return next
+}
+
+// parseClock 将HH:MM解析为一天中的分钟数
+func parseClock(value string) (int, error) {
+	var hour, minute int
+	if _, err := fmt.Sscanf(value, "%d:%d", &hour, &minute); err != nil {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", value)
+	}
+	if hour < 0 || hour > 23 || minute < 0 || minute > 59 {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", value)
+	}
+	return hour*60 + minute, nil
+}
@@ -0,0 +1,47 @@
+package common
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInWindows(t *testing.T) {
+	at := func(hour, minute int) time.Time {
+		return time.Date(2024, 11, 29, hour, minute, 0, 0, time.Local)
+	}
+
+	assert.True(t, InWindows(nil, at(12, 0)), "No windows should allow all times")
+
+	night := []TimeWindow{{Start: "00:00", End: "06:00"}}
+	assert.True(t, InWindows(night, at(0, 0)), "Window start should be inclusive")
+	assert.True(t, InWindows(night, at(5, 59)))
+	assert.False(t, InWindows(night, at(6, 0)), "Window end should be exclusive")
+
+	overnight := []TimeWindow{{Start: "22:00", End: "02:00"}}
+	assert.True(t, InWindows(overnight, at(23, 30)))
+	assert.True(t, InWindows(overnight, at(1, 0)))
+	assert.False(t, InWindows(overnight, at(12, 0)))
+}
+
+func TestNextWindowStart(t *testing.T) {
+	windows := []TimeWindow{{Start: "00:00", End: "06:00"}, {Start: "20:00", End: "21:00"}}
+
+	now := time.Date(2024, 11, 29, 12, 0, 0, 0, time.Local)
+	assert.Equal(t, time.Date(2024, 11, 29, 20, 0, 0, 0, time.Local), NextWindowStart(windows, now))
+
+	now = time.Date(2024, 11, 29, 21, 30, 0, 0, time.Local)
+	assert.Equal(t, time.Date(2024, 11, 30, 0, 0, 0, 0, time.Local), NextWindowStart(windows, now),
+		"Should roll over to the next day")
+
+	now = time.Date(2024, 11, 29, 3, 0, 0, 0, time.Local)
+	assert.Equal(t, now, NextWindowStart(windows, now), "Time inside a window should be returned as is")
+}
+
+func TestTimeWindowValidate(t *testing.T) {
+	assert.NoError(t, TimeWindow{Start: "22:00", End: "02:00"}.Validate())
+	assert.Error(t, TimeWindow{Start: "25:00", End: "02:00"}.Validate())
+	assert.Error(t, TimeWindow{Start: "1am", End: "02:00"}.Validate())
+	assert.Error(t, TimeWindow{Start: "02:00", End: "02:00"}.Validate())
+}
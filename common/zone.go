@@ -0,0 +1,19 @@
+package common
+
+import "time"
+
+// FailoverDelay 首选可用区的worker未接手时，其他可用区接手前的等待时间
+func (j *Job) FailoverDelay() time.Duration {
+	if j.ZoneFailoverDelay > 0 {
+		return time.Duration(j.ZoneFailoverDelay) * time.Second
+	}
+	return DefaultZoneFailoverDelay * time.Second
+}
+
+// ZoneDelay 位于zone的worker抢锁前需要等待的时间，任务未指定首选可用区或worker位于首选可用区时无需等待
+func (j *Job) ZoneDelay(zone string) time.Duration {
+	if j.PreferredZone == "" || j.PreferredZone == zone {
+		return 0
+	}
+	return j.FailoverDelay()
+}
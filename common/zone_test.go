@@ -0,0 +1,21 @@
+package common
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestZoneDelay(t *testing.T) {
+	job := &Job{Name: "job"}
+	assert.Zero(t, job.ZoneDelay("zone-b"), "Job without preferred zone should not be delayed")
+
+	job.PreferredZone = "zone-a"
+	assert.Zero(t, job.ZoneDelay("zone-a"), "Preferred zone should not be delayed")
+	assert.Equal(t, DefaultZoneFailoverDelay*time.Second, job.ZoneDelay("zone-b"))
+	assert.Equal(t, DefaultZoneFailoverDelay*time.Second, job.ZoneDelay(""), "Worker without zone should wait for failover")
+
+	job.ZoneFailoverDelay = 30
+	assert.Equal(t, 30*time.Second, job.ZoneDelay("zone-b"))
+}
@@ -5,26 +5,101 @@ import (
 	"flag"
 	"os"
 	"strconv"
+	"strings"
 )
 
 // Config 系统配置结构体
 type Config struct {
 	// master和worker共用配置
-	EtcdEndpoints   []string `json:"etcdEndpoints"`   // etcd集群地址
-	EtcdDialTimeout int      `json:"etcdDialTimeout"` // etcd连接超时时间(毫秒)
+	EtcdEndpoints     []string `json:"etcdEndpoints"`     // etcd集群地址
+	EtcdDialTimeout   int      `json:"etcdDialTimeout"`   // etcd连接超时时间(毫秒)
+	EtcdSlowThreshold int      `json:"etcdSlowThreshold"` // etcd慢操作日志阈值(毫秒)，0表示不记录
+
+	// 进程日志配置，master和worker共用
+	LogLevel            string            `json:"logLevel"`            // 默认日志级别: debug/info/warn/error
+	LogLevels           map[string]string `json:"logLevels"`           // 按组件覆盖的日志级别，组件为api/scheduler/executor/logsink/etcd
+	LogEncoding         string            `json:"logEncoding"`         // 日志格式: json/console
+	LogSampleInitial    int               `json:"logSampleInitial"`    // 每秒同一条日志完整输出的条数，0表示不采样
+	LogSampleThereafter int               `json:"logSampleThereafter"` // 超出后每隔多少条输出一条
 
 	// worker配置
 	WorkerID          string `json:"workerId"`          // worker唯一标识
+	Zone              string `json:"zone"`              // worker所在可用区，为空表示不属于任何可用区
+	Generation        string `json:"generation"`        // worker所属的代，用于蓝绿切换，为空表示不参与切换
 	HeartbeatInterval int    `json:"heartbeatInterval"` // 心跳间隔(毫秒)
 	LogBatchSize      int    `json:"logBatchSize"`      // 日志批处理大小
 	LogCommitTimeout  int    `json:"logCommitTimeout"`  // 日志提交超时(毫秒)
+	LogCleanSchedule  string `json:"logCleanSchedule"`  // 日志清理的cron表达式(含秒)，为空时每天3点清理
+	WorkerLogCleanup  bool   `json:"workerLogCleanup"`  // worker是否自行清理日志，默认由master统一清理
+	AdminPort         int    `json:"adminPort"`         // worker管理接口端口，0表示不启用
+	MetricsPort       int    `json:"metricsPort"`       // worker导出Prometheus指标的端口，0表示不启用
+	TraceScheduler    bool   `json:"traceScheduler"`    // 是否在启动时开启调度决策追踪
+	TraceContext      bool   `json:"traceContext"`      // 是否向执行的命令注入W3C trace context(TRACEPARENT)
 	ExecutorThreads   int    `json:"executorThreads"`   // 执行器线程数
 	JobLockTTL        int    `json:"jobLockTtl"`        // 任务锁超时时间(秒)
+	SchedulerTick     int    `json:"schedulerTick"`     // 调度循环检查到期任务的间隔(毫秒)
+	LockRateLimit     int    `json:"lockRateLimit"`     // 每秒最多抢锁次数，0表示不限制
+	FailureWebhook    string `json:"failureWebhook"`    // 任务执行失败时通知的webhook地址，为空时不通知
+	NotifyOutputLines int    `json:"notifyOutputLines"` // 失败通知中附带的输出末尾行数，0表示不附带
+	DrainTimeout      int    `json:"drainTimeout"`      // 关闭时等待运行中任务结束的时间(秒)，超时后终止任务
+	PluginDir         string `json:"pluginDir"`         // 执行器插件目录，为空时只执行shell任务
+
+	// worker沙箱配置，SandboxCommand优先于Sandbox，两者都为空时不启用沙箱
+	Sandbox        string   `json:"sandbox"`        // 内置沙箱模板: firejail/bwrap/nsjail
+	SandboxCommand []string `json:"sandboxCommand"` // 自定义沙箱命令模板，任务命令以{{command}}占位
 
 	// master配置
 	ApiPort             int    `json:"apiPort"`             // API服务端口
+	ApiGzip             bool   `json:"apiGzip"`             // 是否对API响应启用gzip压缩
+	ApiHTTP2            bool   `json:"apiHttp2"`            // 是否启用HTTP/2(h2c)
 	MongoURI            string `json:"mongoUri"`            // MongoDB连接URI
 	MongoConnectTimeout int    `json:"mongoConnectTimeout"` // MongoDB连接超时(毫秒)
+	MongoSlowThreshold  int    `json:"mongoSlowThreshold"`  // MongoDB慢查询日志阈值(毫秒)，0表示不记录
+	ApprovalRequired    bool   `json:"approvalRequired"`    // 非管理员的任务变更是否需要审批
+	ApprovalWebhook     string `json:"approvalWebhook"`     // 通知审批人的webhook地址
+	EnforceLogScope     bool   `json:"enforceLogScope"`     // 是否按调用方的命名空间和负责人限制日志读取
+	LogRetentionDays    int    `json:"logRetentionDays"`    // 日志保留天数，由master统一清理
+	ReadOnly            bool   `json:"readOnly"`            // 是否以只读模式启动，拒绝所有修改请求
+	MaxJobTimeout       int    `json:"maxJobTimeout"`       // 任务超时时间上限(秒)，0表示不限制
+	MinCronInterval     int    `json:"minCronInterval"`     // 任务触发间隔下限(秒)，更频繁的任务需要显式设置allowHighFrequency，0表示不限制
+
+	// API认证配置，AuthEnabled为false时信任请求头中的身份
+	AuthEnabled bool   `json:"authEnabled"` // 是否要求/api/v1接口携带API密钥或JWT
+	JWTSecret   string `json:"jwtSecret"`   // JWT的HS256签名密钥，为空时只接受API密钥
+	AdminAPIKey string `json:"adminApiKey"` // 启动用的管理员密钥，用于创建第一批API密钥
+
+	// 基于角色的访问控制配置，RBACEnabled为false时只区分管理员和其他调用方
+	RBACEnabled     bool   `json:"rbacEnabled"`     // 是否按viewer/operator/admin角色限制接口
+	RBACDefaultRole string `json:"rbacDefaultRole"` // 没有分配角色且凭证中没有有效角色的调用方的角色，为空时拒绝访问
+
+	IdempotencyWindow int `json:"idempotencyWindow"` // 携带Idempotency-Key的POST请求的响应缓存时间(秒)，0表示不缓存
+
+	// 准入webhook配置，AdmissionWebhook为空时不启用
+	AdmissionWebhook  string `json:"admissionWebhook"`  // 保存任务前调用的准入webhook地址，可修改或拒绝任务定义
+	AdmissionTimeout  int    `json:"admissionTimeout"`  // 调用准入webhook的超时时间(毫秒)
+	AdmissionFailOpen bool   `json:"admissionFailOpen"` // 准入webhook不可用时是否放行，默认拒绝保存
+
+	// 灾备复制配置，DRStandbyEndpoints为空时不启用
+	DRStandbyEndpoints []string `json:"drStandbyEndpoints"` // 备用etcd集群地址，任务定义会复制到该集群
+	DRSyncInterval     int      `json:"drSyncInterval"`     // 全量对账间隔(秒)
+
+	// 每周摘要配置，DigestWebhook为空时不发送
+	DigestWebhook  string `json:"digestWebhook"`  // 接收每周摘要的webhook地址，每个命名空间发送一条
+	DigestSchedule string `json:"digestSchedule"` // 摘要发送的cron表达式(含秒)，为空时每周一9点发送
+
+	// 自动归档配置，ArchiveDisabledDays为0时不归档
+	ArchiveDisabledDays int    `json:"archiveDisabledDays"` // 任务禁用超过多少天后归档
+	ArchiveNoticeDays   int    `json:"archiveNoticeDays"`   // 归档前提前多少天提醒，0表示不提醒
+	ArchiveWebhook      string `json:"archiveWebhook"`      // 接收归档提醒和归档通知的webhook地址
+	ArchiveSchedule     string `json:"archiveSchedule"`     // 归档检查的cron表达式(含秒)，为空时每天4点检查
+
+	// 成本核算配置
+	CostPerCPUHour float64 `json:"costPerCpuHour"` // 每CPU小时的单价
+	CostPerGBHour  float64 `json:"costPerGbHour"`  // 每GB内存小时的单价
+
+	// 日志存储配置
+	LogBackend string `json:"logBackend"` // 日志存储后端: mongodb/sqlite/postgres
+	LogDSN     string `json:"logDsn"`     // SQL后端的连接串，例如 file:/var/lib/cron/logs.db 或 postgres://...
 }
 
 // 全局配置单例
@@ -36,15 +111,35 @@ func InitConfig(configFile string, parseFlags bool) error {
 	GlobalConfig = &Config{
 		EtcdEndpoints:       []string{"localhost:2379"},
 		EtcdDialTimeout:     5000,
+		EtcdSlowThreshold:   500,
+		LogLevel:            "info",
+		LogEncoding:         "json",
+		LogSampleInitial:    100,
+		LogSampleThereafter: 100,
 		WorkerID:            "",
 		HeartbeatInterval:   5000,
 		LogBatchSize:        100,
 		LogCommitTimeout:    1000,
 		ExecutorThreads:     10,
 		JobLockTTL:          5,
+		SchedulerTick:       100,
+		NotifyOutputLines:   20,
+		DrainTimeout:        30,
 		ApiPort:             8070,
+		ApiGzip:             true,
+		ApiHTTP2:            true,
 		MongoURI:            "mongodb://localhost:27017",
 		MongoConnectTimeout: 5000,
+		MongoSlowThreshold:  500,
+		LogRetentionDays:    30,
+		MaxJobTimeout:       86400,
+		MinCronInterval:     5,
+		IdempotencyWindow:   86400,
+		AdmissionTimeout:    5000,
+		RBACDefaultRole:     "viewer",
+		DRSyncInterval:      60,
+		ArchiveNoticeDays:   3,
+		LogBackend:          "mongodb",
 	}
 
 	// 先从配置文件加载
@@ -92,11 +187,39 @@ func loadFromEnv() {
 			GlobalConfig.EtcdDialTimeout = value
 		}
 	}
+	if threshold := os.Getenv("ETCD_SLOW_THRESHOLD"); threshold != "" {
+		if value, err := strconv.Atoi(threshold); err == nil {
+			GlobalConfig.EtcdSlowThreshold = value
+		}
+	}
+
+	// 进程日志配置
+	if level := os.Getenv("LOG_LEVEL"); level != "" {
+		GlobalConfig.LogLevel = level
+	}
+	if levels := os.Getenv("LOG_LEVELS"); levels != "" {
+		// 格式为 component=level,component=level
+		GlobalConfig.LogLevels = make(map[string]string)
+		for _, item := range strings.Split(levels, ",") {
+			if component, level, ok := strings.Cut(item, "="); ok {
+				GlobalConfig.LogLevels[strings.TrimSpace(component)] = strings.TrimSpace(level)
+			}
+		}
+	}
+	if encoding := os.Getenv("LOG_ENCODING"); encoding != "" {
+		GlobalConfig.LogEncoding = encoding
+	}
 
 	// Worker配置
 	if workerID := os.Getenv("WORKER_ID"); workerID != "" {
 		GlobalConfig.WorkerID = workerID
 	}
+	if zone := os.Getenv("WORKER_ZONE"); zone != "" {
+		GlobalConfig.Zone = zone
+	}
+	if generation := os.Getenv("WORKER_GENERATION"); generation != "" {
+		GlobalConfig.Generation = generation
+	}
 	if interval := os.Getenv("HEARTBEAT_INTERVAL"); interval != "" {
 		if value, err := strconv.Atoi(interval); err == nil {
 			GlobalConfig.HeartbeatInterval = value
@@ -108,15 +231,175 @@ func loadFromEnv() {
 		}
 	}
 
+	if schedule := os.Getenv("LOG_CLEAN_SCHEDULE"); schedule != "" {
+		GlobalConfig.LogCleanSchedule = schedule
+	}
+
+	if cleanup := os.Getenv("WORKER_LOG_CLEANUP"); cleanup != "" {
+		if value, err := strconv.ParseBool(cleanup); err == nil {
+			GlobalConfig.WorkerLogCleanup = value
+		}
+	}
+
+	if limit := os.Getenv("LOCK_RATE_LIMIT"); limit != "" {
+		if value, err := strconv.Atoi(limit); err == nil {
+			GlobalConfig.LockRateLimit = value
+		}
+	}
+	if tick := os.Getenv("SCHEDULER_TICK"); tick != "" {
+		if value, err := strconv.Atoi(tick); err == nil {
+			GlobalConfig.SchedulerTick = value
+		}
+	}
+	if port := os.Getenv("WORKER_ADMIN_PORT"); port != "" {
+		if value, err := strconv.Atoi(port); err == nil {
+			GlobalConfig.AdminPort = value
+		}
+	}
+	if port := os.Getenv("WORKER_METRICS_PORT"); port != "" {
+		if value, err := strconv.Atoi(port); err == nil {
+			GlobalConfig.MetricsPort = value
+		}
+	}
+	if trace := os.Getenv("TRACE_SCHEDULER"); trace != "" {
+		if value, err := strconv.ParseBool(trace); err == nil {
+			GlobalConfig.TraceScheduler = value
+		}
+	}
+
+	if traceContext := os.Getenv("TRACE_CONTEXT"); traceContext != "" {
+		if value, err := strconv.ParseBool(traceContext); err == nil {
+			GlobalConfig.TraceContext = value
+		}
+	}
+
+	if webhook := os.Getenv("FAILURE_WEBHOOK"); webhook != "" {
+		GlobalConfig.FailureWebhook = webhook
+	}
+	if lines := os.Getenv("NOTIFY_OUTPUT_LINES"); lines != "" {
+		if value, err := strconv.Atoi(lines); err == nil {
+			GlobalConfig.NotifyOutputLines = value
+		}
+	}
+
+	if drain := os.Getenv("DRAIN_TIMEOUT"); drain != "" {
+		if value, err := strconv.Atoi(drain); err == nil {
+			GlobalConfig.DrainTimeout = value
+		}
+	}
+
+	if sandbox := os.Getenv("SANDBOX"); sandbox != "" {
+		GlobalConfig.Sandbox = sandbox
+	}
+
+	if pluginDir := os.Getenv("PLUGIN_DIR"); pluginDir != "" {
+		GlobalConfig.PluginDir = pluginDir
+	}
+
 	// Master配置
 	if port := os.Getenv("API_PORT"); port != "" {
 		if value, err := strconv.Atoi(port); err == nil {
 			GlobalConfig.ApiPort = value
 		}
 	}
+	if gzip := os.Getenv("API_GZIP"); gzip != "" {
+		if value, err := strconv.ParseBool(gzip); err == nil {
+			GlobalConfig.ApiGzip = value
+		}
+	}
+	if http2 := os.Getenv("API_HTTP2"); http2 != "" {
+		if value, err := strconv.ParseBool(http2); err == nil {
+			GlobalConfig.ApiHTTP2 = value
+		}
+	}
 	if mongoURI := os.Getenv("MONGO_URI"); mongoURI != "" {
 		GlobalConfig.MongoURI = mongoURI
 	}
+	if threshold := os.Getenv("MONGO_SLOW_THRESHOLD"); threshold != "" {
+		if value, err := strconv.Atoi(threshold); err == nil {
+			GlobalConfig.MongoSlowThreshold = value
+		}
+	}
+	if readOnly := os.Getenv("READ_ONLY"); readOnly != "" {
+		if value, err := strconv.ParseBool(readOnly); err == nil {
+			GlobalConfig.ReadOnly = value
+		}
+	}
+	if enabled := os.Getenv("AUTH_ENABLED"); enabled != "" {
+		if value, err := strconv.ParseBool(enabled); err == nil {
+			GlobalConfig.AuthEnabled = value
+		}
+	}
+	if secret := os.Getenv("JWT_SECRET"); secret != "" {
+		GlobalConfig.JWTSecret = secret
+	}
+	if key := os.Getenv("ADMIN_API_KEY"); key != "" {
+		GlobalConfig.AdminAPIKey = key
+	}
+	if enabled := os.Getenv("RBAC_ENABLED"); enabled != "" {
+		if value, err := strconv.ParseBool(enabled); err == nil {
+			GlobalConfig.RBACEnabled = value
+		}
+	}
+	if role, ok := os.LookupEnv("RBAC_DEFAULT_ROLE"); ok {
+		GlobalConfig.RBACDefaultRole = role
+	}
+	if window := os.Getenv("IDEMPOTENCY_WINDOW"); window != "" {
+		if value, err := strconv.Atoi(window); err == nil {
+			GlobalConfig.IdempotencyWindow = value
+		}
+	}
+	if timeout := os.Getenv("MAX_JOB_TIMEOUT"); timeout != "" {
+		if value, err := strconv.Atoi(timeout); err == nil {
+			GlobalConfig.MaxJobTimeout = value
+		}
+	}
+	if interval := os.Getenv("MIN_CRON_INTERVAL"); interval != "" {
+		if value, err := strconv.Atoi(interval); err == nil {
+			GlobalConfig.MinCronInterval = value
+		}
+	}
+	if webhook := os.Getenv("ADMISSION_WEBHOOK"); webhook != "" {
+		GlobalConfig.AdmissionWebhook = webhook
+	}
+	if standby := os.Getenv("DR_STANDBY_ENDPOINTS"); standby != "" {
+		GlobalConfig.DRStandbyEndpoints = strings.Split(standby, ",")
+	}
+	if webhook := os.Getenv("DIGEST_WEBHOOK"); webhook != "" {
+		GlobalConfig.DigestWebhook = webhook
+	}
+	if schedule := os.Getenv("DIGEST_SCHEDULE"); schedule != "" {
+		GlobalConfig.DigestSchedule = schedule
+	}
+	if days := os.Getenv("ARCHIVE_DISABLED_DAYS"); days != "" {
+		if value, err := strconv.Atoi(days); err == nil {
+			GlobalConfig.ArchiveDisabledDays = value
+		}
+	}
+	if days := os.Getenv("ARCHIVE_NOTICE_DAYS"); days != "" {
+		if value, err := strconv.Atoi(days); err == nil {
+			GlobalConfig.ArchiveNoticeDays = value
+		}
+	}
+	if webhook := os.Getenv("ARCHIVE_WEBHOOK"); webhook != "" {
+		GlobalConfig.ArchiveWebhook = webhook
+	}
+	if schedule := os.Getenv("ARCHIVE_SCHEDULE"); schedule != "" {
+		GlobalConfig.ArchiveSchedule = schedule
+	}
+	if retention := os.Getenv("LOG_RETENTION_DAYS"); retention != "" {
+		if value, err := strconv.Atoi(retention); err == nil {
+			GlobalConfig.LogRetentionDays = value
+		}
+	}
+
+	// 日志存储配置
+	if backend := os.Getenv("LOG_BACKEND"); backend != "" {
+		GlobalConfig.LogBackend = backend
+	}
+	if dsn := os.Getenv("LOG_DSN"); dsn != "" {
+		GlobalConfig.LogDSN = dsn
+	}
 }
 
 // loadFromFlags 从命令行参数加载配置
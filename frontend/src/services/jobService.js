@@ -4,10 +4,11 @@ export default {
     /**
      * Get a list of all jobs
      * @param {string} keyword - Optional search keyword
+     * @param {Object} sort - Optional server-side sort, e.g. { sort: 'failureRate', order: 'desc' }
      * @returns {Promise} - Promise resolving to job list
      */
-    listJobs(keyword = '') {
-        return apiClient.get('/job/list', { params: { keyword } });
+    listJobs(keyword = '', sort = {}) {
+        return apiClient.get('/job/list', { params: { keyword, ...sort } });
     },
 
     /**
@@ -0,0 +1,149 @@
+package admission
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+)
+
+// DefaultTimeout 调用准入webhook的默认超时时间
+const DefaultTimeout = 5 * time.Second
+
+// maxResponseBytes 准入webhook响应的最大字节数
+const maxResponseBytes = 1 << 20
+
+// OperationSave 保存任务的准入操作
+const OperationSave = "save"
+
+// Request 发送给准入webhook的请求
+type Request struct {
+	Operation string      `json:"operation"`        // 操作类型，目前只有save
+	User      string      `json:"user"`             // 提交变更的调用方
+	Job       *common.Job `json:"job"`              // 提交的任务定义
+	OldJob    *common.Job `json:"oldJob,omitempty"` // 当前保存的任务定义，新建任务时为空
+}
+
+// Response 准入webhook的响应
+type Response struct {
+	Allowed  bool        `json:"allowed"`            // 是否允许保存
+	Reason   string      `json:"reason,omitempty"`   // 拒绝原因
+	Job      *common.Job `json:"job,omitempty"`      // 修改后的任务定义，为空时按提交的定义保存
+	Warnings []string    `json:"warnings,omitempty"` // 提示信息，随保存结果返回给调用方
+}
+
+// Result 准入结果
+type Result struct {
+	Job      *common.Job // 最终保存的任务定义
+	Mutated  bool        // webhook是否修改了任务定义
+	Warnings []string    // webhook返回的提示信息
+}
+
+// Webhook 在保存任务前调用外部HTTP服务，由其修改或拒绝任务定义
+type Webhook struct {
+	url      string       // webhook地址
+	client   *http.Client // HTTP客户端
+	failOpen bool         // webhook不可用时是否放行
+	logger   *zap.Logger  // 日志对象
+}
+
+// NewWebhook 创建准入webhook，timeout为0时使用默认超时时间
+func NewWebhook(url string, timeout time.Duration, logger *zap.Logger) *Webhook {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	return &Webhook{
+		url:    url,
+		client: &http.Client{Timeout: timeout},
+		logger: logger,
+	}
+}
+
+// SetFailOpen 设置webhook不可用或响应无效时是否放行，默认拒绝保存
+func (w *Webhook) SetFailOpen(failOpen bool) {
+	w.failOpen = failOpen
+}
+
+// Review 请求webhook审查任务定义。被拒绝时返回ErrAdmissionDenied，
+// webhook不可用或响应无效时返回ErrAdmissionUnavailable，配置了放行时按提交的定义继续
+func (w *Webhook) Review(req *Request) (*Result, error) {
+	resp, err := w.call(req)
+	if err == nil {
+		err = validateResponse(req, resp)
+	}
+	if err != nil {
+		w.logger.Warn("admission webhook unavailable",
+			zap.String("jobName", req.Job.Name),
+			zap.Bool("failOpen", w.failOpen),
+			zap.Error(err))
+		if w.failOpen {
+			return &Result{Job: req.Job}, nil
+		}
+		return nil, fmt.Errorf("%w: %v", common.ErrAdmissionUnavailable, err)
+	}
+
+	if !resp.Allowed {
+		w.logger.Info("job rejected by admission webhook",
+			zap.String("jobName", req.Job.Name),
+			zap.String("user", req.User),
+			zap.String("reason", resp.Reason))
+		return nil, fmt.Errorf("%w: %s", common.ErrAdmissionDenied, resp.Reason)
+	}
+
+	result := &Result{Job: req.Job, Warnings: resp.Warnings}
+	if resp.Job != nil {
+		result.Job = resp.Job
+		result.Mutated = true
+		w.logger.Info("job mutated by admission webhook",
+			zap.String("jobName", req.Job.Name),
+			zap.String("user", req.User))
+	}
+	return result, nil
+}
+
+// call 以JSON POST方式调用webhook，只接受2xx响应
+func (w *Webhook) call(req *Request) (*Response, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal admission request: %v", err)
+	}
+
+	httpResp, err := w.client.Post(w.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	defer httpResp.Body.Close()
+
+	if httpResp.StatusCode < 200 || httpResp.StatusCode >= 300 {
+		return nil, fmt.Errorf("admission webhook returned status %d", httpResp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(httpResp.Body, maxResponseBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxResponseBytes {
+		return nil, errors.New("admission response is too large")
+	}
+
+	resp := &Response{}
+	if err = json.Unmarshal(data, resp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal admission response: %v", err)
+	}
+	return resp, nil
+}
+
+// validateResponse 检查webhook的响应，修改后的任务不能改名，改名需要通过改名接口
+func validateResponse(req *Request, resp *Response) error {
+	if resp.Job != nil && resp.Job.Name != req.Job.Name {
+		return fmt.Errorf("admission webhook must not rename job %s to %s", req.Job.Name, resp.Job.Name)
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package admission
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+)
+
+// newTestWebhook 创建调用handler的准入webhook
+func newTestWebhook(t *testing.T, handler func(req *Request) *Response) *Webhook {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := &Request{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(req))
+		json.NewEncoder(w).Encode(handler(req))
+	}))
+	t.Cleanup(server.Close)
+	return NewWebhook(server.URL, time.Second, zap.NewNop())
+}
+
+func TestWebhook_Review_Allowed(t *testing.T) {
+	var received *Request
+	webhook := newTestWebhook(t, func(req *Request) *Response {
+		received = req
+		return &Response{Allowed: true, Warnings: []string{"no owner"}}
+	})
+
+	job := &common.Job{Name: "backup", Command: "echo hi", CronExpr: "0 * * * * *"}
+	result, err := webhook.Review(&Request{Operation: OperationSave, User: "alice", Job: job})
+	require.NoError(t, err)
+	assert.Same(t, job, result.Job, "Unmutated job should be kept as submitted")
+	assert.False(t, result.Mutated)
+	assert.Equal(t, []string{"no owner"}, result.Warnings)
+	assert.Equal(t, "alice", received.User)
+	assert.Equal(t, "backup", received.Job.Name)
+	assert.Nil(t, received.OldJob)
+}
+
+func TestWebhook_Review_Mutated(t *testing.T) {
+	webhook := newTestWebhook(t, func(req *Request) *Response {
+		mutated := *req.Job
+		mutated.Timeout = 600
+		return &Response{Allowed: true, Job: &mutated}
+	})
+
+	result, err := webhook.Review(&Request{Operation: OperationSave, Job: &common.Job{Name: "backup"}})
+	require.NoError(t, err)
+	assert.True(t, result.Mutated)
+	assert.Equal(t, 600, result.Job.Timeout)
+}
+
+func TestWebhook_Review_Denied(t *testing.T) {
+	webhook := newTestWebhook(t, func(req *Request) *Response {
+		return &Response{Allowed: false, Reason: "jobs must set an owner"}
+	})
+
+	_, err := webhook.Review(&Request{Operation: OperationSave, Job: &common.Job{Name: "backup"}})
+	assert.True(t, errors.Is(err, common.ErrAdmissionDenied))
+	assert.Contains(t, err.Error(), "jobs must set an owner")
+}
+
+func TestWebhook_Review_Rename(t *testing.T) {
+	webhook := newTestWebhook(t, func(req *Request) *Response {
+		return &Response{Allowed: true, Job: &common.Job{Name: "other"}}
+	})
+
+	_, err := webhook.Review(&Request{Operation: OperationSave, Job: &common.Job{Name: "backup"}})
+	assert.True(t, errors.Is(err, common.ErrAdmissionUnavailable), "Renaming should be treated as an invalid response")
+}
+
+func TestWebhook_Review_Unavailable(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	job := &common.Job{Name: "backup"}
+	webhook := NewWebhook(server.URL, time.Second, zap.NewNop())
+	_, err := webhook.Review(&Request{Operation: OperationSave, Job: job})
+	assert.True(t, errors.Is(err, common.ErrAdmissionUnavailable), "Should fail closed by default")
+
+	webhook.SetFailOpen(true)
+	result, err := webhook.Review(&Request{Operation: OperationSave, Job: job})
+	require.NoError(t, err)
+	assert.Same(t, job, result.Job, "Fail-open should keep the submitted job")
+}
@@ -0,0 +1,46 @@
+package api
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+	"github.com/fyerfyer/scheduler-refactor/master/admission"
+)
+
+// admitJob 请求准入webhook审查要保存的任务，允许时用审查后的定义替换job，
+// 拒绝或webhook不可用时返回错误响应并返回false。未配置webhook时直接放行
+func (s *Server) admitJob(c *gin.Context, job *common.Job) bool {
+	if s.admission == nil {
+		return true
+	}
+
+	existing, err := s.jobMgr.GetJob(job.Name)
+	if err != nil && !errors.Is(err, common.ErrJobNotFound) {
+		s.logger.Error("failed to get job",
+			zap.String("jobName", job.Name),
+			zap.Error(err))
+		failure(c, common.ApiEtcdError, "failed to get job: "+err.Error())
+		return false
+	}
+
+	result, err := s.admission.Review(&admission.Request{
+		Operation: admission.OperationSave,
+		User:      currentUser(c),
+		Job:       job,
+		OldJob:    existing,
+	})
+	if err != nil {
+		failure(c, common.ApiAdmission, err.Error())
+		return false
+	}
+
+	*job = *result.Job
+	for _, warning := range result.Warnings {
+		c.Writer.Header().Add("Warning", `299 - `+strconv.Quote(warning))
+	}
+	return true
+}
@@ -1,10 +1,15 @@
 package api
 
 import (
+	"archive/zip"
 	"bytes"
+	"compress/gzip"
 	"encoding/json"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -15,11 +20,18 @@ import (
 
 	"github.com/fyerfyer/scheduler-refactor/common"
 	"github.com/fyerfyer/scheduler-refactor/config"
+	"github.com/fyerfyer/scheduler-refactor/master/approvalmgr"
+	"github.com/fyerfyer/scheduler-refactor/master/eventhub"
+	"github.com/fyerfyer/scheduler-refactor/master/freezemgr"
 	"github.com/fyerfyer/scheduler-refactor/master/jobmgr"
 	"github.com/fyerfyer/scheduler-refactor/master/logmgr"
+	"github.com/fyerfyer/scheduler-refactor/master/policymgr"
+	"github.com/fyerfyer/scheduler-refactor/master/rbacmgr"
 	"github.com/fyerfyer/scheduler-refactor/master/workermgr"
 	"github.com/fyerfyer/scheduler-refactor/pkg/etcd"
 	"github.com/fyerfyer/scheduler-refactor/pkg/mongodb"
+	"github.com/fyerfyer/scheduler-refactor/pkg/notify"
+	"github.com/fyerfyer/scheduler-refactor/pkg/sqlstore"
 )
 
 func setupTest(t *testing.T) (*Server, *etcd.Client, *mongodb.Client, func()) {
@@ -44,12 +56,19 @@ func setupTest(t *testing.T) (*Server, *etcd.Client, *mongodb.Client, func()) {
 	workerManager := workermgr.NewWorkerManager(etcdClient, logger)
 
 	// 创建API服务器
-	apiServer := NewServer(logger, jobManager, logManager, workerManager)
+	policyManager := policymgr.NewPolicyManager(etcdClient, logger)
+	approvalManager := approvalmgr.NewApprovalManager(etcdClient, jobManager, notify.NewNotifier(""), logger)
+	freezeManager := freezemgr.NewFreezeManager(etcdClient, logger)
+
+	apiServer := NewServer(logger, jobManager, logManager, workerManager, policyManager, approvalManager, freezeManager)
 
 	// 返回清理函数
 	cleanup := func() {
 		// 清理测试数据
 		etcdClient.DeleteWithPrefix(common.JobSaveDir)
+		etcdClient.DeleteWithPrefix(common.PolicyDir)
+		etcdClient.DeleteWithPrefix(common.JobPendingDir)
+		etcdClient.DeleteWithPrefix(common.FreezeDir)
 	}
 
 	return apiServer, etcdClient, mongoClient, cleanup
@@ -368,4 +387,728 @@ func TestInvalidRequest(t *testing.T) {
 	require.NoError(t, err, "Failed to unmarshal response")
 
 	assert.Equal(t, common.ApiParamError, response.Code, "Response code should be parameter error")
+	assert.Contains(t, response.Message, `field "invalidField": unknown field`, "Unknown fields should be rejected")
+}
+
+func TestBindStrictJSON(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	bind := func(body string) (*common.Job, []fieldError) {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(http.MethodPost, "/api/v1/job/save", strings.NewReader(body))
+		var job common.Job
+		return &job, bindStrictJSON(c, &job)
+	}
+
+	job, errs := bind(`{"name":"demo","command":"echo hi","cronExpr":"* * * * * *","allowedWindows":[{"start":"00:00","end":"06:00"}]}`)
+	assert.Empty(t, errs)
+	assert.Equal(t, "demo", job.Name)
+
+	// 与encoding/json一致，字段名忽略大小写
+	_, errs = bind(`{"Name":"demo"}`)
+	assert.Empty(t, errs)
+
+	// 所有错误逐个报告，拼写错误给出建议
+	_, errs = bind(`{"name":"demo","cronExp":"* * * * * *","timeout":"30","allowedWindows":[{"start":"00:00","ends":"06:00"}],"bogusSetting":1}`)
+	assert.Equal(t, []fieldError{
+		{Field: "allowedWindows[0].ends", Message: `unknown field, did you mean "end"?`},
+		{Field: "bogusSetting", Message: "unknown field"},
+		{Field: "cronExp", Message: `unknown field, did you mean "cronExpr"?`},
+		{Field: "timeout", Message: "expected integer"},
+	}, errs)
+	assert.Contains(t, joinFieldErrors(errs), `field "cronExp": unknown field, did you mean "cronExpr"?`)
+
+	_, errs = bind(`{"name":`)
+	assert.Equal(t, []fieldError{{Message: "request body is not valid JSON"}}, errs)
+
+	_, errs = bind(`[]`)
+	assert.Equal(t, []fieldError{{Message: "expected object"}}, errs)
+}
+
+func TestMinFireInterval(t *testing.T) {
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	interval := func(expr string) time.Duration {
+		schedule, err := cronParser.Parse(expr)
+		require.NoError(t, err)
+		return minFireInterval(schedule, from)
+	}
+
+	assert.Equal(t, time.Second, interval("* * * * * *"))
+	assert.Equal(t, 5*time.Second, interval("*/5 * * * * *"))
+	assert.Equal(t, time.Hour, interval("0 0 * * * *"))
+	assert.Equal(t, time.Second, interval("0,1 0 3 * * *"), "Bursts inside a daily schedule should be detected")
+}
+
+func TestValidateWhen(t *testing.T) {
+	assert.NoError(t, validateWhen("lastRun.failed"))
+	assert.NoError(t, validateWhen(`now.hour >= 9 && lastRun.status != "success"`))
+	assert.Error(t, validateWhen("lastRun.failed &&"), "Syntax errors should be rejected")
+	assert.Error(t, validateWhen("lastrun.failed"), "Unknown variables should be rejected at save time")
+}
+
+func TestValidateExecutor(t *testing.T) {
+	manifest := &common.ExecutorManifest{Type: "ansible", Config: map[string]*common.ExecutorConfigField{
+		"playbook": {Type: common.ConfigFieldString, Required: true},
+	}}
+	manifests := func(jobType string) map[string]*common.ExecutorManifest {
+		if jobType == "ansible" {
+			return map[string]*common.ExecutorManifest{"w1": manifest}
+		}
+		return nil
+	}
+
+	assert.NoError(t, validateExecutor(&common.Job{Command: "echo"}, manifests))
+	assert.Error(t, validateExecutor(&common.Job{Command: "echo", Config: map[string]any{"playbook": "x"}}, manifests),
+		"Shell jobs should not accept plugin config")
+	assert.NoError(t, validateExecutor(&common.Job{Type: "ansible", Config: map[string]any{"playbook": "site.yml"}}, manifests))
+	assert.ErrorContains(t, validateExecutor(&common.Job{Type: "ansible"}, manifests), "worker w1")
+	assert.ErrorContains(t, validateExecutor(&common.Job{Type: "spark-submit"}, manifests), "no online worker")
+}
+
+func TestParseEventFilter(t *testing.T) {
+	all, err := parseEventFilter("")
+	require.NoError(t, err)
+	assert.True(t, all(eventhub.EventWorkerOffline))
+
+	filter, err := parseEventFilter("run, job.delete")
+	require.NoError(t, err)
+	assert.True(t, filter(eventhub.EventRunStart), "Categories should match every event type in them")
+	assert.True(t, filter(eventhub.EventJobDelete))
+	assert.False(t, filter(eventhub.EventJobSave))
+	assert.False(t, filter(eventhub.EventWorkerOnline))
+
+	_, err = parseEventFilter("job.rename")
+	assert.Error(t, err)
+}
+
+func TestEventVisible(t *testing.T) {
+	scope := &common.Scope{Namespaces: []string{"ops"}}
+	assert.True(t, eventVisible(scope, &eventhub.Event{Type: eventhub.EventRunFinish, Namespace: "ops"}))
+	assert.False(t, eventVisible(scope, &eventhub.Event{Type: eventhub.EventJobSave, Namespace: "billing"}))
+	assert.True(t, eventVisible(scope, &eventhub.Event{Type: eventhub.EventWorkerOffline}), "Worker events should not be scoped")
+	assert.True(t, eventVisible(nil, &eventhub.Event{Type: eventhub.EventJobSave, Namespace: "billing"}))
+}
+
+func TestNextFireTimes(t *testing.T) {
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	schedule, err := cronParser.Parse("0 0 9 * * *")
+	require.NoError(t, err)
+
+	times := nextFireTimes(schedule, from, 3)
+	require.Len(t, times, 3)
+	assert.Equal(t, time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC), times[0])
+	assert.Equal(t, time.Date(2024, 1, 3, 9, 0, 0, 0, time.UTC), times[2])
+
+	schedule, err = cronParser.Parse("0 0 0 30 2 *")
+	require.NoError(t, err)
+	assert.Empty(t, nextFireTimes(schedule, from, 3), "Schedules that never fire should return no times")
+}
+
+func TestDescribeCronTimezone(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	s := &Server{engine: gin.New()}
+	s.engine.GET("/api/v1/cron/describe", s.describeCron)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/cron/describe?expr=0+0+9+*+*+*&count=2&tz=America/New_York", nil)
+	w := httptest.NewRecorder()
+	s.engine.ServeHTTP(w, req)
+
+	var resp common.ApiResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Equal(t, common.ApiSuccess, resp.Code)
+	data := resp.Data.(map[string]interface{})
+	assert.Equal(t, "America/New_York", data["timezone"])
+
+	// 表达式按指定时区解释，每次都在纽约时间9点触发
+	next := data["next"].([]interface{})
+	require.Len(t, next, 2)
+	for _, value := range next {
+		fireTime, err := time.Parse(time.RFC3339, value.(string))
+		require.NoError(t, err)
+		assert.Equal(t, 9, fireTime.Hour(), value)
+	}
+}
+
+func TestValidateJob(t *testing.T) {
+	saved := config.GlobalConfig
+	config.GlobalConfig = &config.Config{MinCronInterval: 60, MaxJobTimeout: 3600}
+	defer func() { config.GlobalConfig = saved }()
+
+	valid := func() *common.Job {
+		return &common.Job{Name: "backup", Command: "echo backup", CronExpr: "0 */5 * * * *"}
+	}
+	require.NoError(t, validateJob(valid()))
+
+	cases := []struct {
+		name   string
+		modify func(job *common.Job)
+		want   string
+	}{
+		{"missing command", func(job *common.Job) { job.Command = "" }, "job command is required"},
+		{"missing cron", func(job *common.Job) { job.CronExpr = "" }, "cron expression is required"},
+		{"unknown timezone", func(job *common.Job) { job.Timezone = "Mars/Olympus" }, "unknown timezone"},
+		{"invalid cron", func(job *common.Job) { job.CronExpr = "not cron" }, "invalid cron expression"},
+		{"too frequent", func(job *common.Job) { job.CronExpr = "*/10 * * * * *" }, "allowHighFrequency"},
+		{"negative timeout", func(job *common.Job) { job.Timeout = -1 }, "must not be negative"},
+		{"timeout above limit", func(job *common.Job) { job.Timeout = 7200 }, "must not exceed 3600 seconds"},
+		{"defer without window", func(job *common.Job) { job.DeferToWindow = true }, "deferToWindow requires allowedWindows"},
+		{"relative runbook", func(job *common.Job) { job.RunbookURL = "/wiki/backup" }, "runbookUrl"},
+		{"empty annotation key", func(job *common.Job) { job.Annotations = map[string]string{"": "x"} }, "annotation key"},
+		{"both instances and run lock", func(job *common.Job) { job.MaxInstances, job.LockDuringRun = 2, true }, "lockDuringRun"},
+		{"slash in exclusion group", func(job *common.Job) { job.ExclusionGroups = []string{"db/main"} }, "exclusion group"},
+		{"slash in gang", func(job *common.Job) { job.Gang = "etl/nightly" }, "job gang"},
+		{"slash in namespace", func(job *common.Job) { job.Namespace = "team/a" }, "job namespace"},
+	}
+	for _, c := range cases {
+		job := valid()
+		c.modify(job)
+		assert.ErrorContains(t, validateJob(job), c.want, c.name)
+	}
+
+	job := valid()
+	job.CronExpr = "*/10 * * * * *"
+	job.AllowHighFrequency = true
+	assert.NoError(t, validateJob(job), "Confirmed high frequency jobs should be accepted")
+}
+
+func TestCheckGang(t *testing.T) {
+	jobs := []*common.Job{
+		{Name: "extract", Gang: "etl", CronExpr: "0 0 2 * * *", Timezone: "Asia/Shanghai"},
+		{Name: "other", Gang: "report", CronExpr: "0 0 3 * * *"},
+	}
+
+	assert.NoError(t, checkGang(&common.Job{Name: "load", Gang: "etl", CronExpr: "0 0 2 * * *", Timezone: "Asia/Shanghai"}, jobs))
+	assert.NoError(t, checkGang(&common.Job{Name: "extract", Gang: "etl", CronExpr: "0 0 4 * * *"}, jobs), "A job should not conflict with its own saved definition")
+	assert.ErrorContains(t, checkGang(&common.Job{Name: "load", Gang: "etl", CronExpr: "0 0 4 * * *", Timezone: "Asia/Shanghai"}, jobs), "cron expression")
+	assert.ErrorContains(t, checkGang(&common.Job{Name: "load", Gang: "etl", CronExpr: "0 0 2 * * *", Timezone: "America/New_York"}, jobs), "timezone")
+	assert.ErrorContains(t, checkGang(&common.Job{Name: "load", Gang: "etl", CronExpr: "0 0 2 * * *"}, jobs), "timezone",
+		"Worker-local time should not match an explicit timezone")
+
+	source := &common.ScheduleSource{Type: common.ScheduleSourceHTTP, Target: "http://calendar/next"}
+	assert.ErrorContains(t, checkGang(&common.Job{Name: "load", Gang: "etl", CronExpr: "0 0 2 * * *", Timezone: "Asia/Shanghai", Schedule: source}, jobs), "schedule source")
+	jobs = append(jobs, &common.Job{Name: "publish", Gang: "etl", CronExpr: "0 0 2 * * *", Timezone: "Asia/Shanghai", Schedule: source})
+	assert.ErrorContains(t, checkGang(&common.Job{Name: "load", Gang: "etl", CronExpr: "0 0 2 * * *", Timezone: "Asia/Shanghai"}, jobs), "publish uses one")
+}
+
+func TestCheckOverlap(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	job := &common.Job{Name: "report", CronExpr: "*/10 * * * * *"}
+
+	warning := checkOverlap(job, 5, 12.5, now)
+	require.NotNil(t, warning, "Runs longer than the interval should always overlap")
+	assert.Equal(t, 10.0, warning.Interval)
+	assert.Equal(t, 12.5, warning.AvgDuration)
+	assert.Contains(t, warning.String(), "every run overlaps")
+
+	assert.Nil(t, checkOverlap(job, 5, 8, now), "Runs shorter than the interval should not warn")
+	assert.Nil(t, checkOverlap(job, minOverlapRuns-1, 60, now), "Too few runs should not warn")
+	assert.Nil(t, checkOverlap(&common.Job{Name: "bad", CronExpr: "bad"}, 5, 60, now))
+}
+
+func TestCheckStale(t *testing.T) {
+	now := time.Date(2024, 1, 31, 12, 0, 0, 0, time.UTC)
+	window := 30 * 24 * time.Hour
+	old := now.AddDate(0, 0, -20).Unix()
+	reasons := func(stale []*staleJob) []string {
+		result := make([]string, 0, len(stale))
+		for _, s := range stale {
+			result = append(result, s.Reason)
+		}
+		return result
+	}
+
+	hourly := &common.Job{Name: "hourly", CronExpr: "0 0 * * * *", UpdatedAt: old}
+	stale := checkStale(hourly, nil, nil, 3, window, now)
+	require.Len(t, stale, 1, "Jobs without runs in the window should be stale")
+	assert.Equal(t, staleNoRuns, stale[0].Reason)
+	assert.Equal(t, 3600.0, stale[0].Interval)
+
+	recent := &logmgr.JobRunSummary{LastRunTime: now.Add(-2 * time.Hour).Unix()}
+	assert.Empty(t, checkStale(hourly, recent, nil, 3, window, now))
+	lagging := &logmgr.JobRunSummary{LastRunTime: now.Add(-4 * time.Hour).Unix()}
+	assert.Equal(t, []string{staleNoRuns}, reasons(checkStale(hourly, lagging, nil, 3, window, now)))
+
+	edited := &common.Job{Name: "edited", CronExpr: "0 0 * * * *", UpdatedAt: now.Add(-time.Hour).Unix()}
+	assert.Empty(t, checkStale(edited, nil, nil, 3, window, now), "Recently changed jobs should not be judged yet")
+
+	monthly := &common.Job{Name: "monthly", CronExpr: "0 0 0 1 * *", UpdatedAt: old}
+	assert.Empty(t, checkStale(monthly, nil, nil, 3, window, now), "Intervals beyond the window cannot be judged")
+
+	never := &common.Job{Name: "never", CronExpr: "0 0 0 30 2 *", PreferredZone: "zone-b", UpdatedAt: old}
+	assert.Equal(t, []string{staleNoZoneWorker, staleNeverFires},
+		reasons(checkStale(never, nil, map[string]bool{"zone-a": true}, 3, window, now)))
+	assert.Equal(t, []string{staleInvalidCron}, reasons(checkStale(&common.Job{Name: "bad", CronExpr: "bad"}, nil, nil, 3, window, now)))
+}
+
+func TestSaveJobInvalidRunbook(t *testing.T) {
+	server, _, _, cleanup := setupTest(t)
+	defer cleanup()
+
+	job := &common.Job{
+		Name:       "test-runbook-job",
+		Command:    "echo hello",
+		CronExpr:   "*/5 * * * * *",
+		RunbookURL: "wiki/runbooks/test",
+	}
+	jsonData, err := json.Marshal(job)
+	require.NoError(t, err, "Failed to marshal job data")
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/job/save", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	server.engine.ServeHTTP(w, req)
+
+	var response common.ApiResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, common.ApiParamError, response.Code, "Relative runbook url should be rejected")
+}
+
+func TestSortJobs(t *testing.T) {
+	jobs := []*common.Job{
+		{Name: "b", UpdatedAt: 300},
+		{Name: "a", UpdatedAt: 100},
+		{Name: "c", UpdatedAt: 200},
+	}
+	summaries := map[string]*logmgr.JobRunSummary{
+		"b": {LastRunTime: 50, FailureRate: 0.5},
+		"c": {LastRunTime: 80, FailureRate: 0.5},
+	}
+
+	names := func() []string {
+		result := make([]string, len(jobs))
+		for i, job := range jobs {
+			result[i] = job.Name
+		}
+		return result
+	}
+
+	sortJobs(jobs, jobSortName, false, nil)
+	assert.Equal(t, []string{"a", "b", "c"}, names())
+
+	sortJobs(jobs, jobSortUpdatedAt, true, nil)
+	assert.Equal(t, []string{"b", "c", "a"}, names())
+
+	sortJobs(jobs, jobSortLastRunTime, true, summaries)
+	assert.Equal(t, []string{"c", "b", "a"}, names(), "Jobs without runs should sort as zero")
+
+	sortJobs(jobs, jobSortFailureRate, true, summaries)
+	assert.Equal(t, []string{"b", "c", "a"}, names(), "Equal failure rates should fall back to name")
+}
+
+func TestProjectFields(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/v1/log/list?fields=jobName,%20exitCode", nil)
+
+	fields, err := parseFields(c, common.JobLog{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"jobName", "exitCode"}, fields)
+
+	projected, err := projectFields([]*common.JobLog{{JobName: "job", Output: "large output", ExitCode: 2}}, fields)
+	require.NoError(t, err)
+	data, err := json.Marshal(projected)
+	require.NoError(t, err)
+	assert.JSONEq(t, `[{"jobName":"job","exitCode":2}]`, string(data), "Only requested fields should be returned")
+
+	c, _ = gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/v1/log/list?fields=password", nil)
+	_, err = parseFields(c, common.JobLog{})
+	assert.Error(t, err, "Unknown fields should be rejected")
+}
+
+func TestGzipMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.Use(gzipMiddleware())
+	engine.GET("/data", func(c *gin.Context) {
+		success(c, "payload")
+	})
+	engine.GET("/empty", func(c *gin.Context) {
+		c.Status(http.StatusNotModified)
+	})
+
+	// 接受gzip时压缩响应
+	req := httptest.NewRequest(http.MethodGet, "/data", nil)
+	req.Header.Set("Accept-Encoding", "gzip, deflate")
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	assert.Equal(t, "gzip", w.Header().Get("Content-Encoding"))
+	reader, err := gzip.NewReader(w.Body)
+	require.NoError(t, err)
+	body, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Contains(t, string(body), "payload")
+
+	// 不接受gzip时原样返回
+	req = httptest.NewRequest(http.MethodGet, "/data", nil)
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	assert.Empty(t, w.Header().Get("Content-Encoding"))
+	assert.Contains(t, w.Body.String(), "payload")
+
+	// 没有响应体时不压缩
+	req = httptest.NewRequest(http.MethodGet, "/empty", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	assert.Empty(t, w.Header().Get("Content-Encoding"))
+	assert.Zero(t, w.Body.Len())
+}
+
+func TestEtagMatches(t *testing.T) {
+	etag := `W/"jobs-7-3"`
+	assert.True(t, etagMatches(`W/"jobs-7-3"`, etag))
+	assert.True(t, etagMatches(`"jobs-7-3"`, etag), "Weak comparison should ignore the W/ prefix")
+	assert.True(t, etagMatches(`W/"jobs-5-2", W/"jobs-7-3"`, etag))
+	assert.True(t, etagMatches("*", etag))
+	assert.False(t, etagMatches(`W/"jobs-7-2"`, etag))
+	assert.False(t, etagMatches("", etag))
+}
+
+func TestPreferredLanguage(t *testing.T) {
+	assert.Equal(t, common.LangEn, preferredLanguage(""))
+	assert.Equal(t, common.LangZh, preferredLanguage("zh-CN,zh;q=0.9,en;q=0.8"))
+	assert.Equal(t, common.LangEn, preferredLanguage("fr-FR,en;q=0.5,zh;q=0.3"))
+	assert.Equal(t, common.LangZh, preferredLanguage("en;q=0.4, zh_TW;q=0.6"))
+	assert.Equal(t, common.LangEn, preferredLanguage("ja, ko"), "Unsupported languages should fall back to English")
+}
+
+func TestLocalizedFailure(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.GET("/fail", func(c *gin.Context) {
+		failure(c, common.ApiJobNotExist, "job not found: demo")
+	})
+
+	// 默认保持原有的英文信息，只增加机器可读错误码
+	req := httptest.NewRequest(http.MethodGet, "/fail", nil)
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	var response common.ApiResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, common.ApiJobNotExist, response.Code)
+	assert.Equal(t, "job not found: demo", response.Message)
+	assert.Equal(t, "JOB_NOT_FOUND", response.ErrorCode)
+	assert.Empty(t, response.Detail)
+
+	// 中文请求翻译提示信息，原始信息保留在detail中
+	req = httptest.NewRequest(http.MethodGet, "/fail", nil)
+	req.Header.Set("Accept-Language", "zh-CN,zh;q=0.9")
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	response = common.ApiResponse{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "任务不存在", response.Message)
+	assert.Equal(t, "job not found: demo", response.Detail)
+	assert.Equal(t, "JOB_NOT_FOUND", response.ErrorCode)
+}
+
+func TestGetVersion(t *testing.T) {
+	server, _, _, cleanup := setupTest(t)
+	defer cleanup()
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/version", nil)
+	w := httptest.NewRecorder()
+	server.engine.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code, "HTTP status code should be 200")
+
+	var response common.ApiResponse
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	require.NoError(t, err, "Failed to unmarshal response")
+
+	assert.Equal(t, common.ApiSuccess, response.Code, "Response code should be success")
+
+	versionData, ok := response.Data.(map[string]interface{})
+	assert.True(t, ok, "Data should be a version map")
+	assert.Contains(t, versionData, "version", "Version info should contain version")
+	assert.Contains(t, versionData, "commit", "Version info should contain commit")
+}
+
+func TestApprovalWorkflow(t *testing.T) {
+	server, _, _, cleanup := setupTest(t)
+	defer cleanup()
+
+	config.GlobalConfig.ApprovalRequired = true
+	defer func() { config.GlobalConfig.ApprovalRequired = false }()
+
+	doRequest := func(method, path, user, role string, body []byte) common.ApiResponse {
+		req := httptest.NewRequest(method, path, bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(headerUser, user)
+		req.Header.Set(headerRole, role)
+		w := httptest.NewRecorder()
+		server.engine.ServeHTTP(w, req)
+
+		var response common.ApiResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response), "Failed to unmarshal response")
+		return response
+	}
+
+	jsonData, err := json.Marshal(common.Job{Name: "approval-job", Command: "echo hi", CronExpr: "*/5 * * * * *"})
+	require.NoError(t, err)
+
+	// 非管理员的变更进入待审批状态
+	response := doRequest(http.MethodPost, "/api/v1/job/save", "alice", "", jsonData)
+	assert.Equal(t, common.ApiPending, response.Code, "Change should be pending approval")
+	_, err = server.jobMgr.GetJob("approval-job")
+	assert.ErrorIs(t, err, common.ErrJobNotFound, "Pending change should not be applied")
+
+	// 非管理员不能审批
+	response = doRequest(http.MethodPost, "/api/v1/approval/approve/approval-job", "alice", "", nil)
+	assert.Equal(t, common.ApiForbidden, response.Code)
+
+	// 管理员审批后生效
+	response = doRequest(http.MethodPost, "/api/v1/approval/approve/approval-job", "bob", common.RoleAdmin, nil)
+	assert.Equal(t, common.ApiSuccess, response.Code)
+
+	savedJob, err := server.jobMgr.GetJob("approval-job")
+	require.NoError(t, err, "Approved change should be applied")
+	assert.Equal(t, "alice", savedJob.Owner, "Requester should own the new job")
+
+	response = doRequest(http.MethodGet, "/api/v1/approval/approval-job", "bob", common.RoleAdmin, nil)
+	assert.Equal(t, common.ApiParamError, response.Code, "Approved change should be removed")
+}
+
+func TestFreezeWindow(t *testing.T) {
+	server, _, _, cleanup := setupTest(t)
+	defer cleanup()
+
+	now := time.Now().Unix()
+	require.NoError(t, server.freezeMgr.SaveWindow(&common.FreezeWindow{
+		Name: "black-friday", Start: now - 60, End: now + 3600, Reason: "peak traffic",
+	}))
+
+	jsonData, err := json.Marshal(common.Job{Name: "frozen-job", Command: "echo hi", CronExpr: "*/5 * * * * *"})
+	require.NoError(t, err)
+
+	saveJob := func(role, override string) common.ApiResponse {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/job/save", bytes.NewBuffer(jsonData))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(headerUser, "bob")
+		req.Header.Set(headerRole, role)
+		req.Header.Set(headerFreezeOverride, override)
+		w := httptest.NewRecorder()
+		server.engine.ServeHTTP(w, req)
+
+		var response common.ApiResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response), "Failed to unmarshal response")
+		return response
+	}
+
+	assert.Equal(t, common.ApiFrozen, saveJob("", "").Code, "Changes should be rejected during freeze")
+	assert.Equal(t, common.ApiFrozen, saveJob("", "hotfix").Code, "Only admins can override a freeze")
+	assert.Equal(t, common.ApiSuccess, saveJob(common.RoleAdmin, "hotfix").Code, "Admin override should apply the change")
+}
+
+func TestReadOnlyMode(t *testing.T) {
+	server, _, _, cleanup := setupTest(t)
+	defer cleanup()
+
+	send := func(method, path string, body interface{}, role string) common.ApiResponse {
+		jsonData, err := json.Marshal(body)
+		require.NoError(t, err)
+
+		req := httptest.NewRequest(method, path, bytes.NewBuffer(jsonData))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(headerUser, "alice")
+		req.Header.Set(headerRole, role)
+		w := httptest.NewRecorder()
+		server.engine.ServeHTTP(w, req)
+
+		var response common.ApiResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response), "Failed to unmarshal response")
+		return response
+	}
+	job := common.Job{Name: "readonly-job", Command: "echo hi", CronExpr: "*/5 * * * * *"}
+
+	// 只有管理员可以开启只读模式
+	assert.Equal(t, common.ApiForbidden, send(http.MethodPost, "/api/v1/admin/readonly", readOnlyRequest{Enabled: true}, "").Code)
+	assert.Equal(t, common.ApiSuccess, send(http.MethodPost, "/api/v1/admin/readonly", readOnlyRequest{Enabled: true}, common.RoleAdmin).Code)
+
+	// 只读模式下修改被拒绝，读取不受影响
+	assert.Equal(t, common.ApiReadOnly, send(http.MethodPost, "/api/v1/job/save", job, common.RoleAdmin).Code)
+	assert.Equal(t, common.ApiReadOnly, send(http.MethodDelete, "/api/v1/job/readonly-job", nil, common.RoleAdmin).Code)
+	assert.Equal(t, common.ApiSuccess, send(http.MethodGet, "/api/v1/job/list", nil, "").Code)
+
+	// 关闭只读模式后恢复修改
+	assert.Equal(t, common.ApiSuccess, send(http.MethodPost, "/api/v1/admin/readonly", readOnlyRequest{Enabled: false}, common.RoleAdmin).Code)
+	assert.Equal(t, common.ApiSuccess, send(http.MethodPost, "/api/v1/job/save", job, common.RoleAdmin).Code)
+}
+
+func TestWriteRunBundle(t *testing.T) {
+	bundle := &runBundle{
+		Manifest: &bundleManifest{RunID: "run-1", JobName: "report", Definition: "revision", Notes: []string{}},
+		Run: &common.JobLog{JobName: "report", RunID: "run-1", Output: "line 1\nline 2\n", Error: "boom",
+			PlanTime: 100, ScheduleTime: 102, StartTime: 103, EndTime: 110, ExitCode: 1, JobRevision: 7},
+		Job: &common.Job{Name: "report", Command: "./report.sh"},
+	}
+
+	buf := &bytes.Buffer{}
+	require.NoError(t, writeRunBundle(buf, bundle))
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	files := make(map[string]string)
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		require.NoError(t, err)
+		data, err := io.ReadAll(rc)
+		require.NoError(t, err)
+		rc.Close()
+		files[f.Name] = string(data)
+	}
+
+	assert.Equal(t, "line 1\nline 2\n", files["output.txt"])
+	assert.Equal(t, "boom", files["error.txt"])
+	assert.Contains(t, files["job.json"], `"command": "./report.sh"`)
+	assert.Equal(t, "null", files["worker.json"], "Missing parts should still be present in the bundle")
+
+	var run common.JobLog
+	require.NoError(t, json.Unmarshal([]byte(files["run.json"]), &run))
+	assert.Empty(t, run.Output, "run.json should not repeat the output")
+	assert.Equal(t, common.RunStatusFailed, run.Status)
+
+	var timings bundleTimings
+	require.NoError(t, json.Unmarshal([]byte(files["timings.json"]), &timings))
+	assert.Equal(t, int64(2), timings.ScheduleDelay)
+	assert.Equal(t, int64(7), timings.Duration)
+}
+
+func TestCacheableResponse(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	var captured *captureWriter
+	engine.Use(func(c *gin.Context) {
+		captured = &captureWriter{ResponseWriter: c.Writer}
+		c.Writer = captured
+		c.Next()
+	})
+	engine.POST("/ok", func(c *gin.Context) { success(c, "created") })
+	engine.POST("/param", func(c *gin.Context) { failure(c, common.ApiParamError, "bad request") })
+	engine.POST("/etcd", func(c *gin.Context) { failure(c, common.ApiEtcdError, "etcd unavailable") })
+	engine.POST("/large", func(c *gin.Context) { success(c, strings.Repeat("x", maxIdempotentResponse)) })
+	// 取决于角色、冻结窗口和审批要求的结果在状态变化后重试时需要重新执行
+	engine.POST("/forbidden", func(c *gin.Context) { failure(c, common.ApiForbidden, "operator role required") })
+	engine.POST("/frozen", func(c *gin.Context) { failure(c, common.ApiFrozen, "change freeze in effect") })
+	engine.POST("/pending", func(c *gin.Context) { pending(c, "submitted") })
+
+	for path, cacheable := range map[string]bool{
+		"/ok": true, "/param": true, "/etcd": false, "/large": false,
+		"/forbidden": false, "/frozen": false, "/pending": false,
+	} {
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest(http.MethodPost, path, nil))
+		assert.Equal(t, cacheable, cacheableResponse(captured), path)
+		if cacheable {
+			assert.Equal(t, w.Body.String(), captured.body.String(), "The captured body should match the response")
+		}
+	}
+}
+
+func TestRBACGuard(t *testing.T) {
+	previous := config.GlobalConfig
+	config.GlobalConfig = &config.Config{RBACDefaultRole: common.RoleViewer}
+	defer func() { config.GlobalConfig = previous }()
+
+	gin.SetMode(gin.TestMode)
+	s := &Server{engine: gin.New(), roleMgr: &rbacmgr.RoleManager{}}
+	s.engine.Use(identityMiddleware())
+	// 模拟认证：携带X-API-Key时以请求头中的角色作为凭证中的角色
+	s.engine.Use(func(c *gin.Context) {
+		if c.GetHeader(headerAPIKey) != "" {
+			c.Set(ctxKeyAuthMethod, "apikey")
+		}
+	})
+	v1 := s.engine.Group("/api/v1", s.rbacGuard())
+	v1.GET("/job/list", func(c *gin.Context) { success(c, nil) })
+	v1.POST("/job/save", func(c *gin.Context) { success(c, nil) })
+	v1.POST("/job/batchGet", func(c *gin.Context) { success(c, nil) })
+	v1.GET("/admin/readonly", s.requireRole(common.RoleAdmin), func(c *gin.Context) { success(c, nil) })
+
+	request := func(method, path, role string) int {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set(headerUser, "alice")
+		req.Header.Set(headerRole, role)
+		req.Header.Set(headerAPIKey, "key")
+		w := httptest.NewRecorder()
+		s.engine.ServeHTTP(w, req)
+
+		var resp common.ApiResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return resp.Code
+	}
+
+	// 没有角色时使用默认角色viewer
+	assert.Equal(t, common.ApiSuccess, request(http.MethodGet, "/api/v1/job/list", ""))
+	assert.Equal(t, common.ApiSuccess, request(http.MethodPost, "/api/v1/job/batchGet", ""))
+	assert.Equal(t, common.ApiForbidden, request(http.MethodPost, "/api/v1/job/save", ""))
+	assert.Equal(t, common.ApiSuccess, request(http.MethodPost, "/api/v1/job/save", common.RoleOperator))
+	assert.Equal(t, common.ApiForbidden, request(http.MethodGet, "/api/v1/admin/readonly", common.RoleOperator))
+	assert.Equal(t, common.ApiSuccess, request(http.MethodGet, "/api/v1/admin/readonly", common.RoleAdmin))
+
+	// 未经认证的请求头中的角色不可信
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/admin/readonly", nil)
+	req.Header.Set(headerRole, common.RoleAdmin)
+	w := httptest.NewRecorder()
+	s.engine.ServeHTTP(w, req)
+	var resp common.ApiResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, common.ApiForbidden, resp.Code, "The X-Role header should be ignored without authentication")
+
+	// 没有默认角色时拒绝未分配角色的调用方
+	config.GlobalConfig.RBACDefaultRole = ""
+	assert.Equal(t, common.ApiForbidden, request(http.MethodGet, "/api/v1/job/list", "unknown"))
+}
+
+func TestAdminOnlySettings(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	s := &Server{engine: gin.New()}
+	s.engine.Use(identityMiddleware())
+	s.engine.POST("/api/v1/policy/save", s.savePolicyRule)
+	s.engine.DELETE("/api/v1/policy/:name", s.deletePolicyRule)
+	s.engine.POST("/api/v1/worker/config/:target", s.saveWorkerSettings)
+	s.engine.DELETE("/api/v1/worker/config/:target", s.deleteWorkerSettings)
+
+	// operator不能修改命令策略和worker配置
+	for _, route := range [][2]string{
+		{http.MethodPost, "/api/v1/policy/save"},
+		{http.MethodDelete, "/api/v1/policy/no-rm-root"},
+		{http.MethodPost, "/api/v1/worker/config/global"},
+		{http.MethodDelete, "/api/v1/worker/config/global"},
+	} {
+		req := httptest.NewRequest(route[0], route[1], bytes.NewBufferString("{}"))
+		req.Header.Set(headerRole, common.RoleOperator)
+		w := httptest.NewRecorder()
+		s.engine.ServeHTTP(w, req)
+
+		var resp common.ApiResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, common.ApiForbidden, resp.Code, route[1])
+	}
+}
+
+func TestRunNotFound(t *testing.T) {
+	store, err := sqlstore.NewClient("sqlite", "file:"+filepath.Join(t.TempDir(), "logs.db"))
+	require.NoError(t, err)
+	defer store.Close()
+
+	saved := config.GlobalConfig
+	config.GlobalConfig = &config.Config{}
+	defer func() { config.GlobalConfig = saved }()
+
+	gin.SetMode(gin.TestMode)
+	s := &Server{engine: gin.New(), logger: zap.NewNop(), logMgr: logmgr.NewLogManager(store, zap.NewNop())}
+	s.engine.Use(identityMiddleware())
+	s.engine.POST("/api/v1/log/:runId/replay", s.replayRun)
+
+	// 执行不存在时返回通用的NOT_FOUND，而不是任务不存在
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/log/missing/replay", nil)
+	w := httptest.NewRecorder()
+	s.engine.ServeHTTP(w, req)
+
+	var resp common.ApiResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, common.ApiNotFound, resp.Code)
+	assert.Equal(t, "NOT_FOUND", resp.ErrorCode)
 }
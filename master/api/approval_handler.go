@@ -0,0 +1,99 @@
+package api
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+	"github.com/fyerfyer/scheduler-refactor/config"
+)
+
+// requiresApproval 当前调用方的任务变更是否需要审批
+func (s *Server) requiresApproval(c *gin.Context) bool {
+	return config.GlobalConfig.ApprovalRequired && !isAdmin(c)
+}
+
+// submitChange 提交待审批变更
+func (s *Server) submitChange(c *gin.Context, change *common.PendingChange) {
+	change.RequestedBy = currentUser(c)
+
+	if err := s.approvalMgr.Submit(change); err != nil {
+		s.logger.Error("failed to submit job change",
+			zap.String("jobName", change.JobName),
+			zap.Error(err))
+		failure(c, common.ApiEtcdError, "failed to submit job change: "+err.Error())
+		return
+	}
+
+	pending(c, change)
+}
+
+// listPendingChanges 获取待审批变更列表
+func (s *Server) listPendingChanges(c *gin.Context) {
+	changes, err := s.approvalMgr.ListPending()
+	if err != nil {
+		failure(c, common.ApiEtcdError, "failed to list pending changes: "+err.Error())
+		return
+	}
+
+	success(c, changes)
+}
+
+// getPendingChange 获取任务的待审批变更
+func (s *Server) getPendingChange(c *gin.Context) {
+	change, err := s.approvalMgr.GetPending(c.Param("name"))
+	if err != nil {
+		s.approvalFailure(c, err)
+		return
+	}
+
+	success(c, change)
+}
+
+// approveChange 通过待审批变更
+func (s *Server) approveChange(c *gin.Context) {
+	if !isAdmin(c) {
+		failure(c, common.ApiForbidden, "only admins can approve job changes")
+		return
+	}
+
+	change, err := s.approvalMgr.Approve(c.Param("name"), currentUser(c))
+	if err != nil {
+		s.approvalFailure(c, err)
+		return
+	}
+
+	success(c, change)
+}
+
+// rejectChange 驳回待审批变更
+func (s *Server) rejectChange(c *gin.Context) {
+	if !isAdmin(c) {
+		failure(c, common.ApiForbidden, "only admins can reject job changes")
+		return
+	}
+
+	change, err := s.approvalMgr.Reject(c.Param("name"), currentUser(c))
+	if err != nil {
+		s.approvalFailure(c, err)
+		return
+	}
+
+	success(c, change)
+}
+
+// approvalFailure 返回审批相关错误
+func (s *Server) approvalFailure(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, common.ErrPendingChangeNotFound):
+		failure(c, common.ApiParamError, "pending change does not exist")
+	case errors.Is(err, common.ErrSelfApproval):
+		failure(c, common.ApiForbidden, "changes must be approved by another named admin")
+	case errors.Is(err, common.ErrJobNotFound):
+		failure(c, common.ApiJobNotExist, "job does not exist")
+	default:
+		s.logger.Error("failed to process pending change", zap.Error(err))
+		failure(c, common.ApiEtcdError, "failed to process pending change: "+err.Error())
+	}
+}
@@ -0,0 +1,71 @@
+package api
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+)
+
+// listArchivedJobs 获取归档的任务
+func (s *Server) listArchivedJobs(c *gin.Context) {
+	list, err := s.jobMgr.ListArchivedJobs()
+	if err != nil {
+		failure(c, common.ApiEtcdError, "failed to list archived jobs: "+err.Error())
+		return
+	}
+
+	success(c, list)
+}
+
+// archiveJob 手动归档禁用的任务，reason参数为归档原因
+func (s *Server) archiveJob(c *gin.Context) {
+	jobName := c.Param("name")
+
+	archived, err := s.jobMgr.ArchiveJob(jobName, currentUser(c), c.Query("reason"))
+	if err != nil {
+		switch {
+		case errors.Is(err, common.ErrJobNotFound):
+			failure(c, common.ApiJobNotExist, "job does not exist")
+		case errors.Is(err, common.ErrJobNotDisabled):
+			failure(c, common.ApiParamError, "only disabled jobs can be archived")
+		case errors.Is(err, common.ErrJobSaveConflict):
+			failure(c, common.ApiFailure, "job was modified during archiving, please retry")
+		default:
+			s.logger.Error("failed to archive job",
+				zap.String("jobName", jobName),
+				zap.Error(err))
+			failure(c, common.ApiEtcdError, "failed to archive job: "+err.Error())
+		}
+		return
+	}
+
+	success(c, archived)
+}
+
+// restoreJob 恢复归档的任务，恢复后保持禁用
+func (s *Server) restoreJob(c *gin.Context) {
+	jobName := c.Param("name")
+
+	job, err := s.jobMgr.RestoreJob(jobName)
+	if err != nil {
+		switch {
+		case errors.Is(err, common.ErrArchivedJobNotFound):
+			failure(c, common.ApiJobNotExist, "archived job does not exist")
+		case errors.Is(err, common.ErrJobExists):
+			failure(c, common.ApiParamError, "a job named "+jobName+" already exists")
+		case errors.Is(err, common.ErrJobSaveConflict):
+			failure(c, common.ApiFailure, "archived job was modified during restore, please retry")
+		default:
+			s.logger.Error("failed to restore job",
+				zap.String("jobName", jobName),
+				zap.Error(err))
+			failure(c, common.ApiEtcdError, "failed to restore job: "+err.Error())
+		}
+		return
+	}
+
+	success(c, job)
+}
@@ -0,0 +1,197 @@
+package api
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+	"github.com/fyerfyer/scheduler-refactor/master/authmgr"
+	"github.com/fyerfyer/scheduler-refactor/pkg/websocket"
+)
+
+// 认证凭证请求头和查询参数
+const (
+	headerAPIKey        = "X-API-Key"
+	headerAuthorization = "Authorization"
+	queryAccessToken    = "access_token" // WebSocket升级请求携带凭证的查询参数
+)
+
+// ctxKeyAuthMethod gin上下文中保存认证方式的key，值为apikey或jwt
+const ctxKeyAuthMethod = "authMethod"
+
+// apiPathPrefix 需要认证的接口前缀，健康检查不需要认证
+const apiPathPrefix = "/api/v1/"
+
+// authGuard 认证中间件，设置了认证管理器时/api/v1接口必须携带API密钥或JWT，
+// 认证通过后以凭证中的身份覆盖请求头中的身份
+func (s *Server) authGuard() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if s.authMgr == nil || !strings.HasPrefix(c.Request.URL.Path, apiPathPrefix) {
+			c.Next()
+			return
+		}
+
+		credential, method := requestCredential(c)
+		identity, err := s.authMgr.Authenticate(credential)
+		if err != nil {
+			if errors.Is(err, common.ErrUnauthenticated) {
+				failure(c, common.ApiUnauthorized, "missing or invalid credentials")
+			} else {
+				s.logger.Error("failed to authenticate request", zap.Error(err))
+				failure(c, common.ApiEtcdError, "failed to authenticate request: "+err.Error())
+			}
+			c.Abort()
+			return
+		}
+
+		namespaces := identity.Namespaces
+		if namespaces == nil {
+			namespaces = []string{}
+		}
+		c.Set(ctxKeyUser, identity.User)
+		c.Set(ctxKeyRole, identity.Role)
+		c.Set(ctxKeyNamespaces, namespaces)
+		c.Set(ctxKeyAuthMethod, method)
+		c.Next()
+	}
+}
+
+// requestCredential 从请求头获取凭证，X-API-Key优先于Authorization: Bearer。
+// 浏览器无法为WebSocket设置请求头，升级请求也可以通过access_token参数携带凭证
+func requestCredential(c *gin.Context) (string, string) {
+	if key := c.GetHeader(headerAPIKey); key != "" {
+		return key, "apikey"
+	}
+
+	scheme, token, ok := strings.Cut(c.GetHeader(headerAuthorization), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		if token = c.Query(queryAccessToken); token == "" || !websocket.IsUpgrade(c.Request) {
+			return "", ""
+		}
+	}
+	token = strings.TrimSpace(token)
+	if strings.Count(token, ".") == 2 {
+		return token, "jwt"
+	}
+	return token, "apikey"
+}
+
+// authAvailable 检查是否开启了认证，未开启时返回错误
+func (s *Server) authAvailable(c *gin.Context) bool {
+	if s.authMgr == nil {
+		failure(c, common.ApiFailure, "authentication is not enabled")
+		return false
+	}
+	return true
+}
+
+// issueToken 以API密钥换取JWT，ttl为有效期(秒)，不传时为1小时，最长24小时。
+// 只接受API密钥，JWT不能用于续期
+func (s *Server) issueToken(c *gin.Context) {
+	if !s.authAvailable(c) {
+		return
+	}
+	if c.GetString(ctxKeyAuthMethod) != "apikey" {
+		failure(c, common.ApiForbidden, "tokens can only be issued for api keys")
+		return
+	}
+
+	ttl, err := strconv.Atoi(c.DefaultQuery("ttl", "0"))
+	if err != nil || ttl < 0 {
+		failure(c, common.ApiParamError, "ttl must be a non-negative number of seconds")
+		return
+	}
+
+	identity := &authmgr.Identity{
+		User:       currentUser(c),
+		Role:       c.GetString(ctxKeyRole),
+		Namespaces: c.GetStringSlice(ctxKeyNamespaces),
+	}
+	token, expiresAt, err := s.authMgr.IssueToken(identity, time.Duration(ttl)*time.Second)
+	if err != nil {
+		failure(c, common.ApiFailure, "failed to issue token: "+err.Error())
+		return
+	}
+
+	success(c, gin.H{"token": token, "expiresAt": expiresAt})
+}
+
+// listAPIKeys 获取API密钥列表，不包含密钥明文
+func (s *Server) listAPIKeys(c *gin.Context) {
+	if !s.authAvailable(c) {
+		return
+	}
+	if !isAdmin(c) {
+		failure(c, common.ApiForbidden, "only admins can list api keys")
+		return
+	}
+
+	keys, err := s.authMgr.ListKeys()
+	if err != nil {
+		failure(c, common.ApiEtcdError, "failed to list api keys: "+err.Error())
+		return
+	}
+
+	success(c, keys)
+}
+
+// createAPIKey 创建API密钥，密钥明文只在响应中返回一次
+func (s *Server) createAPIKey(c *gin.Context) {
+	if !s.authAvailable(c) {
+		return
+	}
+	if !isAdmin(c) {
+		failure(c, common.ApiForbidden, "only admins can create api keys")
+		return
+	}
+
+	var key common.APIKey
+	if err := c.ShouldBindJSON(&key); err != nil {
+		failure(c, common.ApiParamError, "invalid api key: "+err.Error())
+		return
+	}
+	key.CreatedBy = currentUser(c)
+
+	secret, err := s.authMgr.CreateKey(&key)
+	if err != nil {
+		if errors.Is(err, common.ErrInvalidAPIKey) {
+			failure(c, common.ApiParamError, err.Error())
+		} else {
+			failure(c, common.ApiEtcdError, "failed to create api key: "+err.Error())
+		}
+		return
+	}
+
+	success(c, gin.H{"key": &key, "secret": secret})
+}
+
+// deleteAPIKey 吊销API密钥，已签发的JWT在过期前仍然有效
+func (s *Server) deleteAPIKey(c *gin.Context) {
+	if !s.authAvailable(c) {
+		return
+	}
+	if !isAdmin(c) {
+		failure(c, common.ApiForbidden, "only admins can revoke api keys")
+		return
+	}
+
+	id := c.Param("id")
+	if err := s.authMgr.DeleteKey(id); err != nil {
+		if errors.Is(err, common.ErrAPIKeyNotFound) {
+			failure(c, common.ApiNotFound, "api key does not exist")
+		} else {
+			failure(c, common.ApiEtcdError, "failed to revoke api key: "+err.Error())
+		}
+		return
+	}
+
+	s.logger.Info("api key revoked by user",
+		zap.String("id", id),
+		zap.String("user", currentUser(c)))
+	success(c, nil)
+}
@@ -0,0 +1,99 @@
+package api
+
+import (
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+)
+
+// maxBatchGetJobs 批量获取任务时最多的任务数
+const maxBatchGetJobs = 100
+
+// batchGetRequest 批量获取任务的请求
+type batchGetRequest struct {
+	Names []string `json:"names" binding:"required"` // 任务名列表
+	Days  int      `json:"days"`                     // 统计最近执行情况的天数，默认7天
+}
+
+// jobStatus 任务当前的执行状态
+type jobStatus struct {
+	Running     bool             `json:"running"`               // 任务锁被持有，正在执行
+	Worker      string           `json:"worker,omitempty"`      // 正在执行的worker
+	LastRunTime int64            `json:"lastRunTime,omitempty"` // 最近一次执行的开始时间
+	LastStatus  common.RunStatus `json:"lastStatus,omitempty"`  // 最近一次执行的状态
+}
+
+// jobWithStatus 任务定义及其执行状态
+type jobWithStatus struct {
+	Job    *common.Job `json:"job"`    // 任务定义
+	Status *jobStatus  `json:"status"` // 执行状态
+}
+
+// batchGetResponse 批量获取任务的结果
+type batchGetResponse struct {
+	Jobs    []*jobWithStatus `json:"jobs"`    // 存在的任务，按请求的顺序
+	Missing []string         `json:"missing"` // 不存在的任务名
+}
+
+// batchGetJobs 一次获取多个任务的定义和执行状态，日志存储不可用时只返回是否正在执行
+func (s *Server) batchGetJobs(c *gin.Context) {
+	var req batchGetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		failure(c, common.ApiParamError, "invalid batch get request: "+err.Error())
+		return
+	}
+
+	// 去重并保持顺序
+	names := make([]string, 0, len(req.Names))
+	seen := make(map[string]bool, len(req.Names))
+	for _, name := range req.Names {
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		names = append(names, name)
+	}
+	if len(names) == 0 {
+		failure(c, common.ApiParamError, "names must not be empty")
+		return
+	}
+	if len(names) > maxBatchGetJobs {
+		failure(c, common.ApiParamError, fmt.Sprintf("at most %d jobs can be fetched at once", maxBatchGetJobs))
+		return
+	}
+
+	states, err := s.jobMgr.BatchGetJobs(names)
+	if err != nil {
+		failure(c, common.ApiEtcdError, "failed to get jobs: "+err.Error())
+		return
+	}
+
+	summaries, err := s.logMgr.GetRunSummaries(callerScope(c), req.Days)
+	if err != nil {
+		s.logger.Warn("failed to summarize job runs for batch get", zap.Error(err))
+	}
+
+	resp := &batchGetResponse{
+		Jobs:    make([]*jobWithStatus, 0, len(states)),
+		Missing: make([]string, 0),
+	}
+	for _, name := range names {
+		state, exists := states[name]
+		if !exists {
+			resp.Missing = append(resp.Missing, name)
+			continue
+		}
+
+		status := &jobStatus{Running: state.Holder != "", Worker: state.Holder}
+		if summary, ok := summaries[name]; ok {
+			status.LastRunTime = summary.LastRunTime
+			status.LastStatus = summary.LastStatus
+		}
+		resp.Jobs = append(resp.Jobs, &jobWithStatus{Job: state.Job, Status: status})
+	}
+
+	success(c, resp)
+}
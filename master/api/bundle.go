@@ -0,0 +1,202 @@
+package api
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+)
+
+// runBundle 一次执行的诊断包内容，打包为zip附在工单中
+type runBundle struct {
+	Manifest  *bundleManifest      // 诊断包说明
+	Run       *common.JobLog       // 执行日志
+	Job       *common.Job          // 执行所用的任务定义，取不到时为当前定义
+	Worker    *common.WorkerInfo   // 执行的worker，worker已下线时为空
+	Placement *common.JobPlacement // 本次触发的调度决策，已被之后的触发覆盖时为空
+}
+
+// bundleManifest 诊断包的说明，notes列出没有取到的内容及原因
+type bundleManifest struct {
+	RunID       string   `json:"runId"`       // 执行的唯一标识
+	JobName     string   `json:"jobName"`     // 任务名称
+	GeneratedAt int64    `json:"generatedAt"` // 生成时间
+	GeneratedBy string   `json:"generatedBy"` // 下载人
+	Definition  string   `json:"definition"`  // 任务定义的来源：revision（执行所用版本）、current（当前定义）或missing
+	Notes       []string `json:"notes"`       // 缺失内容的说明
+}
+
+// bundleTimings 执行各阶段的时间，时长单位为秒
+type bundleTimings struct {
+	PlanTime      int64   `json:"planTime"`      // 计划执行时间
+	ScheduleTime  int64   `json:"scheduleTime"`  // 实际调度时间
+	StartTime     int64   `json:"startTime"`     // 开始执行时间
+	EndTime       int64   `json:"endTime"`       // 结束时间
+	ScheduleDelay int64   `json:"scheduleDelay"` // 调度延迟，实际调度时间减计划时间
+	Duration      int64   `json:"duration"`      // 执行时长
+	CPUTime       float64 `json:"cpuTime"`       // CPU时间
+	MaxRSS        int64   `json:"maxRss"`        // 峰值内存(KB)
+}
+
+// getRunBundle 下载一次执行的诊断包，包含执行所用的任务定义、完整输出、各阶段时间、
+// 执行的worker和调度决策，取不到的内容记录在manifest.json中
+func (s *Server) getRunBundle(c *gin.Context) {
+	runID := c.Param("runId")
+
+	run, err := s.logMgr.GetRun(runID, callerScope(c))
+	if err != nil {
+		if errors.Is(err, common.ErrRunNotFound) {
+			failure(c, common.ApiNotFound, "run does not exist")
+		} else {
+			failure(c, common.ApiDbError, "failed to get run: "+err.Error())
+		}
+		return
+	}
+
+	bundle := s.collectRunBundle(run, currentUser(c))
+
+	buf := &bytes.Buffer{}
+	if err = writeRunBundle(buf, bundle); err != nil {
+		s.logger.Error("failed to write run bundle",
+			zap.String("runId", runID),
+			zap.Error(err))
+		failure(c, common.ApiFailure, "failed to write run bundle: "+err.Error())
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="run-%s.zip"`, runID))
+	c.Data(http.StatusOK, "application/zip", buf.Bytes())
+}
+
+// collectRunBundle 从日志存储和etcd中收集诊断包的内容，单项失败只记录在说明中
+func (s *Server) collectRunBundle(run *common.JobLog, generatedBy string) *runBundle {
+	bundle := &runBundle{
+		Manifest: &bundleManifest{
+			RunID:       run.RunID,
+			JobName:     run.JobName,
+			GeneratedAt: time.Now().Unix(),
+			GeneratedBy: generatedBy,
+			Notes:       []string{},
+		},
+		Run: run,
+	}
+	note := func(format string, args ...any) {
+		bundle.Manifest.Notes = append(bundle.Manifest.Notes, fmt.Sprintf(format, args...))
+	}
+
+	if run.JobRevision > 0 {
+		job, err := s.jobMgr.GetJobAtRevision(run.JobName, run.JobRevision)
+		if err == nil {
+			bundle.Job = job
+			bundle.Manifest.Definition = "revision"
+		} else {
+			note("job definition at revision %d unavailable: %v", run.JobRevision, err)
+		}
+	} else {
+		note("run has no recorded job revision")
+	}
+	if bundle.Job == nil {
+		if job, err := s.jobMgr.GetJob(run.JobName); err == nil {
+			bundle.Job = job
+			bundle.Manifest.Definition = "current"
+			note("job.json contains the current job definition")
+		} else {
+			bundle.Manifest.Definition = "missing"
+			note("current job definition unavailable: %v", err)
+		}
+	}
+
+	// 日志的workerIp记录的是worker ID
+	if worker, exists := s.workerMgr.GetWorker(run.WorkerIP); exists {
+		bundle.Worker = worker
+	} else {
+		note("worker %s is no longer registered", run.WorkerIP)
+	}
+
+	placement, err := s.jobMgr.GetPlacementAt(run.JobName, run.PlanTime)
+	if err == nil {
+		bundle.Placement = placement
+	} else if errors.Is(err, common.ErrPlacementNotFound) {
+		note("scheduler decisions for this run have been replaced by later triggers")
+	} else {
+		note("scheduler decisions unavailable: %v", err)
+	}
+
+	return bundle
+}
+
+// writeRunBundle 将诊断包写为zip，输出和错误输出单独存放，其余内容为JSON
+func writeRunBundle(w io.Writer, bundle *runBundle) error {
+	zw := zip.NewWriter(w)
+
+	// run.json不重复包含输出
+	run := *bundle.Run
+	run.Output, run.Error = "", ""
+	run.Status = bundle.Run.GetStatus()
+
+	timings := &bundleTimings{
+		PlanTime:     run.PlanTime,
+		ScheduleTime: run.ScheduleTime,
+		StartTime:    run.StartTime,
+		EndTime:      run.EndTime,
+		CPUTime:      run.CPUTime,
+		MaxRSS:       run.MaxRSS,
+	}
+	if run.ScheduleTime > 0 && run.PlanTime > 0 {
+		timings.ScheduleDelay = run.ScheduleTime - run.PlanTime
+	}
+	if run.EndTime > run.StartTime {
+		timings.Duration = run.EndTime - run.StartTime
+	}
+
+	files := []struct {
+		name string
+		data any
+	}{
+		{"manifest.json", bundle.Manifest},
+		{"run.json", &run},
+		{"timings.json", timings},
+		{"job.json", bundle.Job},
+		{"worker.json", bundle.Worker},
+		{"decisions.json", bundle.Placement},
+	}
+	for _, file := range files {
+		data, err := json.MarshalIndent(file.data, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal %s: %v", file.name, err)
+		}
+		if err = writeBundleFile(zw, file.name, data); err != nil {
+			return err
+		}
+	}
+
+	if err := writeBundleFile(zw, "output.txt", []byte(bundle.Run.Output)); err != nil {
+		return err
+	}
+	if err := writeBundleFile(zw, "error.txt", []byte(bundle.Run.Error)); err != nil {
+		return err
+	}
+
+	return zw.Close()
+}
+
+// writeBundleFile 向zip中写入一个文件
+func writeBundleFile(zw *zip.Writer, name string, data []byte) error {
+	fw, err := zw.Create(name)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %v", name, err)
+	}
+	if _, err = fw.Write(data); err != nil {
+		return fmt.Errorf("failed to write %s: %v", name, err)
+	}
+	return nil
+}
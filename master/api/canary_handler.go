@@ -0,0 +1,111 @@
+package api
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+)
+
+// startCanary 以灰度方式保存已有任务的新定义，新定义只在指定worker上执行
+func (s *Server) startCanary(c *gin.Context, job *common.Job, worker string) {
+	// 灰度结束后由worker直接全量，无法经过审批
+	if s.requiresApproval(c) {
+		failure(c, common.ApiForbidden, "canary rollouts are not available for changes that require approval")
+		return
+	}
+
+	if _, exists := s.workerMgr.GetWorker(worker); !exists {
+		failure(c, common.ApiParamError, "canary worker is not registered")
+		return
+	}
+
+	runs, err := strconv.Atoi(c.DefaultQuery("canaryRuns", strconv.Itoa(common.DefaultCanaryRuns)))
+	if err != nil || runs <= 0 {
+		failure(c, common.ApiParamError, "canaryRuns must be a positive integer")
+		return
+	}
+
+	minSuccessRate, err := strconv.ParseFloat(c.DefaultQuery("canaryMinSuccessRate", "1"), 64)
+	if err != nil || minSuccessRate < 0 || minSuccessRate > 1 {
+		failure(c, common.ApiParamError, "canaryMinSuccessRate must be between 0 and 1")
+		return
+	}
+
+	existing, err := s.jobMgr.GetJob(job.Name)
+	if err != nil {
+		if errors.Is(err, common.ErrJobNotFound) {
+			failure(c, common.ApiJobNotExist, "canary rollout requires an existing job")
+		} else {
+			failure(c, common.ApiEtcdError, "failed to get job: "+err.Error())
+		}
+		return
+	}
+
+	now := time.Now().Unix()
+	job.CreatedAt = existing.CreatedAt
+	job.UpdatedAt = now
+
+	canary := &common.CanaryRelease{
+		Job:            job,
+		Worker:         worker,
+		Runs:           runs,
+		MinSuccessRate: minSuccessRate,
+		StartedBy:      currentUser(c),
+		StartedAt:      now,
+	}
+	if err := s.jobMgr.StartCanary(canary); err != nil {
+		if errors.Is(err, common.ErrJobNotFound) {
+			failure(c, common.ApiJobNotExist, "canary rollout requires an existing job")
+		} else {
+			failure(c, common.ApiEtcdError, "failed to start canary release: "+err.Error())
+		}
+		return
+	}
+
+	success(c, canary)
+}
+
+// getCanary 获取任务进行中的灰度发布及其执行结果
+func (s *Server) getCanary(c *gin.Context) {
+	jobName := c.Param("name")
+
+	canary, err := s.jobMgr.GetCanary(jobName)
+	if err != nil {
+		if errors.Is(err, common.ErrCanaryNotFound) {
+			failure(c, common.ApiNotFound, "job has no canary release")
+		} else {
+			s.logger.Error("failed to get canary release",
+				zap.String("jobName", jobName),
+				zap.Error(err))
+			failure(c, common.ApiEtcdError, "failed to get canary release: "+err.Error())
+		}
+		return
+	}
+
+	success(c, canary)
+}
+
+// cancelCanary 取消灰度发布，相当于手动回滚
+func (s *Server) cancelCanary(c *gin.Context) {
+	jobName := c.Param("name")
+
+	if err := s.jobMgr.CancelCanary(jobName); err != nil {
+		if errors.Is(err, common.ErrCanaryNotFound) {
+			failure(c, common.ApiNotFound, "job has no canary release")
+		} else {
+			failure(c, common.ApiEtcdError, "failed to cancel canary release: "+err.Error())
+		}
+		return
+	}
+
+	s.logger.Info("canary release cancelled by user",
+		zap.String("jobName", jobName),
+		zap.String("user", currentUser(c)))
+
+	success(c, nil)
+}
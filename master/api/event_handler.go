@@ -0,0 +1,128 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+	"github.com/fyerfyer/scheduler-refactor/master/eventhub"
+	"github.com/fyerfyer/scheduler-refactor/pkg/websocket"
+)
+
+// eventTypes 可以订阅的事件类型，types参数也可以只写类别(job/run/worker)
+var eventTypes = []string{
+	eventhub.EventJobSave,
+	eventhub.EventJobDelete,
+	eventhub.EventRunStart,
+	eventhub.EventRunFinish,
+	eventhub.EventWorkerJoin,
+	eventhub.EventWorkerLeave,
+	eventhub.EventWorkerOnline,
+	eventhub.EventWorkerOffline,
+}
+
+// streamEvents 通过WebSocket推送任务保存/删除、执行开始/结束和worker上下线事件，直到客户端断开
+func (s *Server) streamEvents(c *gin.Context) {
+	if s.events == nil {
+		failure(c, common.ApiFailure, "event stream is not available")
+		return
+	}
+
+	filter, err := parseEventFilter(c.Query("types"))
+	if err != nil {
+		failure(c, common.ApiParamError, err.Error())
+		return
+	}
+
+	conn, err := websocket.Upgrade(c.Writer, c.Request)
+	if err != nil {
+		failure(c, common.ApiParamError, "websocket upgrade failed: "+err.Error())
+		return
+	}
+	defer conn.Close()
+
+	events, unsubscribe := s.events.Subscribe()
+	defer unsubscribe()
+
+	// 执行和任务事件按调用方可读取的范围过滤
+	scope := callerScope(c)
+
+	// 客户端不发送业务消息，读取只用于回复ping和感知断开
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	// 定期发送ping，避免空闲连接被代理断开，同时发现失联的客户端
+	keepalive := time.NewTicker(eventStreamKeepalive)
+	defer keepalive.Stop()
+
+	for {
+		select {
+		case <-closed:
+			return
+		case event := <-events:
+			if !filter(event.Type) || !eventVisible(scope, event) {
+				continue
+			}
+			data, err := json.Marshal(event)
+			if err != nil {
+				s.logger.Error("failed to marshal event", zap.String("type", event.Type), zap.Error(err))
+				continue
+			}
+			if err = conn.WriteText(data); err != nil {
+				return
+			}
+		case <-keepalive.C:
+			if err := conn.Ping(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// eventVisible 任务和执行事件按调用方范围过滤，worker事件对所有调用方可见
+func eventVisible(scope *common.Scope, event *eventhub.Event) bool {
+	return event.IsWorker() || scope.Allows(event.Namespace, event.Owner)
+}
+
+// parseEventFilter 解析逗号分隔的事件类型或类别，为空时订阅全部事件
+func parseEventFilter(value string) (func(string) bool, error) {
+	if strings.TrimSpace(value) == "" {
+		return func(string) bool { return true }, nil
+	}
+
+	known := make(map[string]bool)
+	for _, eventType := range eventTypes {
+		category, _, _ := strings.Cut(eventType, ".")
+		known[eventType] = true
+		known[category] = true
+	}
+
+	selected := make(map[string]bool)
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		if !known[item] {
+			return nil, fmt.Errorf("unknown event type: %s", item)
+		}
+		selected[item] = true
+	}
+
+	return func(eventType string) bool {
+		category, _, _ := strings.Cut(eventType, ".")
+		return selected[eventType] || selected[category]
+	}, nil
+}
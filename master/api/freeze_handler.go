@@ -0,0 +1,102 @@
+package api
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+)
+
+// headerFreezeOverride 管理员在冻结窗口内强制变更时携带的请求头，值为变更原因
+const headerFreezeOverride = "X-Freeze-Override"
+
+// freezeGuard 冻结窗口中间件，窗口内拒绝任务变更，管理员可以携带原因强制变更
+func (s *Server) freezeGuard() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		window, err := s.freezeMgr.ActiveWindow()
+		if err != nil {
+			s.logger.Error("failed to check freeze windows", zap.Error(err))
+			failure(c, common.ApiEtcdError, "failed to check freeze windows: "+err.Error())
+			c.Abort()
+			return
+		}
+
+		if window == nil {
+			c.Next()
+			return
+		}
+
+		if reason := c.GetHeader(headerFreezeOverride); reason != "" && isAdmin(c) {
+			s.logger.Warn("freeze window overridden",
+				zap.String("window", window.Name),
+				zap.String("user", currentUser(c)),
+				zap.String("path", c.Request.URL.Path),
+				zap.String("reason", reason))
+			c.Next()
+			return
+		}
+
+		failure(c, common.ApiFrozen, "job changes are frozen until "+
+			time.Unix(window.End, 0).Format(time.RFC3339)+" ("+window.Name+": "+window.Reason+")")
+		c.Abort()
+	}
+}
+
+// listFreezeWindows 获取冻结窗口列表
+func (s *Server) listFreezeWindows(c *gin.Context) {
+	windows, err := s.freezeMgr.ListWindows()
+	if err != nil {
+		failure(c, common.ApiEtcdError, "failed to list freeze windows: "+err.Error())
+		return
+	}
+
+	success(c, windows)
+}
+
+// saveFreezeWindow 保存冻结窗口
+func (s *Server) saveFreezeWindow(c *gin.Context) {
+	if !isAdmin(c) {
+		failure(c, common.ApiForbidden, "only admins can declare freeze windows")
+		return
+	}
+
+	var window common.FreezeWindow
+	if err := c.ShouldBindJSON(&window); err != nil {
+		failure(c, common.ApiParamError, "invalid freeze window: "+err.Error())
+		return
+	}
+	window.CreatedBy = currentUser(c)
+
+	if err := s.freezeMgr.SaveWindow(&window); err != nil {
+		if errors.Is(err, common.ErrInvalidFreezeWindow) {
+			failure(c, common.ApiParamError, err.Error())
+		} else {
+			failure(c, common.ApiEtcdError, "failed to save freeze window: "+err.Error())
+		}
+		return
+	}
+
+	success(c, window)
+}
+
+// deleteFreezeWindow 删除冻结窗口
+func (s *Server) deleteFreezeWindow(c *gin.Context) {
+	if !isAdmin(c) {
+		failure(c, common.ApiForbidden, "only admins can delete freeze windows")
+		return
+	}
+
+	if err := s.freezeMgr.DeleteWindow(c.Param("name")); err != nil {
+		if errors.Is(err, common.ErrFreezeWindowNotFound) {
+			failure(c, common.ApiParamError, "freeze window does not exist")
+		} else {
+			failure(c, common.ApiEtcdError, "failed to delete freeze window: "+err.Error())
+		}
+		return
+	}
+
+	success(c, nil)
+}
@@ -0,0 +1,76 @@
+package api
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/fyerfyer/scheduler-refactor/pkg/websocket"
+)
+
+// gzipWriter 将响应体写入gzip压缩流
+type gzipWriter struct {
+	gin.ResponseWriter
+	writer  *gzip.Writer
+	written bool // 是否写入过响应体
+}
+
+// Write 压缩后写出响应体
+func (g *gzipWriter) Write(data []byte) (int, error) {
+	g.written = true
+	g.Header().Del("Content-Length")
+	return g.writer.Write(data)
+}
+
+// WriteString 压缩后写出字符串响应体
+func (g *gzipWriter) WriteString(s string) (int, error) {
+	return g.Write([]byte(s))
+}
+
+// Flush 先刷出压缩流中缓存的数据，保证流式响应及时送达
+func (g *gzipWriter) Flush() {
+	g.written = true
+	g.writer.Flush()
+	g.ResponseWriter.Flush()
+}
+
+// gzipMiddleware 对接受gzip编码的请求压缩响应体
+func gzipMiddleware() gin.HandlerFunc {
+	pool := sync.Pool{
+		New: func() interface{} {
+			return gzip.NewWriter(io.Discard)
+		},
+	}
+
+	return func(c *gin.Context) {
+		// WebSocket升级后的连接不经过响应体写出，不压缩
+		if c.Request.Method == http.MethodHead || !strings.Contains(c.GetHeader("Accept-Encoding"), "gzip") || websocket.IsUpgrade(c.Request) {
+			c.Next()
+			return
+		}
+
+		gz := pool.Get().(*gzip.Writer)
+		gz.Reset(c.Writer)
+
+		c.Header("Content-Encoding", "gzip")
+		c.Header("Vary", "Accept-Encoding")
+		writer := &gzipWriter{ResponseWriter: c.Writer, writer: gz}
+		c.Writer = writer
+
+		defer func() {
+			// 没有响应体时(例如304)不写出gzip尾部
+			if !writer.written {
+				c.Writer.Header().Del("Content-Encoding")
+				gz.Reset(io.Discard)
+			}
+			gz.Close()
+			pool.Put(gz)
+		}()
+
+		c.Next()
+	}
+}
@@ -0,0 +1,66 @@
+package api
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+)
+
+// supportedLanguages 可以协商的消息语言
+var supportedLanguages = map[string]bool{
+	common.LangEn: true,
+	common.LangZh: true,
+}
+
+// preferredLanguage 按Accept-Language选出权重最高的支持语言，没有匹配时返回英文
+func preferredLanguage(header string) string {
+	best, bestQ := common.LangEn, 0.0
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		// zh-CN、zh_TW等按主语言匹配
+		lang := strings.ToLower(strings.TrimSpace(fields[0]))
+		if i := strings.IndexAny(lang, "-_"); i >= 0 {
+			lang = lang[:i]
+		}
+		if !supportedLanguages[lang] {
+			continue
+		}
+
+		q := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if value, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					q = value
+				}
+			}
+		}
+		// 权重相同时取先出现的语言
+		if q > bestQ {
+			best, bestQ = lang, q
+		}
+	}
+	return best
+}
+
+// localize 把错误响应的提示信息翻译为调用方的语言，原始信息保留在detail中
+func localize(c *gin.Context, resp *common.ApiResponse) {
+	resp.ErrorCode = common.ApiErrorCode(resp.Code)
+
+	lang := preferredLanguage(c.GetHeader("Accept-Language"))
+	if lang == common.LangEn {
+		return
+	}
+	if message, ok := common.ApiMessage(resp.Code, lang); ok {
+		resp.Detail = resp.Message
+		resp.Message = message
+	}
+}
+
+// getErrorCatalog 获取错误目录，客户端可以据此按errorCode自行展示
+func (s *Server) getErrorCatalog(c *gin.Context) {
+	success(c, common.ApiErrorCatalog())
+}
@@ -0,0 +1,137 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+	"github.com/fyerfyer/scheduler-refactor/master/idempotency"
+)
+
+// 幂等相关的请求头和响应头
+const (
+	headerIdempotencyKey      = "Idempotency-Key"
+	headerIdempotencyReplayed = "Idempotent-Replayed" // 响应为缓存的首次响应时为true
+)
+
+// 幂等键和缓存响应的限制
+const (
+	maxIdempotencyKeyLength = 255
+	maxIdempotentResponse   = 512 * 1024 // 超过该大小的响应不缓存
+)
+
+// captureWriter 在写出响应的同时保留一份副本
+type captureWriter struct {
+	gin.ResponseWriter
+	body     bytes.Buffer
+	overflow bool // 响应超过缓存上限
+}
+
+// Write 写出响应体并保留副本
+func (w *captureWriter) Write(data []byte) (int, error) {
+	if !w.overflow {
+		if w.body.Len()+len(data) > maxIdempotentResponse {
+			w.overflow = true
+			w.body.Reset()
+		} else {
+			w.body.Write(data)
+		}
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+// WriteString 写出字符串响应体并保留副本
+func (w *captureWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// idempotencyGuard 幂等中间件。携带Idempotency-Key的POST请求在缓存时间内重试时直接返回首次的响应，
+// 不会重复创建触发或重复修改；同一幂等键用于不同的请求体时拒绝
+func (s *Server) idempotencyGuard() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(headerIdempotencyKey)
+		if s.idempotency == nil || key == "" || c.Request.Method != http.MethodPost {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			failure(c, common.ApiParamError, "idempotency key is too long")
+			c.Abort()
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			failure(c, common.ApiParamError, "failed to read request body: "+err.Error())
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		scope := currentUser(c) + " " + c.Request.URL.Path
+		reservation, cached, err := s.idempotency.Begin(scope, key, idempotency.Fingerprint(body))
+		if err != nil {
+			switch {
+			case errors.Is(err, common.ErrIdempotencyConflict), errors.Is(err, common.ErrIdempotencyInProgress):
+				failure(c, common.ApiIdempotency, err.Error())
+			default:
+				s.logger.Error("failed to check idempotency key", zap.Error(err))
+				failure(c, common.ApiEtcdError, "failed to check idempotency key: "+err.Error())
+			}
+			c.Abort()
+			return
+		}
+		if cached != nil {
+			c.Header(headerIdempotencyReplayed, "true")
+			c.Data(cached.Status, cached.ContentType, cached.Body)
+			c.Abort()
+			return
+		}
+
+		writer := &captureWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		c.Next()
+
+		if !cacheableResponse(writer) {
+			s.idempotency.Cancel(reservation)
+			return
+		}
+		if err = s.idempotency.Complete(reservation, writer.Status(), writer.Header().Get("Content-Type"), writer.body.Bytes()); err != nil {
+			s.logger.Warn("failed to save idempotent response",
+				zap.String("path", c.Request.URL.Path),
+				zap.Error(err))
+		}
+	}
+}
+
+// transientCodes 取决于调用方权限或集群当前状态的结果。幂等中间件在角色检查和冻结检查之前执行，
+// 这些结果不缓存，角色授予、冻结结束或审批要求变化后用同一幂等键重试时重新执行
+var transientCodes = map[int]bool{
+	common.ApiPending:      true,
+	common.ApiForbidden:    true,
+	common.ApiFrozen:       true,
+	common.ApiReadOnly:     true,
+	common.ApiUnauthorized: true,
+}
+
+// cacheableResponse 响应是否可以缓存。系统错误（etcd、数据库不可用等）和取决于当前状态的拒绝不缓存，
+// 客户端重试时重新执行
+func cacheableResponse(w *captureWriter) bool {
+	if w.overflow || w.Status() != http.StatusOK {
+		return false
+	}
+
+	var resp struct {
+		Code int `json:"code"`
+	}
+	if err := json.Unmarshal(w.body.Bytes(), &resp); err != nil {
+		return false
+	}
+	return resp.Code < common.ApiSystemError && !transientCodes[resp.Code]
+}
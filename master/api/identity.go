@@ -0,0 +1,67 @@
+package api
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+	"github.com/fyerfyer/scheduler-refactor/config"
+)
+
+// 调用方身份请求头，由前置网关在认证后注入
+const (
+	headerUser       = "X-User"
+	headerRole       = "X-Role"
+	headerNamespaces = "X-Namespaces" // 逗号分隔的可访问命名空间
+)
+
+// gin上下文中保存身份的key
+const (
+	ctxKeyUser       = "user"
+	ctxKeyRole       = "role"
+	ctxKeyNamespaces = "namespaces"
+)
+
+// identityMiddleware 从请求头解析调用方身份
+func identityMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(ctxKeyUser, c.GetHeader(headerUser))
+		c.Set(ctxKeyRole, c.GetHeader(headerRole))
+		c.Set(ctxKeyNamespaces, parseNamespaces(c.GetHeader(headerNamespaces)))
+		c.Next()
+	}
+}
+
+// currentUser 获取调用方用户名，匿名调用返回空字符串
+func currentUser(c *gin.Context) string {
+	return c.GetString(ctxKeyUser)
+}
+
+// isAdmin 调用方是否为管理员
+func isAdmin(c *gin.Context) bool {
+	return c.GetString(ctxKeyRole) == common.RoleAdmin
+}
+
+// callerScope 获取调用方可读取的数据范围，未开启范围限制或管理员返回nil
+func callerScope(c *gin.Context) *common.Scope {
+	if !config.GlobalConfig.EnforceLogScope || isAdmin(c) {
+		return nil
+	}
+
+	return &common.Scope{
+		Namespaces: c.GetStringSlice(ctxKeyNamespaces),
+		Owner:      currentUser(c),
+	}
+}
+
+// parseNamespaces 解析逗号分隔的命名空间列表
+func parseNamespaces(value string) []string {
+	namespaces := make([]string, 0)
+	for _, ns := range strings.Split(value, ",") {
+		if ns = strings.TrimSpace(ns); ns != "" {
+			namespaces = append(namespaces, ns)
+		}
+	}
+	return namespaces
+}
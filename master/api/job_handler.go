@@ -1,21 +1,32 @@
 package api
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"github.com/gin-gonic/gin"
-	"github.com/robfig/cron/v3"
 	"go.uber.org/zap"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/fyerfyer/scheduler-refactor/common"
+	"github.com/fyerfyer/scheduler-refactor/config"
+	"github.com/fyerfyer/scheduler-refactor/master/jobmgr"
+	"github.com/fyerfyer/scheduler-refactor/master/logmgr"
+	"github.com/fyerfyer/scheduler-refactor/pkg/expr"
 )
 
 // saveJob 保存任务
 func (s *Server) saveJob(c *gin.Context) {
 	var job common.Job
 
-	// 解析请求
-	if err := c.ShouldBindJSON(&job); err != nil {
-		failure(c, common.ApiParamError, "invalid job data: "+err.Error())
+	// 严格解析请求，拼错的字段会被拒绝而不是忽略
+	if errs := bindStrictJSON(c, &job); len(errs) > 0 {
+		failureWithData(c, common.ApiParamError, "invalid job data: "+joinFieldErrors(errs), errs)
 		return
 	}
 
@@ -25,20 +36,118 @@ func (s *Server) saveJob(c *gin.Context) {
 		return
 	}
 
-	if job.Command == "" {
-		failure(c, common.ApiParamError, "job command is required")
+	// 准入webhook可以修改或拒绝任务，修改后的定义同样要经过下面的校验和命令策略
+	if !s.admitJob(c, &job) {
 		return
 	}
 
-	if job.CronExpr == "" {
-		failure(c, common.ApiParamError, "job cron expression is required")
+	if err := validateJob(&job); err != nil {
+		failure(c, common.ApiParamError, err.Error())
 		return
 	}
 
-	// 验证cron表达式
-	parser := cron.NewParser(cron.Second | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow)
-	if _, err := parser.Parse(job.CronExpr); err != nil {
-		failure(c, common.ApiParamError, "invalid cron expression: "+err.Error())
+	// 插件任务按在线worker上报的插件声明校验配置
+	if err := validateExecutor(&job, s.workerMgr.ExecutorManifests); err != nil {
+		failure(c, common.ApiParamError, "invalid executor: "+err.Error())
+		return
+	}
+
+	// 信号量需要先由管理员创建
+	if s.semMgr != nil {
+		for _, name := range job.Semaphores {
+			if _, err := s.semMgr.GetSemaphore(name); err != nil {
+				if errors.Is(err, common.ErrSemaphoreNotFound) {
+					failure(c, common.ApiParamError, "semaphore "+name+" does not exist")
+				} else {
+					failure(c, common.ApiEtcdError, "failed to check semaphore: "+err.Error())
+				}
+				return
+			}
+		}
+	}
+
+	// 同组任务需要在同一时间触发
+	if job.Gang != "" {
+		jobs, err := s.jobMgr.ListJobs()
+		if err != nil {
+			s.logger.Error("failed to list jobs", zap.Error(err))
+			failure(c, common.ApiEtcdError, "failed to list jobs: "+err.Error())
+			return
+		}
+		if err := checkGang(&job, jobs); err != nil {
+			failure(c, common.ApiParamError, err.Error())
+			return
+		}
+	}
+
+	job.Namespace = common.NamespaceOf(job.Namespace)
+
+	// 命令策略检查
+	if decision, err := s.policyMgr.CheckJob(&job); err != nil {
+		if errors.Is(err, common.ErrCommandDenied) {
+			failure(c, common.ApiPolicyDeny, "command denied by policy: "+decision.Reason)
+		} else {
+			s.logger.Error("failed to evaluate command policy",
+				zap.String("jobName", job.Name),
+				zap.Error(err))
+			failure(c, common.ApiEtcdError, "failed to evaluate command policy: "+err.Error())
+		}
+		return
+	}
+
+	// 实验命令同样需要通过命令策略
+	if job.ExperimentCommand != "" {
+		experiment := job
+		experiment.Command = job.ExperimentCommand
+		if decision, err := s.policyMgr.CheckJob(&experiment); err != nil {
+			if errors.Is(err, common.ErrCommandDenied) {
+				failure(c, common.ApiPolicyDeny, "experiment command denied by policy: "+decision.Reason)
+			} else {
+				failure(c, common.ApiEtcdError, "failed to evaluate command policy: "+err.Error())
+			}
+			return
+		}
+	}
+
+	// 未指定负责人时沿用原负责人，新任务默认由提交人负责；曾用名只由改名维护
+	existing, err := s.jobMgr.GetJob(job.Name)
+	if err != nil && !errors.Is(err, common.ErrJobNotFound) {
+		s.logger.Error("failed to get job",
+			zap.String("jobName", job.Name),
+			zap.Error(err))
+		failure(c, common.ApiEtcdError, "failed to get job: "+err.Error())
+		return
+	}
+	job.Aliases = nil
+	if existing != nil {
+		job.Aliases = existing.Aliases
+	}
+	if job.Owner == "" {
+		if existing != nil {
+			job.Owner = existing.Owner
+		} else {
+			job.Owner = currentUser(c)
+		}
+	}
+
+	// 新定义的触发间隔短于最近的平均执行时长时提示
+	if existing != nil {
+		s.warnOverlap(c, &job)
+	}
+
+	// 指定灰度worker时新定义先只在该worker上试运行
+	if worker := c.Query("canaryWorker"); worker != "" {
+		s.startCanary(c, &job, worker)
+		return
+	}
+
+	// 需要审批时只提交待审批变更
+	if s.requiresApproval(c) {
+		s.submitChange(c, &common.PendingChange{
+			JobName: job.Name,
+			Action:  common.ChangeActionSave,
+			Job:     &job,
+		})
 		return
 	}
 
@@ -54,10 +163,205 @@ func (s *Server) saveJob(c *gin.Context) {
 	success(c, job)
 }
 
+// validateJob 校验任务定义本身，不依赖etcd中的其他数据。返回的错误信息直接作为参数错误返回给调用方
+func validateJob(job *common.Job) error {
+	// 插件任务是否需要command由插件声明决定
+	if job.Command == "" && job.ExecutorType() == common.ExecutorShell {
+		return errors.New("job command is required")
+	}
+	if job.CronExpr == "" {
+		return errors.New("job cron expression is required")
+	}
+
+	// 验证时区和cron表达式
+	if _, err := job.Location(); err != nil {
+		return errors.New("unknown timezone: " + job.Timezone)
+	}
+	schedule, err := parseJobSchedule(job)
+	if err != nil {
+		return errors.New("invalid cron expression: " + err.Error())
+	}
+
+	// 过于频繁的任务需要显式确认，避免误配的* * * * * *压垮集群
+	if floor := time.Duration(config.GlobalConfig.MinCronInterval) * time.Second; floor > 0 && !job.AllowHighFrequency {
+		if interval := minFireInterval(schedule, time.Now()); interval > 0 && interval < floor {
+			return fmt.Errorf("cron expression fires every %s, more often than the minimum interval %s; set allowHighFrequency to confirm", interval, floor)
+		}
+	}
+
+	// 校验超时时间
+	if job.Timeout < 0 {
+		return errors.New("job timeout must not be negative")
+	}
+	if limit := config.GlobalConfig.MaxJobTimeout; limit > 0 && job.Timeout > limit {
+		return fmt.Errorf("job timeout must not exceed %d seconds", limit)
+	}
+
+	// 校验允许执行的时间段
+	for _, window := range job.AllowedWindows {
+		if err := window.Validate(); err != nil {
+			return errors.New("invalid allowed window: " + err.Error())
+		}
+	}
+	if job.DeferToWindow && len(job.AllowedWindows) == 0 {
+		return errors.New("deferToWindow requires allowedWindows")
+	}
+
+	// 校验说明和运行手册链接
+	if utf8.RuneCountInString(job.Description) > common.MaxJobDescriptionLength {
+		return fmt.Errorf("job description must not exceed %d characters", common.MaxJobDescriptionLength)
+	}
+	if job.RunbookURL != "" {
+		if u, err := url.Parse(job.RunbookURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return errors.New("runbookUrl must be an absolute http(s) URL")
+		}
+	}
+
+	// 注解原样保存，只限制数量和key
+	if len(job.Annotations) > common.MaxJobAnnotations {
+		return fmt.Errorf("at most %d annotations are allowed", common.MaxJobAnnotations)
+	}
+	for key := range job.Annotations {
+		if key == "" {
+			return errors.New("annotation key must not be empty")
+		}
+	}
+
+	// 校验前置条件
+	if len(job.Preconditions) > common.MaxPreconditions {
+		return fmt.Errorf("at most %d preconditions are allowed", common.MaxPreconditions)
+	}
+	for _, p := range job.Preconditions {
+		if err := p.Validate(); err != nil {
+			return errors.New("invalid precondition: " + err.Error())
+		}
+	}
+	if job.PreconditionRetries < 0 || job.PreconditionRetryDelay < 0 {
+		return errors.New("preconditionRetries and preconditionRetryDelay must not be negative")
+	}
+
+	// 校验外部调度来源，cron表达式作为来源不可用时的后备
+	if job.Schedule != nil {
+		if err := job.Schedule.Validate(); err != nil {
+			return errors.New("invalid schedule source: " + err.Error())
+		}
+	}
+
+	// 校验when条件，只能引用worker提供的变量
+	if job.When != "" {
+		if err := validateWhen(job.When); err != nil {
+			return errors.New("invalid when condition: " + err.Error())
+		}
+	}
+
+	// 校验失败通知路由
+	if job.Notify != nil {
+		if err := job.Notify.Validate(); err != nil {
+			return errors.New("invalid notify route: " + err.Error())
+		}
+	}
+
+	if job.ZoneFailoverDelay < 0 {
+		return errors.New("zoneFailoverDelay must not be negative")
+	}
+
+	// 校验互斥组
+	if len(job.ExclusionGroups) > common.MaxExclusionGroups {
+		return fmt.Errorf("at most %d exclusion groups are allowed", common.MaxExclusionGroups)
+	}
+	for _, group := range job.ExclusionGroups {
+		if group == "" || strings.Contains(group, "/") {
+			return errors.New("exclusion group must be non-empty and must not contain '/'")
+		}
+	}
+
+	// 执行期间持有任务锁时同一时间只有一个实例
+	if job.MaxInstances < 0 {
+		return errors.New("maxInstances must not be negative")
+	}
+	if job.MaxInstances > 1 && job.LockDuringRun {
+		return errors.New("maxInstances cannot be greater than 1 when lockDuringRun is enabled")
+	}
+
+	// 校验信号量名称，信号量是否存在由调用方检查
+	if len(job.Semaphores) > common.MaxJobSemaphores {
+		return fmt.Errorf("at most %d semaphores are allowed", common.MaxJobSemaphores)
+	}
+	for _, name := range job.Semaphores {
+		if name == "" || strings.Contains(name, "/") {
+			return errors.New("semaphore must be non-empty and must not contain '/'")
+		}
+	}
+
+	// 校验任务组，同组其他任务由checkGang检查
+	if job.GangTimeout < 0 {
+		return errors.New("gangTimeout must not be negative")
+	}
+	if strings.Contains(job.Gang, "/") {
+		return errors.New("job gang must not contain '/'")
+	}
+
+	// 校验命名空间
+	if strings.Contains(job.Namespace, "/") {
+		return errors.New("job namespace must not contain '/'")
+	}
+
+	return nil
+}
+
+// checkGang 检查任务与同组的其他任务是否在同一时间触发。任务组按组名和计划时间汇合，
+// cron表达式或时区不同、或由外部调度来源决定触发时间的成员永远等不到彼此
+func checkGang(job *common.Job, jobs []*common.Job) error {
+	if job.Schedule != nil {
+		return fmt.Errorf("jobs in gang %s cannot use a schedule source", job.Gang)
+	}
+
+	location, err := job.Location()
+	if err != nil {
+		return err
+	}
+
+	for _, other := range jobs {
+		if other.Gang != job.Gang || other.Name == job.Name {
+			continue
+		}
+		if other.Schedule != nil {
+			return fmt.Errorf("jobs in gang %s cannot use a schedule source, %s uses one", job.Gang, other.Name)
+		}
+		if other.CronExpr != job.CronExpr {
+			return fmt.Errorf("jobs in gang %s must share the same cron expression, %s uses %q", job.Gang, other.Name, other.CronExpr)
+		}
+		// 未设置时区的任务按worker本地时区触发，只与同样未设置时区的任务一致
+		otherLocation, err := other.Location()
+		if err != nil || otherLocation.String() != location.String() {
+			return fmt.Errorf("jobs in gang %s must share the same timezone, %s uses %q", job.Gang, other.Name, other.Timezone)
+		}
+	}
+	return nil
+}
+
 // deleteJob 删除任务
 func (s *Server) deleteJob(c *gin.Context) {
 	jobName := c.Param("name")
 
+	// 需要审批时只提交待审批变更
+	if s.requiresApproval(c) {
+		if _, err := s.jobMgr.GetJob(jobName); err != nil {
+			if errors.Is(err, common.ErrJobNotFound) {
+				failure(c, common.ApiJobNotExist, "job does not exist")
+			} else {
+				failure(c, common.ApiEtcdError, "failed to get job: "+err.Error())
+			}
+			return
+		}
+
+		s.submitChange(c, &common.PendingChange{
+			JobName: jobName,
+			Action:  common.ChangeActionDelete,
+		})
+		return
+	}
+
 	// 删除任务
 	if err := s.jobMgr.DeleteJob(jobName); err != nil {
 		if errors.Is(err, common.ErrJobNotFound) {
@@ -79,15 +383,118 @@ func (s *Server) listJobs(c *gin.Context) {
 	// 获取查询关键字
 	keyword := c.Query("keyword")
 
+	// 排序字段，为空时保持etcd中的顺序，sortBy与sort相同
+	sortBy := c.DefaultQuery("sortBy", c.Query("sort"))
+	if sortBy != "" && !validJobSort(sortBy) {
+		failure(c, common.ApiParamError, "sort must be one of name, updatedAt, lastRunTime, failureRate")
+		return
+	}
+	order := c.DefaultQuery("order", "asc")
+	if order != "asc" && order != "desc" {
+		failure(c, common.ApiParamError, "order must be asc or desc")
+		return
+	}
+
+	// 只返回指定的字段
+	fields, err := parseFields(c, common.Job{})
+	if err != nil {
+		failure(c, common.ApiParamError, "invalid fields: "+err.Error())
+		return
+	}
+
 	// 获取任务列表
-	jobs, err := s.jobMgr.SearchJobs(keyword)
+	jobs, version, err := s.jobMgr.SearchJobsWithVersion(keyword)
 	if err != nil {
 		s.logger.Error("failed to list jobs", zap.Error(err))
 		failure(c, common.ApiSystemError, "failed to list jobs: "+err.Error())
 		return
 	}
 
-	success(c, jobs)
+	// 任务集合没有变化时返回304，按执行情况排序时结果还取决于日志，不做条件请求
+	if !jobSortNeedsLogs(sortBy) && checkNotModified(c, `W/"jobs-`+version+`"`) {
+		return
+	}
+
+	if sortBy != "" {
+		// 按执行情况排序时统计最近days天的日志
+		var summaries map[string]*logmgr.JobRunSummary
+		if jobSortNeedsLogs(sortBy) {
+			days, _ := strconv.Atoi(c.DefaultQuery("days", "7"))
+			summaries, err = s.logMgr.GetRunSummaries(callerScope(c), days)
+			if err != nil {
+				s.logger.Error("failed to summarize job runs", zap.Error(err))
+				failure(c, common.ApiDbError, "failed to summarize job runs: "+err.Error())
+				return
+			}
+		}
+		sortJobs(jobs, sortBy, order == "desc", summaries)
+	}
+
+	// 携带分页参数时只返回排序后的一页和总数，否则返回全部任务
+	_, paged := c.GetQuery("page")
+	if _, ok := c.GetQuery("pageSize"); ok {
+		paged = true
+	}
+	if !paged {
+		projected, err := projectFields(jobs, fields)
+		if err != nil {
+			failure(c, common.ApiSystemError, "failed to project job fields: "+err.Error())
+			return
+		}
+		success(c, projected)
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", strconv.Itoa(common.DefaultPageSize)))
+	jobPage := jobmgr.PageJobs(jobs, page, pageSize)
+
+	projected, err := projectFields(jobPage.Jobs, fields)
+	if err != nil {
+		failure(c, common.ApiSystemError, "failed to project job fields: "+err.Error())
+		return
+	}
+
+	success(c, map[string]interface{}{
+		"jobs":  projected,
+		"total": jobPage.Total,
+		"page":  jobPage.Page,
+		"size":  jobPage.PageSize,
+	})
+}
+
+// watchJobs 等待任务定义变化，有变化或超时后返回，用于代替频繁轮询任务列表
+func (s *Server) watchJobs(c *gin.Context) {
+	fromRevision, err := strconv.ParseInt(c.DefaultQuery("fromRevision", "0"), 10, 64)
+	if err != nil || fromRevision < 0 {
+		failure(c, common.ApiParamError, "fromRevision must be a non-negative integer")
+		return
+	}
+
+	timeout, err := parseWatchTimeout(c)
+	if err != nil {
+		failure(c, common.ApiParamError, err.Error())
+		return
+	}
+
+	// 客户端断开时同时结束监听
+	ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+	defer cancel()
+
+	changes, err := s.jobMgr.WatchJobs(ctx, fromRevision)
+	if err != nil {
+		if errors.Is(err, common.ErrRevisionCompacted) {
+			failure(c, common.ApiCompacted, "fromRevision has been compacted, reload the job list")
+		} else {
+			s.logger.Error("failed to watch jobs",
+				zap.Int64("fromRevision", fromRevision),
+				zap.Error(err))
+			failure(c, common.ApiEtcdError, "failed to watch jobs: "+err.Error())
+		}
+		return
+	}
+
+	success(c, changes)
 }
 
 // getJob 获取任务详情
@@ -98,7 +505,12 @@ func (s *Server) getJob(c *gin.Context) {
 	job, err := s.jobMgr.GetJob(jobName)
 	if err != nil {
 		if errors.Is(err, common.ErrJobNotFound) {
-			failure(c, common.ApiJobNotExist, "job does not exist")
+			// 任务改过名时提示新名称
+			if rename, renameErr := s.jobMgr.GetRename(jobName); renameErr == nil {
+				failure(c, common.ApiJobNotExist, "job has been renamed to "+rename.NewName)
+			} else {
+				failure(c, common.ApiJobNotExist, "job does not exist")
+			}
 		} else {
 			s.logger.Error("failed to get job",
 				zap.String("jobName", jobName),
@@ -111,6 +523,102 @@ func (s *Server) getJob(c *gin.Context) {
 	success(c, job)
 }
 
+// getJobDiff 比较任务在两个etcd版本之间的定义，逐字段返回差异
+func (s *Server) getJobDiff(c *gin.Context) {
+	jobName := c.Param("name")
+
+	revisions := make([]int64, 2)
+	for i, param := range []string{"from", "to"} {
+		revision, err := strconv.ParseInt(c.DefaultQuery(param, "0"), 10, 64)
+		if err != nil || revision < 0 {
+			failure(c, common.ApiParamError, param+" must be a non-negative integer")
+			return
+		}
+		revisions[i] = revision
+	}
+
+	diff, err := s.jobMgr.DiffJob(jobName, revisions[0], revisions[1])
+	if err != nil {
+		switch {
+		case errors.Is(err, common.ErrJobNotFound):
+			failure(c, common.ApiJobNotExist, "job does not exist at the given revisions")
+		case errors.Is(err, common.ErrRevisionCompacted):
+			failure(c, common.ApiCompacted, "revision has been compacted, job history before it is no longer available")
+		case errors.Is(err, common.ErrFutureRevision):
+			failure(c, common.ApiParamError, err.Error())
+		default:
+			s.logger.Error("failed to diff job",
+				zap.String("jobName", jobName),
+				zap.Int64("from", revisions[0]),
+				zap.Int64("to", revisions[1]),
+				zap.Error(err))
+			failure(c, common.ApiEtcdError, "failed to diff job: "+err.Error())
+		}
+		return
+	}
+
+	success(c, diff)
+}
+
+// renameJobRequest 任务改名请求
+type renameJobRequest struct {
+	Name    string `json:"name" binding:"required"`    // 当前任务名
+	NewName string `json:"newName" binding:"required"` // 新任务名
+}
+
+// renameJob 任务改名，旧名称记入新任务的aliases
+func (s *Server) renameJob(c *gin.Context) {
+	var req renameJobRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		failure(c, common.ApiParamError, "invalid rename request: "+err.Error())
+		return
+	}
+	if req.NewName == req.Name {
+		failure(c, common.ApiParamError, "newName must differ from name")
+		return
+	}
+
+	// 需要审批时只提交待审批变更
+	if s.requiresApproval(c) {
+		if _, err := s.jobMgr.GetJob(req.Name); err != nil {
+			if errors.Is(err, common.ErrJobNotFound) {
+				failure(c, common.ApiJobNotExist, "job does not exist")
+			} else {
+				failure(c, common.ApiEtcdError, "failed to get job: "+err.Error())
+			}
+			return
+		}
+
+		s.submitChange(c, &common.PendingChange{
+			JobName: req.Name,
+			Action:  common.ChangeActionRename,
+			NewName: req.NewName,
+		})
+		return
+	}
+
+	job, err := s.jobMgr.RenameJob(req.Name, req.NewName, currentUser(c))
+	if err != nil {
+		switch {
+		case errors.Is(err, common.ErrJobNotFound):
+			failure(c, common.ApiJobNotExist, "job does not exist")
+		case errors.Is(err, common.ErrJobExists):
+			failure(c, common.ApiParamError, "a job named "+req.NewName+" already exists")
+		case errors.Is(err, common.ErrJobSaveConflict):
+			failure(c, common.ApiFailure, "job was modified during rename, please retry")
+		default:
+			s.logger.Error("failed to rename job",
+				zap.String("jobName", req.Name),
+				zap.String("newName", req.NewName),
+				zap.Error(err))
+			failure(c, common.ApiEtcdError, "failed to rename job: "+err.Error())
+		}
+		return
+	}
+
+	success(c, job)
+}
+
 // killJob 强制终止任务
 func (s *Server) killJob(c *gin.Context) {
 	jobName := c.Param("name")
@@ -127,6 +635,136 @@ func (s *Server) killJob(c *gin.Context) {
 	success(c, nil)
 }
 
+// runJob 立即触发一次任务执行，不受cron表达式影响，返回的runId可用于查询本次执行的日志。
+// 指定revision时执行该版本的任务定义（日志中的jobRevision），用于复现历史执行
+func (s *Server) runJob(c *gin.Context) {
+	jobName := c.Param("name")
+
+	revision, err := strconv.ParseInt(c.DefaultQuery("revision", "0"), 10, 64)
+	if err != nil || revision < 0 {
+		failure(c, common.ApiParamError, "revision must be a non-negative integer")
+		return
+	}
+
+	trigger, err := s.jobMgr.TriggerJobAtRevision(jobName, currentUser(c), revision)
+	if err != nil {
+		s.triggerFailure(c, jobName, err)
+		return
+	}
+
+	success(c, trigger)
+}
+
+// triggerFailure 返回手动触发失败的原因
+func (s *Server) triggerFailure(c *gin.Context, jobName string, err error) {
+	switch {
+	case errors.Is(err, common.ErrJobNotFound):
+		failure(c, common.ApiJobNotExist, "job does not exist")
+	case errors.Is(err, common.ErrJobDisabled):
+		failure(c, common.ApiParamError, "job is disabled, enable it before triggering")
+	case errors.Is(err, common.ErrJobRevisionNotFound), errors.Is(err, common.ErrFutureRevision):
+		failure(c, common.ApiParamError, "revision is not a saved definition of this job")
+	case errors.Is(err, common.ErrRevisionCompacted):
+		failure(c, common.ApiCompacted, "revision has been compacted, the definition is no longer available")
+	default:
+		s.logger.Error("failed to trigger job",
+			zap.String("jobName", jobName),
+			zap.Error(err))
+		failure(c, common.ApiEtcdError, "failed to trigger job: "+err.Error())
+	}
+}
+
+// getJobLock 获取任务锁的持有者和剩余时间，用于排查任务卡住时锁被谁持有
+func (s *Server) getJobLock(c *gin.Context) {
+	jobName := c.Param("name")
+
+	info, err := s.jobMgr.GetJobLock(jobName)
+	if err != nil {
+		s.logger.Error("failed to get job lock",
+			zap.String("jobName", jobName),
+			zap.Error(err))
+		failure(c, common.ApiEtcdError, "failed to get job lock: "+err.Error())
+		return
+	}
+
+	success(c, info)
+}
+
+// getJobProgress 获取运行中任务最近上报的进度
+func (s *Server) getJobProgress(c *gin.Context) {
+	jobName := c.Param("name")
+
+	progress, err := s.jobMgr.GetProgress(jobName)
+	if err != nil {
+		if errors.Is(err, common.ErrProgressNotFound) {
+			failure(c, common.ApiNotFound, "job is not running or has not reported progress")
+		} else {
+			s.logger.Error("failed to get job progress",
+				zap.String("jobName", jobName),
+				zap.Error(err))
+			failure(c, common.ApiEtcdError, "failed to get job progress: "+err.Error())
+		}
+		return
+	}
+
+	success(c, progress)
+}
+
+// getJobPlacement 说明任务最近一次触发时哪些worker参与了抢锁、由谁执行、其他worker被排除的原因
+func (s *Server) getJobPlacement(c *gin.Context) {
+	jobName := c.Param("name")
+
+	placement, err := s.jobMgr.GetPlacement(jobName)
+	if err != nil {
+		s.logger.Error("failed to get job placement",
+			zap.String("jobName", jobName),
+			zap.Error(err))
+		failure(c, common.ApiEtcdError, "failed to get job placement: "+err.Error())
+		return
+	}
+
+	success(c, placement)
+}
+
+// getJobCheckpoint 获取任务上次失败执行留下的检查点
+func (s *Server) getJobCheckpoint(c *gin.Context) {
+	jobName := c.Param("name")
+
+	checkpoint, err := s.jobMgr.GetCheckpoint(jobName)
+	if err != nil {
+		if errors.Is(err, common.ErrCheckpointNotFound) {
+			failure(c, common.ApiNotFound, "job has no checkpoint")
+		} else {
+			s.logger.Error("failed to get job checkpoint",
+				zap.String("jobName", jobName),
+				zap.Error(err))
+			failure(c, common.ApiEtcdError, "failed to get job checkpoint: "+err.Error())
+		}
+		return
+	}
+
+	success(c, checkpoint)
+}
+
+// deleteJobCheckpoint 删除任务的检查点，下次执行从头开始
+func (s *Server) deleteJobCheckpoint(c *gin.Context) {
+	jobName := c.Param("name")
+
+	if err := s.jobMgr.DeleteCheckpoint(jobName); err != nil {
+		if errors.Is(err, common.ErrCheckpointNotFound) {
+			failure(c, common.ApiNotFound, "job has no checkpoint")
+		} else {
+			s.logger.Error("failed to delete job checkpoint",
+				zap.String("jobName", jobName),
+				zap.Error(err))
+			failure(c, common.ApiEtcdError, "failed to delete job checkpoint: "+err.Error())
+		}
+		return
+	}
+
+	success(c, nil)
+}
+
 // disableJob 禁用任务
 func (s *Server) disableJob(c *gin.Context) {
 	jobName := c.Param("name")
@@ -166,3 +804,80 @@ func (s *Server) enableJob(c *gin.Context) {
 
 	success(c, nil)
 }
+
+// zombieCleanupRequest 清理孤立key的请求
+type zombieCleanupRequest struct {
+	DryRun bool `json:"dryRun"` // 只报告孤立的key，不删除
+}
+
+// cleanupZombies 清理没有租约或引用已删除任务的key，dryRun时只返回扫描结果
+func (s *Server) cleanupZombies(c *gin.Context) {
+	if !isAdmin(c) {
+		failure(c, common.ApiForbidden, "only admins can clean up zombie keys")
+		return
+	}
+
+	var req zombieCleanupRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			failure(c, common.ApiParamError, "invalid zombie cleanup request: "+err.Error())
+			return
+		}
+	}
+
+	report, err := s.jobMgr.CleanupZombies(req.DryRun)
+	if err != nil {
+		failure(c, common.ApiEtcdError, "failed to clean up zombie keys: "+err.Error())
+		return
+	}
+
+	s.logger.Info("zombie key cleanup requested",
+		zap.String("user", currentUser(c)),
+		zap.Bool("dryRun", report.DryRun),
+		zap.Int("zombies", len(report.Zombies)),
+		zap.Int("purged", report.Purged))
+
+	success(c, report)
+}
+
+// validateWhen 解析when条件并检查引用的变量都在common.WhenVariables中
+func validateWhen(when string) error {
+	e, err := expr.Parse(when)
+	if err != nil {
+		return err
+	}
+	for _, name := range e.Identifiers() {
+		if _, ok := common.WhenVariables[name]; !ok {
+			return fmt.Errorf("unknown variable %s", name)
+		}
+	}
+	return nil
+}
+
+// validateExecutor 校验任务的执行器类型和配置。插件任务必须有在线worker安装了该插件，
+// 并通过每个worker上报的插件声明的校验，保证由哪个worker执行都能接受这份配置
+func validateExecutor(job *common.Job, manifests func(jobType string) map[string]*common.ExecutorManifest) error {
+	if job.ExecutorType() == common.ExecutorShell {
+		if len(job.Config) > 0 {
+			return fmt.Errorf("config is only supported by executor plugins")
+		}
+		return nil
+	}
+
+	providers := manifests(job.Type)
+	if len(providers) == 0 {
+		return fmt.Errorf("no online worker provides executor type %s", job.Type)
+	}
+
+	workers := make([]string, 0, len(providers))
+	for id := range providers {
+		workers = append(workers, id)
+	}
+	sort.Strings(workers)
+	for _, id := range workers {
+		if err := providers[id].ValidateJob(job); err != nil {
+			return fmt.Errorf("%v (plugin on worker %s)", err, id)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package api
+
+import (
+	"sort"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+	"github.com/fyerfyer/scheduler-refactor/master/logmgr"
+)
+
+// 任务列表的排序字段
+const (
+	jobSortName        = "name"        // 按任务名
+	jobSortUpdatedAt   = "updatedAt"   // 按更新时间
+	jobSortLastRunTime = "lastRunTime" // 按最近一次执行时间
+	jobSortFailureRate = "failureRate" // 按失败率
+)
+
+// validJobSort 判断排序字段是否合法
+func validJobSort(by string) bool {
+	switch by {
+	case jobSortName, jobSortUpdatedAt, jobSortLastRunTime, jobSortFailureRate:
+		return true
+	}
+	return false
+}
+
+// jobSortNeedsLogs 判断排序是否需要执行日志
+func jobSortNeedsLogs(by string) bool {
+	return by == jobSortLastRunTime || by == jobSortFailureRate
+}
+
+// sortJobs 按指定字段排序任务，值相同时按任务名升序，没有执行记录的任务视为0
+func sortJobs(jobs []*common.Job, by string, desc bool, summaries map[string]*logmgr.JobRunSummary) {
+	value := func(job *common.Job) float64 {
+		switch by {
+		case jobSortUpdatedAt:
+			return float64(job.UpdatedAt)
+		case jobSortLastRunTime:
+			if summary, ok := summaries[job.Name]; ok {
+				return float64(summary.LastRunTime)
+			}
+		case jobSortFailureRate:
+			if summary, ok := summaries[job.Name]; ok {
+				return summary.FailureRate
+			}
+		}
+		return 0
+	}
+
+	sort.SliceStable(jobs, func(i, j int) bool {
+		if by != jobSortName {
+			if vi, vj := value(jobs[i]), value(jobs[j]); vi != vj {
+				if desc {
+					return vi > vj
+				}
+				return vi < vj
+			}
+		} else if desc {
+			return jobs[i].Name > jobs[j].Name
+		}
+		return jobs[i].Name < jobs[j].Name
+	})
+}
@@ -4,19 +4,44 @@ import (
 	"errors"
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
+	"io"
 	"strconv"
+	"time"
 
 	"github.com/fyerfyer/scheduler-refactor/common"
+	"github.com/fyerfyer/scheduler-refactor/config"
+	"github.com/fyerfyer/scheduler-refactor/master/digest"
+	"github.com/fyerfyer/scheduler-refactor/master/logmgr"
 )
 
+// jobAliases 请求指定includeAliases=true时返回任务的曾用名，用于一并查询改名前的日志
+func (s *Server) jobAliases(c *gin.Context, jobName string) []string {
+	if jobName == "" || c.Query("includeAliases") != "true" {
+		return nil
+	}
+
+	job, err := s.jobMgr.GetJob(jobName)
+	if err != nil {
+		return nil
+	}
+	return job.Aliases
+}
+
 // listJobLogs 获取任务日志列表
 func (s *Server) listJobLogs(c *gin.Context) {
 	jobName := c.Query("jobName")
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", strconv.Itoa(common.DefaultPageSize)))
 
+	// 只返回指定的字段，避免大段输出占用带宽
+	fields, err := parseFields(c, common.JobLog{})
+	if err != nil {
+		failure(c, common.ApiParamError, "invalid fields: "+err.Error())
+		return
+	}
+
 	// 获取日志
-	logs, total, err := s.logMgr.ListLogs(jobName, page, pageSize)
+	logs, total, err := s.logMgr.ListLogs(jobName, callerScope(c), page, pageSize, s.jobAliases(c, jobName)...)
 	if err != nil {
 		s.logger.Error("failed to list job logs",
 			zap.String("jobName", jobName),
@@ -25,9 +50,15 @@ func (s *Server) listJobLogs(c *gin.Context) {
 		return
 	}
 
+	projected, err := projectFields(logs, fields)
+	if err != nil {
+		failure(c, common.ApiSystemError, "failed to project log fields: "+err.Error())
+		return
+	}
+
 	// 构建分页数据
 	result := map[string]interface{}{
-		"logs":  logs,
+		"logs":  projected,
 		"total": total,
 		"page":  page,
 		"size":  pageSize,
@@ -41,7 +72,7 @@ func (s *Server) getJobLog(c *gin.Context) {
 	jobName := c.Param("name")
 
 	// 获取最新日志
-	log, err := s.logMgr.GetJobLog(jobName)
+	log, err := s.logMgr.GetJobLog(jobName, callerScope(c), s.jobAliases(c, jobName)...)
 	if err != nil {
 		if errors.Is(err, common.ErrJobNotFound) {
 			failure(c, common.ApiJobNotExist, "no logs found for job")
@@ -57,13 +88,112 @@ func (s *Server) getJobLog(c *gin.Context) {
 	success(c, log)
 }
 
+// streamJobLogs 以SSE推送任务新结束的执行日志，用于界面实时跟踪任务执行。
+// since为Unix秒，默认只推送连接之后结束的执行
+func (s *Server) streamJobLogs(c *gin.Context) {
+	jobName := c.Param("name")
+
+	since := time.Now()
+	if value := c.Query("since"); value != "" {
+		timestamp, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || time.Since(time.Unix(timestamp, 0)) > maxLogStreamSince {
+			failure(c, common.ApiParamError, "since must be a unix timestamp within the last "+maxLogStreamSince.String())
+			return
+		}
+		since = time.Unix(timestamp, 0)
+	}
+
+	follower := s.logMgr.FollowLogs(jobName, callerScope(c), since, s.jobAliases(c, jobName)...)
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no") // 避免反向代理缓冲事件
+
+	poll := time.NewTicker(logStreamInterval)
+	defer poll.Stop()
+	keepalive := time.NewTicker(logStreamKeepalive)
+	defer keepalive.Stop()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case <-poll.C:
+			logs, err := follower.Next()
+			if err != nil {
+				// 日志存储暂时不可用时保持连接，下次轮询重试
+				c.SSEvent("error", err.Error())
+				return true
+			}
+			for _, log := range logs {
+				c.SSEvent("log", log)
+			}
+		case <-keepalive.C:
+			c.SSEvent("ping", time.Now().UnixMilli())
+		}
+		return true
+	})
+}
+
+// replayRun 按历史执行所用的任务定义版本重新执行一次，新执行的日志通过replayOf关联原执行，
+// 返回的runId可用于对比两次执行
+func (s *Server) replayRun(c *gin.Context) {
+	runID := c.Param("runId")
+
+	run, err := s.logMgr.GetRun(runID, callerScope(c))
+	if err != nil {
+		if errors.Is(err, common.ErrRunNotFound) {
+			failure(c, common.ApiNotFound, "run does not exist")
+		} else {
+			failure(c, common.ApiDbError, "failed to get run: "+err.Error())
+		}
+		return
+	}
+
+	trigger, err := s.jobMgr.ReplayRun(run, currentUser(c))
+	if err != nil {
+		if errors.Is(err, common.ErrRunNotReplayable) {
+			failure(c, common.ApiParamError, "run has no recorded job revision or is a canary or experiment run")
+		} else {
+			s.triggerFailure(c, run.JobName, err)
+		}
+		return
+	}
+
+	success(c, trigger)
+}
+
+// listJobRuns 按开始时间倒序分页获取任务的执行记录
+func (s *Server) listJobRuns(c *gin.Context) {
+	jobName := c.Param("name")
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", strconv.Itoa(common.DefaultPageSize)))
+
+	runs, total, err := s.logMgr.ListRuns(jobName, callerScope(c), page, pageSize, s.jobAliases(c, jobName)...)
+	if err != nil {
+		s.logger.Error("failed to list job runs",
+			zap.String("jobName", jobName),
+			zap.Error(err))
+		failure(c, common.ApiDbError, "failed to list job runs: "+err.Error())
+		return
+	}
+
+	result := map[string]interface{}{
+		"runs":  runs,
+		"total": total,
+		"page":  page,
+		"size":  pageSize,
+	}
+
+	success(c, result)
+}
+
 // getJobLogStats 获取任务日志统计
 func (s *Server) getJobLogStats(c *gin.Context) {
 	jobName := c.Param("name")
 	days, _ := strconv.Atoi(c.DefaultQuery("days", "7"))
 
 	// 获取统计信息
-	stats, err := s.logMgr.GetLogStatistics(jobName, days)
+	stats, err := s.logMgr.GetLogStatistics(jobName, callerScope(c), days, s.jobAliases(c, jobName)...)
 	if err != nil {
 		s.logger.Error("failed to get job log statistics",
 			zap.String("jobName", jobName),
@@ -74,3 +204,184 @@ func (s *Server) getJobLogStats(c *gin.Context) {
 
 	success(c, stats)
 }
+
+// driftGrace 统计偏移时忽略最近的触发，这些触发可能仍在执行、日志尚未写入
+const driftGrace = time.Minute
+
+// getJobDrift 对比任务最近一天按cron表达式应触发的时间与实际触发时间
+func (s *Server) getJobDrift(c *gin.Context) {
+	jobName := c.Param("name")
+
+	job, err := s.jobMgr.GetJob(jobName)
+	if err != nil {
+		if errors.Is(err, common.ErrJobNotFound) {
+			failure(c, common.ApiJobNotExist, "job does not exist")
+		} else {
+			failure(c, common.ApiEtcdError, "failed to get job: "+err.Error())
+		}
+		return
+	}
+	schedule, err := parseJobSchedule(job)
+	if err != nil {
+		failure(c, common.ApiParamError, "invalid cron expression: "+err.Error())
+		return
+	}
+
+	end := time.Now().Add(-driftGrace)
+	start := end.AddDate(0, 0, -1)
+	if created := time.Unix(job.CreatedAt, 0); job.CreatedAt > 0 && created.After(start) {
+		start = created
+	}
+
+	var expected []int64
+	for next := schedule.Next(start.Add(-time.Second)); !next.IsZero() && !next.After(end); next = schedule.Next(next) {
+		expected = append(expected, next.Unix())
+	}
+
+	report, err := s.logMgr.GetDriftReport(jobName, callerScope(c), start, end, expected)
+	if err != nil {
+		s.logger.Error("failed to build drift report",
+			zap.String("jobName", jobName),
+			zap.Error(err))
+		failure(c, common.ApiDbError, "failed to build drift report: "+err.Error())
+		return
+	}
+
+	success(c, report)
+}
+
+// getJobHistory 获取任务按天汇总的长期统计
+func (s *Server) getJobHistory(c *gin.Context) {
+	jobName := c.Param("name")
+	days, _ := strconv.Atoi(c.DefaultQuery("days", "90"))
+
+	history, err := s.logMgr.GetJobHistory(jobName, callerScope(c), days, s.jobAliases(c, jobName)...)
+	if err != nil {
+		s.logger.Error("failed to get job history",
+			zap.String("jobName", jobName),
+			zap.Error(err))
+		failure(c, common.ApiDbError, "failed to get job history: "+err.Error())
+		return
+	}
+
+	success(c, history)
+}
+
+// getExperimentReport 获取任务实验命令与当前命令的对比报告
+func (s *Server) getExperimentReport(c *gin.Context) {
+	jobName := c.Param("name")
+	days, _ := strconv.Atoi(c.DefaultQuery("days", "7"))
+
+	report, err := s.logMgr.GetExperimentReport(jobName, callerScope(c), days)
+	if err != nil {
+		s.logger.Error("failed to build experiment report",
+			zap.String("jobName", jobName),
+			zap.Error(err))
+		failure(c, common.ApiDbError, "failed to build experiment report: "+err.Error())
+		return
+	}
+
+	success(c, report)
+}
+
+// cleanupRequest 按需清理日志的请求
+type cleanupRequest struct {
+	RetentionDays int  `json:"retentionDays"` // 保留天数，默认30天
+	DryRun        bool `json:"dryRun"`        // 只统计将要删除的日志数量
+}
+
+// cleanupLogs 按需清理过期日志，dryRun时只返回将要删除的数量
+func (s *Server) cleanupLogs(c *gin.Context) {
+	if !isAdmin(c) {
+		failure(c, common.ApiForbidden, "only admins can clean up logs")
+		return
+	}
+
+	var req cleanupRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			failure(c, common.ApiParamError, "invalid cleanup request: "+err.Error())
+			return
+		}
+	}
+	if req.RetentionDays < 0 {
+		failure(c, common.ApiParamError, "retentionDays must not be negative")
+		return
+	}
+
+	result, err := s.logMgr.CleanupLogs(req.RetentionDays, req.DryRun)
+	if err != nil {
+		failure(c, common.ApiDbError, "failed to clean up logs: "+err.Error())
+		return
+	}
+
+	s.logger.Info("log cleanup requested",
+		zap.String("user", currentUser(c)),
+		zap.Int("retentionDays", result.RetentionDays),
+		zap.Bool("dryRun", result.DryRun),
+		zap.Int64("count", result.Count))
+
+	success(c, result)
+}
+
+// getCostReport 获取按负责人或命名空间分组的月度成本报表
+func (s *Server) getCostReport(c *gin.Context) {
+	month := time.Now()
+	if value := c.Query("month"); value != "" {
+		parsed, err := time.ParseInLocation("2006-01", value, time.Local)
+		if err != nil {
+			failure(c, common.ApiParamError, "invalid month, expected YYYY-MM")
+			return
+		}
+		month = parsed
+	}
+
+	groupBy := c.DefaultQuery("groupBy", logmgr.CostGroupByOwner)
+	if groupBy != logmgr.CostGroupByOwner && groupBy != logmgr.CostGroupByNamespace {
+		failure(c, common.ApiParamError, "groupBy must be owner or namespace")
+		return
+	}
+
+	prices := logmgr.CostPrices{
+		CPUHour: config.GlobalConfig.CostPerCPUHour,
+		GBHour:  config.GlobalConfig.CostPerGBHour,
+	}
+
+	report, err := s.logMgr.GetCostReport(month, groupBy, prices, callerScope(c))
+	if err != nil {
+		s.logger.Error("failed to build cost report",
+			zap.String("month", month.Format("2006-01")),
+			zap.Error(err))
+		failure(c, common.ApiDbError, "failed to build cost report: "+err.Error())
+		return
+	}
+
+	success(c, report)
+}
+
+// getDigest 预览从上次发送到现在的每周摘要，只返回调用方可访问的命名空间
+func (s *Server) getDigest(c *gin.Context) {
+	if s.digestMgr == nil {
+		failure(c, common.ApiFailure, "weekly digest is not available")
+		return
+	}
+
+	result, err := s.digestMgr.Build()
+	if err != nil {
+		s.logger.Error("failed to build weekly digest", zap.Error(err))
+		failure(c, common.ApiSystemError, "failed to build weekly digest: "+err.Error())
+		return
+	}
+
+	scope := callerScope(c)
+	namespace := c.Query("namespace")
+	namespaces := make([]*digest.NamespaceDigest, 0, len(result.Namespaces))
+	for _, ns := range result.Namespaces {
+		if (namespace == "" || ns.Namespace == namespace) && scope.Allows(ns.Namespace, "") {
+			namespaces = append(namespaces, ns)
+		}
+	}
+	result.Namespaces = namespaces
+
+	success(c, result)
+}
@@ -0,0 +1,103 @@
+package api
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+)
+
+// namespaceSettingsAvailable 检查是否设置了命名空间设置管理器，未设置时返回错误
+func (s *Server) namespaceSettingsAvailable(c *gin.Context) bool {
+	if s.nsMgr == nil {
+		failure(c, common.ApiFailure, "namespace settings are not available")
+		return false
+	}
+	return true
+}
+
+// listNamespaceSettings 获取所有命名空间设置
+func (s *Server) listNamespaceSettings(c *gin.Context) {
+	if !s.namespaceSettingsAvailable(c) {
+		return
+	}
+
+	list, err := s.nsMgr.ListSettings()
+	if err != nil {
+		failure(c, common.ApiEtcdError, "failed to list namespace settings: "+err.Error())
+		return
+	}
+
+	success(c, list)
+}
+
+// getNamespaceSettings 获取命名空间设置
+func (s *Server) getNamespaceSettings(c *gin.Context) {
+	if !s.namespaceSettingsAvailable(c) {
+		return
+	}
+
+	settings, err := s.nsMgr.GetSettings(c.Param("name"))
+	if err != nil {
+		if errors.Is(err, common.ErrNamespaceSettingsNotFound) {
+			failure(c, common.ApiNotFound, "namespace settings do not exist")
+		} else {
+			failure(c, common.ApiEtcdError, "failed to get namespace settings: "+err.Error())
+		}
+		return
+	}
+
+	success(c, settings)
+}
+
+// saveNamespaceSettings 保存命名空间设置
+func (s *Server) saveNamespaceSettings(c *gin.Context) {
+	if !isAdmin(c) {
+		failure(c, common.ApiForbidden, "only admins can change namespace settings")
+		return
+	}
+	if !s.namespaceSettingsAvailable(c) {
+		return
+	}
+
+	var settings common.NamespaceSettings
+	if err := c.ShouldBindJSON(&settings); err != nil {
+		failure(c, common.ApiParamError, "invalid namespace settings: "+err.Error())
+		return
+	}
+	settings.UpdatedBy = currentUser(c)
+
+	if err := s.nsMgr.SaveSettings(&settings); err != nil {
+		if errors.Is(err, common.ErrInvalidNamespaceSettings) {
+			failure(c, common.ApiParamError, err.Error())
+		} else {
+			failure(c, common.ApiEtcdError, "failed to save namespace settings: "+err.Error())
+		}
+		return
+	}
+
+	success(c, settings)
+}
+
+// deleteNamespaceSettings 删除命名空间设置
+func (s *Server) deleteNamespaceSettings(c *gin.Context) {
+	if !isAdmin(c) {
+		failure(c, common.ApiForbidden, "only admins can change namespace settings")
+		return
+	}
+	if !s.namespaceSettingsAvailable(c) {
+		return
+	}
+
+	if err := s.nsMgr.DeleteSettings(c.Param("name")); err != nil {
+		if errors.Is(err, common.ErrNamespaceSettingsNotFound) {
+			failure(c, common.ApiNotFound, "namespace settings do not exist")
+		} else {
+			failure(c, common.ApiEtcdError, "failed to delete namespace settings: "+err.Error())
+		}
+		return
+	}
+
+	success(c, nil)
+}
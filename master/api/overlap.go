@@ -0,0 +1,106 @@
+package api
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+)
+
+// minOverlapRuns 判断执行重叠至少需要的执行次数，避免个别慢执行造成误报
+const minOverlapRuns = 3
+
+// overlapWarning 触发间隔短于平均执行时长的任务，每次执行都会赶上下一次触发
+type overlapWarning struct {
+	JobName     string  `json:"jobName"`     // 任务名称
+	Interval    float64 `json:"interval"`    // 最小触发间隔(秒)
+	AvgDuration float64 `json:"avgDuration"` // 最近的平均执行时长(秒)
+	Runs        int     `json:"runs"`        // 统计的执行次数
+}
+
+// String 格式化为一行告警信息
+func (w *overlapWarning) String() string {
+	return fmt.Sprintf("job %s fires every %.0fs but its runs take %.1fs on average, so every run overlaps the next trigger",
+		w.JobName, w.Interval, w.AvgDuration)
+}
+
+// checkOverlap 根据最近的执行情况检查任务是否总会与下次触发重叠，不重叠、数据不足或按外部来源调度时返回nil
+func checkOverlap(job *common.Job, runs int, avgDuration float64, now time.Time) *overlapWarning {
+	if runs < minOverlapRuns || avgDuration <= 0 || job.Schedule != nil {
+		return nil
+	}
+
+	schedule, err := parseJobSchedule(job)
+	if err != nil {
+		return nil
+	}
+	interval := minFireInterval(schedule, now).Seconds()
+	if interval <= 0 || interval >= avgDuration {
+		return nil
+	}
+
+	return &overlapWarning{
+		JobName:     job.Name,
+		Interval:    interval,
+		AvgDuration: avgDuration,
+		Runs:        runs,
+	}
+}
+
+// warnOverlap 保存任务时按该任务最近7天的执行情况检查重叠，通过Warning响应头提示，不阻止保存
+func (s *Server) warnOverlap(c *gin.Context, job *common.Job) {
+	stats, err := s.logMgr.GetLogStatistics(job.Name, callerScope(c), 7, job.Aliases...)
+	if err != nil {
+		s.logger.Debug("failed to load job statistics for overlap check",
+			zap.String("jobName", job.Name),
+			zap.Error(err))
+		return
+	}
+
+	runs, _ := stats["totalCount"].(int)
+	avgDuration, _ := stats["avgDuration"].(float64)
+	if warning := checkOverlap(job, runs, avgDuration, time.Now()); warning != nil {
+		c.Writer.Header().Add("Warning", `299 - `+strconv.Quote(warning.String()))
+	}
+}
+
+// listOverlaps 列出触发间隔短于最近平均执行时长的任务
+func (s *Server) listOverlaps(c *gin.Context) {
+	days, err := strconv.Atoi(c.DefaultQuery("days", "7"))
+	if err != nil || days <= 0 {
+		failure(c, common.ApiParamError, "days must be a positive integer")
+		return
+	}
+
+	jobs, err := s.jobMgr.ListJobs()
+	if err != nil {
+		s.logger.Error("failed to list jobs", zap.Error(err))
+		failure(c, common.ApiEtcdError, "failed to list jobs: "+err.Error())
+		return
+	}
+
+	summaries, err := s.logMgr.GetRunSummaries(callerScope(c), days)
+	if err != nil {
+		s.logger.Error("failed to summarize job runs", zap.Error(err))
+		failure(c, common.ApiDbError, "failed to summarize job runs: "+err.Error())
+		return
+	}
+
+	now := time.Now()
+	warnings := make([]*overlapWarning, 0)
+	for _, job := range jobs {
+		summary, ok := summaries[job.Name]
+		if job.Disabled || !ok {
+			continue
+		}
+		if warning := checkOverlap(job, summary.Runs, summary.AvgDuration, now); warning != nil {
+			warnings = append(warnings, warning)
+		}
+	}
+
+	success(c, warnings)
+}
@@ -0,0 +1,101 @@
+package api
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+	"github.com/fyerfyer/scheduler-refactor/pkg/policy"
+)
+
+// listPolicyRules 获取命令策略规则列表
+func (s *Server) listPolicyRules(c *gin.Context) {
+	rules, err := s.policyMgr.ListRules()
+	if err != nil {
+		s.logger.Error("failed to list policy rules", zap.Error(err))
+		failure(c, common.ApiEtcdError, "failed to list policy rules: "+err.Error())
+		return
+	}
+
+	success(c, rules)
+}
+
+// savePolicyRule 保存命令策略规则
+func (s *Server) savePolicyRule(c *gin.Context) {
+	if !isAdmin(c) {
+		failure(c, common.ApiForbidden, "only admins can change policy rules")
+		return
+	}
+
+	var rule policy.Rule
+	if err := c.ShouldBindJSON(&rule); err != nil {
+		failure(c, common.ApiParamError, "invalid policy rule: "+err.Error())
+		return
+	}
+
+	if err := s.policyMgr.SaveRule(&rule); err != nil {
+		if errors.Is(err, common.ErrInvalidPolicyRule) {
+			failure(c, common.ApiParamError, err.Error())
+		} else {
+			s.logger.Error("failed to save policy rule",
+				zap.String("rule", rule.Name),
+				zap.Error(err))
+			failure(c, common.ApiEtcdError, "failed to save policy rule: "+err.Error())
+		}
+		return
+	}
+
+	success(c, rule)
+}
+
+// deletePolicyRule 删除命令策略规则
+func (s *Server) deletePolicyRule(c *gin.Context) {
+	if !isAdmin(c) {
+		failure(c, common.ApiForbidden, "only admins can change policy rules")
+		return
+	}
+
+	name := c.Param("name")
+
+	if err := s.policyMgr.DeleteRule(name); err != nil {
+		if errors.Is(err, common.ErrPolicyRuleNotFound) {
+			failure(c, common.ApiParamError, "policy rule does not exist")
+		} else {
+			failure(c, common.ApiEtcdError, "failed to delete policy rule: "+err.Error())
+		}
+		return
+	}
+
+	success(c, nil)
+}
+
+// checkPolicy 用当前规则试算命令，不记录审计
+func (s *Server) checkPolicy(c *gin.Context) {
+	var req struct {
+		Command string `json:"command"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || req.Command == "" {
+		failure(c, common.ApiParamError, "command is required")
+		return
+	}
+
+	decision, err := s.policyMgr.Evaluate(req.Command)
+	if err != nil {
+		failure(c, common.ApiEtcdError, "failed to evaluate command policy: "+err.Error())
+		return
+	}
+
+	success(c, decision)
+}
+
+// listPolicyRejections 获取最近被策略拒绝的记录
+func (s *Server) listPolicyRejections(c *gin.Context) {
+	rejections, err := s.policyMgr.ListRejections()
+	if err != nil {
+		failure(c, common.ApiEtcdError, "failed to list policy rejections: "+err.Error())
+		return
+	}
+
+	success(c, rejections)
+}
@@ -0,0 +1,172 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+	"github.com/fyerfyer/scheduler-refactor/config"
+)
+
+// viewerActions viewer可以调用的非GET接口，它们不修改任何数据
+var viewerActions = map[string]bool{
+	"/api/v1/policy/check": true,
+	"/api/v1/job/batchGet": true,
+	"/api/v1/auth/token":   true,
+}
+
+// rbacGuard 角色中间件，设置了角色管理器时确定调用方的角色：etcd中的分配优先，
+// 其次为认证通过的凭证中的角色，都没有时为默认角色。查询接口需要viewer，其余接口需要operator
+func (s *Server) rbacGuard() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if s.roleMgr == nil {
+			c.Next()
+			return
+		}
+
+		role := s.resolveRole(c)
+		c.Set(ctxKeyRole, role)
+
+		required := common.RoleOperator
+		switch {
+		case c.Request.Method == http.MethodGet, c.Request.Method == http.MethodHead, c.Request.Method == http.MethodOptions:
+			required = common.RoleViewer
+		case viewerActions[c.FullPath()]:
+			required = common.RoleViewer
+		}
+
+		if !checkRole(c, role, required) {
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// requireRole 要求调用方的角色不低于role，只在设置了角色管理器时生效
+func (s *Server) requireRole(role string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if s.roleMgr != nil && !checkRole(c, c.GetString(ctxKeyRole), role) {
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// resolveRole 确定调用方的角色，无法确定时返回空字符串
+func (s *Server) resolveRole(c *gin.Context) string {
+	if user := currentUser(c); user != "" {
+		if role, exists := s.roleMgr.Role(user); exists {
+			return role
+		}
+	}
+	if role := credentialRole(c); common.ValidRole(role) {
+		return role
+	}
+	return config.GlobalConfig.RBACDefaultRole
+}
+
+// credentialRole 认证通过的API密钥或JWT中的角色，没有经过认证时请求头中的X-Role不可信，返回空字符串
+func credentialRole(c *gin.Context) string {
+	if c.GetString(ctxKeyAuthMethod) == "" {
+		return ""
+	}
+	return c.GetString(ctxKeyRole)
+}
+
+// checkRole 检查角色，权限不足时返回1006
+func checkRole(c *gin.Context, role, required string) bool {
+	if common.RoleAtLeast(role, required) {
+		return true
+	}
+
+	if role == "" {
+		role = "anonymous"
+	}
+	failure(c, common.ApiForbidden, "role "+role+" is not allowed, "+required+" is required")
+	return false
+}
+
+// getCurrentIdentity 获取调用方的身份和生效的角色
+func (s *Server) getCurrentIdentity(c *gin.Context) {
+	success(c, gin.H{
+		"user":       currentUser(c),
+		"role":       c.GetString(ctxKeyRole),
+		"namespaces": c.GetStringSlice(ctxKeyNamespaces),
+		"rbac":       s.roleMgr != nil,
+	})
+}
+
+// rolesAvailable 检查是否开启了角色控制，未开启时返回错误
+func (s *Server) rolesAvailable(c *gin.Context) bool {
+	if s.roleMgr == nil {
+		failure(c, common.ApiFailure, "role based access control is not enabled")
+		return false
+	}
+	if !isAdmin(c) {
+		failure(c, common.ApiForbidden, "only admins can manage roles")
+		return false
+	}
+	return true
+}
+
+// listRoleBindings 获取所有角色分配
+func (s *Server) listRoleBindings(c *gin.Context) {
+	if !s.rolesAvailable(c) {
+		return
+	}
+
+	bindings, err := s.roleMgr.ListBindings()
+	if err != nil {
+		failure(c, common.ApiEtcdError, "failed to list role bindings: "+err.Error())
+		return
+	}
+
+	success(c, bindings)
+}
+
+// saveRoleBinding 为用户分配角色
+func (s *Server) saveRoleBinding(c *gin.Context) {
+	if !s.rolesAvailable(c) {
+		return
+	}
+
+	var binding common.RoleBinding
+	if err := c.ShouldBindJSON(&binding); err != nil {
+		failure(c, common.ApiParamError, "invalid role binding: "+err.Error())
+		return
+	}
+	binding.UpdatedBy = currentUser(c)
+
+	if err := s.roleMgr.SaveBinding(&binding); err != nil {
+		if errors.Is(err, common.ErrInvalidRoleBinding) {
+			failure(c, common.ApiParamError, err.Error())
+		} else {
+			failure(c, common.ApiEtcdError, "failed to save role binding: "+err.Error())
+		}
+		return
+	}
+
+	success(c, &binding)
+}
+
+// deleteRoleBinding 删除用户的角色分配
+func (s *Server) deleteRoleBinding(c *gin.Context) {
+	if !s.rolesAvailable(c) {
+		return
+	}
+
+	if err := s.roleMgr.DeleteBinding(c.Param("user")); err != nil {
+		if errors.Is(err, common.ErrRoleBindingNotFound) {
+			failure(c, common.ApiNotFound, "role binding does not exist")
+		} else {
+			failure(c, common.ApiEtcdError, "failed to delete role binding: "+err.Error())
+		}
+		return
+	}
+
+	success(c, nil)
+}
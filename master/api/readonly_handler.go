@@ -0,0 +1,73 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+)
+
+// readOnlyAllowed 只读模式下仍允许的非GET接口，它们不修改任何数据或用于退出只读模式
+var readOnlyAllowed = map[string]bool{
+	"/api/v1/policy/check":   true,
+	"/api/v1/job/batchGet":   true,
+	"/api/v1/admin/readonly": true,
+	"/api/v1/auth/token":     true,
+}
+
+// readOnlyRequest 开关只读模式的请求
+type readOnlyRequest struct {
+	Enabled bool `json:"enabled"` // 是否开启只读模式
+}
+
+// readOnlyGuard 只读模式中间件，拒绝所有修改请求，读取请求不受影响
+func (s *Server) readOnlyGuard() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !s.readOnly.Load() {
+			c.Next()
+			return
+		}
+
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			c.Next()
+			return
+		}
+
+		if readOnlyAllowed[c.FullPath()] {
+			c.Next()
+			return
+		}
+
+		failure(c, common.ApiReadOnly, "master is in read-only mode")
+		c.Abort()
+	}
+}
+
+// getReadOnly 获取只读模式状态
+func (s *Server) getReadOnly(c *gin.Context) {
+	success(c, gin.H{"enabled": s.readOnly.Load()})
+}
+
+// setReadOnly 开启或关闭只读模式，只影响处理该请求的master
+func (s *Server) setReadOnly(c *gin.Context) {
+	if !isAdmin(c) {
+		failure(c, common.ApiForbidden, "only admins can toggle read-only mode")
+		return
+	}
+
+	var req readOnlyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		failure(c, common.ApiParamError, "invalid read-only request: "+err.Error())
+		return
+	}
+
+	s.readOnly.Store(req.Enabled)
+	s.logger.Warn("read-only mode toggled",
+		zap.String("user", currentUser(c)),
+		zap.Bool("enabled", req.Enabled))
+
+	success(c, gin.H{"enabled": req.Enabled})
+}
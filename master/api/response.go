@@ -18,9 +18,27 @@ func success(c *gin.Context, data interface{}) {
 
 // failure 返回失败响应
 func failure(c *gin.Context, code int, message string) {
-	c.JSON(http.StatusOK, common.ApiResponse{
+	failureWithData(c, code, message, nil)
+}
+
+// failureWithData 返回带有错误详情数据的失败响应
+func failureWithData(c *gin.Context, code int, message string, data interface{}) {
+	resp := common.ApiResponse{
 		Code:    code,
 		Message: message,
-		Data:    nil,
-	})
+		Data:    data,
+	}
+	localize(c, &resp)
+	c.JSON(http.StatusOK, resp)
+}
+
+// pending 返回变更已提交等待审批的响应
+func pending(c *gin.Context, data interface{}) {
+	resp := common.ApiResponse{
+		Code:    common.ApiPending,
+		Message: "change submitted for approval",
+		Data:    data,
+	}
+	localize(c, &resp)
+	c.JSON(http.StatusOK, resp)
 }
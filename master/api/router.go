@@ -1,28 +1,95 @@
 package api
 
+import (
+	"github.com/fyerfyer/scheduler-refactor/common"
+	"github.com/fyerfyer/scheduler-refactor/pkg/healthcheck"
+)
+
 // registerRoutes 注册API路由
 func (s *Server) registerRoutes() {
+	// 健康检查接口，供容器HEALTHCHECK和负载均衡探测
+	s.engine.GET(healthcheck.Path, s.getHealth)
+
 	// API版本分组
-	v1 := s.engine.Group("/api/v1")
+	// 开启角色控制时，查询接口需要viewer，修改接口需要operator，管理接口需要admin
+	v1 := s.engine.Group("/api/v1", s.rbacGuard())
+
+	// 系统信息接口
+	v1.GET("/version", s.getVersion)
+	v1.GET("/metrics", s.getMetrics)
+	v1.GET("/errors", s.getErrorCatalog)
+
+	// 以API密钥换取JWT
+	v1.POST("/auth/token", s.issueToken)
+	v1.GET("/auth/me", s.getCurrentIdentity)
 
 	// 任务相关接口
+	// 修改任务定义的接口受冻结窗口限制，终止任务属于执行控制，不受限制
 	jobGroup := v1.Group("/job")
 	{
-		jobGroup.POST("/save", s.saveJob)
-		jobGroup.DELETE("/:name", s.deleteJob)
+		jobGroup.POST("/save", s.freezeGuard(), s.saveJob)
+		jobGroup.DELETE("/:name", s.freezeGuard(), s.deleteJob)
+		jobGroup.POST("/rename", s.freezeGuard(), s.renameJob)
 		jobGroup.GET("/list", s.listJobs)
+		jobGroup.GET("/archived", s.listArchivedJobs)
+		jobGroup.GET("/watch", s.watchJobs)
+		jobGroup.GET("/overlaps", s.listOverlaps)
+		jobGroup.GET("/stale", s.listStaleJobs)
+		jobGroup.POST("/batchGet", s.batchGetJobs)
 		jobGroup.GET("/:name", s.getJob)
+		jobGroup.GET("/:name/diff", s.getJobDiff)
+		jobGroup.GET("/:name/runs", s.listJobRuns)
+		jobGroup.GET("/:name/lock", s.getJobLock)
+		jobGroup.GET("/:name/progress", s.getJobProgress)
+		jobGroup.GET("/:name/placement", s.getJobPlacement)
+		jobGroup.GET("/:name/checkpoint", s.getJobCheckpoint)
+		jobGroup.DELETE("/:name/checkpoint", s.deleteJobCheckpoint)
+		jobGroup.GET("/:name/canary", s.getCanary)
+		jobGroup.DELETE("/:name/canary", s.cancelCanary)
+		jobGroup.GET("/:name/experiment", s.getExperimentReport)
 		jobGroup.POST("/kill/:name", s.killJob)
-		jobGroup.POST("/disable/:name", s.disableJob)
-		jobGroup.POST("/enable/:name", s.enableJob)
+		jobGroup.POST("/run/:name", s.runJob)
+		jobGroup.POST("/disable/:name", s.freezeGuard(), s.disableJob)
+		jobGroup.POST("/enable/:name", s.freezeGuard(), s.enableJob)
+		jobGroup.POST("/archive/:name", s.freezeGuard(), s.archiveJob)
+		jobGroup.POST("/restore/:name", s.freezeGuard(), s.restoreJob)
 	}
 
 	// 日志相关接口
 	logGroup := v1.Group("/log")
 	{
 		logGroup.GET("/list", s.listJobLogs)
+		logGroup.GET("/stream/:name", s.streamJobLogs)
 		logGroup.GET("/:name", s.getJobLog)
 		logGroup.GET("/stats/:name", s.getJobLogStats)
+		logGroup.GET("/stats/:name/drift", s.getJobDrift)
+		logGroup.GET("/history/:name", s.getJobHistory)
+		logGroup.POST("/:runId/replay", s.replayRun)
+		logGroup.GET("/bundle/:runId", s.getRunBundle)
+	}
+
+	// 管理接口
+	adminGroup := v1.Group("/admin", s.requireRole(common.RoleAdmin))
+	{
+		adminGroup.POST("/logs/cleanup", s.cleanupLogs)
+		adminGroup.GET("/readonly", s.getReadOnly)
+		adminGroup.POST("/readonly", s.setReadOnly)
+		adminGroup.GET("/replication", s.getReplication)
+		adminGroup.POST("/zombies/cleanup", s.cleanupZombies)
+		adminGroup.POST("/intents/reconcile", s.reconcileIntents)
+		adminGroup.GET("/apikeys", s.listAPIKeys)
+		adminGroup.POST("/apikeys", s.createAPIKey)
+		adminGroup.DELETE("/apikeys/:id", s.deleteAPIKey)
+		adminGroup.GET("/roles", s.listRoleBindings)
+		adminGroup.POST("/roles", s.saveRoleBinding)
+		adminGroup.DELETE("/roles/:user", s.deleteRoleBinding)
+	}
+
+	// 报表相关接口
+	reportGroup := v1.Group("/report")
+	{
+		reportGroup.GET("/cost", s.getCostReport)
+		reportGroup.GET("/digest", s.getDigest)
 	}
 
 	// 工作节点相关接口
@@ -30,5 +97,68 @@ func (s *Server) registerRoutes() {
 	{
 		workerGroup.GET("/list", s.listWorkers)
 		workerGroup.GET("/stats", s.getWorkerStats)
+		workerGroup.GET("/watch", s.watchWorkers)
+		workerGroup.GET("/executors", s.listExecutors)
+		workerGroup.GET("/config/:target", s.getWorkerSettings)
+		workerGroup.POST("/config/:target", s.saveWorkerSettings)
+		workerGroup.DELETE("/config/:target", s.deleteWorkerSettings)
+		workerGroup.GET("/killswitch/:id", s.getKillSwitch)
+		workerGroup.POST("/killswitch/:id", s.engageKillSwitch)
+		workerGroup.DELETE("/killswitch/:id", s.releaseKillSwitch)
+		workerGroup.GET("/fleet", s.getFleetStatus)
+		workerGroup.POST("/fleet", s.saveFleetSwitch)
+		workerGroup.POST("/fleet/rollback", s.rollbackFleetSwitch)
+		workerGroup.DELETE("/fleet", s.deleteFleetSwitch)
+	}
+
+	// 命令策略相关接口
+	policyGroup := v1.Group("/policy")
+	{
+		policyGroup.GET("/list", s.listPolicyRules)
+		policyGroup.POST("/save", s.savePolicyRule)
+		policyGroup.DELETE("/:name", s.deletePolicyRule)
+		policyGroup.POST("/check", s.checkPolicy)
+		policyGroup.GET("/audit", s.listPolicyRejections)
+	}
+
+	// 任务变更审批相关接口
+	approvalGroup := v1.Group("/approval")
+	{
+		approvalGroup.GET("/list", s.listPendingChanges)
+		approvalGroup.GET("/:name", s.getPendingChange)
+		approvalGroup.POST("/approve/:name", s.freezeGuard(), s.approveChange)
+		approvalGroup.POST("/reject/:name", s.rejectChange)
+	}
+
+	// 实时事件流，面板通过WebSocket接收任务、执行和worker变化
+	v1.GET("/events/ws", s.streamEvents)
+
+	// cron表达式相关接口
+	v1.GET("/cron/describe", s.describeCron)
+
+	// 变更冻结窗口相关接口
+	freezeGroup := v1.Group("/freeze")
+	{
+		freezeGroup.GET("/list", s.listFreezeWindows)
+		freezeGroup.POST("/save", s.saveFreezeWindow)
+		freezeGroup.DELETE("/:name", s.deleteFreezeWindow)
+	}
+
+	// 命名空间设置相关接口
+	namespaceGroup := v1.Group("/namespace")
+	{
+		namespaceGroup.GET("/list", s.listNamespaceSettings)
+		namespaceGroup.GET("/:name", s.getNamespaceSettings)
+		namespaceGroup.POST("/save", s.saveNamespaceSettings)
+		namespaceGroup.DELETE("/:name", s.deleteNamespaceSettings)
+	}
+
+	// 信号量相关接口
+	semaphoreGroup := v1.Group("/semaphore")
+	{
+		semaphoreGroup.GET("/list", s.listSemaphores)
+		semaphoreGroup.GET("/:name", s.getSemaphore)
+		semaphoreGroup.POST("/save", s.saveSemaphore)
+		semaphoreGroup.DELETE("/:name", s.deleteSemaphore)
 	}
 }
@@ -0,0 +1,121 @@
+package api
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/robfig/cron/v3"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+	"github.com/fyerfyer/scheduler-refactor/pkg/crondesc"
+)
+
+// intervalSamples 计算最小触发间隔时采样的触发次数
+const intervalSamples = 100
+
+// 描述cron表达式时返回的触发时间数量
+const (
+	defaultDescribeCount = 5
+	maxDescribeCount     = 50
+)
+
+// cronParser 任务cron表达式解析器，与worker一致带秒字段
+var cronParser = cron.NewParser(cron.Second | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow)
+
+// parseJobSchedule 按任务的时区解析cron表达式，与worker计算的触发时间一致
+func parseJobSchedule(job *common.Job) (cron.Schedule, error) {
+	location, err := job.Location()
+	if err != nil {
+		return nil, err
+	}
+	schedule, err := cronParser.Parse(job.CronExpr)
+	if err != nil {
+		return nil, err
+	}
+	if spec, ok := schedule.(*cron.SpecSchedule); ok {
+		spec.Location = location
+	}
+	return schedule, nil
+}
+
+// minFireInterval 从from开始采样后续的触发时间，返回相邻两次触发的最小间隔，无法再触发时返回0
+func minFireInterval(schedule cron.Schedule, from time.Time) time.Duration {
+	var shortest time.Duration
+	prev := schedule.Next(from)
+	if prev.IsZero() {
+		return 0
+	}
+	for i := 0; i < intervalSamples; i++ {
+		next := schedule.Next(prev)
+		if next.IsZero() {
+			break
+		}
+		if interval := next.Sub(prev); shortest == 0 || interval < shortest {
+			shortest = interval
+		}
+		prev = next
+	}
+	return shortest
+}
+
+// nextFireTimes 从from开始的后续count次触发时间，无法再触发时提前结束
+func nextFireTimes(schedule cron.Schedule, from time.Time, count int) []time.Time {
+	times := make([]time.Time, 0, count)
+	next := from
+	for len(times) < count {
+		next = schedule.Next(next)
+		if next.IsZero() {
+			break
+		}
+		times = append(times, next)
+	}
+	return times
+}
+
+// describeCron 描述cron表达式并列出后续的触发时间。
+// tz与任务的时区设置相同，表达式按该时区解释，未指定时按master本地时区计算
+func (s *Server) describeCron(c *gin.Context) {
+	expr := c.Query("expr")
+	if expr == "" {
+		failure(c, common.ApiParamError, "expr is required")
+		return
+	}
+	schedule, err := cronParser.Parse(expr)
+	if err != nil {
+		failure(c, common.ApiParamError, "invalid cron expression: "+err.Error())
+		return
+	}
+	description, err := crondesc.Describe(expr)
+	if err != nil {
+		failure(c, common.ApiParamError, "invalid cron expression: "+err.Error())
+		return
+	}
+
+	count, err := strconv.Atoi(c.DefaultQuery("count", strconv.Itoa(defaultDescribeCount)))
+	if err != nil || count <= 0 || count > maxDescribeCount {
+		failure(c, common.ApiParamError, "count must be between 1 and "+strconv.Itoa(maxDescribeCount))
+		return
+	}
+	location := time.Local
+	if tz := c.Query("tz"); tz != "" {
+		if location, err = time.LoadLocation(tz); err != nil {
+			failure(c, common.ApiParamError, "unknown timezone: "+tz)
+			return
+		}
+		if spec, ok := schedule.(*cron.SpecSchedule); ok {
+			spec.Location = location
+		}
+	}
+
+	next := make([]string, 0, count)
+	for _, fireTime := range nextFireTimes(schedule, time.Now(), count) {
+		next = append(next, fireTime.In(location).Format(time.RFC3339))
+	}
+	success(c, gin.H{
+		"expr":        expr,
+		"description": description,
+		"timezone":    location.String(),
+		"next":        next,
+	})
+}
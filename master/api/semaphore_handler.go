@@ -0,0 +1,124 @@
+package api
+
+import (
+	"errors"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+)
+
+// semaphoresAvailable 检查是否设置了信号量管理器，未设置时返回错误
+func (s *Server) semaphoresAvailable(c *gin.Context) bool {
+	if s.semMgr == nil {
+		failure(c, common.ApiFailure, "semaphores are not available")
+		return false
+	}
+	return true
+}
+
+// listSemaphores 获取所有信号量及其占用情况
+func (s *Server) listSemaphores(c *gin.Context) {
+	if !s.semaphoresAvailable(c) {
+		return
+	}
+
+	list, err := s.semMgr.ListSemaphores()
+	if err != nil {
+		failure(c, common.ApiEtcdError, "failed to list semaphores: "+err.Error())
+		return
+	}
+
+	success(c, list)
+}
+
+// getSemaphore 获取信号量及其占用情况
+func (s *Server) getSemaphore(c *gin.Context) {
+	if !s.semaphoresAvailable(c) {
+		return
+	}
+
+	usage, err := s.semMgr.GetSemaphore(c.Param("name"))
+	if err != nil {
+		if errors.Is(err, common.ErrSemaphoreNotFound) {
+			failure(c, common.ApiNotFound, "semaphore does not exist")
+		} else {
+			failure(c, common.ApiEtcdError, "failed to get semaphore: "+err.Error())
+		}
+		return
+	}
+
+	success(c, usage)
+}
+
+// saveSemaphore 保存信号量
+func (s *Server) saveSemaphore(c *gin.Context) {
+	if !isAdmin(c) {
+		failure(c, common.ApiForbidden, "only admins can change semaphores")
+		return
+	}
+	if !s.semaphoresAvailable(c) {
+		return
+	}
+
+	var sem common.Semaphore
+	if err := c.ShouldBindJSON(&sem); err != nil {
+		failure(c, common.ApiParamError, "invalid semaphore: "+err.Error())
+		return
+	}
+	sem.UpdatedBy = currentUser(c)
+
+	if err := s.semMgr.SaveSemaphore(&sem); err != nil {
+		if errors.Is(err, common.ErrInvalidSemaphore) {
+			failure(c, common.ApiParamError, err.Error())
+		} else {
+			failure(c, common.ApiEtcdError, "failed to save semaphore: "+err.Error())
+		}
+		return
+	}
+
+	success(c, sem)
+}
+
+// deleteSemaphore 删除信号量，仍有任务占用该信号量时拒绝删除
+func (s *Server) deleteSemaphore(c *gin.Context) {
+	if !isAdmin(c) {
+		failure(c, common.ApiForbidden, "only admins can change semaphores")
+		return
+	}
+	if !s.semaphoresAvailable(c) {
+		return
+	}
+
+	name := c.Param("name")
+	jobs, err := s.jobMgr.ListJobs()
+	if err != nil {
+		s.logger.Error("failed to list jobs", zap.Error(err))
+		failure(c, common.ApiEtcdError, "failed to list jobs: "+err.Error())
+		return
+	}
+	users := make([]string, 0)
+	for _, job := range jobs {
+		if slices.Contains(job.Semaphores, name) {
+			users = append(users, job.Name)
+		}
+	}
+	if len(users) > 0 {
+		failure(c, common.ApiParamError, "semaphore is still used by jobs: "+strings.Join(users, ","))
+		return
+	}
+
+	if err := s.semMgr.DeleteSemaphore(name); err != nil {
+		if errors.Is(err, common.ErrSemaphoreNotFound) {
+			failure(c, common.ApiNotFound, "semaphore does not exist")
+		} else {
+			failure(c, common.ApiEtcdError, "failed to delete semaphore: "+err.Error())
+		}
+		return
+	}
+
+	success(c, nil)
+}
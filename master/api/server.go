@@ -4,20 +4,48 @@ import (
 	"fmt"
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
+	"sync/atomic"
 
 	"github.com/fyerfyer/scheduler-refactor/config"
+	"github.com/fyerfyer/scheduler-refactor/master/admission"
+	"github.com/fyerfyer/scheduler-refactor/master/approvalmgr"
+	"github.com/fyerfyer/scheduler-refactor/master/authmgr"
+	"github.com/fyerfyer/scheduler-refactor/master/digest"
+	"github.com/fyerfyer/scheduler-refactor/master/eventhub"
+	"github.com/fyerfyer/scheduler-refactor/master/freezemgr"
+	"github.com/fyerfyer/scheduler-refactor/master/idempotency"
 	"github.com/fyerfyer/scheduler-refactor/master/jobmgr"
 	"github.com/fyerfyer/scheduler-refactor/master/logmgr"
+	"github.com/fyerfyer/scheduler-refactor/master/nsmgr"
+	"github.com/fyerfyer/scheduler-refactor/master/policymgr"
+	"github.com/fyerfyer/scheduler-refactor/master/rbacmgr"
+	"github.com/fyerfyer/scheduler-refactor/master/reconciler"
+	"github.com/fyerfyer/scheduler-refactor/master/replicator"
+	"github.com/fyerfyer/scheduler-refactor/master/semaphoremgr"
 	"github.com/fyerfyer/scheduler-refactor/master/workermgr"
 )
 
 // Server API服务器
 type Server struct {
-	engine    *gin.Engine              // gin引擎
-	logger    *zap.Logger              // 日志对象
-	jobMgr    *jobmgr.JobManager       // 任务管理器
-	logMgr    *logmgr.LogManager       // 日志管理器
-	workerMgr *workermgr.WorkerManager // 工作节点管理器
+	engine      *gin.Engine                    // gin引擎
+	logger      *zap.Logger                    // 日志对象
+	jobMgr      *jobmgr.JobManager             // 任务管理器
+	logMgr      *logmgr.LogManager             // 日志管理器
+	workerMgr   *workermgr.WorkerManager       // 工作节点管理器
+	policyMgr   *policymgr.PolicyManager       // 命令策略管理器
+	approvalMgr *approvalmgr.ApprovalManager   // 任务变更审批管理器
+	freezeMgr   *freezemgr.FreezeManager       // 变更冻结窗口管理器
+	replicator  *replicator.Replicator         // 灾备复制器，未配置备用集群时为nil
+	digestMgr   *digest.Manager                // 每周摘要管理器，为nil时不提供摘要预览
+	nsMgr       *nsmgr.NamespaceManager        // 命名空间设置管理器，为nil时不提供命名空间设置
+	semMgr      *semaphoremgr.SemaphoreManager // 信号量管理器，为nil时不提供信号量管理
+	authMgr     *authmgr.AuthManager           // 认证管理器，为nil时不要求认证
+	reconciler  *reconciler.Manager            // 执行意图对账器，为nil时不提供手动对账
+	idempotency *idempotency.Store             // 幂等记录存储，为nil时忽略Idempotency-Key
+	roleMgr     *rbacmgr.RoleManager           // 角色分配管理器，为nil时不按角色限制接口
+	admission   *admission.Webhook             // 准入webhook，为nil时保存任务不经过外部审查
+	events      *eventhub.Hub                  // 事件中心，为nil时不提供WebSocket事件流
+	readOnly    atomic.Bool                    // 是否处于只读模式
 }
 
 // NewServer 创建API服务器
@@ -26,6 +54,9 @@ func NewServer(
 	jobMgr *jobmgr.JobManager,
 	logMgr *logmgr.LogManager,
 	workerMgr *workermgr.WorkerManager,
+	policyMgr *policymgr.PolicyManager,
+	approvalMgr *approvalmgr.ApprovalManager,
+	freezeMgr *freezemgr.FreezeManager,
 ) *Server {
 	// 创建gin引擎
 	gin.SetMode(gin.ReleaseMode)
@@ -34,25 +65,103 @@ func NewServer(
 	// 使用恢复中间件
 	engine.Use(gin.Recovery())
 
+	// 启用HTTP/2(h2c)，轮询的面板可以复用同一个连接
+	engine.UseH2C = config.GlobalConfig.ApiHTTP2
+
+	// 日志等大响应压缩后传输
+	if config.GlobalConfig.ApiGzip {
+		engine.Use(gzipMiddleware())
+	}
+
+	// 解析调用方身份
+	engine.Use(identityMiddleware())
+
 	// 创建服务器
 	server := &Server{
-		engine:    engine,
-		logger:    logger,
-		jobMgr:    jobMgr,
-		logMgr:    logMgr,
-		workerMgr: workerMgr,
+		engine:      engine,
+		logger:      logger,
+		jobMgr:      jobMgr,
+		logMgr:      logMgr,
+		workerMgr:   workerMgr,
+		policyMgr:   policyMgr,
+		approvalMgr: approvalMgr,
+		freezeMgr:   freezeMgr,
 	}
 
+	server.readOnly.Store(config.GlobalConfig.ReadOnly)
+
+	// 开启认证时以凭证中的身份覆盖请求头中的身份
+	engine.Use(server.authGuard())
+
+	// 只读模式下拒绝修改请求
+	engine.Use(server.readOnlyGuard())
+
+	// 携带Idempotency-Key的POST请求重试时返回首次的响应
+	engine.Use(server.idempotencyGuard())
+
 	// 注册路由
 	server.registerRoutes()
 
 	return server
 }
 
+// SetReplicator 设置灾备复制器，用于查询复制状态
+func (s *Server) SetReplicator(r *replicator.Replicator) {
+	s.replicator = r
+}
+
+// SetDigest 设置每周摘要管理器，用于预览摘要
+func (s *Server) SetDigest(m *digest.Manager) {
+	s.digestMgr = m
+}
+
+// SetNamespaceManager 设置命名空间设置管理器
+func (s *Server) SetNamespaceManager(m *nsmgr.NamespaceManager) {
+	s.nsMgr = m
+}
+
+// SetSemaphoreManager 设置信号量管理器
+func (s *Server) SetSemaphoreManager(m *semaphoremgr.SemaphoreManager) {
+	s.semMgr = m
+}
+
+// SetAuthManager 设置认证管理器，设置后/api/v1接口必须携带API密钥或JWT
+func (s *Server) SetAuthManager(m *authmgr.AuthManager) {
+	s.authMgr = m
+}
+
+// SetReconciler 设置执行意图对账器，用于手动对账
+func (s *Server) SetReconciler(m *reconciler.Manager) {
+	s.reconciler = m
+}
+
+// SetIdempotencyStore 设置幂等记录存储，设置后POST请求可以携带Idempotency-Key安全重试
+func (s *Server) SetIdempotencyStore(store *idempotency.Store) {
+	s.idempotency = store
+}
+
+// SetRoleManager 设置角色分配管理器，设置后按viewer/operator/admin角色限制接口
+func (s *Server) SetRoleManager(m *rbacmgr.RoleManager) {
+	s.roleMgr = m
+}
+
+// SetAdmission 设置准入webhook，设置后保存任务前由webhook修改或拒绝任务定义
+func (s *Server) SetAdmission(w *admission.Webhook) {
+	s.admission = w
+}
+
+// SetEventHub 设置事件中心，用于向面板推送实时事件
+func (s *Server) SetEventHub(hub *eventhub.Hub) {
+	s.events = hub
+}
+
 // Start 启动API服务器
 func (s *Server) Start() error {
 	port := config.GlobalConfig.ApiPort
-	s.logger.Info("starting API server", zap.Int("port", port))
+	s.logger.Info("starting API server",
+		zap.Int("port", port),
+		zap.Bool("gzip", config.GlobalConfig.ApiGzip),
+		zap.Bool("http2", config.GlobalConfig.ApiHTTP2))
 
 	return s.engine.Run(fmt.Sprintf(":%d", port))
 }
@@ -0,0 +1,149 @@
+package api
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/robfig/cron/v3"
+	"go.uber.org/zap"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+	"github.com/fyerfyer/scheduler-refactor/master/logmgr"
+)
+
+// defaultStaleFactor 默认超过最长触发间隔的几倍没有执行视为长期未执行
+const defaultStaleFactor = 3
+
+// 任务可能配置错误的原因
+const (
+	staleInvalidCron  = "invalid_cron"   // cron表达式无法解析
+	staleNeverFires   = "never_fires"    // cron表达式不会再触发，例如2月30日
+	staleNoRuns       = "no_runs"        // 超过factor倍的最长触发间隔没有执行
+	staleNoZoneWorker = "no_zone_worker" // 首选可用区没有在线worker，只能在故障转移后由其他可用区执行
+)
+
+// staleJob 启用中但可能配置错误的任务，同一个任务可能有多条原因
+type staleJob struct {
+	JobName     string  `json:"jobName"`               // 任务名称
+	Reason      string  `json:"reason"`                // 原因
+	Detail      string  `json:"detail"`                // 说明
+	LastRunTime int64   `json:"lastRunTime,omitempty"` // 统计范围内最近一次执行的开始时间，没有执行时为0
+	Interval    float64 `json:"interval,omitempty"`    // 最长触发间隔(秒)
+}
+
+// maxFireInterval 从from开始采样后续的触发时间，返回相邻两次触发的最大间隔，无法再触发时返回0
+func maxFireInterval(schedule cron.Schedule, from time.Time) time.Duration {
+	var longest time.Duration
+	prev := schedule.Next(from)
+	if prev.IsZero() {
+		return 0
+	}
+	for i := 0; i < intervalSamples; i++ {
+		next := schedule.Next(prev)
+		if next.IsZero() {
+			break
+		}
+		longest = max(longest, next.Sub(prev))
+		prev = next
+	}
+	return longest
+}
+
+// checkStale 检查启用中的任务是否可能配置错误。summary为统计范围window内的执行概况，没有执行时为nil；
+// zones为有在线worker的可用区。最长触发间隔的factor倍超出window、或任务在这段时间内修改过时不判断是否长期未执行
+func checkStale(job *common.Job, summary *logmgr.JobRunSummary, zones map[string]bool, factor int, window time.Duration, now time.Time) []*staleJob {
+	var result []*staleJob
+
+	if job.PreferredZone != "" && !zones[job.PreferredZone] {
+		result = append(result, &staleJob{
+			JobName: job.Name,
+			Reason:  staleNoZoneWorker,
+			Detail: fmt.Sprintf("no online worker in preferred zone %s, runs wait %s for failover to other zones",
+				job.PreferredZone, job.FailoverDelay()),
+		})
+	}
+
+	// 按外部来源调度的任务的执行时间与cron表达式无关，不按触发间隔判断
+	if job.Schedule != nil {
+		return result
+	}
+
+	schedule, err := parseJobSchedule(job)
+	if err != nil {
+		return append(result, &staleJob{JobName: job.Name, Reason: staleInvalidCron, Detail: err.Error()})
+	}
+	if schedule.Next(now).IsZero() {
+		return append(result, &staleJob{JobName: job.Name, Reason: staleNeverFires, Detail: "cron expression " + job.CronExpr + " never fires"})
+	}
+
+	interval := maxFireInterval(schedule, now)
+	threshold := interval * time.Duration(factor)
+	if interval <= 0 || threshold > window || now.Sub(time.Unix(job.UpdatedAt, 0)) < threshold {
+		return result
+	}
+
+	var lastRun int64
+	if summary != nil {
+		lastRun = summary.LastRunTime
+	}
+	if now.Sub(time.Unix(lastRun, 0)) > threshold {
+		result = append(result, &staleJob{
+			JobName:     job.Name,
+			Reason:      staleNoRuns,
+			Detail:      fmt.Sprintf("no runs in the last %s, %d times its longest interval of %s", threshold, factor, interval),
+			LastRunTime: lastRun,
+			Interval:    interval.Seconds(),
+		})
+	}
+	return result
+}
+
+// listStaleJobs 列出启用中但可能配置错误的任务：长期没有执行、cron表达式不会触发或首选可用区没有worker
+func (s *Server) listStaleJobs(c *gin.Context) {
+	days, err := strconv.Atoi(c.DefaultQuery("days", "30"))
+	if err != nil || days <= 0 {
+		failure(c, common.ApiParamError, "days must be a positive integer")
+		return
+	}
+	factor, err := strconv.Atoi(c.DefaultQuery("factor", strconv.Itoa(defaultStaleFactor)))
+	if err != nil || factor <= 0 {
+		failure(c, common.ApiParamError, "factor must be a positive integer")
+		return
+	}
+
+	jobs, err := s.jobMgr.ListJobs()
+	if err != nil {
+		s.logger.Error("failed to list jobs", zap.Error(err))
+		failure(c, common.ApiEtcdError, "failed to list jobs: "+err.Error())
+		return
+	}
+
+	scope := callerScope(c)
+	summaries, err := s.logMgr.GetRunSummaries(scope, days)
+	if err != nil {
+		s.logger.Error("failed to summarize job runs", zap.Error(err))
+		failure(c, common.ApiDbError, "failed to summarize job runs: "+err.Error())
+		return
+	}
+
+	// 有在线worker的可用区
+	zones := make(map[string]bool)
+	for _, worker := range s.workerMgr.OnlineWorkers() {
+		zones[worker.Zone] = true
+	}
+
+	now := time.Now()
+	window := time.Duration(days) * 24 * time.Hour
+	stale := make([]*staleJob, 0)
+	for _, job := range jobs {
+		// 调用方看不到的任务没有日志，不能判断
+		if job.Disabled || !scope.Allows(common.NamespaceOf(job.Namespace), job.Owner) {
+			continue
+		}
+		stale = append(stale, checkStale(job, summaries[job.Name], zones, factor, window, now)...)
+	}
+
+	success(c, stale)
+}
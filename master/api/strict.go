@@ -0,0 +1,247 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// fieldError 请求体中某个字段的错误
+type fieldError struct {
+	Field   string `json:"field"`   // 字段路径，例如allowedWindows[0].start，整体错误时为空
+	Message string `json:"message"` // 错误说明
+}
+
+// String 格式化为一行错误信息
+func (e fieldError) String() string {
+	if e.Field == "" {
+		return e.Message
+	}
+	return fmt.Sprintf("field %q: %s", e.Field, e.Message)
+}
+
+// joinFieldErrors 把字段错误拼接为一条错误信息
+func joinFieldErrors(errs []fieldError) string {
+	parts := make([]string, len(errs))
+	for i, e := range errs {
+		parts[i] = e.String()
+	}
+	return strings.Join(parts, "; ")
+}
+
+// bindStrictJSON 严格解析JSON请求体：未知字段和类型不匹配的字段都会被逐个报告，
+// 避免cronExp之类的拼写错误被静默忽略。解析失败时返回非空的字段错误列表
+func bindStrictJSON(c *gin.Context, v interface{}) []fieldError {
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		return []fieldError{{Message: "failed to read request body: " + err.Error()}}
+	}
+	if !json.Valid(body) {
+		return []fieldError{{Message: "request body is not valid JSON"}}
+	}
+
+	if errs := checkJSONFields(body, reflect.TypeOf(v), ""); len(errs) > 0 {
+		return errs
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.DisallowUnknownFields()
+	if err = decoder.Decode(v); err != nil {
+		return []fieldError{{Message: err.Error()}}
+	}
+	return nil
+}
+
+// unmarshalerType 自定义解析的类型不做结构检查
+var unmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+
+// checkJSONFields 按目标类型递归检查JSON值，收集所有未知字段和类型错误
+func checkJSONFields(raw json.RawMessage, t reflect.Type, path string) []fieldError {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if bytes.Equal(bytes.TrimSpace(raw), []byte("null")) || reflect.PointerTo(t).Implements(unmarshalerType) {
+		return nil
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		var object map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &object); err != nil {
+			return []fieldError{{Field: path, Message: "expected object"}}
+		}
+
+		fields := jsonFields(t)
+		var errs []fieldError
+		for _, key := range sortedKeys(object) {
+			field, ok := lookupJSONField(fields, key)
+			if !ok {
+				message := "unknown field"
+				if suggestion := suggestField(fields, key); suggestion != "" {
+					message += fmt.Sprintf(", did you mean %q?", suggestion)
+				}
+				errs = append(errs, fieldError{Field: joinPath(path, key), Message: message})
+				continue
+			}
+			errs = append(errs, checkJSONFields(object[key], field.Type, joinPath(path, key))...)
+		}
+		return errs
+
+	case reflect.Slice, reflect.Array:
+		var items []json.RawMessage
+		if err := json.Unmarshal(raw, &items); err != nil {
+			// []byte以base64字符串编码
+			if t.Elem().Kind() == reflect.Uint8 {
+				break
+			}
+			return []fieldError{{Field: path, Message: "expected array"}}
+		}
+		var errs []fieldError
+		for i, item := range items {
+			errs = append(errs, checkJSONFields(item, t.Elem(), fmt.Sprintf("%s[%d]", path, i))...)
+		}
+		return errs
+
+	case reflect.Map:
+		var object map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &object); err != nil {
+			return []fieldError{{Field: path, Message: "expected object"}}
+		}
+		var errs []fieldError
+		for _, key := range sortedKeys(object) {
+			errs = append(errs, checkJSONFields(object[key], t.Elem(), joinPath(path, key))...)
+		}
+		return errs
+
+	case reflect.Interface:
+		return nil
+	}
+
+	if err := json.Unmarshal(raw, reflect.New(t).Interface()); err != nil {
+		return []fieldError{{Field: path, Message: "expected " + jsonKind(t)}}
+	}
+	return nil
+}
+
+// jsonFields 返回结构体的JSON字段，key为JSON名称，包括嵌入结构体的字段
+func jsonFields(t reflect.Type) map[string]reflect.StructField {
+	fields := make(map[string]reflect.StructField)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name := strings.Split(tag, ",")[0]
+
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Ptr {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				for key, f := range jsonFields(embedded) {
+					if _, exists := fields[key]; !exists {
+						fields[key] = f
+					}
+				}
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fields[name] = field
+	}
+	return fields
+}
+
+// lookupJSONField 按encoding/json的规则查找字段：优先精确匹配，其次忽略大小写
+func lookupJSONField(fields map[string]reflect.StructField, key string) (reflect.StructField, bool) {
+	if field, ok := fields[key]; ok {
+		return field, true
+	}
+	for name, field := range fields {
+		if strings.EqualFold(name, key) {
+			return field, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// suggestField 为拼错的字段找出编辑距离最近的已知字段，相差太远时返回空串
+func suggestField(fields map[string]reflect.StructField, key string) string {
+	best, bestDistance := "", 3
+	for name := range fields {
+		distance := editDistance(strings.ToLower(name), strings.ToLower(key))
+		if distance < bestDistance || (distance == bestDistance && best != "" && name < best) {
+			best, bestDistance = name, distance
+		}
+	}
+	return best
+}
+
+// editDistance 计算两个字符串的编辑距离
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
+
+// jsonKind 返回类型在JSON中的名称
+func jsonKind(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	default:
+		return t.Kind().String()
+	}
+}
+
+// joinPath 拼接字段路径
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// sortedKeys 按字母序返回对象的key，保证错误顺序稳定
+func sortedKeys(object map[string]json.RawMessage) []string {
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
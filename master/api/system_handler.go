@@ -0,0 +1,74 @@
+package api
+
+import (
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+	"github.com/fyerfyer/scheduler-refactor/master/replicator"
+	"github.com/fyerfyer/scheduler-refactor/pkg/metrics"
+	"github.com/fyerfyer/scheduler-refactor/pkg/version"
+)
+
+// getHealth 健康检查，进程能够处理请求即视为健康
+func (s *Server) getHealth(c *gin.Context) {
+	success(c, gin.H{"status": "ok"})
+}
+
+// getVersion 获取master的版本和构建信息
+func (s *Server) getVersion(c *gin.Context) {
+	success(c, version.Get())
+}
+
+// getMetrics 获取etcd等外部依赖的调用延迟统计
+func (s *Server) getMetrics(c *gin.Context) {
+	success(c, metrics.Snapshot())
+}
+
+// getReplication 获取灾备复制状态，包括最近的复制冲突
+func (s *Server) getReplication(c *gin.Context) {
+	if s.replicator == nil {
+		success(c, &replicator.Status{Enabled: false})
+		return
+	}
+
+	success(c, s.replicator.Status())
+}
+
+// intentReconcileRequest 执行意图对账的请求
+type intentReconcileRequest struct {
+	DryRun bool `json:"dryRun"` // 只报告结果丢失的执行，不补记日志
+}
+
+// reconcileIntents 立即对账执行意图，为随worker丢失结果的执行补记日志，dryRun时只返回对账结果
+func (s *Server) reconcileIntents(c *gin.Context) {
+	if !isAdmin(c) {
+		failure(c, common.ApiForbidden, "only admins can reconcile run intents")
+		return
+	}
+	if s.reconciler == nil {
+		failure(c, common.ApiFailure, "run intent reconciler is not available")
+		return
+	}
+
+	var req intentReconcileRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			failure(c, common.ApiParamError, "invalid reconcile request: "+err.Error())
+			return
+		}
+	}
+
+	report, err := s.reconciler.Reconcile(req.DryRun)
+	if err != nil {
+		failure(c, common.ApiEtcdError, "failed to reconcile run intents: "+err.Error())
+		return
+	}
+
+	s.logger.Info("run intent reconciliation requested",
+		zap.String("user", currentUser(c)),
+		zap.Bool("dryRun", report.DryRun),
+		zap.Int("lost", len(report.Lost)))
+
+	success(c, report)
+}
@@ -0,0 +1,37 @@
+package api
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 长轮询监听的等待时间(秒)
+const (
+	defaultWatchTimeout = 30
+	maxWatchTimeout     = 120
+)
+
+// 事件流的心跳间隔
+const (
+	workerWatchKeepalive = 15 * time.Second // worker事件流(SSE)
+	eventStreamKeepalive = 30 * time.Second // WebSocket事件流
+	logStreamKeepalive   = 15 * time.Second // 任务日志流(SSE)
+)
+
+// 任务日志流的参数
+const (
+	logStreamInterval = 2 * time.Second // 轮询日志存储的间隔
+	maxLogStreamSince = time.Hour       // since最多回溯的时间
+)
+
+// parseWatchTimeout 解析长轮询的等待时间
+func parseWatchTimeout(c *gin.Context) (time.Duration, error) {
+	timeout, err := strconv.Atoi(c.DefaultQuery("timeout", strconv.Itoa(defaultWatchTimeout)))
+	if err != nil || timeout <= 0 || timeout > maxWatchTimeout {
+		return 0, fmt.Errorf("timeout must be between 1 and %d seconds", maxWatchTimeout)
+	}
+	return time.Duration(timeout) * time.Second, nil
+}
@@ -1,7 +1,14 @@
 package api
 
 import (
+	"errors"
+	"io"
+	"time"
+
 	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
 )
 
 // listWorkers 获取工作节点列表
@@ -27,6 +34,9 @@ func (s *Server) listWorkers(c *gin.Context) {
 			"memUsage": worker.MemUsage,
 			"lastSeen": worker.LastSeen,
 			"status":   status,
+			"version":  worker.Version,
+			"commit":   worker.Commit,
+			"stats":    worker.Stats,
 		}
 		result = append(result, workerInfo)
 	}
@@ -34,9 +44,262 @@ func (s *Server) listWorkers(c *gin.Context) {
 	success(c, result)
 }
 
+// watchWorkers 以SSE推送worker加入、离开和健康状态变化事件，直到客户端断开
+func (s *Server) watchWorkers(c *gin.Context) {
+	events, unsubscribe := s.workerMgr.Subscribe()
+	defer unsubscribe()
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no") // 避免反向代理缓冲事件
+
+	// 定期发送心跳，避免空闲连接被代理断开
+	keepalive := time.NewTicker(workerWatchKeepalive)
+	defer keepalive.Stop()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case event := <-events:
+			c.SSEvent(event.Type, event)
+		case <-keepalive.C:
+			c.SSEvent("ping", time.Now().UnixMilli())
+		}
+		return true
+	})
+}
+
+// listExecutors 获取在线worker安装的执行器插件，用于查看可用的任务类型和配置项
+func (s *Server) listExecutors(c *gin.Context) {
+	success(c, s.workerMgr.ListExecutors())
+}
+
 // getWorkerStats 获取工作节点统计信息
 func (s *Server) getWorkerStats(c *gin.Context) {
 	// 获取统计信息
 	stats := s.workerMgr.GetWorkerStats()
+
+	// 附带集群版本偏差信息
+	stats["versionSkew"] = s.workerMgr.GetVersionSkew()
+
 	success(c, stats)
 }
+
+// getWorkerSettings 获取已发布的worker配置
+func (s *Server) getWorkerSettings(c *gin.Context) {
+	target := c.Param("target")
+
+	settings, err := s.workerMgr.GetWorkerSettings(target)
+	if err != nil {
+		if errors.Is(err, common.ErrWorkerSettingsNotFound) {
+			failure(c, common.ApiParamError, "worker settings do not exist")
+		} else {
+			s.logger.Error("failed to get worker settings",
+				zap.String("target", target),
+				zap.Error(err))
+			failure(c, common.ApiEtcdError, "failed to get worker settings: "+err.Error())
+		}
+		return
+	}
+
+	success(c, settings)
+}
+
+// saveWorkerSettings 发布worker配置，target为global或worker ID
+func (s *Server) saveWorkerSettings(c *gin.Context) {
+	if !isAdmin(c) {
+		failure(c, common.ApiForbidden, "only admins can change worker settings")
+		return
+	}
+
+	target := c.Param("target")
+
+	var settings common.WorkerSettings
+	if err := c.ShouldBindJSON(&settings); err != nil {
+		failure(c, common.ApiParamError, "invalid worker settings: "+err.Error())
+		return
+	}
+
+	if err := s.workerMgr.PutWorkerSettings(target, &settings); err != nil {
+		if errors.Is(err, common.ErrInvalidWorkerSettings) {
+			failure(c, common.ApiParamError, err.Error())
+		} else {
+			s.logger.Error("failed to save worker settings",
+				zap.String("target", target),
+				zap.Error(err))
+			failure(c, common.ApiEtcdError, "failed to save worker settings: "+err.Error())
+		}
+		return
+	}
+
+	success(c, settings)
+}
+
+// deleteWorkerSettings 删除已发布的worker配置
+func (s *Server) deleteWorkerSettings(c *gin.Context) {
+	if !isAdmin(c) {
+		failure(c, common.ApiForbidden, "only admins can change worker settings")
+		return
+	}
+
+	target := c.Param("target")
+
+	if err := s.workerMgr.DeleteWorkerSettings(target); err != nil {
+		if errors.Is(err, common.ErrWorkerSettingsNotFound) {
+			failure(c, common.ApiParamError, "worker settings do not exist")
+		} else {
+			failure(c, common.ApiEtcdError, "failed to delete worker settings: "+err.Error())
+		}
+		return
+	}
+
+	success(c, nil)
+}
+
+// getKillSwitch 获取worker的紧急停机开关
+func (s *Server) getKillSwitch(c *gin.Context) {
+	ks, err := s.workerMgr.GetKillSwitch(c.Param("id"))
+	if err != nil {
+		if errors.Is(err, common.ErrKillSwitchNotFound) {
+			failure(c, common.ApiParamError, "kill switch is not engaged")
+		} else {
+			failure(c, common.ApiEtcdError, "failed to get kill switch: "+err.Error())
+		}
+		return
+	}
+
+	success(c, ks)
+}
+
+// engageKillSwitch 开启worker的紧急停机开关，worker立即拒绝新的执行，宽限时间后终止运行中的任务
+func (s *Server) engageKillSwitch(c *gin.Context) {
+	if !isAdmin(c) {
+		failure(c, common.ApiForbidden, "only admins can engage the kill switch")
+		return
+	}
+
+	var ks common.KillSwitch
+	if err := c.ShouldBindJSON(&ks); err != nil {
+		failure(c, common.ApiParamError, "invalid kill switch: "+err.Error())
+		return
+	}
+	if ks.GracePeriod < 0 {
+		failure(c, common.ApiParamError, "gracePeriod must not be negative")
+		return
+	}
+	ks.CreatedBy = currentUser(c)
+
+	workerID := c.Param("id")
+	if err := s.workerMgr.EngageKillSwitch(workerID, &ks); err != nil {
+		failure(c, common.ApiEtcdError, "failed to engage kill switch: "+err.Error())
+		return
+	}
+
+	success(c, ks)
+}
+
+// releaseKillSwitch 关闭worker的紧急停机开关
+func (s *Server) releaseKillSwitch(c *gin.Context) {
+	if !isAdmin(c) {
+		failure(c, common.ApiForbidden, "only admins can release the kill switch")
+		return
+	}
+
+	if err := s.workerMgr.ReleaseKillSwitch(c.Param("id")); err != nil {
+		if errors.Is(err, common.ErrKillSwitchNotFound) {
+			failure(c, common.ApiParamError, "kill switch is not engaged")
+		} else {
+			failure(c, common.ApiEtcdError, "failed to release kill switch: "+err.Error())
+		}
+		return
+	}
+
+	success(c, nil)
+}
+
+// getFleetStatus 获取蓝绿切换和在线worker的代号分布
+func (s *Server) getFleetStatus(c *gin.Context) {
+	status, err := s.workerMgr.GetFleetStatus()
+	if err != nil {
+		failure(c, common.ApiEtcdError, "failed to get fleet status: "+err.Error())
+		return
+	}
+
+	success(c, status)
+}
+
+// saveFleetSwitch 开始或调整蓝绿切换，percent为切换到新一代worker的任务百分比
+func (s *Server) saveFleetSwitch(c *gin.Context) {
+	if !isAdmin(c) {
+		failure(c, common.ApiForbidden, "only admins can switch the worker fleet")
+		return
+	}
+
+	var fs common.FleetSwitch
+	if err := c.ShouldBindJSON(&fs); err != nil {
+		failure(c, common.ApiParamError, "invalid fleet switch: "+err.Error())
+		return
+	}
+	fs.UpdatedBy = currentUser(c)
+
+	if err := s.workerMgr.SaveFleetSwitch(&fs); err != nil {
+		if errors.Is(err, common.ErrInvalidFleetSwitch) {
+			failure(c, common.ApiParamError, err.Error())
+		} else {
+			failure(c, common.ApiEtcdError, "failed to save fleet switch: "+err.Error())
+		}
+		return
+	}
+
+	s.respondFleetStatus(c)
+}
+
+// rollbackFleetSwitch 立即把所有任务切回旧一代worker
+func (s *Server) rollbackFleetSwitch(c *gin.Context) {
+	if !isAdmin(c) {
+		failure(c, common.ApiForbidden, "only admins can switch the worker fleet")
+		return
+	}
+
+	if _, err := s.workerMgr.RollbackFleetSwitch(currentUser(c)); err != nil {
+		if errors.Is(err, common.ErrFleetSwitchNotFound) {
+			failure(c, common.ApiParamError, "no fleet switch in progress")
+		} else {
+			failure(c, common.ApiEtcdError, "failed to roll back fleet switch: "+err.Error())
+		}
+		return
+	}
+
+	s.respondFleetStatus(c)
+}
+
+// deleteFleetSwitch 结束蓝绿切换，所有worker都可以执行所有任务
+func (s *Server) deleteFleetSwitch(c *gin.Context) {
+	if !isAdmin(c) {
+		failure(c, common.ApiForbidden, "only admins can switch the worker fleet")
+		return
+	}
+
+	if err := s.workerMgr.DeleteFleetSwitch(); err != nil {
+		if errors.Is(err, common.ErrFleetSwitchNotFound) {
+			failure(c, common.ApiParamError, "no fleet switch in progress")
+		} else {
+			failure(c, common.ApiEtcdError, "failed to delete fleet switch: "+err.Error())
+		}
+		return
+	}
+
+	success(c, nil)
+}
+
+// respondFleetStatus 修改切换后返回最新状态，状态读取失败时只返回成功
+func (s *Server) respondFleetStatus(c *gin.Context) {
+	status, err := s.workerMgr.GetFleetStatus()
+	if err != nil {
+		s.logger.Warn("failed to get fleet status", zap.Error(err))
+		success(c, nil)
+		return
+	}
+
+	success(c, status)
+}
@@ -0,0 +1,229 @@
+package approvalmgr
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+	"github.com/fyerfyer/scheduler-refactor/master/jobmgr"
+	"github.com/fyerfyer/scheduler-refactor/pkg/etcd"
+	"github.com/fyerfyer/scheduler-refactor/pkg/notify"
+)
+
+// 审批通知事件
+const (
+	EventChangeSubmitted = "approval.submitted" // 提交了待审批变更
+	EventChangeApproved  = "approval.approved"  // 变更已通过
+	EventChangeRejected  = "approval.rejected"  // 变更被驳回
+)
+
+// ApprovalManager 任务变更审批管理器，待审批变更单独存放在etcd中，审批通过后才写入任务目录
+type ApprovalManager struct {
+	etcdClient *etcd.Client       // etcd客户端
+	jobMgr     *jobmgr.JobManager // 任务管理器，用于应用通过的变更
+	notifier   notify.Notifier    // 审批人通知
+	logger     *zap.Logger        // 日志对象
+}
+
+// NewApprovalManager 创建审批管理器
+func NewApprovalManager(etcdClient *etcd.Client, jobMgr *jobmgr.JobManager, notifier notify.Notifier, logger *zap.Logger) *ApprovalManager {
+	return &ApprovalManager{
+		etcdClient: etcdClient,
+		jobMgr:     jobMgr,
+		notifier:   notifier,
+		logger:     logger,
+	}
+}
+
+// Submit 提交待审批变更，同一任务只保留最新一次提交
+func (am *ApprovalManager) Submit(change *common.PendingChange) error {
+	change.RequestedAt = time.Now().Unix()
+
+	data, err := json.Marshal(change)
+	if err != nil {
+		return fmt.Errorf("failed to marshal pending change: %v", err)
+	}
+
+	if _, err = am.etcdClient.Put(common.JobPendingDir+change.JobName, string(data)); err != nil {
+		am.logger.Error("failed to submit pending change",
+			zap.String("jobName", change.JobName),
+			zap.Error(err))
+		return err
+	}
+
+	am.logger.Info("job change submitted for approval",
+		zap.String("jobName", change.JobName),
+		zap.String("action", change.Action),
+		zap.String("requestedBy", change.RequestedBy))
+
+	am.notify(EventChangeSubmitted, change, change.RequestedBy)
+	return nil
+}
+
+// GetPending 获取任务的待审批变更
+func (am *ApprovalManager) GetPending(jobName string) (*common.PendingChange, error) {
+	resp, err := am.etcdClient.Get(common.JobPendingDir + jobName)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.Count == 0 {
+		return nil, common.ErrPendingChangeNotFound
+	}
+
+	change := &common.PendingChange{}
+	if err = json.Unmarshal(resp.Kvs[0].Value, change); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal pending change: %v", err)
+	}
+
+	return change, nil
+}
+
+// ListPending 获取所有待审批变更
+func (am *ApprovalManager) ListPending() ([]*common.PendingChange, error) {
+	resp, err := am.etcdClient.GetWithPrefix(common.JobPendingDir)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := make([]*common.PendingChange, 0, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		change := &common.PendingChange{}
+		if err = json.Unmarshal(kv.Value, change); err != nil {
+			am.logger.Error("failed to unmarshal pending change",
+				zap.String("key", string(kv.Key)),
+				zap.Error(err))
+			continue
+		}
+		changes = append(changes, change)
+	}
+
+	return changes, nil
+}
+
+// Approve 通过变更并应用到任务目录
+func (am *ApprovalManager) Approve(jobName, approver string) (*common.PendingChange, error) {
+	change, err := am.take(jobName, approver)
+	if err != nil {
+		return nil, err
+	}
+
+	switch change.Action {
+	case common.ChangeActionSave:
+		err = am.jobMgr.SaveJob(change.Job)
+	case common.ChangeActionDelete:
+		err = am.jobMgr.DeleteJob(change.JobName)
+	case common.ChangeActionRename:
+		_, err = am.jobMgr.RenameJob(change.JobName, change.NewName, change.RequestedBy)
+	default:
+		err = fmt.Errorf("unknown change action: %s", change.Action)
+	}
+
+	if err != nil {
+		// 应用失败时放回待审批队列，便于重试
+		if restoreErr := am.restore(change); restoreErr != nil {
+			am.logger.Error("failed to restore pending change",
+				zap.String("jobName", jobName),
+				zap.Error(restoreErr))
+		}
+		return nil, err
+	}
+
+	am.logger.Info("job change approved",
+		zap.String("jobName", jobName),
+		zap.String("action", change.Action),
+		zap.String("approver", approver))
+
+	am.notify(EventChangeApproved, change, approver)
+	return change, nil
+}
+
+// Reject 驳回变更
+func (am *ApprovalManager) Reject(jobName, approver string) (*common.PendingChange, error) {
+	change, err := am.take(jobName, approver)
+	if err != nil {
+		return nil, err
+	}
+
+	am.logger.Info("job change rejected",
+		zap.String("jobName", jobName),
+		zap.String("action", change.Action),
+		zap.String("approver", approver))
+
+	am.notify(EventChangeRejected, change, approver)
+	return change, nil
+}
+
+// take 取出待审批变更，删除成功的调用方才能处理该变更，避免并发审批重复应用
+func (am *ApprovalManager) take(jobName, approver string) (*common.PendingChange, error) {
+	change, err := am.GetPending(jobName)
+	if err != nil {
+		return nil, err
+	}
+
+	if approver == "" || approver == change.RequestedBy {
+		return nil, common.ErrSelfApproval
+	}
+
+	resp, err := am.etcdClient.Delete(common.JobPendingDir + jobName)
+	if err != nil {
+		return nil, err
+	}
+	if resp != nil && resp.Deleted == 0 {
+		return nil, common.ErrPendingChangeNotFound
+	}
+
+	return change, nil
+}
+
+// restore 将变更放回待审批目录，保留原始提交时间
+func (am *ApprovalManager) restore(change *common.PendingChange) error {
+	data, err := json.Marshal(change)
+	if err != nil {
+		return err
+	}
+
+	_, err = am.etcdClient.Put(common.JobPendingDir+change.JobName, string(data))
+	return err
+}
+
+// notify 异步发送审批通知，发送失败只记录日志
+func (am *ApprovalManager) notify(event string, change *common.PendingChange, actor string) {
+	msg := &notify.Message{
+		Event: event,
+		Title: fmt.Sprintf("job %s: %s %s", change.JobName, change.Action, event),
+		Text:  fmt.Sprintf("%s change of job %s requested by %s", change.Action, change.JobName, change.RequestedBy),
+		Fields: map[string]string{
+			"jobName":     change.JobName,
+			"action":      change.Action,
+			"requestedBy": change.RequestedBy,
+			"actor":       actor,
+		},
+	}
+
+	if change.NewName != "" {
+		msg.Fields["newName"] = change.NewName
+	}
+
+	// 带上任务说明和运行手册，值班人员可以直接查看处理文档
+	if change.Job != nil {
+		if change.Job.Description != "" {
+			msg.Fields["description"] = change.Job.Description
+		}
+		if change.Job.RunbookURL != "" {
+			msg.Fields["runbookUrl"] = change.Job.RunbookURL
+		}
+	}
+
+	go func() {
+		if err := am.notifier.Notify(msg); err != nil {
+			am.logger.Warn("failed to send approval notification",
+				zap.String("jobName", change.JobName),
+				zap.String("event", event),
+				zap.Error(err))
+		}
+	}()
+}
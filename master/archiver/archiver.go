@@ -0,0 +1,283 @@
+package archiver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.uber.org/zap"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+	"github.com/fyerfyer/scheduler-refactor/master/jobmgr"
+	"github.com/fyerfyer/scheduler-refactor/pkg/etcd"
+	"github.com/fyerfyer/scheduler-refactor/pkg/notify"
+)
+
+// 归档相关的通知事件类型
+const (
+	EventArchiveNotice = "job_archive_notice" // 任务即将被归档
+	EventJobArchived   = "job_archived"       // 任务已被归档
+)
+
+// archivedBy 自动归档的操作人
+const archivedBy = "system"
+
+// LeaderChecker 判断当前master是否为leader，只有leader执行归档
+type LeaderChecker interface {
+	IsLeader() bool
+}
+
+// SweepResult 一次归档检查的结果
+type SweepResult struct {
+	Noticed  []string `json:"noticed"`  // 本次提醒即将归档的任务
+	Archived []string `json:"archived"` // 本次归档的任务
+}
+
+// notice 已发出的归档提醒
+type notice struct {
+	DisabledAt int64 `json:"disabledAt"` // 提醒时任务的禁用时间，任务重新禁用后需要重新提醒
+	NoticedAt  int64 `json:"noticedAt"`  // 提醒时间
+}
+
+// Manager 归档管理器，定时将禁用超过days天的任务移到归档目录，归档前提前noticeDays天提醒
+type Manager struct {
+	etcdClient *etcd.Client       // etcd客户端
+	jobMgr     *jobmgr.JobManager // 任务管理器
+	notifier   notify.Notifier    // 归档通知
+	days       int                // 禁用多少天后归档
+	noticeDays int                // 提前多少天提醒，0表示不提醒
+	leader     LeaderChecker      // leader判断，为nil时视为leader
+	logger     *zap.Logger        // 日志对象
+	ctx        context.Context    // 上下文，用于控制退出
+	cancelFunc context.CancelFunc // 取消函数
+}
+
+// NewManager 创建归档管理器
+func NewManager(etcdClient *etcd.Client, jobMgr *jobmgr.JobManager, notifier notify.Notifier, days, noticeDays int, logger *zap.Logger) *Manager {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &Manager{
+		etcdClient: etcdClient,
+		jobMgr:     jobMgr,
+		notifier:   notifier,
+		days:       days,
+		noticeDays: noticeDays,
+		logger:     logger,
+		ctx:        ctx,
+		cancelFunc: cancel,
+	}
+}
+
+// SetLeader 设置leader判断，设置后只有leader执行归档
+func (m *Manager) SetLeader(leader LeaderChecker) {
+	m.leader = leader
+}
+
+// Start 按cron表达式定时检查，schedule为空时使用默认时间
+func (m *Manager) Start(schedule string) error {
+	if schedule == "" {
+		schedule = common.DefaultArchiveSchedule
+	}
+
+	parser := cron.NewParser(cron.Second | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow)
+	expr, err := parser.Parse(schedule)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		for {
+			timer := time.NewTimer(time.Until(expr.Next(time.Now())))
+
+			select {
+			case <-m.ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+				if m.leader != nil && !m.leader.IsLeader() {
+					m.logger.Debug("not master leader, skip job archiving")
+					continue
+				}
+				if _, err := m.Sweep(); err != nil {
+					m.logger.Error("failed to archive disabled jobs", zap.Error(err))
+				}
+			}
+		}
+	}()
+
+	m.logger.Info("job archiver started",
+		zap.String("schedule", schedule),
+		zap.Int("days", m.days),
+		zap.Int("noticeDays", m.noticeDays))
+	return nil
+}
+
+// Stop 停止定时检查
+func (m *Manager) Stop() {
+	m.cancelFunc()
+}
+
+// Sweep 检查所有禁用的任务，提醒即将归档的任务并归档到期的任务。
+// 先在etcd中更新提醒记录，多个master同时检查时只有更新成功的一方继续
+func (m *Manager) Sweep() (*SweepResult, error) {
+	notices, revision, err := m.loadNotices()
+	if err != nil {
+		return nil, err
+	}
+	jobs, err := m.jobMgr.ListJobs()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	remind, archive, next := plan(jobs, notices, now, m.days, m.noticeDays)
+
+	data, err := json.Marshal(next)
+	if err != nil {
+		return nil, err
+	}
+	applied, err := m.etcdClient.ApplyIfUnchanged(common.ArchiveNoticeKey, revision,
+		clientv3.OpPut(common.ArchiveNoticeKey, string(data)))
+	if err != nil {
+		return nil, err
+	}
+	if !applied {
+		m.logger.Info("job archiving already handled by another master")
+		return &SweepResult{}, nil
+	}
+
+	result := &SweepResult{Noticed: []string{}, Archived: []string{}}
+	for _, job := range remind {
+		archiveAt := time.Unix(next[job.Name].NoticedAt, 0).AddDate(0, 0, m.noticeDays)
+		if at := time.Unix(disabledAt(job), 0).AddDate(0, 0, m.days); at.After(archiveAt) {
+			archiveAt = at
+		}
+		m.send(noticeMessage(job, archiveAt))
+		result.Noticed = append(result.Noticed, job.Name)
+	}
+
+	reason := fmt.Sprintf("disabled for more than %d days", m.days)
+	for _, job := range archive {
+		if _, err := m.jobMgr.ArchiveJob(job.Name, archivedBy, reason); err != nil {
+			// 任务在检查后被启用或修改，下次检查时重新判断
+			m.logger.Warn("failed to archive disabled job",
+				zap.String("jobName", job.Name),
+				zap.Error(err))
+			continue
+		}
+		m.send(archivedMessage(job, reason))
+		result.Archived = append(result.Archived, job.Name)
+	}
+
+	m.logger.Info("disabled jobs checked for archiving",
+		zap.Strings("noticed", result.Noticed),
+		zap.Strings("archived", result.Archived))
+	return result, nil
+}
+
+// send 发送通知，失败时只记录日志
+func (m *Manager) send(msg *notify.Message) {
+	if err := m.notifier.Notify(msg); err != nil {
+		m.logger.Warn("failed to send archive notification",
+			zap.String("event", msg.Event),
+			zap.String("jobName", msg.Fields["jobName"]),
+			zap.Error(err))
+	}
+}
+
+// loadNotices 读取已发出的提醒和它的修改版本
+func (m *Manager) loadNotices() (map[string]*notice, int64, error) {
+	resp, err := m.etcdClient.Get(common.ArchiveNoticeKey)
+	if err != nil {
+		return nil, 0, err
+	}
+	if resp.Count == 0 {
+		return nil, 0, nil
+	}
+
+	kv := resp.Kvs[0]
+	notices := make(map[string]*notice)
+	if err = json.Unmarshal(kv.Value, &notices); err != nil {
+		// 记录损坏时按从未提醒处理，检查后会被覆盖
+		m.logger.Warn("invalid archive notice state, ignoring it", zap.Error(err))
+		return nil, kv.ModRevision, nil
+	}
+	return notices, kv.ModRevision, nil
+}
+
+// plan 确定需要提醒和归档的任务，同时返回检查后应保存的提醒记录。
+// 任务禁用满days天、且提醒后已满noticeDays天才归档，保证每个任务都提前收到提醒
+func plan(jobs []*common.Job, notices map[string]*notice, now time.Time, days, noticeDays int) (remind, archive []*common.Job, next map[string]*notice) {
+	next = make(map[string]*notice)
+	for _, job := range jobs {
+		if !job.Disabled {
+			continue
+		}
+
+		disabled := time.Unix(disabledAt(job), 0)
+		archiveAt := disabled.AddDate(0, 0, days)
+		if now.Before(archiveAt.AddDate(0, 0, -noticeDays)) {
+			continue
+		}
+
+		n, ok := notices[job.Name]
+		if !ok || n.DisabledAt != disabled.Unix() {
+			n = &notice{DisabledAt: disabled.Unix(), NoticedAt: now.Unix()}
+			if noticeDays > 0 {
+				remind = append(remind, job)
+			}
+		}
+
+		if !now.Before(archiveAt) && !now.Before(time.Unix(n.NoticedAt, 0).AddDate(0, 0, noticeDays)) {
+			archive = append(archive, job)
+			continue
+		}
+		next[job.Name] = n
+	}
+
+	sort.Slice(remind, func(i, j int) bool { return remind[i].Name < remind[j].Name })
+	sort.Slice(archive, func(i, j int) bool { return archive[i].Name < archive[j].Name })
+	return remind, archive, next
+}
+
+// disabledAt 任务的禁用时间，没有记录禁用时间的旧任务按最后修改时间计算
+func disabledAt(job *common.Job) int64 {
+	if job.DisabledAt > 0 {
+		return job.DisabledAt
+	}
+	return job.UpdatedAt
+}
+
+// noticeMessage 即将归档的提醒
+func noticeMessage(job *common.Job, archiveAt time.Time) *notify.Message {
+	return &notify.Message{
+		Event: EventArchiveNotice,
+		Title: "job " + job.Name + " will be archived",
+		Text: fmt.Sprintf("job %s has been disabled since %s and will be archived after %s unless it is enabled",
+			job.Name, time.Unix(disabledAt(job), 0).Format(time.RFC3339), archiveAt.Format(time.RFC3339)),
+		Fields: map[string]string{
+			"jobName":   job.Name,
+			"namespace": common.NamespaceOf(job.Namespace),
+			"owner":     job.Owner,
+			"archiveAt": archiveAt.Format(time.RFC3339),
+		},
+	}
+}
+
+// archivedMessage 已归档的通知
+func archivedMessage(job *common.Job, reason string) *notify.Message {
+	return &notify.Message{
+		Event: EventJobArchived,
+		Title: "job " + job.Name + " archived",
+		Text:  fmt.Sprintf("job %s was archived (%s), it can be restored with POST /api/v1/job/restore/%s", job.Name, reason, job.Name),
+		Fields: map[string]string{
+			"jobName":   job.Name,
+			"namespace": common.NamespaceOf(job.Namespace),
+			"owner":     job.Owner,
+		},
+	}
+}
@@ -0,0 +1,59 @@
+package archiver
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+)
+
+func TestPlan(t *testing.T) {
+	now := time.Date(2024, 6, 30, 4, 0, 0, 0, time.UTC)
+	daysAgo := func(days int) int64 { return now.AddDate(0, 0, -days).Unix() }
+
+	jobs := []*common.Job{
+		{Name: "active"},
+		{Name: "recent", Disabled: true, DisabledAt: daysAgo(10)},
+		{Name: "soon", Disabled: true, DisabledAt: daysAgo(28)},
+		{Name: "overdue", Disabled: true, DisabledAt: daysAgo(40)},
+		{Name: "noticed", Disabled: true, DisabledAt: daysAgo(31)},
+		{Name: "legacy", Disabled: true, UpdatedAt: daysAgo(29)},
+	}
+	notices := map[string]*notice{
+		"noticed": {DisabledAt: daysAgo(31), NoticedAt: daysAgo(3)},
+		"active":  {DisabledAt: daysAgo(50), NoticedAt: daysAgo(5)},
+	}
+
+	remind, archive, next := plan(jobs, notices, now, 30, 3)
+	assert.Equal(t, []string{"legacy", "overdue", "soon"}, names(remind),
+		"Jobs entering the notice period should be reminded, legacy jobs use updatedAt")
+	assert.Equal(t, []string{"noticed"}, names(archive), "Overdue jobs should wait for the notice period")
+
+	require.Contains(t, next, "overdue")
+	assert.Equal(t, now.Unix(), next["overdue"].NoticedAt)
+	assert.NotContains(t, next, "noticed", "Archived jobs should be dropped from the notices")
+	assert.NotContains(t, next, "active", "Enabled jobs should be dropped from the notices")
+	assert.NotContains(t, next, "recent")
+
+	// 任务重新禁用后重新提醒
+	notices = map[string]*notice{"soon": {DisabledAt: daysAgo(60), NoticedAt: daysAgo(30)}}
+	remind, archive, _ = plan(jobs[2:3], notices, now, 30, 3)
+	assert.Equal(t, []string{"soon"}, names(remind))
+	assert.Empty(t, archive)
+
+	// 不提醒时到期直接归档
+	remind, archive, _ = plan(jobs, nil, now, 30, 0)
+	assert.Empty(t, remind)
+	assert.Equal(t, []string{"noticed", "overdue"}, names(archive))
+}
+
+func names(jobs []*common.Job) []string {
+	result := make([]string, 0, len(jobs))
+	for _, job := range jobs {
+		result = append(result, job.Name)
+	}
+	return result
+}
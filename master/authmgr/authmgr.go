@@ -0,0 +1,252 @@
+package authmgr
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+	"github.com/fyerfyer/scheduler-refactor/pkg/etcd"
+)
+
+// 令牌相关的参数
+const (
+	DefaultTokenTTL = time.Hour      // 签发JWT的默认有效期
+	MaxTokenTTL     = 24 * time.Hour // 签发JWT的最长有效期
+)
+
+// bootstrapUser 以启动密钥调用时的用户名
+const bootstrapUser = "bootstrap-admin"
+
+// Identity 认证后的调用方身份
+type Identity struct {
+	User       string   `json:"user"`                 // 用户名
+	Role       string   `json:"role,omitempty"`       // 角色
+	Namespaces []string `json:"namespaces,omitempty"` // 可访问的命名空间
+}
+
+// AuthManager 认证管理器，校验API密钥和JWT，管理保存在etcd中的API密钥
+type AuthManager struct {
+	etcdClient   *etcd.Client // etcd客户端
+	logger       *zap.Logger  // 日志对象
+	jwtSecret    []byte       // JWT的HS256签名密钥，为空时不接受JWT
+	bootstrapKey string       // 配置文件中的管理员密钥，用于创建第一批API密钥
+	now          func() time.Time
+}
+
+// NewAuthManager 创建认证管理器
+func NewAuthManager(etcdClient *etcd.Client, logger *zap.Logger) *AuthManager {
+	return &AuthManager{
+		etcdClient: etcdClient,
+		logger:     logger,
+		now:        time.Now,
+	}
+}
+
+// SetJWTSecret 设置JWT的签名密钥，为空时不接受也不签发JWT
+func (am *AuthManager) SetJWTSecret(secret string) {
+	am.jwtSecret = []byte(secret)
+}
+
+// SetBootstrapKey 设置管理员密钥，以该密钥调用时为管理员
+func (am *AuthManager) SetBootstrapKey(key string) {
+	am.bootstrapKey = key
+}
+
+// JWTEnabled 是否配置了JWT签名密钥
+func (am *AuthManager) JWTEnabled() bool {
+	return len(am.jwtSecret) > 0
+}
+
+// Authenticate 校验凭证并返回调用方身份。包含两个"."的凭证按JWT校验，其余按API密钥校验，
+// 凭证无效时返回ErrUnauthenticated
+func (am *AuthManager) Authenticate(credential string) (*Identity, error) {
+	if credential == "" {
+		return nil, common.ErrUnauthenticated
+	}
+	if strings.Count(credential, ".") == 2 {
+		return am.authenticateJWT(credential)
+	}
+	return am.authenticateKey(credential)
+}
+
+// authenticateKey 校验API密钥，密钥明文格式为<密钥ID>.<随机串>
+func (am *AuthManager) authenticateKey(key string) (*Identity, error) {
+	if am.bootstrapKey != "" && subtle.ConstantTimeCompare([]byte(key), []byte(am.bootstrapKey)) == 1 {
+		return &Identity{User: bootstrapUser, Role: common.RoleAdmin}, nil
+	}
+
+	id, secret, ok := strings.Cut(key, ".")
+	if !ok || id == "" || secret == "" {
+		return nil, common.ErrUnauthenticated
+	}
+
+	apiKey, err := am.getKey(id)
+	if err != nil {
+		if errors.Is(err, common.ErrAPIKeyNotFound) {
+			return nil, common.ErrUnauthenticated
+		}
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare([]byte(hashSecret(secret)), []byte(apiKey.SecretHash)) != 1 || apiKey.Expired(am.now()) {
+		return nil, common.ErrUnauthenticated
+	}
+
+	return &Identity{User: apiKey.User, Role: apiKey.Role, Namespaces: apiKey.Namespaces}, nil
+}
+
+// authenticateJWT 校验JWT
+func (am *AuthManager) authenticateJWT(token string) (*Identity, error) {
+	if !am.JWTEnabled() {
+		return nil, common.ErrUnauthenticated
+	}
+
+	claims, err := parseJWT(token, am.jwtSecret, am.now())
+	if err != nil {
+		am.logger.Debug("rejected jwt", zap.Error(err))
+		return nil, common.ErrUnauthenticated
+	}
+	return &Identity{User: claims.Subject, Role: claims.Role, Namespaces: claims.Namespaces}, nil
+}
+
+// IssueToken 为调用方签发有效期为ttl的JWT，ttl为0时使用默认有效期，超过上限时按上限签发
+func (am *AuthManager) IssueToken(identity *Identity, ttl time.Duration) (string, int64, error) {
+	if !am.JWTEnabled() {
+		return "", 0, fmt.Errorf("jwt secret is not configured")
+	}
+	if ttl <= 0 {
+		ttl = DefaultTokenTTL
+	}
+	ttl = min(ttl, MaxTokenTTL)
+
+	now := am.now()
+	claims := &jwtClaims{
+		Subject:    identity.User,
+		Role:       identity.Role,
+		Namespaces: identity.Namespaces,
+		IssuedAt:   now.Unix(),
+		ExpiresAt:  now.Add(ttl).Unix(),
+	}
+	token, err := signJWT(claims, am.jwtSecret)
+	if err != nil {
+		return "", 0, err
+	}
+	return token, claims.ExpiresAt, nil
+}
+
+// CreateKey 创建API密钥，返回密钥明文，明文只在此时返回一次
+func (am *AuthManager) CreateKey(key *common.APIKey) (string, error) {
+	if err := key.Validate(); err != nil {
+		return "", err
+	}
+
+	id, err := randomHex(8)
+	if err != nil {
+		return "", err
+	}
+	secret, err := randomHex(32)
+	if err != nil {
+		return "", err
+	}
+
+	key.ID = id
+	key.SecretHash = hashSecret(secret)
+	key.CreatedAt = am.now().Unix()
+
+	data, err := json.Marshal(key)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal api key: %v", err)
+	}
+	if _, err = am.etcdClient.Put(common.APIKeyDir+id, string(data)); err != nil {
+		am.logger.Error("failed to save api key",
+			zap.String("id", id),
+			zap.Error(err))
+		return "", err
+	}
+
+	key.SecretHash = ""
+	am.logger.Info("api key created",
+		zap.String("id", id),
+		zap.String("user", key.User),
+		zap.String("createdBy", key.CreatedBy))
+	return id + "." + secret, nil
+}
+
+// ListKeys 获取所有API密钥，不含密钥哈希，按创建时间排序
+func (am *AuthManager) ListKeys() ([]*common.APIKey, error) {
+	resp, err := am.etcdClient.GetWithPrefix(common.APIKeyDir)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]*common.APIKey, 0, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		key := &common.APIKey{}
+		if err = json.Unmarshal(kv.Value, key); err != nil {
+			am.logger.Error("failed to unmarshal api key",
+				zap.String("key", string(kv.Key)),
+				zap.Error(err))
+			continue
+		}
+		key.SecretHash = ""
+		keys = append(keys, key)
+	}
+
+	sort.Slice(keys, func(i, j int) bool { return keys[i].CreatedAt < keys[j].CreatedAt })
+	return keys, nil
+}
+
+// DeleteKey 吊销API密钥，吊销后立即失效
+func (am *AuthManager) DeleteKey(id string) error {
+	resp, err := am.etcdClient.Delete(common.APIKeyDir + id)
+	if err != nil {
+		return err
+	}
+	if resp.Deleted == 0 {
+		return common.ErrAPIKeyNotFound
+	}
+
+	am.logger.Info("api key revoked", zap.String("id", id))
+	return nil
+}
+
+// getKey 读取API密钥，每次认证都从etcd读取，吊销和过期立即生效
+func (am *AuthManager) getKey(id string) (*common.APIKey, error) {
+	resp, err := am.etcdClient.Get(common.APIKeyDir + id)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Count == 0 {
+		return nil, common.ErrAPIKeyNotFound
+	}
+
+	key := &common.APIKey{}
+	if err = json.Unmarshal(resp.Kvs[0].Value, key); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal api key: %v", err)
+	}
+	return key, nil
+}
+
+// hashSecret 计算密钥的SHA-256哈希
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// randomHex 生成n字节的随机串
+func randomHex(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
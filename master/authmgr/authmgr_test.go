@@ -0,0 +1,79 @@
+package authmgr
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+)
+
+func TestJWT(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	secret := []byte("secret")
+	claims := &jwtClaims{Subject: "alice", Role: common.RoleAdmin, Namespaces: []string{"ops"}, ExpiresAt: now.Add(time.Hour).Unix()}
+
+	token, err := signJWT(claims, secret)
+	require.NoError(t, err)
+	parsed, err := parseJWT(token, secret, now)
+	require.NoError(t, err)
+	assert.Equal(t, claims, parsed)
+
+	_, err = parseJWT(token, []byte("other"), now)
+	assert.Error(t, err, "Tokens signed with another secret should be rejected")
+
+	_, err = parseJWT(token, secret, now.Add(time.Hour))
+	assert.Error(t, err, "Expired tokens should be rejected")
+
+	// 篡改声明后签名不匹配
+	parts := strings.Split(token, ".")
+	forged, err := signJWT(&jwtClaims{Subject: "mallory", Role: common.RoleAdmin, ExpiresAt: claims.ExpiresAt}, secret)
+	require.NoError(t, err)
+	_, err = parseJWT(parts[0]+"."+strings.Split(forged, ".")[1]+"."+parts[2], secret, now)
+	assert.Error(t, err, "Tampered tokens should be rejected")
+
+	// 不接受alg为none的令牌
+	_, err = parseJWT("eyJhbGciOiJub25lIn0."+parts[1]+".", secret, now)
+	assert.Error(t, err)
+
+	noExpiry, _ := signJWT(&jwtClaims{Subject: "alice"}, secret)
+	_, err = parseJWT(noExpiry, secret, now)
+	assert.Error(t, err, "Tokens without expiry should be rejected")
+}
+
+func TestAuthenticate(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	am := NewAuthManager(nil, zap.NewNop())
+	am.now = func() time.Time { return now }
+	am.SetBootstrapKey("bootstrap")
+
+	identity, err := am.Authenticate("bootstrap")
+	require.NoError(t, err)
+	assert.Equal(t, common.RoleAdmin, identity.Role)
+
+	for _, credential := range []string{"", "wrong", "id.", ".secret"} {
+		_, err = am.Authenticate(credential)
+		assert.ErrorIs(t, err, common.ErrUnauthenticated, credential)
+	}
+
+	// 未配置签名密钥时不接受也不签发JWT
+	_, _, err = am.IssueToken(identity, 0)
+	assert.Error(t, err)
+
+	am.SetJWTSecret("secret")
+	token, expiresAt, err := am.IssueToken(&Identity{User: "bob", Namespaces: []string{"data"}}, 48*time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, now.Add(MaxTokenTTL).Unix(), expiresAt, "TTL should be capped")
+
+	identity, err = am.Authenticate(token)
+	require.NoError(t, err)
+	assert.Equal(t, &Identity{User: "bob", Namespaces: []string{"data"}}, identity)
+
+	am.now = func() time.Time { return now.Add(MaxTokenTTL) }
+	_, err = am.Authenticate(token)
+	assert.ErrorIs(t, err, common.ErrUnauthenticated)
+}
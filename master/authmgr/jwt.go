@@ -0,0 +1,89 @@
+package authmgr
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// jwtHeader 签发的JWT头部，只支持HS256
+var jwtHeader = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+
+// jwtClaims JWT中的身份声明
+type jwtClaims struct {
+	Subject    string   `json:"sub"`                  // 用户名
+	Role       string   `json:"role,omitempty"`       // 角色
+	Namespaces []string `json:"namespaces,omitempty"` // 可访问的命名空间
+	IssuedAt   int64    `json:"iat,omitempty"`        // 签发时间
+	NotBefore  int64    `json:"nbf,omitempty"`        // 生效时间
+	ExpiresAt  int64    `json:"exp"`                  // 过期时间，必须设置
+}
+
+// signJWT 使用HS256签名生成JWT
+func signJWT(claims *jwtClaims, secret []byte) (string, error) {
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal jwt claims: %v", err)
+	}
+
+	signingInput := jwtHeader + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return signingInput + "." + jwtSignature(signingInput, secret), nil
+}
+
+// parseJWT 校验HS256签名和有效期并解析声明，外部签发的JWT同样需要sub和exp
+func parseJWT(token string, secret []byte, now time.Time) (*jwtClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed jwt")
+	}
+
+	headerData, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid jwt header: %v", err)
+	}
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if err = json.Unmarshal(headerData, &header); err != nil {
+		return nil, fmt.Errorf("invalid jwt header: %v", err)
+	}
+	if header.Alg != "HS256" {
+		return nil, fmt.Errorf("unsupported jwt algorithm %q", header.Alg)
+	}
+
+	expected := jwtSignature(parts[0]+"."+parts[1], secret)
+	if !hmac.Equal([]byte(parts[2]), []byte(expected)) {
+		return nil, errors.New("invalid jwt signature")
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("invalid jwt payload: %v", err)
+	}
+	claims := &jwtClaims{}
+	if err = json.Unmarshal(payload, claims); err != nil {
+		return nil, fmt.Errorf("invalid jwt payload: %v", err)
+	}
+
+	switch {
+	case claims.Subject == "":
+		return nil, errors.New("jwt has no subject")
+	case claims.ExpiresAt == 0 || now.Unix() >= claims.ExpiresAt:
+		return nil, errors.New("jwt has expired or has no expiry")
+	case claims.NotBefore > 0 && now.Unix() < claims.NotBefore:
+		return nil, errors.New("jwt is not valid yet")
+	}
+	return claims, nil
+}
+
+// jwtSignature 计算HS256签名
+func jwtSignature(signingInput string, secret []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(signingInput))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
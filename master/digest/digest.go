@@ -0,0 +1,413 @@
+package digest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.uber.org/zap"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+	"github.com/fyerfyer/scheduler-refactor/master/jobmgr"
+	"github.com/fyerfyer/scheduler-refactor/master/logmgr"
+	"github.com/fyerfyer/scheduler-refactor/pkg/etcd"
+	"github.com/fyerfyer/scheduler-refactor/pkg/notify"
+)
+
+// EventWeeklyDigest 每周摘要的通知事件类型
+const EventWeeklyDigest = "weekly_digest"
+
+// LeaderChecker 判断当前master是否为leader，只有leader发送摘要
+type LeaderChecker interface {
+	IsLeader() bool
+}
+
+// JobCount 任务在摘要周期内的计数
+type JobCount struct {
+	JobName string `json:"jobName"` // 任务名称
+	Count   int    `json:"count"`   // 失败或超时次数
+	Runs    int    `json:"runs"`    // 执行次数
+}
+
+// NamespaceDigest 一个命名空间在摘要周期内的情况
+type NamespaceDigest struct {
+	Namespace   string      `json:"namespace"`   // 命名空间
+	Jobs        int         `json:"jobs"`        // 当前任务数
+	Added       []string    `json:"added"`       // 新增的任务
+	Removed     []string    `json:"removed"`     // 删除的任务
+	Runs        int         `json:"runs"`        // 执行次数，不含跳过的执行和实验命令
+	Failures    int         `json:"failures"`    // 失败次数（失败、超时、被终止）
+	TopFailures []*JobCount `json:"topFailures"` // 失败次数最多的任务
+	SLABreaches []*JobCount `json:"slaBreaches"` // 执行超过任务超时时间的任务，按超时次数排序
+	CPUHours    float64     `json:"cpuHours"`    // CPU小时
+	GBHours     float64     `json:"gbHours"`     // 内存GB小时
+	Cost        float64     `json:"cost"`        // 估算成本
+}
+
+// Digest 每周摘要，按命名空间汇总
+type Digest struct {
+	Start      int64              `json:"start"`      // 统计开始时间
+	End        int64              `json:"end"`        // 统计结束时间
+	Namespaces []*NamespaceDigest `json:"namespaces"` // 按命名空间排序
+}
+
+// state 上次发送摘要的记录，用于确定下次的统计周期和新增、删除的任务
+type state struct {
+	SentAt int64               `json:"sentAt"` // 发送时间
+	Jobs   map[string][]string `json:"jobs"`   // 发送时各命名空间的任务名
+}
+
+// Manager 每周摘要管理器，按配置的时间生成摘要并通过webhook发送
+type Manager struct {
+	etcdClient *etcd.Client       // etcd客户端
+	jobMgr     *jobmgr.JobManager // 任务管理器
+	logMgr     *logmgr.LogManager // 日志管理器
+	notifier   notify.Notifier    // 摘要通知
+	prices     logmgr.CostPrices  // 成本单价
+	leader     LeaderChecker      // leader判断，为nil时视为leader
+	logger     *zap.Logger        // 日志对象
+	ctx        context.Context    // 上下文，用于控制退出
+	cancelFunc context.CancelFunc // 取消函数
+}
+
+// NewManager 创建每周摘要管理器
+func NewManager(etcdClient *etcd.Client, jobMgr *jobmgr.JobManager, logMgr *logmgr.LogManager, notifier notify.Notifier, prices logmgr.CostPrices, logger *zap.Logger) *Manager {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &Manager{
+		etcdClient: etcdClient,
+		jobMgr:     jobMgr,
+		logMgr:     logMgr,
+		notifier:   notifier,
+		prices:     prices,
+		logger:     logger,
+		ctx:        ctx,
+		cancelFunc: cancel,
+	}
+}
+
+// SetLeader 设置leader判断，设置后只有leader发送摘要
+func (m *Manager) SetLeader(leader LeaderChecker) {
+	m.leader = leader
+}
+
+// Start 按cron表达式定时发送摘要，schedule为空时使用默认时间
+func (m *Manager) Start(schedule string) error {
+	if schedule == "" {
+		schedule = common.DefaultDigestSchedule
+	}
+
+	parser := cron.NewParser(cron.Second | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow)
+	expr, err := parser.Parse(schedule)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		for {
+			timer := time.NewTimer(time.Until(expr.Next(time.Now())))
+
+			select {
+			case <-m.ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+				if m.leader != nil && !m.leader.IsLeader() {
+					m.logger.Debug("not master leader, skip weekly digest")
+					continue
+				}
+				if err := m.Send(); err != nil {
+					m.logger.Error("failed to send weekly digest", zap.Error(err))
+				}
+			}
+		}
+	}()
+
+	m.logger.Info("weekly digest started", zap.String("schedule", schedule))
+	return nil
+}
+
+// Stop 停止定时发送
+func (m *Manager) Stop() {
+	m.cancelFunc()
+}
+
+// Build 生成从上次发送到现在的摘要，不发送也不更新发送记录
+func (m *Manager) Build() (*Digest, error) {
+	previous, _, err := m.loadState()
+	if err != nil {
+		return nil, err
+	}
+	digest, _, err := m.build(previous, time.Now())
+	return digest, err
+}
+
+// Send 生成摘要并发送，每个命名空间一条消息。先在etcd中更新发送记录，
+// 多个master同时发送时只有更新成功的一方发送
+func (m *Manager) Send() error {
+	previous, revision, err := m.loadState()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	digest, current, err := m.build(previous, now)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(current)
+	if err != nil {
+		return err
+	}
+	applied, err := m.etcdClient.ApplyIfUnchanged(common.DigestStateKey, revision,
+		clientv3.OpPut(common.DigestStateKey, string(data)))
+	if err != nil {
+		return err
+	}
+	if !applied {
+		m.logger.Info("weekly digest already sent by another master")
+		return nil
+	}
+
+	for _, namespace := range digest.Namespaces {
+		if err := m.notifier.Notify(namespaceMessage(digest, namespace)); err != nil {
+			m.logger.Warn("failed to send weekly digest",
+				zap.String("namespace", namespace.Namespace),
+				zap.Error(err))
+		}
+	}
+
+	m.logger.Info("weekly digest sent",
+		zap.Int("namespaces", len(digest.Namespaces)),
+		zap.Time("since", time.Unix(digest.Start, 0)))
+	return nil
+}
+
+// loadState 读取上次发送的记录和它的修改版本，从未发送过时返回nil
+func (m *Manager) loadState() (*state, int64, error) {
+	resp, err := m.etcdClient.Get(common.DigestStateKey)
+	if err != nil {
+		return nil, 0, err
+	}
+	if resp.Count == 0 {
+		return nil, 0, nil
+	}
+
+	kv := resp.Kvs[0]
+	previous := &state{}
+	if err = json.Unmarshal(kv.Value, previous); err != nil {
+		// 记录损坏时按从未发送处理，发送后会被覆盖
+		m.logger.Warn("invalid weekly digest state, ignoring it", zap.Error(err))
+		return nil, kv.ModRevision, nil
+	}
+	return previous, kv.ModRevision, nil
+}
+
+// build 读取任务和日志生成摘要，同时返回本次发送后应保存的记录
+func (m *Manager) build(previous *state, now time.Time) (*Digest, *state, error) {
+	start := now.AddDate(0, 0, -common.DefaultDigestDays)
+	if previous != nil && previous.SentAt > 0 {
+		start = time.Unix(previous.SentAt, 0)
+	}
+
+	jobs, err := m.jobMgr.ListJobs()
+	if err != nil {
+		return nil, nil, err
+	}
+	logs, err := m.logMgr.GetLogsSince(start)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	digest := buildDigest(jobs, logs, previous, start.Unix(), now.Unix(), m.prices)
+	current := &state{SentAt: now.Unix(), Jobs: jobsByNamespace(jobs)}
+	return digest, current, nil
+}
+
+// jobsByNamespace 按命名空间整理任务名，任务名按字母排序
+func jobsByNamespace(jobs []*common.Job) map[string][]string {
+	names := make(map[string][]string)
+	for _, job := range jobs {
+		namespace := common.NamespaceOf(job.Namespace)
+		names[namespace] = append(names[namespace], job.Name)
+	}
+	for _, jobNames := range names {
+		sort.Strings(jobNames)
+	}
+	return names
+}
+
+// buildDigest 汇总[start, end)内的任务变化和执行情况。没有上次发送记录时，
+// 新增任务按创建时间判断，删除的任务无法得知
+func buildDigest(jobs []*common.Job, logs []*common.JobLog, previous *state, start, end int64, prices logmgr.CostPrices) *Digest {
+	namespaces := make(map[string]*NamespaceDigest)
+	get := func(namespace string) *NamespaceDigest {
+		namespace = common.NamespaceOf(namespace)
+		digest, ok := namespaces[namespace]
+		if !ok {
+			digest = &NamespaceDigest{Namespace: namespace, Added: []string{}, Removed: []string{}}
+			namespaces[namespace] = digest
+		}
+		return digest
+	}
+
+	current := jobsByNamespace(jobs)
+	for _, job := range jobs {
+		digest := get(job.Namespace)
+		digest.Jobs++
+		if previous == nil && job.CreatedAt >= start {
+			digest.Added = append(digest.Added, job.Name)
+		}
+	}
+	if previous != nil {
+		for namespace, names := range current {
+			get(namespace).Added = difference(names, previous.Jobs[namespace])
+		}
+		for namespace, names := range previous.Jobs {
+			get(namespace).Removed = difference(names, current[namespace])
+		}
+	}
+
+	failures := make(map[string]map[string]*JobCount)
+	breaches := make(map[string]map[string]*JobCount)
+	for _, log := range logs {
+		if log.StartTime < start || log.StartTime >= end {
+			continue
+		}
+		digest := get(log.Namespace)
+
+		cpuHours, gbHours, cost := logmgr.RunCost(log, prices)
+		digest.CPUHours += cpuHours
+		digest.GBHours += gbHours
+		digest.Cost += cost
+
+		// 实验命令和跳过的执行不是任务本身的执行
+		status := log.GetStatus()
+		if log.Experiment || status == common.RunStatusSkipped {
+			continue
+		}
+		digest.Runs++
+		if log.QuietFailures {
+			// 静默失败的任务失败在预期内，不计入失败和超时
+			continue
+		}
+		count(failures, digest.Namespace, log.JobName, status.IsFailure())
+		count(breaches, digest.Namespace, log.JobName, status == common.RunStatusTimeout)
+		if status.IsFailure() {
+			digest.Failures++
+		}
+	}
+
+	result := &Digest{Start: start, End: end, Namespaces: make([]*NamespaceDigest, 0, len(namespaces))}
+	for namespace, digest := range namespaces {
+		digest.TopFailures = topJobs(failures[namespace])
+		digest.SLABreaches = topJobs(breaches[namespace])
+		result.Namespaces = append(result.Namespaces, digest)
+	}
+	sort.Slice(result.Namespaces, func(i, j int) bool {
+		return result.Namespaces[i].Namespace < result.Namespaces[j].Namespace
+	})
+	return result
+}
+
+// count 记录一次执行，matched表示这次执行是否计入Count
+func count(counts map[string]map[string]*JobCount, namespace, jobName string, matched bool) {
+	if counts[namespace] == nil {
+		counts[namespace] = make(map[string]*JobCount)
+	}
+	job, ok := counts[namespace][jobName]
+	if !ok {
+		job = &JobCount{JobName: jobName}
+		counts[namespace][jobName] = job
+	}
+	job.Runs++
+	if matched {
+		job.Count++
+	}
+}
+
+// topJobs 按次数倒序返回次数大于0的前DigestTopJobs个任务
+func topJobs(counts map[string]*JobCount) []*JobCount {
+	jobs := make([]*JobCount, 0)
+	for _, job := range counts {
+		if job.Count > 0 {
+			jobs = append(jobs, job)
+		}
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		if jobs[i].Count != jobs[j].Count {
+			return jobs[i].Count > jobs[j].Count
+		}
+		return jobs[i].JobName < jobs[j].JobName
+	})
+	if len(jobs) > common.DigestTopJobs {
+		jobs = jobs[:common.DigestTopJobs]
+	}
+	return jobs
+}
+
+// difference 返回在names中但不在others中的名称
+func difference(names, others []string) []string {
+	exclude := make(map[string]bool, len(others))
+	for _, name := range others {
+		exclude[name] = true
+	}
+	result := make([]string, 0)
+	for _, name := range names {
+		if !exclude[name] {
+			result = append(result, name)
+		}
+	}
+	return result
+}
+
+// namespaceMessage 生成一个命名空间的摘要通知
+func namespaceMessage(digest *Digest, namespace *NamespaceDigest) *notify.Message {
+	period := fmt.Sprintf("%s ~ %s",
+		time.Unix(digest.Start, 0).Format("2006-01-02"),
+		time.Unix(digest.End, 0).Format("2006-01-02"))
+
+	lines := []string{
+		fmt.Sprintf("Jobs: %d (%d added, %d removed)", namespace.Jobs, len(namespace.Added), len(namespace.Removed)),
+		fmt.Sprintf("Runs: %d, failures: %d", namespace.Runs, namespace.Failures),
+	}
+	if len(namespace.Added) > 0 {
+		lines = append(lines, "Added: "+strings.Join(namespace.Added, ", "))
+	}
+	if len(namespace.Removed) > 0 {
+		lines = append(lines, "Removed: "+strings.Join(namespace.Removed, ", "))
+	}
+	if len(namespace.TopFailures) > 0 {
+		lines = append(lines, "Top failures: "+formatCounts(namespace.TopFailures))
+	}
+	if len(namespace.SLABreaches) > 0 {
+		lines = append(lines, "Timeouts: "+formatCounts(namespace.SLABreaches))
+	}
+	lines = append(lines, fmt.Sprintf("Estimated cost: %.2f (%.2f CPU hours, %.2f GB hours)",
+		namespace.Cost, namespace.CPUHours, namespace.GBHours))
+
+	return &notify.Message{
+		Event: EventWeeklyDigest,
+		Title: fmt.Sprintf("Weekly digest for namespace %s", namespace.Namespace),
+		Text:  strings.Join(lines, "\n"),
+		Fields: map[string]string{
+			"namespace": namespace.Namespace,
+			"period":    period,
+		},
+	}
+}
+
+// formatCounts 格式化为"job (3/20), ..."，即次数和执行次数
+func formatCounts(jobs []*JobCount) string {
+	items := make([]string, len(jobs))
+	for i, job := range jobs {
+		items[i] = fmt.Sprintf("%s (%d/%d)", job.JobName, job.Count, job.Runs)
+	}
+	return strings.Join(items, ", ")
+}
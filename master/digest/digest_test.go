@@ -0,0 +1,74 @@
+package digest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+	"github.com/fyerfyer/scheduler-refactor/master/logmgr"
+)
+
+func TestBuildDigest(t *testing.T) {
+	jobs := []*common.Job{
+		{Name: "backup", Namespace: "ops", CreatedAt: 50},
+		{Name: "report", Namespace: "ops", CreatedAt: 150},
+		{Name: "sync"},
+	}
+	logs := []*common.JobLog{
+		{JobName: "backup", Namespace: "ops", StartTime: 110, EndTime: 120, Status: common.RunStatusFailed, CPUTime: 3600},
+		{JobName: "backup", Namespace: "ops", StartTime: 120, EndTime: 130, Status: common.RunStatusTimeout},
+		{JobName: "report", Namespace: "ops", StartTime: 130, EndTime: 140, Status: common.RunStatusSuccess},
+		{JobName: "report", Namespace: "ops", StartTime: 140, EndTime: 150, Status: common.RunStatusFailed, Experiment: true},
+		{JobName: "report", Namespace: "ops", StartTime: 145, EndTime: 150, Status: common.RunStatusTimeout, QuietFailures: true},
+		{JobName: "sync", StartTime: 150, EndTime: 150, Status: common.RunStatusSkipped},
+		{JobName: "sync", StartTime: 90, EndTime: 95, Status: common.RunStatusFailed},
+	}
+	prices := logmgr.CostPrices{CPUHour: 2}
+
+	digest := buildDigest(jobs, logs, nil, 100, 200, prices)
+	require.Len(t, digest.Namespaces, 2)
+	assert.Equal(t, common.DefaultNamespace, digest.Namespaces[0].Namespace)
+
+	ops := digest.Namespaces[1]
+	assert.Equal(t, "ops", ops.Namespace)
+	assert.Equal(t, 2, ops.Jobs)
+	assert.Equal(t, []string{"report"}, ops.Added, "Without a previous digest jobs created in the period are new")
+	assert.Empty(t, ops.Removed)
+	assert.Equal(t, 4, ops.Runs, "Experiment runs should not be counted")
+	assert.Equal(t, 2, ops.Failures, "Quiet failures should not be counted as failures")
+	assert.Equal(t, []*JobCount{{JobName: "backup", Count: 2, Runs: 2}}, ops.TopFailures)
+	assert.Equal(t, []*JobCount{{JobName: "backup", Count: 1, Runs: 2}}, ops.SLABreaches)
+	assert.Equal(t, 2.0, ops.Cost)
+
+	defaults := digest.Namespaces[0]
+	assert.Zero(t, defaults.Runs, "Skipped runs and runs before the period should not be counted")
+	assert.Empty(t, defaults.TopFailures)
+
+	previous := &state{SentAt: 100, Jobs: map[string][]string{"ops": {"backup", "cleanup"}}}
+	digest = buildDigest(jobs, logs, previous, 100, 200, prices)
+	ops = digest.Namespaces[1]
+	assert.Equal(t, []string{"report"}, ops.Added)
+	assert.Equal(t, []string{"cleanup"}, ops.Removed)
+	assert.Equal(t, []string{"sync"}, digest.Namespaces[0].Added)
+}
+
+func TestNamespaceMessage(t *testing.T) {
+	digest := &Digest{Start: 0, End: 7 * 86400}
+	namespace := &NamespaceDigest{
+		Namespace:   "ops",
+		Jobs:        2,
+		Added:       []string{"report"},
+		Runs:        10,
+		Failures:    3,
+		TopFailures: []*JobCount{{JobName: "backup", Count: 3, Runs: 5}},
+	}
+
+	msg := namespaceMessage(digest, namespace)
+	assert.Equal(t, EventWeeklyDigest, msg.Event)
+	assert.Equal(t, "ops", msg.Fields["namespace"])
+	assert.Contains(t, msg.Text, "Jobs: 2 (1 added, 0 removed)")
+	assert.Contains(t, msg.Text, "Top failures: backup (3/5)")
+	assert.NotContains(t, msg.Text, "Timeouts")
+}
@@ -0,0 +1,125 @@
+package election
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.uber.org/zap"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+	"github.com/fyerfyer/scheduler-refactor/pkg/etcd"
+)
+
+// Elector master选主器，通过etcd租约锁保证同一时刻只有一个master是leader
+type Elector struct {
+	etcdClient *etcd.Client       // etcd客户端
+	logger     *zap.Logger        // 日志对象
+	key        string             // 选主key
+	id         string             // 本master标识，作为选主key的值
+	ttl        int64              // 租约时间(秒)
+	leader     atomic.Bool        // 当前是否为leader
+	leaseID    clientv3.LeaseID   // 持有的租约ID
+	lock       sync.Mutex         // 保护leaseID
+	ctx        context.Context    // 上下文，用于控制退出
+	cancelFunc context.CancelFunc // 取消函数
+}
+
+// NewElector 创建选主器
+func NewElector(etcdClient *etcd.Client, logger *zap.Logger) *Elector {
+	ctx, cancel := context.WithCancel(context.Background())
+	hostname, _ := os.Hostname()
+
+	return &Elector{
+		etcdClient: etcdClient,
+		logger:     logger,
+		key:        common.MasterLeaderKey,
+		id:         fmt.Sprintf("%s:%d", hostname, os.Getpid()),
+		ttl:        common.MasterLeaderTTL,
+		ctx:        ctx,
+		cancelFunc: cancel,
+	}
+}
+
+// Start 开始竞选，失去leader身份后自动重新竞选
+func (e *Elector) Start() {
+	go e.campaignLoop()
+}
+
+// Stop 停止竞选并主动释放leader身份
+func (e *Elector) Stop() {
+	e.cancelFunc()
+
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	if e.leaseID != 0 {
+		e.etcdClient.ReleaseLock(e.key, e.id, e.leaseID)
+		e.leaseID = 0
+	}
+	e.leader.Store(false)
+}
+
+// IsLeader 判断当前master是否为leader
+func (e *Elector) IsLeader() bool {
+	return e.leader.Load()
+}
+
+// campaignLoop 循环竞选，每个租约周期重试一次
+func (e *Elector) campaignLoop() {
+	retry := time.Duration(e.ttl) * time.Second / 2
+
+	for {
+		leaseID, err := e.etcdClient.TryAcquireLock(e.key, e.id, e.ttl)
+		if err == nil {
+			e.lead(leaseID)
+		} else if !errors.Is(err, common.ErrLockAlreadyAcquired) {
+			e.logger.Warn("failed to campaign for leader", zap.Error(err))
+		}
+
+		select {
+		case <-e.ctx.Done():
+			return
+		case <-time.After(retry):
+		}
+	}
+}
+
+// lead 持有leader身份，直到续租失败或选主器停止
+func (e *Elector) lead(leaseID clientv3.LeaseID) {
+	keepAliveChan, err := e.etcdClient.KeepAlive(leaseID)
+	if err != nil {
+		e.logger.Warn("failed to keep leader lease alive", zap.Error(err))
+		return
+	}
+
+	e.lock.Lock()
+	e.leaseID = leaseID
+	e.lock.Unlock()
+	e.leader.Store(true)
+	e.logger.Info("became master leader")
+
+	defer func() {
+		e.leader.Store(false)
+		e.lock.Lock()
+		e.leaseID = 0
+		e.lock.Unlock()
+	}()
+
+	for {
+		select {
+		case <-e.ctx.Done():
+			return
+		case _, ok := <-keepAliveChan:
+			if !ok {
+				e.logger.Warn("lost master leadership")
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package election
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/fyerfyer/scheduler-refactor/config"
+	"github.com/fyerfyer/scheduler-refactor/pkg/etcd"
+)
+
+func setupEtcdClient(t *testing.T) *etcd.Client {
+	config.GlobalConfig = &config.Config{
+		EtcdEndpoints:   []string{"localhost:2379"},
+		EtcdDialTimeout: 5000,
+	}
+
+	client, err := etcd.NewClient()
+	require.NoError(t, err, "Failed to create etcd client")
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+func TestSingleLeader(t *testing.T) {
+	client := setupEtcdClient(t)
+	logger := zaptest.NewLogger(t)
+
+	first := NewElector(client, logger)
+	second := NewElector(client, logger)
+	first.Start()
+	defer first.Stop()
+
+	require.Eventually(t, first.IsLeader, 3*time.Second, 50*time.Millisecond, "First elector should become leader")
+
+	second.Start()
+	defer second.Stop()
+	time.Sleep(200 * time.Millisecond)
+	assert.False(t, second.IsLeader(), "Only one master should be leader")
+
+	// leader停止后另一个master接任
+	first.Stop()
+	assert.False(t, first.IsLeader())
+	require.Eventually(t, second.IsLeader, 10*time.Second, 100*time.Millisecond, "Second elector should take over")
+}
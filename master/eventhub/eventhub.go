@@ -0,0 +1,298 @@
+package eventhub
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.uber.org/zap"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+	"github.com/fyerfyer/scheduler-refactor/master/workermgr"
+	"github.com/fyerfyer/scheduler-refactor/pkg/etcd"
+)
+
+// 事件类型
+const (
+	EventJobSave       = "job.save"                                       // 保存任务
+	EventJobDelete     = "job.delete"                                     // 删除任务
+	EventRunStart      = "run.start"                                      // 开始执行
+	EventRunFinish     = "run.finish"                                     // 执行结束
+	EventWorkerJoin    = workerEventPrefix + workermgr.WorkerEventJoin    // worker注册
+	EventWorkerLeave   = workerEventPrefix + workermgr.WorkerEventLeave   // worker注销
+	EventWorkerOnline  = workerEventPrefix + workermgr.WorkerEventOnline  // worker恢复心跳
+	EventWorkerOffline = workerEventPrefix + workermgr.WorkerEventOffline // worker心跳超时
+)
+
+// workerEventPrefix worker事件类型的前缀
+const workerEventPrefix = "worker."
+
+// 事件中心参数
+const (
+	// subscriberBuffer 每个订阅者缓存的事件数，订阅者消费过慢时丢弃新事件
+	subscriberBuffer = 256
+
+	// retryInterval 监听中断后重新监听的间隔
+	retryInterval = time.Second
+)
+
+// WorkerSource worker变化事件的来源
+type WorkerSource interface {
+	Subscribe() (<-chan *workermgr.WorkerEvent, func())
+}
+
+// Event 推送给面板的一条事件
+type Event struct {
+	Type      string `json:"type"`               // 事件类型
+	Name      string `json:"name"`               // 任务名，worker事件为worker ID
+	Data      any    `json:"data,omitempty"`     // job.save为任务定义，run.*为执行意图，worker.*为节点信息
+	Revision  int64  `json:"revision,omitempty"` // 事件对应的etcd版本，worker事件为0
+	Time      int64  `json:"time"`               // 事件时间(毫秒)
+	Namespace string `json:"-"`                  // 任务所属命名空间，用于按调用方范围过滤
+	Owner     string `json:"-"`                  // 任务负责人，用于按调用方范围过滤
+}
+
+// IsWorker 是否为worker事件，worker事件不属于任何命名空间
+func (e *Event) IsWorker() bool {
+	return strings.HasPrefix(e.Type, workerEventPrefix)
+}
+
+// Hub 事件中心，由master的etcd监听和worker变化事件汇集任务保存/删除、执行开始/结束和worker上下线事件，
+// 推送给所有订阅者，面板不需要轮询列表接口
+type Hub struct {
+	etcdClient  *etcd.Client        // etcd客户端
+	workers     WorkerSource        // worker事件来源
+	logger      *zap.Logger         // 日志对象
+	subscribers map[int]chan *Event // 订阅者ID -> 事件通道
+	nextSubID   int                 // 下一个订阅者ID
+	subLock     sync.Mutex          // 保护subscribers
+	ctx         context.Context     // 上下文，用于控制退出
+	cancelFunc  context.CancelFunc  // 取消函数
+}
+
+// NewHub 创建事件中心
+func NewHub(etcdClient *etcd.Client, workers WorkerSource, logger *zap.Logger) *Hub {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &Hub{
+		etcdClient:  etcdClient,
+		workers:     workers,
+		logger:      logger,
+		subscribers: make(map[int]chan *Event),
+		ctx:         ctx,
+		cancelFunc:  cancel,
+	}
+}
+
+// Start 开始监听任务目录、执行意图目录和worker变化
+func (h *Hub) Start() {
+	go h.watchLoop(common.JobSaveDir, jobEvent)
+	go h.watchLoop(common.RunIntentDir, runEvent)
+	go h.forwardWorkers()
+
+	h.logger.Info("event hub started")
+}
+
+// Stop 停止监听
+func (h *Hub) Stop() {
+	h.cancelFunc()
+}
+
+// Subscribe 订阅事件，返回事件通道和取消订阅函数
+func (h *Hub) Subscribe() (<-chan *Event, func()) {
+	ch := make(chan *Event, subscriberBuffer)
+
+	h.subLock.Lock()
+	h.nextSubID++
+	id := h.nextSubID
+	h.subscribers[id] = ch
+	h.subLock.Unlock()
+
+	return ch, func() {
+		h.subLock.Lock()
+		delete(h.subscribers, id)
+		h.subLock.Unlock()
+	}
+}
+
+// publish 向所有订阅者发送事件，不阻塞监听
+func (h *Hub) publish(event *Event) {
+	h.subLock.Lock()
+	defer h.subLock.Unlock()
+
+	for _, ch := range h.subscribers {
+		select {
+		case ch <- event:
+		default:
+			h.logger.Warn("event subscriber is too slow, dropping event",
+				zap.String("type", event.Type),
+				zap.String("name", event.Name))
+		}
+	}
+}
+
+// forwardWorkers 转发worker变化事件
+func (h *Hub) forwardWorkers() {
+	events, unsubscribe := h.workers.Subscribe()
+	defer unsubscribe()
+
+	for {
+		select {
+		case <-h.ctx.Done():
+			return
+		case event := <-events:
+			h.publish(workerEvent(event))
+		}
+	}
+}
+
+// watchLoop 监听前缀下的变化并转换为事件，监听中断后从已推送到的版本继续，版本被压缩时从当前版本开始
+func (h *Hub) watchLoop(prefix string, convert func(*clientv3.Event) *Event) {
+	var revision int64
+	for {
+		err := h.watch(prefix, convert, &revision)
+
+		select {
+		case <-h.ctx.Done():
+			return
+		default:
+		}
+		if errors.Is(err, common.ErrRevisionCompacted) {
+			revision = 0
+		}
+		h.logger.Warn("event watch interrupted, rewatching",
+			zap.String("prefix", prefix),
+			zap.Error(err))
+
+		select {
+		case <-h.ctx.Done():
+			return
+		case <-time.After(retryInterval):
+		}
+	}
+}
+
+// watch 从revision之后开始监听，revision为0时从当前版本开始，监听结束时返回原因
+func (h *Hub) watch(prefix string, convert func(*clientv3.Event) *Event, revision *int64) error {
+	var from int64
+	if *revision > 0 {
+		from = *revision + 1
+	}
+
+	watchChan := h.etcdClient.WatchWithPrefixFrom(h.ctx, prefix, from, clientv3.WithPrevKV())
+	for watchResp := range watchChan {
+		if watchResp.CompactRevision != 0 {
+			return common.ErrRevisionCompacted
+		}
+		if err := watchResp.Err(); err != nil {
+			return common.NewEtcdError("watch", prefix, err)
+		}
+		for _, event := range watchResp.Events {
+			if e := convert(event); e != nil {
+				h.publish(e)
+			}
+		}
+		*revision = watchResp.Header.Revision
+	}
+	return fmt.Errorf("watch channel closed")
+}
+
+// jobEvent 将任务目录的变化转换为任务保存/删除事件，删除时从旧值获取命名空间
+func jobEvent(event *clientv3.Event) *Event {
+	e := &Event{
+		Type:     EventJobSave,
+		Name:     strings.TrimPrefix(string(event.Kv.Key), common.JobSaveDir),
+		Revision: event.Kv.ModRevision,
+		Time:     time.Now().UnixMilli(),
+	}
+
+	kv := event.Kv
+	if event.Type == clientv3.EventTypeDelete {
+		e.Type = EventJobDelete
+		kv = event.PrevKv
+	}
+	if job := parseJob(kv); job != nil {
+		e.Namespace = job.Namespace
+		e.Owner = job.Owner
+		if e.Type == EventJobSave {
+			e.Data = job
+		}
+	}
+	return e
+}
+
+// runEvent 将执行意图的变化转换为执行开始/结束事件。意图创建时为开始，写入结束状态时为结束，
+// 对账删除意图不产生事件
+func runEvent(event *clientv3.Event) *Event {
+	if event.Type == clientv3.EventTypeDelete {
+		return nil
+	}
+
+	intent := parseIntent(event.Kv)
+	if intent == nil {
+		return nil
+	}
+
+	eventType := EventRunStart
+	if intent.Finished() {
+		if prev := parseIntent(event.PrevKv); prev != nil && prev.Finished() {
+			return nil
+		}
+		eventType = EventRunFinish
+	} else if !event.IsCreate() {
+		return nil
+	}
+
+	return &Event{
+		Type:      eventType,
+		Name:      intent.JobName,
+		Data:      intent,
+		Revision:  event.Kv.ModRevision,
+		Time:      time.Now().UnixMilli(),
+		Namespace: intent.Namespace,
+		Owner:     intent.Owner,
+	}
+}
+
+// workerEvent 将worker变化事件转换为事件
+func workerEvent(event *workermgr.WorkerEvent) *Event {
+	e := &Event{
+		Type: workerEventPrefix + event.Type,
+		Name: event.WorkerID,
+		Time: event.Time,
+	}
+	if event.Worker != nil {
+		e.Data = event.Worker
+	}
+	return e
+}
+
+// parseJob 解析任务定义，无法解析时返回nil
+func parseJob(kv *mvccpb.KeyValue) *common.Job {
+	if kv == nil {
+		return nil
+	}
+	job := &common.Job{}
+	if err := json.Unmarshal(kv.Value, job); err != nil {
+		return nil
+	}
+	return job
+}
+
+// parseIntent 解析执行意图，无法解析时返回nil
+func parseIntent(kv *mvccpb.KeyValue) *common.RunIntent {
+	if kv == nil {
+		return nil
+	}
+	intent := &common.RunIntent{}
+	if err := json.Unmarshal(kv.Value, intent); err != nil {
+		return nil
+	}
+	return intent
+}
@@ -0,0 +1,98 @@
+package eventhub
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.uber.org/zap"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+	"github.com/fyerfyer/scheduler-refactor/master/workermgr"
+)
+
+func TestJobEvent(t *testing.T) {
+	value := []byte(`{"name":"backup","command":"echo backup","cronExpr":"0 0 * * * *","namespace":"ops","owner":"alice"}`)
+
+	save := jobEvent(&clientv3.Event{
+		Type: clientv3.EventTypePut,
+		Kv:   &mvccpb.KeyValue{Key: []byte(common.JobSaveDir + "backup"), Value: value, ModRevision: 10},
+	})
+	assert.Equal(t, EventJobSave, save.Type)
+	assert.Equal(t, "backup", save.Name)
+	assert.Equal(t, int64(10), save.Revision)
+	assert.Equal(t, "ops", save.Namespace)
+	require.IsType(t, &common.Job{}, save.Data)
+	assert.Equal(t, "echo backup", save.Data.(*common.Job).Command)
+
+	del := jobEvent(&clientv3.Event{
+		Type:   clientv3.EventTypeDelete,
+		Kv:     &mvccpb.KeyValue{Key: []byte(common.JobSaveDir + "backup"), ModRevision: 11},
+		PrevKv: &mvccpb.KeyValue{Key: []byte(common.JobSaveDir + "backup"), Value: value},
+	})
+	assert.Equal(t, EventJobDelete, del.Type)
+	assert.Equal(t, "ops", del.Namespace, "Namespace of deleted jobs should come from the previous value")
+	assert.Equal(t, "alice", del.Owner)
+	assert.Nil(t, del.Data)
+}
+
+func TestRunEvent(t *testing.T) {
+	key := []byte(common.RunIntentDir + "run-1")
+	running := []byte(`{"runId":"run-1","jobName":"backup","namespace":"ops","startTime":100}`)
+	finished := []byte(`{"runId":"run-1","jobName":"backup","namespace":"ops","startTime":100,"status":"success","endTime":110}`)
+
+	start := runEvent(&clientv3.Event{
+		Type: clientv3.EventTypePut,
+		Kv:   &mvccpb.KeyValue{Key: key, Value: running, CreateRevision: 20, ModRevision: 20},
+	})
+	require.NotNil(t, start)
+	assert.Equal(t, EventRunStart, start.Type)
+	assert.Equal(t, "backup", start.Name)
+	assert.Equal(t, "ops", start.Namespace)
+
+	finish := runEvent(&clientv3.Event{
+		Type:   clientv3.EventTypePut,
+		Kv:     &mvccpb.KeyValue{Key: key, Value: finished, CreateRevision: 20, ModRevision: 21},
+		PrevKv: &mvccpb.KeyValue{Key: key, Value: running},
+	})
+	require.NotNil(t, finish)
+	assert.Equal(t, EventRunFinish, finish.Type)
+	assert.Equal(t, common.RunStatusSuccess, finish.Data.(*common.RunIntent).Status)
+
+	assert.Nil(t, runEvent(&clientv3.Event{
+		Type:   clientv3.EventTypePut,
+		Kv:     &mvccpb.KeyValue{Key: key, Value: finished, CreateRevision: 20, ModRevision: 22},
+		PrevKv: &mvccpb.KeyValue{Key: key, Value: finished},
+	}), "Rewriting a finished intent should not finish the run twice")
+	assert.Nil(t, runEvent(&clientv3.Event{
+		Type: clientv3.EventTypeDelete,
+		Kv:   &mvccpb.KeyValue{Key: key, ModRevision: 23},
+	}), "Reconciler deleting an intent should not produce an event")
+}
+
+func TestWorkerEvent(t *testing.T) {
+	event := workerEvent(&workermgr.WorkerEvent{Type: workermgr.WorkerEventOffline, WorkerID: "w1", Time: 5})
+	assert.Equal(t, EventWorkerOffline, event.Type)
+	assert.Equal(t, "w1", event.Name)
+	assert.Nil(t, event.Data, "Missing worker info should not be encoded as null data")
+}
+
+func TestHubPublish(t *testing.T) {
+	hub := NewHub(nil, nil, zap.NewNop())
+
+	events, unsubscribe := hub.Subscribe()
+	hub.publish(&Event{Type: EventJobSave, Name: "backup"})
+	event := <-events
+	assert.Equal(t, "backup", event.Name)
+
+	// 订阅者消费过慢时丢弃新事件，不阻塞发送方
+	for i := 0; i < subscriberBuffer+10; i++ {
+		hub.publish(&Event{Type: EventJobSave})
+	}
+	assert.Len(t, events, subscriberBuffer)
+
+	unsubscribe()
+	assert.Empty(t, hub.subscribers)
+}
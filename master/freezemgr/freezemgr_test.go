@@ -0,0 +1,28 @@
+package freezemgr
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+)
+
+func TestActiveWindow(t *testing.T) {
+	windows := []*common.FreezeWindow{
+		{Name: "black-friday", Start: 100, End: 200},
+		{Name: "year-end", Start: 150, End: 300},
+	}
+
+	assert.Nil(t, activeWindow(windows, 50), "No window should be active before start")
+	assert.Equal(t, "black-friday", activeWindow(windows, 120).Name)
+	assert.Equal(t, "year-end", activeWindow(windows, 160).Name, "Overlapping windows should report the latest end")
+	assert.Nil(t, activeWindow(windows, 300), "Window end should be exclusive")
+}
+
+func TestValidateWindow(t *testing.T) {
+	assert.NoError(t, validateWindow(&common.FreezeWindow{Name: "w", Start: 100, End: 200}, 150))
+	assert.ErrorIs(t, validateWindow(&common.FreezeWindow{Name: "", Start: 100, End: 200}, 150), common.ErrInvalidFreezeWindow)
+	assert.ErrorIs(t, validateWindow(&common.FreezeWindow{Name: "w", Start: 200, End: 100}, 150), common.ErrInvalidFreezeWindow)
+	assert.ErrorIs(t, validateWindow(&common.FreezeWindow{Name: "w", Start: 100, End: 200}, 250), common.ErrInvalidFreezeWindow)
+}
@@ -0,0 +1,137 @@
+package idempotency
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.uber.org/zap"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+	"github.com/fyerfyer/scheduler-refactor/pkg/etcd"
+)
+
+// pendingTTL 处理中的预留记录的租约时间(秒)，master在处理期间退出时预留随租约过期，客户端可以重试
+const pendingTTL = 60
+
+// Response 首次请求的响应，Status为0表示请求仍在处理
+type Response struct {
+	Fingerprint string `json:"fingerprint"`           // 请求体的哈希，同一幂等键的请求体必须一致
+	Status      int    `json:"status,omitempty"`      // HTTP状态码
+	ContentType string `json:"contentType,omitempty"` // 响应的Content-Type
+	Body        []byte `json:"body,omitempty"`        // 响应体
+	CreatedAt   int64  `json:"createdAt"`             // 首次请求的时间
+}
+
+// Reservation 幂等键的预留，请求处理完成后保存响应或取消预留
+type Reservation struct {
+	key      string           // etcd key
+	leaseID  clientv3.LeaseID // 预留的租约
+	response *Response        // 预留时写入的记录
+}
+
+// Store 幂等记录存储，记录保存在etcd中，多个master共享
+type Store struct {
+	etcdClient *etcd.Client  // etcd客户端
+	logger     *zap.Logger   // 日志对象
+	window     time.Duration // 响应的缓存时间
+}
+
+// NewStore 创建幂等记录存储，window为响应的缓存时间
+func NewStore(etcdClient *etcd.Client, window time.Duration, logger *zap.Logger) *Store {
+	return &Store{
+		etcdClient: etcdClient,
+		logger:     logger,
+		window:     window,
+	}
+}
+
+// Fingerprint 计算请求体的哈希
+func Fingerprint(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+// Begin 预留幂等键。首次使用时返回预留；已有完成的响应且请求体一致时返回该响应；
+// 请求体不一致时返回ErrIdempotencyConflict，首次请求仍在处理时返回ErrIdempotencyInProgress。
+// scope区分调用方和接口，不同调用方或接口使用相同的幂等键互不影响
+func (s *Store) Begin(scope, idempotencyKey, fingerprint string) (*Reservation, *Response, error) {
+	key := common.IdempotencyDir + Fingerprint([]byte(scope+"\n"+idempotencyKey))
+
+	pending := &Response{Fingerprint: fingerprint, CreatedAt: time.Now().Unix()}
+	data, err := json.Marshal(pending)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal idempotency record: %v", err)
+	}
+
+	leaseID, err := s.etcdClient.TryAcquireLock(key, string(data), pendingTTL)
+	if err == nil {
+		return &Reservation{key: key, leaseID: leaseID, response: pending}, nil, nil
+	}
+	if !errors.Is(err, common.ErrLockAlreadyAcquired) {
+		return nil, nil, err
+	}
+
+	resp, err := s.etcdClient.Get(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	// 预留刚好过期，按仍在处理返回，客户端重试即可
+	if resp.Count == 0 {
+		return nil, nil, common.ErrIdempotencyInProgress
+	}
+
+	existing := &Response{}
+	if err = json.Unmarshal(resp.Kvs[0].Value, existing); err != nil {
+		return nil, nil, fmt.Errorf("failed to unmarshal idempotency record: %v", err)
+	}
+	if existing.Fingerprint != fingerprint {
+		return nil, nil, common.ErrIdempotencyConflict
+	}
+	if existing.Status == 0 {
+		return nil, nil, common.ErrIdempotencyInProgress
+	}
+	return nil, existing, nil
+}
+
+// Complete 保存响应，之后使用同一幂等键的请求在缓存时间内直接返回该响应
+func (s *Store) Complete(r *Reservation, status int, contentType string, body []byte) error {
+	response := *r.response
+	response.Status = status
+	response.ContentType = contentType
+	response.Body = body
+
+	data, err := json.Marshal(&response)
+	if err != nil {
+		s.Cancel(r)
+		return fmt.Errorf("failed to marshal idempotency record: %v", err)
+	}
+
+	leaseID, err := s.etcdClient.GrantLease(int64(s.window.Seconds()))
+	if err != nil {
+		s.Cancel(r)
+		return err
+	}
+	if err = s.etcdClient.PutManyWithLease(map[string]string{r.key: string(data)}, leaseID); err != nil {
+		s.etcdClient.RevokeLease(leaseID)
+		s.Cancel(r)
+		return err
+	}
+
+	// 记录已绑定到新租约，撤销预留的租约不会删除它
+	s.etcdClient.RevokeLease(r.leaseID)
+	return nil
+}
+
+// Cancel 取消预留，之后可以使用同一幂等键重新请求
+func (s *Store) Cancel(r *Reservation) {
+	if err := s.etcdClient.RevokeLease(r.leaseID); err != nil {
+		s.logger.Warn("failed to release idempotency key, it expires with its lease",
+			zap.String("key", r.key),
+			zap.Error(err))
+	}
+}
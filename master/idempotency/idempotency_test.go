@@ -0,0 +1,93 @@
+package idempotency
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+	"github.com/fyerfyer/scheduler-refactor/config"
+	"github.com/fyerfyer/scheduler-refactor/pkg/etcd"
+)
+
+func setupTestStore(t *testing.T) (*Store, *etcd.Client) {
+	config.GlobalConfig = &config.Config{
+		EtcdEndpoints:   []string{"localhost:2379"},
+		EtcdDialTimeout: 5000,
+	}
+
+	etcdClient, err := etcd.NewClient()
+	require.NoError(t, err, "Failed to create etcd client")
+	t.Cleanup(func() { etcdClient.Close() })
+
+	return NewStore(etcdClient, time.Minute, zaptest.NewLogger(t)), etcdClient
+}
+
+// cleanupKey 删除测试使用的幂等记录
+func cleanupKey(t *testing.T, etcdClient *etcd.Client, scope, idempotencyKey string) {
+	key := common.IdempotencyDir + Fingerprint([]byte(scope+"\n"+idempotencyKey))
+	etcdClient.Delete(key)
+	t.Cleanup(func() { etcdClient.Delete(key) })
+}
+
+func TestBeginAndReplay(t *testing.T) {
+	store, etcdClient := setupTestStore(t)
+	scope, key := "alice /api/v1/job/run/report", "test-idempotency-replay"
+	cleanupKey(t, etcdClient, scope, key)
+	fingerprint := Fingerprint([]byte(`{"name":"report"}`))
+
+	// 首次使用返回预留
+	reservation, cached, err := store.Begin(scope, key, fingerprint)
+	require.NoError(t, err)
+	require.NotNil(t, reservation, "First use should reserve the key")
+	assert.Nil(t, cached)
+
+	// 处理期间使用同一幂等键
+	_, _, err = store.Begin(scope, key, fingerprint)
+	assert.ErrorIs(t, err, common.ErrIdempotencyInProgress)
+
+	require.NoError(t, store.Complete(reservation, 200, "application/json", []byte(`{"code":0}`)))
+
+	// 请求体一致时返回首次的响应
+	reservation, cached, err = store.Begin(scope, key, fingerprint)
+	require.NoError(t, err)
+	assert.Nil(t, reservation, "Retries should not reserve the key again")
+	require.NotNil(t, cached)
+	assert.Equal(t, 200, cached.Status)
+	assert.Equal(t, "application/json", cached.ContentType)
+	assert.Equal(t, []byte(`{"code":0}`), cached.Body)
+
+	// 同一幂等键用于不同的请求体
+	_, _, err = store.Begin(scope, key, Fingerprint([]byte(`{"name":"other"}`)))
+	assert.ErrorIs(t, err, common.ErrIdempotencyConflict)
+
+	// 不同调用方使用相同的幂等键互不影响
+	otherScope := "bob /api/v1/job/run/report"
+	cleanupKey(t, etcdClient, otherScope, key)
+	reservation, _, err = store.Begin(otherScope, key, fingerprint)
+	require.NoError(t, err)
+	require.NotNil(t, reservation)
+	store.Cancel(reservation)
+}
+
+func TestCancel(t *testing.T) {
+	store, etcdClient := setupTestStore(t)
+	scope, key := "alice /api/v1/job/save", "test-idempotency-cancel"
+	cleanupKey(t, etcdClient, scope, key)
+	fingerprint := Fingerprint([]byte(`{"name":"backup"}`))
+
+	reservation, _, err := store.Begin(scope, key, fingerprint)
+	require.NoError(t, err)
+	require.NotNil(t, reservation)
+
+	// 取消后可以使用同一幂等键重新请求
+	store.Cancel(reservation)
+	reservation, cached, err := store.Begin(scope, key, fingerprint)
+	require.NoError(t, err)
+	assert.NotNil(t, reservation, "Cancelled keys should be reserved again")
+	assert.Nil(t, cached)
+	store.Cancel(reservation)
+}
@@ -18,11 +18,15 @@ import (
 	"github.com/fyerfyer/scheduler-refactor/common"
 	"github.com/fyerfyer/scheduler-refactor/config"
 	"github.com/fyerfyer/scheduler-refactor/master/api"
+	"github.com/fyerfyer/scheduler-refactor/master/approvalmgr"
+	"github.com/fyerfyer/scheduler-refactor/master/freezemgr"
 	"github.com/fyerfyer/scheduler-refactor/master/jobmgr"
 	"github.com/fyerfyer/scheduler-refactor/master/logmgr"
+	"github.com/fyerfyer/scheduler-refactor/master/policymgr"
 	"github.com/fyerfyer/scheduler-refactor/master/workermgr"
 	"github.com/fyerfyer/scheduler-refactor/pkg/etcd"
 	"github.com/fyerfyer/scheduler-refactor/pkg/mongodb"
+	"github.com/fyerfyer/scheduler-refactor/pkg/notify"
 )
 
 var baseURL string
@@ -47,8 +51,11 @@ func setupIntegrationTest(t *testing.T) (*http.Server, func()) {
 	jobManager := jobmgr.NewJobManager(etcdClient, logger)
 	logManager := logmgr.NewLogManager(mongoClient, logger)
 	workerManager := workermgr.NewWorkerManager(etcdClient, logger)
+	policyManager := policymgr.NewPolicyManager(etcdClient, logger)
+	approvalManager := approvalmgr.NewApprovalManager(etcdClient, jobManager, notify.NewNotifier(""), logger)
+	freezeManager := freezemgr.NewFreezeManager(etcdClient, logger)
 
-	apiServer := api.NewServer(logger, jobManager, logManager, workerManager)
+	apiServer := api.NewServer(logger, jobManager, logManager, workerManager, policyManager, approvalManager, freezeManager)
 
 	server := &http.Server{
 		Addr:    fmt.Sprintf(":%d", apiPort),
@@ -338,6 +345,7 @@ func TestKillJob(t *testing.T) {
 		Command:  "sleep 30",
 		CronExpr: "*/1 * * * * *",
 		Timeout:  60,
+		AllowHighFrequency: true,
 	}
 
 	resp, body, err := apiTest.doRequest(http.MethodPost, "/job/save", job)
@@ -278,7 +278,7 @@ func TestLogManagement(t *testing.T) {
 		err = ctx.logMgr.CleanExpiredLogs(30)
 		require.NoError(t, err, "Failed to clean old logs")
 
-		count, err := ctx.mongoClient.CountJobLogs(jobName)
+		count, err := ctx.mongoClient.CountJobLogs(jobName, nil)
 		require.NoError(t, err, "Failed to count logs")
 		assert.Equal(t, int64(1), count, "Should have only 1 log after cleaning")
 	})
@@ -326,7 +326,7 @@ func TestLogManagement(t *testing.T) {
 		_, err = ctx.mongoClient.InsertMany(logs)
 		require.NoError(t, err, "Failed to insert test logs")
 
-		stats, err := ctx.logMgr.GetLogStatistics(jobName, 1) // Last 1 day
+		stats, err := ctx.logMgr.GetLogStatistics(jobName, nil, 1) // Last 1 day
 		require.NoError(t, err, "Failed to get log statistics")
 
 		assert.Equal(t, 3, stats["totalCount"], "Should have 3 logs in total")
@@ -384,7 +384,7 @@ func TestFullWorkflow(t *testing.T) {
 
 		time.Sleep(100 * time.Millisecond)
 
-		logs, total, err := ctx.logMgr.ListLogs(jobName, 1, 10)
+		logs, total, err := ctx.logMgr.ListLogs(jobName, nil, 1, 10)
 		require.NoError(t, err, "Failed to list logs")
 		assert.Equal(t, int64(1), total, "Should have one log")
 		assert.Equal(t, jobName, logs[0].JobName, "Log job name should match")
@@ -424,7 +424,7 @@ func TestFullWorkflow(t *testing.T) {
 	})
 
 	t.Run("LogStatisticsVerification", func(t *testing.T) {
-		stats, err := ctx.logMgr.GetLogStatistics(jobName, 1)
+		stats, err := ctx.logMgr.GetLogStatistics(jobName, nil, 1)
 		require.NoError(t, err, "Failed to get log statistics")
 
 		assert.GreaterOrEqual(t, stats["totalCount"], 1, "Should have at least one log")
@@ -0,0 +1,53 @@
+package jobmgr
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"go.uber.org/zap"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+)
+
+// JobState 任务定义及其锁的持有情况
+type JobState struct {
+	Job    *common.Job // 任务定义
+	Holder string      // 持有任务锁的worker，为空表示没有在执行
+}
+
+// BatchGetJobs 批量读取任务定义和任务锁，返回存在的任务，key为任务名
+func (jm *JobManager) BatchGetJobs(names []string) (map[string]*JobState, error) {
+	keys := make([]string, 0, len(names)*2)
+	for _, name := range names {
+		keys = append(keys, common.JobSaveDir+name, common.JobLockDir+name)
+	}
+
+	kvs, err := jm.etcdClient.GetMany(keys)
+	if err != nil {
+		jm.logger.Error("failed to batch get jobs",
+			zap.Int("count", len(names)),
+			zap.Error(err))
+		return nil, err
+	}
+
+	states := make(map[string]*JobState, len(names))
+	for _, name := range names {
+		kv, exists := kvs[common.JobSaveDir+name]
+		if !exists {
+			continue
+		}
+
+		job := &common.Job{}
+		if err = json.Unmarshal(kv.Value, job); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal job %s: %v", name, err)
+		}
+
+		state := &JobState{Job: job}
+		if lock, locked := kvs[common.JobLockDir+name]; locked {
+			state.Holder = string(lock.Value)
+		}
+		states[name] = state
+	}
+
+	return states, nil
+}
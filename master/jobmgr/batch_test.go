@@ -0,0 +1,29 @@
+package jobmgr
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+)
+
+func TestBatchGetJobs(t *testing.T) {
+	jobMgr, etcdClient, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	for _, name := range []string{"test-batch-a", "test-batch-b"} {
+		require.NoError(t, jobMgr.SaveJob(&common.Job{Name: name, Command: "echo", CronExpr: "*/5 * * * * *"}))
+		defer jobMgr.DeleteJob(name)
+	}
+	require.NoError(t, etcdClient.PutWithLease(common.JobLockDir+"test-batch-b", "worker-1", 10))
+	defer etcdClient.Delete(common.JobLockDir + "test-batch-b")
+
+	states, err := jobMgr.BatchGetJobs([]string{"test-batch-a", "test-batch-b", "test-batch-missing"})
+	require.NoError(t, err)
+	require.Len(t, states, 2, "Missing job should be left out")
+	assert.Equal(t, "echo", states["test-batch-a"].Job.Command)
+	assert.Empty(t, states["test-batch-a"].Holder)
+	assert.Equal(t, "worker-1", states["test-batch-b"].Holder)
+}
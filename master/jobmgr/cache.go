@@ -0,0 +1,172 @@
+package jobmgr
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.uber.org/zap"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+)
+
+// cacheRetryInterval 监听中断后重新加载缓存的间隔
+const cacheRetryInterval = time.Second
+
+// cachedJob 缓存中的一个任务
+type cachedJob struct {
+	job         *common.Job // 任务定义，无法解析时为nil，仍计入任务集合的版本
+	modRevision int64       // 任务key的修改版本
+}
+
+// jobCache 随监听更新的任务缓存，列出任务时不需要每次从etcd读取并解析全部任务
+type jobCache struct {
+	jobs  map[string]*cachedJob // 任务名 -> 任务
+	ready bool                  // 是否已加载，监听中断期间未就绪，列出任务时直接读取etcd
+	lock  sync.RWMutex          // 保护jobs和ready
+}
+
+// StartCache 加载任务缓存并开始监听任务目录，之后列出任务从缓存读取。
+// 缓存随监听更新，刚保存的任务可能要稍后才出现在列表中
+func (jm *JobManager) StartCache() {
+	jm.cache = &jobCache{jobs: make(map[string]*cachedJob)}
+	go jm.cacheLoop()
+}
+
+// cacheLoop 加载缓存并监听变化，监听中断（包括版本被压缩）后重新加载
+func (jm *JobManager) cacheLoop() {
+	for {
+		revision, err := jm.loadCache()
+		if err == nil {
+			err = jm.watchCache(revision)
+		}
+		jm.cache.setReady(false)
+
+		select {
+		case <-jm.ctx.Done():
+			return
+		default:
+		}
+		jm.logger.Warn("job cache watch interrupted, reloading", zap.Error(err))
+
+		select {
+		case <-jm.ctx.Done():
+			return
+		case <-time.After(cacheRetryInterval):
+		}
+	}
+}
+
+// loadCache 从etcd加载全部任务，返回读取时的版本
+func (jm *JobManager) loadCache() (int64, error) {
+	resp, err := jm.etcdClient.GetWithPrefix(common.JobSaveDir)
+	if err != nil {
+		return 0, err
+	}
+
+	jobs := make(map[string]*cachedJob, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		jobs[strings.TrimPrefix(string(kv.Key), common.JobSaveDir)] = jm.parseCachedJob(kv)
+	}
+
+	jm.cache.lock.Lock()
+	jm.cache.jobs = jobs
+	jm.cache.ready = true
+	jm.cache.lock.Unlock()
+
+	jm.logger.Info("job cache loaded",
+		zap.Int("jobs", len(jobs)),
+		zap.Int64("revision", resp.Header.Revision))
+	return resp.Header.Revision, nil
+}
+
+// watchCache 从revision之后开始监听任务目录，监听结束时返回原因
+func (jm *JobManager) watchCache(revision int64) error {
+	watchChan := jm.etcdClient.WatchWithPrefixFrom(jm.ctx, common.JobSaveDir, revision+1)
+	for watchResp := range watchChan {
+		if watchResp.CompactRevision != 0 {
+			return common.ErrRevisionCompacted
+		}
+		if err := watchResp.Err(); err != nil {
+			return common.NewEtcdError("watch", common.JobSaveDir, err)
+		}
+		for _, event := range watchResp.Events {
+			name := strings.TrimPrefix(string(event.Kv.Key), common.JobSaveDir)
+			if event.Type == clientv3.EventTypeDelete {
+				jm.cache.remove(name)
+			} else {
+				jm.cache.put(name, jm.parseCachedJob(event.Kv))
+			}
+		}
+	}
+	return fmt.Errorf("watch channel closed")
+}
+
+// parseCachedJob 解析任务，无法解析时只记录日志
+func (jm *JobManager) parseCachedJob(kv *mvccpb.KeyValue) *cachedJob {
+	job := &common.Job{}
+	if err := json.Unmarshal(kv.Value, job); err != nil {
+		jm.logger.Error("failed to unmarshal job data",
+			zap.String("key", string(kv.Key)),
+			zap.Error(err))
+		job = nil
+	}
+	return &cachedJob{job: job, modRevision: kv.ModRevision}
+}
+
+// put 更新缓存中的任务
+func (c *jobCache) put(name string, cached *cachedJob) {
+	c.lock.Lock()
+	c.jobs[name] = cached
+	c.lock.Unlock()
+}
+
+// remove 从缓存中删除任务
+func (c *jobCache) remove(name string) {
+	c.lock.Lock()
+	delete(c.jobs, name)
+	c.lock.Unlock()
+}
+
+// setReady 设置缓存是否可用
+func (c *jobCache) setReady(ready bool) {
+	c.lock.Lock()
+	c.ready = ready
+	c.lock.Unlock()
+}
+
+// list 按任务名顺序（与etcd中key的顺序一致）返回任务的副本和任务集合的版本，缓存未就绪时返回false
+func (c *jobCache) list() ([]*common.Job, string, bool) {
+	if c == nil {
+		return nil, "", false
+	}
+
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	if !c.ready {
+		return nil, "", false
+	}
+
+	names := make([]string, 0, len(c.jobs))
+	var maxRevision int64
+	for name, cached := range c.jobs {
+		names = append(names, name)
+		maxRevision = max(maxRevision, cached.modRevision)
+	}
+	sort.Strings(names)
+
+	jobs := make([]*common.Job, 0, len(names))
+	for _, name := range names {
+		if job := c.jobs[name].job; job != nil {
+			copied := *job
+			jobs = append(jobs, &copied)
+		}
+	}
+	return jobs, formatJobSetVersion(maxRevision, len(names)), true
+}
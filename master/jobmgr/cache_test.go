@@ -0,0 +1,47 @@
+package jobmgr
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+)
+
+func TestJobCache(t *testing.T) {
+	var missing *jobCache
+	_, _, ok := missing.list()
+	assert.False(t, ok, "Listing without a cache should fall back to etcd")
+
+	cache := &jobCache{jobs: make(map[string]*cachedJob)}
+	cache.put("backup", &cachedJob{job: &common.Job{Name: "backup"}, modRevision: 5})
+	_, _, ok = cache.list()
+	assert.False(t, ok, "Listing before the cache is loaded should fall back to etcd")
+
+	cache.setReady(true)
+	cache.put("cleanup", &cachedJob{job: &common.Job{Name: "cleanup"}, modRevision: 7})
+	cache.put("archive", &cachedJob{job: &common.Job{Name: "archive"}, modRevision: 3})
+	cache.put("broken", &cachedJob{modRevision: 6})
+
+	jobs, version, ok := cache.list()
+	require.True(t, ok)
+	assert.Equal(t, []string{"archive", "backup", "cleanup"}, jobNames(jobs), "Jobs should be listed in key order")
+	assert.Equal(t, "7-4", version, "Unparsable jobs should still count towards the version")
+
+	jobs[0].Command = "changed"
+	jobs, _, _ = cache.list()
+	assert.Empty(t, jobs[0].Command, "Callers should get copies of the cached jobs")
+
+	cache.remove("cleanup")
+	_, version, _ = cache.list()
+	assert.Equal(t, "6-3", version)
+}
+
+func jobNames(jobs []*common.Job) []string {
+	names := make([]string, 0, len(jobs))
+	for _, job := range jobs {
+		names = append(names, job.Name)
+	}
+	return names
+}
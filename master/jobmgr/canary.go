@@ -0,0 +1,78 @@
+package jobmgr
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"go.uber.org/zap"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+)
+
+// StartCanary 开始灰度发布，同一任务已有的灰度发布会被替换并重新计数。
+// 记录任务当前定义的修改版本，灰度期间任务被保存过时worker不会用旧的灰度定义覆盖它
+func (jm *JobManager) StartCanary(canary *common.CanaryRelease) error {
+	resp, err := jm.etcdClient.Get(common.JobSaveDir + canary.Job.Name)
+	if err != nil {
+		return err
+	}
+	if resp.Count == 0 {
+		return common.ErrJobNotFound
+	}
+	canary.JobRevision = resp.Kvs[0].ModRevision
+
+	data, err := json.Marshal(canary)
+	if err != nil {
+		return fmt.Errorf("failed to marshal canary release: %v", err)
+	}
+
+	canaryKey := common.CanaryDir + canary.Job.Name
+	if _, err = jm.etcdClient.Put(canaryKey, string(data)); err != nil {
+		jm.logger.Error("failed to start canary release",
+			zap.String("jobName", canary.Job.Name),
+			zap.Error(err))
+		return err
+	}
+
+	jm.logger.Info("canary release started",
+		zap.String("jobName", canary.Job.Name),
+		zap.String("worker", canary.Worker),
+		zap.Int("runs", canary.Runs),
+		zap.Float64("minSuccessRate", canary.MinSuccessRate))
+	return nil
+}
+
+// GetCanary 获取任务进行中的灰度发布
+func (jm *JobManager) GetCanary(jobName string) (*common.CanaryRelease, error) {
+	resp, err := jm.etcdClient.Get(common.CanaryDir + jobName)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Count == 0 {
+		return nil, common.ErrCanaryNotFound
+	}
+
+	canary := &common.CanaryRelease{}
+	if err = json.Unmarshal(resp.Kvs[0].Value, canary); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal canary release: %v", err)
+	}
+
+	return canary, nil
+}
+
+// CancelCanary 取消灰度发布，任务保持当前定义
+func (jm *JobManager) CancelCanary(jobName string) error {
+	resp, err := jm.etcdClient.Delete(common.CanaryDir + jobName)
+	if err != nil {
+		jm.logger.Error("failed to cancel canary release",
+			zap.String("jobName", jobName),
+			zap.Error(err))
+		return err
+	}
+	if resp.Deleted == 0 {
+		return common.ErrCanaryNotFound
+	}
+
+	jm.logger.Info("canary release cancelled", zap.String("jobName", jobName))
+	return nil
+}
@@ -0,0 +1,48 @@
+package jobmgr
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+)
+
+func TestCanaryLifecycle(t *testing.T) {
+	jobMgr, _, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	jobName := "test-canary-job"
+	jobMgr.CancelCanary(jobName)
+
+	_, err := jobMgr.GetCanary(jobName)
+	assert.ErrorIs(t, err, common.ErrCanaryNotFound)
+
+	canary := &common.CanaryRelease{
+		Job:    &common.Job{Name: jobName, Command: "echo v2", CronExpr: "*/5 * * * * *"},
+		Worker: "worker-1",
+	}
+	jobMgr.DeleteJob(jobName)
+	assert.ErrorIs(t, jobMgr.StartCanary(canary), common.ErrJobNotFound, "Canary requires an existing job")
+
+	require.NoError(t, jobMgr.SaveJob(&common.Job{Name: jobName, Command: "echo v1", CronExpr: "*/5 * * * * *"}))
+	defer jobMgr.DeleteJob(jobName)
+
+	canary = &common.CanaryRelease{
+		Job:            &common.Job{Name: jobName, Command: "echo v2", CronExpr: "*/5 * * * * *"},
+		Worker:         "worker-1",
+		Runs:           3,
+		MinSuccessRate: 1,
+	}
+	require.NoError(t, jobMgr.StartCanary(canary))
+
+	loaded, err := jobMgr.GetCanary(jobName)
+	require.NoError(t, err)
+	assert.Equal(t, "worker-1", loaded.Worker)
+	assert.Equal(t, "echo v2", loaded.Job.Command)
+	assert.NotZero(t, loaded.JobRevision, "Canary should record the revision of the current definition")
+
+	require.NoError(t, jobMgr.CancelCanary(jobName))
+	assert.ErrorIs(t, jobMgr.CancelCanary(jobName), common.ErrCanaryNotFound, "Cancelled canary should be gone")
+}
@@ -0,0 +1,138 @@
+package jobmgr
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"go.uber.org/zap"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+)
+
+// diffIgnoredFields 每次保存都会变化的字段，不参与比较
+var diffIgnoredFields = map[string]bool{"updatedAt": true}
+
+// JobFieldChange 任务定义中一个字段的变化，字段按JSON名称表示
+type JobFieldChange struct {
+	Field string `json:"field"`          // 字段名，例如command、cronExpr
+	From  any    `json:"from,omitempty"` // 变更前的值，新增的字段为空
+	To    any    `json:"to,omitempty"`   // 变更后的值，删除的字段为空
+}
+
+// JobDiff 任务定义在两个版本之间的差异。版本为etcd中任务key的修改版本，
+// 任务在某个版本不存在时对应的修改版本为0
+type JobDiff struct {
+	JobName      string            `json:"jobName"`      // 任务名称
+	FromRevision int64             `json:"fromRevision"` // 比较的旧版本
+	ToRevision   int64             `json:"toRevision"`   // 比较的新版本
+	Changes      []*JobFieldChange `json:"changes"`      // 发生变化的字段，按字段名排序
+}
+
+// jobAtRevision 读取任务在指定etcd版本时的定义和该定义的修改版本，任务当时不存在时返回nil
+func (jm *JobManager) jobAtRevision(jobName string, revision int64) (*common.Job, int64, error) {
+	resp, err := jm.etcdClient.GetAtRevision(common.JobSaveDir+jobName, revision)
+	if err != nil {
+		return nil, 0, err
+	}
+	if resp.Count == 0 {
+		return nil, 0, nil
+	}
+
+	kv := resp.Kvs[0]
+	job := &common.Job{}
+	if err = json.Unmarshal(kv.Value, job); err != nil {
+		jm.logger.Error("failed to unmarshal job data",
+			zap.String("jobName", jobName),
+			zap.Int64("revision", revision),
+			zap.Error(err))
+		return nil, 0, fmt.Errorf("failed to unmarshal job data: %v", err)
+	}
+	return job, kv.ModRevision, nil
+}
+
+// DiffJob 比较任务在from和to两个etcd版本时的定义。to为0时使用当前定义，
+// from为0时使用to之前的上一个定义。任务在其中一个版本不存在时视为全部字段新增或删除
+func (jm *JobManager) DiffJob(jobName string, from, to int64) (*JobDiff, error) {
+	toJob, toRevision, err := jm.jobAtRevision(jobName, to)
+	if err != nil {
+		return nil, err
+	}
+
+	var fromJob *common.Job
+	var fromRevision int64
+	switch {
+	case from > 0:
+		if fromJob, fromRevision, err = jm.jobAtRevision(jobName, from); err != nil {
+			return nil, err
+		}
+	case toJob != nil:
+		// 上一个定义是toJob写入前一刻的值
+		if fromJob, fromRevision, err = jm.jobAtRevision(jobName, toRevision-1); err != nil {
+			return nil, err
+		}
+	}
+	if fromJob == nil && toJob == nil {
+		return nil, common.ErrJobNotFound
+	}
+
+	changes, err := diffJobs(fromJob, toJob)
+	if err != nil {
+		return nil, err
+	}
+	return &JobDiff{
+		JobName:      jobName,
+		FromRevision: fromRevision,
+		ToRevision:   toRevision,
+		Changes:      changes,
+	}, nil
+}
+
+// diffJobs 逐字段比较两个任务定义，job为nil时视为没有任何字段
+func diffJobs(from, to *common.Job) ([]*JobFieldChange, error) {
+	fromFields, err := jobFields(from)
+	if err != nil {
+		return nil, err
+	}
+	toFields, err := jobFields(to)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(fromFields)+len(toFields))
+	for name := range fromFields {
+		names = append(names, name)
+	}
+	for name := range toFields {
+		if _, exists := fromFields[name]; !exists {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	changes := make([]*JobFieldChange, 0)
+	for _, name := range names {
+		if diffIgnoredFields[name] || reflect.DeepEqual(fromFields[name], toFields[name]) {
+			continue
+		}
+		changes = append(changes, &JobFieldChange{Field: name, From: fromFields[name], To: toFields[name]})
+	}
+	return changes, nil
+}
+
+// jobFields 将任务定义转换为以JSON字段名为key的map，省略的空字段不出现
+func jobFields(job *common.Job) (map[string]any, error) {
+	fields := make(map[string]any)
+	if job == nil {
+		return fields, nil
+	}
+	data, err := json.Marshal(job)
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
@@ -0,0 +1,95 @@
+package jobmgr
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+)
+
+func TestDiffJobs(t *testing.T) {
+	from := &common.Job{Name: "report", Command: "echo 1", CronExpr: "0 * * * * *", Timeout: 10, UpdatedAt: 1}
+	to := &common.Job{Name: "report", Command: "echo 2", CronExpr: "0 * * * * *", Timeout: 30, Owner: "ops", UpdatedAt: 2}
+
+	changes, err := diffJobs(from, to)
+	require.NoError(t, err)
+	assert.Equal(t, []*JobFieldChange{
+		{Field: "command", From: "echo 1", To: "echo 2"},
+		{Field: "owner", From: "", To: "ops"},
+		{Field: "timeout", From: float64(10), To: float64(30)},
+	}, changes, "updatedAt should be ignored")
+
+	changes, err = diffJobs(nil, &common.Job{Name: "report", Description: "daily"})
+	require.NoError(t, err)
+	fields := make(map[string]*JobFieldChange)
+	for _, change := range changes {
+		fields[change.Field] = change
+	}
+	assert.Equal(t, &JobFieldChange{Field: "description", To: "daily"}, fields["description"])
+	assert.Contains(t, fields, "name", "All fields of a new job should be reported")
+}
+
+func TestDiffJob(t *testing.T) {
+	jobMgr, _, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	jobName := "test-diff-job"
+	require.NoError(t, jobMgr.SaveJob(&common.Job{Name: jobName, Command: "echo 1", CronExpr: "*/5 * * * * *"}))
+	defer jobMgr.DeleteJob(jobName)
+	require.NoError(t, jobMgr.SaveJob(&common.Job{Name: jobName, Command: "echo 2", CronExpr: "*/10 * * * * *"}))
+
+	diff, err := jobMgr.DiffJob(jobName, 0, 0)
+	require.NoError(t, err)
+	assert.Less(t, diff.FromRevision, diff.ToRevision)
+	fields := make([]string, 0, len(diff.Changes))
+	for _, change := range diff.Changes {
+		fields = append(fields, change.Field)
+	}
+	assert.Equal(t, []string{"command", "cronExpr"}, fields)
+
+	// 与最早的版本比较时，任务在创建之前不存在
+	created, err := jobMgr.DiffJob(jobName, 0, diff.FromRevision)
+	require.NoError(t, err)
+	assert.Zero(t, created.FromRevision)
+
+	_, err = jobMgr.DiffJob("test-diff-missing", 0, 0)
+	assert.ErrorIs(t, err, common.ErrJobNotFound)
+}
+
+func TestTriggerJobAtRevision(t *testing.T) {
+	jobMgr, etcdClient, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	jobName := "test-pinned-trigger-job"
+	require.NoError(t, jobMgr.SaveJob(&common.Job{Name: jobName, Command: "echo 1", CronExpr: "*/5 * * * * *"}))
+	defer jobMgr.DeleteJob(jobName)
+	require.NoError(t, jobMgr.SaveJob(&common.Job{Name: jobName, Command: "echo 2", CronExpr: "*/5 * * * * *"}))
+
+	diff, err := jobMgr.DiffJob(jobName, 0, 0)
+	require.NoError(t, err)
+
+	trigger, err := jobMgr.TriggerJobAtRevision(jobName, "alice", diff.FromRevision)
+	require.NoError(t, err)
+	defer etcdClient.Delete(common.TriggerKey(jobName, trigger.RunID))
+	require.NotNil(t, trigger.Job)
+	assert.Equal(t, "echo 1", trigger.Job.Command, "The historical definition should be executed")
+	assert.Equal(t, diff.FromRevision, trigger.Revision)
+
+	_, err = jobMgr.TriggerJobAtRevision(jobName, "alice", diff.FromRevision+1)
+	assert.ErrorIs(t, err, common.ErrJobRevisionNotFound, "Revisions that are not a save of the job should be rejected")
+}
+
+func TestReplayRunNotReplayable(t *testing.T) {
+	jobMgr := &JobManager{}
+
+	for _, run := range []*common.JobLog{
+		{JobName: "report", RunID: "legacy"},
+		{JobName: "report", RunID: "canary", JobRevision: 5, Canary: true},
+		{JobName: "report", RunID: "experiment", JobRevision: 5, Experiment: true},
+	} {
+		_, err := jobMgr.ReplayRun(run, "alice")
+		assert.ErrorIs(t, err, common.ErrRunNotReplayable, run.RunID)
+	}
+}
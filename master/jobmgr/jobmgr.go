@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"time"
 
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
 	"go.uber.org/zap"
 
 	"github.com/fyerfyer/scheduler-refactor/common"
@@ -16,6 +18,7 @@ import (
 type JobManager struct {
 	etcdClient *etcd.Client       // etcd客户端
 	logger     *zap.Logger        // 日志对象
+	cache      *jobCache          // 任务缓存，为空时每次从etcd读取
 	ctx        context.Context    // 上下文，用于控制退出
 	cancelFunc context.CancelFunc // 取消函数
 }
@@ -41,6 +44,13 @@ func (jm *JobManager) SaveJob(job *common.Job) error {
 	}
 	job.UpdatedAt = now
 
+	// 记录禁用时间，启用后清除
+	if !job.Disabled {
+		job.DisabledAt = 0
+	} else if job.DisabledAt == 0 {
+		job.DisabledAt = now
+	}
+
 	// 序列化为JSON
 	jobData, err := json.Marshal(job)
 	if err != nil {
@@ -79,6 +89,23 @@ func (jm *JobManager) DeleteJob(jobName string) error {
 		return common.ErrJobNotFound
 	}
 
+	// 任务删除后灰度发布、检查点和最近执行结果没有意义，一并清理
+	if _, err = jm.etcdClient.Delete(common.CanaryDir + jobName); err != nil {
+		jm.logger.Warn("failed to clean up canary release of deleted job",
+			zap.String("jobName", jobName),
+			zap.Error(err))
+	}
+	if _, err = jm.etcdClient.Delete(common.JobCheckpointDir + jobName); err != nil {
+		jm.logger.Warn("failed to clean up checkpoint of deleted job",
+			zap.String("jobName", jobName),
+			zap.Error(err))
+	}
+	if _, err = jm.etcdClient.Delete(common.JobLastRunDir + jobName); err != nil {
+		jm.logger.Warn("failed to clean up last run of deleted job",
+			zap.String("jobName", jobName),
+			zap.Error(err))
+	}
+
 	jm.logger.Info("job deleted", zap.String("jobName", jobName))
 	return nil
 }
@@ -111,12 +138,23 @@ func (jm *JobManager) GetJob(jobName string) (*common.Job, error) {
 
 // ListJobs 获取任务列表
 func (jm *JobManager) ListJobs() ([]*common.Job, error) {
+	jobs, _, err := jm.ListJobsWithVersion()
+	return jobs, err
+}
+
+// ListJobsWithVersion 获取任务列表和任务集合的版本，任务增删改后版本随之变化。
+// 开启了任务缓存时从缓存读取
+func (jm *JobManager) ListJobsWithVersion() ([]*common.Job, string, error) {
+	if jobs, version, ok := jm.cache.list(); ok {
+		return jobs, version, nil
+	}
+
 	// 从etcd获取所有任务
 	resp, err := jm.etcdClient.GetWithPrefix(common.JobSaveDir)
 	if err != nil {
 		jm.logger.Error("failed to list jobs",
 			zap.Error(err))
-		return nil, err
+		return nil, "", err
 	}
 
 	// 解析任务列表
@@ -132,16 +170,32 @@ func (jm *JobManager) ListJobs() ([]*common.Job, error) {
 		jobs = append(jobs, job)
 	}
 
-	return jobs, nil
+	return jobs, jobSetVersion(resp.Kvs), nil
+}
+
+// jobSetVersion 根据任务key的最大修改版本和任务数量生成任务集合的版本。
+// 修改或新增任务会产生更大的修改版本；版本最大的任务不变时，其余任务只可能被删除，
+// 任务数量随之减少，因此两者共同确定了任务集合，各master计算出的版本也一致
+func jobSetVersion(kvs []*mvccpb.KeyValue) string {
+	var maxRevision int64
+	for _, kv := range kvs {
+		maxRevision = max(maxRevision, kv.ModRevision)
+	}
+	return formatJobSetVersion(maxRevision, len(kvs))
+}
+
+// formatJobSetVersion 格式化任务集合的版本
+func formatJobSetVersion(maxRevision int64, count int) string {
+	return fmt.Sprintf("%d-%d", maxRevision, count)
 }
 
 // KillJob 强制终止任务
 func (jm *JobManager) KillJob(jobName string) error {
 	// 创建kill标记
-	killKey := common.JobLockDir + jobName
+	killKey := common.JobKillDir + jobName
 
 	// 上传一个临时的key，worker节点监听到这个key后会停止对应任务
-	err := jm.etcdClient.PutWithLease(killKey, "", 5)
+	err := jm.etcdClient.PutWithLease(killKey, "", common.JobKillTTL)
 	if err != nil {
 		jm.logger.Error("failed to create kill marker",
 			zap.String("jobName", jobName),
@@ -155,6 +209,263 @@ func (jm *JobManager) KillJob(jobName string) error {
 	return nil
 }
 
+// MigrateKillMarkers 删除旧版本写入任务锁目录的kill标记（值为空），这些标记会阻止worker获取任务锁，
+// 返回删除的数量。期间被修改的key会保留
+func (jm *JobManager) MigrateKillMarkers() (int, error) {
+	resp, err := jm.etcdClient.GetWithPrefix(common.JobLockDir)
+	if err != nil {
+		return 0, err
+	}
+
+	migrated := 0
+	for _, kv := range resp.Kvs {
+		// 任务锁的值为持有者，不会为空
+		if len(kv.Value) != 0 {
+			continue
+		}
+
+		key := string(kv.Key)
+		applied, err := jm.etcdClient.ApplyIfUnchanged(key, kv.ModRevision, clientv3.OpDelete(key))
+		if err != nil {
+			return migrated, err
+		}
+		if applied {
+			migrated++
+		}
+	}
+
+	if migrated > 0 {
+		jm.logger.Info("legacy kill markers removed from lock directory", zap.Int("count", migrated))
+	}
+	return migrated, nil
+}
+
+// TriggerJob 手动触发一次任务执行：写入带租约的触发记录，由第一个抢到记录的worker立即执行。
+// 任务被禁用时返回ErrJobDisabled，租约到期前没有worker接手时触发自动失效
+func (jm *JobManager) TriggerJob(jobName, triggeredBy string) (*common.JobTrigger, error) {
+	return jm.TriggerJobAtRevision(jobName, triggeredBy, 0)
+}
+
+// TriggerJobAtRevision 手动触发一次任务执行，执行修改版本为revision的历史定义，用于复现历史执行；
+// revision为0时执行当前定义。版本不是任务某次保存的修改版本时返回ErrJobRevisionNotFound
+func (jm *JobManager) TriggerJobAtRevision(jobName, triggeredBy string, revision int64) (*common.JobTrigger, error) {
+	return jm.triggerJob(jobName, triggeredBy, revision, "")
+}
+
+// ReplayRun 按历史执行所用的任务定义版本重新执行一次，新执行的日志通过replayOf关联原执行。
+// 执行没有记录定义版本（旧日志、灰度执行）或是实验命令的执行时返回ErrRunNotReplayable
+func (jm *JobManager) ReplayRun(run *common.JobLog, triggeredBy string) (*common.JobTrigger, error) {
+	if run.JobRevision == 0 || run.Canary || run.Experiment {
+		return nil, common.ErrRunNotReplayable
+	}
+	return jm.triggerJob(run.JobName, triggeredBy, run.JobRevision, run.RunID)
+}
+
+// triggerJob 写入手动触发记录，revision不为0时携带该版本的任务定义，replayOf为重放的原执行
+func (jm *JobManager) triggerJob(jobName, triggeredBy string, revision int64, replayOf string) (*common.JobTrigger, error) {
+	job, err := jm.GetJob(jobName)
+	if err != nil {
+		return nil, err
+	}
+	if job.Disabled {
+		return nil, common.ErrJobDisabled
+	}
+
+	trigger := &common.JobTrigger{
+		JobName:     jobName,
+		RunID:       common.NewRunID(),
+		TriggeredBy: triggeredBy,
+		TriggeredAt: time.Now().Unix(),
+		ReplayOf:    replayOf,
+	}
+	if revision > 0 {
+		pinned, err := jm.GetJobAtRevision(jobName, revision)
+		if err != nil {
+			return nil, err
+		}
+		trigger.Revision = revision
+		trigger.Job = pinned
+	}
+	data, err := json.Marshal(trigger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal job trigger: %v", err)
+	}
+
+	if err = jm.etcdClient.PutWithLease(common.TriggerKey(jobName, trigger.RunID), string(data), common.JobTriggerTTL); err != nil {
+		jm.logger.Error("failed to trigger job",
+			zap.String("jobName", jobName),
+			zap.Error(err))
+		return nil, err
+	}
+
+	jm.logger.Info("job triggered manually",
+		zap.String("jobName", jobName),
+		zap.String("runId", trigger.RunID),
+		zap.Int64("revision", revision),
+		zap.String("replayOf", replayOf),
+		zap.String("triggeredBy", triggeredBy))
+	return trigger, nil
+}
+
+// GetJobAtRevision 获取任务修改版本为revision时保存的定义。版本不是任务某次保存的修改版本时
+// 返回ErrJobRevisionNotFound，已被etcd压缩时返回ErrRevisionCompacted
+func (jm *JobManager) GetJobAtRevision(jobName string, revision int64) (*common.Job, error) {
+	job, modRevision, err := jm.jobAtRevision(jobName, revision)
+	if err != nil {
+		return nil, err
+	}
+	if job == nil || modRevision != revision {
+		return nil, common.ErrJobRevisionNotFound
+	}
+	job.Revision = modRevision
+	return job, nil
+}
+
+// GetJobLock 获取任务锁的持有者和租约剩余时间
+func (jm *JobManager) GetJobLock(jobName string) (*common.JobLockInfo, error) {
+	lockKey := common.JobLockDir + jobName
+	resp, err := jm.etcdClient.Get(lockKey)
+	if err != nil {
+		jm.logger.Error("failed to get job lock",
+			zap.String("jobName", jobName),
+			zap.Error(err))
+		return nil, err
+	}
+
+	info := &common.JobLockInfo{JobName: jobName}
+	if resp.Count == 0 {
+		return info, nil
+	}
+
+	kv := resp.Kvs[0]
+	info.Locked = true
+	info.Holder = string(kv.Value)
+	info.LeaseID = kv.Lease
+
+	// 没有租约的锁不会自动过期
+	if kv.Lease == 0 {
+		return info, nil
+	}
+
+	ttl, err := jm.etcdClient.LeaseTTL(clientv3.LeaseID(kv.Lease))
+	if err != nil {
+		jm.logger.Error("failed to get job lock lease ttl",
+			zap.String("jobName", jobName),
+			zap.Int64("leaseId", kv.Lease),
+			zap.Error(err))
+		return nil, err
+	}
+
+	// 租约已过期，锁即将被etcd删除
+	if ttl < 0 {
+		return &common.JobLockInfo{JobName: jobName}, nil
+	}
+	info.TTL = ttl
+
+	return info, nil
+}
+
+// GetProgress 获取运行中任务最近上报的进度
+func (jm *JobManager) GetProgress(jobName string) (*common.JobProgress, error) {
+	resp, err := jm.etcdClient.Get(common.JobProgressDir + jobName)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Count == 0 {
+		return nil, common.ErrProgressNotFound
+	}
+
+	progress := &common.JobProgress{}
+	if err = json.Unmarshal(resp.Kvs[0].Value, progress); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal job progress: %v", err)
+	}
+	return progress, nil
+}
+
+// GetPlacement 汇总各worker上报的决策，说明任务最近一次触发由哪个worker执行、其他worker为什么没有执行
+func (jm *JobManager) GetPlacement(jobName string) (*common.JobPlacement, error) {
+	decisions, err := jm.loadPlacementDecisions(jobName)
+	if err != nil {
+		return nil, err
+	}
+	return common.ExplainPlacement(jobName, decisions), nil
+}
+
+// GetPlacementAt 汇总各worker对计划时间为planTime的触发的决策。每个worker只保留最近一次触发的决策，
+// 该触发的决策已被之后的触发覆盖时返回ErrPlacementNotFound
+func (jm *JobManager) GetPlacementAt(jobName string, planTime int64) (*common.JobPlacement, error) {
+	decisions, err := jm.loadPlacementDecisions(jobName)
+	if err != nil {
+		return nil, err
+	}
+
+	matched := make([]*common.PlacementDecision, 0, len(decisions))
+	for _, d := range decisions {
+		if d.PlanTime == planTime {
+			matched = append(matched, d)
+		}
+	}
+	if len(matched) == 0 {
+		return nil, common.ErrPlacementNotFound
+	}
+	return common.ExplainPlacement(jobName, matched), nil
+}
+
+// loadPlacementDecisions 读取各worker上报的任务调度决策
+func (jm *JobManager) loadPlacementDecisions(jobName string) ([]*common.PlacementDecision, error) {
+	resp, err := jm.etcdClient.GetWithPrefix(common.JobPlacementDir + jobName + "/")
+	if err != nil {
+		return nil, err
+	}
+
+	decisions := make([]*common.PlacementDecision, 0, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		decision := &common.PlacementDecision{}
+		if err = json.Unmarshal(kv.Value, decision); err != nil {
+			jm.logger.Warn("failed to unmarshal placement decision",
+				zap.String("key", string(kv.Key)),
+				zap.Error(err))
+			continue
+		}
+		decisions = append(decisions, decision)
+	}
+	return decisions, nil
+}
+
+// GetCheckpoint 获取任务上次失败执行留下的检查点
+func (jm *JobManager) GetCheckpoint(jobName string) (*common.JobCheckpoint, error) {
+	resp, err := jm.etcdClient.Get(common.JobCheckpointDir + jobName)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Count == 0 {
+		return nil, common.ErrCheckpointNotFound
+	}
+
+	checkpoint := &common.JobCheckpoint{}
+	if err = json.Unmarshal(resp.Kvs[0].Value, checkpoint); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal job checkpoint: %v", err)
+	}
+	return checkpoint, nil
+}
+
+// DeleteCheckpoint 删除任务的检查点，下次执行从头开始
+func (jm *JobManager) DeleteCheckpoint(jobName string) error {
+	resp, err := jm.etcdClient.Delete(common.JobCheckpointDir + jobName)
+	if err != nil {
+		jm.logger.Error("failed to delete job checkpoint",
+			zap.String("jobName", jobName),
+			zap.Error(err))
+		return err
+	}
+	if resp.Deleted == 0 {
+		return common.ErrCheckpointNotFound
+	}
+
+	jm.logger.Info("job checkpoint deleted", zap.String("jobName", jobName))
+	return nil
+}
+
 // DisableJob 禁用任务
 func (jm *JobManager) DisableJob(jobName string) error {
 	// 先获取任务
@@ -195,15 +506,21 @@ func (jm *JobManager) Stop() {
 
 // SearchJobs 搜索任务
 func (jm *JobManager) SearchJobs(keyword string) ([]*common.Job, error) {
+	jobs, _, err := jm.SearchJobsWithVersion(keyword)
+	return jobs, err
+}
+
+// SearchJobsWithVersion 搜索任务，同时返回任务集合的版本
+func (jm *JobManager) SearchJobsWithVersion(keyword string) ([]*common.Job, string, error) {
 	// 获取所有任务
-	allJobs, err := jm.ListJobs()
+	allJobs, version, err := jm.ListJobsWithVersion()
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
 	// 如果关键词为空，返回全部
 	if keyword == "" {
-		return allJobs, nil
+		return allJobs, version, nil
 	}
 
 	// 过滤匹配关键词的任务
@@ -215,7 +532,7 @@ func (jm *JobManager) SearchJobs(keyword string) ([]*common.Job, error) {
 		}
 	}
 
-	return matchedJobs, nil
+	return matchedJobs, version, nil
 }
 
 // 字符串包含检查，不区分大小写
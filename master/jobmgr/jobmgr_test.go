@@ -8,6 +8,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.etcd.io/etcd/api/v3/mvccpb"
 	"go.uber.org/zap/zaptest"
 
 	"github.com/fyerfyer/scheduler-refactor/common"
@@ -200,17 +201,71 @@ func TestKillJob(t *testing.T) {
 	err = jobMgr.KillJob("test-kill-job")
 	require.NoError(t, err, "KillJob should not return error")
 
-	resp, err := etcdClient.Get(common.JobLockDir + "test-kill-job")
+	resp, err := etcdClient.Get(common.JobKillDir + "test-kill-job")
 	require.NoError(t, err, "etcd Get should not return error")
 	assert.Equal(t, int64(1), resp.Count, "Kill marker should exist in etcd")
 
+	resp, err = etcdClient.Get(common.JobLockDir + "test-kill-job")
+	require.NoError(t, err, "etcd Get should not return error")
+	assert.Equal(t, int64(0), resp.Count, "Kill marker should not occupy the job lock")
+
 	time.Sleep(6 * time.Second)
 
-	resp, err = etcdClient.Get(common.JobLockDir + "test-kill-job")
+	resp, err = etcdClient.Get(common.JobKillDir + "test-kill-job")
 	require.NoError(t, err, "etcd Get should not return error")
 	assert.Equal(t, int64(0), resp.Count, "Kill marker should be expired after TTL")
 }
 
+func TestMigrateKillMarkers(t *testing.T) {
+	jobMgr, etcdClient, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	// 旧版本的kill标记和正常的任务锁
+	_, err := etcdClient.Put(common.JobLockDir+"test-legacy-kill", "")
+	require.NoError(t, err)
+	require.NoError(t, etcdClient.PutWithLease(common.JobLockDir+"test-held-lock", "worker-1", 10))
+	defer etcdClient.Delete(common.JobLockDir + "test-held-lock")
+
+	migrated, err := jobMgr.MigrateKillMarkers()
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, migrated, 1)
+
+	resp, err := etcdClient.Get(common.JobLockDir + "test-legacy-kill")
+	require.NoError(t, err)
+	assert.Zero(t, resp.Count, "Legacy kill marker should be removed")
+
+	resp, err = etcdClient.Get(common.JobLockDir + "test-held-lock")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), resp.Count, "Job locks should be kept")
+}
+
+func TestGetJobLock(t *testing.T) {
+	jobMgr, etcdClient, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	jobName := "test-lock-info-job"
+	lockKey := common.JobLockDir + jobName
+	etcdClient.Delete(lockKey)
+
+	// 锁不存在
+	info, err := jobMgr.GetJobLock(jobName)
+	require.NoError(t, err, "GetJobLock should not return error")
+	assert.False(t, info.Locked, "Lock should not exist")
+	assert.Empty(t, info.Holder)
+
+	// 锁被worker持有
+	leaseID, err := etcdClient.TryAcquireLock(lockKey, "worker-1", 10)
+	require.NoError(t, err, "TryAcquireLock should not return error")
+	defer etcdClient.ReleaseLock(lockKey, "worker-1", leaseID)
+
+	info, err = jobMgr.GetJobLock(jobName)
+	require.NoError(t, err, "GetJobLock should not return error")
+	assert.True(t, info.Locked, "Lock should exist")
+	assert.Equal(t, "worker-1", info.Holder)
+	assert.Equal(t, int64(leaseID), info.LeaseID)
+	assert.True(t, info.TTL > 0 && info.TTL <= 10, "TTL should be within lease time")
+}
+
 func TestSearchJobs(t *testing.T) {
 	jobMgr, _, cleanup := setupTestEnv(t)
 	defer cleanup()
@@ -361,3 +416,16 @@ func TestToLower(t *testing.T) {
 		})
 	}
 }
+
+func TestJobSetVersion(t *testing.T) {
+	kvs := []*mvccpb.KeyValue{
+		{Key: []byte(common.JobSaveDir + "a"), ModRevision: 3},
+		{Key: []byte(common.JobSaveDir + "b"), ModRevision: 7},
+		{Key: []byte(common.JobSaveDir + "c"), ModRevision: 5},
+	}
+	assert.Equal(t, "7-3", jobSetVersion(kvs))
+
+	// 删除任务后数量变化，版本随之变化
+	assert.Equal(t, "7-2", jobSetVersion(append(kvs[:1:1], kvs[1])))
+	assert.Equal(t, "0-0", jobSetVersion(nil))
+}
@@ -0,0 +1,35 @@
+package jobmgr
+
+import (
+	"github.com/fyerfyer/scheduler-refactor/common"
+)
+
+// JobPage 一页任务
+type JobPage struct {
+	Jobs     []*common.Job // 当前页的任务
+	Total    int           // 分页前的任务总数
+	Page     int           // 页码，从1开始
+	PageSize int           // 每页大小
+}
+
+// PageJobs 对已排序的任务分页，页码和每页大小的默认值和上限与日志分页一致，超出范围的页为空
+func PageJobs(jobs []*common.Job, page, pageSize int) *JobPage {
+	if page <= 0 {
+		page = common.DefaultPage
+	}
+	if pageSize <= 0 {
+		pageSize = common.DefaultPageSize
+	}
+	if pageSize > common.MaxPageSize {
+		pageSize = common.MaxPageSize
+	}
+
+	start := min((page-1)*pageSize, len(jobs))
+	end := min(start+pageSize, len(jobs))
+	return &JobPage{
+		Jobs:     jobs[start:end],
+		Total:    len(jobs),
+		Page:     page,
+		PageSize: pageSize,
+	}
+}
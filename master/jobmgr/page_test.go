@@ -0,0 +1,38 @@
+package jobmgr
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+)
+
+func TestPageJobs(t *testing.T) {
+	jobs := make([]*common.Job, 25)
+	for i := range jobs {
+		jobs[i] = &common.Job{Name: fmt.Sprintf("job-%02d", i)}
+	}
+
+	page := PageJobs(jobs, 2, 10)
+	assert.Equal(t, 25, page.Total)
+	assert.Len(t, page.Jobs, 10)
+	assert.Equal(t, "job-10", page.Jobs[0].Name)
+
+	page = PageJobs(jobs, 3, 10)
+	assert.Len(t, page.Jobs, 5, "The last page should hold the remaining jobs")
+
+	page = PageJobs(jobs, 4, 10)
+	assert.Empty(t, page.Jobs, "Pages past the end should be empty")
+	assert.Equal(t, 25, page.Total)
+
+	page = PageJobs(jobs, 0, 1000)
+	assert.Equal(t, common.DefaultPage, page.Page)
+	assert.Equal(t, common.MaxPageSize, page.PageSize)
+	assert.Len(t, page.Jobs, 25)
+
+	page = PageJobs(nil, 1, 0)
+	assert.Equal(t, common.DefaultPageSize, page.PageSize)
+	assert.Empty(t, page.Jobs)
+}
@@ -0,0 +1,107 @@
+package jobmgr
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.uber.org/zap"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+)
+
+// RenameJob 任务改名：在一个事务中写入新任务、删除旧任务并在旧任务名下留下改名记录。
+// 旧名称记入新任务的aliases，用于关联改名前的日志。新任务名已存在时返回ErrJobExists
+func (jm *JobManager) RenameJob(oldName, newName, renamedBy string) (*common.Job, error) {
+	oldKey := common.JobSaveDir + oldName
+	newKey := common.JobSaveDir + newName
+
+	resp, err := jm.etcdClient.Get(oldKey)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Count == 0 {
+		return nil, common.ErrJobNotFound
+	}
+
+	job := &common.Job{}
+	if err = json.Unmarshal(resp.Kvs[0].Value, job); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal job data: %v", err)
+	}
+	renameJob(job, oldName, newName)
+
+	jobData, err := json.Marshal(job)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal job: %v", err)
+	}
+	renameData, err := json.Marshal(&common.JobRename{
+		OldName:   oldName,
+		NewName:   newName,
+		RenamedBy: renamedBy,
+		RenamedAt: job.UpdatedAt,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal job rename: %v", err)
+	}
+
+	// 旧任务在读取后被修改或新任务名已被占用时放弃改名
+	applied, err := jm.etcdClient.ApplyIfAllUnchanged(
+		map[string]int64{oldKey: resp.Kvs[0].ModRevision, newKey: 0},
+		clientv3.OpPut(newKey, string(jobData)),
+		clientv3.OpDelete(oldKey),
+		clientv3.OpPut(common.JobRenamedDir+oldName, string(renameData)),
+		clientv3.OpDelete(common.JobRenamedDir+newName),
+		clientv3.OpDelete(common.CanaryDir+oldName),
+	)
+	if err != nil {
+		jm.logger.Error("failed to rename job",
+			zap.String("oldName", oldName),
+			zap.String("newName", newName),
+			zap.Error(err))
+		return nil, err
+	}
+	if !applied {
+		if _, err = jm.GetJob(newName); err == nil {
+			return nil, common.ErrJobExists
+		}
+		return nil, common.ErrJobSaveConflict
+	}
+
+	jm.logger.Info("job renamed",
+		zap.String("oldName", oldName),
+		zap.String("newName", newName),
+		zap.String("renamedBy", renamedBy))
+	return job, nil
+}
+
+// GetRename 获取旧任务名的改名记录
+func (jm *JobManager) GetRename(oldName string) (*common.JobRename, error) {
+	resp, err := jm.etcdClient.Get(common.JobRenamedDir + oldName)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Count == 0 {
+		return nil, common.ErrJobNotFound
+	}
+
+	rename := &common.JobRename{}
+	if err = json.Unmarshal(resp.Kvs[0].Value, rename); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal job rename: %v", err)
+	}
+	return rename, nil
+}
+
+// renameJob 修改任务名并记录旧名称，改回曾用名时从aliases中移除该名称
+func renameJob(job *common.Job, oldName, newName string) {
+	aliases := make([]string, 0, len(job.Aliases)+1)
+	for _, alias := range job.Aliases {
+		if alias != newName && alias != oldName {
+			aliases = append(aliases, alias)
+		}
+	}
+
+	job.Name = newName
+	job.Aliases = append(aliases, oldName)
+	job.UpdatedAt = time.Now().Unix()
+}
@@ -0,0 +1,25 @@
+package jobmgr
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+)
+
+func TestRenameJobAliases(t *testing.T) {
+	job := &common.Job{Name: "backup"}
+
+	renameJob(job, "backup", "db-backup")
+	assert.Equal(t, "db-backup", job.Name)
+	assert.Equal(t, []string{"backup"}, job.Aliases)
+
+	renameJob(job, "db-backup", "nightly-backup")
+	assert.Equal(t, []string{"backup", "db-backup"}, job.Aliases)
+
+	// 改回曾用名时该名称不再是别名
+	renameJob(job, "nightly-backup", "backup")
+	assert.Equal(t, "backup", job.Name)
+	assert.Equal(t, []string{"db-backup", "nightly-backup"}, job.Aliases)
+}
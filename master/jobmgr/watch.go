@@ -0,0 +1,96 @@
+package jobmgr
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+)
+
+// 任务变更类型
+const (
+	JobChangeSave   = "save"   // 保存任务
+	JobChangeDelete = "delete" // 删除任务
+)
+
+// JobChange 一次任务定义变更
+type JobChange struct {
+	Type     string      `json:"type"`          // 变更类型: save/delete
+	Name     string      `json:"name"`          // 任务名
+	Job      *common.Job `json:"job,omitempty"` // 保存后的任务定义，删除时为空
+	Revision int64       `json:"revision"`      // 变更对应的etcd版本
+}
+
+// JobChanges 监听到的任务变更，Revision用作下一次监听的起始版本
+type JobChanges struct {
+	Revision int64        `json:"revision"` // 已经返回到的etcd版本
+	Changes  []*JobChange `json:"changes"`  // 任务变更，等待超时时为空
+}
+
+// WatchJobs 等待fromRevision之后的任务变更，有变更或ctx结束时返回。
+// fromRevision为0时从当前版本开始等待
+func (jm *JobManager) WatchJobs(ctx context.Context, fromRevision int64) (*JobChanges, error) {
+	if fromRevision <= 0 {
+		resp, err := jm.etcdClient.GetWithPrefix(common.JobSaveDir)
+		if err != nil {
+			return nil, err
+		}
+		fromRevision = resp.Header.Revision
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	result := &JobChanges{Revision: fromRevision, Changes: []*JobChange{}}
+	watchChan := jm.etcdClient.WatchWithPrefixFrom(ctx, common.JobSaveDir, fromRevision+1)
+	for {
+		select {
+		case <-ctx.Done():
+			return result, nil
+		case watchResp, ok := <-watchChan:
+			if !ok {
+				return result, nil
+			}
+			if watchResp.CompactRevision != 0 {
+				return nil, common.ErrRevisionCompacted
+			}
+			if err := watchResp.Err(); err != nil {
+				return nil, common.NewEtcdError("watch", common.JobSaveDir, err)
+			}
+			if len(watchResp.Events) == 0 {
+				continue
+			}
+
+			result.Changes = jobChanges(watchResp.Events)
+			result.Revision = watchResp.Header.Revision
+			return result, nil
+		}
+	}
+}
+
+// jobChanges 将etcd事件转换为任务变更，无法解析的任务定义只返回任务名
+func jobChanges(events []*clientv3.Event) []*JobChange {
+	changes := make([]*JobChange, 0, len(events))
+	for _, event := range events {
+		change := &JobChange{
+			Type:     JobChangeSave,
+			Name:     strings.TrimPrefix(string(event.Kv.Key), common.JobSaveDir),
+			Revision: event.Kv.ModRevision,
+		}
+
+		if event.Type == clientv3.EventTypeDelete {
+			change.Type = JobChangeDelete
+		} else {
+			job := &common.Job{}
+			if err := json.Unmarshal(event.Kv.Value, job); err == nil {
+				change.Job = job
+			}
+		}
+
+		changes = append(changes, change)
+	}
+	return changes
+}
@@ -0,0 +1,45 @@
+package jobmgr
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+)
+
+func TestJobChanges(t *testing.T) {
+	events := []*clientv3.Event{
+		{
+			Type: clientv3.EventTypePut,
+			Kv: &mvccpb.KeyValue{
+				Key:         []byte(common.JobSaveDir + "backup"),
+				Value:       []byte(`{"name":"backup","command":"echo backup","cronExpr":"0 0 * * * *"}`),
+				ModRevision: 10,
+			},
+		},
+		{
+			Type: clientv3.EventTypeDelete,
+			Kv: &mvccpb.KeyValue{
+				Key:         []byte(common.JobSaveDir + "cleanup"),
+				ModRevision: 11,
+			},
+		},
+	}
+
+	changes := jobChanges(events)
+	require.Len(t, changes, 2)
+
+	assert.Equal(t, JobChangeSave, changes[0].Type)
+	assert.Equal(t, "backup", changes[0].Name)
+	assert.Equal(t, int64(10), changes[0].Revision)
+	require.NotNil(t, changes[0].Job)
+	assert.Equal(t, "echo backup", changes[0].Job.Command)
+
+	assert.Equal(t, JobChangeDelete, changes[1].Type)
+	assert.Equal(t, "cleanup", changes[1].Name)
+	assert.Nil(t, changes[1].Job, "Deleted jobs should not carry a definition")
+}
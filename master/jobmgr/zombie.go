@@ -0,0 +1,136 @@
+package jobmgr
+
+import (
+	"strings"
+
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.uber.org/zap"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+)
+
+// 孤立key的原因
+const (
+	ZombieNoLease    = "no_lease"    // 本应随租约过期的key没有绑定租约，永远不会被删除
+	ZombieJobDeleted = "job_deleted" // key引用的任务已不存在
+)
+
+// ZombieKey 孤立的etcd key
+type ZombieKey struct {
+	Key         string `json:"key"`         // etcd key
+	JobName     string `json:"jobName"`     // 引用的任务名
+	Reason      string `json:"reason"`      // 孤立原因
+	Lease       int64  `json:"lease"`       // 绑定的租约ID，0表示没有租约
+	ModRevision int64  `json:"modRevision"` // 最后修改的版本
+	Purged      bool   `json:"purged"`      // 是否已删除
+}
+
+// ZombieReport 孤立key扫描结果
+type ZombieReport struct {
+	Scanned int          `json:"scanned"` // 扫描的key数量
+	Zombies []*ZombieKey `json:"zombies"` // 孤立的key
+	Purged  int          `json:"purged"`  // 删除的key数量
+	DryRun  bool         `json:"dryRun"`  // 是否只报告不删除
+}
+
+// zombieDir 按任务名组织的目录
+type zombieDir struct {
+	prefix string // 目录前缀，key为前缀加任务名
+	leased bool   // key是否应当绑定租约
+}
+
+// zombieDirs 参与扫描的目录：任务锁（含实验锁和旧版本写入的kill标记）、kill标记、执行进度、检查点、灰度发布和最近执行结果
+var zombieDirs = []zombieDir{
+	{prefix: common.JobLockDir, leased: true},
+	{prefix: common.JobKillDir, leased: true},
+	{prefix: common.JobProgressDir, leased: true},
+	{prefix: common.JobCheckpointDir},
+	{prefix: common.CanaryDir},
+	{prefix: common.JobLastRunDir},
+}
+
+// classifyZombie 判断key是否孤立，正常的key返回nil
+func classifyZombie(dir zombieDir, kv *mvccpb.KeyValue, jobs map[string]bool) *ZombieKey {
+	key := string(kv.Key)
+	jobName := strings.TrimSuffix(strings.TrimPrefix(key, dir.prefix), common.ExperimentLockSuffix)
+
+	reason := ""
+	switch {
+	case dir.leased && kv.Lease == 0:
+		reason = ZombieNoLease
+	case !jobs[jobName]:
+		reason = ZombieJobDeleted
+	default:
+		return nil
+	}
+
+	return &ZombieKey{
+		Key:         key,
+		JobName:     jobName,
+		Reason:      reason,
+		Lease:       kv.Lease,
+		ModRevision: kv.ModRevision,
+	}
+}
+
+// CleanupZombies 扫描没有租约或引用已删除任务的key，dryRun为false时删除它们。
+// 先扫描目录再读取任务列表，扫描期间新建的任务不会被误判；删除时确认key未被修改
+func (jm *JobManager) CleanupZombies(dryRun bool) (*ZombieReport, error) {
+	report := &ZombieReport{Zombies: make([]*ZombieKey, 0), DryRun: dryRun}
+
+	scanned := make(map[zombieDir][]*mvccpb.KeyValue, len(zombieDirs))
+	for _, dir := range zombieDirs {
+		resp, err := jm.etcdClient.GetWithPrefix(dir.prefix)
+		if err != nil {
+			jm.logger.Error("failed to scan keys",
+				zap.String("prefix", dir.prefix),
+				zap.Error(err))
+			return nil, err
+		}
+		scanned[dir] = resp.Kvs
+		report.Scanned += len(resp.Kvs)
+	}
+
+	resp, err := jm.etcdClient.GetWithPrefix(common.JobSaveDir)
+	if err != nil {
+		jm.logger.Error("failed to list jobs", zap.Error(err))
+		return nil, err
+	}
+	jobs := make(map[string]bool, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		jobs[strings.TrimPrefix(string(kv.Key), common.JobSaveDir)] = true
+	}
+
+	for _, dir := range zombieDirs {
+		for _, kv := range scanned[dir] {
+			if zombie := classifyZombie(dir, kv, jobs); zombie != nil {
+				report.Zombies = append(report.Zombies, zombie)
+			}
+		}
+	}
+	if dryRun {
+		return report, nil
+	}
+
+	for _, zombie := range report.Zombies {
+		applied, err := jm.etcdClient.ApplyIfUnchanged(zombie.Key, zombie.ModRevision, clientv3.OpDelete(zombie.Key))
+		if err != nil {
+			jm.logger.Error("failed to purge zombie key",
+				zap.String("key", zombie.Key),
+				zap.Error(err))
+			return nil, err
+		}
+		// 扫描后被修改或删除的key保留
+		if !applied {
+			continue
+		}
+		zombie.Purged = true
+		report.Purged++
+		jm.logger.Info("zombie key purged",
+			zap.String("key", zombie.Key),
+			zap.String("reason", zombie.Reason))
+	}
+
+	return report, nil
+}
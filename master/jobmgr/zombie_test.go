@@ -0,0 +1,57 @@
+package jobmgr
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+)
+
+func TestCleanupZombies(t *testing.T) {
+	jobMgr, etcdClient, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	jobName := "test-zombie-job"
+	require.NoError(t, jobMgr.SaveJob(&common.Job{Name: jobName, Command: "echo", CronExpr: "*/5 * * * * *"}))
+	defer jobMgr.DeleteJob(jobName)
+
+	// 没有租约的锁、已删除任务的检查点，以及正常的租约锁
+	unleasedLock := common.JobLockDir + jobName
+	orphanCheckpoint := common.JobCheckpointDir + "test-zombie-deleted"
+	leasedLock := common.JobLockDir + jobName + common.ExperimentLockSuffix
+	_, err := etcdClient.Put(unleasedLock, "worker-1")
+	require.NoError(t, err)
+	_, err = etcdClient.Put(orphanCheckpoint, "{}")
+	require.NoError(t, err)
+	require.NoError(t, etcdClient.PutWithLease(leasedLock, "worker-2", 10))
+	defer etcdClient.Delete(leasedLock)
+
+	reasons := func(report *ZombieReport) map[string]string {
+		found := make(map[string]string)
+		for _, zombie := range report.Zombies {
+			found[zombie.Key] = zombie.Reason
+		}
+		return found
+	}
+
+	report, err := jobMgr.CleanupZombies(true)
+	require.NoError(t, err)
+	found := reasons(report)
+	assert.Equal(t, ZombieNoLease, found[unleasedLock])
+	assert.Equal(t, ZombieJobDeleted, found[orphanCheckpoint])
+	assert.NotContains(t, found, leasedLock, "Leased lock of an existing job is not a zombie")
+	assert.Zero(t, report.Purged, "Dry run should not purge")
+
+	report, err = jobMgr.CleanupZombies(false)
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, report.Purged, 2)
+
+	resp, err := etcdClient.Get(unleasedLock)
+	require.NoError(t, err)
+	assert.Zero(t, resp.Count, "Unleased lock should be purged")
+	resp, err = etcdClient.Get(leasedLock)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), resp.Count, "Leased lock should be kept")
+}
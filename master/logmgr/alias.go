@@ -0,0 +1,89 @@
+package logmgr
+
+import (
+	"sort"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+)
+
+// 任务改名后，改名前的日志仍记录在旧任务名下。查询时传入任务的aliases即可
+// 一并查询这些日志：分页查询由存储按名称列表完成，其他查询每个名称单独查询后在内存中合并，别名通常只有几个
+
+// jobNames 查询涉及的任务名，jobName为空表示查询全部任务，此时忽略别名
+func jobNames(jobName string, aliases []string) []string {
+	names := []string{jobName}
+	if jobName == "" {
+		return names
+	}
+
+	seen := map[string]bool{jobName: true}
+	for _, alias := range aliases {
+		if alias != "" && !seen[alias] {
+			seen[alias] = true
+			names = append(names, alias)
+		}
+	}
+	return names
+}
+
+// findJobLogs 按开始时间倒序分页查询多个任务名下的日志
+func (lm *LogManager) findJobLogs(names []string, scope *common.Scope, skip, limit int64) ([]*common.JobLog, error) {
+	if len(names) == 1 {
+		return lm.logStore.FindJobLogs(names[0], scope, skip, limit)
+	}
+	return lm.logStore.FindJobLogsByNames(names, scope, skip, limit)
+}
+
+// countJobLogs 统计多个任务名下的日志总数
+func (lm *LogManager) countJobLogs(names []string, scope *common.Scope) (int64, error) {
+	var total int64
+	for _, name := range names {
+		count, err := lm.logStore.CountJobLogs(name, scope)
+		if err != nil {
+			return 0, err
+		}
+		total += count
+	}
+	return total, nil
+}
+
+// findJobLogsSince 查询多个任务名下指定时间之后开始的日志
+func (lm *LogManager) findJobLogsSince(names []string, scope *common.Scope, timestamp int64) ([]*common.JobLog, error) {
+	if len(names) == 1 {
+		return lm.logStore.FindJobLogsSince(names[0], scope, timestamp)
+	}
+
+	var logs []*common.JobLog
+	for _, name := range names {
+		found, err := lm.logStore.FindJobLogsSince(name, scope, timestamp)
+		if err != nil {
+			return nil, err
+		}
+		logs = append(logs, found...)
+	}
+	sortLogsByStartTime(logs)
+	return logs, nil
+}
+
+// findDailyStats 查询多个任务名下的按天统计，按日期升序
+func (lm *LogManager) findDailyStats(names []string, scope *common.Scope, from, to int64) ([]*common.JobDailyStats, error) {
+	if len(names) == 1 {
+		return lm.logStore.FindDailyStats(names[0], scope, from, to)
+	}
+
+	var stats []*common.JobDailyStats
+	for _, name := range names {
+		found, err := lm.logStore.FindDailyStats(name, scope, from, to)
+		if err != nil {
+			return nil, err
+		}
+		stats = append(stats, found...)
+	}
+	sort.SliceStable(stats, func(i, j int) bool { return stats[i].Day < stats[j].Day })
+	return stats, nil
+}
+
+// sortLogsByStartTime 按开始时间倒序排列日志
+func sortLogsByStartTime(logs []*common.JobLog) {
+	sort.SliceStable(logs, func(i, j int) bool { return logs[i].StartTime > logs[j].StartTime })
+}
@@ -0,0 +1,56 @@
+package logmgr
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+	"github.com/fyerfyer/scheduler-refactor/pkg/sqlstore"
+)
+
+func TestJobNames(t *testing.T) {
+	assert.Equal(t, []string{"job"}, jobNames("job", nil))
+	assert.Equal(t, []string{"job", "old"}, jobNames("job", []string{"old", "job", "", "old"}))
+	assert.Equal(t, []string{""}, jobNames("", []string{"old"}), "Aliases are ignored when querying all jobs")
+}
+
+func TestListLogsWithAliases(t *testing.T) {
+	store, err := sqlstore.NewClient("sqlite", "file:"+filepath.Join(t.TempDir(), "logs.db"))
+	require.NoError(t, err)
+	defer store.Close()
+
+	now := time.Now().Unix()
+	require.NoError(t, store.InsertLogs([]*common.JobLog{
+		{JobName: "backup", StartTime: now - 30, EndTime: now - 29},
+		{JobName: "db-backup", StartTime: now - 20, EndTime: now - 19},
+		{JobName: "backup", StartTime: now - 10, EndTime: now - 9},
+		{JobName: "other", StartTime: now, EndTime: now + 1},
+	}))
+	logMgr := NewLogManager(store, zap.NewNop())
+
+	logs, total, err := logMgr.ListLogs("db-backup", nil, 1, 10)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), total, "Aliases are only included on request")
+	assert.Len(t, logs, 1)
+
+	logs, total, err = logMgr.ListLogs("db-backup", nil, 1, 2, "backup")
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), total)
+	require.Len(t, logs, 2)
+	assert.Equal(t, now-10, logs[0].StartTime, "Logs should be merged by start time")
+	assert.Equal(t, now-20, logs[1].StartTime)
+
+	logs, _, err = logMgr.ListLogs("db-backup", nil, 2, 2, "backup")
+	require.NoError(t, err)
+	require.Len(t, logs, 1)
+	assert.Equal(t, now-30, logs[0].StartTime)
+
+	stats, err := logMgr.GetLogStatistics("db-backup", nil, 1, "backup")
+	require.NoError(t, err)
+	assert.Equal(t, 3, stats["totalCount"])
+}
@@ -0,0 +1,138 @@
+package logmgr
+
+import (
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+)
+
+// 成本报表的分组方式
+const (
+	CostGroupByOwner     = "owner"     // 按负责人分组
+	CostGroupByNamespace = "namespace" // 按命名空间分组
+)
+
+// CostPrices 成本单价
+type CostPrices struct {
+	CPUHour float64 `json:"cpuHour"` // 每CPU小时的单价
+	GBHour  float64 `json:"gbHour"`  // 每GB内存小时的单价
+}
+
+// JobCost 单个任务的资源使用和成本
+type JobCost struct {
+	JobName  string  `json:"jobName"`  // 任务名称
+	Runs     int     `json:"runs"`     // 执行次数
+	CPUHours float64 `json:"cpuHours"` // CPU小时
+	GBHours  float64 `json:"gbHours"`  // 内存GB小时（峰值内存×执行时长）
+	Cost     float64 `json:"cost"`     // 估算成本
+}
+
+// CostGroup 一个负责人或命名空间的成本汇总
+type CostGroup struct {
+	Key      string     `json:"key"`      // 负责人或命名空间
+	CPUHours float64    `json:"cpuHours"` // CPU小时
+	GBHours  float64    `json:"gbHours"`  // 内存GB小时
+	Cost     float64    `json:"cost"`     // 估算成本
+	Jobs     []*JobCost `json:"jobs"`     // 按成本倒序排列的任务明细
+}
+
+// CostReport 成本报表
+type CostReport struct {
+	Month   string       `json:"month"`   // 统计月份，格式2006-01
+	GroupBy string       `json:"groupBy"` // 分组方式
+	Prices  CostPrices   `json:"prices"`  // 使用的单价
+	Cost    float64      `json:"cost"`    // 总成本
+	Groups  []*CostGroup `json:"groups"`  // 按成本倒序排列的分组
+}
+
+// GetCostReport 统计指定月份的任务资源使用并估算成本
+func (lm *LogManager) GetCostReport(month time.Time, groupBy string, prices CostPrices, scope *common.Scope) (*CostReport, error) {
+	start := time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, month.Location())
+	end := start.AddDate(0, 1, 0)
+
+	logs, err := lm.getLogsSince("", scope, start.Unix())
+	if err != nil {
+		return nil, err
+	}
+
+	report := buildCostReport(logs, start.Unix(), end.Unix(), groupBy, prices)
+	report.Month = start.Format("2006-01")
+
+	lm.logger.Debug("cost report built",
+		zap.String("month", report.Month),
+		zap.Int("logs", len(logs)),
+		zap.Int("groups", len(report.Groups)))
+
+	return report, nil
+}
+
+// RunCost 估算一次执行的资源使用和成本，内存按峰值内存乘以执行时长计为GB小时
+func RunCost(log *common.JobLog, prices CostPrices) (cpuHours, gbHours, cost float64) {
+	cpuHours = log.CPUTime / 3600
+	gbHours = float64(log.MaxRSS) / (1024 * 1024) * float64(log.EndTime-log.StartTime) / 3600
+	cost = cpuHours*prices.CPUHour + gbHours*prices.GBHour
+	return cpuHours, gbHours, cost
+}
+
+// buildCostReport 根据日志汇总成本，只统计[start, end)内开始的执行
+func buildCostReport(logs []*common.JobLog, start, end int64, groupBy string, prices CostPrices) *CostReport {
+	if groupBy != CostGroupByNamespace {
+		groupBy = CostGroupByOwner
+	}
+
+	groups := make(map[string]*CostGroup)
+	jobs := make(map[string]map[string]*JobCost)
+
+	for _, log := range logs {
+		if log.StartTime < start || log.StartTime >= end {
+			continue
+		}
+
+		key := log.Owner
+		if groupBy == CostGroupByNamespace {
+			key = common.NamespaceOf(log.Namespace)
+		}
+
+		group, ok := groups[key]
+		if !ok {
+			group = &CostGroup{Key: key}
+			groups[key] = group
+			jobs[key] = make(map[string]*JobCost)
+		}
+
+		job, ok := jobs[key][log.JobName]
+		if !ok {
+			job = &JobCost{JobName: log.JobName}
+			jobs[key][log.JobName] = job
+			group.Jobs = append(group.Jobs, job)
+		}
+
+		cpuHours, gbHours, cost := RunCost(log, prices)
+
+		job.Runs++
+		job.CPUHours += cpuHours
+		job.GBHours += gbHours
+		job.Cost += cost
+
+		group.CPUHours += cpuHours
+		group.GBHours += gbHours
+		group.Cost += cost
+	}
+
+	report := &CostReport{
+		GroupBy: groupBy,
+		Prices:  prices,
+		Groups:  make([]*CostGroup, 0, len(groups)),
+	}
+	for _, group := range groups {
+		sort.Slice(group.Jobs, func(i, j int) bool { return group.Jobs[i].Cost > group.Jobs[j].Cost })
+		report.Groups = append(report.Groups, group)
+		report.Cost += group.Cost
+	}
+	sort.Slice(report.Groups, func(i, j int) bool { return report.Groups[i].Cost > report.Groups[j].Cost })
+
+	return report
+}
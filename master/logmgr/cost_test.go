@@ -0,0 +1,35 @@
+package logmgr
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+)
+
+func TestBuildCostReport(t *testing.T) {
+	logs := []*common.JobLog{
+		// 1 CPU小时，1GB内存运行1小时
+		{JobName: "etl", Owner: "alice", Namespace: "data", StartTime: 1000, EndTime: 4600, CPUTime: 3600, MaxRSS: 1024 * 1024},
+		{JobName: "etl", Owner: "alice", Namespace: "data", StartTime: 5000, EndTime: 5000, CPUTime: 1800},
+		{JobName: "backup", Owner: "bob", StartTime: 2000, EndTime: 2000, CPUTime: 360},
+		// 统计区间之外
+		{JobName: "etl", Owner: "alice", StartTime: 99999, EndTime: 99999, CPUTime: 3600},
+	}
+	prices := CostPrices{CPUHour: 2, GBHour: 1}
+
+	report := buildCostReport(logs, 0, 10000, CostGroupByOwner, prices)
+	require.Len(t, report.Groups, 2)
+	assert.Equal(t, "alice", report.Groups[0].Key, "Groups should be sorted by cost")
+	assert.InDelta(t, 1.5, report.Groups[0].CPUHours, 1e-9)
+	assert.InDelta(t, 1.0, report.Groups[0].GBHours, 1e-9)
+	assert.InDelta(t, 4.0, report.Groups[0].Cost, 1e-9)
+	assert.Equal(t, 2, report.Groups[0].Jobs[0].Runs)
+	assert.InDelta(t, 4.2, report.Cost, 1e-9)
+
+	report = buildCostReport(logs, 0, 10000, CostGroupByNamespace, prices)
+	keys := []string{report.Groups[0].Key, report.Groups[1].Key}
+	assert.ElementsMatch(t, []string{"data", common.DefaultNamespace}, keys, "Logs without namespace should fall into default")
+}
@@ -0,0 +1,96 @@
+package logmgr
+
+import (
+	"time"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+)
+
+// 偏移报告的参数
+const (
+	maxDriftMissed = 100  // 报告中保留的最近没有执行或被跳过的计划时间数
+	lateThreshold  = 1000 // 实际调度比计划时间晚多少毫秒计为迟到
+)
+
+// DriftReport 任务按cron表达式应触发的时间与实际触发时间的对比
+type DriftReport struct {
+	JobName      string               `json:"jobName"`      // 任务名称
+	Start        int64                `json:"start"`        // 统计开始时间
+	End          int64                `json:"end"`          // 统计结束时间
+	Expected     int                  `json:"expected"`     // 按cron表达式应触发的次数
+	Fired        int                  `json:"fired"`        // 实际执行的触发次数
+	SkippedCount int                  `json:"skippedCount"` // 只有跳过记录的触发次数
+	Skipped      []int64              `json:"skipped"`      // 最近只有跳过记录的计划时间，新的在前
+	MissedCount  int                  `json:"missedCount"`  // 没有任何记录的触发次数
+	Missed       []int64              `json:"missed"`       // 最近没有任何记录的计划时间，新的在前
+	Late         int                  `json:"late"`         // 实际调度比计划时间晚1秒以上的执行次数
+	Drift        *common.LatencyStats `json:"drift"`        // 计划时间到实际调度的延迟分布，不含手动触发和跳过的触发
+}
+
+// GetDriftReport 对比start到end之间应触发的时间expected(unix秒)与日志中记录的计划时间和实际调度时间
+func (lm *LogManager) GetDriftReport(jobName string, scope *common.Scope, start, end time.Time, expected []int64) (*DriftReport, error) {
+	logs, err := lm.getLogsSince(jobName, scope, start.Unix())
+	if err != nil {
+		return nil, err
+	}
+
+	report := buildDriftReport(expected, logs)
+	report.JobName = jobName
+	report.Start = start.Unix()
+	report.End = end.Unix()
+
+	return report, nil
+}
+
+// buildDriftReport 按计划时间匹配应触发的时间和日志，手动触发的执行不参与对比。
+// 计划时间只有跳过记录（过载、时间窗口、暂停、加锁失败等）时单独列出，不算作已执行
+func buildDriftReport(expected []int64, logs []*common.JobLog) *DriftReport {
+	fired := make(map[int64]bool)
+	skipped := make(map[int64]bool)
+	var delays []int64
+	late := 0
+	for _, log := range logs {
+		// 实验命令与当前命令同时触发，只统计一次
+		if log.TriggeredBy != "" || log.Experiment {
+			continue
+		}
+		if log.GetStatus() == common.RunStatusSkipped {
+			skipped[log.PlanTime] = true
+			continue
+		}
+		fired[log.PlanTime] = true
+
+		delay, _ := log.Latencies()
+		delays = append(delays, delay)
+		if delay > lateThreshold {
+			late++
+		}
+	}
+
+	report := &DriftReport{
+		Expected: len(expected),
+		Skipped:  []int64{},
+		Missed:   []int64{},
+		Late:     late,
+		Drift:    latencyStats(delays),
+	}
+	for i := len(expected) - 1; i >= 0; i-- {
+		if fired[expected[i]] {
+			report.Fired++
+			continue
+		}
+		if skipped[expected[i]] {
+			report.SkippedCount++
+			if len(report.Skipped) < maxDriftMissed {
+				report.Skipped = append(report.Skipped, expected[i])
+			}
+			continue
+		}
+		report.MissedCount++
+		if len(report.Missed) < maxDriftMissed {
+			report.Missed = append(report.Missed, expected[i])
+		}
+	}
+
+	return report
+}
@@ -0,0 +1,33 @@
+package logmgr
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+)
+
+func TestBuildDriftReport(t *testing.T) {
+	expected := []int64{100, 110, 120, 130}
+	logs := []*common.JobLog{
+		{PlanTime: 100, ScheduleTime: 100, StartTime: 100, EndTime: 101, ScheduleDelayMs: 120},
+		{PlanTime: 100, ScheduleTime: 100, StartTime: 100, EndTime: 101, ScheduleDelayMs: 130, Experiment: true},
+		{PlanTime: 110, ScheduleTime: 112, StartTime: 112, EndTime: 113, ScheduleDelayMs: 2300},
+		{PlanTime: 130, ScheduleTime: 131, StartTime: 131, EndTime: 131, Status: common.RunStatusSkipped},
+		{PlanTime: 120, ScheduleTime: 125, StartTime: 125, EndTime: 126, TriggeredBy: "alice"},
+		// 一个worker跳过、另一个worker执行的触发仍算作已执行
+		{PlanTime: 110, ScheduleTime: 110, StartTime: 110, EndTime: 110, Status: common.RunStatusSkipped, SkipReason: common.SkipReasonOverload},
+	}
+
+	report := buildDriftReport(expected, logs)
+	assert.Equal(t, 4, report.Expected)
+	assert.Equal(t, 2, report.Fired)
+	assert.Equal(t, 1, report.SkippedCount, "Fires with only skip records should not count as fired")
+	assert.Equal(t, []int64{130}, report.Skipped)
+	assert.Equal(t, 1, report.MissedCount, "Manual triggers should not cover a cron fire")
+	assert.Equal(t, []int64{120}, report.Missed)
+	assert.Equal(t, 1, report.Late)
+	assert.Equal(t, 2, report.Drift.Count, "Experiment and skipped runs should not be counted")
+	assert.Equal(t, int64(2300), report.Drift.MaxMs)
+}
@@ -0,0 +1,113 @@
+package logmgr
+
+import (
+	"sort"
+	"time"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+)
+
+// maxExperimentRuns 实验报告中保留的最近对比记录数
+const maxExperimentRuns = 50
+
+// ExperimentRun 同一次触发中当前命令与实验命令的执行结果
+type ExperimentRun struct {
+	PlanTime           int64 `json:"planTime"`           // 计划执行时间
+	ExitCode           int   `json:"exitCode"`           // 当前命令的退出码
+	ExperimentExitCode int   `json:"experimentExitCode"` // 实验命令的退出码
+	Duration           int64 `json:"duration"`           // 当前命令的执行时长(秒)
+	ExperimentDuration int64 `json:"experimentDuration"` // 实验命令的执行时长(秒)
+	Match              bool  `json:"match"`              // 退出码是否一致
+}
+
+// ExperimentReport 实验命令与当前命令的对比报告
+type ExperimentReport struct {
+	JobName               string           `json:"jobName"`               // 任务名称
+	Days                  int              `json:"days"`                  // 统计天数
+	Compared              int              `json:"compared"`              // 两个版本都执行了的触发数
+	Matched               int              `json:"matched"`               // 退出码一致的触发数
+	AvgDuration           float64          `json:"avgDuration"`           // 当前命令的平均执行时长(秒)
+	ExperimentAvgDuration float64          `json:"experimentAvgDuration"` // 实验命令的平均执行时长(秒)
+	Unpaired              int              `json:"unpaired"`              // 只有一个版本执行了的触发数
+	Runs                  []*ExperimentRun `json:"runs"`                  // 最近的对比记录，新的在前
+}
+
+// GetExperimentReport 按计划执行时间配对当前命令和实验命令的日志，对比退出码和执行时长
+func (lm *LogManager) GetExperimentReport(jobName string, scope *common.Scope, days int) (*ExperimentReport, error) {
+	if days <= 0 {
+		days = 7
+	}
+
+	logs, err := lm.getLogsSince(jobName, scope, time.Now().AddDate(0, 0, -days).Unix())
+	if err != nil {
+		return nil, err
+	}
+
+	report := buildExperimentReport(logs)
+	report.JobName = jobName
+	report.Days = days
+
+	return report, nil
+}
+
+// buildExperimentReport 按计划执行时间配对日志并汇总对比结果，跳过记录不参与对比
+func buildExperimentReport(logs []*common.JobLog) *ExperimentReport {
+	controls := make(map[int64]*common.JobLog)
+	experiments := make(map[int64]*common.JobLog)
+	for _, log := range logs {
+		if log.GetStatus() == common.RunStatusSkipped {
+			continue
+		}
+		if log.Experiment {
+			experiments[log.PlanTime] = log
+		} else {
+			controls[log.PlanTime] = log
+		}
+	}
+
+	report := &ExperimentReport{Runs: make([]*ExperimentRun, 0)}
+	var totalDuration, experimentDuration int64
+	for planTime, experiment := range experiments {
+		control, exists := controls[planTime]
+		if !exists {
+			report.Unpaired++
+			continue
+		}
+
+		run := &ExperimentRun{
+			PlanTime:           planTime,
+			ExitCode:           control.ExitCode,
+			ExperimentExitCode: experiment.ExitCode,
+			Duration:           control.EndTime - control.StartTime,
+			ExperimentDuration: experiment.EndTime - experiment.StartTime,
+		}
+		run.Match = run.ExitCode == run.ExperimentExitCode
+
+		report.Compared++
+		if run.Match {
+			report.Matched++
+		}
+		totalDuration += run.Duration
+		experimentDuration += run.ExperimentDuration
+		report.Runs = append(report.Runs, run)
+	}
+
+	// 只有当前命令执行了的触发，实验命令没有其他worker可用或执行前被跳过
+	for planTime := range controls {
+		if _, exists := experiments[planTime]; !exists {
+			report.Unpaired++
+		}
+	}
+
+	if report.Compared > 0 {
+		report.AvgDuration = float64(totalDuration) / float64(report.Compared)
+		report.ExperimentAvgDuration = float64(experimentDuration) / float64(report.Compared)
+	}
+
+	sort.Slice(report.Runs, func(i, j int) bool { return report.Runs[i].PlanTime > report.Runs[j].PlanTime })
+	if len(report.Runs) > maxExperimentRuns {
+		report.Runs = report.Runs[:maxExperimentRuns]
+	}
+
+	return report
+}
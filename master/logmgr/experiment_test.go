@@ -0,0 +1,33 @@
+package logmgr
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+)
+
+func TestBuildExperimentReport(t *testing.T) {
+	logs := []*common.JobLog{
+		{PlanTime: 100, StartTime: 100, EndTime: 110, ExitCode: 0, Status: common.RunStatusSuccess},
+		{PlanTime: 100, StartTime: 100, EndTime: 104, ExitCode: 0, Status: common.RunStatusSuccess, Experiment: true},
+		{PlanTime: 200, StartTime: 200, EndTime: 210, ExitCode: 0, Status: common.RunStatusSuccess},
+		{PlanTime: 200, StartTime: 200, EndTime: 206, ExitCode: 1, Status: common.RunStatusFailed, Experiment: true},
+		{PlanTime: 300, StartTime: 300, EndTime: 310, ExitCode: 0, Status: common.RunStatusSuccess},
+		{PlanTime: 400, StartTime: 400, EndTime: 400, Status: common.RunStatusSkipped},
+	}
+
+	report := buildExperimentReport(logs)
+	assert.Equal(t, 2, report.Compared)
+	assert.Equal(t, 1, report.Matched)
+	assert.Equal(t, 1, report.Unpaired, "Run without experiment should be unpaired, skipped runs ignored")
+	assert.Equal(t, 10.0, report.AvgDuration)
+	assert.Equal(t, 5.0, report.ExperimentAvgDuration)
+
+	require.Len(t, report.Runs, 2)
+	assert.Equal(t, int64(200), report.Runs[0].PlanTime, "Newest comparison should come first")
+	assert.False(t, report.Runs[0].Match)
+	assert.Equal(t, 1, report.Runs[0].ExperimentExitCode)
+}
@@ -0,0 +1,118 @@
+package logmgr
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+)
+
+// followLookback 跟踪日志时每次回看的时间窗口，覆盖worker批量写入日志的延迟和节点间的时钟偏差，
+// 结束时间早于窗口才写入的日志不会再被推送
+const followLookback = time.Minute
+
+// followBatch 每次轮询每个任务名最多返回的新日志条数，积压的日志在之后的轮询中分批返回
+const followBatch = 200
+
+// LogFollower 按结束时间轮询任务新写入的执行日志。日志在执行结束后才写入，
+// 因此以结束时间而不是开始时间作为游标，长时间运行的执行结束后也能被推送。
+// 这里用轮询而不是MongoDB的change stream：日志存储可以是SQLite或Postgres，
+// change stream也要求MongoDB以副本集部署，轮询对所有后端都可用
+type LogFollower struct {
+	lm      *LogManager
+	names   []string
+	scope   *common.Scope
+	cursors map[string]*followCursor // 任务名 -> 查询游标，曾用名各自分页
+}
+
+// followCursor 单个任务名的查询游标
+type followCursor struct {
+	from int64            // 下次查询的结束时间下界
+	seen map[string]int64 // 结束时间不早于from的已返回日志 -> 结束时间，用于去重
+}
+
+// FollowLogs 跟踪任务在since之后结束的执行日志，aliases为任务的曾用名
+func (lm *LogManager) FollowLogs(jobName string, scope *common.Scope, since time.Time, aliases ...string) *LogFollower {
+	names := jobNames(jobName, aliases)
+	cursors := make(map[string]*followCursor, len(names))
+	for _, name := range names {
+		cursors[name] = &followCursor{from: since.Unix(), seen: make(map[string]int64)}
+	}
+
+	return &LogFollower{
+		lm:      lm,
+		names:   names,
+		scope:   scope,
+		cursors: cursors,
+	}
+}
+
+// Next 返回上次调用之后新写入的日志，按结束时间升序，每个任务名最多返回followBatch条
+func (f *LogFollower) Next() ([]*common.JobLog, error) {
+	var logs []*common.JobLog
+	for _, name := range f.names {
+		found, err := f.next(name)
+		if err != nil {
+			f.lm.logger.Error("failed to follow job logs",
+				zap.String("jobName", name),
+				zap.Int64("from", f.cursors[name].from),
+				zap.Error(err))
+			return nil, err
+		}
+		logs = append(logs, found...)
+	}
+
+	sort.SliceStable(logs, func(i, j int) bool {
+		return logs[i].EndTime < logs[j].EndTime
+	})
+	normalizeStatus(logs)
+
+	return logs, nil
+}
+
+// next 查询一个任务名的下一页日志。结束时间不早于游标的已返回日志会被再次查出，
+// 查询条数加上这部分日志，保证每页最多有followBatch条新日志
+func (f *LogFollower) next(name string) ([]*common.JobLog, error) {
+	cursor := f.cursors[name]
+	limit := int64(followBatch + len(cursor.seen))
+
+	found, err := f.lm.logStore.FindJobLogsEndedSince(name, f.scope, cursor.from, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	logs := make([]*common.JobLog, 0, len(found))
+	for _, log := range found {
+		key := followKey(log)
+		if _, ok := cursor.seen[key]; ok {
+			continue
+		}
+		cursor.seen[key] = log.EndTime
+		logs = append(logs, log)
+	}
+
+	if int64(len(found)) == limit {
+		// 还有积压，游标推进到本页最后的结束时间，下次从这里继续
+		cursor.from = found[len(found)-1].EndTime
+	} else {
+		// 已追上最新日志，游标只回看固定窗口
+		cursor.from = max(cursor.from, time.Now().Add(-followLookback).Unix())
+	}
+
+	// 游标之前的日志不会再被查出，去重记录不再需要
+	for key, endTime := range cursor.seen {
+		if endTime < cursor.from {
+			delete(cursor.seen, key)
+		}
+	}
+
+	return logs, nil
+}
+
+// followKey 日志的去重键，同一执行重试时会写入多条日志，旧日志没有runId
+func followKey(log *common.JobLog) string {
+	return fmt.Sprintf("%s/%s/%s/%d/%d", log.JobName, log.RunID, log.WorkerIP, log.StartTime, log.EndTime)
+}
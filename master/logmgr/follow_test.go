@@ -0,0 +1,80 @@
+package logmgr
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+	"github.com/fyerfyer/scheduler-refactor/pkg/sqlstore"
+)
+
+func TestFollowLogs(t *testing.T) {
+	store, err := sqlstore.NewClient("sqlite", "file:"+filepath.Join(t.TempDir(), "logs.db"))
+	require.NoError(t, err)
+	defer store.Close()
+
+	now := time.Now().Unix()
+	require.NoError(t, store.InsertLogs([]*common.JobLog{
+		{JobName: "backup", RunID: "run-1", StartTime: now - 30, EndTime: now - 20},
+		{JobName: "backup", RunID: "run-2", StartTime: now - 8, EndTime: now - 2},
+		{JobName: "db-backup", RunID: "run-3", StartTime: now - 6, EndTime: now - 4},
+		{JobName: "other", RunID: "run-4", StartTime: now - 4, EndTime: now - 3},
+	}))
+	logMgr := NewLogManager(store, zap.NewNop())
+
+	follower := logMgr.FollowLogs("backup", nil, time.Unix(now-10, 0), "db-backup")
+	logs, err := follower.Next()
+	require.NoError(t, err)
+	require.Len(t, logs, 2, "Logs that ended before since should not be followed")
+	assert.Equal(t, "run-3", logs[0].RunID, "Logs should be ordered by end time")
+	assert.Equal(t, "run-2", logs[1].RunID)
+	assert.Equal(t, common.RunStatusSuccess, logs[0].Status)
+
+	logs, err = follower.Next()
+	require.NoError(t, err)
+	assert.Empty(t, logs, "Logs already sent should not be sent again")
+
+	// 长时间运行的执行结束后才写入日志，开始时间早于since也要推送
+	require.NoError(t, store.InsertLogs([]*common.JobLog{
+		{JobName: "backup", RunID: "run-5", StartTime: now - 600, EndTime: now - 1, ExitCode: 1},
+		{JobName: "backup", RunID: "run-5", StartTime: now - 1, EndTime: now},
+	}))
+	logs, err = follower.Next()
+	require.NoError(t, err)
+	require.Len(t, logs, 2, "Each attempt of a retried run should be sent")
+	assert.Equal(t, common.RunStatusFailed, logs[0].Status)
+	assert.Equal(t, now, logs[1].EndTime)
+}
+
+func TestFollowLogsPaging(t *testing.T) {
+	store, err := sqlstore.NewClient("sqlite", "file:"+filepath.Join(t.TempDir(), "logs.db"))
+	require.NoError(t, err)
+	defer store.Close()
+
+	// 同一秒结束的日志超过一页时也要分页推送完
+	now := time.Now().Unix()
+	backlog := make([]*common.JobLog, 0, followBatch+5)
+	for i := 0; i < followBatch+5; i++ {
+		backlog = append(backlog, &common.JobLog{JobName: "backup", RunID: fmt.Sprintf("run-%d", i), StartTime: now - 10, EndTime: now - 5})
+	}
+	require.NoError(t, store.InsertLogs(backlog))
+	follower := NewLogManager(store, zap.NewNop()).FollowLogs("backup", nil, time.Unix(now-10, 0))
+
+	logs, err := follower.Next()
+	require.NoError(t, err)
+	assert.Len(t, logs, followBatch, "Each poll should return at most one batch")
+
+	logs, err = follower.Next()
+	require.NoError(t, err)
+	assert.Len(t, logs, 5, "The rest of the backlog should be returned by the next poll")
+
+	logs, err = follower.Next()
+	require.NoError(t, err)
+	assert.Empty(t, logs)
+}
@@ -0,0 +1,32 @@
+package logmgr
+
+import (
+	"sort"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+	"github.com/fyerfyer/scheduler-refactor/pkg/metrics"
+)
+
+// latencyStats 汇总一组延迟(毫秒)，按metrics.LatencyBuckets分桶，便于与worker上报的直方图对照
+func latencyStats(values []int64) *common.LatencyStats {
+	stats := &common.LatencyStats{
+		Count:   len(values),
+		Buckets: make([]int64, len(metrics.LatencyBuckets)+1),
+	}
+	if len(values) == 0 {
+		return stats
+	}
+
+	sort.Slice(values, func(i, j int) bool { return values[i] < values[j] })
+
+	total := int64(0)
+	for _, v := range values {
+		total += v
+		stats.Buckets[sort.SearchFloat64s(metrics.LatencyBuckets, float64(v))]++
+	}
+	stats.AvgMs = float64(total) / float64(len(values))
+	stats.P50Ms = percentile(values, 50)
+	stats.P95Ms = percentile(values, 95)
+	stats.MaxMs = values[len(values)-1]
+	return stats
+}
@@ -0,0 +1,39 @@
+package logmgr
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+	"github.com/fyerfyer/scheduler-refactor/pkg/metrics"
+)
+
+func TestLatencyStats(t *testing.T) {
+	stats := latencyStats([]int64{3000, 0, 20, 4, 40})
+	assert.Equal(t, 5, stats.Count)
+	assert.InDelta(t, 612.8, stats.AvgMs, 0.001)
+	assert.Equal(t, int64(20), stats.P50Ms)
+	assert.Equal(t, int64(3000), stats.MaxMs)
+
+	// 0和4ms落在前两个桶，20ms落在25ms桶，3000ms落在5000ms桶
+	require.Len(t, stats.Buckets, len(metrics.LatencyBuckets)+1)
+	assert.Equal(t, []int64{1, 1, 0, 1, 1, 0, 0, 0, 0, 0, 1, 0}, stats.Buckets)
+
+	empty := latencyStats(nil)
+	assert.Zero(t, empty.Count)
+	assert.Len(t, empty.Buckets, len(metrics.LatencyBuckets)+1)
+}
+
+func TestJobLogLatencies(t *testing.T) {
+	log := &common.JobLog{PlanTime: 100, ScheduleTime: 102, StartTime: 103, EndTime: 110}
+	scheduleDelay, startDelay := log.Latencies()
+	assert.Equal(t, int64(2000), scheduleDelay, "Old logs should fall back to second precision")
+	assert.Equal(t, int64(1000), startDelay)
+
+	log.ScheduleDelayMs, log.StartDelayMs = 1850, 12
+	scheduleDelay, startDelay = log.Latencies()
+	assert.Equal(t, int64(1850), scheduleDelay)
+	assert.Equal(t, int64(12), startDelay)
+}
@@ -4,34 +4,56 @@ import (
 	"context"
 	"time"
 
+	"github.com/robfig/cron/v3"
 	"go.uber.org/zap"
 
 	"github.com/fyerfyer/scheduler-refactor/common"
-	"github.com/fyerfyer/scheduler-refactor/pkg/mongodb"
+	"github.com/fyerfyer/scheduler-refactor/config"
+	"github.com/fyerfyer/scheduler-refactor/pkg/logstore"
 )
 
+// LeaderChecker 判断当前master是否为leader，集群级的定时清理和统计汇总只在leader上执行
+type LeaderChecker interface {
+	IsLeader() bool
+}
+
+// CleanupResult 日志清理结果
+type CleanupResult struct {
+	RetentionDays int   `json:"retentionDays"` // 保留天数
+	Before        int64 `json:"before"`        // 清理结束时间早于该时间戳的日志
+	Count         int64 `json:"count"`         // 删除（预览时为将要删除）的日志数量
+	DryRun        bool  `json:"dryRun"`        // 是否只预览不删除
+}
+
 // LogManager 日志管理器，负责任务日志的查询和管理
 type LogManager struct {
-	mongoClient *mongodb.Client // MongoDB客户端
-	logger      *zap.Logger     // 日志对象
-	ctx         context.Context // 上下文，用于控制退出
-	cancelFunc  context.CancelFunc
+	logStore   logstore.LogStore // 日志存储
+	logger     *zap.Logger       // 日志对象
+	leader     LeaderChecker     // leader判断，为nil时视为leader
+	ctx        context.Context   // 上下文，用于控制退出
+	cancelFunc context.CancelFunc
 }
 
 // NewLogManager 创建日志管理器
-func NewLogManager(mongoClient *mongodb.Client, logger *zap.Logger) *LogManager {
+func NewLogManager(logStore logstore.LogStore, logger *zap.Logger) *LogManager {
 	ctx, cancel := context.WithCancel(context.Background())
 
 	return &LogManager{
-		mongoClient: mongoClient,
-		logger:      logger,
-		ctx:         ctx,
-		cancelFunc:  cancel,
+		logStore:   logStore,
+		logger:     logger,
+		ctx:        ctx,
+		cancelFunc: cancel,
 	}
 }
 
-// ListLogs 获取任务日志列表
-func (lm *LogManager) ListLogs(jobName string, page, pageSize int) ([]*common.JobLog, int64, error) {
+// SetLeader 设置leader判断，设置后只有leader执行定时清理和统计汇总
+func (lm *LogManager) SetLeader(leader LeaderChecker) {
+	lm.leader = leader
+}
+
+// ListLogs 获取任务日志列表，scope限制调用方可读取的日志范围，nil表示不限制。
+// aliases为任务的曾用名，传入时一并返回改名前的日志
+func (lm *LogManager) ListLogs(jobName string, scope *common.Scope, page, pageSize int, aliases ...string) ([]*common.JobLog, int64, error) {
 	// 参数校验
 	if page <= 0 {
 		page = common.DefaultPage
@@ -48,7 +70,8 @@ func (lm *LogManager) ListLogs(jobName string, page, pageSize int) ([]*common.Jo
 	limit := int64(pageSize)
 
 	// 查询日志
-	logs, err := lm.mongoClient.FindJobLogs(jobName, skip, limit)
+	names := jobNames(jobName, aliases)
+	logs, err := lm.findJobLogs(names, scope, skip, limit)
 	if err != nil {
 		lm.logger.Error("failed to fetch job logs",
 			zap.String("jobName", jobName),
@@ -57,9 +80,10 @@ func (lm *LogManager) ListLogs(jobName string, page, pageSize int) ([]*common.Jo
 			zap.Error(err))
 		return nil, 0, err
 	}
+	normalizeStatus(logs)
 
 	// 获取总数
-	total, err := lm.mongoClient.CountJobLogs(jobName)
+	total, err := lm.countJobLogs(names, scope)
 	if err != nil {
 		lm.logger.Error("failed to count job logs",
 			zap.String("jobName", jobName),
@@ -70,10 +94,10 @@ func (lm *LogManager) ListLogs(jobName string, page, pageSize int) ([]*common.Jo
 	return logs, total, nil
 }
 
-// GetJobLog 获取指定任务的最近一条日志
-func (lm *LogManager) GetJobLog(jobName string) (*common.JobLog, error) {
+// GetJobLog 获取指定任务的最近一条日志，aliases为任务的曾用名
+func (lm *LogManager) GetJobLog(jobName string, scope *common.Scope, aliases ...string) (*common.JobLog, error) {
 	// 查询最近一条日志
-	logs, err := lm.mongoClient.FindJobLogs(jobName, 0, 1)
+	logs, err := lm.findJobLogs(jobNames(jobName, aliases), scope, 0, 1)
 	if err != nil {
 		lm.logger.Error("failed to fetch latest job log",
 			zap.String("jobName", jobName),
@@ -85,12 +109,47 @@ func (lm *LogManager) GetJobLog(jobName string) (*common.JobLog, error) {
 	if len(logs) == 0 {
 		return nil, common.ErrJobNotFound
 	}
+	normalizeStatus(logs)
 
 	return logs[0], nil
 }
 
+// GetRun 获取指定执行的日志，执行不存在或不在调用方可读取的范围内时返回ErrRunNotFound
+func (lm *LogManager) GetRun(runID string, scope *common.Scope) (*common.JobLog, error) {
+	log, err := lm.logStore.FindLogByRunID(runID, scope)
+	if err != nil {
+		lm.logger.Error("failed to fetch run log",
+			zap.String("runId", runID),
+			zap.Error(err))
+		return nil, err
+	}
+	if log == nil {
+		return nil, common.ErrRunNotFound
+	}
+	normalizeStatus([]*common.JobLog{log})
+
+	return log, nil
+}
+
+// InsertLogs 写入日志，用于补记worker没有写入的执行
+func (lm *LogManager) InsertLogs(logs []*common.JobLog) error {
+	if err := lm.logStore.InsertLogs(logs); err != nil {
+		lm.logger.Error("failed to insert logs",
+			zap.Int("count", len(logs)),
+			zap.Error(err))
+		return err
+	}
+	return nil
+}
+
 // CleanExpiredLogs 清理过期日志
 func (lm *LogManager) CleanExpiredLogs(retentionDays int) error {
+	_, err := lm.CleanupLogs(retentionDays, false)
+	return err
+}
+
+// CleanupLogs 清理过期日志，dryRun为true时只统计将要删除的数量
+func (lm *LogManager) CleanupLogs(retentionDays int, dryRun bool) (*CleanupResult, error) {
 	// 默认保留30天的日志
 	if retentionDays <= 0 {
 		retentionDays = 30
@@ -98,27 +157,47 @@ func (lm *LogManager) CleanExpiredLogs(retentionDays int) error {
 
 	// 计算截止时间
 	cutoffTime := time.Now().AddDate(0, 0, -retentionDays)
+	result := &CleanupResult{
+		RetentionDays: retentionDays,
+		Before:        cutoffTime.Unix(),
+		DryRun:        dryRun,
+	}
+
+	// 预览只统计数量
+	if dryRun {
+		count, err := lm.logStore.CountOldLogs(cutoffTime)
+		if err != nil {
+			lm.logger.Error("failed to count expired logs",
+				zap.Time("before", cutoffTime),
+				zap.Int("retentionDays", retentionDays),
+				zap.Error(err))
+			return nil, err
+		}
+		result.Count = count
+		return result, nil
+	}
 
 	// 执行清理
-	deletedCount, err := lm.mongoClient.DeleteOldLogs(cutoffTime)
+	deletedCount, err := lm.logStore.DeleteOldLogs(cutoffTime)
 	if err != nil {
 		lm.logger.Error("failed to clean expired logs",
 			zap.Time("before", cutoffTime),
 			zap.Int("retentionDays", retentionDays),
 			zap.Error(err))
-		return err
+		return nil, err
 	}
+	result.Count = deletedCount
 
 	lm.logger.Info("cleaned expired logs",
 		zap.Time("before", cutoffTime),
 		zap.Int("retentionDays", retentionDays),
 		zap.Int64("deletedCount", deletedCount))
 
-	return nil
+	return result, nil
 }
 
-// GetLogStatistics 获取任务日志统计信息
-func (lm *LogManager) GetLogStatistics(jobName string, days int) (map[string]interface{}, error) {
+// GetLogStatistics 获取任务日志统计信息，aliases为任务的曾用名
+func (lm *LogManager) GetLogStatistics(jobName string, scope *common.Scope, days int, aliases ...string) (map[string]interface{}, error) {
 	// 默认统计最近7天
 	if days <= 0 {
 		days = 7
@@ -128,60 +207,91 @@ func (lm *LogManager) GetLogStatistics(jobName string, days int) (map[string]int
 	startTime := time.Now().AddDate(0, 0, -days).Unix()
 
 	// 获取日志
-	logs, err := lm.getLogsSince(jobName, startTime)
+	logs, err := lm.getLogsSince(jobName, scope, startTime, aliases...)
 	if err != nil {
 		return nil, err
 	}
 
-	// 统计成功、失败数量
+	// 按执行状态统计数量
 	successCount := 0
 	failCount := 0
 	timeoutCount := 0
+	killedCount := 0
+	skippedCount := 0
+	experimentCount := 0
 	totalDuration := int64(0)
+	var scheduleDelays, startDelays []int64
 
 	for _, log := range logs {
-		if log.ExitCode == 0 {
-			successCount++
-		} else {
-			failCount++
+		// 实验命令的执行不是任务本身的执行
+		if log.Experiment {
+			experimentCount++
+			continue
 		}
 
-		if log.IsTimeout {
+		switch log.GetStatus() {
+		case common.RunStatusSkipped:
+			// 跳过记录不是真正的执行，不计入执行次数和时长
+			skippedCount++
+			continue
+		case common.RunStatusSuccess:
+			successCount++
+		case common.RunStatusTimeout:
+			failCount++
 			timeoutCount++
+		case common.RunStatusKilled:
+			failCount++
+			killedCount++
+		default:
+			failCount++
 		}
 
 		// 计算执行时长
 		duration := log.EndTime - log.StartTime
 		totalDuration += duration
+
+		scheduleDelay, startDelay := log.Latencies()
+		scheduleDelays = append(scheduleDelays, scheduleDelay)
+		startDelays = append(startDelays, startDelay)
 	}
 
 	// 计算平均执行时长
+	executedCount := len(logs) - skippedCount - experimentCount
 	var avgDuration float64
-	if len(logs) > 0 {
-		avgDuration = float64(totalDuration) / float64(len(logs))
+	if executedCount > 0 {
+		avgDuration = float64(totalDuration) / float64(executedCount)
 	}
 
 	// 构建统计结果
 	stats := map[string]interface{}{
-		"totalCount":   len(logs),
+		"totalCount":   executedCount,
 		"successCount": successCount,
 		"failCount":    failCount,
 		"timeoutCount": timeoutCount,
+		"killedCount":  killedCount,
+		"skippedCount": skippedCount,
 		"avgDuration":  avgDuration, // 单位：秒
 		"period":       days,
+		// 计划时间到实际调度、实际调度到开始执行的延迟，后者升高通常说明worker已经饱和
+		"scheduleDelay": latencyStats(scheduleDelays),
+		"startDelay":    latencyStats(startDelays),
 	}
 
 	return stats, nil
 }
 
+// GetLogsSince 获取所有任务在指定时间之后开始的日志，用于跨任务的汇总报表
+func (lm *LogManager) GetLogsSince(since time.Time) ([]*common.JobLog, error) {
+	return lm.getLogsSince("", nil, since.Unix())
+}
+
 // getLogsSince 获取指定时间之后的日志
-func (lm *LogManager) getLogsSince(jobName string, timestamp int64) ([]*common.JobLog, error) {
+func (lm *LogManager) getLogsSince(jobName string, scope *common.Scope, timestamp int64, aliases ...string) ([]*common.JobLog, error) {
 	_, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	// 从MongoDB中获取日志数据
-	// 注意：这里需要扩展mongodb.Client以支持这种查询
-	logs, err := lm.mongoClient.FindJobLogsSince(jobName, timestamp)
+	// 从日志存储中获取日志数据
+	logs, err := lm.findJobLogsSince(jobNames(jobName, aliases), scope, timestamp)
 	if err != nil {
 		lm.logger.Error("failed to get logs since timestamp",
 			zap.String("jobName", jobName),
@@ -193,25 +303,47 @@ func (lm *LogManager) getLogsSince(jobName string, timestamp int64) ([]*common.J
 	return logs, nil
 }
 
+// normalizeStatus 为旧日志补全执行状态，保证API返回的状态字段一致
+func normalizeStatus(logs []*common.JobLog) {
+	for _, log := range logs {
+		log.Status = log.GetStatus()
+	}
+}
+
 // Stop 停止日志管理器
 func (lm *LogManager) Stop() {
 	lm.cancelFunc()
 	lm.logger.Info("log manager stopped")
 }
 
-// StartLogCleaner 启动日志清理器
-func (lm *LogManager) StartLogCleaner(retentionDays int) {
-	go func() {
-		// 定期清理日志，每天运行一次
-		ticker := time.NewTicker(24 * time.Hour)
-		defer ticker.Stop()
+// StartLogCleaner 按配置的cron表达式启动日志清理器，只有leader执行清理
+func (lm *LogManager) StartLogCleaner(retentionDays int) error {
+	schedule := config.GlobalConfig.LogCleanSchedule
+	if schedule == "" {
+		schedule = common.DefaultLogCleanSchedule
+	}
 
+	parser := cron.NewParser(cron.Second | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow)
+	expr, err := parser.Parse(schedule)
+	if err != nil {
+		return err
+	}
+
+	go func() {
 		for {
+			timer := time.NewTimer(time.Until(expr.Next(time.Now())))
+
 			select {
 			case <-lm.ctx.Done():
 				// 上下文被取消，退出清理
+				timer.Stop()
 				return
-			case <-ticker.C:
+			case <-timer.C:
+				if lm.leader != nil && !lm.leader.IsLeader() {
+					lm.logger.Debug("not master leader, skip log cleaning")
+					continue
+				}
+
 				// 运行日志清理
 				if err := lm.CleanExpiredLogs(retentionDays); err != nil {
 					lm.logger.Error("periodic log cleaning failed", zap.Error(err))
@@ -219,4 +351,10 @@ func (lm *LogManager) StartLogCleaner(retentionDays int) {
 			}
 		}
 	}()
+
+	lm.logger.Info("log cleaner started",
+		zap.String("schedule", schedule),
+		zap.Int("retentionDays", retentionDays))
+
+	return nil
 }
@@ -69,34 +69,34 @@ func TestListLogs(t *testing.T) {
 	insertTestLogs(t, mongoClient, 25, jobName)
 
 	t.Run("DefaultPagination", func(t *testing.T) {
-		logs, total, err := logMgr.ListLogs(jobName, 0, 0)
+		logs, total, err := logMgr.ListLogs(jobName, nil, 0, 0)
 		require.NoError(t, err, "ListLogs should not return error with default pagination")
 		assert.Equal(t, int64(25), total, "Total count should match inserted logs count")
 		assert.Equal(t, common.DefaultPageSize, len(logs), "Should return DefaultPageSize logs")
 	})
 
 	t.Run("CustomPagination", func(t *testing.T) {
-		logs, total, err := logMgr.ListLogs(jobName, 2, 5)
+		logs, total, err := logMgr.ListLogs(jobName, nil, 2, 5)
 		require.NoError(t, err, "ListLogs should not return error with custom pagination")
 		assert.Equal(t, int64(25), total, "Total count should match inserted logs count")
 		assert.Equal(t, 5, len(logs), "Should return specified page size")
 	})
 
 	t.Run("LimitMaxPageSize", func(t *testing.T) {
-		logs, _, err := logMgr.ListLogs(jobName, 1, 200)
+		logs, _, err := logMgr.ListLogs(jobName, nil, 1, 200)
 		require.NoError(t, err, "ListLogs should not return error when exceeding MaxPageSize")
 		assert.Equal(t, common.MaxPageSize, len(logs), "Should limit page size to MaxPageSize")
 	})
 
 	t.Run("EmptyJobName", func(t *testing.T) {
-		logs, total, err := logMgr.ListLogs("", 1, 10)
+		logs, total, err := logMgr.ListLogs("", nil, 1, 10)
 		require.NoError(t, err, "ListLogs should not return error with empty job name")
 		assert.Equal(t, int64(25), total, "Total count should match all logs")
 		assert.Equal(t, 10, len(logs), "Should return logs for all jobs")
 	})
 
 	t.Run("NonExistentJob", func(t *testing.T) {
-		logs, total, err := logMgr.ListLogs("non-existent-job", 1, 10)
+		logs, total, err := logMgr.ListLogs("non-existent-job", nil, 1, 10)
 		require.NoError(t, err, "ListLogs should not return error for non-existent job")
 		assert.Equal(t, int64(0), total, "Total count should be 0 for non-existent job")
 		assert.Equal(t, 0, len(logs), "Should return empty logs array")
@@ -111,14 +111,14 @@ func TestGetJobLog(t *testing.T) {
 	insertTestLogs(t, mongoClient, 5, jobName)
 
 	t.Run("ExistingJob", func(t *testing.T) {
-		log, err := logMgr.GetJobLog(jobName)
+		log, err := logMgr.GetJobLog(jobName, nil)
 		require.NoError(t, err, "GetJobLog should not return error for existing job")
 		assert.Equal(t, jobName, log.JobName, "Job name should match")
 		assert.NotEmpty(t, log.Command, "Command should not be empty")
 	})
 
 	t.Run("NonExistentJob", func(t *testing.T) {
-		_, err := logMgr.GetJobLog("non-existent-job")
+		_, err := logMgr.GetJobLog("non-existent-job", nil)
 		assert.Equal(t, common.ErrJobNotFound, err, "GetJobLog should return ErrJobNotFound")
 	})
 }
@@ -162,18 +162,18 @@ func TestCleanExpiredLogs(t *testing.T) {
 	require.NoError(t, err, "CleanExpiredLogs should not return error")
 
 	// 验证只有最近的日志还存在
-	count, err := mongoClient.CountJobLogs("")
+	count, err := mongoClient.CountJobLogs("", nil)
 	require.NoError(t, err, "CountJobLogs should not return error")
 	assert.Equal(t, int64(1), count, "Only recent logs should remain")
 
 	// 验证存在的是最近的日志
-	logs, err := mongoClient.FindJobLogs("recent-job", 0, 10)
+	logs, err := mongoClient.FindJobLogs("recent-job", nil, 0, 10)
 	require.NoError(t, err, "FindJobLogs should not return error")
 	assert.Equal(t, 1, len(logs), "Should find the recent log")
 	assert.Equal(t, "recent-job", logs[0].JobName, "Recent job should still exist")
 
 	// 验证旧日志已被删除
-	logs, err = mongoClient.FindJobLogs("old-job", 0, 10)
+	logs, err = mongoClient.FindJobLogs("old-job", nil, 0, 10)
 	require.NoError(t, err, "FindJobLogs should not return error")
 	assert.Equal(t, 0, len(logs), "Old logs should be deleted")
 }
@@ -186,7 +186,7 @@ func TestGetLogStatistics(t *testing.T) {
 	insertTestLogs(t, mongoClient, 20, jobName)
 
 	t.Run("DefaultPeriod", func(t *testing.T) {
-		stats, err := logMgr.GetLogStatistics(jobName, 0)
+		stats, err := logMgr.GetLogStatistics(jobName, nil, 0)
 		require.NoError(t, err, "GetLogStatistics should not return error with default period")
 
 		assert.Contains(t, stats, "totalCount", "Stats should contain totalCount")
@@ -200,20 +200,20 @@ func TestGetLogStatistics(t *testing.T) {
 	})
 
 	t.Run("CustomPeriod", func(t *testing.T) {
-		stats, err := logMgr.GetLogStatistics(jobName, 14)
+		stats, err := logMgr.GetLogStatistics(jobName, nil, 14)
 		require.NoError(t, err, "GetLogStatistics should not return error with custom period")
 		assert.Equal(t, 14, stats["period"], "Period should match specified value")
 	})
 
 	t.Run("NonExistentJob", func(t *testing.T) {
-		stats, err := logMgr.GetLogStatistics("non-existent-job", 7)
+		stats, err := logMgr.GetLogStatistics("non-existent-job", nil, 7)
 		require.NoError(t, err, "GetLogStatistics should not return error for non-existent job")
 		assert.Equal(t, 0, stats["totalCount"], "Total count should be 0")
 		assert.Equal(t, 0, stats["successCount"], "Success count should be 0")
 	})
 
 	t.Run("SuccessAndFailureCounts", func(t *testing.T) {
-		stats, err := logMgr.GetLogStatistics(jobName, 7)
+		stats, err := logMgr.GetLogStatistics(jobName, nil, 7)
 		require.NoError(t, err, "GetLogStatistics should not return error")
 
 		// 因为我们在insertTestLogs中设置了偶数索引成功，奇数索引失败
@@ -261,3 +261,22 @@ func TestLogManagerStop(t *testing.T) {
 		t.Fatal("Context was not canceled after Stop")
 	}
 }
+
+func TestGetRun(t *testing.T) {
+	logMgr, mongoClient, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	now := time.Now().Unix()
+	require.NoError(t, mongoClient.InsertLogs([]*common.JobLog{
+		{JobName: "replay-job", RunID: "run-1", StartTime: now - 10, ExitCode: 1, JobRevision: 7},
+		{JobName: "replay-job", RunID: "run-2", StartTime: now},
+	}))
+
+	run, err := logMgr.GetRun("run-1", nil)
+	require.NoError(t, err)
+	assert.Equal(t, int64(7), run.JobRevision)
+	assert.Equal(t, common.RunStatusFailed, run.Status, "Status should be inferred for old logs")
+
+	_, err = logMgr.GetRun("missing", nil)
+	assert.ErrorIs(t, err, common.ErrRunNotFound)
+}
@@ -0,0 +1,235 @@
+package logmgr
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+)
+
+// 长期统计默认和最大的查询天数
+const (
+	defaultHistoryDays = 90
+	maxHistoryDays     = 366
+)
+
+// RollupSince 将指定时间所在日期至今的原始日志按任务和日期汇总，写入统计集合
+func (lm *LogManager) RollupSince(since time.Time) (int, error) {
+	from := startOfDay(since)
+
+	logs, err := lm.getLogsSince("", nil, from.Unix())
+	if err != nil {
+		return 0, err
+	}
+
+	stats := buildDailyStats(logs)
+	if err = lm.logStore.UpsertDailyStats(stats); err != nil {
+		lm.logger.Error("failed to save daily stats",
+			zap.Time("since", from),
+			zap.Error(err))
+		return 0, err
+	}
+
+	lm.logger.Info("rolled up job logs",
+		zap.Time("since", from),
+		zap.Int("logs", len(logs)),
+		zap.Int("stats", len(stats)))
+
+	return len(stats), nil
+}
+
+// StartStatsRollup 启动统计汇总任务，只有leader执行。成为leader后先补齐原始日志保留期内的完整日期，
+// 之后每小时重新汇总昨天和今天。保留期边界当天的原始日志已被部分清理，不再重新汇总，避免覆盖完整的统计
+func (lm *LogManager) StartStatsRollup(retentionDays int) {
+	go func() {
+		backfilled := false
+		rollup := func() {
+			if lm.leader != nil && !lm.leader.IsLeader() {
+				lm.logger.Debug("not master leader, skip stats rollup")
+				return
+			}
+
+			now := time.Now()
+			since := earliestCompleteDay(now, retentionDays)
+			if backfilled {
+				// 重新汇总昨天，保证跨零点结束前写入的日志被计入
+				if yesterday := startOfDay(now).AddDate(0, 0, -1); yesterday.After(since) {
+					since = yesterday
+				}
+			}
+
+			if _, err := lm.RollupSince(since); err != nil {
+				lm.logger.Error("stats rollup failed", zap.Bool("backfill", !backfilled), zap.Error(err))
+				return
+			}
+			backfilled = true
+		}
+
+		rollup()
+
+		ticker := time.NewTicker(time.Hour)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-lm.ctx.Done():
+				return
+			case <-ticker.C:
+				rollup()
+			}
+		}
+	}()
+}
+
+// earliestCompleteDay 返回原始日志仍完整保留的最早日期零点，即清理截止时间所在日期的下一天
+func earliestCompleteDay(now time.Time, retentionDays int) time.Time {
+	// 与日志清理的默认保留天数一致
+	if retentionDays <= 0 {
+		retentionDays = 30
+	}
+	return startOfDay(now.AddDate(0, 0, -retentionDays)).AddDate(0, 0, 1)
+}
+
+// GetJobHistory 获取任务最近days天的按天统计及汇总，不受原始日志保留时间限制，aliases为任务的曾用名
+func (lm *LogManager) GetJobHistory(jobName string, scope *common.Scope, days int, aliases ...string) (map[string]interface{}, error) {
+	if days <= 0 {
+		days = defaultHistoryDays
+	}
+	if days > maxHistoryDays {
+		days = maxHistoryDays
+	}
+
+	to := startOfDay(time.Now()).AddDate(0, 0, 1)
+	from := to.AddDate(0, 0, -days)
+
+	stats, err := lm.findDailyStats(jobNames(jobName, aliases), scope, from.Unix(), to.Unix())
+	if err != nil {
+		lm.logger.Error("failed to fetch daily stats",
+			zap.String("jobName", jobName),
+			zap.Int("days", days),
+			zap.Error(err))
+		return nil, err
+	}
+
+	// 分位数无法跨天合并，汇总只给出次数、平均和最长时长
+	total := &common.JobDailyStats{JobName: jobName}
+	for _, s := range stats {
+		total.TotalCount += s.TotalCount
+		total.SuccessCount += s.SuccessCount
+		total.FailCount += s.FailCount
+		total.TimeoutCount += s.TimeoutCount
+		total.KilledCount += s.KilledCount
+		total.SkippedCount += s.SkippedCount
+		total.TotalDuration += s.TotalDuration
+		if s.MaxDuration > total.MaxDuration {
+			total.MaxDuration = s.MaxDuration
+		}
+	}
+
+	var avgDuration float64
+	if total.TotalCount > 0 {
+		avgDuration = float64(total.TotalDuration) / float64(total.TotalCount)
+	}
+
+	return map[string]interface{}{
+		"totalCount":   total.TotalCount,
+		"successCount": total.SuccessCount,
+		"failCount":    total.FailCount,
+		"timeoutCount": total.TimeoutCount,
+		"killedCount":  total.KilledCount,
+		"skippedCount": total.SkippedCount,
+		"avgDuration":  avgDuration, // 单位：秒
+		"maxDuration":  total.MaxDuration,
+		"period":       days,
+		"days":         stats,
+	}, nil
+}
+
+// buildDailyStats 将日志按任务和开始日期汇总
+func buildDailyStats(logs []*common.JobLog) []*common.JobDailyStats {
+	type key struct {
+		jobName string
+		day     int64
+	}
+
+	groups := make(map[key]*common.JobDailyStats)
+	durations := make(map[key][]int64)
+	keys := make([]key, 0)
+
+	for _, log := range logs {
+		// 实验命令的执行不计入任务统计
+		if log.Experiment {
+			continue
+		}
+
+		k := key{jobName: log.JobName, day: startOfDay(time.Unix(log.StartTime, 0)).Unix()}
+
+		s, ok := groups[k]
+		if !ok {
+			s = &common.JobDailyStats{
+				JobName:   log.JobName,
+				Day:       k.day,
+				Namespace: common.NamespaceOf(log.Namespace),
+				Owner:     log.Owner,
+			}
+			groups[k] = s
+			keys = append(keys, k)
+		}
+
+		switch log.GetStatus() {
+		case common.RunStatusSkipped:
+			// 跳过记录不是真正的执行，不计入执行次数和时长
+			s.SkippedCount++
+			continue
+		case common.RunStatusSuccess:
+			s.SuccessCount++
+		case common.RunStatusTimeout:
+			s.FailCount++
+			s.TimeoutCount++
+		case common.RunStatusKilled:
+			s.FailCount++
+			s.KilledCount++
+		default:
+			s.FailCount++
+		}
+
+		duration := log.EndTime - log.StartTime
+		s.TotalCount++
+		s.TotalDuration += duration
+		durations[k] = append(durations[k], duration)
+	}
+
+	stats := make([]*common.JobDailyStats, 0, len(keys))
+	for _, k := range keys {
+		s := groups[k]
+		values := durations[k]
+		if len(values) > 0 {
+			sort.Slice(values, func(i, j int) bool { return values[i] < values[j] })
+			s.AvgDuration = float64(s.TotalDuration) / float64(s.TotalCount)
+			s.P50Duration = percentile(values, 50)
+			s.P95Duration = percentile(values, 95)
+			s.P99Duration = percentile(values, 99)
+			s.MaxDuration = values[len(values)-1]
+		}
+		stats = append(stats, s)
+	}
+
+	return stats
+}
+
+// percentile 按最近秩法计算已排序数据的分位数
+func percentile(sorted []int64, p float64) int64 {
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// startOfDay 返回本地时间当天零点
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.Local)
+}
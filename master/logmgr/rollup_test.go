@@ -0,0 +1,57 @@
+package logmgr
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+)
+
+func TestBuildDailyStats(t *testing.T) {
+	day := time.Date(2024, 5, 1, 0, 0, 0, 0, time.Local)
+	at := func(d, hour int) int64 { return day.AddDate(0, 0, d).Add(time.Duration(hour) * time.Hour).Unix() }
+
+	logs := make([]*common.JobLog, 0)
+	// 第一天执行10次，时长1~10秒，最后一次失败
+	for i := 1; i <= 10; i++ {
+		log := &common.JobLog{JobName: "etl", Namespace: "data", StartTime: at(0, 1), EndTime: at(0, 1) + int64(i), Status: common.RunStatusSuccess}
+		if i == 10 {
+			log.Status = common.RunStatusTimeout
+		}
+		logs = append(logs, log)
+	}
+	logs = append(logs,
+		&common.JobLog{JobName: "etl", StartTime: at(0, 2), EndTime: at(0, 2), Status: common.RunStatusSkipped},
+		&common.JobLog{JobName: "etl", StartTime: at(1, 3), EndTime: at(1, 3) + 7, Status: common.RunStatusSuccess},
+	)
+
+	stats := buildDailyStats(logs)
+	require.Len(t, stats, 2)
+
+	first := stats[0]
+	assert.Equal(t, day.Unix(), first.Day)
+	assert.Equal(t, "data", first.Namespace)
+	assert.Equal(t, 10, first.TotalCount)
+	assert.Equal(t, 9, first.SuccessCount)
+	assert.Equal(t, 1, first.TimeoutCount)
+	assert.Equal(t, 1, first.SkippedCount, "Skipped runs should not count as executions")
+	assert.InDelta(t, 5.5, first.AvgDuration, 1e-9)
+	assert.Equal(t, int64(5), first.P50Duration)
+	assert.Equal(t, int64(10), first.P95Duration)
+	assert.Equal(t, int64(10), first.MaxDuration)
+
+	assert.Equal(t, day.AddDate(0, 0, 1).Unix(), stats[1].Day)
+	assert.Equal(t, int64(7), stats[1].P99Duration)
+}
+
+func TestEarliestCompleteDay(t *testing.T) {
+	now := time.Date(2024, 5, 31, 15, 30, 0, 0, time.Local)
+
+	// 清理截止时间为5月1日15:30，5月1日的原始日志已不完整，从5月2日开始汇总
+	assert.Equal(t, time.Date(2024, 5, 2, 0, 0, 0, 0, time.Local), earliestCompleteDay(now, 30))
+	assert.Equal(t, earliestCompleteDay(now, 30), earliestCompleteDay(now, 0), "Unset retention should match the cleaner default")
+	assert.Equal(t, time.Date(2024, 5, 31, 0, 0, 0, 0, time.Local), earliestCompleteDay(now, 1))
+}
@@ -0,0 +1,53 @@
+package logmgr
+
+import (
+	"github.com/fyerfyer/scheduler-refactor/common"
+)
+
+// JobRun 一次执行的摘要，不含输出，按runId关联任务自己上报的日志
+type JobRun struct {
+	RunID      string           `json:"runId"`                // 执行的唯一标识，旧日志没有记录时为空
+	JobName    string           `json:"jobName"`              // 执行时的任务名，可能是曾用名
+	Worker     string           `json:"worker"`               // 执行的worker
+	Status     common.RunStatus `json:"status"`               // 执行状态
+	SkipReason string           `json:"skipReason,omitempty"` // 执行被跳过的原因
+	ExitCode   int              `json:"exitCode"`             // 退出码
+	PlanTime   int64            `json:"planTime"`             // 计划执行时间
+	StartTime  int64            `json:"startTime"`            // 开始时间
+	EndTime    int64            `json:"endTime"`              // 结束时间
+	Duration   int64            `json:"duration"`             // 执行时长(秒)
+	Canary     bool             `json:"canary,omitempty"`     // 是否为灰度新定义的执行
+	Experiment bool             `json:"experiment,omitempty"` // 是否为实验命令的执行
+}
+
+// ListRuns 按开始时间倒序分页获取任务的执行记录，aliases为任务的曾用名
+func (lm *LogManager) ListRuns(jobName string, scope *common.Scope, page, pageSize int, aliases ...string) ([]*JobRun, int64, error) {
+	logs, total, err := lm.ListLogs(jobName, scope, page, pageSize, aliases...)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	runs := make([]*JobRun, 0, len(logs))
+	for _, log := range logs {
+		runs = append(runs, toRun(log))
+	}
+	return runs, total, nil
+}
+
+// toRun 从日志中提取执行摘要
+func toRun(log *common.JobLog) *JobRun {
+	return &JobRun{
+		RunID:      log.RunID,
+		JobName:    log.JobName,
+		Worker:     log.WorkerIP,
+		Status:     log.GetStatus(),
+		SkipReason: log.SkipReason,
+		ExitCode:   log.ExitCode,
+		PlanTime:   log.PlanTime,
+		StartTime:  log.StartTime,
+		EndTime:    log.EndTime,
+		Duration:   max(log.EndTime-log.StartTime, 0),
+		Canary:     log.Canary,
+		Experiment: log.Experiment,
+	}
+}
@@ -0,0 +1,30 @@
+package logmgr
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+)
+
+func TestToRun(t *testing.T) {
+	run := toRun(&common.JobLog{
+		JobName:   "backup",
+		RunID:     "0123456789abcdef",
+		WorkerIP:  "worker-1",
+		PlanTime:  100,
+		StartTime: 101,
+		EndTime:   111,
+		ExitCode:  2,
+		Output:    "lots of output",
+	})
+
+	assert.Equal(t, "0123456789abcdef", run.RunID)
+	assert.Equal(t, "worker-1", run.Worker)
+	assert.Equal(t, common.RunStatusFailed, run.Status, "Legacy logs should infer the status from the exit code")
+	assert.Equal(t, int64(10), run.Duration)
+
+	run = toRun(&common.JobLog{JobName: "backup", StartTime: 100, EndTime: 0, Status: common.RunStatusSkipped})
+	assert.Zero(t, run.Duration, "Duration should never be negative")
+}
@@ -0,0 +1,66 @@
+package logmgr
+
+import (
+	"time"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+)
+
+// JobRunSummary 任务最近一段时间的执行概况
+type JobRunSummary struct {
+	LastRunTime int64            `json:"lastRunTime"` // 最近一次执行的开始时间
+	LastStatus  common.RunStatus `json:"lastStatus"`  // 最近一次执行的状态
+	Runs        int              `json:"runs"`        // 执行次数，不含跳过
+	Failures    int              `json:"failures"`    // 失败次数，含超时和终止
+	FailureRate float64          `json:"failureRate"` // 失败率
+	AvgDuration float64          `json:"avgDuration"` // 平均执行时长(秒)
+}
+
+// GetRunSummaries 获取所有任务最近days天的执行概况，没有执行记录的任务不在结果中
+func (lm *LogManager) GetRunSummaries(scope *common.Scope, days int) (map[string]*JobRunSummary, error) {
+	if days <= 0 {
+		days = 7
+	}
+
+	logs, err := lm.getLogsSince("", scope, time.Now().AddDate(0, 0, -days).Unix())
+	if err != nil {
+		return nil, err
+	}
+
+	return buildRunSummaries(logs), nil
+}
+
+// buildRunSummaries 按任务汇总执行概况，跳过记录和实验命令的执行不计入
+func buildRunSummaries(logs []*common.JobLog) map[string]*JobRunSummary {
+	summaries := make(map[string]*JobRunSummary)
+	for _, log := range logs {
+		status := log.GetStatus()
+		if status == common.RunStatusSkipped || log.Experiment {
+			continue
+		}
+
+		summary, ok := summaries[log.JobName]
+		if !ok {
+			summary = &JobRunSummary{}
+			summaries[log.JobName] = summary
+		}
+
+		summary.Runs++
+		if status != common.RunStatusSuccess {
+			summary.Failures++
+		}
+		if log.StartTime > summary.LastRunTime {
+			summary.LastRunTime = log.StartTime
+			summary.LastStatus = status
+		}
+		// 先累计总时长，最后求平均
+		summary.AvgDuration += float64(log.EndTime - log.StartTime)
+	}
+
+	for _, summary := range summaries {
+		summary.FailureRate = float64(summary.Failures) / float64(summary.Runs)
+		summary.AvgDuration /= float64(summary.Runs)
+	}
+
+	return summaries
+}
@@ -0,0 +1,29 @@
+package logmgr
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+)
+
+func TestBuildRunSummaries(t *testing.T) {
+	logs := []*common.JobLog{
+		{JobName: "a", StartTime: 100, EndTime: 110, Status: common.RunStatusSuccess},
+		{JobName: "a", StartTime: 300, EndTime: 330, Status: common.RunStatusFailed},
+		{JobName: "a", StartTime: 400, EndTime: 400, Status: common.RunStatusSkipped},
+		{JobName: "a", StartTime: 500, Status: common.RunStatusFailed, Experiment: true},
+		{JobName: "b", StartTime: 200, Status: common.RunStatusTimeout},
+	}
+
+	summaries := buildRunSummaries(logs)
+	require.Len(t, summaries, 2)
+	assert.Equal(t, int64(300), summaries["a"].LastRunTime, "Skipped and experiment runs should not count")
+	assert.Equal(t, common.RunStatusFailed, summaries["a"].LastStatus)
+	assert.Equal(t, 2, summaries["a"].Runs)
+	assert.Equal(t, 0.5, summaries["a"].FailureRate)
+	assert.Equal(t, 20.0, summaries["a"].AvgDuration)
+	assert.Equal(t, 1.0, summaries["b"].FailureRate, "Timeouts should count as failures")
+}
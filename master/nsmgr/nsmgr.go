@@ -0,0 +1,119 @@
+package nsmgr
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+	"github.com/fyerfyer/scheduler-refactor/pkg/etcd"
+)
+
+// NamespaceManager 命名空间设置管理器，设置作为命名空间中任务的默认值
+type NamespaceManager struct {
+	etcdClient *etcd.Client // etcd客户端
+	logger     *zap.Logger  // 日志对象
+}
+
+// NewNamespaceManager 创建命名空间设置管理器
+func NewNamespaceManager(etcdClient *etcd.Client, logger *zap.Logger) *NamespaceManager {
+	return &NamespaceManager{
+		etcdClient: etcdClient,
+		logger:     logger,
+	}
+}
+
+// SaveSettings 保存命名空间设置
+func (nm *NamespaceManager) SaveSettings(settings *common.NamespaceSettings) error {
+	if err := validateSettings(settings); err != nil {
+		return err
+	}
+	settings.UpdatedAt = time.Now().Unix()
+
+	data, err := json.Marshal(settings)
+	if err != nil {
+		return fmt.Errorf("failed to marshal namespace settings: %v", err)
+	}
+
+	if _, err = nm.etcdClient.Put(common.NamespaceDir+settings.Name, string(data)); err != nil {
+		nm.logger.Error("failed to save namespace settings",
+			zap.String("namespace", settings.Name),
+			zap.Error(err))
+		return err
+	}
+
+	nm.logger.Info("namespace settings saved",
+		zap.String("namespace", settings.Name),
+		zap.String("updatedBy", settings.UpdatedBy))
+	return nil
+}
+
+// GetSettings 获取命名空间设置
+func (nm *NamespaceManager) GetSettings(name string) (*common.NamespaceSettings, error) {
+	resp, err := nm.etcdClient.Get(common.NamespaceDir + name)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Count == 0 {
+		return nil, common.ErrNamespaceSettingsNotFound
+	}
+
+	settings := &common.NamespaceSettings{}
+	if err = json.Unmarshal(resp.Kvs[0].Value, settings); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal namespace settings: %v", err)
+	}
+	return settings, nil
+}
+
+// DeleteSettings 删除命名空间设置，其中的任务恢复使用全局默认值
+func (nm *NamespaceManager) DeleteSettings(name string) error {
+	resp, err := nm.etcdClient.Delete(common.NamespaceDir + name)
+	if err != nil {
+		return err
+	}
+
+	if resp != nil && resp.Deleted == 0 {
+		return common.ErrNamespaceSettingsNotFound
+	}
+
+	nm.logger.Info("namespace settings deleted", zap.String("namespace", name))
+	return nil
+}
+
+// ListSettings 获取所有命名空间设置
+func (nm *NamespaceManager) ListSettings() ([]*common.NamespaceSettings, error) {
+	resp, err := nm.etcdClient.GetWithPrefix(common.NamespaceDir)
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]*common.NamespaceSettings, 0, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		settings := &common.NamespaceSettings{}
+		if err = json.Unmarshal(kv.Value, settings); err != nil {
+			nm.logger.Error("failed to unmarshal namespace settings",
+				zap.String("key", string(kv.Key)),
+				zap.Error(err))
+			continue
+		}
+		list = append(list, settings)
+	}
+
+	return list, nil
+}
+
+// validateSettings 校验命名空间设置
+func validateSettings(settings *common.NamespaceSettings) error {
+	if settings.Name == "" || strings.Contains(settings.Name, "/") {
+		return fmt.Errorf("%w: name is required and must not contain '/'", common.ErrInvalidNamespaceSettings)
+	}
+	if settings.Notify != nil {
+		if err := settings.Notify.Validate(); err != nil {
+			return fmt.Errorf("%w: %v", common.ErrInvalidNamespaceSettings, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,24 @@
+package nsmgr
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+)
+
+func TestValidateSettings(t *testing.T) {
+	assert.NoError(t, validateSettings(&common.NamespaceSettings{Name: "ops"}))
+	assert.NoError(t, validateSettings(&common.NamespaceSettings{
+		Name:   "ops",
+		Notify: &common.NotifyRoute{Webhooks: []string{"https://hooks.example.com/ops"}},
+	}))
+
+	assert.ErrorIs(t, validateSettings(&common.NamespaceSettings{Name: ""}), common.ErrInvalidNamespaceSettings)
+	assert.ErrorIs(t, validateSettings(&common.NamespaceSettings{Name: "a/b"}), common.ErrInvalidNamespaceSettings)
+	assert.ErrorIs(t, validateSettings(&common.NamespaceSettings{
+		Name:   "ops",
+		Notify: &common.NotifyRoute{Webhooks: []string{"not-a-url"}},
+	}), common.ErrInvalidNamespaceSettings)
+}
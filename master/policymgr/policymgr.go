@@ -0,0 +1,191 @@
+package policymgr
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	"go.uber.org/zap"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+	"github.com/fyerfyer/scheduler-refactor/pkg/etcd"
+	"github.com/fyerfyer/scheduler-refactor/pkg/policy"
+)
+
+// maxRejections 审计接口返回的最多拒绝记录条数
+const maxRejections = 500
+
+// PolicyManager 命令策略管理器，负责规则的CRUD、保存任务时的判定和拒绝审计
+type PolicyManager struct {
+	etcdClient *etcd.Client // etcd客户端
+	logger     *zap.Logger  // 日志对象
+}
+
+// NewPolicyManager 创建策略管理器
+func NewPolicyManager(etcdClient *etcd.Client, logger *zap.Logger) *PolicyManager {
+	return &PolicyManager{
+		etcdClient: etcdClient,
+		logger:     logger,
+	}
+}
+
+// SaveRule 保存策略规则
+func (pm *PolicyManager) SaveRule(rule *policy.Rule) error {
+	if err := rule.Validate(); err != nil {
+		return fmt.Errorf("%w: %v", common.ErrInvalidPolicyRule, err)
+	}
+	if strings.Contains(rule.Name, "/") {
+		return fmt.Errorf("%w: rule name must not contain '/'", common.ErrInvalidPolicyRule)
+	}
+
+	now := time.Now().Unix()
+	if rule.CreatedAt == 0 {
+		rule.CreatedAt = now
+	}
+	rule.UpdatedAt = now
+
+	data, err := json.Marshal(rule)
+	if err != nil {
+		return fmt.Errorf("failed to marshal policy rule: %v", err)
+	}
+
+	if _, err = pm.etcdClient.Put(common.PolicyDir+rule.Name, string(data)); err != nil {
+		pm.logger.Error("failed to save policy rule",
+			zap.String("rule", rule.Name),
+			zap.Error(err))
+		return err
+	}
+
+	pm.logger.Info("policy rule saved",
+		zap.String("rule", rule.Name),
+		zap.String("action", rule.Action),
+		zap.String("pattern", rule.Pattern))
+	return nil
+}
+
+// DeleteRule 删除策略规则
+func (pm *PolicyManager) DeleteRule(name string) error {
+	resp, err := pm.etcdClient.Delete(common.PolicyDir + name)
+	if err != nil {
+		return err
+	}
+
+	if resp != nil && resp.Deleted == 0 {
+		return common.ErrPolicyRuleNotFound
+	}
+
+	pm.logger.Info("policy rule deleted", zap.String("rule", name))
+	return nil
+}
+
+// ListRules 获取所有策略规则
+func (pm *PolicyManager) ListRules() ([]*policy.Rule, error) {
+	resp, err := pm.etcdClient.GetWithPrefix(common.PolicyDir)
+	if err != nil {
+		return nil, err
+	}
+
+	rules := make([]*policy.Rule, 0, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		rule := &policy.Rule{}
+		if err = json.Unmarshal(kv.Value, rule); err != nil {
+			pm.logger.Error("failed to unmarshal policy rule",
+				zap.String("key", string(kv.Key)),
+				zap.Error(err))
+			continue
+		}
+		rules = append(rules, rule)
+	}
+
+	return rules, nil
+}
+
+// Evaluate 根据当前规则判定命令
+func (pm *PolicyManager) Evaluate(command string) (policy.Decision, error) {
+	rules, err := pm.ListRules()
+	if err != nil {
+		return policy.Decision{}, err
+	}
+
+	engine, err := policy.NewEngine(rules)
+	if err != nil {
+		return policy.Decision{}, err
+	}
+
+	return engine.Evaluate(command), nil
+}
+
+// CheckJob 判定任务命令是否允许保存，被拒绝时记录审计并返回ErrCommandDenied
+func (pm *PolicyManager) CheckJob(job *common.Job) (policy.Decision, error) {
+	decision, err := pm.Evaluate(job.Command)
+	if err != nil {
+		return decision, err
+	}
+
+	if !decision.Allowed {
+		pm.RecordRejection(job.Name, job.Command, decision)
+		return decision, common.NewJobError(job.Name, common.ErrCommandDenied)
+	}
+
+	return decision, nil
+}
+
+// RecordRejection 记录一次保存任务时的拒绝，拒绝记录保存到etcd，写入失败只记录日志
+func (pm *PolicyManager) RecordRejection(jobName, command string, decision policy.Decision) {
+	now := time.Now()
+	rejection := &common.PolicyRejection{
+		JobName: jobName,
+		Command: command,
+		Rule:    decision.Rule,
+		Reason:  decision.Reason,
+		Stage:   common.PolicyStageSave,
+		Time:    now.Unix(),
+	}
+
+	pm.logger.Warn("command rejected by policy",
+		zap.String("jobName", jobName),
+		zap.String("command", command),
+		zap.String("rule", decision.Rule),
+		zap.String("reason", decision.Reason))
+
+	data, err := json.Marshal(rejection)
+	if err != nil {
+		pm.logger.Error("failed to marshal policy rejection", zap.Error(err))
+		return
+	}
+
+	if err = pm.etcdClient.PutWithLease(common.PolicyAuditKey(rejection, now), string(data), common.PolicyAuditTTL); err != nil {
+		pm.logger.Error("failed to save policy rejection",
+			zap.String("jobName", jobName),
+			zap.Error(err))
+	}
+}
+
+// ListRejections 获取master和worker最近的拒绝记录，按时间倒序
+func (pm *PolicyManager) ListRejections() ([]*common.PolicyRejection, error) {
+	resp, err := pm.etcdClient.GetWithPrefix(common.PolicyAuditDir)
+	if err != nil {
+		return nil, err
+	}
+
+	return pm.decodeRejections(resp.Kvs), nil
+}
+
+// decodeRejections 解析按key升序的拒绝记录，返回最新的maxRejections条
+func (pm *PolicyManager) decodeRejections(kvs []*mvccpb.KeyValue) []*common.PolicyRejection {
+	result := make([]*common.PolicyRejection, 0, min(len(kvs), maxRejections))
+	for i := len(kvs) - 1; i >= 0 && len(result) < maxRejections; i-- {
+		rejection := &common.PolicyRejection{}
+		if err := json.Unmarshal(kvs[i].Value, rejection); err != nil {
+			pm.logger.Error("failed to unmarshal policy rejection",
+				zap.String("key", string(kvs[i].Key)),
+				zap.Error(err))
+			continue
+		}
+		result = append(result, rejection)
+	}
+
+	return result
+}
@@ -0,0 +1,37 @@
+package policymgr
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+)
+
+func TestDecodeRejections(t *testing.T) {
+	pm := NewPolicyManager(nil, zaptest.NewLogger(t))
+
+	start := time.Now()
+	kvs := make([]*mvccpb.KeyValue, 0, maxRejections+10)
+	for i := 0; i < maxRejections+10; i++ {
+		rejection := &common.PolicyRejection{JobName: fmt.Sprintf("job-%d", i), Rule: "no-rm-root", Stage: common.PolicyStageSave}
+		data, err := json.Marshal(rejection)
+		require.NoError(t, err)
+		kvs = append(kvs, &mvccpb.KeyValue{
+			Key:   []byte(common.PolicyAuditKey(rejection, start.Add(time.Duration(i)))),
+			Value: data,
+		})
+	}
+	kvs = append(kvs, &mvccpb.KeyValue{Key: []byte(common.PolicyAuditDir + "broken"), Value: []byte("{")})
+
+	rejections := pm.decodeRejections(kvs)
+	assert.Len(t, rejections, maxRejections, "Rejections should be capped")
+	assert.Equal(t, fmt.Sprintf("job-%d", maxRejections+9), rejections[0].JobName, "Newest rejection should come first")
+	assert.Equal(t, "no-rm-root", rejections[0].Rule)
+}
@@ -0,0 +1,189 @@
+package rbacmgr
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.uber.org/zap"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+	"github.com/fyerfyer/scheduler-refactor/pkg/etcd"
+)
+
+// RoleManager 角色分配管理器，角色分配保存在etcd中，本地缓存随监听更新，每个请求不需要读取etcd
+type RoleManager struct {
+	etcdClient *etcd.Client       // etcd客户端
+	logger     *zap.Logger        // 日志对象
+	roles      map[string]string  // 用户名 -> 角色
+	roleLock   sync.RWMutex       // 保护roles
+	ctx        context.Context    // 上下文，用于控制退出
+	cancelFunc context.CancelFunc // 取消函数
+}
+
+// NewRoleManager 创建角色分配管理器，加载当前的角色分配并开始监听变化
+func NewRoleManager(etcdClient *etcd.Client, logger *zap.Logger) *RoleManager {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	rm := &RoleManager{
+		etcdClient: etcdClient,
+		logger:     logger,
+		roles:      make(map[string]string),
+		ctx:        ctx,
+		cancelFunc: cancel,
+	}
+
+	rm.loadRoles()
+	go rm.watchRoles()
+
+	return rm
+}
+
+// Role 获取用户分配的角色，没有分配时返回false
+func (rm *RoleManager) Role(user string) (string, bool) {
+	rm.roleLock.RLock()
+	defer rm.roleLock.RUnlock()
+
+	role, exists := rm.roles[user]
+	return role, exists
+}
+
+// ListBindings 获取所有角色分配，按用户名排序
+func (rm *RoleManager) ListBindings() ([]*common.RoleBinding, error) {
+	resp, err := rm.etcdClient.GetWithPrefix(common.RoleBindingDir)
+	if err != nil {
+		return nil, err
+	}
+
+	bindings := make([]*common.RoleBinding, 0, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		binding := &common.RoleBinding{}
+		if err = json.Unmarshal(kv.Value, binding); err != nil {
+			rm.logger.Error("failed to unmarshal role binding",
+				zap.String("key", string(kv.Key)),
+				zap.Error(err))
+			continue
+		}
+		bindings = append(bindings, binding)
+	}
+
+	sort.Slice(bindings, func(i, j int) bool { return bindings[i].User < bindings[j].User })
+	return bindings, nil
+}
+
+// SaveBinding 为用户分配角色，覆盖原有的分配
+func (rm *RoleManager) SaveBinding(binding *common.RoleBinding) error {
+	if err := binding.Validate(); err != nil {
+		return err
+	}
+	binding.UpdatedAt = time.Now().Unix()
+
+	data, err := json.Marshal(binding)
+	if err != nil {
+		return fmt.Errorf("failed to marshal role binding: %v", err)
+	}
+	if _, err = rm.etcdClient.Put(common.RoleBindingDir+binding.User, string(data)); err != nil {
+		rm.logger.Error("failed to save role binding",
+			zap.String("user", binding.User),
+			zap.Error(err))
+		return err
+	}
+
+	rm.logger.Info("role binding saved",
+		zap.String("user", binding.User),
+		zap.String("role", binding.Role),
+		zap.String("updatedBy", binding.UpdatedBy))
+	return nil
+}
+
+// DeleteBinding 删除用户的角色分配，之后使用凭证或请求头中的角色
+func (rm *RoleManager) DeleteBinding(user string) error {
+	resp, err := rm.etcdClient.Delete(common.RoleBindingDir + user)
+	if err != nil {
+		return err
+	}
+	if resp.Deleted == 0 {
+		return common.ErrRoleBindingNotFound
+	}
+
+	rm.logger.Info("role binding deleted", zap.String("user", user))
+	return nil
+}
+
+// Stop 停止监听
+func (rm *RoleManager) Stop() {
+	rm.cancelFunc()
+}
+
+// loadRoles 加载所有角色分配
+func (rm *RoleManager) loadRoles() {
+	bindings, err := rm.ListBindings()
+	if err != nil {
+		rm.logger.Error("failed to load role bindings", zap.Error(err))
+		return
+	}
+
+	roles := make(map[string]string, len(bindings))
+	for _, binding := range bindings {
+		roles[binding.User] = binding.Role
+	}
+
+	rm.roleLock.Lock()
+	rm.roles = roles
+	rm.roleLock.Unlock()
+
+	rm.logger.Info("role bindings loaded", zap.Int("count", len(roles)))
+}
+
+// watchRoles 监听角色分配的变化
+func (rm *RoleManager) watchRoles() {
+	watchChan := rm.etcdClient.WatchWithPrefix(rm.ctx, common.RoleBindingDir)
+
+	for {
+		select {
+		case <-rm.ctx.Done():
+			return
+		case watchResp, ok := <-watchChan:
+			if !ok || watchResp.Err() != nil {
+				// 监听中断期间的变化可能已经丢失，重新加载全部角色分配
+				rm.logger.Warn("role binding watch interrupted, reloading", zap.Bool("closed", !ok))
+				if !ok {
+					watchChan = rm.etcdClient.WatchWithPrefix(rm.ctx, common.RoleBindingDir)
+				}
+				rm.loadRoles()
+				continue
+			}
+
+			for _, event := range watchResp.Events {
+				rm.handleEvent(event)
+			}
+		}
+	}
+}
+
+// handleEvent 处理角色分配的变化
+func (rm *RoleManager) handleEvent(event *clientv3.Event) {
+	user := strings.TrimPrefix(string(event.Kv.Key), common.RoleBindingDir)
+
+	rm.roleLock.Lock()
+	defer rm.roleLock.Unlock()
+
+	switch event.Type {
+	case clientv3.EventTypePut:
+		binding := &common.RoleBinding{}
+		if err := json.Unmarshal(event.Kv.Value, binding); err != nil {
+			rm.logger.Error("failed to unmarshal role binding",
+				zap.String("user", user),
+				zap.Error(err))
+			return
+		}
+		rm.roles[user] = binding.Role
+	case clientv3.EventTypeDelete:
+		delete(rm.roles, user)
+	}
+}
@@ -0,0 +1,31 @@
+package rbacmgr
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.uber.org/zap"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+)
+
+func TestHandleEvent(t *testing.T) {
+	rm := &RoleManager{logger: zap.NewNop(), roles: make(map[string]string)}
+	key := []byte(common.RoleBindingDir + "alice")
+
+	rm.handleEvent(&clientv3.Event{Type: clientv3.EventTypePut, Kv: &mvccpb.KeyValue{Key: key, Value: []byte(`{"user":"alice","role":"operator"}`)}})
+	role, exists := rm.Role("alice")
+	assert.True(t, exists)
+	assert.Equal(t, common.RoleOperator, role)
+
+	// 无法解析的分配不覆盖原有的角色
+	rm.handleEvent(&clientv3.Event{Type: clientv3.EventTypePut, Kv: &mvccpb.KeyValue{Key: key, Value: []byte(`{`)}})
+	role, _ = rm.Role("alice")
+	assert.Equal(t, common.RoleOperator, role)
+
+	rm.handleEvent(&clientv3.Event{Type: clientv3.EventTypeDelete, Kv: &mvccpb.KeyValue{Key: key}})
+	_, exists = rm.Role("alice")
+	assert.False(t, exists)
+}
@@ -0,0 +1,257 @@
+package reconciler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.uber.org/zap"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+	"github.com/fyerfyer/scheduler-refactor/master/logmgr"
+	"github.com/fyerfyer/scheduler-refactor/pkg/etcd"
+)
+
+// 对账参数
+const (
+	// Interval 定时对账的间隔
+	Interval = time.Minute
+
+	// resultGrace 执行结束后等待日志写入的时间，超过后视为日志丢失，需大于worker的日志提交超时
+	resultGrace = 5 * time.Minute
+)
+
+// 意图的对账结果
+const (
+	actionRunning   = "running"   // worker仍在执行
+	actionPending   = "pending"   // 已结束，日志尚未写入
+	actionCompleted = "completed" // 日志已写入，删除意图
+)
+
+// LeaderChecker 判断当前master是否为leader，只有leader执行对账
+type LeaderChecker interface {
+	IsLeader() bool
+}
+
+// LostRun 结果丢失的执行
+type LostRun struct {
+	RunID    string `json:"runId"`    // 执行的唯一标识
+	JobName  string `json:"jobName"`  // 任务名称
+	WorkerID string `json:"workerId"` // 执行的worker
+	Reason   string `json:"reason"`   // 丢失原因: worker_lost/result_lost
+}
+
+// Report 一次对账的结果
+type Report struct {
+	Scanned   int        `json:"scanned"`   // 扫描的意图数量
+	Running   int        `json:"running"`   // 仍在执行的数量
+	Pending   int        `json:"pending"`   // 已结束、等待日志写入的数量
+	Completed int        `json:"completed"` // 日志已写入、清理的意图数量
+	Lost      []*LostRun `json:"lost"`      // 结果丢失的执行，非预览时已补记失败日志
+	DryRun    bool       `json:"dryRun"`    // 是否只报告不处理
+}
+
+// Manager 执行意图对账器，定时检查worker写入的执行意图：
+// 已写入日志的意图直接删除；worker锁租约过期而意图未结束，说明worker在执行期间退出，补记失败日志；
+// 已结束但长时间没有日志，说明日志在写入前丢失，按意图中的结束状态补记日志
+type Manager struct {
+	etcdClient *etcd.Client       // etcd客户端
+	logMgr     *logmgr.LogManager // 日志管理器
+	leader     LeaderChecker      // leader判断，为nil时视为leader
+	logger     *zap.Logger        // 日志对象
+	ctx        context.Context    // 上下文，用于控制退出
+	cancelFunc context.CancelFunc // 取消函数
+}
+
+// NewManager 创建对账器
+func NewManager(etcdClient *etcd.Client, logMgr *logmgr.LogManager, logger *zap.Logger) *Manager {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &Manager{
+		etcdClient: etcdClient,
+		logMgr:     logMgr,
+		logger:     logger,
+		ctx:        ctx,
+		cancelFunc: cancel,
+	}
+}
+
+// SetLeader 设置leader判断，设置后只有leader执行对账
+func (m *Manager) SetLeader(leader LeaderChecker) {
+	m.leader = leader
+}
+
+// Start 启动定时对账
+func (m *Manager) Start() {
+	go func() {
+		ticker := time.NewTicker(Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-m.ctx.Done():
+				return
+			case <-ticker.C:
+				if m.leader != nil && !m.leader.IsLeader() {
+					continue
+				}
+				if _, err := m.Reconcile(false); err != nil {
+					m.logger.Error("failed to reconcile run intents", zap.Error(err))
+				}
+			}
+		}
+	}()
+
+	m.logger.Info("run intent reconciler started", zap.Duration("interval", Interval))
+}
+
+// Stop 停止定时对账
+func (m *Manager) Stop() {
+	m.cancelFunc()
+}
+
+// Reconcile 检查所有执行意图，dryRun为true时只报告不处理
+func (m *Manager) Reconcile(dryRun bool) (*Report, error) {
+	resp, err := m.etcdClient.GetWithPrefix(common.RunIntentDir)
+	if err != nil {
+		m.logger.Error("failed to list run intents", zap.Error(err))
+		return nil, err
+	}
+
+	report := &Report{Scanned: len(resp.Kvs), Lost: make([]*LostRun, 0), DryRun: dryRun}
+	now := time.Now()
+	leases := make(map[int64]bool)
+
+	for _, kv := range resp.Kvs {
+		intent := &common.RunIntent{}
+		if err = json.Unmarshal(kv.Value, intent); err != nil {
+			m.logger.Error("failed to unmarshal run intent",
+				zap.String("key", string(kv.Key)),
+				zap.Error(err))
+			continue
+		}
+
+		logged, err := m.logged(intent.RunID)
+		if err != nil {
+			return nil, err
+		}
+		leaseAlive := true
+		if !intent.Finished() && !logged {
+			if leaseAlive, err = m.leaseAlive(leases, intent.LeaseID); err != nil {
+				return nil, err
+			}
+		}
+
+		action := classify(intent, logged, leaseAlive, now)
+		switch action {
+		case actionRunning:
+			report.Running++
+			continue
+		case actionPending:
+			report.Pending++
+			continue
+		case actionCompleted:
+			report.Completed++
+		default:
+			report.Lost = append(report.Lost, &LostRun{
+				RunID:    intent.RunID,
+				JobName:  intent.JobName,
+				WorkerID: intent.WorkerID,
+				Reason:   action,
+			})
+		}
+		if dryRun {
+			continue
+		}
+
+		if err = m.resolve(kv.Key, kv.ModRevision, kv.Value, intent, action, now); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(report.Lost) > 0 {
+		m.logger.Warn("run intents reconciled",
+			zap.Bool("dryRun", dryRun),
+			zap.Int("lost", len(report.Lost)),
+			zap.Int("completed", report.Completed))
+	}
+	return report, nil
+}
+
+// classify 判断意图的对账结果，返回actionXxx或丢失原因
+func classify(intent *common.RunIntent, logged, leaseAlive bool, now time.Time) string {
+	switch {
+	case logged:
+		return actionCompleted
+	case !intent.Finished() && leaseAlive:
+		return actionRunning
+	case !intent.Finished():
+		return common.IntentWorkerLost
+	case now.Sub(time.Unix(intent.EndTime, 0)) < resultGrace:
+		return actionPending
+	default:
+		return common.IntentResultLost
+	}
+}
+
+// resolve 删除意图，结果丢失时补记日志。删除时确认意图未被修改，
+// 与worker同时更新时以先提交的一方为准；补记失败时写回意图，下次对账重试
+func (m *Manager) resolve(key []byte, modRevision int64, value []byte, intent *common.RunIntent, action string, now time.Time) error {
+	applied, err := m.etcdClient.ApplyIfUnchanged(string(key), modRevision, clientv3.OpDelete(string(key)))
+	if err != nil {
+		m.logger.Error("failed to delete run intent",
+			zap.String("runId", intent.RunID),
+			zap.Error(err))
+		return err
+	}
+	if !applied || action == actionCompleted {
+		return nil
+	}
+
+	if err = m.logMgr.InsertLogs([]*common.JobLog{intent.LostLog(action, now)}); err != nil {
+		if _, putErr := m.etcdClient.Put(string(key), string(value)); putErr != nil {
+			m.logger.Error("failed to restore run intent",
+				zap.String("runId", intent.RunID),
+				zap.Error(putErr))
+		}
+		return err
+	}
+
+	m.logger.Warn("lost run recorded",
+		zap.String("runId", intent.RunID),
+		zap.String("jobName", intent.JobName),
+		zap.String("workerId", intent.WorkerID),
+		zap.String("reason", action))
+	return nil
+}
+
+// logged 执行的日志是否已写入日志存储
+func (m *Manager) logged(runID string) (bool, error) {
+	_, err := m.logMgr.GetRun(runID, nil)
+	if errors.Is(err, common.ErrRunNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// leaseAlive 租约是否仍然有效，同一租约只查询一次
+func (m *Manager) leaseAlive(leases map[int64]bool, leaseID int64) (bool, error) {
+	if leaseID == 0 {
+		return false, nil
+	}
+	if alive, exists := leases[leaseID]; exists {
+		return alive, nil
+	}
+
+	ttl, err := m.etcdClient.LeaseTTL(clientv3.LeaseID(leaseID))
+	if err != nil {
+		m.logger.Error("failed to check worker lease",
+			zap.Int64("leaseId", leaseID),
+			zap.Error(err))
+		return false, err
+	}
+	leases[leaseID] = ttl > 0
+	return ttl > 0, nil
+}
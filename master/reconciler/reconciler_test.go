@@ -0,0 +1,24 @@
+package reconciler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+)
+
+func TestClassify(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	open := &common.RunIntent{RunID: "r1", StartTime: now.Add(-time.Hour).Unix()}
+	finished := &common.RunIntent{RunID: "r2", Status: common.RunStatusSuccess, EndTime: now.Add(-time.Minute).Unix()}
+	overdue := &common.RunIntent{RunID: "r3", Status: common.RunStatusFailed, EndTime: now.Add(-resultGrace).Unix()}
+
+	assert.Equal(t, actionRunning, classify(open, false, true, now))
+	assert.Equal(t, common.IntentWorkerLost, classify(open, false, false, now), "Open intents whose lease expired should be lost")
+	assert.Equal(t, actionCompleted, classify(open, true, false, now), "Logged runs should be completed even if the intent was not finalized")
+	assert.Equal(t, actionPending, classify(finished, false, false, now), "Finished runs should wait for the log sink")
+	assert.Equal(t, actionCompleted, classify(finished, true, false, now))
+	assert.Equal(t, common.IntentResultLost, classify(overdue, false, false, now))
+}
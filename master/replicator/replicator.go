@@ -0,0 +1,286 @@
+package replicator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.uber.org/zap"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+	"github.com/fyerfyer/scheduler-refactor/pkg/etcd"
+)
+
+// maxConflicts 保留的最近冲突记录数
+const maxConflicts = 100
+
+// LeaderChecker 判断当前master是否为leader，只有leader执行复制
+type LeaderChecker interface {
+	IsLeader() bool
+}
+
+// Conflict 复制冲突，备用集群上的任务在复制之外被修改过，不会被覆盖
+type Conflict struct {
+	JobName string `json:"jobName"` // 任务名称
+	Reason  string `json:"reason"`  // 冲突原因
+	Time    int64  `json:"time"`    // 发现时间
+}
+
+// Status 复制状态
+type Status struct {
+	Enabled    bool        `json:"enabled"`    // 是否启用复制
+	Leader     bool        `json:"leader"`     // 当前master是否负责复制
+	LastSync   int64       `json:"lastSync"`   // 最近一次全量对账时间
+	Replicated int64       `json:"replicated"` // 累计复制的变更数
+	Conflicts  []*Conflict `json:"conflicts"`  // 最近的冲突，新的在前
+}
+
+// record 备用集群上的复制记录
+type record struct {
+	SourceRevision int64 `json:"sourceRevision"` // 主集群中任务的修改版本
+	ReplicatedAt   int64 `json:"replicatedAt"`   // 复制时间
+}
+
+// Replicator 灾备复制器，将任务定义从主集群复制到备用集群，不复制锁和心跳。
+// 备用集群上的任务与复制记录在同一事务中写入，两者修改版本不一致说明任务在复制之外被修改过
+type Replicator struct {
+	primary    *etcd.Client       // 主集群客户端
+	standby    *etcd.Client       // 备用集群客户端
+	logger     *zap.Logger        // 日志对象
+	leader     LeaderChecker      // leader判断，为nil时视为leader
+	interval   time.Duration      // 全量对账间隔
+	syncedRev  int64              // 最近一次全量对账时主集群的版本，之前的事件已包含在对账中
+	lock       sync.Mutex         // 保护以下状态
+	lastSync   int64              // 最近一次全量对账时间
+	replicated int64              // 累计复制的变更数
+	conflicts  []*Conflict        // 最近的冲突
+	ctx        context.Context    // 上下文，用于控制退出
+	cancelFunc context.CancelFunc // 取消函数
+}
+
+// NewReplicator 创建灾备复制器
+func NewReplicator(primary, standby *etcd.Client, interval time.Duration, logger *zap.Logger) *Replicator {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &Replicator{
+		primary:    primary,
+		standby:    standby,
+		logger:     logger,
+		interval:   interval,
+		ctx:        ctx,
+		cancelFunc: cancel,
+	}
+}
+
+// SetLeader 设置leader判断，设置后只有leader执行复制
+func (r *Replicator) SetLeader(leader LeaderChecker) {
+	r.leader = leader
+}
+
+// Start 开始复制：监听主集群的任务变化，并定期全量对账
+func (r *Replicator) Start() {
+	// 先监听再对账，对账版本之前的事件在处理时忽略
+	watchChan := r.primary.WatchWithPrefix(r.ctx, common.JobSaveDir)
+	r.syncIfLeader()
+
+	go func() {
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-r.ctx.Done():
+				return
+			case <-ticker.C:
+				r.syncIfLeader()
+			case watchResp, ok := <-watchChan:
+				if !ok || watchResp.Err() != nil {
+					r.logger.Warn("primary job watch interrupted, resyncing", zap.Bool("closed", !ok))
+					if !ok {
+						watchChan = r.primary.WatchWithPrefix(r.ctx, common.JobSaveDir)
+					}
+					r.syncIfLeader()
+					continue
+				}
+
+				if !r.isLeader() {
+					continue
+				}
+				for _, event := range watchResp.Events {
+					if event.Kv.ModRevision <= r.syncedRev {
+						continue
+					}
+
+					name := strings.TrimPrefix(string(event.Kv.Key), common.JobSaveDir)
+					var value []byte
+					if event.Type == clientv3.EventTypePut {
+						value = event.Kv.Value
+					}
+					r.replicate(name, value, event.Kv.ModRevision)
+				}
+			}
+		}
+	}()
+
+	r.logger.Info("job replicator started", zap.Duration("syncInterval", r.interval))
+}
+
+// Stop 停止复制
+func (r *Replicator) Stop() {
+	r.cancelFunc()
+	r.logger.Info("job replicator stopped")
+}
+
+// Status 获取复制状态
+func (r *Replicator) Status() *Status {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	return &Status{
+		Enabled:    true,
+		Leader:     r.isLeader(),
+		LastSync:   r.lastSync,
+		Replicated: r.replicated,
+		Conflicts:  append([]*Conflict(nil), r.conflicts...),
+	}
+}
+
+// isLeader 判断是否负责复制
+func (r *Replicator) isLeader() bool {
+	return r.leader == nil || r.leader.IsLeader()
+}
+
+// syncIfLeader leader执行全量对账
+func (r *Replicator) syncIfLeader() {
+	if !r.isLeader() {
+		return
+	}
+
+	if err := r.Sync(); err != nil {
+		r.logger.Error("failed to sync jobs to standby", zap.Error(err))
+	}
+}
+
+// Sync 全量对账：复制主集群的全部任务，删除主集群已删除的已复制任务
+func (r *Replicator) Sync() error {
+	primaryResp, err := r.primary.GetWithPrefix(common.JobSaveDir)
+	if err != nil {
+		return err
+	}
+	standbyResp, err := r.standby.GetWithPrefix(common.JobSaveDir)
+	if err != nil {
+		return err
+	}
+	recordResp, err := r.standby.GetWithPrefix(common.ReplicationDir)
+	if err != nil {
+		return err
+	}
+
+	standbyJobs := make(map[string][]byte, len(standbyResp.Kvs))
+	for _, kv := range standbyResp.Kvs {
+		standbyJobs[strings.TrimPrefix(string(kv.Key), common.JobSaveDir)] = kv.Value
+	}
+
+	primaryJobs := make(map[string]struct{}, len(primaryResp.Kvs))
+	for _, kv := range primaryResp.Kvs {
+		name := strings.TrimPrefix(string(kv.Key), common.JobSaveDir)
+		primaryJobs[name] = struct{}{}
+
+		// 已同步的任务跳过，避免每次对账都写入备用集群
+		if value, exists := standbyJobs[name]; exists && bytes.Equal(value, kv.Value) {
+			continue
+		}
+		r.replicate(name, kv.Value, kv.ModRevision)
+	}
+
+	// 只删除由复制写入的任务，备用集群上独有的任务保持不变
+	for _, kv := range recordResp.Kvs {
+		name := strings.TrimPrefix(string(kv.Key), common.ReplicationDir)
+		if _, exists := primaryJobs[name]; !exists {
+			r.replicate(name, nil, primaryResp.Header.Revision)
+		}
+	}
+
+	r.syncedRev = primaryResp.Header.Revision
+	r.lock.Lock()
+	r.lastSync = time.Now().Unix()
+	r.lock.Unlock()
+
+	return nil
+}
+
+// replicate 将任务写入备用集群，value为nil表示删除。任务在复制之外被修改过时记录冲突并跳过
+func (r *Replicator) replicate(name string, value []byte, sourceRevision int64) {
+	jobKey := common.JobSaveDir + name
+	recordKey := common.ReplicationDir + name
+
+	jobResp, err := r.standby.Get(jobKey)
+	if err != nil {
+		r.logger.Error("failed to read standby job", zap.String("jobName", name), zap.Error(err))
+		return
+	}
+	recordResp, err := r.standby.Get(recordKey)
+	if err != nil {
+		r.logger.Error("failed to read replication record", zap.String("jobName", name), zap.Error(err))
+		return
+	}
+
+	var jobRev, recordRev int64
+	if len(jobResp.Kvs) > 0 {
+		jobRev = jobResp.Kvs[0].ModRevision
+	}
+	if len(recordResp.Kvs) > 0 {
+		recordRev = recordResp.Kvs[0].ModRevision
+	}
+
+	// 备用集群上的任务不是由复制写入的，内容一致时接管，否则不覆盖
+	if jobRev != 0 && jobRev != recordRev && !bytes.Equal(jobResp.Kvs[0].Value, value) {
+		r.addConflict(name, "job modified on standby outside replication")
+		return
+	}
+
+	var ops []clientv3.Op
+	if value == nil {
+		ops = []clientv3.Op{clientv3.OpDelete(jobKey), clientv3.OpDelete(recordKey)}
+	} else {
+		data, _ := json.Marshal(&record{SourceRevision: sourceRevision, ReplicatedAt: time.Now().Unix()})
+		ops = []clientv3.Op{clientv3.OpPut(jobKey, string(value)), clientv3.OpPut(recordKey, string(data))}
+	}
+
+	applied, err := r.standby.ApplyIfUnchanged(jobKey, jobRev, ops...)
+	if err != nil {
+		r.logger.Error("failed to replicate job", zap.String("jobName", name), zap.Error(err))
+		return
+	}
+	if !applied {
+		r.addConflict(name, "job changed on standby during replication")
+		return
+	}
+
+	r.lock.Lock()
+	r.replicated++
+	r.lock.Unlock()
+
+	r.logger.Debug("job replicated to standby",
+		zap.String("jobName", name),
+		zap.Bool("deleted", value == nil))
+}
+
+// addConflict 记录冲突
+func (r *Replicator) addConflict(name, reason string) {
+	r.logger.Warn("job replication conflict",
+		zap.String("jobName", name),
+		zap.String("reason", reason))
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.conflicts = append([]*Conflict{{JobName: name, Reason: reason, Time: time.Now().Unix()}}, r.conflicts...)
+	if len(r.conflicts) > maxConflicts {
+		r.conflicts = r.conflicts[:maxConflicts]
+	}
+}
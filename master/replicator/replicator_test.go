@@ -0,0 +1,24 @@
+package replicator
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestAddConflict(t *testing.T) {
+	r := NewReplicator(nil, nil, 0, zaptest.NewLogger(t))
+	defer r.Stop()
+
+	for i := 0; i < maxConflicts+10; i++ {
+		r.addConflict(fmt.Sprintf("job-%d", i), "job modified on standby outside replication")
+	}
+
+	status := r.Status()
+	assert.True(t, status.Enabled)
+	assert.True(t, status.Leader, "Replicator without leader checker should act as leader")
+	assert.Len(t, status.Conflicts, maxConflicts, "Conflicts should be capped")
+	assert.Equal(t, fmt.Sprintf("job-%d", maxConflicts+9), status.Conflicts[0].JobName, "Newest conflict should come first")
+}
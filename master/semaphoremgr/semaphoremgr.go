@@ -0,0 +1,144 @@
+package semaphoremgr
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	"go.uber.org/zap"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+	"github.com/fyerfyer/scheduler-refactor/pkg/etcd"
+)
+
+// SemaphoreManager 信号量管理器，信号量限制集群内同时占用同一共享资源的执行数
+type SemaphoreManager struct {
+	etcdClient *etcd.Client // etcd客户端
+	logger     *zap.Logger  // 日志对象
+}
+
+// NewSemaphoreManager 创建信号量管理器
+func NewSemaphoreManager(etcdClient *etcd.Client, logger *zap.Logger) *SemaphoreManager {
+	return &SemaphoreManager{
+		etcdClient: etcdClient,
+		logger:     logger,
+	}
+}
+
+// SaveSemaphore 保存信号量，调小上限时已占用的槽位在执行结束前不受影响
+func (sm *SemaphoreManager) SaveSemaphore(sem *common.Semaphore) error {
+	if err := sem.Validate(); err != nil {
+		return err
+	}
+	sem.UpdatedAt = time.Now().Unix()
+
+	data, err := json.Marshal(sem)
+	if err != nil {
+		return fmt.Errorf("failed to marshal semaphore: %v", err)
+	}
+
+	if _, err = sm.etcdClient.Put(common.SemaphoreDir+sem.Name, string(data)); err != nil {
+		sm.logger.Error("failed to save semaphore",
+			zap.String("name", sem.Name),
+			zap.Error(err))
+		return err
+	}
+
+	sm.logger.Info("semaphore saved",
+		zap.String("name", sem.Name),
+		zap.Int("limit", sem.Limit),
+		zap.String("updatedBy", sem.UpdatedBy))
+	return nil
+}
+
+// GetSemaphore 获取信号量及其占用情况
+func (sm *SemaphoreManager) GetSemaphore(name string) (*common.SemaphoreUsage, error) {
+	resp, err := sm.etcdClient.Get(common.SemaphoreDir + name)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Count == 0 {
+		return nil, common.ErrSemaphoreNotFound
+	}
+
+	sem := &common.Semaphore{}
+	if err = json.Unmarshal(resp.Kvs[0].Value, sem); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal semaphore: %v", err)
+	}
+
+	slots, err := sm.etcdClient.GetWithPrefix(common.SemaphoreSlotDir + name + "/")
+	if err != nil {
+		return nil, err
+	}
+	return usageOf(sem, holdersBySemaphore(slots.Kvs)), nil
+}
+
+// DeleteSemaphore 删除信号量，已占用的槽位在执行结束后释放
+func (sm *SemaphoreManager) DeleteSemaphore(name string) error {
+	resp, err := sm.etcdClient.Delete(common.SemaphoreDir + name)
+	if err != nil {
+		return err
+	}
+
+	if resp != nil && resp.Deleted == 0 {
+		return common.ErrSemaphoreNotFound
+	}
+
+	sm.logger.Info("semaphore deleted", zap.String("name", name))
+	return nil
+}
+
+// ListSemaphores 获取所有信号量及其占用情况
+func (sm *SemaphoreManager) ListSemaphores() ([]*common.SemaphoreUsage, error) {
+	resp, err := sm.etcdClient.GetWithPrefix(common.SemaphoreDir)
+	if err != nil {
+		return nil, err
+	}
+	slots, err := sm.etcdClient.GetWithPrefix(common.SemaphoreSlotDir)
+	if err != nil {
+		return nil, err
+	}
+	holders := holdersBySemaphore(slots.Kvs)
+
+	list := make([]*common.SemaphoreUsage, 0, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		sem := &common.Semaphore{}
+		if err = json.Unmarshal(kv.Value, sem); err != nil {
+			sm.logger.Error("failed to unmarshal semaphore",
+				zap.String("key", string(kv.Key)),
+				zap.Error(err))
+			continue
+		}
+		list = append(list, usageOf(sem, holders))
+	}
+
+	return list, nil
+}
+
+// holdersBySemaphore 按信号量名整理槽位的占用者
+func holdersBySemaphore(kvs []*mvccpb.KeyValue) map[string][]string {
+	holders := make(map[string][]string)
+	for _, kv := range kvs {
+		key := strings.TrimPrefix(string(kv.Key), common.SemaphoreSlotDir)
+		idx := strings.LastIndex(key, "/")
+		if idx <= 0 {
+			continue
+		}
+		holders[key[:idx]] = append(holders[key[:idx]], string(kv.Value))
+	}
+	return holders
+}
+
+// usageOf 构建信号量的占用情况
+func usageOf(sem *common.Semaphore, holders map[string][]string) *common.SemaphoreUsage {
+	list := append([]string{}, holders[sem.Name]...)
+	sort.Strings(list)
+	return &common.SemaphoreUsage{
+		Semaphore: sem,
+		InUse:     len(list),
+		Holders:   list,
+	}
+}
@@ -0,0 +1,31 @@
+package semaphoremgr
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+)
+
+func TestUsageOf(t *testing.T) {
+	kvs := []*mvccpb.KeyValue{
+		{Key: []byte(common.SemaphoreSlotDir + "db/0"), Value: []byte("worker-b")},
+		{Key: []byte(common.SemaphoreSlotDir + "db/1"), Value: []byte("worker-a")},
+		{Key: []byte(common.SemaphoreSlotDir + "db-replica/0"), Value: []byte("worker-c")},
+		{Key: []byte(common.SemaphoreSlotDir + "broken"), Value: []byte("worker-d")},
+	}
+	holders := holdersBySemaphore(kvs)
+
+	usage := usageOf(&common.Semaphore{Name: "db", Limit: 3}, holders)
+	assert.Equal(t, 2, usage.InUse)
+	assert.Equal(t, []string{"worker-a", "worker-b"}, usage.Holders)
+
+	usage = usageOf(&common.Semaphore{Name: "db-replica", Limit: 1}, holders)
+	assert.Equal(t, []string{"worker-c"}, usage.Holders, "Semaphores sharing a name prefix should not mix")
+
+	usage = usageOf(&common.Semaphore{Name: "api", Limit: 1}, holders)
+	assert.Equal(t, 0, usage.InUse)
+	assert.NotNil(t, usage.Holders)
+}
@@ -0,0 +1,96 @@
+package workermgr
+
+import (
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+)
+
+// worker变化事件类型
+const (
+	WorkerEventJoin    = "join"    // 节点注册
+	WorkerEventLeave   = "leave"   // 节点注销
+	WorkerEventOnline  = "online"  // 节点恢复心跳
+	WorkerEventOffline = "offline" // 节点心跳超时
+)
+
+// workerEventBuffer 每个订阅者缓存的事件数，订阅者消费过慢时丢弃新事件
+const workerEventBuffer = 64
+
+// WorkerEvent worker加入、离开和健康状态变化事件
+type WorkerEvent struct {
+	Type     string             `json:"type"`             // 事件类型: join/leave/online/offline
+	WorkerID string             `json:"workerId"`         // worker ID
+	Worker   *common.WorkerInfo `json:"worker,omitempty"` // 节点信息，注销时为空
+	Time     int64              `json:"time"`             // 事件时间(毫秒)
+}
+
+// Subscribe 订阅worker变化事件，返回事件通道和取消订阅函数
+func (wm *WorkerManager) Subscribe() (<-chan *WorkerEvent, func()) {
+	ch := make(chan *WorkerEvent, workerEventBuffer)
+
+	wm.subLock.Lock()
+	wm.nextSubID++
+	id := wm.nextSubID
+	wm.subscribers[id] = ch
+	wm.subLock.Unlock()
+
+	return ch, func() {
+		wm.subLock.Lock()
+		delete(wm.subscribers, id)
+		wm.subLock.Unlock()
+	}
+}
+
+// publish 向所有订阅者发送事件，不阻塞worker监听
+func (wm *WorkerManager) publish(event *WorkerEvent) {
+	wm.subLock.Lock()
+	defer wm.subLock.Unlock()
+
+	for _, ch := range wm.subscribers {
+		select {
+		case ch <- event:
+		default:
+			wm.logger.Warn("worker event subscriber is too slow, dropping event",
+				zap.String("type", event.Type),
+				zap.String("workerID", event.WorkerID))
+		}
+	}
+}
+
+// newWorkerEvent 创建当前时间的worker事件
+func newWorkerEvent(eventType, workerID string, worker *common.WorkerInfo) *WorkerEvent {
+	return &WorkerEvent{
+		Type:     eventType,
+		WorkerID: workerID,
+		Worker:   worker,
+		Time:     time.Now().UnixMilli(),
+	}
+}
+
+// checkHealth 检查所有节点的心跳，节点健康状态变化时发送事件
+func (wm *WorkerManager) checkHealth() {
+	var events []*WorkerEvent
+
+	wm.workerLock.Lock()
+	for id, worker := range wm.workers {
+		online := wm.isOnline(worker.LastSeen)
+		if online == wm.online[id] {
+			continue
+		}
+
+		wm.online[id] = online
+		if online {
+			events = append(events, newWorkerEvent(WorkerEventOnline, id, worker))
+		} else {
+			events = append(events, newWorkerEvent(WorkerEventOffline, id, worker))
+		}
+	}
+	wm.workerLock.Unlock()
+
+	for _, event := range events {
+		wm.publish(event)
+	}
+}
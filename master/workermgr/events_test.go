@@ -0,0 +1,63 @@
+package workermgr
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.uber.org/zap"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+)
+
+func TestWorkerEvents(t *testing.T) {
+	wm := &WorkerManager{
+		logger:      zap.NewNop(),
+		workers:     make(map[string]*common.WorkerInfo),
+		online:      make(map[string]bool),
+		subscribers: make(map[int]chan *WorkerEvent),
+	}
+	events, unsubscribe := wm.Subscribe()
+	defer unsubscribe()
+
+	putEvent := func(lastSeen int64) *clientv3.Event {
+		data, err := json.Marshal(&common.WorkerInfo{IP: "worker-1", LastSeen: lastSeen})
+		require.NoError(t, err)
+		return &clientv3.Event{
+			Type: clientv3.EventTypePut,
+			Kv:   &mvccpb.KeyValue{Key: []byte(common.WorkerRegisterDir + "worker-1"), Value: data},
+		}
+	}
+
+	// 注册
+	wm.handleWorkerEvent(putEvent(time.Now().UnixMilli()))
+	event := <-events
+	assert.Equal(t, WorkerEventJoin, event.Type)
+	assert.Equal(t, "worker-1", event.WorkerID)
+
+	// 心跳超时
+	wm.workers["worker-1"].LastSeen = time.Now().Add(-time.Minute).UnixMilli()
+	wm.checkHealth()
+	assert.Equal(t, WorkerEventOffline, (<-events).Type)
+
+	// 状态没有变化时不重复发送
+	wm.checkHealth()
+	assert.Empty(t, events)
+
+	// 恢复心跳
+	wm.handleWorkerEvent(putEvent(time.Now().UnixMilli()))
+	assert.Equal(t, WorkerEventOnline, (<-events).Type)
+
+	// 注销
+	wm.handleWorkerEvent(&clientv3.Event{
+		Type: clientv3.EventTypeDelete,
+		Kv:   &mvccpb.KeyValue{Key: []byte(common.WorkerRegisterDir + "worker-1")},
+	})
+	event = <-events
+	assert.Equal(t, WorkerEventLeave, event.Type)
+	assert.Nil(t, event.Worker)
+}
@@ -0,0 +1,65 @@
+package workermgr
+
+import (
+	"sort"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+)
+
+// ExecutorProvider 一种执行器插件及安装了该插件的在线worker
+type ExecutorProvider struct {
+	Type      string                              `json:"type"`      // 任务类型
+	Workers   []string                            `json:"workers"`   // 安装了插件的在线worker
+	Manifests map[string]*common.ExecutorManifest `json:"manifests"` // worker ID -> 插件声明，各worker插件版本可能不同
+}
+
+// ListExecutors 汇总在线worker上报的执行器插件，按类型排序
+func (wm *WorkerManager) ListExecutors() []*ExecutorProvider {
+	wm.workerLock.RLock()
+	defer wm.workerLock.RUnlock()
+
+	providers := make(map[string]*ExecutorProvider)
+	for id, worker := range wm.workers {
+		if !wm.isOnline(worker.LastSeen) {
+			continue
+		}
+		for _, manifest := range worker.Executors {
+			provider, ok := providers[manifest.Type]
+			if !ok {
+				provider = &ExecutorProvider{Type: manifest.Type, Manifests: make(map[string]*common.ExecutorManifest)}
+				providers[manifest.Type] = provider
+			}
+			provider.Workers = append(provider.Workers, id)
+			provider.Manifests[id] = manifest
+		}
+	}
+
+	result := make([]*ExecutorProvider, 0, len(providers))
+	for _, provider := range providers {
+		sort.Strings(provider.Workers)
+		result = append(result, provider)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Type < result[j].Type
+	})
+	return result
+}
+
+// ExecutorManifests 返回安装了该类型插件的在线worker及其插件声明，没有worker安装时返回空map
+func (wm *WorkerManager) ExecutorManifests(jobType string) map[string]*common.ExecutorManifest {
+	wm.workerLock.RLock()
+	defer wm.workerLock.RUnlock()
+
+	manifests := make(map[string]*common.ExecutorManifest)
+	for id, worker := range wm.workers {
+		if !wm.isOnline(worker.LastSeen) {
+			continue
+		}
+		for _, manifest := range worker.Executors {
+			if manifest.Type == jobType {
+				manifests[id] = manifest
+			}
+		}
+	}
+	return manifests
+}
@@ -0,0 +1,160 @@
+package workermgr
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.uber.org/zap"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+)
+
+// FleetStatus 蓝绿切换状态
+type FleetStatus struct {
+	Switch      *common.FleetSwitch `json:"switch"`      // 当前的切换，没有进行中的切换时为空
+	Generations map[string][]string `json:"generations"` // 代号 -> 在线worker列表，未声明代号的worker位于""下
+	Warnings    []string            `json:"warnings"`    // 切换后没有worker可以执行部分任务等问题
+}
+
+// SaveFleetSwitch 保存蓝绿切换，所有worker立即按新的百分比判断可以执行的任务
+func (wm *WorkerManager) SaveFleetSwitch(fs *common.FleetSwitch) error {
+	if err := fs.Validate(); err != nil {
+		return err
+	}
+	fs.UpdatedAt = time.Now().Unix()
+
+	data, err := json.Marshal(fs)
+	if err != nil {
+		return fmt.Errorf("failed to marshal fleet switch: %v", err)
+	}
+
+	if _, err = wm.etcdClient.Put(common.FleetSwitchKey, string(data)); err != nil {
+		wm.logger.Error("failed to save fleet switch", zap.Error(err))
+		return err
+	}
+
+	wm.logger.Warn("fleet switch updated",
+		zap.String("from", fs.From),
+		zap.String("to", fs.To),
+		zap.Int("percent", fs.Percent),
+		zap.String("updatedBy", fs.UpdatedBy))
+	return nil
+}
+
+// GetFleetSwitch 获取当前的蓝绿切换
+func (wm *WorkerManager) GetFleetSwitch() (*common.FleetSwitch, error) {
+	fs, _, err := wm.loadFleetSwitch()
+	return fs, err
+}
+
+// RollbackFleetSwitch 把切换百分比改回0，所有任务立即回到旧一代worker
+func (wm *WorkerManager) RollbackFleetSwitch(rolledBackBy string) (*common.FleetSwitch, error) {
+	fs, modRevision, err := wm.loadFleetSwitch()
+	if err != nil {
+		return nil, err
+	}
+
+	fs.Percent = 0
+	fs.UpdatedBy = rolledBackBy
+	fs.UpdatedAt = time.Now().Unix()
+	data, err := json.Marshal(fs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal fleet switch: %v", err)
+	}
+
+	applied, err := wm.etcdClient.ApplyIfUnchanged(common.FleetSwitchKey, modRevision, clientv3.OpPut(common.FleetSwitchKey, string(data)))
+	if err != nil {
+		wm.logger.Error("failed to roll back fleet switch", zap.Error(err))
+		return nil, err
+	}
+	if !applied {
+		return nil, fmt.Errorf("fleet switch was changed concurrently, retry the rollback")
+	}
+
+	wm.logger.Warn("fleet switch rolled back",
+		zap.String("from", fs.From),
+		zap.String("to", fs.To),
+		zap.String("rolledBackBy", rolledBackBy))
+	return fs, nil
+}
+
+// DeleteFleetSwitch 结束蓝绿切换，所有worker都可以执行所有任务
+func (wm *WorkerManager) DeleteFleetSwitch() error {
+	resp, err := wm.etcdClient.Delete(common.FleetSwitchKey)
+	if err != nil {
+		wm.logger.Error("failed to delete fleet switch", zap.Error(err))
+		return err
+	}
+	if resp.Deleted == 0 {
+		return common.ErrFleetSwitchNotFound
+	}
+
+	wm.logger.Info("fleet switch deleted")
+	return nil
+}
+
+// GetFleetStatus 获取蓝绿切换和在线worker的代号分布
+func (wm *WorkerManager) GetFleetStatus() (*FleetStatus, error) {
+	fs, err := wm.GetFleetSwitch()
+	if err != nil && !errors.Is(err, common.ErrFleetSwitchNotFound) {
+		return nil, err
+	}
+
+	generations := make(map[string][]string)
+	for _, worker := range wm.OnlineWorkers() {
+		generations[worker.Generation] = append(generations[worker.Generation], worker.IP)
+	}
+	for generation := range generations {
+		sort.Strings(generations[generation])
+	}
+
+	return &FleetStatus{
+		Switch:      fs,
+		Generations: generations,
+		Warnings:    fleetWarnings(fs, generations),
+	}, nil
+}
+
+// fleetWarnings 检查切换后是否有任务没有worker可以执行
+func fleetWarnings(fs *common.FleetSwitch, generations map[string][]string) []string {
+	warnings := make([]string, 0)
+	if fs == nil {
+		return warnings
+	}
+
+	// 不属于切换两代的worker可以执行所有任务
+	for generation := range generations {
+		if generation != fs.From && generation != fs.To {
+			return warnings
+		}
+	}
+
+	if fs.Percent > 0 && len(generations[fs.To]) == 0 {
+		warnings = append(warnings, fmt.Sprintf("%d%% of jobs are assigned to generation %s, which has no online workers", fs.Percent, fs.To))
+	}
+	if fs.Percent < 100 && len(generations[fs.From]) == 0 {
+		warnings = append(warnings, fmt.Sprintf("%d%% of jobs are assigned to generation %s, which has no online workers", 100-fs.Percent, fs.From))
+	}
+	return warnings
+}
+
+// loadFleetSwitch 读取当前的蓝绿切换及其修改版本
+func (wm *WorkerManager) loadFleetSwitch() (*common.FleetSwitch, int64, error) {
+	resp, err := wm.etcdClient.Get(common.FleetSwitchKey)
+	if err != nil {
+		return nil, 0, err
+	}
+	if resp.Count == 0 {
+		return nil, 0, common.ErrFleetSwitchNotFound
+	}
+
+	fs := &common.FleetSwitch{}
+	if err = json.Unmarshal(resp.Kvs[0].Value, fs); err != nil {
+		return nil, 0, fmt.Errorf("failed to unmarshal fleet switch: %v", err)
+	}
+	return fs, resp.Kvs[0].ModRevision, nil
+}
@@ -0,0 +1,26 @@
+package workermgr
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+)
+
+func TestFleetWarnings(t *testing.T) {
+	fs := &common.FleetSwitch{From: "blue", To: "green", Percent: 30}
+
+	assert.Empty(t, fleetWarnings(nil, map[string][]string{"blue": {"w1"}}))
+	assert.Empty(t, fleetWarnings(fs, map[string][]string{"blue": {"w1"}, "green": {"w2"}}))
+
+	warnings := fleetWarnings(fs, map[string][]string{"blue": {"w1"}})
+	assert.Len(t, warnings, 1, "Jobs moved to a generation without workers should be reported")
+	assert.Contains(t, warnings[0], "green")
+
+	assert.Empty(t, fleetWarnings(fs, map[string][]string{"blue": {"w1"}, "": {"w3"}}),
+		"Workers outside the switch can run every job")
+
+	fs.Percent = 100
+	assert.Empty(t, fleetWarnings(fs, map[string][]string{"green": {"w2"}}))
+}
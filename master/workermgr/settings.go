@@ -0,0 +1,86 @@
+package workermgr
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"go.uber.org/zap"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+)
+
+// PutWorkerSettings 发布worker配置，target为global时对所有worker生效，否则只对指定worker生效
+func (wm *WorkerManager) PutWorkerSettings(target string, settings *common.WorkerSettings) error {
+	if err := validateWorkerSettings(settings); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(settings)
+	if err != nil {
+		return fmt.Errorf("failed to marshal worker settings: %v", err)
+	}
+
+	if _, err = wm.etcdClient.Put(common.WorkerConfigDir+target, string(data)); err != nil {
+		wm.logger.Error("failed to publish worker settings",
+			zap.String("target", target),
+			zap.Error(err))
+		return err
+	}
+
+	wm.logger.Info("worker settings published", zap.String("target", target))
+	return nil
+}
+
+// GetWorkerSettings 获取已发布的worker配置
+func (wm *WorkerManager) GetWorkerSettings(target string) (*common.WorkerSettings, error) {
+	resp, err := wm.etcdClient.Get(common.WorkerConfigDir + target)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.Count == 0 {
+		return nil, common.ErrWorkerSettingsNotFound
+	}
+
+	settings := &common.WorkerSettings{}
+	if err = json.Unmarshal(resp.Kvs[0].Value, settings); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal worker settings: %v", err)
+	}
+
+	return settings, nil
+}
+
+// DeleteWorkerSettings 删除已发布的worker配置，worker会回退到本地配置
+func (wm *WorkerManager) DeleteWorkerSettings(target string) error {
+	resp, err := wm.etcdClient.Delete(common.WorkerConfigDir + target)
+	if err != nil {
+		wm.logger.Error("failed to delete worker settings",
+			zap.String("target", target),
+			zap.Error(err))
+		return err
+	}
+
+	if resp != nil && resp.Deleted == 0 {
+		return common.ErrWorkerSettingsNotFound
+	}
+
+	wm.logger.Info("worker settings deleted", zap.String("target", target))
+	return nil
+}
+
+// validateWorkerSettings 校验worker配置取值范围
+func validateWorkerSettings(settings *common.WorkerSettings) error {
+	if settings.LogBatchSize != nil && *settings.LogBatchSize <= 0 {
+		return fmt.Errorf("%w: logBatchSize must be positive", common.ErrInvalidWorkerSettings)
+	}
+	if settings.LogCommitTimeout != nil && *settings.LogCommitTimeout <= 0 {
+		return fmt.Errorf("%w: logCommitTimeout must be positive", common.ErrInvalidWorkerSettings)
+	}
+	if settings.LogRetentionDays != nil && *settings.LogRetentionDays <= 0 {
+		return fmt.Errorf("%w: logRetentionDays must be positive", common.ErrInvalidWorkerSettings)
+	}
+	if settings.MaxConcurrentJobs != nil && *settings.MaxConcurrentJobs < 0 {
+		return fmt.Errorf("%w: maxConcurrentJobs must not be negative", common.ErrInvalidWorkerSettings)
+	}
+	return nil
+}
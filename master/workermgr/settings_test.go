@@ -0,0 +1,22 @@
+package workermgr
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+)
+
+func TestValidateWorkerSettings(t *testing.T) {
+	intPtr := func(v int) *int { return &v }
+
+	assert.NoError(t, validateWorkerSettings(&common.WorkerSettings{}))
+	assert.NoError(t, validateWorkerSettings(&common.WorkerSettings{
+		LogBatchSize:      intPtr(50),
+		MaxConcurrentJobs: intPtr(0),
+	}))
+	assert.ErrorIs(t, validateWorkerSettings(&common.WorkerSettings{LogBatchSize: intPtr(0)}), common.ErrInvalidWorkerSettings)
+	assert.Error(t, validateWorkerSettings(&common.WorkerSettings{LogRetentionDays: intPtr(-1)}))
+	assert.Error(t, validateWorkerSettings(&common.WorkerSettings{MaxConcurrentJobs: intPtr(-2)}))
+}
@@ -0,0 +1,57 @@
+package workermgr
+
+import (
+	"sort"
+
+	"github.com/fyerfyer/scheduler-refactor/pkg/version"
+)
+
+// VersionSkew 集群版本偏差报告
+type VersionSkew struct {
+	MasterVersion   string              `json:"masterVersion"`   // master版本号
+	Versions        map[string][]string `json:"versions"`        // 版本号 -> worker列表
+	OutdatedWorkers []string            `json:"outdatedWorkers"` // 与master版本不一致的worker
+	Skewed          bool                `json:"skewed"`          // 是否存在版本偏差
+}
+
+// GetVersionSkew 统计在线worker的版本分布，并与master版本比较
+func (wm *WorkerManager) GetVersionSkew() *VersionSkew {
+	wm.workerLock.RLock()
+	defer wm.workerLock.RUnlock()
+
+	skew := &VersionSkew{
+		MasterVersion:   version.Version,
+		Versions:        make(map[string][]string),
+		OutdatedWorkers: make([]string, 0),
+	}
+
+	for id, worker := range wm.workers {
+		if !wm.isOnline(worker.LastSeen) {
+			continue
+		}
+
+		workerVersion := normalizeVersion(worker.Version)
+		skew.Versions[workerVersion] = append(skew.Versions[workerVersion], id)
+		if workerVersion != skew.MasterVersion {
+			skew.OutdatedWorkers = append(skew.OutdatedWorkers, id)
+		}
+	}
+
+	for v := range skew.Versions {
+		sort.Strings(skew.Versions[v])
+	}
+	sort.Strings(skew.OutdatedWorkers)
+
+	// worker之间版本不一致，或者worker与master版本不一致，都视为存在偏差
+	skew.Skewed = len(skew.Versions) > 1 || len(skew.OutdatedWorkers) > 0
+
+	return skew
+}
+
+// normalizeVersion 旧版本worker的心跳中没有版本号
+func normalizeVersion(v string) string {
+	if v == "" {
+		return "unknown"
+	}
+	return v
+}
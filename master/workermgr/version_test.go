@@ -0,0 +1,96 @@
+package workermgr
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+	"github.com/fyerfyer/scheduler-refactor/pkg/version"
+)
+
+func TestGetVersionSkew(t *testing.T) {
+	masterVersion := version.Version
+	version.Version = "v1.2.0"
+	defer func() { version.Version = masterVersion }()
+
+	online := time.Now().UnixMilli()
+	offline := time.Now().Add(-time.Minute).UnixMilli()
+
+	tests := []struct {
+		name     string
+		workers  map[string]*common.WorkerInfo
+		versions map[string][]string
+		outdated []string
+		skewed   bool
+	}{
+		{
+			name:     "no workers",
+			workers:  map[string]*common.WorkerInfo{},
+			versions: map[string][]string{},
+			outdated: []string{},
+		},
+		{
+			name: "all workers match master",
+			workers: map[string]*common.WorkerInfo{
+				"w2": {Version: "v1.2.0", LastSeen: online},
+				"w1": {Version: "v1.2.0", LastSeen: online},
+			},
+			versions: map[string][]string{"v1.2.0": {"w1", "w2"}},
+			outdated: []string{},
+		},
+		{
+			name: "workers grouped by version",
+			workers: map[string]*common.WorkerInfo{
+				"w1": {Version: "v1.2.0", LastSeen: online},
+				"w3": {Version: "v1.1.0", LastSeen: online},
+				"w2": {Version: "v1.1.0", LastSeen: online},
+			},
+			versions: map[string][]string{"v1.2.0": {"w1"}, "v1.1.0": {"w2", "w3"}},
+			outdated: []string{"w2", "w3"},
+			skewed:   true,
+		},
+		{
+			name: "workers on the same version differing from master",
+			workers: map[string]*common.WorkerInfo{
+				"w1": {Version: "v1.3.0", LastSeen: online},
+			},
+			versions: map[string][]string{"v1.3.0": {"w1"}},
+			outdated: []string{"w1"},
+			skewed:   true,
+		},
+		{
+			name: "offline workers excluded",
+			workers: map[string]*common.WorkerInfo{
+				"w1": {Version: "v1.2.0", LastSeen: online},
+				"w2": {Version: "v1.1.0", LastSeen: offline},
+			},
+			versions: map[string][]string{"v1.2.0": {"w1"}},
+			outdated: []string{},
+		},
+		{
+			name: "empty version reported as unknown",
+			workers: map[string]*common.WorkerInfo{
+				"w1": {Version: "v1.2.0", LastSeen: online},
+				"w2": {LastSeen: online},
+			},
+			versions: map[string][]string{"v1.2.0": {"w1"}, "unknown": {"w2"}},
+			outdated: []string{"w2"},
+			skewed:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wm := &WorkerManager{logger: zap.NewNop(), workers: tt.workers}
+
+			skew := wm.GetVersionSkew()
+			assert.Equal(t, "v1.2.0", skew.MasterVersion)
+			assert.Equal(t, tt.versions, skew.Versions)
+			assert.Equal(t, tt.outdated, skew.OutdatedWorkers)
+			assert.Equal(t, tt.skewed, skew.Skewed)
+		})
+	}
+}
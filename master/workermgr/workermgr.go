@@ -11,6 +11,7 @@ import (
 
 	"github.com/fyerfyer/scheduler-refactor/common"
 	"github.com/fyerfyer/scheduler-refactor/pkg/etcd"
+	"github.com/fyerfyer/scheduler-refactor/pkg/version"
 )
 
 // WorkerManager 工作节点管理器
@@ -18,9 +19,14 @@ type WorkerManager struct {
 	etcdClient *etcd.Client                  // etcd客户端
 	logger     *zap.Logger                   // 日志对象
 	workers    map[string]*common.WorkerInfo // 工作节点列表
-	workerLock sync.RWMutex                  // 读写锁，保护workers
+	online     map[string]bool               // 节点最近一次的健康状态，用于发现状态变化
+	workerLock sync.RWMutex                  // 读写锁，保护workers和online
 	ctx        context.Context               // 上下文，用于控制退出
 	cancelFunc context.CancelFunc            // 取消函数
+
+	subscribers map[int]chan *WorkerEvent // worker变化事件的订阅者
+	nextSubID   int                       // 下一个订阅者ID
+	subLock     sync.Mutex                // 保护subscribers
 }
 
 // NewWorkerManager 创建工作节点管理器
@@ -31,8 +37,11 @@ func NewWorkerManager(etcdClient *etcd.Client, logger *zap.Logger) *WorkerManage
 		etcdClient: etcdClient,
 		logger:     logger,
 		workers:    make(map[string]*common.WorkerInfo),
+		online:     make(map[string]bool),
 		ctx:        ctx,
 		cancelFunc: cancel,
+
+		subscribers: make(map[int]chan *WorkerEvent),
 	}
 
 	// 立即获取当前所有工作节点
@@ -73,6 +82,10 @@ func (wm *WorkerManager) loadWorkers() {
 	// 更新工作节点列表
 	wm.workerLock.Lock()
 	wm.workers = workers
+	wm.online = make(map[string]bool, len(workers))
+	for id, worker := range workers {
+		wm.online[id] = wm.isOnline(worker.LastSeen)
+	}
 	wm.workerLock.Unlock()
 
 	wm.logger.Info("workers loaded", zap.Int("count", len(workers)))
@@ -81,7 +94,11 @@ func (wm *WorkerManager) loadWorkers() {
 // watchWorkers 监控工作节点变化
 func (wm *WorkerManager) watchWorkers() {
 	// 监听worker目录变化
-	watchChan := wm.etcdClient.WatchWithPrefix(common.WorkerRegisterDir)
+	watchChan := wm.etcdClient.WatchWithPrefix(wm.ctx, common.WorkerRegisterDir)
+
+	// 心跳超时不会产生etcd事件，需要定期检查
+	healthTicker := time.NewTicker(common.WorkerHeartbeatTime * time.Millisecond)
+	defer healthTicker.Stop()
 
 	// 处理工作节点变化事件
 	for {
@@ -91,7 +108,20 @@ func (wm *WorkerManager) watchWorkers() {
 			wm.logger.Info("worker watcher stopped")
 			return
 
-		case watchResp := <-watchChan:
+		case <-healthTicker.C:
+			wm.checkHealth()
+
+		case watchResp, ok := <-watchChan:
+			if !ok || watchResp.Err() != nil {
+				// 监听中断期间的变化可能已经丢失，重新加载全部工作节点
+				wm.logger.Warn("worker watch interrupted, reloading", zap.Bool("closed", !ok))
+				if !ok {
+					watchChan = wm.etcdClient.WatchWithPrefix(wm.ctx, common.WorkerRegisterDir)
+				}
+				wm.loadWorkers()
+				continue
+			}
+
 			for _, event := range watchResp.Events {
 				wm.handleWorkerEvent(event)
 			}
@@ -116,9 +146,30 @@ func (wm *WorkerManager) handleWorkerEvent(event *clientv3.Event) {
 
 		// 更新工作节点信息
 		wm.workerLock.Lock()
+		previous, existed := wm.workers[workerID]
+		wasOnline := wm.online[workerID]
+		online := wm.isOnline(worker.LastSeen)
 		wm.workers[workerID] = worker
+		wm.online[workerID] = online
 		wm.workerLock.Unlock()
 
+		// 新注册的节点发送加入事件，离线节点恢复心跳时发送上线事件
+		if !existed {
+			wm.publish(newWorkerEvent(WorkerEventJoin, workerID, worker))
+		} else if online && !wasOnline {
+			wm.publish(newWorkerEvent(WorkerEventOnline, workerID, worker))
+		}
+
+		// 新注册或版本变化时检查与master的版本偏差
+		if !existed || previous.Version != worker.Version {
+			if workerVersion := normalizeVersion(worker.Version); workerVersion != version.Version {
+				wm.logger.Warn("worker version differs from master",
+					zap.String("workerID", workerID),
+					zap.String("workerVersion", workerVersion),
+					zap.String("masterVersion", version.Version))
+			}
+		}
+
 		wm.logger.Debug("worker registered or heartbeat",
 			zap.String("workerID", workerID),
 			zap.String("hostname", worker.Hostname))
@@ -126,9 +177,15 @@ func (wm *WorkerManager) handleWorkerEvent(event *clientv3.Event) {
 	case clientv3.EventTypeDelete: // 工作节点注销
 		// 从节点列表中删除
 		wm.workerLock.Lock()
+		_, existed := wm.workers[workerID]
 		delete(wm.workers, workerID)
+		delete(wm.online, workerID)
 		wm.workerLock.Unlock()
 
+		if existed {
+			wm.publish(newWorkerEvent(WorkerEventLeave, workerID, nil))
+		}
+
 		wm.logger.Info("worker unregistered",
 			zap.String("workerID", workerID))
 	}
@@ -148,6 +205,21 @@ func (wm *WorkerManager) ListWorkers() []*common.WorkerInfo {
 	return workers
 }
 
+// OnlineWorkers 获取在线的工作节点
+func (wm *WorkerManager) OnlineWorkers() []*common.WorkerInfo {
+	wm.workerLock.RLock()
+	defer wm.workerLock.RUnlock()
+
+	workers := make([]*common.WorkerInfo, 0, len(wm.workers))
+	for _, worker := range wm.workers {
+		if wm.isOnline(worker.LastSeen) {
+			workers = append(workers, worker)
+		}
+	}
+
+	return workers
+}
+
 // GetWorker 获取指定工作节点信息
 func (wm *WorkerManager) GetWorker(workerID string) (*common.WorkerInfo, bool) {
 	wm.workerLock.RLock()
@@ -162,25 +234,27 @@ func (wm *WorkerManager) CheckWorkers() map[string]string {
 	wm.workerLock.RLock()
 	defer wm.workerLock.RUnlock()
 
-	now := time.Now().Unix()
 	result := make(map[string]string)
 
 	// 检查每个节点的心跳时间
 	for id, worker := range wm.workers {
-		// 计算最后心跳时间与现在的差值（秒）
-		lastHeartbeat := now - worker.LastSeen/1000 // 转换为秒
-
-		if lastHeartbeat > int64(common.WorkerHeartbeatTime/1000*3) {
-			// 超过3个心跳周期未收到心跳，标记为离线
-			result[id] = "offline"
-		} else {
+		if wm.isOnline(worker.LastSeen) {
 			result[id] = "online"
+		} else {
+			result[id] = "offline"
 		}
 	}
 
 	return result
 }
 
+// isOnline 根据最后心跳时间(毫秒)判断节点是否在线，超过3个心跳周期未收到心跳视为离线
+func (wm *WorkerManager) isOnline(lastSeen int64) bool {
+	// 计算最后心跳时间与现在的差值（秒）
+	lastHeartbeat := time.Now().Unix() - lastSeen/1000
+	return lastHeartbeat <= int64(common.WorkerHeartbeatTime/1000*3)
+}
+
 // GetWorkerStats 获取工作节点统计信息
 func (wm *WorkerManager) GetWorkerStats() map[string]interface{} {
 	wm.workerLock.RLock()
@@ -194,15 +268,27 @@ func (wm *WorkerManager) GetWorkerStats() map[string]interface{} {
 	var totalCPU float64
 	var totalMem float64
 
-	now := time.Now().Unix()
-	for _, worker := range wm.workers {
-		lastHeartbeat := now - worker.LastSeen/1000 // 转换为秒
+	// 汇总在线worker心跳中的执行统计
+	runs := &common.WorkerRunStats{}
+	var totalDuration float64
 
-		if lastHeartbeat <= int64(common.WorkerHeartbeatTime/1000*3) {
+	for _, worker := range wm.workers {
+		if wm.isOnline(worker.LastSeen) {
 			// 节点在线
 			online++
 			totalCPU += worker.CPUUsage
 			totalMem += worker.MemUsage
+			if worker.Stats != nil {
+				if runs.Since == 0 || worker.Stats.Since < runs.Since {
+					runs.Since = worker.Stats.Since
+				}
+				runs.Executed += worker.Stats.Executed
+				runs.Succeeded += worker.Stats.Succeeded
+				runs.Failed += worker.Stats.Failed
+				runs.QuietFailed += worker.Stats.QuietFailed
+				runs.Skipped += worker.Stats.Skipped
+				totalDuration += worker.Stats.AvgDuration * float64(worker.Stats.Executed)
+			}
 		}
 	}
 
@@ -213,6 +299,10 @@ func (wm *WorkerManager) GetWorkerStats() map[string]interface{} {
 		avgMem = totalMem / float64(online)
 	}
 
+	if runs.Executed > 0 {
+		runs.AvgDuration = totalDuration / float64(runs.Executed)
+	}
+
 	// 构建统计结果
 	stats := map[string]interface{}{
 		"total":       total,
@@ -220,6 +310,7 @@ func (wm *WorkerManager) GetWorkerStats() map[string]interface{} {
 		"offline":     total - online,
 		"avgCpuUsage": avgCPU,
 		"avgMemUsage": avgMem,
+		"runs":        runs,
 	}
 
 	return stats
@@ -53,6 +53,7 @@ func registerTestWorker(t *testing.T, etcdClient *etcd.Client, workerID string,
 		CPUUsage: 0.5,
 		MemUsage: 0.3,
 		LastSeen: time.Now().UnixNano() / int64(time.Millisecond),
+		Stats:    &common.WorkerRunStats{Executed: 4, Succeeded: 3, Failed: 1, AvgDuration: 2},
 	}
 
 	// 如果要模拟离线状态，将LastSeen设置为很久以前
@@ -219,6 +220,12 @@ func TestGetWorkerStats(t *testing.T) {
 	assert.Equal(t, 1, stats["offline"], "Should have 1 offline worker")
 	assert.Equal(t, 0.5, stats["avgCpuUsage"], "Average CPU usage should be 0.5")
 	assert.Equal(t, 0.3, stats["avgMemUsage"], "Average memory usage should be 0.3")
+
+	// 只汇总在线worker心跳中的执行统计
+	runs := stats["runs"].(*common.WorkerRunStats)
+	assert.Equal(t, int64(4), runs.Executed)
+	assert.Equal(t, int64(1), runs.Failed)
+	assert.Equal(t, 2.0, runs.AvgDuration)
 }
 
 func TestHandleWorkerEvent(t *testing.T) {
@@ -0,0 +1,349 @@
+package crondesc
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// field cron表达式中的一个字段
+type field struct {
+	unit   string           // 单位名称，例如minute
+	min    int              // 最小值
+	max    int              // 最大值
+	names  map[string]int   // 可以使用的名称，例如JAN、MON
+	format func(int) string // 值的显示格式
+}
+
+var (
+	monthNames = []string{"", "January", "February", "March", "April", "May", "June",
+		"July", "August", "September", "October", "November", "December"}
+	weekdayNames = []string{"Sunday", "Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday"}
+)
+
+var (
+	secondField = field{unit: "second", min: 0, max: 59, format: strconv.Itoa}
+	minuteField = field{unit: "minute", min: 0, max: 59, format: strconv.Itoa}
+	hourField   = field{unit: "hour", min: 0, max: 23, format: func(h int) string { return fmt.Sprintf("%02d:00", h) }}
+	domField    = field{unit: "day", min: 1, max: 31, format: strconv.Itoa}
+	monthField  = field{unit: "month", min: 1, max: 12, format: func(m int) string { return monthNames[m] },
+		names: map[string]int{"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+			"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12}}
+	dowField = field{unit: "day of the week", min: 0, max: 6, format: func(d int) string { return weekdayNames[d] },
+		names: map[string]int{"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6}}
+)
+
+// part 字段的一种取值形式：单值、范围或步长
+type part struct {
+	from, to int // 取值范围，单值时from等于to
+	step     int // 步长，没有步长时为1
+	all      bool
+}
+
+// spec 解析后的字段
+type spec struct {
+	field field
+	parts []part
+}
+
+// parse 解析字段，"?"与"*"相同
+func (f field) parse(text string) (*spec, error) {
+	s := &spec{field: f}
+	for _, item := range strings.Split(text, ",") {
+		p := part{step: 1}
+		rangeText, stepText, hasStep := strings.Cut(item, "/")
+		if hasStep {
+			step, err := strconv.Atoi(stepText)
+			if err != nil || step <= 0 {
+				return nil, fmt.Errorf("invalid step in %q", item)
+			}
+			p.step = step
+		}
+
+		switch {
+		case rangeText == "*" || rangeText == "?":
+			p.all, p.from, p.to = true, f.min, f.max
+		case strings.Contains(rangeText, "-"):
+			fromText, toText, _ := strings.Cut(rangeText, "-")
+			from, err := f.value(fromText)
+			if err != nil {
+				return nil, err
+			}
+			to, err := f.value(toText)
+			if err != nil {
+				return nil, err
+			}
+			p.from, p.to = from, to
+		default:
+			value, err := f.value(rangeText)
+			if err != nil {
+				return nil, err
+			}
+			p.from, p.to = value, value
+			// 单值带步长表示从该值到最大值
+			if hasStep {
+				p.to = f.max
+			}
+		}
+		s.parts = append(s.parts, p)
+	}
+	return s, nil
+}
+
+// value 解析数字或名称
+func (f field) value(text string) (int, error) {
+	if value, ok := f.names[strings.ToLower(text)]; ok {
+		return value, nil
+	}
+	value, err := strconv.Atoi(text)
+	if err != nil || value < f.min || value > f.max {
+		return 0, fmt.Errorf("invalid %s %q", f.unit, text)
+	}
+	return value, nil
+}
+
+// any 是否匹配所有取值
+func (s *spec) any() bool {
+	return len(s.parts) == 1 && s.parts[0].all && s.parts[0].step == 1
+}
+
+// single 是否只有一个取值，返回该值
+func (s *spec) single() (int, bool) {
+	if len(s.parts) == 1 && !s.parts[0].all && s.parts[0].from == s.parts[0].to {
+		return s.parts[0].from, true
+	}
+	return 0, false
+}
+
+// singles 是否全部由单值组成，返回这些值
+func (s *spec) singles() ([]int, bool) {
+	values := make([]int, 0, len(s.parts))
+	for _, p := range s.parts {
+		if p.all || p.from != p.to {
+			return nil, false
+		}
+		values = append(values, p.from)
+	}
+	return values, true
+}
+
+// every 是否为"*/n"形式，返回步长
+func (s *spec) every() (int, bool) {
+	if len(s.parts) == 1 && s.parts[0].all && s.parts[0].step > 1 {
+		return s.parts[0].step, true
+	}
+	return 0, false
+}
+
+// phrase 描述字段的取值，例如"every 5 minutes"、"minutes 0 through 30"
+func (s *spec) phrase() string {
+	unit := s.field.unit
+	if step, ok := s.every(); ok {
+		return fmt.Sprintf("every %d %ss", step, unit)
+	}
+	if values, ok := s.singles(); ok {
+		names := make([]string, len(values))
+		for i, value := range values {
+			names[i] = s.field.format(value)
+		}
+		if len(names) == 1 {
+			return unit + " " + names[0]
+		}
+		return unit + "s " + joinAnd(names)
+	}
+
+	phrases := make([]string, len(s.parts))
+	for i, p := range s.parts {
+		switch {
+		case p.from == p.to:
+			phrases[i] = unit + " " + s.field.format(p.from)
+		case p.step > 1:
+			phrases[i] = fmt.Sprintf("every %d %ss from %s through %s", p.step, unit, s.field.format(p.from), s.field.format(p.to))
+		default:
+			phrases[i] = fmt.Sprintf("every %s from %s through %s", unit, s.field.format(p.from), s.field.format(p.to))
+		}
+	}
+	return joinAnd(phrases)
+}
+
+// Describe 生成6字段（含秒）cron表达式的英文描述，例如"0 */5 * * * 1-5"描述为"every 5 minutes on weekdays"。
+// 表达式应先经过cron解析器校验
+func Describe(expr string) (string, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 6 {
+		return "", fmt.Errorf("expected 6 fields, got %d", len(fields))
+	}
+
+	specs := make([]*spec, 6)
+	for i, f := range []field{secondField, minuteField, hourField, domField, monthField, dowField} {
+		s, err := f.parse(fields[i])
+		if err != nil {
+			return "", err
+		}
+		specs[i] = s
+	}
+	second, minute, hour, dom, month, dow := specs[0], specs[1], specs[2], specs[3], specs[4], specs[5]
+
+	timePart, atClock := describeTime(second, minute, hour)
+	pieces := []string{timePart}
+
+	dayPart := describeDays(dom, dow)
+	if dayPart == "" && atClock && month.any() {
+		dayPart = "every day"
+	}
+	if dayPart != "" {
+		pieces = append(pieces, dayPart)
+	}
+	if !month.any() {
+		pieces = append(pieces, describeMonths(month))
+	}
+
+	return strings.Join(pieces, " "), nil
+}
+
+// describeTime 描述一天中的触发时间，秒、分、时都为单值时返回"at HH:MM"形式并返回true
+func describeTime(second, minute, hour *spec) (string, bool) {
+	sec, secSingle := second.single()
+	min, minSingle := minute.single()
+
+	if secSingle && minSingle {
+		if hours, ok := hour.singles(); ok {
+			clocks := make([]string, len(hours))
+			for i, h := range hours {
+				clocks[i] = clock(h, min, sec)
+			}
+			return "at " + joinAnd(clocks), true
+		}
+
+		past := fmt.Sprintf("at minute %d", min)
+		if sec != 0 {
+			past = fmt.Sprintf("at %02d:%02d past", min, sec)
+		}
+		if hour.any() {
+			return "every hour " + past, false
+		}
+		if step, ok := hour.every(); ok {
+			return fmt.Sprintf("every %d hours %s", step, past), false
+		}
+		return past + " of " + hour.phrase(), false
+	}
+
+	var pieces []string
+	switch {
+	case second.any():
+		pieces = append(pieces, "every second")
+	case secSingle && sec == 0:
+		if minute.any() {
+			pieces = append(pieces, "every minute")
+		}
+	case secSingle:
+		pieces = append(pieces, fmt.Sprintf("at second %d", sec))
+		if minute.any() {
+			pieces[0] = "every minute " + pieces[0]
+		}
+	default:
+		pieces = append(pieces, second.phrase())
+	}
+
+	if !minute.any() {
+		if minSingle {
+			pieces = append(pieces, fmt.Sprintf("during minute %d", min))
+		} else {
+			pieces = append(pieces, minute.phrase())
+		}
+	}
+
+	if !hour.any() {
+		if values, ok := hour.singles(); ok {
+			ranges := make([]string, len(values))
+			for i, h := range values {
+				ranges[i] = fmt.Sprintf("%02d:00-%02d:59", h, h)
+			}
+			pieces = append(pieces, "between "+joinAnd(ranges))
+		} else if len(hour.parts) == 1 && hour.parts[0].step == 1 {
+			pieces = append(pieces, fmt.Sprintf("between %02d:00 and %02d:59", hour.parts[0].from, hour.parts[0].to))
+		} else {
+			pieces = append(pieces, hour.phrase())
+		}
+	}
+
+	return strings.Join(pieces, ", "), false
+}
+
+// describeDays 描述日期和星期，两者都有限制时满足其一即触发
+func describeDays(dom, dow *spec) string {
+	var pieces []string
+	if !dom.any() {
+		if step, ok := dom.every(); ok {
+			pieces = append(pieces, fmt.Sprintf("every %d days", step))
+		} else {
+			pieces = append(pieces, "on "+dom.phrase()+" of the month")
+		}
+	}
+	if !dow.any() {
+		pieces = append(pieces, describeWeekdays(dow))
+	}
+	return strings.Join(pieces, " or ")
+}
+
+// describeWeekdays 描述星期，识别工作日和周末
+func describeWeekdays(dow *spec) string {
+	days := make(map[int]bool)
+	for _, p := range dow.parts {
+		for d := p.from; d <= p.to; d += p.step {
+			days[d] = true
+		}
+	}
+	switch {
+	case len(days) == 5 && !days[0] && !days[6]:
+		return "on weekdays"
+	case len(days) == 2 && days[0] && days[6]:
+		return "on weekends"
+	}
+
+	if values, ok := dow.singles(); ok {
+		names := make([]string, len(values))
+		for i, d := range values {
+			names[i] = weekdayNames[d]
+		}
+		return "on " + joinAnd(names)
+	}
+	if len(dow.parts) == 1 && dow.parts[0].step == 1 {
+		return fmt.Sprintf("from %s through %s", weekdayNames[dow.parts[0].from], weekdayNames[dow.parts[0].to])
+	}
+	return "on " + dow.phrase()
+}
+
+// describeMonths 描述月份
+func describeMonths(month *spec) string {
+	if step, ok := month.every(); ok {
+		return fmt.Sprintf("every %d months", step)
+	}
+	if values, ok := month.singles(); ok {
+		names := make([]string, len(values))
+		for i, m := range values {
+			names[i] = monthNames[m]
+		}
+		return "in " + joinAnd(names)
+	}
+	if len(month.parts) == 1 && month.parts[0].step == 1 {
+		return fmt.Sprintf("from %s through %s", monthNames[month.parts[0].from], monthNames[month.parts[0].to])
+	}
+	return "in " + month.phrase()
+}
+
+// clock 格式化时刻，秒为0时省略秒
+func clock(hour, minute, second int) string {
+	if second == 0 {
+		return fmt.Sprintf("%02d:%02d", hour, minute)
+	}
+	return fmt.Sprintf("%02d:%02d:%02d", hour, minute, second)
+}
+
+// joinAnd 用逗号和and连接，例如"a, b and c"
+func joinAnd(items []string) string {
+	if len(items) <= 1 {
+		return strings.Join(items, "")
+	}
+	return strings.Join(items[:len(items)-1], ", ") + " and " + items[len(items)-1]
+}
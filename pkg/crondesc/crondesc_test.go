@@ -0,0 +1,40 @@
+package crondesc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDescribe(t *testing.T) {
+	cases := map[string]string{
+		"* * * * * *":          "every second",
+		"*/10 * * * * *":       "every 10 seconds",
+		"0 * * * * *":          "every minute",
+		"0 */5 * * * 1-5":      "every 5 minutes on weekdays",
+		"0 30 9 * * *":         "at 09:30 every day",
+		"15 30 9 * * *":        "at 09:30:15 every day",
+		"0 0 9,18 * * MON-FRI": "at 09:00 and 18:00 on weekdays",
+		"0 0 0 * * 0,6":        "at 00:00 on weekends",
+		"0 5 * * * *":          "every hour at minute 5",
+		"0 0 */2 * * *":        "every 2 hours at minute 0",
+		"0 */15 9-17 * * *":    "every 15 minutes, between 09:00 and 17:59",
+		"0 0 3 1 * *":          "at 03:00 on day 1 of the month",
+		"0 0 3 1,15 * *":       "at 03:00 on days 1 and 15 of the month",
+		"0 0 3 1 JAN *":        "at 03:00 on day 1 of the month in January",
+		"0 0 8 * * 1,3,5":      "at 08:00 on Monday, Wednesday and Friday",
+		"0 0 8 1 * 1":          "at 08:00 on day 1 of the month or on Monday",
+		"0 0 0 1 */3 *":        "at 00:00 on day 1 of the month every 3 months",
+	}
+	for expr, expected := range cases {
+		description, err := Describe(expr)
+		require.NoError(t, err, expr)
+		assert.Equal(t, expected, description, expr)
+	}
+
+	_, err := Describe("*/5 * * * *")
+	assert.Error(t, err, "Five-field expressions are not supported")
+	_, err = Describe("0 61 * * * *")
+	assert.Error(t, err)
+}
@@ -2,12 +2,17 @@ package etcd
 
 import (
 	"context"
+	"errors"
 	"time"
 
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
 	"go.etcd.io/etcd/client/v3"
+	"go.uber.org/zap"
 
 	"github.com/fyerfyer/scheduler-refactor/common"
 	"github.com/fyerfyer/scheduler-refactor/config"
+	"github.com/fyerfyer/scheduler-refactor/pkg/metrics"
 )
 
 // Client Etcd客户端封装
@@ -16,8 +21,15 @@ type Client struct {
 	kv      clientv3.KV
 	lease   clientv3.Lease
 	watcher clientv3.Watcher
+	mux     *watchMux // 监听复用器，为nil时每次监听单独建立watch
+
+	logger        *zap.Logger   // 日志对象，用于记录慢操作
+	slowThreshold time.Duration // 慢操作阈值，0表示不记录
 }
 
+// etcdMetrics etcd操作的延迟统计
+var etcdMetrics = metrics.NewRecorder("etcd")
+
 // EtcdConfig Etcd配置
 type EtcdConfig struct {
 	Endpoints   []string
@@ -26,11 +38,16 @@ type EtcdConfig struct {
 
 // NewClient 创建Etcd客户端
 func NewClient() (*Client, error) {
+	return NewClientFor(config.GlobalConfig.EtcdEndpoints)
+}
+
+// NewClientFor 创建连接指定etcd集群的客户端，用于连接灾备等其他集群
+func NewClientFor(endpoints []string) (*Client, error) {
 	cfg := config.GlobalConfig
 
 	// 创建etcd客户端配置
 	clientConfig := clientv3.Config{
-		Endpoints:   cfg.EtcdEndpoints,
+		Endpoints:   endpoints,
 		DialTimeout: time.Duration(cfg.EtcdDialTimeout) * time.Millisecond,
 	}
 
@@ -47,24 +64,68 @@ func NewClient() (*Client, error) {
 
 	// 返回封装后的客户端
 	return &Client{
-		client:  client,
-		kv:      kv,
-		lease:   lease,
-		watcher: watcher,
+		client:        client,
+		kv:            kv,
+		lease:         lease,
+		watcher:       watcher,
+		logger:        zap.NewNop(),
+		slowThreshold: time.Duration(cfg.EtcdSlowThreshold) * time.Millisecond,
 	}, nil
 }
 
+// SetLogger 设置日志对象，用于记录慢操作
+func (c *Client) SetLogger(logger *zap.Logger) {
+	c.logger = logger
+}
+
+// observe 记录一次etcd操作的耗时，超过阈值时记录慢操作日志。锁已被占用属于正常竞争，不计为错误
+func (c *Client) observe(op, key string, start time.Time, err *error) {
+	latency := time.Since(start)
+
+	opErr := *err
+	if errors.Is(opErr, common.ErrLockAlreadyAcquired) {
+		opErr = nil
+	}
+	etcdMetrics.Observe(op, latency, opErr)
+
+	if c.slowThreshold > 0 && latency >= c.slowThreshold {
+		c.logger.Warn("slow etcd operation",
+			zap.String("operation", op),
+			zap.String("key", key),
+			zap.Duration("latency", latency),
+			zap.Error(opErr))
+	}
+}
+
+// firstKey 批量操作的第一个key，用于慢操作日志
+func firstKey(keys []string) string {
+	if len(keys) == 0 {
+		return ""
+	}
+	return keys[0]
+}
+
+// EnableWatchMux 开启监听复用，root前缀下的监听共用一个底层watch，需在开始监听前调用
+func (c *Client) EnableWatchMux(root string) {
+	c.mux = newWatchMux(c.watcher, root)
+}
+
 // Close 关闭连接
 func (c *Client) Close() error {
+	if c.mux != nil {
+		c.mux.stop()
+	}
 	return c.client.Close()
 }
 
 // Get 获取键值
-func (c *Client) Get(key string) (*clientv3.GetResponse, error) {
+func (c *Client) Get(key string) (resp *clientv3.GetResponse, err error) {
+	defer c.observe("get", key, time.Now(), &err)
+
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	resp, err := c.kv.Get(ctx, key)
+	resp, err = c.kv.Get(ctx, key)
 	if err != nil {
 		return nil, common.NewEtcdError("get", key, err)
 	}
@@ -72,12 +133,35 @@ func (c *Client) Get(key string) (*clientv3.GetResponse, error) {
 	return resp, nil
 }
 
+// GetAtRevision 获取key在指定版本时的键值，revision为0时读取当前版本。
+// 版本已被压缩时返回ErrRevisionCompacted，版本比当前版本新时返回ErrFutureRevision
+func (c *Client) GetAtRevision(key string, revision int64) (resp *clientv3.GetResponse, err error) {
+	defer c.observe("getAtRevision", key, time.Now(), &err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	resp, err = c.kv.Get(ctx, key, clientv3.WithRev(revision))
+	switch {
+	case errors.Is(err, rpctypes.ErrCompacted):
+		return nil, common.ErrRevisionCompacted
+	case errors.Is(err, rpctypes.ErrFutureRev):
+		return nil, common.ErrFutureRevision
+	case err != nil:
+		return nil, common.NewEtcdError("getAtRevision", key, err)
+	}
+
+	return resp, nil
+}
+
 // GetWithPrefix 获取前缀匹配的键值
-func (c *Client) GetWithPrefix(prefix string) (*clientv3.GetResponse, error) {
+func (c *Client) GetWithPrefix(prefix string) (resp *clientv3.GetResponse, err error) {
+	defer c.observe("getWithPrefix", prefix, time.Now(), &err)
+
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	resp, err := c.kv.Get(ctx, prefix, clientv3.WithPrefix())
+	resp, err = c.kv.Get(ctx, prefix, clientv3.WithPrefix())
 	if err != nil {
 		return nil, common.NewEtcdError("getWithPrefix", prefix, err)
 	}
@@ -85,12 +169,45 @@ func (c *Client) GetWithPrefix(prefix string) (*clientv3.GetResponse, error) {
 	return resp, nil
 }
 
+// GetMany 在尽量少的事务中读取多个key，返回存在的key及其键值，同一事务内的读取属于同一版本
+func (c *Client) GetMany(keys []string) (kvs map[string]*mvccpb.KeyValue, err error) {
+	defer c.observe("getMany", firstKey(keys), time.Now(), &err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	kvs = make(map[string]*mvccpb.KeyValue, len(keys))
+	for start := 0; start < len(keys); start += maxTxnOps {
+		end := min(start+maxTxnOps, len(keys))
+
+		ops := make([]clientv3.Op, 0, end-start)
+		for _, key := range keys[start:end] {
+			ops = append(ops, clientv3.OpGet(key))
+		}
+
+		txnResp, err := c.client.Txn(ctx).Then(ops...).Commit()
+		if err != nil {
+			return nil, common.NewEtcdError("txn.get", keys[start], err)
+		}
+
+		for _, resp := range txnResp.Responses {
+			for _, kv := range resp.GetResponseRange().Kvs {
+				kvs[string(kv.Key)] = kv
+			}
+		}
+	}
+
+	return kvs, nil
+}
+
 // Put 设置键值
-func (c *Client) Put(key, value string) (*clientv3.PutResponse, error) {
+func (c *Client) Put(key, value string) (resp *clientv3.PutResponse, err error) {
+	defer c.observe("put", key, time.Now(), &err)
+
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	resp, err := c.kv.Put(ctx, key, value)
+	resp, err = c.kv.Put(ctx, key, value)
 	if err != nil {
 		return nil, common.NewEtcdError("put", key, err)
 	}
@@ -99,7 +216,9 @@ func (c *Client) Put(key, value string) (*clientv3.PutResponse, error) {
 }
 
 // PutWithLease 设置带租约的键值
-func (c *Client) PutWithLease(key, value string, ttl int64) error {
+func (c *Client) PutWithLease(key, value string, ttl int64) (err error) {
+	defer c.observe("putWithLease", key, time.Now(), &err)
+
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
@@ -129,11 +248,13 @@ func (c *Client) KeepAlive(leaseID clientv3.LeaseID) (<-chan *clientv3.LeaseKeep
 }
 
 // Delete 删除键值
-func (c *Client) Delete(key string) (*clientv3.DeleteResponse, error) {
+func (c *Client) Delete(key string) (resp *clientv3.DeleteResponse, err error) {
+	defer c.observe("delete", key, time.Now(), &err)
+
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	resp, err := c.kv.Delete(ctx, key)
+	resp, err = c.kv.Delete(ctx, key)
 	if err != nil {
 		return nil, common.NewEtcdError("delete", key, err)
 	}
@@ -141,18 +262,33 @@ func (c *Client) Delete(key string) (*clientv3.DeleteResponse, error) {
 	return resp, nil
 }
 
-// Watch 监听键值变化
-func (c *Client) Watch(key string) clientv3.WatchChan {
-	return c.watcher.Watch(context.Background(), key)
+// Watch 监听键值变化，ctx取消时结束监听。通道被关闭或收到错误时调用方需要重新监听并重新加载
+func (c *Client) Watch(ctx context.Context, key string) clientv3.WatchChan {
+	if c.mux != nil && c.mux.covers(key) {
+		return c.mux.subscribe(ctx, key, false)
+	}
+	return c.watcher.Watch(ctx, key)
 }
 
-// WatchWithPrefix 监听前缀下的键值变化
-func (c *Client) WatchWithPrefix(prefix string) clientv3.WatchChan {
-	return c.watcher.Watch(context.Background(), prefix, clientv3.WithPrefix())
+// WatchWithPrefix 监听前缀下的键值变化，ctx取消时结束监听。通道被关闭或收到错误时调用方需要重新监听并重新加载
+func (c *Client) WatchWithPrefix(ctx context.Context, prefix string) clientv3.WatchChan {
+	if c.mux != nil && c.mux.covers(prefix) {
+		return c.mux.subscribe(ctx, prefix, true)
+	}
+	return c.watcher.Watch(ctx, prefix, clientv3.WithPrefix())
 }
 
-// TryAcquireLock 尝试获取分布式锁
-func (c *Client) TryAcquireLock(lockKey string, ttl int64) (clientv3.LeaseID, error) {
+// WatchWithPrefixFrom 从指定版本开始监听前缀下的键值变化，ctx取消时结束监听，revision为0时从当前版本开始。
+// 需要从历史版本开始，因此不经过监听复用器；opts为附加的监听选项，例如WithPrevKV
+func (c *Client) WatchWithPrefixFrom(ctx context.Context, prefix string, revision int64, opts ...clientv3.OpOption) clientv3.WatchChan {
+	opts = append([]clientv3.OpOption{clientv3.WithPrefix(), clientv3.WithRev(revision)}, opts...)
+	return c.watcher.Watch(ctx, prefix, opts...)
+}
+
+// TryAcquireLock 尝试获取分布式锁，锁的值为持有者标识
+func (c *Client) TryAcquireLock(lockKey, owner string, ttl int64) (leaseID clientv3.LeaseID, err error) {
+	defer c.observe("tryAcquireLock", lockKey, time.Now(), &err)
+
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
@@ -165,7 +301,7 @@ func (c *Client) TryAcquireLock(lockKey string, ttl int64) (clientv3.LeaseID, er
 	// 尝试获取锁（创建key）
 	txn := c.client.Txn(ctx)
 	txn = txn.If(clientv3.Compare(clientv3.CreateRevision(lockKey), "=", 0))
-	txn = txn.Then(clientv3.OpPut(lockKey, "", clientv3.WithLease(leaseResp.ID)))
+	txn = txn.Then(clientv3.OpPut(lockKey, owner, clientv3.WithLease(leaseResp.ID)))
 	txn = txn.Else(clientv3.OpGet(lockKey))
 
 	txnResp, err := txn.Commit()
@@ -173,21 +309,173 @@ func (c *Client) TryAcquireLock(lockKey string, ttl int64) (clientv3.LeaseID, er
 		return 0, common.NewEtcdError("txn", lockKey, err)
 	}
 
-	// 判断事务是否成功
+	// 判断事务是否成功，失败时撤销刚申请的租约
 	if !txnResp.Succeeded {
+		c.lease.Revoke(ctx, leaseResp.ID)
 		return 0, common.ErrLockAlreadyAcquired
 	}
 
 	return leaseResp.ID, nil
 }
 
-// ReleaseLock 释放分布式锁
-func (c *Client) ReleaseLock(lockKey string, leaseID clientv3.LeaseID) error {
+// maxTxnOps 单个事务的最大操作数，与etcd默认的--max-txn-ops一致
+const maxTxnOps = 128
+
+// GrantLease 申请租约
+func (c *Client) GrantLease(ttl int64) (leaseID clientv3.LeaseID, err error) {
+	defer c.observe("lease.grant", "", time.Now(), &err)
+
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
+	leaseResp, err := c.lease.Grant(ctx, ttl)
+	if err != nil {
+		return 0, common.NewEtcdError("lease.grant", "", err)
+	}
+
+	return leaseResp.ID, nil
+}
+
+// RevokeLease 撤销租约，绑定该租约的key会被一起删除
+func (c *Client) RevokeLease(leaseID clientv3.LeaseID) (err error) {
+	defer c.observe("revoke", "", time.Now(), &err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if _, err := c.lease.Revoke(ctx, leaseID); err != nil {
+		return common.NewEtcdError("revoke", "", err)
+	}
+
+	return nil
+}
+
+// LeaseTTL 获取租约剩余时间(秒)，租约不存在或已过期时返回-1
+func (c *Client) LeaseTTL(leaseID clientv3.LeaseID) (ttl int64, err error) {
+	defer c.observe("lease.timeToLive", "", time.Now(), &err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	resp, err := c.lease.TimeToLive(ctx, leaseID)
+	if err != nil {
+		return 0, common.NewEtcdError("lease.timeToLive", "", err)
+	}
+
+	return resp.TTL, nil
+}
+
+// TryAcquireLocks 使用已有租约批量尝试获取分布式锁，每个事务内为每个key嵌套一个独立的比较事务。
+// 锁的值为持有者标识，返回的切片与lockKeys一一对应，表示是否获取成功
+func (c *Client) TryAcquireLocks(lockKeys []string, owner string, leaseID clientv3.LeaseID) (acquired []bool, err error) {
+	defer c.observe("tryAcquireLocks", firstKey(lockKeys), time.Now(), &err)
+
+	acquired = make([]bool, len(lockKeys))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	for start := 0; start < len(lockKeys); start += maxTxnOps {
+		end := min(start+maxTxnOps, len(lockKeys))
+
+		ops := make([]clientv3.Op, 0, end-start)
+		for _, key := range lockKeys[start:end] {
+			ops = append(ops, clientv3.OpTxn(
+				[]clientv3.Cmp{clientv3.Compare(clientv3.CreateRevision(key), "=", 0)},
+				[]clientv3.Op{clientv3.OpPut(key, owner, clientv3.WithLease(leaseID))},
+				nil,
+			))
+		}
+
+		txnResp, err := c.client.Txn(ctx).Then(ops...).Commit()
+		if err != nil {
+			return acquired, common.NewEtcdError("txn", lockKeys[start], err)
+		}
+
+		for i, resp := range txnResp.Responses {
+			acquired[start+i] = resp.GetResponseTxn().GetSucceeded()
+		}
+	}
+
+	return acquired, nil
+}
+
+// PutManyWithLease 在尽量少的事务中写入多个绑定到已有租约的键值
+func (c *Client) PutManyWithLease(kvs map[string]string, leaseID clientv3.LeaseID) (err error) {
+	keys := make([]string, 0, len(kvs))
+	for key := range kvs {
+		keys = append(keys, key)
+	}
+	defer c.observe("putManyWithLease", firstKey(keys), time.Now(), &err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	for start := 0; start < len(keys); start += maxTxnOps {
+		end := min(start+maxTxnOps, len(keys))
+
+		ops := make([]clientv3.Op, 0, end-start)
+		for _, key := range keys[start:end] {
+			ops = append(ops, clientv3.OpPut(key, kvs[key], clientv3.WithLease(leaseID)))
+		}
+
+		if _, err := c.client.Txn(ctx).Then(ops...).Commit(); err != nil {
+			return common.NewEtcdError("txn.put", keys[start], err)
+		}
+	}
+
+	return nil
+}
+
+// ReleaseLocks 在尽量少的事务中删除仍由自己持有的锁，不撤销租约
+func (c *Client) ReleaseLocks(lockKeys []string, owner string, leaseID clientv3.LeaseID) (err error) {
+	defer c.observe("releaseLocks", firstKey(lockKeys), time.Now(), &err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	for start := 0; start < len(lockKeys); start += maxTxnOps {
+		end := min(start+maxTxnOps, len(lockKeys))
+
+		ops := make([]clientv3.Op, 0, end-start)
+		for _, key := range lockKeys[start:end] {
+			ops = append(ops, clientv3.OpTxn(ownedBy(key, owner, leaseID), []clientv3.Op{clientv3.OpDelete(key)}, nil))
+		}
+
+		if _, err := c.client.Txn(ctx).Then(ops...).Commit(); err != nil {
+			return common.NewEtcdError("txn.delete", lockKeys[start], err)
+		}
+	}
+
+	return nil
+}
+
+// ownedBy 锁仍由指定持有者通过指定租约持有的比较条件
+func ownedBy(lockKey, owner string, leaseID clientv3.LeaseID) []clientv3.Cmp {
+	return []clientv3.Cmp{
+		clientv3.Compare(clientv3.Value(lockKey), "=", owner),
+		clientv3.Compare(clientv3.LeaseValue(lockKey), "=", leaseID),
+	}
+}
+
+// ReleaseLock 释放分布式锁：先删除仍由自己持有的锁key，再撤销租约
+func (c *Client) ReleaseLock(lockKey, owner string, leaseID clientv3.LeaseID) (err error) {
+	defer c.observe("releaseLock", lockKey, time.Now(), &err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// 只删除租约和持有者都匹配的锁，避免误删其他worker重新获取的锁
+	_, err = c.client.Txn(ctx).
+		If(ownedBy(lockKey, owner, leaseID)...).
+		Then(clientv3.OpDelete(lockKey)).
+		Commit()
+	if err != nil {
+		return common.NewEtcdError("txn.delete", lockKey, err)
+	}
+
 	// 撤销租约
-	_, err := c.lease.Revoke(ctx, leaseID)
+	_, err = c.lease.Revoke(ctx, leaseID)
 	if err != nil {
 		return common.NewEtcdError("revoke", lockKey, err)
 	}
@@ -195,12 +483,73 @@ func (c *Client) ReleaseLock(lockKey string, leaseID clientv3.LeaseID) error {
 	return nil
 }
 
+// ApplyIfUnchanged 仅当key的修改版本仍为modRevision时执行ops，modRevision为0表示key不存在。
+// 返回是否执行成功
+func (c *Client) ApplyIfUnchanged(key string, modRevision int64, ops ...clientv3.Op) (applied bool, err error) {
+	revision, err := c.ApplyIfUnchangedAt(key, modRevision, ops...)
+	return revision > 0, err
+}
+
+// ApplyIfUnchangedAt 与ApplyIfUnchanged相同，执行成功时返回事务提交后的集群修订版本，未执行时返回0
+func (c *Client) ApplyIfUnchangedAt(key string, modRevision int64, ops ...clientv3.Op) (revision int64, err error) {
+	defer c.observe("applyIfUnchanged", key, time.Now(), &err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	txnResp, err := c.client.Txn(ctx).
+		If(clientv3.Compare(clientv3.ModRevision(key), "=", modRevision)).
+		Then(ops...).
+		Commit()
+	if err != nil {
+		return 0, common.NewEtcdError("txn", key, err)
+	}
+	if !txnResp.Succeeded {
+		return 0, nil
+	}
+
+	return txnResp.Header.Revision, nil
+}
+
+// ApplyIfAllUnchanged 仅当所有key的修改版本都与revisions一致时执行ops，版本为0表示key不存在。
+// 返回是否执行成功
+func (c *Client) ApplyIfAllUnchanged(revisions map[string]int64, ops ...clientv3.Op) (applied bool, err error) {
+	revision, err := c.ApplyIfAllUnchangedAt(revisions, ops...)
+	return revision > 0, err
+}
+
+// ApplyIfAllUnchangedAt 与ApplyIfAllUnchanged相同，执行成功时返回事务提交后的集群修订版本，未执行时返回0
+func (c *Client) ApplyIfAllUnchangedAt(revisions map[string]int64, ops ...clientv3.Op) (revision int64, err error) {
+	keys := make([]string, 0, len(revisions))
+	cmps := make([]clientv3.Cmp, 0, len(revisions))
+	for key, modRevision := range revisions {
+		keys = append(keys, key)
+		cmps = append(cmps, clientv3.Compare(clientv3.ModRevision(key), "=", modRevision))
+	}
+	defer c.observe("applyIfAllUnchanged", firstKey(keys), time.Now(), &err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	txnResp, err := c.client.Txn(ctx).If(cmps...).Then(ops...).Commit()
+	if err != nil {
+		return 0, common.NewEtcdError("txn", firstKey(keys), err)
+	}
+	if !txnResp.Succeeded {
+		return 0, nil
+	}
+
+	return txnResp.Header.Revision, nil
+}
+
 // DeleteWithPrefix 删除前缀匹配的所有键值
-func (c *Client) DeleteWithPrefix(prefix string) (*clientv3.DeleteResponse, error) {
+func (c *Client) DeleteWithPrefix(prefix string) (resp *clientv3.DeleteResponse, err error) {
+	defer c.observe("deleteWithPrefix", prefix, time.Now(), &err)
+
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	resp, err := c.kv.Delete(ctx, prefix, clientv3.WithPrefix())
+	resp, err = c.kv.Delete(ctx, prefix, clientv3.WithPrefix())
 	if err != nil {
 		return nil, common.NewEtcdError("deleteWithPrefix", prefix, err)
 	}
@@ -0,0 +1,179 @@
+package etcd
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"go.etcd.io/etcd/client/v3"
+)
+
+// watchSubBuffer 每个订阅者的事件缓冲大小，缓冲满的订阅者会被移除，需要重新同步
+const watchSubBuffer = 100
+
+// watchMux 监听复用器，用一个底层watch监听根前缀，按key将事件分发给各订阅者
+type watchMux struct {
+	watcher    clientv3.Watcher   // 底层监听器
+	root       string             // 底层监听的根前缀
+	subs       []*watchSub        // 订阅者
+	lock       sync.Mutex         // 保护subs
+	revision   int64              // 已分发到的版本，重建监听时从下一版本继续
+	ctx        context.Context    // 上下文，用于控制退出
+	cancelFunc context.CancelFunc // 取消函数
+}
+
+// watchSub 订阅者，prefix为true时按前缀匹配，否则精确匹配key
+type watchSub struct {
+	key    string
+	prefix bool
+	ch     chan clientv3.WatchResponse
+	done   chan struct{} // 订阅者被移除时关闭
+}
+
+// newWatchMux 创建并启动监听复用器
+func newWatchMux(watcher clientv3.Watcher, root string) *watchMux {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	m := &watchMux{
+		watcher:    watcher,
+		root:       root,
+		ctx:        ctx,
+		cancelFunc: cancel,
+	}
+	go m.run()
+
+	return m
+}
+
+// covers 判断key是否在复用器监听范围内
+func (m *watchMux) covers(key string) bool {
+	return strings.HasPrefix(key, m.root)
+}
+
+// subscribe 注册订阅者，ctx取消时移除订阅并关闭通道。订阅者处理过慢或底层监听出错（如版本被压缩）时通道也会被关闭，
+// 订阅者需要重新订阅并重新加载全量数据。复用器停止后通道不再有事件
+func (m *watchMux) subscribe(ctx context.Context, key string, prefix bool) clientv3.WatchChan {
+	sub := &watchSub{
+		key:    key,
+		prefix: prefix,
+		ch:     make(chan clientv3.WatchResponse, watchSubBuffer),
+		done:   make(chan struct{}),
+	}
+
+	m.lock.Lock()
+	m.subs = append(m.subs, sub)
+	m.lock.Unlock()
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			m.unsubscribe(sub)
+		case <-sub.done:
+		case <-m.ctx.Done():
+		}
+	}()
+
+	return sub.ch
+}
+
+// unsubscribe 移除订阅者并关闭其通道，订阅者已被移除时忽略
+func (m *watchMux) unsubscribe(sub *watchSub) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	for i, s := range m.subs {
+		if s == sub {
+			m.subs = append(m.subs[:i], m.subs[i+1:]...)
+			sub.close()
+			return
+		}
+	}
+}
+
+// run 维持底层监听，监听中断后从已分发的版本之后重新监听
+func (m *watchMux) run() {
+	for {
+		opts := []clientv3.OpOption{clientv3.WithPrefix()}
+		if m.revision > 0 {
+			opts = append(opts, clientv3.WithRev(m.revision+1))
+		}
+
+		for watchResp := range m.watcher.Watch(m.ctx, m.root, opts...) {
+			m.dispatch(watchResp)
+
+			if watchResp.CompactRevision != 0 {
+				// 需要的版本已被压缩，订阅者的通道已关闭并自行重新同步。从压缩版本重新监听，
+				// 订阅者重新加载的版本不早于压缩版本，不会遗漏重新加载之后的事件
+				m.revision = watchResp.CompactRevision - 1
+			} else if watchResp.Err() == nil {
+				m.revision = watchResp.Header.Revision
+			}
+		}
+
+		select {
+		case <-m.ctx.Done():
+			return
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// dispatch 将事件分发给匹配的订阅者。发送不阻塞，缓冲已满的订阅者被移除并关闭通道，
+// 不会拖慢其他订阅者；监听出错时事件可能已经丢失，关闭所有订阅者的通道
+func (m *watchMux) dispatch(watchResp clientv3.WatchResponse) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if watchResp.Err() != nil {
+		for _, sub := range m.subs {
+			sub.close()
+		}
+		m.subs = nil
+		return
+	}
+
+	kept := m.subs[:0]
+	for _, sub := range m.subs {
+		resp := watchResp
+		resp.Events = nil
+		for _, event := range watchResp.Events {
+			if sub.match(string(event.Kv.Key)) {
+				resp.Events = append(resp.Events, event)
+			}
+		}
+
+		if len(resp.Events) > 0 {
+			select {
+			case sub.ch <- resp:
+			default:
+				sub.close()
+				continue
+			}
+		}
+		kept = append(kept, sub)
+	}
+	for i := len(kept); i < len(m.subs); i++ {
+		m.subs[i] = nil
+	}
+	m.subs = kept
+}
+
+// match 判断key是否属于该订阅者
+func (s *watchSub) match(key string) bool {
+	if s.prefix {
+		return strings.HasPrefix(key, s.key)
+	}
+	return key == s.key
+}
+
+// close 关闭订阅者的通道，调用方需持有复用器的锁
+func (s *watchSub) close() {
+	close(s.ch)
+	close(s.done)
+}
+
+// stop 停止监听复用器
+func (m *watchMux) stop() {
+	m.cancelFunc()
+}
@@ -0,0 +1,122 @@
+package etcd
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	"go.etcd.io/etcd/client/v3"
+)
+
+func newTestMux() *watchMux {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &watchMux{root: "/cron/", ctx: ctx, cancelFunc: cancel}
+}
+
+func putEvent(key string) *clientv3.Event {
+	return &clientv3.Event{
+		Type: mvccpb.PUT,
+		Kv:   &mvccpb.KeyValue{Key: []byte(key)},
+	}
+}
+
+func TestWatchMux_Dispatch(t *testing.T) {
+	m := newTestMux()
+	defer m.stop()
+
+	jobs := m.subscribe(context.Background(), "/cron/jobs/", true)
+	killSwitch := m.subscribe(context.Background(), "/cron/killswitch/w1", false)
+
+	m.dispatch(clientv3.WatchResponse{Events: []*clientv3.Event{
+		putEvent("/cron/jobs/a"),
+		putEvent("/cron/lock/a"),
+		putEvent("/cron/killswitch/w1"),
+		putEvent("/cron/killswitch/w10"),
+		putEvent("/cron/jobs/b"),
+	}})
+
+	// 前缀订阅者只收到自己前缀下的事件
+	require.Len(t, jobs, 1)
+	resp := <-jobs
+	require.Len(t, resp.Events, 2)
+	assert.Equal(t, "/cron/jobs/a", string(resp.Events[0].Kv.Key))
+	assert.Equal(t, "/cron/jobs/b", string(resp.Events[1].Kv.Key))
+
+	// 精确订阅者不匹配相同前缀的其他key
+	require.Len(t, killSwitch, 1)
+	resp = <-killSwitch
+	require.Len(t, resp.Events, 1)
+	assert.Equal(t, "/cron/killswitch/w1", string(resp.Events[0].Kv.Key))
+
+	// 没有匹配事件时不通知订阅者
+	m.dispatch(clientv3.WatchResponse{Events: []*clientv3.Event{putEvent("/cron/lock/b")}})
+	assert.Len(t, jobs, 0)
+	assert.Len(t, killSwitch, 0)
+}
+
+func TestWatchMux_DispatchError(t *testing.T) {
+	m := newTestMux()
+	defer m.stop()
+
+	jobs := m.subscribe(context.Background(), "/cron/jobs/", true)
+	policy := m.subscribe(context.Background(), "/cron/policy/", true)
+
+	// 版本被压缩时关闭所有订阅者的通道，订阅者需要重新同步
+	m.dispatch(clientv3.WatchResponse{CompactRevision: 10})
+
+	for _, ch := range []clientv3.WatchChan{jobs, policy} {
+		_, ok := <-ch
+		assert.False(t, ok, "Subscribers should be closed after a watch error")
+	}
+	assert.Empty(t, m.subs)
+}
+
+func TestWatchMux_SlowSubscriber(t *testing.T) {
+	m := newTestMux()
+	defer m.stop()
+
+	slow := m.subscribe(context.Background(), "/cron/jobs/", true)
+	fast := m.subscribe(context.Background(), "/cron/jobs/", true)
+
+	// 慢订阅者的缓冲填满后再分发一次，慢订阅者被关闭，不阻塞其他订阅者
+	for i := 0; i <= watchSubBuffer; i++ {
+		m.dispatch(clientv3.WatchResponse{Events: []*clientv3.Event{putEvent("/cron/jobs/a")}})
+		<-fast
+	}
+
+	require.Len(t, m.subs, 1)
+	for range slow {
+	}
+
+	m.dispatch(clientv3.WatchResponse{Events: []*clientv3.Event{putEvent("/cron/jobs/b")}})
+	resp := <-fast
+	require.Len(t, resp.Events, 1)
+	assert.Equal(t, "/cron/jobs/b", string(resp.Events[0].Kv.Key))
+}
+
+func TestWatchMux_Unsubscribe(t *testing.T) {
+	m := newTestMux()
+	defer m.stop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	jobs := m.subscribe(ctx, "/cron/jobs/", true)
+
+	// ctx取消后订阅被移除，通道关闭
+	cancel()
+	_, ok := <-jobs
+	assert.False(t, ok)
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	assert.Empty(t, m.subs)
+}
+
+func TestWatchMux_Covers(t *testing.T) {
+	m := newTestMux()
+	defer m.stop()
+
+	assert.True(t, m.covers("/cron/jobs/"))
+	assert.False(t, m.covers("/other/jobs/"))
+}
@@ -0,0 +1,214 @@
+package expr
+
+import (
+	"fmt"
+	"math"
+)
+
+// node 语法树节点
+type node interface {
+	eval(env Env) (any, error)
+	walk(fn func(node))
+}
+
+// literalNode 字面量
+type literalNode struct {
+	value any
+}
+
+func (n *literalNode) eval(Env) (any, error) {
+	return n.value, nil
+}
+
+func (n *literalNode) walk(fn func(node)) {
+	fn(n)
+}
+
+// identNode 变量
+type identNode struct {
+	name string
+}
+
+func (n *identNode) eval(env Env) (any, error) {
+	value, ok := env[n.name]
+	if !ok {
+		return nil, fmt.Errorf("unknown variable %s", n.name)
+	}
+	return normalize(value)
+}
+
+func (n *identNode) walk(fn func(node)) {
+	fn(n)
+}
+
+// unaryNode 一元运算
+type unaryNode struct {
+	op      string
+	operand node
+}
+
+func (n *unaryNode) eval(env Env) (any, error) {
+	value, err := n.operand.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	switch v := value.(type) {
+	case bool:
+		if n.op == "!" {
+			return !v, nil
+		}
+	case float64:
+		if n.op == "-" {
+			return -v, nil
+		}
+	}
+	return nil, fmt.Errorf("operator %s does not apply to %s", n.op, typeName(value))
+}
+
+func (n *unaryNode) walk(fn func(node)) {
+	fn(n)
+	n.operand.walk(fn)
+}
+
+// logicalNode && 和 ||，短路求值
+type logicalNode struct {
+	and         bool
+	left, right node
+}
+
+func (n *logicalNode) eval(env Env) (any, error) {
+	left, err := n.evalBool(n.left, env)
+	if err != nil {
+		return nil, err
+	}
+	if left != n.and {
+		return left, nil
+	}
+	return n.evalBool(n.right, env)
+}
+
+// evalBool 求值操作数并要求为布尔值
+func (n *logicalNode) evalBool(operand node, env Env) (bool, error) {
+	value, err := operand.eval(env)
+	if err != nil {
+		return false, err
+	}
+	result, ok := value.(bool)
+	if !ok {
+		op := "||"
+		if n.and {
+			op = "&&"
+		}
+		return false, fmt.Errorf("operator %s does not apply to %s", op, typeName(value))
+	}
+	return result, nil
+}
+
+func (n *logicalNode) walk(fn func(node)) {
+	fn(n)
+	n.left.walk(fn)
+	n.right.walk(fn)
+}
+
+// binaryNode 比较和算术运算
+type binaryNode struct {
+	op          string
+	left, right node
+}
+
+func (n *binaryNode) eval(env Env) (any, error) {
+	left, err := n.left.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	right, err := n.right.eval(env)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.op {
+	case "==":
+		if typeName(left) != typeName(right) {
+			return nil, n.mismatch(left, right)
+		}
+		return left == right, nil
+	case "!=":
+		if typeName(left) != typeName(right) {
+			return nil, n.mismatch(left, right)
+		}
+		return left != right, nil
+	}
+
+	switch l := left.(type) {
+	case float64:
+		r, ok := right.(float64)
+		if !ok {
+			return nil, n.mismatch(left, right)
+		}
+		return n.evalNumbers(l, r)
+	case string:
+		r, ok := right.(string)
+		if !ok {
+			return nil, n.mismatch(left, right)
+		}
+		return n.evalStrings(l, r)
+	}
+	return nil, n.mismatch(left, right)
+}
+
+// evalNumbers 数字的比较和算术运算，除数为0时出错
+func (n *binaryNode) evalNumbers(l, r float64) (any, error) {
+	switch n.op {
+	case "<":
+		return l < r, nil
+	case "<=":
+		return l <= r, nil
+	case ">":
+		return l > r, nil
+	case ">=":
+		return l >= r, nil
+	case "+":
+		return l + r, nil
+	case "-":
+		return l - r, nil
+	case "*":
+		return l * r, nil
+	case "/", "%":
+		if r == 0 {
+			return nil, fmt.Errorf("division by zero")
+		}
+		if n.op == "%" {
+			return math.Mod(l, r), nil
+		}
+		return l / r, nil
+	}
+	return nil, n.mismatch(l, r)
+}
+
+// evalStrings 字符串的比较和拼接
+func (n *binaryNode) evalStrings(l, r string) (any, error) {
+	switch n.op {
+	case "<":
+		return l < r, nil
+	case "<=":
+		return l <= r, nil
+	case ">":
+		return l > r, nil
+	case ">=":
+		return l >= r, nil
+	case "+":
+		return l + r, nil
+	}
+	return nil, n.mismatch(l, r)
+}
+
+// mismatch 运算符与操作数类型不匹配的错误
+func (n *binaryNode) mismatch(left, right any) error {
+	return fmt.Errorf("operator %s does not apply to %s and %s", n.op, typeName(left), typeName(right))
+}
+
+func (n *binaryNode) walk(fn func(node)) {
+	fn(n)
+	n.left.walk(fn)
+	n.right.walk(fn)
+}
@@ -0,0 +1,114 @@
+// Package expr 实现任务when条件使用的表达式语言。
+//
+// 支持数字、字符串（单引号或双引号）、true/false字面量，以点分隔的变量名，
+// 运算符 ! - * / % + < <= > >= == != && || 和括号，&&和||短路求值。
+// 值只有数字(float64)、字符串和布尔三种类型，不做隐式转换，类型不匹配时求值出错
+package expr
+
+import (
+	"fmt"
+	"sort"
+)
+
+// MaxLength 表达式的最大长度
+const MaxLength = 1024
+
+// Env 求值时的变量，key为完整的变量名，例如lastRun.failed
+type Env map[string]any
+
+// Expr 解析后的表达式，可以并发求值
+type Expr struct {
+	src  string
+	root node
+}
+
+// Parse 解析表达式
+func Parse(src string) (*Expr, error) {
+	if len(src) > MaxLength {
+		return nil, fmt.Errorf("expression must not exceed %d characters", MaxLength)
+	}
+	tokens, err := tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, fmt.Errorf("unexpected %s at position %d", tok, tok.pos)
+	}
+	return &Expr{src: src, root: root}, nil
+}
+
+// String 返回表达式原文
+func (e *Expr) String() string {
+	return e.src
+}
+
+// Identifiers 返回表达式引用的变量名，已排序去重，用于保存时校验变量是否存在
+func (e *Expr) Identifiers() []string {
+	seen := make(map[string]bool)
+	e.root.walk(func(n node) {
+		if id, ok := n.(*identNode); ok {
+			seen[id.name] = true
+		}
+	})
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Eval 求值，引用了env中不存在的变量时出错。整数类型的变量按数字处理
+func (e *Expr) Eval(env Env) (any, error) {
+	return e.root.eval(env)
+}
+
+// EvalBool 求值并要求结果为布尔值
+func (e *Expr) EvalBool(env Env) (bool, error) {
+	value, err := e.Eval(env)
+	if err != nil {
+		return false, err
+	}
+	result, ok := value.(bool)
+	if !ok {
+		return false, fmt.Errorf("expression must evaluate to a bool, got %s", typeName(value))
+	}
+	return result, nil
+}
+
+// normalize 将变量的值转换为表达式支持的类型
+func normalize(value any) (any, error) {
+	switch v := value.(type) {
+	case bool, string, float64:
+		return v, nil
+	case int:
+		return float64(v), nil
+	case int32:
+		return float64(v), nil
+	case int64:
+		return float64(v), nil
+	case float32:
+		return float64(v), nil
+	default:
+		return nil, fmt.Errorf("unsupported value type %T", value)
+	}
+}
+
+// typeName 值的类型名，用于错误信息
+func typeName(value any) string {
+	switch value.(type) {
+	case bool:
+		return "bool"
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
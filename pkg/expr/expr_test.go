@@ -0,0 +1,96 @@
+package expr
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEval(t *testing.T) {
+	env := Env{
+		"lastRun.failed": true,
+		"lastRun.status": "failed",
+		"now.hour":       9,
+		"worker.load1":   0.5,
+	}
+
+	tests := []struct {
+		src  string
+		want any
+	}{
+		{"lastRun.failed", true},
+		{"!lastRun.failed", false},
+		{`lastRun.status == "failed"`, true},
+		{`lastRun.status != 'success'`, true},
+		{"now.hour >= 9 && now.hour < 18", true},
+		{"now.hour < 9 || worker.load1 < 1", true},
+		{"1 + 2 * 3", 7.0},
+		{"(1 + 2) * 3", 9.0},
+		{"-now.hour % 4", -1.0},
+		{"10 - 4 - 3", 3.0},
+		{`"a" + "b" == "ab"`, true},
+		{"true == false", false},
+	}
+	for _, tt := range tests {
+		e, err := Parse(tt.src)
+		require.NoError(t, err, tt.src)
+		got, err := e.Eval(env)
+		require.NoError(t, err, tt.src)
+		assert.Equal(t, tt.want, got, tt.src)
+	}
+}
+
+func TestEval_ShortCircuit(t *testing.T) {
+	e, err := Parse("false && missing > 1")
+	require.NoError(t, err)
+	got, err := e.EvalBool(Env{})
+	require.NoError(t, err)
+	assert.False(t, got, "Right side should not be evaluated")
+}
+
+func TestEval_Errors(t *testing.T) {
+	env := Env{"n": 1, "s": "x"}
+	for _, src := range []string{
+		"missing",
+		"n == s",
+		"n && true",
+		"!n",
+		"-s",
+		"s * 2",
+		"n / 0",
+	} {
+		e, err := Parse(src)
+		require.NoError(t, err, src)
+		_, err = e.Eval(env)
+		assert.Error(t, err, src)
+	}
+
+	e, err := Parse("n + 1")
+	require.NoError(t, err)
+	_, err = e.EvalBool(env)
+	assert.Error(t, err, "Non-bool result should be rejected")
+}
+
+func TestParse_Errors(t *testing.T) {
+	for _, src := range []string{
+		"",
+		"a &&",
+		"(a",
+		"a b",
+		`"open`,
+		"a.",
+		"1.2.3",
+		"a # b",
+		`"\q"`,
+	} {
+		_, err := Parse(src)
+		assert.Error(t, err, src)
+	}
+}
+
+func TestIdentifiers(t *testing.T) {
+	e, err := Parse("b > 1 && (a || !b) && true")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, e.Identifiers())
+}
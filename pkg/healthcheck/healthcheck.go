@@ -0,0 +1,32 @@
+package healthcheck
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Path 健康检查接口路径，master在API端口、worker在管理接口端口提供
+const Path = "/healthz"
+
+// Timeout 探测超时时间，应短于容器HEALTHCHECK的timeout
+const Timeout = 3 * time.Second
+
+// Probe 请求本机指定端口的健康检查接口，返回200以外的结果时返回错误
+func Probe(port int) error {
+	if port <= 0 {
+		return fmt.Errorf("health endpoint port is not configured")
+	}
+
+	client := &http.Client{Timeout: Timeout}
+	resp, err := client.Get(fmt.Sprintf("http://127.0.0.1:%d%s", port, Path))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+package healthcheck
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProbe(t *testing.T) {
+	var healthy atomic.Bool
+	healthy.Store(true)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != Path || !healthy.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	_, portText, err := net.SplitHostPort(server.Listener.Addr().String())
+	require.NoError(t, err)
+	port, err := net.LookupPort("tcp", portText)
+	require.NoError(t, err)
+
+	assert.NoError(t, Probe(port))
+
+	healthy.Store(false)
+	assert.ErrorContains(t, Probe(port), "unexpected status 503")
+
+	assert.ErrorContains(t, Probe(0), "not configured")
+}
@@ -0,0 +1,193 @@
+package logging
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/fyerfyer/scheduler-refactor/config"
+)
+
+// 可以单独设置日志级别的组件
+const (
+	ComponentAPI       = "api"       // master的API服务
+	ComponentScheduler = "scheduler" // worker的调度器
+	ComponentExecutor  = "executor"  // worker的执行器
+	ComponentLogSink   = "logsink"   // worker的日志写入
+	ComponentEtcd      = "etcd"      // etcd客户端的慢操作日志
+)
+
+// 日志格式
+const (
+	EncodingJSON    = "json"    // 每行一个JSON对象，便于日志系统采集
+	EncodingConsole = "console" // 便于人工阅读的文本格式
+)
+
+// components 已知的组件
+var components = map[string]bool{
+	ComponentAPI:       true,
+	ComponentScheduler: true,
+	ComponentExecutor:  true,
+	ComponentLogSink:   true,
+	ComponentEtcd:      true,
+}
+
+// Loggers 进程的根日志和按组件设置了级别的日志。所有日志共用一个输出，
+// 底层按最低的级别创建，各日志再分别提高到自己的级别
+type Loggers struct {
+	base   *zap.Logger              // 按最低级别创建的日志
+	min    zapcore.Level            // 所有级别中最低的级别
+	root   *zap.Logger              // 未单独设置级别的组件使用的日志
+	levels map[string]zapcore.Level // 组件的日志级别
+}
+
+// New 按配置创建日志：logLevel为默认级别，logLevels按组件覆盖，
+// logSampleInitial和logSampleThereafter控制每秒重复日志的采样
+func New(cfg *config.Config) (*Loggers, error) {
+	rootLevel, levels, err := parseLevels(cfg)
+	if err != nil {
+		return nil, err
+	}
+	encoding, err := parseEncoding(cfg.LogEncoding)
+	if err != nil {
+		return nil, err
+	}
+
+	minLevel := rootLevel
+	for _, level := range levels {
+		minLevel = min(minLevel, level)
+	}
+
+	zapConfig := zap.Config{
+		Level:            zap.NewAtomicLevelAt(minLevel),
+		Encoding:         encoding,
+		EncoderConfig:    encoderConfig(),
+		OutputPaths:      []string{"stderr"},
+		ErrorOutputPaths: []string{"stderr"},
+	}
+	// 采样按消息和级别计数，锁竞争等重复日志每秒只输出前几条和之后的少数几条
+	if cfg.LogSampleInitial > 0 {
+		zapConfig.Sampling = &zap.SamplingConfig{
+			Initial:    cfg.LogSampleInitial,
+			Thereafter: cfg.LogSampleThereafter,
+		}
+	}
+
+	base, err := zapConfig.Build()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Loggers{
+		base:   base,
+		min:    minLevel,
+		root:   withLevel(base, minLevel, rootLevel),
+		levels: levels,
+	}, nil
+}
+
+// Logger 返回根日志
+func (l *Loggers) Logger() *zap.Logger {
+	return l.root
+}
+
+// Component 返回组件的日志，日志带有组件名，未单独设置级别时使用默认级别
+func (l *Loggers) Component(name string) *zap.Logger {
+	level, ok := l.levels[name]
+	if !ok {
+		return l.root.Named(name)
+	}
+	return withLevel(l.base, l.min, level).Named(name)
+}
+
+// Sync 刷新缓冲的日志
+func (l *Loggers) Sync() error {
+	return l.base.Sync()
+}
+
+// Validate 校验日志配置，返回第一个不合法的配置项
+func Validate(cfg *config.Config) error {
+	if _, _, err := parseLevels(cfg); err != nil {
+		return err
+	}
+	if _, err := parseEncoding(cfg.LogEncoding); err != nil {
+		return err
+	}
+	if cfg.LogSampleInitial < 0 || cfg.LogSampleThereafter < 0 {
+		return fmt.Errorf("logSampleInitial and logSampleThereafter must not be negative")
+	}
+	return nil
+}
+
+// withLevel 将按minLevel创建的日志提高到level
+func withLevel(logger *zap.Logger, minLevel, level zapcore.Level) *zap.Logger {
+	if level <= minLevel {
+		return logger
+	}
+	return logger.WithOptions(zap.IncreaseLevel(level))
+}
+
+// parseLevels 解析默认级别和组件级别，默认级别为空时为info
+func parseLevels(cfg *config.Config) (zapcore.Level, map[string]zapcore.Level, error) {
+	rootLevel := zapcore.InfoLevel
+	if cfg.LogLevel != "" {
+		if err := rootLevel.Set(cfg.LogLevel); err != nil {
+			return rootLevel, nil, fmt.Errorf("invalid logLevel %q", cfg.LogLevel)
+		}
+	}
+
+	levels := make(map[string]zapcore.Level, len(cfg.LogLevels))
+	for name, text := range cfg.LogLevels {
+		if !components[name] {
+			return rootLevel, nil, fmt.Errorf("unknown logLevels component %q, expected one of %s", name, componentNames())
+		}
+		var level zapcore.Level
+		if err := level.Set(text); err != nil {
+			return rootLevel, nil, fmt.Errorf("invalid logLevels level %q for %s", text, name)
+		}
+		levels[name] = level
+	}
+	return rootLevel, levels, nil
+}
+
+// parseEncoding 解析日志格式，为空时为json
+func parseEncoding(encoding string) (string, error) {
+	switch encoding {
+	case "", EncodingJSON:
+		return EncodingJSON, nil
+	case EncodingConsole:
+		return EncodingConsole, nil
+	default:
+		return "", fmt.Errorf("invalid logEncoding %q, expected json or console", encoding)
+	}
+}
+
+// componentNames 已知组件名，用于错误提示
+func componentNames() string {
+	names := make([]string, 0, len(components))
+	for name := range components {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, "/")
+}
+
+// encoderConfig master和worker共用的日志编码配置
+func encoderConfig() zapcore.EncoderConfig {
+	return zapcore.EncoderConfig{
+		TimeKey:        "time",
+		LevelKey:       "level",
+		NameKey:        "logger",
+		CallerKey:      "caller",
+		MessageKey:     "msg",
+		StacktraceKey:  "stacktrace",
+		LineEnding:     zapcore.DefaultLineEnding,
+		EncodeLevel:    zapcore.LowercaseLevelEncoder,
+		EncodeTime:     zapcore.ISO8601TimeEncoder,
+		EncodeDuration: zapcore.SecondsDurationEncoder,
+		EncodeCaller:   zapcore.ShortCallerEncoder,
+	}
+}
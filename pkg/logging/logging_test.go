@@ -0,0 +1,43 @@
+package logging
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/fyerfyer/scheduler-refactor/config"
+)
+
+func TestComponentLevels(t *testing.T) {
+	cfg := &config.Config{
+		LogLevel:  "warn",
+		LogLevels: map[string]string{ComponentScheduler: "debug", ComponentEtcd: "error"},
+	}
+	loggers, err := New(cfg)
+	require.NoError(t, err)
+
+	assert.False(t, loggers.Logger().Core().Enabled(zapcore.InfoLevel))
+	assert.True(t, loggers.Logger().Core().Enabled(zapcore.WarnLevel))
+	assert.True(t, loggers.Component(ComponentScheduler).Core().Enabled(zapcore.DebugLevel))
+	assert.False(t, loggers.Component(ComponentEtcd).Core().Enabled(zapcore.WarnLevel))
+	assert.False(t, loggers.Component(ComponentAPI).Core().Enabled(zapcore.InfoLevel),
+		"Components without their own level should use the default level")
+}
+
+func TestValidate(t *testing.T) {
+	assert.NoError(t, Validate(&config.Config{}), "Empty settings should use the defaults")
+	assert.NoError(t, Validate(&config.Config{LogLevel: "debug", LogEncoding: EncodingConsole}))
+
+	err := Validate(&config.Config{LogLevels: map[string]string{"sched": "debug"}})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `unknown logLevels component "sched"`)
+
+	assert.Error(t, Validate(&config.Config{LogLevel: "verbose"}))
+	assert.Error(t, Validate(&config.Config{LogEncoding: "text"}))
+	assert.Error(t, Validate(&config.Config{LogSampleInitial: -1}))
+
+	_, err = New(&config.Config{LogEncoding: "text"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,82 @@
+package logstore
+
+import (
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+	"github.com/fyerfyer/scheduler-refactor/config"
+	"github.com/fyerfyer/scheduler-refactor/pkg/mongodb"
+	"github.com/fyerfyer/scheduler-refactor/pkg/sqlstore"
+)
+
+// 日志存储后端类型
+const (
+	BackendMongoDB  = "mongodb"  // MongoDB后端（默认）
+	BackendSQLite   = "sqlite"   // SQLite后端，适用于单节点部署
+	BackendPostgres = "postgres" // Postgres后端，适用于高可用部署
+)
+
+// LogStore 任务日志存储接口，master的logmgr和worker的logsink都通过它读写日志
+type LogStore interface {
+	// InsertLogs 批量写入任务日志
+	InsertLogs(logs []*common.JobLog) error
+
+	// FindJobLogs 按开始时间倒序分页查询任务日志，jobName为空时查询全部，scope为nil时不限制范围
+	FindJobLogs(jobName string, scope *common.Scope, skip, limit int64) ([]*common.JobLog, error)
+
+	// FindJobLogsByNames 按开始时间倒序分页查询多个任务名下的日志，用于连同改名前的旧名称一起查询
+	FindJobLogsByNames(jobNames []string, scope *common.Scope, skip, limit int64) ([]*common.JobLog, error)
+
+	// CountJobLogs 统计任务日志总数
+	CountJobLogs(jobName string, scope *common.Scope) (int64, error)
+
+	// FindJobLogsSince 查询指定时间之后开始的任务日志
+	FindJobLogsSince(jobName string, scope *common.Scope, timestamp int64) ([]*common.JobLog, error)
+
+	// FindJobLogsEndedSince 按结束时间升序查询指定时间之后结束的任务日志，最多返回limit条，用于跟踪新写入的日志
+	FindJobLogsEndedSince(jobName string, scope *common.Scope, timestamp, limit int64) ([]*common.JobLog, error)
+
+	// FindLogByRunID 查询指定执行的日志，同一执行有多条日志时返回最近开始的一条，没有时返回nil
+	FindLogByRunID(runID string, scope *common.Scope) (*common.JobLog, error)
+
+	// DeleteOldLogs 删除结束时间早于指定时间的日志，返回删除数量
+	DeleteOldLogs(before time.Time) (int64, error)
+
+	// CountOldLogs 统计结束时间早于指定时间的日志数量，用于预览清理结果
+	CountOldLogs(before time.Time) (int64, error)
+
+	// UpsertDailyStats 写入按天汇总的统计，同一任务同一天的记录会被覆盖
+	UpsertDailyStats(stats []*common.JobDailyStats) error
+
+	// FindDailyStats 按日期升序查询[from, to)内的按天统计，jobName为空时查询全部
+	FindDailyStats(jobName string, scope *common.Scope, from, to int64) ([]*common.JobDailyStats, error)
+
+	// Close 关闭连接
+	Close() error
+}
+
+// NewLogStore 根据配置创建日志存储，logger用于记录慢查询
+func NewLogStore(logger *zap.Logger) (LogStore, error) {
+	cfg := config.GlobalConfig
+
+	switch cfg.LogBackend {
+	case "", BackendMongoDB:
+		client, err := mongodb.NewClient()
+		if err != nil {
+			return nil, err
+		}
+		client.SetLogger(logger)
+		return client, nil
+	case BackendSQLite, BackendPostgres:
+		client, err := sqlstore.NewClient(cfg.LogBackend, cfg.LogDSN)
+		if err != nil {
+			return nil, err
+		}
+		return client, nil
+	default:
+		return nil, fmt.Errorf("unsupported log backend: %s", cfg.LogBackend)
+	}
+}
@@ -0,0 +1,103 @@
+package metrics
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// LatencyBuckets 延迟直方图各桶的上界(毫秒)，超过最后一个上界的计入溢出桶
+var LatencyBuckets = []float64{1, 5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000}
+
+// OpStats 单个操作的统计
+type OpStats struct {
+	Count    int64   `json:"count"`              // 调用次数
+	Errors   int64   `json:"errors"`             // 出错次数
+	AvgMs    float64 `json:"avgMs"`              // 平均耗时(毫秒)
+	MaxMs    float64 `json:"maxMs"`              // 最大耗时(毫秒)
+	Buckets  []int64 `json:"buckets"`            // 落在每个延迟桶内的次数，与LatencyBuckets对应，最后一个为溢出桶
+	Items    int64   `json:"items,omitempty"`    // 累计处理的条目数，如批量写入的文档数
+	MaxItems int64   `json:"maxItems,omitempty"` // 单次最多处理的条目数
+}
+
+// Recorder 按操作名记录调用延迟和错误
+type Recorder struct {
+	lock sync.Mutex
+	ops  map[string]*OpStats
+}
+
+var (
+	registry     = make(map[string]*Recorder)
+	registryLock sync.Mutex
+)
+
+// NewRecorder 创建并注册记录器，同名记录器已存在时直接返回
+func NewRecorder(name string) *Recorder {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+
+	if r, exists := registry[name]; exists {
+		return r
+	}
+
+	r := &Recorder{ops: make(map[string]*OpStats)}
+	registry[name] = r
+	return r
+}
+
+// Observe 记录一次调用
+func (r *Recorder) Observe(op string, latency time.Duration, err error) {
+	r.ObserveBatch(op, latency, 0, err)
+}
+
+// ObserveBatch 记录一次处理了items个条目的调用
+func (r *Recorder) ObserveBatch(op string, latency time.Duration, items int, err error) {
+	ms := float64(latency) / float64(time.Millisecond)
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	stats, exists := r.ops[op]
+	if !exists {
+		stats = &OpStats{Buckets: make([]int64, len(LatencyBuckets)+1)}
+		r.ops[op] = stats
+	}
+
+	stats.AvgMs = (stats.AvgMs*float64(stats.Count) + ms) / float64(stats.Count+1)
+	stats.Count++
+	if err != nil {
+		stats.Errors++
+	}
+	stats.MaxMs = max(stats.MaxMs, ms)
+	stats.Buckets[sort.SearchFloat64s(LatencyBuckets, ms)]++
+	stats.Items += int64(items)
+	stats.MaxItems = max(stats.MaxItems, int64(items))
+}
+
+// Snapshot 获取各操作统计的副本
+func (r *Recorder) Snapshot() map[string]*OpStats {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	snapshot := make(map[string]*OpStats, len(r.ops))
+	for op, stats := range r.ops {
+		copied := *stats
+		copied.Buckets = append([]int64(nil), stats.Buckets...)
+		snapshot[op] = &copied
+	}
+
+	return snapshot
+}
+
+// Snapshot 获取所有已注册记录器的统计，key为记录器名称
+func Snapshot() map[string]map[string]*OpStats {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+
+	snapshot := make(map[string]map[string]*OpStats, len(registry))
+	for name, r := range registry {
+		snapshot[name] = r.Snapshot()
+	}
+
+	return snapshot
+}
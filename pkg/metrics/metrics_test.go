@@ -0,0 +1,63 @@
+package metrics
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecorder_Observe(t *testing.T) {
+	r := NewRecorder("test_observe")
+
+	r.Observe("get", 2*time.Millisecond, nil)
+	r.Observe("get", 4*time.Millisecond, errors.New("timeout"))
+	r.Observe("get", 10*time.Second, nil)
+
+	stats := r.Snapshot()["get"]
+	require.NotNil(t, stats)
+	assert.Equal(t, int64(3), stats.Count)
+	assert.Equal(t, int64(1), stats.Errors)
+	assert.InDelta(t, 10000.0, stats.MaxMs, 0.001)
+	assert.InDelta(t, (2.0+4.0+10000.0)/3, stats.AvgMs, 0.001)
+
+	// 2ms和4ms落在5ms桶，10s落在溢出桶
+	require.Len(t, stats.Buckets, len(LatencyBuckets)+1)
+	assert.Equal(t, int64(2), stats.Buckets[1])
+	assert.Equal(t, int64(1), stats.Buckets[len(LatencyBuckets)])
+}
+
+func TestRecorder_SnapshotIsCopy(t *testing.T) {
+	r := NewRecorder("test_snapshot_copy")
+	r.Observe("put", time.Millisecond, nil)
+
+	snapshot := r.Snapshot()
+	snapshot["put"].Buckets[0] = 100
+
+	assert.Equal(t, int64(1), r.Snapshot()["put"].Buckets[0], "Snapshot should not share state")
+}
+
+func TestNewRecorder_Registry(t *testing.T) {
+	r := NewRecorder("test_registry")
+	assert.Same(t, r, NewRecorder("test_registry"), "Same name should return the same recorder")
+
+	r.Observe("delete", time.Millisecond, nil)
+	all := Snapshot()
+	require.Contains(t, all, "test_registry")
+	assert.Equal(t, int64(1), all["test_registry"]["delete"].Count)
+}
+
+func TestRecorder_ObserveBatch(t *testing.T) {
+	r := NewRecorder("test_observe_batch")
+
+	r.ObserveBatch("insert_many", time.Millisecond, 50, nil)
+	r.ObserveBatch("insert_many", time.Millisecond, 120, nil)
+
+	stats := r.Snapshot()["insert_many"]
+	require.NotNil(t, stats)
+	assert.Equal(t, int64(2), stats.Count)
+	assert.Equal(t, int64(170), stats.Items)
+	assert.Equal(t, int64(120), stats.MaxItems)
+}
@@ -0,0 +1,282 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// PrometheusContentType Prometheus文本格式的Content-Type
+const PrometheusContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// metricPrefix 导出指标名的前缀
+const metricPrefix = "cron_"
+
+// DurationBuckets 执行时长直方图各桶的上界(秒)
+var DurationBuckets = []float64{1, 5, 10, 30, 60, 300, 600, 1800, 3600, 7200}
+
+// series 一组标签值对应的数据
+type series struct {
+	labelValues []string // 标签值，与指标的标签名一一对应
+	value       float64  // 计数器的值或直方图的总和
+	count       int64    // 直方图的样本数
+	buckets     []int64  // 直方图落在每个桶内的次数，最后一个为溢出桶
+}
+
+// metric 按标签区分的计数器或直方图
+type metric struct {
+	name    string             // 指标名
+	help    string             // 指标说明
+	labels  []string           // 标签名
+	buckets []float64          // 直方图各桶的上界，计数器为nil
+	series  map[string]*series // 标签值 -> 数据
+	lock    sync.Mutex         // 保护series
+}
+
+// Counter 按标签累加的计数器
+type Counter struct {
+	m *metric
+}
+
+// Histogram 按标签记录分布的直方图
+type Histogram struct {
+	m *metric
+}
+
+var (
+	promRegistry     = make(map[string]*metric)
+	promRegistryLock sync.Mutex
+)
+
+// register 注册指标，同名指标已存在时直接返回
+func register(name, help string, buckets []float64, labels []string) *metric {
+	promRegistryLock.Lock()
+	defer promRegistryLock.Unlock()
+
+	if m, exists := promRegistry[name]; exists {
+		return m
+	}
+
+	m := &metric{
+		name:    name,
+		help:    help,
+		labels:  labels,
+		buckets: buckets,
+		series:  make(map[string]*series),
+	}
+	promRegistry[name] = m
+	return m
+}
+
+// NewCounter 创建并注册计数器，导出时指标名加上cron_前缀
+func NewCounter(name, help string, labels ...string) *Counter {
+	return &Counter{m: register(name, help, nil, labels)}
+}
+
+// NewHistogram 创建并注册直方图，buckets为各桶的上界
+func NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	return &Histogram{m: register(name, help, buckets, labels)}
+}
+
+// Inc 计数加1，labelValues与创建时的标签名一一对应
+func (c *Counter) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add 计数加value
+func (c *Counter) Add(value float64, labelValues ...string) {
+	c.m.lock.Lock()
+	defer c.m.lock.Unlock()
+
+	c.m.get(labelValues).value += value
+}
+
+// Observe 记录一个样本
+func (h *Histogram) Observe(value float64, labelValues ...string) {
+	h.m.lock.Lock()
+	defer h.m.lock.Unlock()
+
+	s := h.m.get(labelValues)
+	s.value += value
+	s.count++
+	s.buckets[sort.SearchFloat64s(h.m.buckets, value)]++
+}
+
+// get 获取标签值对应的数据，调用方需持有锁
+func (m *metric) get(labelValues []string) *series {
+	key := strings.Join(labelValues, "\xff")
+	s, exists := m.series[key]
+	if !exists {
+		s = &series{labelValues: append([]string(nil), labelValues...)}
+		if m.buckets != nil {
+			s.buckets = make([]int64, len(m.buckets)+1)
+		}
+		m.series[key] = s
+	}
+	return s
+}
+
+// write 以Prometheus文本格式输出指标
+func (m *metric) write(w *bufio.Writer) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	name := metricPrefix + m.name
+	kind := "counter"
+	if m.buckets != nil {
+		kind = "histogram"
+	}
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, m.help, name, kind)
+
+	keys := make([]string, 0, len(m.series))
+	for key := range m.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		s := m.series[key]
+		if m.buckets == nil {
+			fmt.Fprintf(w, "%s%s %s\n", name, formatLabels(m.labels, s.labelValues), formatValue(s.value))
+			continue
+		}
+		writeHistogram(w, name, m.labels, s.labelValues, m.buckets, s.buckets, s.value, s.count)
+	}
+}
+
+// writeHistogram 输出直方图的累计分桶、总和和样本数
+func writeHistogram(w *bufio.Writer, name string, labels, labelValues []string, bounds []float64, buckets []int64, sum float64, count int64) {
+	bucketLabels := append(append([]string(nil), labels...), "le")
+	cumulative := int64(0)
+	for i, bound := range bounds {
+		cumulative += buckets[i]
+		values := append(append([]string(nil), labelValues...), formatValue(bound))
+		fmt.Fprintf(w, "%s_bucket%s %d\n", name, formatLabels(bucketLabels, values), cumulative)
+	}
+	values := append(append([]string(nil), labelValues...), "+Inf")
+	fmt.Fprintf(w, "%s_bucket%s %d\n", name, formatLabels(bucketLabels, values), count)
+	fmt.Fprintf(w, "%s_sum%s %s\n", name, formatLabels(labels, labelValues), formatValue(sum))
+	fmt.Fprintf(w, "%s_count%s %d\n", name, formatLabels(labels, labelValues), count)
+}
+
+// writeRecorder 将记录器的调用统计输出为调用次数、错误次数和耗时直方图，标签op为操作名
+func writeRecorder(w *bufio.Writer, recorder string, ops map[string]*OpStats) {
+	prefix := metricPrefix + sanitizeName(recorder)
+	opNames := make([]string, 0, len(ops))
+	for op := range ops {
+		opNames = append(opNames, op)
+	}
+	sort.Strings(opNames)
+
+	labels := []string{"op"}
+	bounds := make([]float64, len(LatencyBuckets))
+	for i, ms := range LatencyBuckets {
+		bounds[i] = ms / 1000
+	}
+
+	fmt.Fprintf(w, "# HELP %s_calls_total Calls made by %s.\n# TYPE %s_calls_total counter\n", prefix, recorder, prefix)
+	for _, op := range opNames {
+		fmt.Fprintf(w, "%s_calls_total%s %d\n", prefix, formatLabels(labels, []string{op}), ops[op].Count)
+	}
+	fmt.Fprintf(w, "# HELP %s_errors_total Failed calls made by %s.\n# TYPE %s_errors_total counter\n", prefix, recorder, prefix)
+	for _, op := range opNames {
+		fmt.Fprintf(w, "%s_errors_total%s %d\n", prefix, formatLabels(labels, []string{op}), ops[op].Errors)
+	}
+	fmt.Fprintf(w, "# HELP %s_duration_seconds Latency of calls made by %s.\n# TYPE %s_duration_seconds histogram\n", prefix, recorder, prefix)
+	for _, op := range opNames {
+		stats := ops[op]
+		sum := stats.AvgMs * float64(stats.Count) / 1000
+		writeHistogram(w, prefix+"_duration_seconds", labels, []string{op}, bounds, stats.Buckets, sum, stats.Count)
+	}
+}
+
+// WritePrometheus 以Prometheus文本格式输出所有注册的计数器、直方图和记录器的调用统计
+func WritePrometheus(out io.Writer) error {
+	w := bufio.NewWriter(out)
+
+	promRegistryLock.Lock()
+	names := make([]string, 0, len(promRegistry))
+	for name := range promRegistry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	registered := make([]*metric, 0, len(names))
+	for _, name := range names {
+		registered = append(registered, promRegistry[name])
+	}
+	promRegistryLock.Unlock()
+
+	for _, m := range registered {
+		m.write(w)
+	}
+
+	recorders := Snapshot()
+	recorderNames := make([]string, 0, len(recorders))
+	for name := range recorders {
+		recorderNames = append(recorderNames, name)
+	}
+	sort.Strings(recorderNames)
+	for _, name := range recorderNames {
+		if len(recorders[name]) > 0 {
+			writeRecorder(w, name, recorders[name])
+		}
+	}
+
+	return w.Flush()
+}
+
+// Handler 返回以Prometheus文本格式输出指标的HTTP处理器
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", PrometheusContentType)
+		WritePrometheus(w)
+	})
+}
+
+// formatLabels 格式化标签，没有标签时返回空字符串
+func formatLabels(names, values []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, name := range names {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		value := ""
+		if i < len(values) {
+			value = values[i]
+		}
+		b.WriteString(name)
+		b.WriteString(`="`)
+		b.WriteString(labelEscaper.Replace(value))
+		b.WriteByte('"')
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+// labelEscaper 转义标签值中的反斜杠、双引号和换行
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// formatValue 格式化样本值
+func formatValue(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+// sanitizeName 将记录器名称中不能用于指标名的字符替换为下划线
+func sanitizeName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return '_'
+	}, name)
+}
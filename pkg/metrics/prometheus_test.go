@@ -0,0 +1,53 @@
+package metrics
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWritePrometheus(t *testing.T) {
+	counter := NewCounter("test_runs_total", "Runs.", "job", "status")
+	counter.Inc("report", "success")
+	counter.Add(2, "report", "failed")
+	counter.Inc(`say "hi"`, "success")
+	assert.Same(t, counter.m, NewCounter("test_runs_total", "Runs.", "job", "status").m, "Same name should return the same metric")
+
+	histogram := NewHistogram("test_run_seconds", "Run duration.", []float64{1, 10}, "job")
+	histogram.Observe(0.5, "report")
+	histogram.Observe(10, "report")
+	histogram.Observe(30, "report")
+
+	NewRecorder("test_prom").Observe("get", 3*time.Millisecond, nil)
+
+	buf := &bytes.Buffer{}
+	require.NoError(t, WritePrometheus(buf))
+	out := buf.String()
+
+	assert.Contains(t, out, "# TYPE cron_test_runs_total counter\n")
+	assert.Contains(t, out, `cron_test_runs_total{job="report",status="failed"} 2`+"\n")
+	assert.Contains(t, out, `cron_test_runs_total{job="say \"hi\"",status="success"} 1`+"\n", "Label values should be escaped")
+
+	assert.Contains(t, out, "# TYPE cron_test_run_seconds histogram\n")
+	assert.Contains(t, out, `cron_test_run_seconds_bucket{job="report",le="1"} 1`+"\n")
+	assert.Contains(t, out, `cron_test_run_seconds_bucket{job="report",le="10"} 2`+"\n", "Buckets should be cumulative")
+	assert.Contains(t, out, `cron_test_run_seconds_bucket{job="report",le="+Inf"} 3`+"\n")
+	assert.Contains(t, out, `cron_test_run_seconds_sum{job="report"} 40.5`+"\n")
+	assert.Contains(t, out, `cron_test_run_seconds_count{job="report"} 3`+"\n")
+
+	assert.Contains(t, out, `cron_test_prom_calls_total{op="get"} 1`+"\n")
+	assert.Contains(t, out, `cron_test_prom_errors_total{op="get"} 0`+"\n")
+	assert.Contains(t, out, `cron_test_prom_duration_seconds_bucket{op="get",le="0.005"} 1`+"\n")
+}
+
+func TestHandler(t *testing.T) {
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+
+	assert.Equal(t, 200, rec.Code)
+	assert.Equal(t, PrometheusContentType, rec.Header().Get("Content-Type"))
+}
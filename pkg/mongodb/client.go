@@ -7,9 +7,11 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
 
 	"github.com/fyerfyer/scheduler-refactor/common"
 	"github.com/fyerfyer/scheduler-refactor/config"
+	"github.com/fyerfyer/scheduler-refactor/pkg/metrics"
 )
 
 // Client MongoDB客户端封装
@@ -17,8 +19,15 @@ type Client struct {
 	client     *mongo.Client
 	database   *mongo.Database
 	collection *mongo.Collection
+	stats      *mongo.Collection // 按天汇总的统计集合
+
+	logger        *zap.Logger   // 日志对象，用于记录慢查询
+	slowThreshold time.Duration // 慢查询阈值，0表示不记录
 }
 
+// mongoMetrics MongoDB操作的延迟统计
+var mongoMetrics = metrics.NewRecorder("mongodb")
+
 // NewClient 创建MongoDB客户端
 func NewClient() (*Client, error) {
 	cfg := config.GlobalConfig
@@ -42,27 +51,46 @@ func NewClient() (*Client, error) {
 	// 默认使用"cron"数据库和"job_logs"集合
 	database := client.Database("cron")
 	collection := database.Collection(common.LogCollectionName)
+	stats := database.Collection(common.StatsCollectionName)
 
-	// 创建索引
-	indexModel := mongo.IndexModel{
-		Keys: bson.D{
-			{Key: "jobName", Value: 1},
-			{Key: "startTime", Value: -1},
-		},
-	}
+	// 执行索引等schema迁移，大集合上建索引可能较慢，不使用连接超时
+	migrateCtx, migrateCancel := context.WithTimeout(context.Background(), time.Minute)
+	defer migrateCancel()
 
-	_, err = collection.Indexes().CreateOne(ctx, indexModel)
-	if err != nil {
-		return nil, common.NewMongoError("create_index", common.LogCollectionName, err)
+	if err = migrate(migrateCtx, database); err != nil {
+		return nil, err
 	}
 
 	return &Client{
-		client:     client,
-		database:   database,
-		collection: collection,
+		client:        client,
+		database:      database,
+		collection:    collection,
+		stats:         stats,
+		logger:        zap.NewNop(),
+		slowThreshold: time.Duration(cfg.MongoSlowThreshold) * time.Millisecond,
 	}, nil
 }
 
+// SetLogger 设置日志对象，用于记录慢查询
+func (c *Client) SetLogger(logger *zap.Logger) {
+	c.logger = logger
+}
+
+// observe 记录一次MongoDB操作的耗时和处理的文档数，超过阈值时记录慢查询日志及其过滤条件
+func (c *Client) observe(op string, filter interface{}, items int, start time.Time, err *error) {
+	latency := time.Since(start)
+	mongoMetrics.ObserveBatch(op, latency, items, *err)
+
+	if c.slowThreshold > 0 && latency >= c.slowThreshold {
+		c.logger.Warn("slow mongodb operation",
+			zap.String("operation", op),
+			zap.Any("filter", filter),
+			zap.Int("items", items),
+			zap.Duration("latency", latency),
+			zap.Error(*err))
+	}
+}
+
 // Close 关闭连接
 func (c *Client) Close() error {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -72,11 +100,12 @@ func (c *Client) Close() error {
 }
 
 // InsertOne 插入单个文档
-func (c *Client) InsertOne(doc interface{}) (*mongo.InsertOneResult, error) {
+func (c *Client) InsertOne(doc interface{}) (result *mongo.InsertOneResult, err error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	result, err := c.collection.InsertOne(ctx, doc)
+	defer c.observe("insert", nil, 1, time.Now(), &err)
+	result, err = c.collection.InsertOne(ctx, doc)
 	if err != nil {
 		return nil, common.NewMongoError("insert", common.LogCollectionName, err)
 	}
@@ -85,11 +114,12 @@ func (c *Client) InsertOne(doc interface{}) (*mongo.InsertOneResult, error) {
 }
 
 // InsertMany 批量插入文档
-func (c *Client) InsertMany(docs []interface{}) (*mongo.InsertManyResult, error) {
+func (c *Client) InsertMany(docs []interface{}) (result *mongo.InsertManyResult, err error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	result, err := c.collection.InsertMany(ctx, docs)
+	defer c.observe("insert_many", nil, len(docs), time.Now(), &err)
+	result, err = c.collection.InsertMany(ctx, docs)
 	if err != nil {
 		return nil, common.NewMongoError("insert_many", common.LogCollectionName, err)
 	}
@@ -97,12 +127,28 @@ func (c *Client) InsertMany(docs []interface{}) (*mongo.InsertManyResult, error)
 	return result, nil
 }
 
+// InsertLogs 批量写入任务日志
+func (c *Client) InsertLogs(logs []*common.JobLog) error {
+	if len(logs) == 0 {
+		return nil
+	}
+
+	docs := make([]interface{}, len(logs))
+	for i, log := range logs {
+		docs[i] = log
+	}
+
+	_, err := c.InsertMany(docs)
+	return err
+}
+
 // Find 查询文档
-func (c *Client) Find(filter interface{}, options *options.FindOptions) (*mongo.Cursor, error) {
+func (c *Client) Find(filter interface{}, options *options.FindOptions) (cur *mongo.Cursor, err error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	cur, err := c.collection.Find(ctx, filter, options)
+	defer c.observe("find", filter, 0, time.Now(), &err)
+	cur, err = c.collection.Find(ctx, filter, options)
 	if err != nil {
 		return nil, common.NewMongoError("find", common.LogCollectionName, err)
 	}
@@ -111,7 +157,7 @@ func (c *Client) Find(filter interface{}, options *options.FindOptions) (*mongo.
 }
 
 // FindJobLogs 查询任务日志
-func (c *Client) FindJobLogs(jobName string, skip, limit int64) ([]*common.JobLog, error) {
+func (c *Client) FindJobLogs(jobName string, scope *common.Scope, skip, limit int64) (logs []*common.JobLog, err error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
@@ -120,6 +166,7 @@ func (c *Client) FindJobLogs(jobName string, skip, limit int64) ([]*common.JobLo
 	if jobName != "" {
 		filter["jobName"] = jobName
 	}
+	applyScope(filter, scope)
 
 	// 设置查询选项
 	opts := options.Find().
@@ -128,6 +175,7 @@ func (c *Client) FindJobLogs(jobName string, skip, limit int64) ([]*common.JobLo
 		SetLimit(limit)
 
 	// 执行查询
+	defer func(start time.Time) { c.observe("find_job_logs", filter, len(logs), start, &err) }(time.Now())
 	cursor, err := c.collection.Find(ctx, filter, opts)
 	if err != nil {
 		return nil, common.NewMongoError("find_job_logs", common.LogCollectionName, err)
@@ -135,7 +183,37 @@ func (c *Client) FindJobLogs(jobName string, skip, limit int64) ([]*common.JobLo
 	defer cursor.Close(ctx)
 
 	// 解析结果
-	var logs []*common.JobLog
+	if err = cursor.All(ctx, &logs); err != nil {
+		return nil, common.NewMongoError("cursor_all", common.LogCollectionName, err)
+	}
+
+	return logs, nil
+}
+
+// FindJobLogsByNames 查询多个任务名下的日志
+func (c *Client) FindJobLogsByNames(jobNames []string, scope *common.Scope, skip, limit int64) (logs []*common.JobLog, err error) {
+	if len(jobNames) == 0 {
+		return []*common.JobLog{}, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	filter := bson.M{"jobName": bson.M{"$in": jobNames}}
+	applyScope(filter, scope)
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "startTime", Value: -1}}).
+		SetSkip(skip).
+		SetLimit(limit)
+
+	defer func(start time.Time) { c.observe("find_job_logs_by_names", filter, len(logs), start, &err) }(time.Now())
+	cursor, err := c.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, common.NewMongoError("find_job_logs_by_names", common.LogCollectionName, err)
+	}
+	defer cursor.Close(ctx)
+
 	if err = cursor.All(ctx, &logs); err != nil {
 		return nil, common.NewMongoError("cursor_all", common.LogCollectionName, err)
 	}
@@ -144,7 +222,7 @@ func (c *Client) FindJobLogs(jobName string, skip, limit int64) ([]*common.JobLo
 }
 
 // CountJobLogs 计算任务日志总数
-func (c *Client) CountJobLogs(jobName string) (int64, error) {
+func (c *Client) CountJobLogs(jobName string, scope *common.Scope) (count int64, err error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
@@ -153,9 +231,11 @@ func (c *Client) CountJobLogs(jobName string) (int64, error) {
 	if jobName != "" {
 		filter["jobName"] = jobName
 	}
+	applyScope(filter, scope)
 
 	// 计数
-	count, err := c.collection.CountDocuments(ctx, filter)
+	defer c.observe("count", filter, 0, time.Now(), &err)
+	count, err = c.collection.CountDocuments(ctx, filter)
 	if err != nil {
 		return 0, common.NewMongoError("count", common.LogCollectionName, err)
 	}
@@ -164,7 +244,7 @@ func (c *Client) CountJobLogs(jobName string) (int64, error) {
 }
 
 // DeleteOldLogs 删除过期日志
-func (c *Client) DeleteOldLogs(before time.Time) (int64, error) {
+func (c *Client) DeleteOldLogs(before time.Time) (deleted int64, err error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
@@ -172,6 +252,7 @@ func (c *Client) DeleteOldLogs(before time.Time) (int64, error) {
 	filter := bson.M{"endTime": bson.M{"$lt": before.Unix()}}
 
 	// 执行删除
+	defer func(start time.Time) { c.observe("delete_old_logs", filter, int(deleted), start, &err) }(time.Now())
 	result, err := c.collection.DeleteMany(ctx, filter)
 	if err != nil {
 		return 0, common.NewMongoError("delete_old_logs", common.LogCollectionName, err)
@@ -180,6 +261,22 @@ func (c *Client) DeleteOldLogs(before time.Time) (int64, error) {
 	return result.DeletedCount, nil
 }
 
+// CountOldLogs 统计过期日志数量
+func (c *Client) CountOldLogs(before time.Time) (count int64, err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{"endTime": bson.M{"$lt": before.Unix()}}
+
+	defer c.observe("count_old_logs", filter, 0, time.Now(), &err)
+	count, err = c.collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return 0, common.NewMongoError("count_old_logs", common.LogCollectionName, err)
+	}
+
+	return count, nil
+}
+
 // DropCollection 删除集合
 func (c *Client) DropCollection() error {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -194,7 +291,7 @@ func (c *Client) DropCollection() error {
 }
 
 // FindJobLogsSince 查询指定时间之后的任务日志
-func (c *Client) FindJobLogsSince(jobName string, timestamp int64) ([]*common.JobLog, error) {
+func (c *Client) FindJobLogsSince(jobName string, scope *common.Scope, timestamp int64) (logs []*common.JobLog, err error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
@@ -206,12 +303,14 @@ func (c *Client) FindJobLogsSince(jobName string, timestamp int64) ([]*common.Jo
 	if jobName != "" {
 		filter["jobName"] = jobName
 	}
+	applyScope(filter, scope)
 
 	// 设置查询选项
 	opts := options.Find().
 		SetSort(bson.D{{Key: "startTime", Value: -1}}) // 按开始时间降序排序
 
 	// 执行查询
+	defer func(start time.Time) { c.observe("find_job_logs_since", filter, len(logs), start, &err) }(time.Now())
 	cursor, err := c.collection.Find(ctx, filter, opts)
 	if err != nil {
 		return nil, common.NewMongoError("find_job_logs_since", common.LogCollectionName, err)
@@ -219,7 +318,6 @@ func (c *Client) FindJobLogsSince(jobName string, timestamp int64) ([]*common.Jo
 	defer cursor.Close(ctx)
 
 	// 解析结果
-	var logs []*common.JobLog
 	if err = cursor.All(ctx, &logs); err != nil {
 		return nil, common.NewMongoError("cursor_all", common.LogCollectionName, err)
 	}
@@ -227,6 +325,145 @@ func (c *Client) FindJobLogsSince(jobName string, timestamp int64) ([]*common.Jo
 	return logs, nil
 }
 
+// FindJobLogsEndedSince 按结束时间升序查询指定时间之后结束的任务日志
+func (c *Client) FindJobLogsEndedSince(jobName string, scope *common.Scope, timestamp, limit int64) (logs []*common.JobLog, err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"endTime": bson.M{"$gte": timestamp},
+	}
+
+	if jobName != "" {
+		filter["jobName"] = jobName
+	}
+	applyScope(filter, scope)
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "endTime", Value: 1}, {Key: "_id", Value: 1}}).
+		SetLimit(limit)
+
+	defer func(start time.Time) { c.observe("find_job_logs_ended_since", filter, len(logs), start, &err) }(time.Now())
+	cursor, err := c.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, common.NewMongoError("find_job_logs_ended_since", common.LogCollectionName, err)
+	}
+	defer cursor.Close(ctx)
+
+	if err = cursor.All(ctx, &logs); err != nil {
+		return nil, common.NewMongoError("cursor_all", common.LogCollectionName, err)
+	}
+
+	return logs, nil
+}
+
+// FindLogByRunID 查询指定执行的日志
+func (c *Client) FindLogByRunID(runID string, scope *common.Scope) (log *common.JobLog, err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	filter := bson.M{"runId": runID}
+	applyScope(filter, scope)
+	opts := options.FindOne().SetSort(bson.D{{Key: "startTime", Value: -1}})
+
+	defer c.observe("find_log_by_run_id", filter, 0, time.Now(), &err)
+	log = &common.JobLog{}
+	err = c.collection.FindOne(ctx, filter, opts).Decode(log)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, common.NewMongoError("find_log_by_run_id", common.LogCollectionName, err)
+	}
+
+	return log, nil
+}
+
+// UpsertDailyStats 写入按天汇总的统计
+func (c *Client) UpsertDailyStats(stats []*common.JobDailyStats) (err error) {
+	if len(stats) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	models := make([]mongo.WriteModel, len(stats))
+	for i, s := range stats {
+		models[i] = mongo.NewReplaceOneModel().
+			SetFilter(bson.M{"jobName": s.JobName, "day": s.Day}).
+			SetReplacement(s).
+			SetUpsert(true)
+	}
+
+	defer c.observe("upsert_daily_stats", nil, len(stats), time.Now(), &err)
+	if _, err = c.stats.BulkWrite(ctx, models); err != nil {
+		return common.NewMongoError("upsert_daily_stats", common.StatsCollectionName, err)
+	}
+
+	return nil
+}
+
+// FindDailyStats 查询按天汇总的统计
+func (c *Client) FindDailyStats(jobName string, scope *common.Scope, from, to int64) (stats []*common.JobDailyStats, err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"day": bson.M{"$gte": from, "$lt": to},
+	}
+	if jobName != "" {
+		filter["jobName"] = jobName
+	}
+	applyScope(filter, scope)
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "day", Value: 1}}) // 按日期升序排序
+
+	defer func(start time.Time) { c.observe("find_daily_stats", filter, len(stats), start, &err) }(time.Now())
+	cursor, err := c.stats.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, common.NewMongoError("find_daily_stats", common.StatsCollectionName, err)
+	}
+	defer cursor.Close(ctx)
+
+	if err = cursor.All(ctx, &stats); err != nil {
+		return nil, common.NewMongoError("cursor_all", common.StatsCollectionName, err)
+	}
+
+	return stats, nil
+}
+
+// applyScope 将调用方的可见范围加入查询过滤器
+func applyScope(filter bson.M, scope *common.Scope) {
+	if scope == nil {
+		return
+	}
+
+	conditions := bson.A{}
+	if len(scope.Namespaces) > 0 {
+		namespaces := bson.A{}
+		for _, ns := range scope.Namespaces {
+			namespaces = append(namespaces, ns)
+			// 旧日志没有namespace字段，属于默认命名空间
+			if ns == common.DefaultNamespace {
+				namespaces = append(namespaces, nil, "")
+			}
+		}
+		conditions = append(conditions, bson.M{"namespace": bson.M{"$in": namespaces}})
+	}
+	if scope.Owner != "" {
+		conditions = append(conditions, bson.M{"owner": scope.Owner})
+	}
+
+	if len(conditions) == 0 {
+		// 没有任何可访问范围，不匹配任何日志
+		filter["_id"] = bson.M{"$exists": false}
+		return
+	}
+	filter["$or"] = conditions
+}
+
 func (c *Client) GetCollection(collectionName string) (*mongo.Collection, error) {
 	return c.database.Collection(collectionName), nil
 }
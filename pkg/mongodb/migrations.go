@@ -0,0 +1,147 @@
+package mongodb
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+)
+
+// migration 单个schema迁移步骤
+type migration struct {
+	version    int                                                 // schema版本号，严格递增
+	collection string                                              // 迁移的集合
+	indexes    []mongo.IndexModel                                  // 需要创建的索引
+	apply      func(ctx context.Context, db *mongo.Database) error // 索引之外的迁移操作，可为空
+}
+
+// migrationRecord 已应用的迁移记录
+type migrationRecord struct {
+	Version   int   `bson:"version"`
+	AppliedAt int64 `bson:"appliedAt"`
+}
+
+// migrations 所有schema迁移，按版本号顺序执行，已发布的迁移不允许修改
+var migrations = []migration{
+	{
+		// 按任务查询最近日志
+		version:    1,
+		collection: common.LogCollectionName,
+		indexes: []mongo.IndexModel{
+			{Keys: bson.D{{Key: "jobName", Value: 1}, {Key: "startTime", Value: -1}}},
+		},
+	},
+	{
+		// 统计集合按任务和日期唯一
+		version:    2,
+		collection: common.StatsCollectionName,
+		indexes: []mongo.IndexModel{
+			{
+				Keys:    bson.D{{Key: "jobName", Value: 1}, {Key: "day", Value: 1}},
+				Options: options.Index().SetUnique(true),
+			},
+		},
+	},
+	{
+		// 日志清理按结束时间删除，排查问题时按退出码、执行机器和命名空间过滤
+		version:    3,
+		collection: common.LogCollectionName,
+		indexes: []mongo.IndexModel{
+			{Keys: bson.D{{Key: "endTime", Value: 1}}},
+			{Keys: bson.D{{Key: "exitCode", Value: 1}, {Key: "startTime", Value: -1}}},
+			{Keys: bson.D{{Key: "workerIp", Value: 1}, {Key: "startTime", Value: -1}}},
+			{Keys: bson.D{{Key: "namespace", Value: 1}, {Key: "startTime", Value: -1}}},
+		},
+	},
+	{
+		// 按runId查询单次执行
+		version:    4,
+		collection: common.LogCollectionName,
+		indexes: []mongo.IndexModel{
+			{Keys: bson.D{{Key: "runId", Value: 1}}},
+		},
+	},
+}
+
+// migrate 执行尚未应用的schema迁移，并记录schema版本
+func migrate(ctx context.Context, db *mongo.Database) error {
+	records := db.Collection(common.MigrationCollectionName)
+
+	current, err := schemaVersion(ctx, records)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range migrations {
+		if m.version <= current {
+			continue
+		}
+
+		// 创建已存在的同名同结构索引是幂等的，迁移中途失败后可以安全重试
+		if len(m.indexes) > 0 {
+			if _, err = db.Collection(m.collection).Indexes().CreateMany(ctx, m.indexes); err != nil {
+				return common.NewMongoError("create_index", m.collection, err)
+			}
+		}
+		if m.apply != nil {
+			if err = m.apply(ctx, db); err != nil {
+				return common.NewMongoError("migrate", m.collection, err)
+			}
+		}
+
+		record := migrationRecord{Version: m.version, AppliedAt: time.Now().Unix()}
+		if _, err = records.InsertOne(ctx, record); err != nil {
+			return common.NewMongoError("record_migration", common.MigrationCollectionName, err)
+		}
+	}
+
+	return nil
+}
+
+// schemaVersion 查询已应用的最高迁移版本，未执行过迁移时返回0
+func schemaVersion(ctx context.Context, records *mongo.Collection) (int, error) {
+	opts := options.FindOne().SetSort(bson.D{{Key: "version", Value: -1}})
+
+	var record migrationRecord
+	err := records.FindOne(ctx, bson.M{}, opts).Decode(&record)
+	if err == mongo.ErrNoDocuments {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, common.NewMongoError("query_schema_version", common.MigrationCollectionName, err)
+	}
+
+	return record.Version, nil
+}
+
+// SchemaVersion 获取当前schema版本
+func (c *Client) SchemaVersion() (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	return schemaVersion(ctx, c.database.Collection(common.MigrationCollectionName))
+}
+
+// LatestSchemaVersion 获取当前代码需要的schema版本
+func (c *Client) LatestSchemaVersion() int {
+	return migrations[len(migrations)-1].version
+}
+
+// ServerTime 获取MongoDB服务器的当前时间，用于检查时钟偏差
+func (c *Client) ServerTime() (time.Time, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var result struct {
+		LocalTime time.Time `bson:"localTime"`
+	}
+	if err := c.database.RunCommand(ctx, bson.D{{Key: "hello", Value: 1}}).Decode(&result); err != nil {
+		return time.Time{}, common.NewMongoError("hello", "", err)
+	}
+
+	return result.LocalTime, nil
+}
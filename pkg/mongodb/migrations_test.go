@@ -0,0 +1,15 @@
+package mongodb
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMigrations_VersionsIncreasing(t *testing.T) {
+	for i, m := range migrations {
+		assert.Equal(t, i+1, m.version, "Migration versions should start at 1 and increase by 1")
+		assert.NotEmpty(t, m.collection, "Migration should name its collection")
+		assert.True(t, len(m.indexes) > 0 || m.apply != nil, "Migration should do something")
+	}
+}
@@ -0,0 +1,65 @@
+package notify
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// MaxExcerptBytes 通知中输出摘录的最大字节数，留出余量以适应聊天工具的单条消息长度限制
+const MaxExcerptBytes = 2000
+
+// truncatedMark 摘录被截断时的前缀
+const truncatedMark = "...(truncated)\n"
+
+// redactPatterns 需要在通知中脱敏的敏感信息
+var redactPatterns = []struct {
+	pattern     *regexp.Regexp
+	replacement string
+}{
+	// password=xxx、token: xxx 等键值对
+	{regexp.MustCompile(`(?i)\b([\w.-]*(?:password|passwd|pwd|secret|token|api[_-]?key|access[_-]?key)[\w.-]*)(\s*[=:]\s*)("[^"]*"|'[^']*'|\S+)`), "${1}${2}***"},
+	// Authorization: Bearer xxx
+	{regexp.MustCompile(`(?i)\b(bearer|basic)\s+[A-Za-z0-9._~+/=-]+`), "${1} ***"},
+	// URL中的用户名密码
+	{regexp.MustCompile(`(://[^/\s:@]+):[^/\s@]+@`), "${1}:***@"},
+}
+
+// Redact 将文本中的密码、令牌等敏感信息替换为***
+func Redact(text string) string {
+	for _, r := range redactPatterns {
+		text = r.pattern.ReplaceAllString(text, r.replacement)
+	}
+	return text
+}
+
+// OutputTail 截取输出的最后lines行并脱敏，结果不超过MaxExcerptBytes，lines<=0时返回空串
+func OutputTail(output string, lines int) string {
+	output = strings.TrimRight(output, "\n")
+	if lines <= 0 || output == "" {
+		return ""
+	}
+
+	truncated := false
+	all := strings.Split(output, "\n")
+	if len(all) > lines {
+		all = all[len(all)-lines:]
+		truncated = true
+	}
+	tail := Redact(strings.Join(all, "\n"))
+
+	// 超出长度时保留末尾，从完整的UTF-8字符处切开
+	if len(tail) > MaxExcerptBytes {
+		cut := len(tail) - MaxExcerptBytes + len(truncatedMark)
+		for cut < len(tail) && !utf8.RuneStart(tail[cut]) {
+			cut++
+		}
+		tail = tail[cut:]
+		truncated = true
+	}
+
+	if truncated {
+		return truncatedMark + tail
+	}
+	return tail
+}
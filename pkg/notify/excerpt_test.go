@@ -0,0 +1,31 @@
+package notify
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedact(t *testing.T) {
+	assert.Equal(t, "DB_PASSWORD=*** user=admin", Redact("DB_PASSWORD=hunter2 user=admin"))
+	assert.Equal(t, `api_key: ***`, Redact(`api_key: "abc def"`))
+	assert.Equal(t, "Authorization: Bearer ***", Redact("Authorization: Bearer eyJhbGciOi.J9"))
+	assert.Equal(t, "postgres://app:***@db:5432/app", Redact("postgres://app:s3cret@db:5432/app"))
+	assert.Equal(t, "nothing to hide", Redact("nothing to hide"))
+}
+
+func TestOutputTail(t *testing.T) {
+	assert.Empty(t, OutputTail("a\nb\n", 0), "Zero lines should disable the excerpt")
+	assert.Empty(t, OutputTail("", 5))
+	assert.Equal(t, "a\nb", OutputTail("a\nb\n", 5))
+	assert.Equal(t, truncatedMark+"c\nd", OutputTail("a\nb\nc\nd\n", 2))
+	assert.Equal(t, "token=***", OutputTail("token=abc", 1))
+
+	long := strings.Repeat("错误", MaxExcerptBytes)
+	tail := OutputTail(long, 1)
+	assert.LessOrEqual(t, len(tail), MaxExcerptBytes)
+	assert.True(t, strings.HasPrefix(tail, truncatedMark))
+	assert.True(t, utf8.ValidString(tail), "Truncation should not split a character")
+}
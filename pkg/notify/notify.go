@@ -0,0 +1,77 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Message 通知消息
+type Message struct {
+	Event  string            `json:"event"`            // 事件类型
+	Title  string            `json:"title"`            // 标题
+	Text   string            `json:"text"`             // 正文
+	Fields map[string]string `json:"fields,omitempty"` // 附加字段
+	Time   int64             `json:"time"`             // 发送时间
+}
+
+// Notifier 通知发送接口
+type Notifier interface {
+	Notify(msg *Message) error
+}
+
+// nopNotifier 未配置通知地址时使用，丢弃所有消息
+type nopNotifier struct{}
+
+// Notify 实现Notifier接口
+func (nopNotifier) Notify(msg *Message) error {
+	return nil
+}
+
+// WebhookNotifier 以JSON POST方式发送通知
+type WebhookNotifier struct {
+	url    string
+	client *http.Client
+}
+
+// NewNotifier 创建通知器，url为空时返回不发送任何消息的通知器
+func NewNotifier(url string) Notifier {
+	if url == "" {
+		return nopNotifier{}
+	}
+	return NewWebhookNotifier(url)
+}
+
+// NewWebhookNotifier 创建webhook通知器
+func NewWebhookNotifier(url string) *WebhookNotifier {
+	return &WebhookNotifier{
+		url:    url,
+		client: &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+// Notify 发送通知
+func (n *WebhookNotifier) Notify(msg *Message) error {
+	if msg.Time == 0 {
+		msg.Time = time.Now().Unix()
+	}
+
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to marshal notification: %v", err)
+	}
+
+	resp, err := n.client.Post(n.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to send notification: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("notification webhook returned status %d", resp.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,35 @@
+package notify
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookNotifier(t *testing.T) {
+	var received Message
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+	}))
+	defer server.Close()
+
+	err := NewNotifier(server.URL).Notify(&Message{Event: "test", Title: "hello"})
+	require.NoError(t, err)
+	assert.Equal(t, "hello", received.Title)
+	assert.NotZero(t, received.Time, "Send time should be filled in")
+}
+
+func TestWebhookNotifier_ErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	assert.Error(t, NewWebhookNotifier(server.URL).Notify(&Message{}))
+	assert.NoError(t, NewNotifier("").Notify(&Message{}), "Empty url should drop messages")
+}
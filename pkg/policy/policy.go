@@ -0,0 +1,114 @@
+package policy
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+)
+
+// 规则动作
+const (
+	ActionAllow = "allow" // 白名单规则
+	ActionDeny  = "deny"  // 黑名单规则
+)
+
+// Rule 命令策略规则
+type Rule struct {
+	Name        string `json:"name"`        // 规则名称
+	Pattern     string `json:"pattern"`     // 匹配命令的正则表达式
+	Action      string `json:"action"`      // 动作: allow/deny
+	Description string `json:"description"` // 规则说明
+	CreatedAt   int64  `json:"createdAt"`   // 创建时间
+	UpdatedAt   int64  `json:"updatedAt"`   // 更新时间
+}
+
+// Validate 校验规则
+func (r *Rule) Validate() error {
+	if r.Name == "" {
+		return fmt.Errorf("rule name is required")
+	}
+	if r.Action != ActionAllow && r.Action != ActionDeny {
+		return fmt.Errorf("rule action must be %q or %q", ActionAllow, ActionDeny)
+	}
+	if _, err := regexp.Compile(r.Pattern); err != nil {
+		return fmt.Errorf("invalid rule pattern: %v", err)
+	}
+	return nil
+}
+
+// Decision 策略判定结果
+type Decision struct {
+	Allowed bool   `json:"allowed"` // 是否允许执行
+	Rule    string `json:"rule"`    // 命中的规则名称
+	Reason  string `json:"reason"`  // 判定原因
+}
+
+// compiledRule 编译后的规则
+type compiledRule struct {
+	rule *Rule
+	expr *regexp.Regexp
+}
+
+// Engine 策略引擎：先匹配黑名单，任一命中即拒绝；存在白名单时命令必须至少命中一条白名单
+type Engine struct {
+	deny  []compiledRule
+	allow []compiledRule
+}
+
+// NewEngine 编译规则并创建策略引擎
+func NewEngine(rules []*Rule) (*Engine, error) {
+	engine := &Engine{}
+
+	// 按名称排序，保证判定结果稳定
+	sorted := make([]*Rule, len(rules))
+	copy(sorted, rules)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+
+	for _, rule := range sorted {
+		if err := rule.Validate(); err != nil {
+			return nil, fmt.Errorf("rule %s: %v", rule.Name, err)
+		}
+
+		compiled := compiledRule{rule: rule, expr: regexp.MustCompile(rule.Pattern)}
+		if rule.Action == ActionDeny {
+			engine.deny = append(engine.deny, compiled)
+		} else {
+			engine.allow = append(engine.allow, compiled)
+		}
+	}
+
+	return engine, nil
+}
+
+// Evaluate 判定命令是否允许执行
+func (e *Engine) Evaluate(command string) Decision {
+	if e == nil {
+		return Decision{Allowed: true, Reason: "no policy configured"}
+	}
+
+	for _, c := range e.deny {
+		if c.expr.MatchString(command) {
+			return Decision{
+				Allowed: false,
+				Rule:    c.rule.Name,
+				Reason:  fmt.Sprintf("command matches deny rule %s", c.rule.Name),
+			}
+		}
+	}
+
+	if len(e.allow) == 0 {
+		return Decision{Allowed: true, Reason: "no deny rule matched"}
+	}
+
+	for _, c := range e.allow {
+		if c.expr.MatchString(command) {
+			return Decision{
+				Allowed: true,
+				Rule:    c.rule.Name,
+				Reason:  fmt.Sprintf("command matches allow rule %s", c.rule.Name),
+			}
+		}
+	}
+
+	return Decision{Allowed: false, Reason: "command does not match any allow rule"}
+}
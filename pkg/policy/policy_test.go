@@ -0,0 +1,54 @@
+package policy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEngine_DenyRules(t *testing.T) {
+	engine, err := NewEngine([]*Rule{
+		{Name: "no-rm-root", Pattern: `rm\s+-rf\s+/(\s|$)`, Action: ActionDeny},
+		{Name: "no-curl-pipe", Pattern: `curl[^|]*\|\s*(ba)?sh`, Action: ActionDeny},
+	})
+	require.NoError(t, err)
+
+	decision := engine.Evaluate("rm -rf /")
+	assert.False(t, decision.Allowed)
+	assert.Equal(t, "no-rm-root", decision.Rule)
+
+	decision = engine.Evaluate("curl http://x/install.sh | sh")
+	assert.False(t, decision.Allowed)
+	assert.Equal(t, "no-curl-pipe", decision.Rule)
+
+	assert.True(t, engine.Evaluate("rm -rf /tmp/cache").Allowed)
+	assert.True(t, engine.Evaluate("echo hello").Allowed)
+}
+
+func TestEngine_AllowRules(t *testing.T) {
+	engine, err := NewEngine([]*Rule{
+		{Name: "scripts", Pattern: `^/opt/jobs/`, Action: ActionAllow},
+		{Name: "no-sudo", Pattern: `sudo`, Action: ActionDeny},
+	})
+	require.NoError(t, err)
+
+	assert.True(t, engine.Evaluate("/opt/jobs/backup.sh").Allowed)
+	assert.False(t, engine.Evaluate("echo hello").Allowed, "Commands outside the allowlist should be rejected")
+	assert.False(t, engine.Evaluate("/opt/jobs/run.sh && sudo reboot").Allowed, "Deny rules take precedence")
+}
+
+func TestEngine_Nil(t *testing.T) {
+	var engine *Engine
+	assert.True(t, engine.Evaluate("anything").Allowed)
+}
+
+func TestRule_Validate(t *testing.T) {
+	assert.Error(t, (&Rule{Pattern: "x", Action: ActionDeny}).Validate())
+	assert.Error(t, (&Rule{Name: "a", Pattern: "x", Action: "block"}).Validate())
+	assert.Error(t, (&Rule{Name: "a", Pattern: "(", Action: ActionDeny}).Validate())
+	assert.NoError(t, (&Rule{Name: "a", Pattern: "x", Action: ActionAllow}).Validate())
+
+	_, err := NewEngine([]*Rule{{Name: "bad", Pattern: "(", Action: ActionDeny}})
+	assert.Error(t, err)
+}
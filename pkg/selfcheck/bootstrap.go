@@ -0,0 +1,96 @@
+package selfcheck
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"go.etcd.io/etcd/client/v3"
+	"go.uber.org/zap"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+	"github.com/fyerfyer/scheduler-refactor/config"
+	"github.com/fyerfyer/scheduler-refactor/pkg/etcd"
+	"github.com/fyerfyer/scheduler-refactor/pkg/logstore"
+	"github.com/fyerfyer/scheduler-refactor/pkg/version"
+)
+
+// Bootstrap 初始化新集群：执行日志存储的schema迁移创建集合和索引，
+// 并在etcd中写入初始化记录。重复执行是幂等的，已初始化时只报告原有记录
+func Bootstrap() *Report {
+	report := &Report{}
+	report.Run("config", func() (string, error) {
+		if err := CheckConfig(config.GlobalConfig, RoleMaster); err != nil {
+			return "", err
+		}
+		return "valid", nil
+	})
+
+	// 连接日志存储时会执行全部尚未应用的迁移
+	schemaVersion := 0
+	store, err := logstore.NewLogStore(zap.NewNop())
+	if err != nil {
+		report.Skip("logstore", err.Error())
+	} else {
+		defer store.Close()
+		report.Run("logstore", func() (string, error) {
+			message, err := CheckLogStore(store)
+			if versioned, ok := store.(schemaVersioned); ok && err == nil {
+				schemaVersion, _ = versioned.SchemaVersion()
+			}
+			return message, err
+		})
+	}
+
+	client, err := etcd.NewClient()
+	if err != nil {
+		report.Skip("etcd", err.Error())
+		return report
+	}
+	defer client.Close()
+
+	report.Run("etcd", func() (string, error) {
+		if _, err := CheckEtcd(client); err != nil {
+			return "", err
+		}
+		return writeBootstrapRecord(client, schemaVersion)
+	})
+
+	return report
+}
+
+// writeBootstrapRecord 写入集群初始化记录，记录已存在时不覆盖
+func writeBootstrapRecord(client *etcd.Client, schemaVersion int) (string, error) {
+	hostname, _ := os.Hostname()
+	record := &common.BootstrapRecord{
+		Version:       version.Version,
+		SchemaVersion: schemaVersion,
+		InitializedBy: hostname,
+		InitializedAt: time.Now().Unix(),
+	}
+	data, err := json.Marshal(record)
+	if err != nil {
+		return "", err
+	}
+
+	applied, err := client.ApplyIfUnchanged(common.BootstrapKey, 0, clientv3.OpPut(common.BootstrapKey, string(data)))
+	if err != nil {
+		return "", err
+	}
+	if applied {
+		return "cluster initialized", nil
+	}
+
+	// 已经初始化过，报告原有记录
+	resp, err := client.Get(common.BootstrapKey)
+	if err != nil {
+		return "", err
+	}
+	existing := &common.BootstrapRecord{}
+	if resp.Count == 0 || json.Unmarshal(resp.Kvs[0].Value, existing) != nil {
+		return "already initialized", nil
+	}
+	return fmt.Sprintf("already initialized by %s at %s", existing.InitializedBy,
+		time.Unix(existing.InitializedAt, 0).Format(time.RFC3339)), nil
+}
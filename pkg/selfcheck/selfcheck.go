@@ -0,0 +1,311 @@
+package selfcheck
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"go.uber.org/zap"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+	"github.com/fyerfyer/scheduler-refactor/config"
+	"github.com/fyerfyer/scheduler-refactor/pkg/etcd"
+	"github.com/fyerfyer/scheduler-refactor/pkg/logging"
+	"github.com/fyerfyer/scheduler-refactor/pkg/logstore"
+	"github.com/fyerfyer/scheduler-refactor/pkg/version"
+)
+
+// cronParser 配置中cron表达式的解析器，与任务一致带秒字段
+var cronParser = cron.NewParser(cron.Second | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow)
+
+// MaxClockSkew 本机与存储服务器允许的最大时钟偏差，超过时任务锁和日志时间都不可靠
+const MaxClockSkew = 5 * time.Second
+
+// 检查结果状态
+const (
+	StatusOK   = "ok"   // 通过
+	StatusWarn = "warn" // 有风险但不影响启动
+	StatusFail = "fail" // 失败
+)
+
+// 进程角色，不同角色校验的配置项不同
+const (
+	RoleMaster = "master"
+	RoleWorker = "worker"
+)
+
+// Run 按配置、etcd、日志存储、时钟的顺序执行检查，连接失败时跳过依赖该连接的检查
+func Run(role string) *Report {
+	report := &Report{}
+	report.Run("config", func() (string, error) {
+		if err := CheckConfig(config.GlobalConfig, role); err != nil {
+			return "", err
+		}
+		return "valid", nil
+	})
+
+	client, err := etcd.NewClient()
+	if err != nil {
+		report.Skip("etcd", err.Error())
+	} else {
+		defer client.Close()
+		report.Run("etcd", func() (string, error) {
+			return CheckEtcd(client)
+		})
+	}
+
+	store, err := logstore.NewLogStore(zap.NewNop())
+	if err != nil {
+		report.Skip("logstore", err.Error())
+		report.Skip("clock", "log store unavailable")
+		return report
+	}
+	defer store.Close()
+
+	report.Run("logstore", func() (string, error) {
+		return CheckLogStore(store)
+	})
+	report.Run("clock", func() (string, error) {
+		return CheckClock(store)
+	})
+
+	return report
+}
+
+// Result 单项检查结果
+type Result struct {
+	Name    string        // 检查项
+	Status  string        // 检查结果状态
+	Message string        // 结果说明
+	Elapsed time.Duration // 检查耗时
+}
+
+// Report 自检报告
+type Report struct {
+	Results []*Result
+}
+
+// Run 执行一项检查，fn返回错误时记为失败
+func (r *Report) Run(name string, fn func() (string, error)) {
+	start := time.Now()
+	message, err := fn()
+	result := &Result{Name: name, Status: StatusOK, Message: message, Elapsed: time.Since(start)}
+
+	var warning *Warning
+	switch {
+	case errors.As(err, &warning):
+		result.Status, result.Message = StatusWarn, warning.Message
+	case err != nil:
+		result.Status, result.Message = StatusFail, err.Error()
+	}
+	r.Results = append(r.Results, result)
+}
+
+// Skip 记录因前置检查失败而跳过的检查
+func (r *Report) Skip(name, reason string) {
+	r.Results = append(r.Results, &Result{Name: name, Status: StatusFail, Message: "skipped: " + reason})
+}
+
+// Failed 是否有检查失败
+func (r *Report) Failed() bool {
+	for _, result := range r.Results {
+		if result.Status == StatusFail {
+			return true
+		}
+	}
+	return false
+}
+
+// Print 输出报告，每项检查一行
+func (r *Report) Print(w io.Writer) {
+	for _, result := range r.Results {
+		fmt.Fprintf(w, "[%-4s] %-10s %s (%s)\n",
+			strings.ToUpper(result.Status), result.Name, result.Message, result.Elapsed.Round(time.Millisecond))
+	}
+	if r.Failed() {
+		fmt.Fprintln(w, "self-check failed")
+	} else {
+		fmt.Fprintln(w, "self-check passed")
+	}
+}
+
+// Warning 只需提示的检查问题
+type Warning struct {
+	Message string
+}
+
+// Error 实现error接口
+func (w *Warning) Error() string {
+	return w.Message
+}
+
+// CheckConfig 校验配置取值，返回所有不合法的配置项
+func CheckConfig(cfg *config.Config, role string) error {
+	var problems []string
+	if len(cfg.EtcdEndpoints) == 0 {
+		problems = append(problems, "etcdEndpoints must not be empty")
+	}
+	if cfg.EtcdDialTimeout <= 0 {
+		problems = append(problems, "etcdDialTimeout must be positive")
+	}
+
+	switch cfg.LogBackend {
+	case "", logstore.BackendMongoDB:
+		if cfg.MongoURI == "" {
+			problems = append(problems, "mongoUri must not be empty for the mongodb log backend")
+		}
+	case logstore.BackendSQLite, logstore.BackendPostgres:
+		if cfg.LogDSN == "" {
+			problems = append(problems, "logDsn must not be empty for the "+cfg.LogBackend+" log backend")
+		}
+	default:
+		problems = append(problems, "unsupported logBackend: "+cfg.LogBackend)
+	}
+
+	if err := logging.Validate(cfg); err != nil {
+		problems = append(problems, err.Error())
+	}
+
+	switch role {
+	case RoleMaster:
+		if cfg.ApiPort <= 0 || cfg.ApiPort > 65535 {
+			problems = append(problems, fmt.Sprintf("apiPort %d is out of range", cfg.ApiPort))
+		}
+		if cfg.MaxJobTimeout < 0 {
+			problems = append(problems, "maxJobTimeout must not be negative")
+		}
+		if cfg.MinCronInterval < 0 {
+			problems = append(problems, "minCronInterval must not be negative")
+		}
+		if cfg.DigestSchedule != "" {
+			if _, err := cronParser.Parse(cfg.DigestSchedule); err != nil {
+				problems = append(problems, "invalid digestSchedule: "+err.Error())
+			}
+		}
+		if cfg.ArchiveDisabledDays < 0 || cfg.ArchiveNoticeDays < 0 {
+			problems = append(problems, "archiveDisabledDays and archiveNoticeDays must not be negative")
+		}
+		if cfg.ArchiveSchedule != "" {
+			if _, err := cronParser.Parse(cfg.ArchiveSchedule); err != nil {
+				problems = append(problems, "invalid archiveSchedule: "+err.Error())
+			}
+		}
+	case RoleWorker:
+		if cfg.WorkerID == "" {
+			problems = append(problems, "workerId must not be empty")
+		}
+		if cfg.HeartbeatInterval <= 0 {
+			problems = append(problems, "heartbeatInterval must be positive")
+		}
+		if cfg.ExecutorThreads <= 0 {
+			problems = append(problems, "executorThreads must be positive")
+		}
+		if cfg.JobLockTTL <= 0 {
+			problems = append(problems, "jobLockTtl must be positive")
+		}
+		if cfg.DrainTimeout < 0 {
+			problems = append(problems, "drainTimeout must not be negative")
+		}
+		if cfg.AdminPort < 0 || cfg.AdminPort > 65535 {
+			problems = append(problems, fmt.Sprintf("adminPort %d is out of range", cfg.AdminPort))
+		}
+	}
+
+	if len(problems) > 0 {
+		return errors.New(strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// CheckEtcd 通过写入、读取和删除探测key检查etcd的连通性和读写权限
+func CheckEtcd(client *etcd.Client) (string, error) {
+	hostname, _ := os.Hostname()
+	key := common.CronRootDir + "selfcheck/" + hostname
+	value := time.Now().Format(time.RFC3339)
+
+	if _, err := client.Put(key, value); err != nil {
+		return "", fmt.Errorf("write %s: %v", key, err)
+	}
+	resp, err := client.Get(key)
+	if err != nil {
+		return "", fmt.Errorf("read %s: %v", key, err)
+	}
+	if resp.Count == 0 || string(resp.Kvs[0].Value) != value {
+		return "", fmt.Errorf("read %s: value mismatch", key)
+	}
+	if _, err = client.Delete(key); err != nil {
+		return "", fmt.Errorf("delete %s: %v", key, err)
+	}
+
+	jobs, err := client.GetWithPrefix(common.JobSaveDir)
+	if err != nil {
+		return "", fmt.Errorf("list %s: %v", common.JobSaveDir, err)
+	}
+
+	return fmt.Sprintf("read/write ok, %d jobs, revision %d", jobs.Count, jobs.Header.Revision), nil
+}
+
+// schemaVersioned 带schema迁移的日志存储
+type schemaVersioned interface {
+	SchemaVersion() (int, error)
+	LatestSchemaVersion() int
+}
+
+// CheckLogStore 检查日志存储可读以及schema迁移（索引）是否完整
+func CheckLogStore(store logstore.LogStore) (string, error) {
+	count, err := store.CountJobLogs("", nil)
+	if err != nil {
+		return "", fmt.Errorf("count logs: %v", err)
+	}
+
+	versioned, ok := store.(schemaVersioned)
+	if !ok {
+		return fmt.Sprintf("%d logs", count), nil
+	}
+	current, err := versioned.SchemaVersion()
+	if err != nil {
+		return "", fmt.Errorf("query schema version: %v", err)
+	}
+	if latest := versioned.LatestSchemaVersion(); current < latest {
+		return "", fmt.Errorf("schema version %d is behind %d, indexes may be missing", current, latest)
+	}
+
+	return fmt.Sprintf("%d logs, schema version %d", count, current), nil
+}
+
+// serverClock 可以查询服务器时间的存储
+type serverClock interface {
+	ServerTime() (time.Time, error)
+}
+
+// CheckClock 检查本机时钟：不早于构建时间，与日志存储服务器的偏差不超过MaxClockSkew
+func CheckClock(store logstore.LogStore) (string, error) {
+	now := time.Now()
+	if built, err := time.Parse(time.RFC3339, version.BuildDate); err == nil && now.Before(built) {
+		return "", fmt.Errorf("local clock %s is earlier than build date %s", now.Format(time.RFC3339), version.BuildDate)
+	}
+
+	clock, ok := store.(serverClock)
+	if !ok {
+		return "", &Warning{Message: "log store does not report server time, skew not checked"}
+	}
+	serverTime, err := clock.ServerTime()
+	if err != nil {
+		return "", &Warning{Message: "failed to query server time: " + err.Error()}
+	}
+
+	skew := now.Sub(serverTime)
+	if skew < 0 {
+		skew = -skew
+	}
+	// 服务器时间只精确到秒
+	if skew > MaxClockSkew+time.Second {
+		return "", fmt.Errorf("local clock differs from log store by %s (max %s)", skew.Round(time.Millisecond), MaxClockSkew)
+	}
+
+	return fmt.Sprintf("skew %s", skew.Round(time.Millisecond)), nil
+}
@@ -0,0 +1,89 @@
+package selfcheck
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fyerfyer/scheduler-refactor/config"
+	"github.com/fyerfyer/scheduler-refactor/pkg/sqlstore"
+)
+
+func TestCheckConfig(t *testing.T) {
+	cfg := &config.Config{
+		EtcdEndpoints:     []string{"localhost:2379"},
+		EtcdDialTimeout:   5000,
+		ApiPort:           8070,
+		WorkerID:          "worker-1",
+		HeartbeatInterval: 5000,
+		ExecutorThreads:   10,
+		JobLockTTL:        5,
+		LogBackend:        "sqlite",
+		LogDSN:            "file:logs.db",
+	}
+	assert.NoError(t, CheckConfig(cfg, RoleMaster))
+	assert.NoError(t, CheckConfig(cfg, RoleWorker))
+
+	cfg.LogDSN = ""
+	cfg.ApiPort = 70000
+	cfg.ExecutorThreads = 0
+	err := CheckConfig(cfg, RoleMaster)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "logDsn")
+	assert.Contains(t, err.Error(), "apiPort 70000")
+	assert.NotContains(t, err.Error(), "executorThreads", "Worker settings should not be checked for master")
+
+	cfg.DrainTimeout = -1
+	err = CheckConfig(cfg, RoleWorker)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "drainTimeout must not be negative")
+
+	cfg.DigestSchedule = "0 9 * * 1"
+	err = CheckConfig(cfg, RoleMaster)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid digestSchedule")
+
+	cfg.ArchiveDisabledDays = -1
+	err = CheckConfig(cfg, RoleMaster)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "archiveDisabledDays and archiveNoticeDays must not be negative")
+
+	cfg.LogEncoding = "text"
+	err = CheckConfig(cfg, RoleWorker)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid logEncoding")
+}
+
+func TestReport(t *testing.T) {
+	report := &Report{}
+	report.Run("ok", func() (string, error) { return "fine", nil })
+	report.Run("warn", func() (string, error) { return "", &Warning{Message: "careful"} })
+	assert.False(t, report.Failed(), "Warnings should not fail the check")
+
+	report.Run("fail", func() (string, error) { return "", errors.New("broken") })
+	assert.True(t, report.Failed())
+
+	var out bytes.Buffer
+	report.Print(&out)
+	assert.Contains(t, out.String(), "[WARN] warn       careful")
+	assert.Contains(t, out.String(), "[FAIL] fail       broken")
+	assert.Contains(t, out.String(), "self-check failed")
+}
+
+func TestCheckLogStoreAndClock(t *testing.T) {
+	store, err := sqlstore.NewClient("sqlite", "file:"+filepath.Join(t.TempDir(), "logs.db"))
+	require.NoError(t, err)
+	defer store.Close()
+
+	message, err := CheckLogStore(store)
+	require.NoError(t, err)
+	assert.Contains(t, message, "0 logs, schema version")
+
+	message, err = CheckClock(store)
+	require.NoError(t, err)
+	assert.Contains(t, message, "skew")
+}
@@ -0,0 +1,418 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	_ "github.com/lib/pq"
+	_ "modernc.org/sqlite"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+)
+
+// 支持的SQL方言
+const (
+	dialectSQLite   = "sqlite"
+	dialectPostgres = "postgres"
+)
+
+// 表名，与MongoDB集合名保持一致
+const (
+	logTable   = common.LogCollectionName
+	statsTable = common.StatsCollectionName
+)
+
+// Client SQL日志存储客户端，SQLite用于单节点部署，Postgres用于高可用部署
+type Client struct {
+	db      *sql.DB
+	dialect string
+}
+
+// NewClient 创建SQL日志存储客户端，并自动执行schema迁移
+func NewClient(dialect, dsn string) (*Client, error) {
+	if dialect != dialectSQLite && dialect != dialectPostgres {
+		return nil, common.NewSQLError("connect", "", fmt.Errorf("unsupported dialect: %s", dialect))
+	}
+	if dsn == "" {
+		return nil, common.NewSQLError("connect", "", fmt.Errorf("empty dsn for %s", dialect))
+	}
+
+	db, err := sql.Open(dialect, dsn)
+	if err != nil {
+		return nil, common.NewSQLError("connect", "", err)
+	}
+
+	// SQLite只允许单个写连接，避免database is locked错误
+	if dialect == dialectSQLite {
+		db.SetMaxOpenConns(1)
+	}
+
+	// 检查连接
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err = db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, common.NewSQLError("ping", "", err)
+	}
+
+	client := &Client{
+		db:      db,
+		dialect: dialect,
+	}
+
+	// 执行schema迁移
+	if err = client.migrate(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return client, nil
+}
+
+// Close 关闭连接
+func (c *Client) Close() error {
+	return c.db.Close()
+}
+
+// rebind 将?占位符转换为当前方言的占位符
+func (c *Client) rebind(query string) string {
+	if c.dialect != dialectPostgres {
+		return query
+	}
+
+	var builder strings.Builder
+	n := 0
+	for _, ch := range query {
+		if ch == '?' {
+			n++
+			builder.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		builder.WriteRune(ch)
+	}
+
+	return builder.String()
+}
+
+// InsertLogs 批量写入任务日志
+func (c *Client) InsertLogs(logs []*common.JobLog) error {
+	if len(logs) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	tx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		return common.NewSQLError("insert_many", logTable, err)
+	}
+
+	stmt, err := tx.PrepareContext(ctx, c.rebind(`INSERT INTO `+logTable+
+		` (job_name, start_time, end_time, exit_code, worker_ip, namespace, owner, run_id, payload) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`))
+	if err != nil {
+		tx.Rollback()
+		return common.NewSQLError("insert_many", logTable, err)
+	}
+	defer stmt.Close()
+
+	for _, log := range logs {
+		payload, err := json.Marshal(log)
+		if err != nil {
+			tx.Rollback()
+			return common.NewSQLError("insert_many", logTable, err)
+		}
+
+		_, err = stmt.ExecContext(ctx, log.JobName, log.StartTime, log.EndTime, log.ExitCode, log.WorkerIP,
+			common.NamespaceOf(log.Namespace), log.Owner, log.RunID, string(payload))
+		if err != nil {
+			tx.Rollback()
+			return common.NewSQLError("insert_many", logTable, err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return common.NewSQLError("insert_many", logTable, err)
+	}
+
+	return nil
+}
+
+// FindJobLogs 查询任务日志
+func (c *Client) FindJobLogs(jobName string, scope *common.Scope, skip, limit int64) ([]*common.JobLog, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	where, args := buildWhere(jobName, scope)
+	query := `SELECT payload FROM ` + logTable + where + ` ORDER BY start_time DESC, id DESC LIMIT ? OFFSET ?`
+	args = append(args, limit, skip)
+
+	return c.queryLogs(ctx, "find_job_logs", query, args...)
+}
+
+// FindJobLogsByNames 查询多个任务名下的日志
+func (c *Client) FindJobLogsByNames(jobNames []string, scope *common.Scope, skip, limit int64) ([]*common.JobLog, error) {
+	if len(jobNames) == 0 {
+		return []*common.JobLog{}, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	where, args := buildWhere("", scope)
+	condition := "job_name IN (" + strings.TrimSuffix(strings.Repeat("?, ", len(jobNames)), ", ") + ")"
+	if where == "" {
+		where = " WHERE " + condition
+	} else {
+		where += " AND " + condition
+	}
+	for _, jobName := range jobNames {
+		args = append(args, jobName)
+	}
+
+	query := `SELECT payload FROM ` + logTable + where + ` ORDER BY start_time DESC, id DESC LIMIT ? OFFSET ?`
+	args = append(args, limit, skip)
+
+	return c.queryLogs(ctx, "find_job_logs_by_names", query, args...)
+}
+
+// CountJobLogs 计算任务日志总数
+func (c *Client) CountJobLogs(jobName string, scope *common.Scope) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	where, args := buildWhere(jobName, scope)
+	query := `SELECT COUNT(*) FROM ` + logTable + where
+
+	var count int64
+	if err := c.db.QueryRowContext(ctx, c.rebind(query), args...).Scan(&count); err != nil {
+		return 0, common.NewSQLError("count", logTable, err)
+	}
+
+	return count, nil
+}
+
+// FindJobLogsSince 查询指定时间之后的任务日志
+func (c *Client) FindJobLogsSince(jobName string, scope *common.Scope, timestamp int64) ([]*common.JobLog, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	where, args := buildWhere(jobName, scope, "start_time >= ?", timestamp)
+	query := `SELECT payload FROM ` + logTable + where + ` ORDER BY start_time DESC, id DESC`
+
+	return c.queryLogs(ctx, "find_job_logs_since", query, args...)
+}
+
+// FindJobLogsEndedSince 按结束时间升序查询指定时间之后结束的任务日志
+func (c *Client) FindJobLogsEndedSince(jobName string, scope *common.Scope, timestamp, limit int64) ([]*common.JobLog, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	where, args := buildWhere(jobName, scope, "end_time >= ?", timestamp)
+	query := `SELECT payload FROM ` + logTable + where + ` ORDER BY end_time ASC, id ASC LIMIT ?`
+	args = append(args, limit)
+
+	return c.queryLogs(ctx, "find_job_logs_ended_since", query, args...)
+}
+
+// FindLogByRunID 查询指定执行的日志
+func (c *Client) FindLogByRunID(runID string, scope *common.Scope) (*common.JobLog, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	where, args := buildWhere("", scope, "run_id = ?", runID)
+	query := `SELECT payload FROM ` + logTable + where + ` ORDER BY start_time DESC, id DESC LIMIT 1`
+
+	logs, err := c.queryLogs(ctx, "find_log_by_run_id", query, args...)
+	if err != nil || len(logs) == 0 {
+		return nil, err
+	}
+	return logs[0], nil
+}
+
+// DeleteOldLogs 删除过期日志
+func (c *Client) DeleteOldLogs(before time.Time) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	result, err := c.db.ExecContext(ctx, c.rebind(`DELETE FROM `+logTable+` WHERE end_time < ?`), before.Unix())
+	if err != nil {
+		return 0, common.NewSQLError("delete_old_logs", logTable, err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, common.NewSQLError("delete_old_logs", logTable, err)
+	}
+
+	return deleted, nil
+}
+
+// CountOldLogs 统计过期日志数量
+func (c *Client) CountOldLogs(before time.Time) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var count int64
+	err := c.db.QueryRowContext(ctx, c.rebind(`SELECT COUNT(*) FROM `+logTable+` WHERE end_time < ?`), before.Unix()).Scan(&count)
+	if err != nil {
+		return 0, common.NewSQLError("count_old_logs", logTable, err)
+	}
+
+	return count, nil
+}
+
+// UpsertDailyStats 写入按天汇总的统计
+func (c *Client) UpsertDailyStats(stats []*common.JobDailyStats) error {
+	if len(stats) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	tx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		return common.NewSQLError("upsert_daily_stats", statsTable, err)
+	}
+
+	stmt, err := tx.PrepareContext(ctx, c.rebind(`INSERT INTO `+statsTable+
+		` (job_name, day, namespace, owner, payload) VALUES (?, ?, ?, ?, ?)`+
+		` ON CONFLICT (job_name, day) DO UPDATE SET namespace = excluded.namespace, owner = excluded.owner, payload = excluded.payload`))
+	if err != nil {
+		tx.Rollback()
+		return common.NewSQLError("upsert_daily_stats", statsTable, err)
+	}
+	defer stmt.Close()
+
+	for _, s := range stats {
+		payload, err := json.Marshal(s)
+		if err != nil {
+			tx.Rollback()
+			return common.NewSQLError("upsert_daily_stats", statsTable, err)
+		}
+
+		_, err = stmt.ExecContext(ctx, s.JobName, s.Day, common.NamespaceOf(s.Namespace), s.Owner, string(payload))
+		if err != nil {
+			tx.Rollback()
+			return common.NewSQLError("upsert_daily_stats", statsTable, err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return common.NewSQLError("upsert_daily_stats", statsTable, err)
+	}
+
+	return nil
+}
+
+// FindDailyStats 查询按天汇总的统计
+func (c *Client) FindDailyStats(jobName string, scope *common.Scope, from, to int64) ([]*common.JobDailyStats, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	where, args := buildWhere(jobName, scope, "day >= ?", from, "day < ?", to)
+	query := `SELECT payload FROM ` + statsTable + where + ` ORDER BY day ASC, job_name ASC`
+
+	rows, err := c.db.QueryContext(ctx, c.rebind(query), args...)
+	if err != nil {
+		return nil, common.NewSQLError("find_daily_stats", statsTable, err)
+	}
+	defer rows.Close()
+
+	stats := make([]*common.JobDailyStats, 0)
+	for rows.Next() {
+		var payload string
+		if err = rows.Scan(&payload); err != nil {
+			return nil, common.NewSQLError("find_daily_stats", statsTable, err)
+		}
+
+		s := &common.JobDailyStats{}
+		if err = json.Unmarshal([]byte(payload), s); err != nil {
+			return nil, common.NewSQLError("find_daily_stats", statsTable, err)
+		}
+		stats = append(stats, s)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, common.NewSQLError("find_daily_stats", statsTable, err)
+	}
+
+	return stats, nil
+}
+
+// buildWhere 根据任务名称、调用方范围和附加条件构造WHERE子句，extra为条件和参数交替排列
+func buildWhere(jobName string, scope *common.Scope, extra ...interface{}) (string, []interface{}) {
+	conditions := make([]string, 0, 3)
+	args := make([]interface{}, 0)
+
+	for i := 0; i+1 < len(extra); i += 2 {
+		conditions = append(conditions, extra[i].(string))
+		args = append(args, extra[i+1])
+	}
+
+	if jobName != "" {
+		conditions = append(conditions, "job_name = ?")
+		args = append(args, jobName)
+	}
+
+	if scope != nil {
+		scopeConditions := make([]string, 0, 2)
+		if len(scope.Namespaces) > 0 {
+			placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(scope.Namespaces)), ", ")
+			scopeConditions = append(scopeConditions, "namespace IN ("+placeholders+")")
+			for _, ns := range scope.Namespaces {
+				args = append(args, ns)
+			}
+		}
+		if scope.Owner != "" {
+			scopeConditions = append(scopeConditions, "owner = ?")
+			args = append(args, scope.Owner)
+		}
+
+		// 没有任何可访问范围，不匹配任何日志
+		if len(scopeConditions) == 0 {
+			scopeConditions = append(scopeConditions, "1 = 0")
+		}
+		conditions = append(conditions, "("+strings.Join(scopeConditions, " OR ")+")")
+	}
+
+	if len(conditions) == 0 {
+		return "", args
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// queryLogs 执行查询并解析日志
+func (c *Client) queryLogs(ctx context.Context, operation, query string, args ...interface{}) ([]*common.JobLog, error) {
+	rows, err := c.db.QueryContext(ctx, c.rebind(query), args...)
+	if err != nil {
+		return nil, common.NewSQLError(operation, logTable, err)
+	}
+	defer rows.Close()
+
+	logs := make([]*common.JobLog, 0)
+	for rows.Next() {
+		var payload string
+		if err = rows.Scan(&payload); err != nil {
+			return nil, common.NewSQLError(operation, logTable, err)
+		}
+
+		log := &common.JobLog{}
+		if err = json.Unmarshal([]byte(payload), log); err != nil {
+			return nil, common.NewSQLError(operation, logTable, err)
+		}
+		logs = append(logs, log)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, common.NewSQLError(operation, logTable, err)
+	}
+
+	return logs, nil
+}
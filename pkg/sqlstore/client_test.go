@@ -0,0 +1,219 @@
+package sqlstore
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+)
+
+func setupSQLiteClient(t *testing.T) *Client {
+	dsn := "file:" + filepath.Join(t.TempDir(), "logs.db")
+	client, err := NewClient(dialectSQLite, dsn)
+	require.NoError(t, err, "Failed to create sqlite client")
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+func TestNewClientMigrates(t *testing.T) {
+	client := setupSQLiteClient(t)
+
+	version, err := client.SchemaVersion()
+	require.NoError(t, err)
+	assert.Equal(t, client.LatestSchemaVersion(), version, "Schema should be at latest version")
+
+	// 重复迁移应当是幂等的
+	require.NoError(t, client.migrate())
+}
+
+func TestServerTime(t *testing.T) {
+	client := setupSQLiteClient(t)
+
+	serverTime, err := client.ServerTime()
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now(), serverTime, 2*time.Second)
+}
+
+func TestInsertAndFindLogs(t *testing.T) {
+	client := setupSQLiteClient(t)
+
+	now := time.Now().Unix()
+	logs := []*common.JobLog{
+		{JobName: "job-a", Command: "echo 1", Output: "1", StartTime: now - 20, EndTime: now - 19, WorkerIP: "w1"},
+		{JobName: "job-a", Command: "echo 2", Output: "2", StartTime: now - 10, EndTime: now - 9, ExitCode: 1, WorkerIP: "w1"},
+		{JobName: "job-b", Command: "echo 3", Output: "3", StartTime: now, EndTime: now + 1, IsTimeout: true, WorkerIP: "w2"},
+	}
+	require.NoError(t, client.InsertLogs(logs))
+
+	found, err := client.FindJobLogs("job-a", nil, 0, 10)
+	require.NoError(t, err)
+	require.Len(t, found, 2)
+	assert.Equal(t, "2", found[0].Output, "Logs should be sorted by startTime desc")
+	assert.Equal(t, 1, found[0].ExitCode)
+
+	found, err = client.FindJobLogs("", nil, 1, 1)
+	require.NoError(t, err)
+	require.Len(t, found, 1)
+	assert.Equal(t, "2", found[0].Output, "Skip and limit should apply")
+
+	count, err := client.CountJobLogs("", nil)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), count)
+
+	since, err := client.FindJobLogsSince("job-b", nil, now-5)
+	require.NoError(t, err)
+	require.Len(t, since, 1)
+	assert.True(t, since[0].IsTimeout)
+}
+
+func TestFindJobLogsByNames(t *testing.T) {
+	client := setupSQLiteClient(t)
+
+	now := time.Now().Unix()
+	logs := []*common.JobLog{
+		{JobName: "old-name", Output: "1", StartTime: now - 30, EndTime: now - 29, Namespace: "team-a"},
+		{JobName: "new-name", Output: "2", StartTime: now - 20, EndTime: now - 19, Namespace: "team-a"},
+		{JobName: "other", Output: "3", StartTime: now - 15, EndTime: now - 14, Namespace: "team-a"},
+		{JobName: "old-name", Output: "4", StartTime: now - 10, EndTime: now - 9, Namespace: "team-b"},
+	}
+	require.NoError(t, client.InsertLogs(logs))
+
+	// 多个名称下的日志按开始时间倒序合并，由存储分页
+	found, err := client.FindJobLogsByNames([]string{"new-name", "old-name"}, nil, 0, 10)
+	require.NoError(t, err)
+	require.Len(t, found, 3)
+	assert.Equal(t, "4", found[0].Output)
+	assert.Equal(t, "2", found[1].Output)
+	assert.Equal(t, "1", found[2].Output)
+
+	found, err = client.FindJobLogsByNames([]string{"new-name", "old-name"}, nil, 1, 1)
+	require.NoError(t, err)
+	require.Len(t, found, 1)
+	assert.Equal(t, "2", found[0].Output)
+
+	// 名称列表与访问范围同时生效
+	found, err = client.FindJobLogsByNames([]string{"new-name", "old-name"}, &common.Scope{Namespaces: []string{"team-a"}}, 0, 10)
+	require.NoError(t, err)
+	require.Len(t, found, 2)
+	assert.Equal(t, "2", found[0].Output)
+	assert.Equal(t, "1", found[1].Output)
+
+	found, err = client.FindJobLogsByNames(nil, nil, 0, 10)
+	require.NoError(t, err)
+	assert.Empty(t, found)
+}
+
+func TestFindLogByRunID(t *testing.T) {
+	client := setupSQLiteClient(t)
+
+	now := time.Now().Unix()
+	logs := []*common.JobLog{
+		{JobName: "job-a", Output: "first", StartTime: now - 20, RunID: "run-1", Namespace: "ops"},
+		{JobName: "job-a", Output: "retry", StartTime: now - 10, RunID: "run-1", Namespace: "ops"},
+		{JobName: "job-b", Output: "other", StartTime: now, RunID: "run-2", Namespace: "ops"},
+	}
+	require.NoError(t, client.InsertLogs(logs))
+
+	found, err := client.FindLogByRunID("run-1", nil)
+	require.NoError(t, err)
+	require.NotNil(t, found)
+	assert.Equal(t, "retry", found.Output, "The latest log of the run should be returned")
+
+	found, err = client.FindLogByRunID("run-1", &common.Scope{Namespaces: []string{"payments"}})
+	require.NoError(t, err)
+	assert.Nil(t, found, "Runs outside the caller scope should not be found")
+
+	found, err = client.FindLogByRunID("missing", nil)
+	require.NoError(t, err)
+	assert.Nil(t, found)
+}
+
+func TestDeleteOldLogs(t *testing.T) {
+	client := setupSQLiteClient(t)
+
+	old := time.Now().Add(-10 * 24 * time.Hour).Unix()
+	now := time.Now().Unix()
+	require.NoError(t, client.InsertLogs([]*common.JobLog{
+		{JobName: "old-job", StartTime: old, EndTime: old},
+		{JobName: "new-job", StartTime: now, EndTime: now},
+	}))
+
+	preview, err := client.CountOldLogs(time.Now().AddDate(0, 0, -7))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), preview, "Preview should count only expired logs")
+
+	deleted, err := client.DeleteOldLogs(time.Now().AddDate(0, 0, -7))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+
+	count, err := client.CountJobLogs("", nil)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+}
+
+func TestRebind(t *testing.T) {
+	client := &Client{dialect: dialectPostgres}
+	assert.Equal(t, "SELECT * FROM t WHERE a = $1 AND b = $2", client.rebind("SELECT * FROM t WHERE a = ? AND b = ?"))
+
+	client.dialect = dialectSQLite
+	assert.Equal(t, "SELECT ?", client.rebind("SELECT ?"))
+}
+
+func TestFindJobLogsWithScope(t *testing.T) {
+	client := setupSQLiteClient(t)
+
+	now := time.Now().Unix()
+	require.NoError(t, client.InsertLogs([]*common.JobLog{
+		{JobName: "team-a-job", StartTime: now, EndTime: now, Namespace: "team-a", Owner: "alice"},
+		{JobName: "team-b-job", StartTime: now, EndTime: now, Namespace: "team-b", Owner: "bob"},
+		{JobName: "legacy-job", StartTime: now, EndTime: now},
+	}))
+
+	found, err := client.FindJobLogs("", &common.Scope{Namespaces: []string{"team-a"}}, 0, 10)
+	require.NoError(t, err)
+	require.Len(t, found, 1)
+	assert.Equal(t, "team-a-job", found[0].JobName)
+
+	count, err := client.CountJobLogs("", &common.Scope{Namespaces: []string{common.DefaultNamespace}, Owner: "bob"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count, "Owned jobs and legacy logs in the default namespace should be visible")
+
+	since, err := client.FindJobLogsSince("team-b-job", &common.Scope{Namespaces: []string{"team-a"}}, now-5)
+	require.NoError(t, err)
+	assert.Empty(t, since, "Logs outside the scope should be hidden")
+
+	count, err = client.CountJobLogs("", &common.Scope{})
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), count, "Empty scope should match nothing")
+}
+
+func TestUpsertAndFindDailyStats(t *testing.T) {
+	client := setupSQLiteClient(t)
+
+	day := int64(86400 * 100)
+	require.NoError(t, client.UpsertDailyStats([]*common.JobDailyStats{
+		{JobName: "job-a", Day: day, Namespace: "team-a", TotalCount: 1},
+		{JobName: "job-a", Day: day + 86400, Namespace: "team-a", TotalCount: 2},
+		{JobName: "job-b", Day: day, Namespace: "team-b", TotalCount: 3},
+	}))
+
+	// 重新汇总同一天应覆盖旧记录
+	require.NoError(t, client.UpsertDailyStats([]*common.JobDailyStats{
+		{JobName: "job-a", Day: day, Namespace: "team-a", TotalCount: 5},
+	}))
+
+	stats, err := client.FindDailyStats("job-a", nil, day, day+2*86400)
+	require.NoError(t, err)
+	require.Len(t, stats, 2)
+	assert.Equal(t, 5, stats[0].TotalCount, "Upsert should replace the existing day")
+	assert.Equal(t, day+86400, stats[1].Day, "Stats should be sorted by day asc")
+
+	stats, err = client.FindDailyStats("", &common.Scope{Namespaces: []string{"team-b"}}, day, day+86400)
+	require.NoError(t, err)
+	require.Len(t, stats, 1)
+	assert.Equal(t, "job-b", stats[0].JobName)
+}
@@ -0,0 +1,187 @@
+package sqlstore
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+)
+
+// migration 单个schema迁移步骤
+type migration struct {
+	version  int      // schema版本号，严格递增
+	sqlite   []string // SQLite方言的语句
+	postgres []string // Postgres方言的语句
+}
+
+// migrations 所有schema迁移，按版本号顺序执行，已发布的迁移不允许修改
+var migrations = []migration{
+	{
+		version: 1,
+		sqlite: []string{
+			`CREATE TABLE IF NOT EXISTS job_logs (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				job_name TEXT NOT NULL,
+				start_time BIGINT NOT NULL,
+				end_time BIGINT NOT NULL,
+				exit_code INTEGER NOT NULL,
+				worker_ip TEXT NOT NULL,
+				payload TEXT NOT NULL
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_job_logs_job_start ON job_logs (job_name, start_time DESC)`,
+			`CREATE INDEX IF NOT EXISTS idx_job_logs_end ON job_logs (end_time)`,
+		},
+		postgres: []string{
+			`CREATE TABLE IF NOT EXISTS job_logs (
+				id BIGSERIAL PRIMARY KEY,
+				job_name TEXT NOT NULL,
+				start_time BIGINT NOT NULL,
+				end_time BIGINT NOT NULL,
+				exit_code INTEGER NOT NULL,
+				worker_ip TEXT NOT NULL,
+				payload TEXT NOT NULL
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_job_logs_job_start ON job_logs (job_name, start_time DESC)`,
+			`CREATE INDEX IF NOT EXISTS idx_job_logs_end ON job_logs (end_time)`,
+		},
+	},
+	{
+		// 增加命名空间和负责人列，用于按调用方范围过滤日志
+		version: 2,
+		sqlite: []string{
+			`ALTER TABLE job_logs ADD COLUMN namespace TEXT NOT NULL DEFAULT 'default'`,
+			`ALTER TABLE job_logs ADD COLUMN owner TEXT NOT NULL DEFAULT ''`,
+			`CREATE INDEX IF NOT EXISTS idx_job_logs_namespace ON job_logs (namespace, start_time DESC)`,
+		},
+		postgres: []string{
+			`ALTER TABLE job_logs ADD COLUMN IF NOT EXISTS namespace TEXT NOT NULL DEFAULT 'default'`,
+			`ALTER TABLE job_logs ADD COLUMN IF NOT EXISTS owner TEXT NOT NULL DEFAULT ''`,
+			`CREATE INDEX IF NOT EXISTS idx_job_logs_namespace ON job_logs (namespace, start_time DESC)`,
+		},
+	},
+	{
+		// 增加按天汇总的统计表，原始日志过期后仍可查询长期统计
+		version: 3,
+		sqlite: []string{
+			`CREATE TABLE IF NOT EXISTS job_stats_daily (
+				job_name TEXT NOT NULL,
+				day BIGINT NOT NULL,
+				namespace TEXT NOT NULL DEFAULT 'default',
+				owner TEXT NOT NULL DEFAULT '',
+				payload TEXT NOT NULL,
+				PRIMARY KEY (job_name, day)
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_job_stats_daily_day ON job_stats_daily (day)`,
+		},
+		postgres: []string{
+			`CREATE TABLE IF NOT EXISTS job_stats_daily (
+				job_name TEXT NOT NULL,
+				day BIGINT NOT NULL,
+				namespace TEXT NOT NULL DEFAULT 'default',
+				owner TEXT NOT NULL DEFAULT '',
+				payload TEXT NOT NULL,
+				PRIMARY KEY (job_name, day)
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_job_stats_daily_day ON job_stats_daily (day)`,
+		},
+	},
+	{
+		// 增加执行标识列，用于按runId查询单次执行，已有日志从payload回填
+		version: 4,
+		sqlite: []string{
+			`ALTER TABLE job_logs ADD COLUMN run_id TEXT NOT NULL DEFAULT ''`,
+			`UPDATE job_logs SET run_id = COALESCE(json_extract(payload, '$.runId'), '')`,
+			`CREATE INDEX IF NOT EXISTS idx_job_logs_run ON job_logs (run_id)`,
+		},
+		postgres: []string{
+			`ALTER TABLE job_logs ADD COLUMN IF NOT EXISTS run_id TEXT NOT NULL DEFAULT ''`,
+			`UPDATE job_logs SET run_id = COALESCE(payload::json->>'runId', '')`,
+			`CREATE INDEX IF NOT EXISTS idx_job_logs_run ON job_logs (run_id)`,
+		},
+	},
+}
+
+// migrate 执行尚未应用的schema迁移
+func (c *Client) migrate() error {
+	// 创建版本记录表
+	_, err := c.db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		applied_at BIGINT NOT NULL
+	)`)
+	if err != nil {
+		return common.NewSQLError("create_migrations_table", "schema_migrations", err)
+	}
+
+	// 查询当前版本
+	var current sql.NullInt64
+	if err = c.db.QueryRow(`SELECT MAX(version) FROM schema_migrations`).Scan(&current); err != nil {
+		return common.NewSQLError("query_schema_version", "schema_migrations", err)
+	}
+
+	for _, m := range migrations {
+		if current.Valid && int64(m.version) <= current.Int64 {
+			continue
+		}
+
+		statements := m.sqlite
+		if c.dialect == dialectPostgres {
+			statements = m.postgres
+		}
+
+		// 每个版本在一个事务中执行
+		tx, err := c.db.Begin()
+		if err != nil {
+			return common.NewSQLError("begin_migration", "schema_migrations", err)
+		}
+
+		for _, stmt := range statements {
+			if _, err = tx.Exec(stmt); err != nil {
+				tx.Rollback()
+				return common.NewSQLError("migrate", "schema_migrations", err)
+			}
+		}
+
+		_, err = tx.Exec(c.rebind(`INSERT INTO schema_migrations (version, applied_at) VALUES (?, ?)`),
+			m.version, time.Now().Unix())
+		if err != nil {
+			tx.Rollback()
+			return common.NewSQLError("record_migration", "schema_migrations", err)
+		}
+
+		if err = tx.Commit(); err != nil {
+			return common.NewSQLError("commit_migration", "schema_migrations", err)
+		}
+	}
+
+	return nil
+}
+
+// SchemaVersion 获取当前schema版本
+func (c *Client) SchemaVersion() (int, error) {
+	var version sql.NullInt64
+	if err := c.db.QueryRow(`SELECT MAX(version) FROM schema_migrations`).Scan(&version); err != nil {
+		return 0, common.NewSQLError("query_schema_version", "schema_migrations", err)
+	}
+
+	return int(version.Int64), nil
+}
+
+// LatestSchemaVersion 获取当前代码需要的schema版本
+func (c *Client) LatestSchemaVersion() int {
+	return migrations[len(migrations)-1].version
+}
+
+// ServerTime 获取数据库服务器的当前时间，用于检查时钟偏差
+func (c *Client) ServerTime() (time.Time, error) {
+	query := `SELECT CAST(strftime('%s', 'now') AS INTEGER)`
+	if c.dialect == dialectPostgres {
+		query = `SELECT CAST(EXTRACT(EPOCH FROM NOW()) AS BIGINT)`
+	}
+
+	var seconds int64
+	if err := c.db.QueryRow(query).Scan(&seconds); err != nil {
+		return time.Time{}, common.NewSQLError("query_server_time", "", err)
+	}
+
+	return time.Unix(seconds, 0), nil
+}
@@ -0,0 +1,36 @@
+package version
+
+import (
+	"runtime"
+)
+
+// 构建信息，编译时通过ldflags注入，例如：
+//
+//	go build -ldflags "-X github.com/fyerfyer/scheduler-refactor/pkg/version.Version=v1.2.0 \
+//	  -X github.com/fyerfyer/scheduler-refactor/pkg/version.Commit=$(git rev-parse --short HEAD) \
+//	  -X github.com/fyerfyer/scheduler-refactor/pkg/version.BuildDate=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+var (
+	Version   = "dev"     // 版本号
+	Commit    = "unknown" // git提交
+	BuildDate = "unknown" // 构建时间
+)
+
+// Info 构建信息
+type Info struct {
+	Version   string `json:"version"`   // 版本号
+	Commit    string `json:"commit"`    // git提交
+	BuildDate string `json:"buildDate"` // 构建时间
+	GoVersion string `json:"goVersion"` // Go版本
+	Platform  string `json:"platform"`  // 运行平台
+}
+
+// Get 获取当前进程的构建信息
+func Get() Info {
+	return Info{
+		Version:   Version,
+		Commit:    Commit,
+		BuildDate: BuildDate,
+		GoVersion: runtime.Version(),
+		Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+	}
+}
@@ -0,0 +1,22 @@
+package version
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGet(t *testing.T) {
+	oldVersion, oldCommit := Version, Commit
+	defer func() { Version, Commit = oldVersion, oldCommit }()
+
+	Version = "v1.2.3"
+	Commit = "abc1234"
+
+	info := Get()
+	assert.Equal(t, "v1.2.3", info.Version)
+	assert.Equal(t, "abc1234", info.Commit)
+	assert.Equal(t, runtime.Version(), info.GoVersion)
+	assert.Equal(t, runtime.GOOS+"/"+runtime.GOARCH, info.Platform)
+}
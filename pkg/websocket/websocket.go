@@ -0,0 +1,290 @@
+package websocket
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// acceptGUID RFC 6455中用于计算Sec-WebSocket-Accept的固定GUID
+const acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// 帧类型
+const (
+	OpContinuation = 0x0 // 分片消息的后续帧
+	OpText         = 0x1 // 文本消息
+	OpBinary       = 0x2 // 二进制消息
+	OpClose        = 0x8 // 关闭连接
+	OpPing         = 0x9 // ping
+	OpPong         = 0xA // pong
+)
+
+// 关闭状态码
+const (
+	CloseNormal        = 1000 // 正常关闭
+	CloseProtocolError = 1002 // 协议错误
+	CloseTooLarge      = 1009 // 消息过大
+)
+
+// 连接参数
+const (
+	// MaxMessageSize 客户端消息的最大字节数，超过时以1009关闭连接
+	MaxMessageSize = 64 * 1024
+
+	// writeTimeout 单次写出的超时时间，避免失联的客户端阻塞发送方
+	writeTimeout = 10 * time.Second
+
+	// maxControlPayload 控制帧的最大负载
+	maxControlPayload = 125
+)
+
+var (
+	// ErrNotWebSocket 请求不是WebSocket升级请求
+	ErrNotWebSocket = errors.New("not a websocket upgrade request")
+
+	// ErrClosed 连接已关闭
+	ErrClosed = errors.New("websocket connection closed")
+)
+
+// Conn 服务端的WebSocket连接，可以并发写出，读取需在同一个goroutine中进行
+type Conn struct {
+	conn      net.Conn      // 底层连接
+	reader    *bufio.Reader // 读缓冲，包含握手时已读取的数据
+	writeLock sync.Mutex    // 保证帧完整写出
+	closeOnce sync.Once     // 只发送一次关闭帧
+}
+
+// IsUpgrade 判断请求是否为WebSocket升级请求
+func IsUpgrade(r *http.Request) bool {
+	return headerContains(r.Header, "Connection", "upgrade") &&
+		headerContains(r.Header, "Upgrade", "websocket")
+}
+
+// AcceptKey 根据客户端的Sec-WebSocket-Key计算Sec-WebSocket-Accept
+func AcceptKey(key string) string {
+	sum := sha1.Sum([]byte(key + acceptGUID))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// Upgrade 校验升级请求并完成握手，返回WebSocket连接。
+// 校验失败时不写出响应，由调用方返回错误
+func Upgrade(w http.ResponseWriter, r *http.Request) (*Conn, error) {
+	if r.Method != http.MethodGet || !IsUpgrade(r) {
+		return nil, ErrNotWebSocket
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		return nil, fmt.Errorf("unsupported websocket version %q, only 13 is supported", r.Header.Get("Sec-WebSocket-Version"))
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if key == "" {
+		return nil, fmt.Errorf("missing Sec-WebSocket-Key")
+	}
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		return nil, fmt.Errorf("connection does not support websocket upgrade")
+	}
+	netConn, rw, err := hijacker.Hijack()
+	if err != nil {
+		return nil, err
+	}
+
+	response := "HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + AcceptKey(key) + "\r\n\r\n"
+	netConn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	if _, err = netConn.Write([]byte(response)); err != nil {
+		netConn.Close()
+		return nil, err
+	}
+	netConn.SetDeadline(time.Time{})
+
+	return &Conn{conn: netConn, reader: rw.Reader}, nil
+}
+
+// WriteText 发送一条文本消息
+func (c *Conn) WriteText(data []byte) error {
+	return c.writeFrame(OpText, data)
+}
+
+// Ping 发送ping，客户端失联时写出失败
+func (c *Conn) Ping() error {
+	return c.writeFrame(OpPing, nil)
+}
+
+// ReadMessage 读取客户端的下一条消息，自动回复ping并处理关闭。
+// 客户端关闭连接时返回ErrClosed
+func (c *Conn) ReadMessage() (int, []byte, error) {
+	var (
+		opcode  int
+		message []byte
+	)
+	for {
+		fin, op, payload, err := c.readFrame()
+		if err != nil {
+			return 0, nil, err
+		}
+
+		switch op {
+		case OpPing:
+			if err = c.writeFrame(OpPong, payload); err != nil {
+				return 0, nil, err
+			}
+			continue
+		case OpPong:
+			continue
+		case OpClose:
+			c.closeWith(closeCode(payload))
+			return 0, nil, ErrClosed
+		case OpText, OpBinary:
+			if opcode != 0 {
+				return 0, nil, c.fail(CloseProtocolError, "new message started before previous one finished")
+			}
+			opcode = op
+		case OpContinuation:
+			if opcode == 0 {
+				return 0, nil, c.fail(CloseProtocolError, "unexpected continuation frame")
+			}
+		default:
+			return 0, nil, c.fail(CloseProtocolError, fmt.Sprintf("unknown opcode %d", op))
+		}
+
+		if len(message)+len(payload) > MaxMessageSize {
+			return 0, nil, c.fail(CloseTooLarge, "message too large")
+		}
+		message = append(message, payload...)
+		if fin {
+			return opcode, message, nil
+		}
+	}
+}
+
+// Close 发送关闭帧并关闭连接
+func (c *Conn) Close() error {
+	c.closeWith(CloseNormal)
+	return c.conn.Close()
+}
+
+// readFrame 读取一个客户端帧，客户端发送的帧必须带掩码
+func (c *Conn) readFrame() (bool, int, []byte, error) {
+	var header [2]byte
+	if _, err := io.ReadFull(c.reader, header[:]); err != nil {
+		return false, 0, nil, err
+	}
+
+	fin := header[0]&0x80 != 0
+	opcode := int(header[0] & 0x0f)
+	if header[0]&0x70 != 0 {
+		return false, 0, nil, c.fail(CloseProtocolError, "reserved bits must be zero")
+	}
+	if header[1]&0x80 == 0 {
+		return false, 0, nil, c.fail(CloseProtocolError, "client frames must be masked")
+	}
+
+	length := uint64(header[1] & 0x7f)
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(c.reader, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(c.reader, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = binary.BigEndian.Uint64(ext[:])
+	}
+
+	if opcode >= OpClose && (!fin || length > maxControlPayload) {
+		return false, 0, nil, c.fail(CloseProtocolError, "invalid control frame")
+	}
+	if length > MaxMessageSize {
+		return false, 0, nil, c.fail(CloseTooLarge, "message too large")
+	}
+
+	var mask [4]byte
+	if _, err := io.ReadFull(c.reader, mask[:]); err != nil {
+		return false, 0, nil, err
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(c.reader, payload); err != nil {
+		return false, 0, nil, err
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return fin, opcode, payload, nil
+}
+
+// writeFrame 写出一个不带掩码的完整帧
+func (c *Conn) writeFrame(opcode int, payload []byte) error {
+	frame := make([]byte, 0, len(payload)+10)
+	frame = append(frame, 0x80|byte(opcode))
+
+	length := len(payload)
+	switch {
+	case length < 126:
+		frame = append(frame, byte(length))
+	case length <= 0xffff:
+		frame = append(frame, 126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(length))
+	default:
+		frame = append(frame, 127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(length))
+	}
+	frame = append(frame, payload...)
+
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+
+	c.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	_, err := c.conn.Write(frame)
+	return err
+}
+
+// closeWith 发送关闭帧，只发送一次
+func (c *Conn) closeWith(code int) {
+	c.closeOnce.Do(func() {
+		payload := binary.BigEndian.AppendUint16(nil, uint16(code))
+		c.writeFrame(OpClose, payload)
+	})
+}
+
+// fail 以状态码关闭连接并返回协议错误
+func (c *Conn) fail(code int, reason string) error {
+	c.closeWith(code)
+	c.conn.Close()
+	return fmt.Errorf("websocket protocol error: %s", reason)
+}
+
+// closeCode 解析关闭帧中的状态码，没有状态码时按正常关闭处理
+func closeCode(payload []byte) int {
+	if len(payload) < 2 {
+		return CloseNormal
+	}
+	return int(binary.BigEndian.Uint16(payload))
+}
+
+// headerContains 判断逗号分隔的请求头中是否包含token，不区分大小写
+func headerContains(header http.Header, name, token string) bool {
+	for _, value := range header.Values(name) {
+		for _, item := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(item), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
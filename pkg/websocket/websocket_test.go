@@ -0,0 +1,150 @@
+package websocket
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAcceptKey(t *testing.T) {
+	// RFC 6455 1.3节的示例
+	assert.Equal(t, "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=", AcceptKey("dGhlIHNhbXBsZSBub25jZQ=="))
+}
+
+func TestUpgradeRejectsPlainRequest(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/events", nil)
+	_, err := Upgrade(httptest.NewRecorder(), req)
+	assert.ErrorIs(t, err, ErrNotWebSocket)
+
+	req.Header.Set("Connection", "keep-alive, Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	assert.True(t, IsUpgrade(req))
+	_, err = Upgrade(httptest.NewRecorder(), req)
+	assert.Error(t, err, "version 13 is required")
+}
+
+func TestConn(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := Upgrade(w, r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer conn.Close()
+
+		conn.WriteText([]byte("hello"))
+		for {
+			opcode, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			if opcode == OpText {
+				conn.WriteText(append([]byte("echo:"), data...))
+			}
+		}
+	}))
+	defer server.Close()
+
+	netConn, err := net.Dial("tcp", server.Listener.Addr().String())
+	require.NoError(t, err)
+	defer netConn.Close()
+
+	io.WriteString(netConn, "GET / HTTP/1.1\r\nHost: test\r\nConnection: Upgrade\r\nUpgrade: websocket\r\n"+
+		"Sec-WebSocket-Version: 13\r\nSec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\n\r\n")
+	reader := bufio.NewReader(netConn)
+	resp, err := http.ReadResponse(reader, nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+	assert.Equal(t, "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=", resp.Header.Get("Sec-WebSocket-Accept"))
+
+	opcode, payload := readServerFrame(t, reader)
+	assert.Equal(t, OpText, opcode)
+	assert.Equal(t, "hello", string(payload))
+
+	// ping由服务端自动回复pong
+	writeClientFrame(t, netConn, true, OpPing, []byte("p"))
+	opcode, payload = readServerFrame(t, reader)
+	assert.Equal(t, OpPong, opcode)
+	assert.Equal(t, "p", string(payload))
+
+	// 分片的文本消息合并后返回
+	writeClientFrame(t, netConn, false, OpText, []byte("ab"))
+	writeClientFrame(t, netConn, true, OpContinuation, []byte("cd"))
+	opcode, payload = readServerFrame(t, reader)
+	assert.Equal(t, OpText, opcode)
+	assert.Equal(t, "echo:abcd", string(payload))
+
+	writeClientFrame(t, netConn, true, OpClose, binary.BigEndian.AppendUint16(nil, CloseNormal))
+	opcode, payload = readServerFrame(t, reader)
+	assert.Equal(t, OpClose, opcode)
+	assert.Equal(t, uint16(CloseNormal), binary.BigEndian.Uint16(payload))
+}
+
+func TestConnRejectsUnmaskedFrame(t *testing.T) {
+	result := make(chan error, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := Upgrade(w, r)
+		if err != nil {
+			result <- err
+			return
+		}
+		_, _, err = conn.ReadMessage()
+		result <- err
+	}))
+	defer server.Close()
+
+	netConn, err := net.Dial("tcp", server.Listener.Addr().String())
+	require.NoError(t, err)
+	defer netConn.Close()
+
+	io.WriteString(netConn, "GET / HTTP/1.1\r\nHost: test\r\nConnection: Upgrade\r\nUpgrade: websocket\r\n"+
+		"Sec-WebSocket-Version: 13\r\nSec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\n\r\n")
+	reader := bufio.NewReader(netConn)
+	_, err = http.ReadResponse(reader, nil)
+	require.NoError(t, err)
+
+	netConn.Write([]byte{0x81, 0x01, 'x'})
+	assert.ErrorContains(t, <-result, "masked")
+
+	opcode, payload := readServerFrame(t, reader)
+	assert.Equal(t, OpClose, opcode)
+	assert.Equal(t, uint16(CloseProtocolError), binary.BigEndian.Uint16(payload))
+}
+
+// readServerFrame 读取一个不带掩码的服务端帧
+func readServerFrame(t *testing.T, reader *bufio.Reader) (int, []byte) {
+	var header [2]byte
+	_, err := io.ReadFull(reader, header[:])
+	require.NoError(t, err)
+	require.Zero(t, header[1]&0x80, "server frames must not be masked")
+
+	length := int(header[1] & 0x7f)
+	require.Less(t, length, 126)
+	payload := make([]byte, length)
+	_, err = io.ReadFull(reader, payload)
+	require.NoError(t, err)
+	return int(header[0] & 0x0f), payload
+}
+
+// writeClientFrame 写出一个带掩码的客户端帧
+func writeClientFrame(t *testing.T, w io.Writer, fin bool, opcode int, payload []byte) {
+	first := byte(opcode)
+	if fin {
+		first |= 0x80
+	}
+	mask := []byte{1, 2, 3, 4}
+	frame := []byte{first, 0x80 | byte(len(payload))}
+	frame = append(frame, mask...)
+	for i, b := range payload {
+		frame = append(frame, b^mask[i%4])
+	}
+	_, err := w.Write(frame)
+	require.NoError(t, err)
+}
@@ -0,0 +1,182 @@
+package admin
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+	"github.com/fyerfyer/scheduler-refactor/config"
+	"github.com/fyerfyer/scheduler-refactor/pkg/healthcheck"
+	"github.com/fyerfyer/scheduler-refactor/pkg/metrics"
+	"github.com/fyerfyer/scheduler-refactor/worker/joblock"
+	"github.com/fyerfyer/scheduler-refactor/worker/runstats"
+	"github.com/fyerfyer/scheduler-refactor/worker/tracer"
+)
+
+// Server worker管理接口服务器，用于排查调度问题
+type Server struct {
+	engine     *gin.Engine         // gin引擎
+	httpServer *http.Server        // HTTP服务器
+	logger     *zap.Logger         // 日志对象
+	tracer     *tracer.Tracer      // 调度决策追踪器
+	lockGuard  *joblock.Guard      // 抢锁统计和限流
+	stats      *runstats.Collector // 执行统计
+}
+
+// traceRequest 开关调度决策追踪的请求
+type traceRequest struct {
+	Enabled bool `json:"enabled"` // 是否开启追踪
+}
+
+// NewServer 创建管理接口服务器
+func NewServer(logger *zap.Logger, t *tracer.Tracer, lockGuard *joblock.Guard) *Server {
+	gin.SetMode(gin.ReleaseMode)
+	engine := gin.New()
+	engine.Use(gin.Recovery())
+
+	server := &Server{
+		engine:    engine,
+		logger:    logger,
+		tracer:    t,
+		lockGuard: lockGuard,
+	}
+	server.registerRoutes()
+
+	return server
+}
+
+// SetStats 设置执行统计
+func (s *Server) SetStats(stats *runstats.Collector) {
+	s.stats = stats
+}
+
+// registerRoutes 注册路由
+func (s *Server) registerRoutes() {
+	s.engine.GET(healthcheck.Path, s.getHealth)
+
+	debugGroup := s.engine.Group("/debug")
+	{
+		debugGroup.GET("/trace", s.getTraceStatus)
+		debugGroup.POST("/trace", s.setTraceStatus)
+		debugGroup.GET("/trace/:name", s.getJobTrace)
+		debugGroup.GET("/locks", s.getLockStats)
+		debugGroup.GET("/metrics", s.getMetrics)
+		debugGroup.GET("/stats", s.getRunStats)
+		debugGroup.GET("/stats/:name", s.getJobRunStats)
+	}
+}
+
+// Start 启动管理接口服务器
+func (s *Server) Start() error {
+	port := config.GlobalConfig.AdminPort
+	s.logger.Info("starting worker admin server", zap.Int("port", port))
+
+	s.httpServer = &http.Server{
+		Addr:    fmt.Sprintf(":%d", port),
+		Handler: s.engine,
+	}
+
+	err := s.httpServer.ListenAndServe()
+	if err == http.ErrServerClosed {
+		return nil
+	}
+	return err
+}
+
+// Stop 停止管理接口服务器
+func (s *Server) Stop() {
+	if s.httpServer == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	s.httpServer.Shutdown(ctx)
+	s.logger.Info("worker admin server stopped")
+}
+
+// getHealth 健康检查，进程能够处理请求即视为健康
+func (s *Server) getHealth(c *gin.Context) {
+	success(c, gin.H{"status": "ok"})
+}
+
+// getTraceStatus 获取调度决策追踪是否开启
+func (s *Server) getTraceStatus(c *gin.Context) {
+	success(c, gin.H{"enabled": s.tracer.Enabled()})
+}
+
+// setTraceStatus 开启或关闭调度决策追踪
+func (s *Server) setTraceStatus(c *gin.Context) {
+	var req traceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		failure(c, common.ApiParamError, "invalid trace request: "+err.Error())
+		return
+	}
+
+	s.tracer.SetEnabled(req.Enabled)
+	s.logger.Info("scheduler tracing toggled", zap.Bool("enabled", req.Enabled))
+
+	success(c, gin.H{"enabled": req.Enabled})
+}
+
+// getJobTrace 获取任务最近的调度决策事件
+func (s *Server) getJobTrace(c *gin.Context) {
+	jobName := c.Param("name")
+
+	success(c, gin.H{
+		"enabled": s.tracer.Enabled(),
+		"events":  s.tracer.Events(jobName),
+	})
+}
+
+// getLockStats 获取抢锁统计和限流状态
+func (s *Server) getLockStats(c *gin.Context) {
+	success(c, s.lockGuard.Snapshot())
+}
+
+// getMetrics 获取etcd等外部依赖的调用延迟统计
+func (s *Server) getMetrics(c *gin.Context) {
+	success(c, metrics.Snapshot())
+}
+
+// success 返回成功响应
+func success(c *gin.Context, data interface{}) {
+	c.JSON(http.StatusOK, common.ApiResponse{
+		Code:    common.ApiSuccess,
+		Message: "success",
+		Data:    data,
+	})
+}
+
+// failure 返回失败响应
+func failure(c *gin.Context, code int, message string) {
+	c.JSON(http.StatusOK, common.ApiResponse{
+		Code:    code,
+		Message: message,
+		Data:    nil,
+	})
+}
+
+// getRunStats 获取worker启动以来的执行统计
+func (s *Server) getRunStats(c *gin.Context) {
+	if s.stats == nil {
+		success(c, &common.WorkerRunStats{})
+		return
+	}
+	success(c, s.stats.Snapshot())
+}
+
+// getJobRunStats 获取任务在当前worker上的执行计数
+func (s *Server) getJobRunStats(c *gin.Context) {
+	if s.stats == nil {
+		success(c, &runstats.JobCounts{})
+		return
+	}
+	counts := s.stats.Job(c.Param("name"))
+	success(c, &counts)
+}
@@ -0,0 +1,61 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+	"github.com/fyerfyer/scheduler-refactor/worker/joblock"
+	"github.com/fyerfyer/scheduler-refactor/worker/tracer"
+)
+
+func performRequest(server *Server, method, path, body string) common.ApiResponse {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	server.engine.ServeHTTP(w, req)
+
+	var resp common.ApiResponse
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	return resp
+}
+
+func TestJobTrace(t *testing.T) {
+	tr := tracer.NewTracer(false)
+	server := NewServer(zap.NewNop(), tr, joblock.NewGuard(0))
+
+	resp := performRequest(server, http.MethodPost, "/debug/trace", `{"enabled": true}`)
+	require.Equal(t, common.ApiSuccess, resp.Code)
+	assert.True(t, tr.Enabled(), "Tracing should be enabled")
+
+	tr.Record("backup", tracer.StageLock, false, "lock already acquired")
+
+	resp = performRequest(server, http.MethodGet, "/debug/trace/backup", "")
+	require.Equal(t, common.ApiSuccess, resp.Code)
+	data := resp.Data.(map[string]interface{})
+	events := data["events"].([]interface{})
+	require.Len(t, events, 1)
+	assert.Equal(t, tracer.StageLock, events[0].(map[string]interface{})["stage"])
+
+	resp = performRequest(server, http.MethodPost, "/debug/trace", `not json`)
+	assert.Equal(t, common.ApiParamError, resp.Code)
+}
+
+func TestLockStats(t *testing.T) {
+	guard := joblock.NewGuard(10)
+	guard.Observe("backup", 0, nil)
+	server := NewServer(zap.NewNop(), tracer.NewTracer(false), guard)
+
+	resp := performRequest(server, http.MethodGet, "/debug/locks", "")
+	require.Equal(t, common.ApiSuccess, resp.Code)
+	data := resp.Data.(map[string]interface{})
+	assert.Equal(t, float64(10), data["limit"])
+	assert.Contains(t, data["jobs"], "backup")
+}
@@ -0,0 +1,300 @@
+package canary
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"go.etcd.io/etcd/client/v3"
+	"go.uber.org/zap"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+	"github.com/fyerfyer/scheduler-refactor/config"
+	"github.com/fyerfyer/scheduler-refactor/pkg/etcd"
+)
+
+// maxReportAttempts 上报执行结果时遇到并发修改的最多尝试次数
+const maxReportAttempts = 3
+
+// Watcher 监听指定给当前worker的灰度发布，调度时用新定义替换当前定义，并上报灰度执行结果。
+// 观察够执行次数后按成功率全量（写入任务定义）或回滚（删除灰度发布）
+type Watcher struct {
+	etcdClient *etcd.Client                     // etcd客户端
+	logger     *zap.Logger                      // 日志对象
+	workerID   string                           // 当前worker标识
+	releases   map[string]*common.CanaryRelease // 当前worker负责的灰度发布
+	lock       sync.RWMutex                     // 保护releases
+	revision   int64                            // 已加载的版本，不晚于该版本的事件忽略
+	ctx        context.Context                  // 上下文，用于控制退出
+	cancelFunc context.CancelFunc               // 取消函数
+}
+
+// NewWatcher 创建灰度发布监听器
+func NewWatcher(logger *zap.Logger, etcdClient *etcd.Client) *Watcher {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &Watcher{
+		etcdClient: etcdClient,
+		logger:     logger,
+		workerID:   config.GlobalConfig.WorkerID,
+		releases:   make(map[string]*common.CanaryRelease),
+		ctx:        ctx,
+		cancelFunc: cancel,
+	}
+}
+
+// Start 加载进行中的灰度发布并开始监听
+func (w *Watcher) Start() error {
+	// 先监听再加载，避免错过加载期间的变化
+	watchChan := w.etcdClient.WatchWithPrefix(w.ctx, common.CanaryDir)
+	if err := w.load(); err != nil {
+		w.logger.Error("failed to load canary releases", zap.Error(err))
+		return err
+	}
+
+	go w.watchLoop(watchChan)
+
+	w.logger.Info("canary watcher started")
+	return nil
+}
+
+// Stop 停止监听
+func (w *Watcher) Stop() {
+	w.cancelFunc()
+	w.logger.Info("canary watcher stopped")
+}
+
+// Lookup 返回当前worker需要试运行的新定义，没有灰度发布时返回nil
+func (w *Watcher) Lookup(jobName string) *common.Job {
+	w.lock.RLock()
+	defer w.lock.RUnlock()
+
+	release, exists := w.releases[jobName]
+	if !exists {
+		return nil
+	}
+	return release.Job
+}
+
+// Report 上报一次灰度执行结果
+func (w *Watcher) Report(jobName string, succeeded bool) {
+	canaryKey := common.CanaryDir + jobName
+
+	for attempt := 0; attempt < maxReportAttempts; attempt++ {
+		resp, err := w.etcdClient.Get(canaryKey)
+		if err != nil {
+			w.logger.Error("failed to load canary release", zap.String("jobName", jobName), zap.Error(err))
+			return
+		}
+		if resp.Count == 0 {
+			// 灰度发布已被取消或替换
+			return
+		}
+
+		release := &common.CanaryRelease{}
+		if err = json.Unmarshal(resp.Kvs[0].Value, release); err != nil {
+			w.logger.Error("failed to unmarshal canary release", zap.String("jobName", jobName), zap.Error(err))
+			return
+		}
+		if release.Worker != w.workerID {
+			return
+		}
+
+		done, promote := release.Record(succeeded)
+		ops, err := w.reportOps(canaryKey, release, done, promote)
+		if err != nil {
+			w.logger.Error("failed to marshal canary release", zap.String("jobName", jobName), zap.Error(err))
+			return
+		}
+
+		applied, err := w.etcdClient.ApplyIfAllUnchanged(reportGuards(canaryKey, resp.Kvs[0].ModRevision, release, promote), ops...)
+		if err != nil {
+			w.logger.Error("failed to report canary result", zap.String("jobName", jobName), zap.Error(err))
+			return
+		}
+		if !applied {
+			if promote && w.discardIfJobChanged(canaryKey, resp.Kvs[0].ModRevision, release) {
+				return
+			}
+			continue
+		}
+
+		if done {
+			w.logger.Info("canary release finished",
+				zap.String("jobName", jobName),
+				zap.Bool("promoted", promote),
+				zap.Int("successes", release.Successes),
+				zap.Int("failures", release.Failures))
+		}
+		return
+	}
+
+	w.logger.Warn("canary release kept changing, result dropped", zap.String("jobName", jobName))
+}
+
+// reportGuards 上报结果时需要保持不变的key：灰度发布本身，全量时还包括发起灰度时的任务定义
+func reportGuards(canaryKey string, canaryRevision int64, release *common.CanaryRelease, promote bool) map[string]int64 {
+	guards := map[string]int64{canaryKey: canaryRevision}
+	// 旧版本的灰度发布没有记录任务版本，只检查灰度发布本身
+	if promote && release.JobRevision != 0 {
+		guards[common.JobSaveDir+release.Job.Name] = release.JobRevision
+	}
+	return guards
+}
+
+// discardIfJobChanged 灰度期间任务定义被保存或删除时放弃全量并删除灰度发布，避免旧的灰度定义覆盖新保存的定义
+func (w *Watcher) discardIfJobChanged(canaryKey string, canaryRevision int64, release *common.CanaryRelease) bool {
+	if release.JobRevision == 0 {
+		return false
+	}
+
+	resp, err := w.etcdClient.Get(common.JobSaveDir + release.Job.Name)
+	if err != nil {
+		w.logger.Error("failed to load job for canary promotion", zap.String("jobName", release.Job.Name), zap.Error(err))
+		return false
+	}
+	if resp.Count > 0 && resp.Kvs[0].ModRevision == release.JobRevision {
+		return false
+	}
+
+	if _, err = w.etcdClient.ApplyIfUnchanged(canaryKey, canaryRevision, clientv3.OpDelete(canaryKey)); err != nil {
+		w.logger.Error("failed to discard canary release", zap.String("jobName", release.Job.Name), zap.Error(err))
+		return true
+	}
+	w.logger.Warn("job changed during canary release, promotion discarded", zap.String("jobName", release.Job.Name))
+	return true
+}
+
+// reportOps 构建上报结果的etcd操作：未结束时更新计数，全量时写入新定义并删除灰度发布，回滚时只删除灰度发布
+func (w *Watcher) reportOps(canaryKey string, release *common.CanaryRelease, done, promote bool) ([]clientv3.Op, error) {
+	if !done {
+		data, err := json.Marshal(release)
+		if err != nil {
+			return nil, err
+		}
+		return []clientv3.Op{clientv3.OpPut(canaryKey, string(data))}, nil
+	}
+
+	if !promote {
+		return []clientv3.Op{clientv3.OpDelete(canaryKey)}, nil
+	}
+
+	jobData, err := json.Marshal(release.Job)
+	if err != nil {
+		return nil, err
+	}
+	return []clientv3.Op{
+		clientv3.OpPut(common.JobSaveDir+release.Job.Name, string(jobData)),
+		clientv3.OpDelete(canaryKey),
+	}, nil
+}
+
+// watchLoop 监听灰度发布变化，监听中断时重新监听并重新加载
+func (w *Watcher) watchLoop(watchChan clientv3.WatchChan) {
+	for {
+		select {
+		case <-w.ctx.Done():
+			return
+		case watchResp, ok := <-watchChan:
+			if !ok || watchResp.Err() != nil {
+				w.logger.Warn("canary watch interrupted, resyncing", zap.Bool("closed", !ok))
+				watchChan = w.resync()
+				continue
+			}
+
+			for _, event := range watchResp.Events {
+				if event.Kv.ModRevision <= w.revision {
+					continue
+				}
+
+				switch event.Type {
+				case clientv3.EventTypePut:
+					w.apply(string(event.Kv.Key), event.Kv.Value)
+				case clientv3.EventTypeDelete:
+					w.remove(strings.TrimPrefix(string(event.Kv.Key), common.CanaryDir))
+				}
+			}
+		}
+	}
+}
+
+// resync 重新监听并重新加载灰度发布，加载失败时每秒重试直到成功或停止
+func (w *Watcher) resync() clientv3.WatchChan {
+	watchChan := w.etcdClient.WatchWithPrefix(w.ctx, common.CanaryDir)
+
+	for {
+		err := w.load()
+		if err == nil {
+			return watchChan
+		}
+
+		w.logger.Error("failed to resync canary releases, retrying", zap.Error(err))
+		select {
+		case <-w.ctx.Done():
+			return watchChan
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// load 加载全部灰度发布，移除etcd中已不存在的灰度发布
+func (w *Watcher) load() error {
+	resp, err := w.etcdClient.GetWithPrefix(common.CanaryDir)
+	if err != nil {
+		return err
+	}
+
+	current := make(map[string]struct{}, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		w.apply(string(kv.Key), kv.Value)
+		current[strings.TrimPrefix(string(kv.Key), common.CanaryDir)] = struct{}{}
+	}
+
+	w.lock.Lock()
+	for jobName := range w.releases {
+		if _, exists := current[jobName]; !exists {
+			delete(w.releases, jobName)
+		}
+	}
+	w.lock.Unlock()
+	w.revision = resp.Header.Revision
+
+	return nil
+}
+
+// apply 解析灰度发布，只保留指定给当前worker的
+func (w *Watcher) apply(key string, value []byte) {
+	jobName := strings.TrimPrefix(key, common.CanaryDir)
+
+	release := &common.CanaryRelease{}
+	if err := json.Unmarshal(value, release); err != nil || release.Job == nil {
+		w.logger.Error("failed to unmarshal canary release, ignoring", zap.String("jobName", jobName), zap.Error(err))
+		w.remove(jobName)
+		return
+	}
+
+	if release.Worker != w.workerID {
+		w.remove(jobName)
+		return
+	}
+
+	w.lock.Lock()
+	_, exists := w.releases[jobName]
+	w.releases[jobName] = release
+	w.lock.Unlock()
+
+	if !exists {
+		w.logger.Info("canary release assigned to this worker",
+			zap.String("jobName", jobName),
+			zap.Int("runs", release.Runs))
+	}
+}
+
+// remove 移除灰度发布
+func (w *Watcher) remove(jobName string) {
+	w.lock.Lock()
+	delete(w.releases, jobName)
+	w.lock.Unlock()
+}
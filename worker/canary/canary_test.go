@@ -0,0 +1,126 @@
+package canary
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+	"github.com/fyerfyer/scheduler-refactor/config"
+	"github.com/fyerfyer/scheduler-refactor/pkg/etcd"
+)
+
+func newTestWatcher(t *testing.T) *Watcher {
+	return &Watcher{
+		logger:   zaptest.NewLogger(t),
+		workerID: "worker-a",
+		releases: make(map[string]*common.CanaryRelease),
+	}
+}
+
+func TestApplyAndLookup(t *testing.T) {
+	w := newTestWatcher(t)
+
+	mine, _ := json.Marshal(&common.CanaryRelease{Job: &common.Job{Name: "job", Command: "echo v2"}, Worker: "worker-a", Runs: 3})
+	w.apply(common.CanaryDir+"job", mine)
+	require.NotNil(t, w.Lookup("job"))
+	assert.Equal(t, "echo v2", w.Lookup("job").Command)
+
+	// 改为指定给其他worker后，本worker恢复执行当前定义
+	other, _ := json.Marshal(&common.CanaryRelease{Job: &common.Job{Name: "job", Command: "echo v2"}, Worker: "worker-b", Runs: 3})
+	w.apply(common.CanaryDir+"job", other)
+	assert.Nil(t, w.Lookup("job"))
+
+	w.apply(common.CanaryDir+"job", []byte("not json"))
+	assert.Nil(t, w.Lookup("job"), "Unparseable canary should fall back to current definition")
+}
+
+func TestReportOps(t *testing.T) {
+	w := newTestWatcher(t)
+	release := &common.CanaryRelease{Job: &common.Job{Name: "job"}, Worker: "worker-a", Runs: 3}
+	canaryKey := common.CanaryDir + "job"
+
+	ops, err := w.reportOps(canaryKey, release, false, false)
+	require.NoError(t, err)
+	require.Len(t, ops, 1)
+	assert.True(t, ops[0].IsPut(), "Running canary should update its counters")
+
+	ops, err = w.reportOps(canaryKey, release, true, false)
+	require.NoError(t, err)
+	require.Len(t, ops, 1)
+	assert.True(t, ops[0].IsDelete(), "Rollback should only delete the canary")
+
+	ops, err = w.reportOps(canaryKey, release, true, true)
+	require.NoError(t, err)
+	require.Len(t, ops, 2)
+	assert.Equal(t, common.JobSaveDir+"job", string(ops[0].KeyBytes()), "Promotion should write the new definition")
+	assert.True(t, ops[1].IsDelete())
+}
+
+func TestReportGuards(t *testing.T) {
+	release := &common.CanaryRelease{Job: &common.Job{Name: "job"}, JobRevision: 7}
+	canaryKey := common.CanaryDir + "job"
+
+	assert.Equal(t, map[string]int64{canaryKey: 3}, reportGuards(canaryKey, 3, release, false),
+		"Counting and rollback should only guard the canary")
+	assert.Equal(t, map[string]int64{canaryKey: 3, common.JobSaveDir + "job": 7}, reportGuards(canaryKey, 3, release, true),
+		"Promotion should also guard the job definition")
+
+	release.JobRevision = 0
+	assert.Equal(t, map[string]int64{canaryKey: 3}, reportGuards(canaryKey, 3, release, true),
+		"Canaries without a recorded revision should not require the job to be missing")
+}
+
+func TestPromoteAfterJobSaved(t *testing.T) {
+	config.GlobalConfig = &config.Config{
+		EtcdEndpoints:   []string{"localhost:2379"},
+		EtcdDialTimeout: 5000,
+		WorkerID:        "worker-a",
+	}
+	etcdClient, err := etcd.NewClient()
+	require.NoError(t, err, "Failed to create etcd client")
+	defer etcdClient.Close()
+
+	jobName := "test-canary-promote-job"
+	jobKey := common.JobSaveDir + jobName
+	canaryKey := common.CanaryDir + jobName
+	defer etcdClient.Delete(jobKey)
+	defer etcdClient.Delete(canaryKey)
+
+	current, _ := json.Marshal(&common.Job{Name: jobName, Command: "echo v1"})
+	putResp, err := etcdClient.Put(jobKey, string(current))
+	require.NoError(t, err)
+
+	release, _ := json.Marshal(&common.CanaryRelease{
+		Job:            &common.Job{Name: jobName, Command: "echo v2"},
+		Worker:         "worker-a",
+		Runs:           1,
+		MinSuccessRate: 1,
+		JobRevision:    putResp.Header.Revision,
+	})
+	_, err = etcdClient.Put(canaryKey, string(release))
+	require.NoError(t, err)
+
+	// 灰度期间任务被正常保存
+	saved, _ := json.Marshal(&common.Job{Name: jobName, Command: "echo v3"})
+	_, err = etcdClient.Put(jobKey, string(saved))
+	require.NoError(t, err)
+
+	w := newTestWatcher(t)
+	w.etcdClient = etcdClient
+	w.Report(jobName, true)
+
+	resp, err := etcdClient.Get(jobKey)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), resp.Count)
+	job := &common.Job{}
+	require.NoError(t, json.Unmarshal(resp.Kvs[0].Value, job))
+	assert.Equal(t, "echo v3", job.Command, "Promotion should not overwrite a job saved during the canary")
+
+	resp, err = etcdClient.Get(canaryKey)
+	require.NoError(t, err)
+	assert.Zero(t, resp.Count, "Stale canary should be discarded")
+}
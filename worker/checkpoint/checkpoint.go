@@ -0,0 +1,130 @@
+package checkpoint
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+	"github.com/fyerfyer/scheduler-refactor/pkg/etcd"
+)
+
+// Store 代任务保存检查点。执行前把上次失败留下的检查点写入检查点文件，
+// 任务读写该文件记录进度；执行失败时保存文件内容，成功时清除检查点，下次从头执行
+type Store struct {
+	etcdClient *etcd.Client // etcd客户端
+	logger     *zap.Logger  // 日志对象
+	dir        string       // 检查点文件所在目录
+}
+
+// NewStore 创建检查点存储，dir不存在时自动创建
+func NewStore(logger *zap.Logger, etcdClient *etcd.Client, dir string) (*Store, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	return &Store{
+		etcdClient: etcdClient,
+		logger:     logger,
+		dir:        dir,
+	}, nil
+}
+
+// Prepare 为开启检查点的任务准备检查点文件，返回文件路径和执行结束时调用的保存函数
+func (s *Store) Prepare(info *common.JobExecuteInfo) (string, func(succeeded bool)) {
+	jobName := info.Job.Name
+	path := filepath.Join(s.dir, info.RunID)
+
+	previous, err := s.load(jobName)
+	if err != nil {
+		s.logger.Warn("failed to load job checkpoint, starting from scratch",
+			zap.String("jobName", jobName),
+			zap.Error(err))
+	}
+	if err = os.WriteFile(path, previous, 0666); err != nil {
+		s.logger.Warn("failed to create checkpoint file",
+			zap.String("jobName", jobName),
+			zap.String("path", path),
+			zap.Error(err))
+		return "", func(bool) {}
+	}
+
+	return path, func(succeeded bool) {
+		defer os.Remove(path)
+
+		if succeeded {
+			if len(previous) > 0 {
+				s.clear(jobName)
+			}
+			return
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil || len(data) == 0 {
+			return
+		}
+		s.save(info, data)
+	}
+}
+
+// load 读取任务的检查点，没有检查点时返回nil
+func (s *Store) load(jobName string) ([]byte, error) {
+	resp, err := s.etcdClient.Get(common.JobCheckpointDir + jobName)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Count == 0 {
+		return nil, nil
+	}
+
+	checkpoint := &common.JobCheckpoint{}
+	if err = json.Unmarshal(resp.Kvs[0].Value, checkpoint); err != nil {
+		return nil, err
+	}
+	return checkpoint.Data, nil
+}
+
+// save 保存失败执行留下的检查点
+func (s *Store) save(info *common.JobExecuteInfo, data []byte) {
+	jobName := info.Job.Name
+	if len(data) > common.MaxCheckpointSize {
+		s.logger.Warn("job checkpoint too large, not saved",
+			zap.String("jobName", jobName),
+			zap.Int("size", len(data)),
+			zap.Int("maxSize", common.MaxCheckpointSize))
+		return
+	}
+
+	value, err := json.Marshal(&common.JobCheckpoint{
+		JobName: jobName,
+		RunID:   info.RunID,
+		Data:    data,
+		SavedAt: time.Now().Unix(),
+	})
+	if err != nil {
+		return
+	}
+	if _, err = s.etcdClient.Put(common.JobCheckpointDir+jobName, string(value)); err != nil {
+		s.logger.Warn("failed to save job checkpoint",
+			zap.String("jobName", jobName),
+			zap.Error(err))
+		return
+	}
+
+	s.logger.Info("job checkpoint saved",
+		zap.String("jobName", jobName),
+		zap.String("runId", info.RunID),
+		zap.Int("size", len(data)))
+}
+
+// clear 任务成功后清除检查点
+func (s *Store) clear(jobName string) {
+	if _, err := s.etcdClient.Delete(common.JobCheckpointDir + jobName); err != nil {
+		s.logger.Warn("failed to clear job checkpoint",
+			zap.String("jobName", jobName),
+			zap.Error(err))
+	}
+}
@@ -0,0 +1,196 @@
+package cmdpolicy
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"go.etcd.io/etcd/client/v3"
+	"go.uber.org/zap"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+	"github.com/fyerfyer/scheduler-refactor/pkg/etcd"
+	"github.com/fyerfyer/scheduler-refactor/pkg/policy"
+)
+
+// Watcher 监听etcd中的命令策略规则，维护最新的策略引擎供执行器在执行前判定
+type Watcher struct {
+	etcdClient *etcd.Client            // etcd客户端
+	logger     *zap.Logger             // 日志对象
+	rules      map[string]*policy.Rule // 当前规则，key为规则名称
+	engine     *policy.Engine          // 由当前规则编译出的引擎
+	lock       sync.RWMutex            // 保护rules和engine
+	revision   int64                   // 已加载的版本，不晚于该版本的事件忽略
+	ctx        context.Context         // 上下文，用于控制退出
+	cancelFunc context.CancelFunc      // 取消函数
+}
+
+// NewWatcher 创建策略监听器
+func NewWatcher(logger *zap.Logger, etcdClient *etcd.Client) *Watcher {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &Watcher{
+		etcdClient: etcdClient,
+		logger:     logger,
+		rules:      make(map[string]*policy.Rule),
+		ctx:        ctx,
+		cancelFunc: cancel,
+	}
+}
+
+// Start 加载当前规则并开始监听变化
+func (w *Watcher) Start() error {
+	// 先监听再加载，加载版本之前的事件在处理时忽略
+	watchChan := w.etcdClient.WatchWithPrefix(w.ctx, common.PolicyDir)
+	count, err := w.load()
+	if err != nil {
+		w.logger.Error("failed to load policy rules", zap.Error(err))
+		return err
+	}
+
+	go w.watchLoop(watchChan)
+
+	w.logger.Info("command policy watcher started", zap.Int("rules", count))
+	return nil
+}
+
+// Stop 停止监听
+func (w *Watcher) Stop() {
+	w.cancelFunc()
+	w.logger.Info("command policy watcher stopped")
+}
+
+// Evaluate 判定命令是否允许执行
+func (w *Watcher) Evaluate(command string) policy.Decision {
+	w.lock.RLock()
+	defer w.lock.RUnlock()
+
+	return w.engine.Evaluate(command)
+}
+
+// RecordBlock 把执行前被拦截的命令写入拒绝审计，与master保存任务时的拒绝记录在同一目录
+func (w *Watcher) RecordBlock(rejection *common.PolicyRejection) {
+	data, err := json.Marshal(rejection)
+	if err != nil {
+		w.logger.Error("failed to marshal policy rejection", zap.Error(err))
+		return
+	}
+
+	key := common.PolicyAuditKey(rejection, time.Now())
+	if err = w.etcdClient.PutWithLease(key, string(data), common.PolicyAuditTTL); err != nil {
+		w.logger.Error("failed to save policy rejection",
+			zap.String("jobName", rejection.JobName),
+			zap.Error(err))
+	}
+}
+
+// watchLoop 监听规则目录变化，监听中断时重新监听并重新加载
+func (w *Watcher) watchLoop(watchChan clientv3.WatchChan) {
+	for {
+		select {
+		case <-w.ctx.Done():
+			return
+		case watchResp, ok := <-watchChan:
+			if !ok || watchResp.Err() != nil {
+				w.logger.Warn("policy rule watch interrupted, resyncing", zap.Bool("closed", !ok))
+				watchChan = w.resync()
+				continue
+			}
+
+			w.lock.Lock()
+			for _, event := range watchResp.Events {
+				if event.Kv.ModRevision <= w.revision {
+					continue
+				}
+
+				key := string(event.Kv.Key)
+				switch event.Type {
+				case clientv3.EventTypePut:
+					w.applyKV(key, event.Kv.Value)
+				case clientv3.EventTypeDelete:
+					delete(w.rules, key)
+				}
+			}
+			w.rebuild()
+			w.lock.Unlock()
+		}
+	}
+}
+
+// resync 重新监听并重新加载规则，加载失败时每秒重试直到成功或停止
+func (w *Watcher) resync() clientv3.WatchChan {
+	watchChan := w.etcdClient.WatchWithPrefix(w.ctx, common.PolicyDir)
+
+	for {
+		count, err := w.load()
+		if err == nil {
+			w.logger.Info("policy rules resynced", zap.Int("rules", count))
+			return watchChan
+		}
+
+		w.logger.Error("failed to resync policy rules, retrying", zap.Error(err))
+		select {
+		case <-w.ctx.Done():
+			return watchChan
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// load 加载全部规则替换当前规则，返回加载的规则数
+func (w *Watcher) load() (int, error) {
+	resp, err := w.etcdClient.GetWithPrefix(common.PolicyDir)
+	if err != nil {
+		return 0, err
+	}
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	w.rules = make(map[string]*policy.Rule, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		w.applyKV(string(kv.Key), kv.Value)
+	}
+	w.rebuild()
+	w.revision = resp.Header.Revision
+
+	return len(resp.Kvs), nil
+}
+
+// applyKV 解析并保存规则，非法规则会被忽略
+func (w *Watcher) applyKV(key string, value []byte) {
+	rule := &policy.Rule{}
+	if err := json.Unmarshal(value, rule); err != nil {
+		w.logger.Error("failed to unmarshal policy rule",
+			zap.String("key", key),
+			zap.Error(err))
+		return
+	}
+
+	if err := rule.Validate(); err != nil {
+		w.logger.Error("ignoring invalid policy rule",
+			zap.String("key", key),
+			zap.Error(err))
+		return
+	}
+
+	w.rules[key] = rule
+}
+
+// rebuild 根据当前规则重新编译引擎
+func (w *Watcher) rebuild() {
+	rules := make([]*policy.Rule, 0, len(w.rules))
+	for _, rule := range w.rules {
+		rules = append(rules, rule)
+	}
+
+	engine, err := policy.NewEngine(rules)
+	if err != nil {
+		// 规则在applyKV中已校验，这里不应出错，保留旧引擎
+		w.logger.Error("failed to build policy engine", zap.Error(err))
+		return
+	}
+
+	w.engine = engine
+}
@@ -0,0 +1,29 @@
+package cmdpolicy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+)
+
+func TestWatcher_Evaluate(t *testing.T) {
+	w := NewWatcher(zaptest.NewLogger(t), nil)
+
+	assert.True(t, w.Evaluate("rm -rf /").Allowed, "Everything should be allowed without rules")
+
+	w.applyKV(common.PolicyDir+"no-rm-root", []byte(`{"name":"no-rm-root","pattern":"rm\\s+-rf\\s+/\\s*$","action":"deny"}`))
+	w.applyKV(common.PolicyDir+"broken", []byte(`{"name":"broken","pattern":"(","action":"deny"}`))
+	w.rebuild()
+
+	decision := w.Evaluate("rm -rf /")
+	assert.False(t, decision.Allowed)
+	assert.Equal(t, "no-rm-root", decision.Rule)
+	assert.True(t, w.Evaluate("echo hello").Allowed, "Invalid rules should be ignored")
+
+	delete(w.rules, common.PolicyDir+"no-rm-root")
+	w.rebuild()
+	assert.True(t, w.Evaluate("rm -rf /").Allowed, "Deleted rules should no longer apply")
+}
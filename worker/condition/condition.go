@@ -0,0 +1,164 @@
+package condition
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+	"github.com/fyerfyer/scheduler-refactor/config"
+	"github.com/fyerfyer/scheduler-refactor/pkg/etcd"
+	"github.com/fyerfyer/scheduler-refactor/pkg/expr"
+)
+
+// loadavgFile 系统负载文件，只在Linux上存在
+const loadavgFile = "/proc/loadavg"
+
+// Evaluator 在执行前对任务的when条件求值，并记录设置了when条件的任务最近一次执行的结果，
+// 集群内任意worker接手时都能读到上一次执行的状态
+type Evaluator struct {
+	etcdClient *etcd.Client     // etcd客户端
+	logger     *zap.Logger      // 日志对象
+	running    func() int       // 当前worker上正在执行命令的任务数
+	now        func() time.Time // 当前时间，测试时替换
+	load1      func() float64   // 最近1分钟的系统负载，测试时替换
+}
+
+// NewEvaluator 创建when条件求值器，running返回当前worker上正在执行命令的任务数
+func NewEvaluator(logger *zap.Logger, etcdClient *etcd.Client, running func() int) *Evaluator {
+	return &Evaluator{
+		etcdClient: etcdClient,
+		logger:     logger,
+		running:    running,
+		now:        time.Now,
+		load1:      readLoad1,
+	}
+}
+
+// Check 对任务的when条件求值，没有设置条件时返回true。读取上一次执行结果失败或求值出错时返回错误，
+// 调用方应跳过本次执行
+func (e *Evaluator) Check(info *common.JobExecuteInfo) (bool, error) {
+	if info.Job.When == "" {
+		return true, nil
+	}
+
+	when, err := expr.Parse(info.Job.When)
+	if err != nil {
+		return false, fmt.Errorf("invalid when condition: %v", err)
+	}
+	lastRun, err := e.lastRun(info.Job.Name)
+	if err != nil {
+		return false, fmt.Errorf("failed to read last run: %v", err)
+	}
+
+	ok, err := when.EvalBool(e.env(info, lastRun))
+	if err != nil {
+		return false, fmt.Errorf("failed to evaluate when condition: %v", err)
+	}
+	return ok, nil
+}
+
+// Record 记录设置了when条件的任务的执行结果，被跳过的执行和实验命令不记录。写入失败时只记录日志
+func (e *Evaluator) Record(result *common.JobExecuteResult, info *common.JobExecuteInfo) {
+	if info.Job.When == "" || info.Experiment || result.Status == common.RunStatusSkipped {
+		return
+	}
+
+	lastRun := &common.LastRun{
+		RunID:    info.RunID,
+		Status:   result.Status,
+		ExitCode: result.ExitCode,
+		Duration: result.EndTime.Sub(result.StartTime).Seconds(),
+		EndTime:  result.EndTime.Unix(),
+	}
+	data, err := json.Marshal(lastRun)
+	if err != nil {
+		return
+	}
+	if _, err = e.etcdClient.Put(common.JobLastRunDir+info.Job.Name, string(data)); err != nil {
+		e.logger.Warn("failed to record last run",
+			zap.String("jobName", info.Job.Name),
+			zap.String("runId", info.RunID),
+			zap.Error(err))
+	}
+}
+
+// lastRun 读取任务最近一次执行的结果，没有记录时返回nil
+func (e *Evaluator) lastRun(jobName string) (*common.LastRun, error) {
+	resp, err := e.etcdClient.Get(common.JobLastRunDir + jobName)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Count == 0 {
+		return nil, nil
+	}
+
+	lastRun := &common.LastRun{}
+	if err = json.Unmarshal(resp.Kvs[0].Value, lastRun); err != nil {
+		return nil, err
+	}
+	return lastRun, nil
+}
+
+// env 构建求值时的变量，变量与common.WhenVariables一一对应
+func (e *Evaluator) env(info *common.JobExecuteInfo, lastRun *common.LastRun) expr.Env {
+	now := e.now()
+	local := now
+	if location, err := info.Job.Location(); err == nil && location != nil {
+		local = now.In(location)
+	}
+
+	env := expr.Env{
+		"now.hour":          local.Hour(),
+		"now.minute":        local.Minute(),
+		"now.weekday":       int(local.Weekday()),
+		"now.day":           local.Day(),
+		"now.month":         int(local.Month()),
+		"now.unix":          now.Unix(),
+		"lastRun.exists":    lastRun != nil,
+		"lastRun.status":    "",
+		"lastRun.succeeded": false,
+		"lastRun.failed":    false,
+		"lastRun.exitCode":  0,
+		"lastRun.duration":  0,
+		"lastRun.age":       -1,
+		"worker.id":         config.GlobalConfig.WorkerID,
+		"worker.zone":       config.GlobalConfig.Zone,
+		"worker.running":    e.running(),
+		"worker.load1":      e.load1(),
+		"worker.cpus":       runtime.NumCPU(),
+		"manual":            info.TriggeredBy != "",
+	}
+	if lastRun != nil {
+		env["lastRun.status"] = string(lastRun.Status)
+		env["lastRun.succeeded"] = lastRun.Status == common.RunStatusSuccess
+		env["lastRun.failed"] = lastRun.Status.IsFailure()
+		env["lastRun.exitCode"] = lastRun.ExitCode
+		env["lastRun.duration"] = lastRun.Duration
+		env["lastRun.age"] = now.Unix() - lastRun.EndTime
+	}
+	return env
+}
+
+// readLoad1 读取最近1分钟的系统负载，无法读取时返回0
+func readLoad1() float64 {
+	data, err := os.ReadFile(loadavgFile)
+	if err != nil {
+		return 0
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 {
+		return 0
+	}
+	load, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return 0
+	}
+	return load
+}
@@ -0,0 +1,71 @@
+package condition
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+	"github.com/fyerfyer/scheduler-refactor/config"
+	"github.com/fyerfyer/scheduler-refactor/pkg/expr"
+)
+
+// newTestEvaluator 创建不连接etcd、时间和负载固定的求值器
+func newTestEvaluator(now time.Time) *Evaluator {
+	if config.GlobalConfig == nil {
+		config.GlobalConfig = &config.Config{WorkerID: "worker-1", Zone: "zone-a"}
+	}
+	e := NewEvaluator(zap.NewNop(), nil, func() int { return 3 })
+	e.now = func() time.Time { return now }
+	e.load1 = func() float64 { return 1.5 }
+	return e
+}
+
+// eval 在env中对表达式求值
+func eval(t *testing.T, env expr.Env, src string) bool {
+	e, err := expr.Parse(src)
+	require.NoError(t, err, src)
+	ok, err := e.EvalBool(env)
+	require.NoError(t, err, src)
+	return ok
+}
+
+func TestEvaluator_Env(t *testing.T) {
+	now := time.Date(2026, 3, 2, 1, 30, 0, 0, time.UTC) // 周一
+	e := newTestEvaluator(now)
+	info := &common.JobExecuteInfo{
+		Job:         &common.Job{Name: "report", Timezone: "Asia/Shanghai"},
+		TriggeredBy: "alice",
+	}
+
+	lastRun := &common.LastRun{Status: common.RunStatusTimeout, ExitCode: -1, Duration: 12, EndTime: now.Unix() - 60}
+	env := e.env(info, lastRun)
+
+	for name := range common.WhenVariables {
+		assert.Contains(t, env, name, "Every documented variable should be provided")
+	}
+	assert.Len(t, env, len(common.WhenVariables))
+
+	assert.True(t, eval(t, env, "now.hour == 9 && now.weekday == 1"), "Time should be in the job timezone")
+	assert.True(t, eval(t, env, `lastRun.failed && !lastRun.succeeded && lastRun.status == "timeout"`))
+	assert.True(t, eval(t, env, "lastRun.age == 60 && lastRun.duration == 12"))
+	assert.True(t, eval(t, env, "worker.running == 3 && worker.load1 < 2 && manual"))
+}
+
+func TestEvaluator_Env_NoLastRun(t *testing.T) {
+	e := newTestEvaluator(time.Now())
+	env := e.env(&common.JobExecuteInfo{Job: &common.Job{Name: "report"}}, nil)
+
+	assert.True(t, eval(t, env, `!lastRun.exists && !lastRun.failed && lastRun.status == "" && lastRun.age == -1`))
+	assert.False(t, eval(t, env, "manual"))
+}
+
+func TestEvaluator_Check_NoCondition(t *testing.T) {
+	e := newTestEvaluator(time.Now())
+	ok, err := e.Check(&common.JobExecuteInfo{Job: &common.Job{Name: "report"}})
+	require.NoError(t, err)
+	assert.True(t, ok, "Jobs without a when condition should always run")
+}
@@ -4,20 +4,84 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"fmt"
+	"os"
 	"os/exec"
 	"runtime"
+	"strconv"
+	"sync/atomic"
 	"time"
 
 	"go.uber.org/zap"
 
 	"github.com/fyerfyer/scheduler-refactor/common"
 	"github.com/fyerfyer/scheduler-refactor/config"
+	"github.com/fyerfyer/scheduler-refactor/pkg/metrics"
+	"github.com/fyerfyer/scheduler-refactor/pkg/policy"
+	"github.com/fyerfyer/scheduler-refactor/worker/precondition"
 )
 
+// 按任务名记录的调度延迟直方图：计划时间到实际调度、实际调度到开始执行。
+// 后者持续升高通常说明worker已经饱和
+var (
+	scheduleDelayMetrics = metrics.NewRecorder("schedule_delay")
+	startDelayMetrics    = metrics.NewRecorder("start_delay")
+)
+
+// 按任务名导出的执行次数和执行时长
+var (
+	executionsStarted  = metrics.NewCounter("executions_started_total", "Executions started on this worker.", "job")
+	executionsFinished = metrics.NewCounter("executions_finished_total", "Executions finished on this worker by status.", "job", "status")
+	executionDuration  = metrics.NewHistogram("execution_duration_seconds", "Execution duration on this worker.", metrics.DurationBuckets, "job")
+)
+
+// processWaitDelay 命令被终止后等待输出管道关闭的最长时间
+const processWaitDelay = time.Second
+
+// PolicyChecker 执行前的命令策略判定，被拦截的执行通过RecordBlock写入拒绝审计
+type PolicyChecker interface {
+	Evaluate(command string) policy.Decision
+	RecordBlock(rejection *common.PolicyRejection)
+}
+
+// ProgressTracker 为每次执行提供进度文件，返回文件路径和执行结束时调用的清理函数
+type ProgressTracker interface {
+	Track(info *common.JobExecuteInfo) (string, func())
+}
+
+// CheckpointStore 为开启检查点的任务提供检查点文件，返回文件路径和执行结束时调用的保存函数
+type CheckpointStore interface {
+	Prepare(info *common.JobExecuteInfo) (string, func(succeeded bool))
+}
+
+// IntentRecorder 在启动命令前写入执行意图，返回执行结束时调用的完成函数。
+// 手动触发已被其他worker确认执行时返回ErrDuplicateRun
+type IntentRecorder interface {
+	Begin(info *common.JobExecuteInfo) (func(result *common.JobExecuteResult), error)
+}
+
+// ConditionChecker 执行前对任务的when条件求值，返回false或出错时本次执行被跳过
+type ConditionChecker interface {
+	Check(info *common.JobExecuteInfo) (bool, error)
+}
+
+// PluginRunner 执行器插件，为设置了type的任务构建插件进程
+type PluginRunner interface {
+	Command(ctx context.Context, info *common.JobExecuteInfo) (*exec.Cmd, error)
+}
+
 // Executor 任务执行器
 type Executor struct {
 	logger     *zap.Logger                   // 日志对象
 	jobResults chan *common.JobExecuteResult // 任务执行结果通道
+	policy     PolicyChecker                 // 命令策略，为空时不检查
+	sandbox    []string                      // 沙箱命令模板，为空时直接执行
+	progress   ProgressTracker               // 进度上报，为空时不提供进度文件
+	checkpoint CheckpointStore               // 检查点存储，为空时不提供检查点文件
+	intents    IntentRecorder                // 执行意图记录，为空时不记录
+	conditions ConditionChecker              // when条件求值，为空时不检查
+	plugins    PluginRunner                  // 执行器插件，为空时只能执行shell任务
+	running    atomic.Int64                  // 正在执行命令的任务数
 }
 
 // NewExecutor 创建执行器
@@ -28,16 +92,122 @@ func NewExecutor(logger *zap.Logger) *Executor {
 	}
 }
 
+// SetPolicy 设置执行前的命令策略
+func (e *Executor) SetPolicy(checker PolicyChecker) {
+	e.policy = checker
+}
+
+// SetSandbox 设置沙箱命令模板，模板需先经过ResolveSandbox校验
+func (e *Executor) SetSandbox(template []string) {
+	e.sandbox = template
+}
+
+// SetProgress 设置进度上报，任务可以通过CRON_PROGRESS_FILE上报进度
+func (e *Executor) SetProgress(tracker ProgressTracker) {
+	e.progress = tracker
+}
+
+// SetCheckpoint 设置检查点存储，开启检查点的任务可以通过CRON_CHECKPOINT_FILE恢复进度
+func (e *Executor) SetCheckpoint(store CheckpointStore) {
+	e.checkpoint = store
+}
+
+// SetIntents 设置执行意图记录，worker崩溃导致的结果丢失可以被master发现
+func (e *Executor) SetIntents(recorder IntentRecorder) {
+	e.intents = recorder
+}
+
+// SetConditions 设置执行前的when条件求值
+func (e *Executor) SetConditions(checker ConditionChecker) {
+	e.conditions = checker
+}
+
+// SetPlugins 设置执行器插件，设置了type的任务交给对应插件执行
+func (e *Executor) SetPlugins(runner PluginRunner) {
+	e.plugins = runner
+}
+
+// Running 返回正在执行命令的任务数，不含等待前置条件等尚未启动命令的执行
+func (e *Executor) Running() int {
+	return int(e.running.Load())
+}
+
 // ExecuteJob 执行一个任务
 func (e *Executor) ExecuteJob(info *common.JobExecuteInfo) {
 	go func() {
 		// 记录任务开始执行时间
 		startTime := time.Now()
 
+		// 分配执行标识，任务脚本通过环境变量关联自己的日志
+		if info.RunID == "" {
+			info.RunID = common.NewRunID()
+		}
+		if info.Attempt <= 0 {
+			info.Attempt = 1
+		}
+
 		// 结果对象
 		result := &common.JobExecuteResult{
-			JobName:   info.Job.Name,
-			StartTime: startTime,
+			JobName:    info.Job.Name,
+			RunID:      info.RunID,
+			StartTime:  startTime,
+			Experiment: info.Experiment,
+		}
+		scheduleDelayMetrics.Observe(info.Job.Name, info.RealTime.Sub(info.PlanTime), nil)
+		startDelayMetrics.Observe(info.Job.Name, startTime.Sub(info.RealTime), nil)
+		executionsStarted.Inc(info.Job.Name)
+
+		// 执行前再次检查命令策略，防止保存后规则收紧或绕过master写入的任务
+		if e.policy != nil {
+			if decision := e.policy.Evaluate(info.Job.Command); !decision.Allowed {
+				result.EndTime = time.Now()
+				result.ExitCode = -1
+				result.Status = common.RunStatusFailed
+				result.Error = "command blocked by policy: " + decision.Reason
+
+				e.logger.Warn("job blocked by command policy",
+					zap.String("jobName", info.Job.Name),
+					zap.String("command", info.Job.Command),
+					zap.String("rule", decision.Rule),
+					zap.String("reason", decision.Reason))
+				e.policy.RecordBlock(&common.PolicyRejection{
+					JobName: info.Job.Name,
+					Command: info.Job.Command,
+					Rule:    decision.Rule,
+					Reason:  decision.Reason,
+					Stage:   common.PolicyStageExecute,
+					RunID:   info.RunID,
+					Worker:  config.GlobalConfig.WorkerID,
+					Time:    result.EndTime.Unix(),
+				})
+
+				e.deliver(result)
+				return
+			}
+		}
+
+		// when条件为false时跳过本次执行，求值出错时同样跳过并记录原因
+		if e.conditions != nil && info.Job.When != "" {
+			ok, err := e.conditions.Check(info)
+			if err != nil || !ok {
+				result.EndTime = time.Now()
+				result.ExitCode = -1
+				result.Status = common.RunStatusSkipped
+				result.SkipReason = common.SkipReasonCondition
+				if err != nil {
+					result.Error = err.Error()
+				} else {
+					result.Error = "when condition is false: " + info.Job.When
+				}
+
+				e.logger.Info("job skipped by when condition",
+					zap.String("jobName", info.Job.Name),
+					zap.String("when", info.Job.When),
+					zap.Error(err))
+
+				e.deliver(result)
+				return
+			}
 		}
 
 		// 创建上下文（用于任务超时控制）
@@ -56,24 +226,108 @@ func (e *Executor) ExecuteJob(info *common.JobExecuteInfo) {
 		info.CancelCtx = ctx
 		info.CancelFunc = cancel
 
+		// 检查外部依赖，重试期间任务同样可以被终止或超时
+		if len(info.Job.Preconditions) > 0 {
+			if err := precondition.Wait(ctx, info.Job); err != nil {
+				result.EndTime = time.Now()
+				result.ExitCode = -1
+				result.Status = common.RunStatusSkipped
+				result.SkipReason = common.SkipReasonPrecondition
+				result.Error = "precondition failed: " + err.Error()
+
+				e.logger.Warn("job skipped, precondition failed",
+					zap.String("jobName", info.Job.Name),
+					zap.Error(err))
+
+				e.deliver(result)
+				return
+			}
+		}
+
 		// 执行命令并捕获输出
 		var cmd *exec.Cmd
 		var output bytes.Buffer
 		var errOutput bytes.Buffer
 
-		// 根据不同系统执行命令
-		if runtime.GOOS == "windows" {
+		// 插件任务由插件进程执行；shell任务根据不同系统执行命令，配置了沙箱时在沙箱中执行
+		if info.Job.Type != "" && info.Job.Type != common.ExecutorShell {
+			var err error
+			if cmd, err = e.pluginCommand(ctx, info); err != nil {
+				result.EndTime = time.Now()
+				result.ExitCode = -1
+				result.Status = common.RunStatusFailed
+				result.Error = err.Error()
+
+				e.logger.Warn("failed to start executor plugin",
+					zap.String("jobName", info.Job.Name),
+					zap.String("type", info.Job.Type),
+					zap.Error(err))
+
+				e.deliver(result)
+				return
+			}
+		} else if runtime.GOOS == "windows" {
 			cmd = exec.CommandContext(ctx, "cmd", "/C", info.Job.Command)
+		} else if len(e.sandbox) > 0 {
+			args := sandboxArgs(e.sandbox, info.Job.Command)
+			cmd = exec.CommandContext(ctx, args[0], args[1:]...)
 		} else {
 			cmd = exec.CommandContext(ctx, "sh", "-c", info.Job.Command)
 		}
 
+		// 注入执行上下文
+		cmd.Env = append(os.Environ(), executionEnv(info, config.GlobalConfig.WorkerID)...)
+		if config.GlobalConfig.TraceContext {
+			// 任务调用的已接入追踪的工具会加入这条trace
+			cmd.Env = append(cmd.Env, "TRACEPARENT="+traceParent(info.RunID))
+		}
+		// 启动命令前写入执行意图，重新投递的手动触发已经执行过时不再执行
+		var finishIntent func(result *common.JobExecuteResult)
+		if e.intents != nil {
+			var err error
+			if finishIntent, err = e.intents.Begin(info); err != nil {
+				result.EndTime = time.Now()
+				result.ExitCode = -1
+				result.Status = common.RunStatusSkipped
+				result.SkipReason = common.SkipReasonDuplicate
+				result.Error = err.Error()
+
+				e.logger.Warn("job skipped, trigger was delivered more than once",
+					zap.String("jobName", info.Job.Name),
+					zap.String("runId", info.RunID))
+
+				e.deliver(result)
+				return
+			}
+		}
+
+		if e.progress != nil && !info.Experiment { // 实验命令与任务同名，不上报进度
+			if path, done := e.progress.Track(info); path != "" {
+				defer done()
+				cmd.Env = append(cmd.Env, "CRON_PROGRESS_FILE="+path)
+			}
+		}
+
+		var saveCheckpoint func(succeeded bool)
+		if e.checkpoint != nil && info.Job.Checkpoint && !info.Experiment {
+			var path string
+			if path, saveCheckpoint = e.checkpoint.Prepare(info); path != "" {
+				cmd.Env = append(cmd.Env, "CRON_CHECKPOINT_FILE="+path)
+			}
+		}
+
 		// 捕获输出
 		cmd.Stdout = &output
 		cmd.Stderr = &errOutput
 
+		// 终止时杀死整个进程组，脱离进程组的子进程仍持有输出管道时最多再等待processWaitDelay
+		setProcessGroup(cmd)
+		cmd.WaitDelay = processWaitDelay
+
 		// 执行命令
+		e.running.Add(1)
 		err := cmd.Run()
+		e.running.Add(-1)
 
 		// 记录结束时间
 		endTime := time.Now()
@@ -82,6 +336,12 @@ func (e *Executor) ExecuteJob(info *common.JobExecuteInfo) {
 		result.EndTime = endTime
 		result.Output = output.String()
 
+		// 记录资源使用，用于成本核算
+		if cmd.ProcessState != nil {
+			result.CPUTime = (cmd.ProcessState.UserTime() + cmd.ProcessState.SystemTime()).Seconds()
+			result.MaxRSS = maxRSSKB(cmd.ProcessState)
+		}
+
 		// 处理执行结果
 		if err != nil {
 			// 检查是否因为超时被取消
@@ -89,7 +349,14 @@ func (e *Executor) ExecuteJob(info *common.JobExecuteInfo) {
 				result.Error = "job execution timed out"
 				result.IsTimeout = true
 				result.ExitCode = -1
+				result.Status = common.RunStatusTimeout
+			} else if errors.Is(ctx.Err(), context.Canceled) {
+				// 上下文被主动取消，说明任务被强制终止
+				result.Error = "job killed"
+				result.ExitCode = -1
+				result.Status = common.RunStatusKilled
 			} else {
+				result.Status = common.RunStatusFailed
 				result.Error = err.Error()
 				if exitErr, ok := err.(*exec.ExitError); ok {
 					result.ExitCode = exitErr.ExitCode()
@@ -100,20 +367,48 @@ func (e *Executor) ExecuteJob(info *common.JobExecuteInfo) {
 
 			e.logger.Warn("job execution failed",
 				zap.String("jobName", info.Job.Name),
+				zap.String("status", string(result.Status)),
 				zap.String("error", result.Error),
 				zap.Int("exitCode", result.ExitCode))
 		} else {
 			result.ExitCode = 0
-			e.logger.Info("job executed successfully",
+			result.Status = common.RunStatusSuccess
+			e.logger.Debug("job executed successfully",
 				zap.String("jobName", info.Job.Name),
 				zap.Duration("duration", endTime.Sub(startTime)))
 		}
 
+		// 成功时清除检查点，失败时保存检查点供下次执行恢复
+		if saveCheckpoint != nil {
+			saveCheckpoint(result.Status == common.RunStatusSuccess)
+		}
+
+		if finishIntent != nil {
+			finishIntent(result)
+		}
+
 		// 将结果投递到结果通道
-		e.jobResults <- result
+		e.deliver(result)
 	}()
 }
 
+// pluginCommand 构建插件任务的进程
+func (e *Executor) pluginCommand(ctx context.Context, info *common.JobExecuteInfo) (*exec.Cmd, error) {
+	if e.plugins == nil {
+		return nil, fmt.Errorf("executor plugins are not enabled on this worker")
+	}
+	return e.plugins.Command(ctx, info)
+}
+
+// deliver 记录执行结果的指标并投递到结果通道
+func (e *Executor) deliver(result *common.JobExecuteResult) {
+	executionsFinished.Inc(result.JobName, string(result.Status))
+	if result.Status != common.RunStatusSkipped {
+		executionDuration.Observe(result.EndTime.Sub(result.StartTime).Seconds(), result.JobName)
+	}
+	e.jobResults <- result
+}
+
 // KillJob 强制终止任务
 func (e *Executor) KillJob(jobName string, info *common.JobExecuteInfo) {
 	if info != nil && info.CancelFunc != nil {
@@ -127,6 +422,17 @@ func (e *Executor) KillJob(jobName string, info *common.JobExecuteInfo) {
 	}
 }
 
+// executionEnv 注入到每次执行的环境变量，任务脚本可以据此上报和关联自己的日志
+func executionEnv(info *common.JobExecuteInfo, workerID string) []string {
+	return []string{
+		"CRON_JOB_NAME=" + info.Job.Name,
+		"CRON_RUN_ID=" + info.RunID,
+		"CRON_PLAN_TIME=" + strconv.FormatInt(info.PlanTime.Unix(), 10),
+		"CRON_WORKER_ID=" + workerID,
+		"CRON_ATTEMPT=" + strconv.Itoa(info.Attempt),
+	}
+}
+
 // GetResultChan 获取任务结果通道
 func (e *Executor) GetResultChan() <-chan *common.JobExecuteResult {
 	return e.jobResults
@@ -135,17 +441,42 @@ func (e *Executor) GetResultChan() <-chan *common.JobExecuteResult {
 // BuildJobLog 构建任务执行日志
 func BuildJobLog(result *common.JobExecuteResult, info *common.JobExecuteInfo) *common.JobLog {
 	jobLog := &common.JobLog{
-		JobName:      result.JobName,
-		Command:      info.Job.Command,
-		Output:       result.Output,
-		Error:        result.Error,
-		PlanTime:     info.PlanTime.Unix(),
-		ScheduleTime: info.RealTime.Unix(),
-		StartTime:    result.StartTime.Unix(),
-		EndTime:      result.EndTime.Unix(),
-		ExitCode:     result.ExitCode,
-		IsTimeout:    result.IsTimeout,
-		WorkerIP:     config.GlobalConfig.WorkerID, // 使用WorkerID作为标识
+		JobName:       result.JobName,
+		Command:       info.Job.Command,
+		Output:        result.Output,
+		Error:         result.Error,
+		PlanTime:      info.PlanTime.Unix(),
+		ScheduleTime:  info.RealTime.Unix(),
+		StartTime:     result.StartTime.Unix(),
+		EndTime:       result.EndTime.Unix(),
+		ExitCode:      result.ExitCode,
+		IsTimeout:     result.IsTimeout,
+		Status:        result.Status,
+		SkipReason:    result.SkipReason,
+		CPUTime:       result.CPUTime,
+		MaxRSS:        result.MaxRSS,
+		WorkerIP:      config.GlobalConfig.WorkerID, // 使用WorkerID作为标识
+		Namespace:     common.NamespaceOf(info.Job.Namespace),
+		Owner:         info.Job.Owner,
+		Canary:        info.Canary,
+		Experiment:    info.Experiment,
+		Annotations:   info.Job.Annotations,
+		RunID:         result.RunID,
+		QuietFailures: info.Job.QuietFailures,
+		TriggeredBy:   info.TriggeredBy,
+		JobRevision:   info.Job.Revision,
+		ReplayOf:      info.ReplayOf,
+
+		ScheduleDelayMs: info.RealTime.Sub(info.PlanTime).Milliseconds(),
+		StartDelayMs:    result.StartTime.Sub(info.RealTime).Milliseconds(),
+	}
+
+	// 兼容未设置执行标识和状态的执行结果
+	if jobLog.RunID == "" {
+		jobLog.RunID = info.RunID
+	}
+	if jobLog.Status == "" {
+		jobLog.Status = common.InferRunStatus(result.ExitCode, result.IsTimeout)
 	}
 
 	return jobLog
@@ -3,15 +3,25 @@ package executor
 import (
 	"context"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
 
 	"github.com/fyerfyer/scheduler-refactor/common"
+	"github.com/fyerfyer/scheduler-refactor/config"
+	"github.com/fyerfyer/scheduler-refactor/pkg/policy"
 )
 
+// TestMain 执行器从全局配置读取worker ID，测试前设置
+func TestMain(m *testing.M) {
+	config.GlobalConfig = &config.Config{WorkerID: "test-worker"}
+	os.Exit(m.Run())
+}
+
 func setupTestLogger() *zap.Logger {
 	logger, _ := zap.NewDevelopment()
 	return logger
@@ -42,10 +52,11 @@ func TestExecutor_ExecuteJob_Success(t *testing.T) {
 	select {
 	case result := <-executor.GetResultChan():
 		assert.Equal(t, job.Name, result.JobName)
-		assert.Equal(t, "hello world\r\n", result.Output)
+		assert.Equal(t, "hello world", strings.TrimSpace(result.Output))
 		assert.Equal(t, "", result.Error)
 		assert.Equal(t, 0, result.ExitCode)
 		assert.False(t, result.IsTimeout)
+		assert.Equal(t, common.RunStatusSuccess, result.Status)
 	case <-time.After(3 * time.Second):
 		t.Fatal("execution timeout")
 	}
@@ -78,6 +89,7 @@ func TestExecutor_ExecuteJob_Error(t *testing.T) {
 		assert.Equal(t, job.Name, result.JobName)
 		assert.NotEmpty(t, result.Error)
 		assert.NotEqual(t, 0, result.ExitCode)
+		assert.Equal(t, common.RunStatusFailed, result.Status)
 	case <-time.After(3 * time.Second):
 		t.Fatal("execution timeout")
 	}
@@ -113,6 +125,7 @@ func TestExecutor_ExecuteJob_Timeout(t *testing.T) {
 	case result := <-executor.GetResultChan():
 		assert.Equal(t, job.Name, result.JobName)
 		assert.True(t, result.IsTimeout)
+		assert.Equal(t, common.RunStatusTimeout, result.Status)
 		assert.Contains(t, result.Error, "timed out")
 	case <-time.After(3 * time.Second):
 		t.Fatal("execution timeout")
@@ -157,6 +170,121 @@ func TestExecutor_KillJob(t *testing.T) {
 	}
 }
 
+// denyAllPolicy 拒绝所有命令的测试策略
+type denyAllPolicy struct{}
+
+func (denyAllPolicy) Evaluate(command string) policy.Decision {
+	return policy.Decision{Allowed: false, Rule: "deny-all", Reason: "denied by rule deny-all"}
+}
+
+func (denyAllPolicy) RecordBlock(rejection *common.PolicyRejection) {}
+
+func TestExecutor_ExecuteJob_PolicyBlocked(t *testing.T) {
+	executor := NewExecutor(setupTestLogger())
+	executor.SetPolicy(denyAllPolicy{})
+
+	jobInfo := &common.JobExecuteInfo{
+		Job:      &common.Job{Name: "test_blocked_job", Command: "echo should not run"},
+		PlanTime: time.Now(),
+		RealTime: time.Now(),
+	}
+
+	executor.ExecuteJob(jobInfo)
+
+	select {
+	case result := <-executor.GetResultChan():
+		assert.Equal(t, common.RunStatusFailed, result.Status)
+		assert.Equal(t, -1, result.ExitCode)
+		assert.Empty(t, result.Output, "Blocked command should not run")
+		assert.Contains(t, result.Error, "command blocked by policy")
+	case <-time.After(3 * time.Second):
+		t.Fatal("execution timeout")
+	}
+}
+
+// duplicateIntents 总是判定为重复投递的测试意图记录
+type duplicateIntents struct{}
+
+func (duplicateIntents) Begin(info *common.JobExecuteInfo) (func(result *common.JobExecuteResult), error) {
+	return nil, common.ErrDuplicateRun
+}
+
+func TestExecutor_ExecuteJob_DuplicateDelivery(t *testing.T) {
+	executor := NewExecutor(setupTestLogger())
+	executor.SetIntents(duplicateIntents{})
+
+	jobInfo := &common.JobExecuteInfo{
+		Job:          &common.Job{Name: "test_duplicate_job", Command: "echo should not run"},
+		PlanTime:     time.Now(),
+		RealTime:     time.Now(),
+		TriggerToken: 42,
+	}
+
+	executor.ExecuteJob(jobInfo)
+
+	select {
+	case result := <-executor.GetResultChan():
+		assert.Equal(t, common.RunStatusSkipped, result.Status)
+		assert.Equal(t, common.SkipReasonDuplicate, result.SkipReason)
+		assert.Empty(t, result.Output, "Duplicate delivery should not run the command")
+	case <-time.After(3 * time.Second):
+		t.Fatal("execution timeout")
+	}
+}
+
+// staticConditions 返回固定结果的测试when条件
+type staticConditions struct {
+	ok  bool
+	err error
+}
+
+func (c staticConditions) Check(info *common.JobExecuteInfo) (bool, error) {
+	return c.ok, c.err
+}
+
+func TestExecutor_ExecuteJob_WhenFalse(t *testing.T) {
+	executor := NewExecutor(setupTestLogger())
+	executor.SetConditions(staticConditions{ok: false})
+
+	jobInfo := &common.JobExecuteInfo{
+		Job:      &common.Job{Name: "test_when_job", Command: "echo should not run", When: "lastRun.failed"},
+		PlanTime: time.Now(),
+		RealTime: time.Now(),
+	}
+
+	executor.ExecuteJob(jobInfo)
+
+	select {
+	case result := <-executor.GetResultChan():
+		assert.Equal(t, common.RunStatusSkipped, result.Status)
+		assert.Equal(t, common.SkipReasonCondition, result.SkipReason)
+		assert.Empty(t, result.Output, "False condition should not run the command")
+		assert.Contains(t, result.Error, "lastRun.failed")
+	case <-time.After(3 * time.Second):
+		t.Fatal("execution timeout")
+	}
+}
+
+func TestExecutor_ExecuteJob_PluginNotEnabled(t *testing.T) {
+	executor := NewExecutor(setupTestLogger())
+
+	jobInfo := &common.JobExecuteInfo{
+		Job:      &common.Job{Name: "test_plugin_job", Type: "ansible", Config: map[string]any{"playbook": "site.yml"}},
+		PlanTime: time.Now(),
+		RealTime: time.Now(),
+	}
+
+	executor.ExecuteJob(jobInfo)
+
+	select {
+	case result := <-executor.GetResultChan():
+		assert.Equal(t, common.RunStatusFailed, result.Status)
+		assert.Contains(t, result.Error, "plugins are not enabled", "Plugin jobs must not fall back to running the command in a shell")
+	case <-time.After(3 * time.Second):
+		t.Fatal("execution timeout")
+	}
+}
+
 func TestBuildJobLog(t *testing.T) {
 	now := time.Now()
 	startTime := now.Add(-5 * time.Second)
@@ -169,6 +297,9 @@ func TestBuildJobLog(t *testing.T) {
 		Disabled:  false,
 		CreatedAt: now.Add(-1 * time.Hour).Unix(),
 		UpdatedAt: now.Add(-30 * time.Minute).Unix(),
+		Annotations: map[string]string{
+			"ticket": "OPS-1234",
+		},
 	}
 
 	planTime := now.Add(-10 * time.Second)
@@ -178,6 +309,7 @@ func TestBuildJobLog(t *testing.T) {
 		Job:      job,
 		PlanTime: planTime,
 		RealTime: realTime,
+		RunID:    "run-1",
 	}
 
 	result := &common.JobExecuteResult{
@@ -200,6 +332,46 @@ func TestBuildJobLog(t *testing.T) {
 	assert.Equal(t, realTime.Unix(), jobLog.ScheduleTime)
 	assert.Equal(t, startTime.Unix(), jobLog.StartTime)
 	assert.Equal(t, now.Unix(), jobLog.EndTime)
+	assert.Equal(t, int64(2000), jobLog.ScheduleDelayMs)
+	assert.Equal(t, int64(3000), jobLog.StartDelayMs)
 	assert.Equal(t, 0, jobLog.ExitCode)
+	assert.Equal(t, "run-1", jobLog.RunID)
 	assert.False(t, jobLog.IsTimeout)
+	assert.Equal(t, job.Annotations, jobLog.Annotations, "Annotations should be passed through to the log")
+
+	result.RunID = "run-2"
+	assert.Equal(t, "run-2", BuildJobLog(result, jobInfo).RunID, "The result's run ID should take precedence")
+}
+
+func TestExecutionEnv(t *testing.T) {
+	planTime := time.Unix(1700000000, 0)
+	info := &common.JobExecuteInfo{
+		Job:      &common.Job{Name: "backup"},
+		PlanTime: planTime,
+		RunID:    "abc123",
+		Attempt:  1,
+	}
+
+	env := executionEnv(info, "worker-1")
+	assert.Equal(t, []string{
+		"CRON_JOB_NAME=backup",
+		"CRON_RUN_ID=abc123",
+		"CRON_PLAN_TIME=1700000000",
+		"CRON_WORKER_ID=worker-1",
+		"CRON_ATTEMPT=1",
+	}, env)
+}
+
+func TestTraceParent(t *testing.T) {
+	runID := "0af7651916cd43dd8448eb211c80319c"
+	parts := strings.Split(traceParent(runID), "-")
+	require.Len(t, parts, 4)
+	assert.Equal(t, "00", parts[0])
+	assert.Equal(t, runID, parts[1], "Trace ID should be the run ID")
+	assert.Len(t, parts[2], 16)
+	assert.Equal(t, "01", parts[3])
+
+	// 随机数不可用时生成的短run ID补齐为32位
+	parts = strings.Split(traceParent("17a2b3c4d5e6f70"), "-")
+	assert.Equal(t, strings.Repeat("0", 17)+"17a2b3c4d5e6f70", parts[1])
 }
@@ -0,0 +1,8 @@
+//go:build !unix
+
+package executor
+
+import "os/exec"
+
+// setProcessGroup 当前平台只终止命令进程本身
+func setProcessGroup(cmd *exec.Cmd) {}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package executor
+
+import "os"
+
+// maxRSSKB 当前平台不支持获取峰值内存
+func maxRSSKB(state *os.ProcessState) int64 {
+	return 0
+}
@@ -0,0 +1,55 @@
+package executor
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// SandboxPlaceholder 沙箱命令模板中代表任务命令的占位符
+const SandboxPlaceholder = "{{command}}"
+
+// sandboxPresets 内置沙箱模板：只读挂载根目录、独立/tmp、禁用网络
+var sandboxPresets = map[string][]string{
+	"firejail": {"firejail", "--quiet", "--noprofile", "--net=none", "--private-tmp", "--read-only=/", "--", "sh", "-c", SandboxPlaceholder},
+	"bwrap":    {"bwrap", "--ro-bind", "/", "/", "--dev", "/dev", "--proc", "/proc", "--tmpfs", "/tmp", "--unshare-all", "--die-with-parent", "sh", "-c", SandboxPlaceholder},
+	"nsjail":   {"nsjail", "--mode", "o", "--quiet", "--bindmount_ro", "/", "--tmpfsmount", "/tmp", "--", "/bin/sh", "-c", SandboxPlaceholder},
+}
+
+// ResolveSandbox 解析沙箱配置，template非空时优先使用自定义模板，否则使用preset对应的内置模板，
+// 两者都为空表示不启用沙箱。会检查沙箱程序是否存在，避免任务静默地在沙箱外执行
+func ResolveSandbox(preset string, template []string) ([]string, error) {
+	if len(template) == 0 {
+		if preset == "" {
+			return nil, nil
+		}
+
+		var ok bool
+		if template, ok = sandboxPresets[preset]; !ok {
+			return nil, fmt.Errorf("unknown sandbox preset: %s", preset)
+		}
+	}
+
+	placeholders := 0
+	for _, arg := range template {
+		placeholders += strings.Count(arg, SandboxPlaceholder)
+	}
+	if placeholders != 1 {
+		return nil, fmt.Errorf("sandbox template must contain %s exactly once", SandboxPlaceholder)
+	}
+
+	if _, err := exec.LookPath(template[0]); err != nil {
+		return nil, fmt.Errorf("sandbox binary not found: %v", err)
+	}
+
+	return template, nil
+}
+
+// sandboxArgs 将任务命令填入沙箱模板
+func sandboxArgs(template []string, command string) []string {
+	args := make([]string, len(template))
+	for i, arg := range template {
+		args[i] = strings.Replace(arg, SandboxPlaceholder, command, 1)
+	}
+	return args
+}
@@ -0,0 +1,52 @@
+package executor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+)
+
+func TestResolveSandbox(t *testing.T) {
+	template, err := ResolveSandbox("", nil)
+	assert.NoError(t, err)
+	assert.Nil(t, template, "Sandbox should be disabled by default")
+
+	_, err = ResolveSandbox("unknown", nil)
+	assert.Error(t, err)
+
+	_, err = ResolveSandbox("", []string{"env", "sh", "-c"})
+	assert.Error(t, err, "Template without placeholder should be rejected")
+
+	_, err = ResolveSandbox("", []string{"no-such-sandbox-binary", "sh", "-c", SandboxPlaceholder})
+	assert.Error(t, err, "Missing sandbox binary should be rejected")
+
+	template, err = ResolveSandbox("firejail", []string{"env", "sh", "-c", SandboxPlaceholder})
+	assert.NoError(t, err)
+	assert.Equal(t, "env", template[0], "Custom template should take precedence over preset")
+}
+
+func TestExecutor_ExecuteJob_Sandbox(t *testing.T) {
+	template, err := ResolveSandbox("", []string{"env", "SANDBOXED=yes", "sh", "-c", SandboxPlaceholder})
+	require.NoError(t, err)
+
+	executor := NewExecutor(setupTestLogger())
+	executor.SetSandbox(template)
+
+	executor.ExecuteJob(&common.JobExecuteInfo{
+		Job:      &common.Job{Name: "test_sandbox_job", Command: "echo $SANDBOXED"},
+		PlanTime: time.Now(),
+		RealTime: time.Now(),
+	})
+
+	select {
+	case result := <-executor.GetResultChan():
+		assert.Equal(t, common.RunStatusSuccess, result.Status)
+		assert.Equal(t, "yes\n", result.Output, "Command should run through the sandbox wrapper")
+	case <-time.After(3 * time.Second):
+		t.Fatal("execution timeout")
+	}
+}
@@ -0,0 +1,28 @@
+package executor
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// traceIDLength W3C trace context中trace-id的十六进制长度
+const traceIDLength = 32
+
+// traceParent 生成注入到任务环境变量TRACEPARENT的W3C trace context，每次执行是一条新的trace。
+// trace-id取执行的run ID，日志中的runId即可用于在追踪系统中查找；span-id随机生成，标记为已采样
+func traceParent(runID string) string {
+	traceID := strings.ToLower(runID)
+	if len(traceID) < traceIDLength {
+		traceID = strings.Repeat("0", traceIDLength-len(traceID)) + traceID
+	}
+	traceID = traceID[:traceIDLength]
+
+	spanID := make([]byte, 8)
+	if _, err := rand.Read(spanID); err != nil {
+		// 随机数不可用时取trace-id的后半部分，span-id不能全为0
+		return fmt.Sprintf("00-%s-%s-01", traceID, traceID[traceIDLength/2:])
+	}
+	return fmt.Sprintf("00-%s-%s-01", traceID, hex.EncodeToString(spanID))
+}
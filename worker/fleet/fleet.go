@@ -0,0 +1,152 @@
+package fleet
+
+import (
+	"context"
+	"encoding/json"
+	"sync/atomic"
+	"time"
+
+	"go.etcd.io/etcd/client/v3"
+	"go.uber.org/zap"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+	"github.com/fyerfyer/scheduler-refactor/config"
+	"github.com/fyerfyer/scheduler-refactor/pkg/etcd"
+)
+
+// Watcher 监听worker蓝绿切换，调度器按切换判断当前worker是否可以执行任务
+type Watcher struct {
+	etcdClient *etcd.Client                       // etcd客户端
+	logger     *zap.Logger                        // 日志对象
+	generation string                             // 当前worker所属的代
+	current    atomic.Pointer[common.FleetSwitch] // 当前的切换，为nil时没有进行中的切换
+	revision   int64                              // 已加载的版本，不晚于该版本的事件忽略
+	ctx        context.Context                    // 上下文，用于控制退出
+	cancelFunc context.CancelFunc                 // 取消函数
+}
+
+// NewWatcher 创建蓝绿切换监听器
+func NewWatcher(logger *zap.Logger, etcdClient *etcd.Client) *Watcher {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &Watcher{
+		etcdClient: etcdClient,
+		logger:     logger,
+		generation: config.GlobalConfig.Generation,
+		ctx:        ctx,
+		cancelFunc: cancel,
+	}
+}
+
+// Start 加载当前的切换并开始监听，需在调度器启动前调用，避免重启后短暂执行属于另一代的任务
+func (w *Watcher) Start() error {
+	// 先监听再加载，加载版本之前的事件在处理时忽略
+	watchChan := w.etcdClient.Watch(w.ctx, common.FleetSwitchKey)
+	if err := w.load(); err != nil {
+		w.logger.Error("failed to load fleet switch", zap.Error(err))
+		return err
+	}
+
+	go w.watchLoop(watchChan)
+
+	w.logger.Info("fleet switch watcher started", zap.String("generation", w.generation))
+	return nil
+}
+
+// Stop 停止监听
+func (w *Watcher) Stop() {
+	w.cancelFunc()
+	w.logger.Info("fleet switch watcher stopped")
+}
+
+// Eligible 当前worker是否可以执行任务，没有进行中的切换或worker不属于切换的两代时总是可以执行
+func (w *Watcher) Eligible(jobName string) bool {
+	fs := w.current.Load()
+	if fs == nil || w.generation == "" {
+		return true
+	}
+	return fs.Eligible(w.generation, jobName)
+}
+
+// watchLoop 监听切换变化，监听中断时重新监听并重新加载
+func (w *Watcher) watchLoop(watchChan clientv3.WatchChan) {
+	for {
+		select {
+		case <-w.ctx.Done():
+			return
+		case watchResp, ok := <-watchChan:
+			if !ok || watchResp.Err() != nil {
+				w.logger.Warn("fleet switch watch interrupted, resyncing", zap.Bool("closed", !ok))
+				watchChan = w.resync()
+				continue
+			}
+
+			for _, event := range watchResp.Events {
+				if event.Kv.ModRevision <= w.revision {
+					continue
+				}
+
+				switch event.Type {
+				case clientv3.EventTypePut:
+					w.apply(event.Kv.Value)
+				case clientv3.EventTypeDelete:
+					w.logger.Info("fleet switch removed")
+					w.current.Store(nil)
+				}
+			}
+		}
+	}
+}
+
+// resync 重新监听并重新加载切换，加载失败时每秒重试直到成功或停止
+func (w *Watcher) resync() clientv3.WatchChan {
+	watchChan := w.etcdClient.Watch(w.ctx, common.FleetSwitchKey)
+
+	for {
+		err := w.load()
+		if err == nil {
+			return watchChan
+		}
+
+		w.logger.Error("failed to resync fleet switch, retrying", zap.Error(err))
+		select {
+		case <-w.ctx.Done():
+			return watchChan
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// load 加载当前的切换，切换已被删除时清除
+func (w *Watcher) load() error {
+	resp, err := w.etcdClient.Get(common.FleetSwitchKey)
+	if err != nil {
+		return err
+	}
+
+	if resp.Count > 0 {
+		w.apply(resp.Kvs[0].Value)
+	} else if w.current.Swap(nil) != nil {
+		w.logger.Info("fleet switch removed")
+	}
+	w.revision = resp.Header.Revision
+
+	return nil
+}
+
+// apply 解析并应用切换，无法解析时保留之前的切换
+func (w *Watcher) apply(value []byte) {
+	fs := &common.FleetSwitch{}
+	if err := json.Unmarshal(value, fs); err != nil {
+		w.logger.Error("failed to unmarshal fleet switch, keeping previous switch", zap.Error(err))
+		return
+	}
+
+	w.logger.Info("fleet switch updated",
+		zap.String("from", fs.From),
+		zap.String("to", fs.To),
+		zap.Int("percent", fs.Percent),
+		zap.String("generation", w.generation),
+		zap.String("updatedBy", fs.UpdatedBy))
+	w.current.Store(fs)
+}
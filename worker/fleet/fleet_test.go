@@ -0,0 +1,26 @@
+package fleet
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestWatcher_Eligible(t *testing.T) {
+	w := &Watcher{logger: zaptest.NewLogger(t), generation: "green"}
+	assert.True(t, w.Eligible("backup"), "Workers should run every job without a fleet switch")
+
+	w.apply([]byte(`{"from":"blue","to":"green","percent":0}`))
+	assert.False(t, w.Eligible("backup"), "The new generation should run nothing at 0 percent")
+
+	w.apply([]byte(`not json`))
+	assert.False(t, w.Eligible("backup"), "Unparseable switches should keep the previous switch")
+
+	w.apply([]byte(`{"from":"blue","to":"green","percent":100}`))
+	assert.True(t, w.Eligible("backup"))
+
+	w.generation = ""
+	w.apply([]byte(`{"from":"blue","to":"green","percent":0}`))
+	assert.True(t, w.Eligible("backup"), "Workers without a generation should not be affected")
+}
@@ -0,0 +1,146 @@
+package gang
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+	"github.com/fyerfyer/scheduler-refactor/pkg/etcd"
+)
+
+// State 集合的结果
+type State int
+
+// 集合的结果
+const (
+	StatePending State = iota // 仍在等待其他成员
+	StateCommit               // 全部成员已抢到锁，开始执行
+	StateAbort                // 等待超时，放弃本次触发
+)
+
+// 集合点中记录结果的值
+const (
+	decisionCommit = "commit"
+	decisionAbort  = "abort"
+)
+
+// keyTTLMargin 集合点key在等待时间之外多保留的时间(秒)，保证晚到的成员能看到结果
+const keyTTLMargin = 60
+
+// Barrier 任务组一次触发的集合点。抢到任务锁的成员登记后等待，第一个看到全部成员登记的worker写入commit，
+// 第一个等待超时的worker写入abort，结果只写入一次，所有成员按同一个结果执行或放弃
+type Barrier struct {
+	etcdClient *etcd.Client // etcd客户端
+	prefix     string       // 集合点前缀
+	members    []string     // 需要集合的成员
+	deadline   time.Time    // 等待截止时间
+	ttl        int64        // 集合点key的租约时间(秒)
+}
+
+// NewBarrier 创建任务组在planTime的集合点，members为需要集合的任务名，最多等待wait
+func NewBarrier(etcdClient *etcd.Client, gang string, planTime time.Time, members []string, wait time.Duration) *Barrier {
+	return &Barrier{
+		etcdClient: etcdClient,
+		prefix:     common.JobGangDir + gang + "/" + strconv.FormatInt(planTime.Unix(), 10) + "/",
+		members:    members,
+		deadline:   planTime.Add(wait),
+		ttl:        int64(wait/time.Second) + keyTTLMargin,
+	}
+}
+
+// Deadline 等待截止时间
+func (b *Barrier) Deadline() time.Time {
+	return b.deadline
+}
+
+// Join 登记成员已抢到任务锁
+func (b *Barrier) Join(jobName, workerID string) error {
+	return b.etcdClient.PutWithLease(b.memberKey(jobName), workerID, b.ttl)
+}
+
+// Check 检查集合结果，尚未有结果时根据登记情况尝试写入结果
+func (b *Barrier) Check(now time.Time) (State, error) {
+	resp, err := b.etcdClient.GetWithPrefix(b.prefix)
+	if err != nil {
+		return StatePending, err
+	}
+
+	joined := make(map[string]bool)
+	for _, kv := range resp.Kvs {
+		key := string(kv.Key)
+		if key == b.decisionKey() {
+			return stateOf(string(kv.Value)), nil
+		}
+		joined[strings.TrimPrefix(key, b.prefix+"members/")] = true
+	}
+
+	decision := decide(b.members, joined, now, b.deadline)
+	if decision == "" {
+		return StatePending, nil
+	}
+
+	leaseID, err := b.etcdClient.GrantLease(b.ttl)
+	if err != nil {
+		return StatePending, err
+	}
+	applied, err := b.etcdClient.ApplyIfUnchanged(b.decisionKey(), 0,
+		clientv3.OpPut(b.decisionKey(), decision, clientv3.WithLease(leaseID)))
+	if err != nil {
+		return StatePending, err
+	}
+	if applied {
+		return stateOf(decision), nil
+	}
+
+	// 其他worker先写入了结果
+	result, err := b.etcdClient.Get(b.decisionKey())
+	if err != nil || result.Count == 0 {
+		return StatePending, err
+	}
+	return stateOf(string(result.Kvs[0].Value)), nil
+}
+
+// memberKey 成员的登记key
+func (b *Barrier) memberKey(jobName string) string {
+	return b.prefix + "members/" + jobName
+}
+
+// decisionKey 集合结果的key
+func (b *Barrier) decisionKey() string {
+	return b.prefix + "decision"
+}
+
+// decide 全部成员登记时返回commit，超过截止时间仍有成员未登记时返回abort，否则继续等待
+func decide(members []string, joined map[string]bool, now, deadline time.Time) string {
+	complete := true
+	for _, member := range members {
+		if !joined[member] {
+			complete = false
+			break
+		}
+	}
+
+	switch {
+	case complete:
+		return decisionCommit
+	case !now.Before(deadline):
+		return decisionAbort
+	default:
+		return ""
+	}
+}
+
+// stateOf 集合结果对应的状态
+func stateOf(decision string) State {
+	switch decision {
+	case decisionCommit:
+		return StateCommit
+	case decisionAbort:
+		return StateAbort
+	default:
+		return StatePending
+	}
+}
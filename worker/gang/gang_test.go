@@ -0,0 +1,32 @@
+package gang
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecide(t *testing.T) {
+	deadline := time.Date(2024, 6, 1, 0, 0, 30, 0, time.UTC)
+	before := deadline.Add(-time.Second)
+	members := []string{"extract", "load"}
+
+	assert.Empty(t, decide(members, map[string]bool{"extract": true}, before, deadline), "Should keep waiting before the deadline")
+	assert.Equal(t, decisionAbort, decide(members, map[string]bool{"extract": true}, deadline, deadline))
+	assert.Equal(t, decisionCommit, decide(members, map[string]bool{"extract": true, "load": true}, before, deadline))
+	assert.Equal(t, decisionCommit, decide(members, map[string]bool{"extract": true, "load": true}, deadline.Add(time.Minute), deadline),
+		"A complete gang should commit even if the deadline has passed")
+
+	assert.Equal(t, StatePending, stateOf("unknown"))
+}
+
+func TestNewBarrier(t *testing.T) {
+	planTime := time.Unix(1717200000, 0)
+	b := NewBarrier(nil, "etl", planTime, []string{"extract"}, 30*time.Second)
+
+	assert.Equal(t, "/cron/gang/etl/1717200000/members/extract", b.memberKey("extract"))
+	assert.Equal(t, "/cron/gang/etl/1717200000/decision", b.decisionKey())
+	assert.Equal(t, planTime.Add(30*time.Second), b.Deadline())
+	assert.Equal(t, int64(30+keyTTLMargin), b.ttl)
+}
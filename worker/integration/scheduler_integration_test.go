@@ -62,9 +62,10 @@ func cleanupSchedulerTestData(t *testing.T, etcdClient *clientv3.Client, mongoCl
 
 func createSchedulerTestJob(t *testing.T) {
 	job := map[string]interface{}{
-		"name":     testJobName,
-		"command":  "echo \"Hello world from integration test\"",
-		"cronExpr": "* * * * * *", // Changed to run every second
+		"name":               testJobName,
+		"command":            "echo \"Hello world from integration test\"",
+		"cronExpr":           "* * * * * *", // Changed to run every second
+		"allowHighFrequency": true,
 	}
 
 	jsonData, err := json.Marshal(job)
@@ -0,0 +1,157 @@
+package intent
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.uber.org/zap"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+	"github.com/fyerfyer/scheduler-refactor/config"
+	"github.com/fyerfyer/scheduler-refactor/pkg/etcd"
+)
+
+// LeaseSource 提供worker锁租约，由joblock.Session实现
+type LeaseSource interface {
+	LeaseID() (clientv3.LeaseID, error)
+}
+
+// Recorder 在启动命令前把执行意图写入etcd，执行结束后更新结束状态。
+// 意图不绑定租约，worker崩溃后仍然保留，由master对账
+type Recorder struct {
+	etcdClient *etcd.Client // etcd客户端
+	logger     *zap.Logger  // 日志对象
+	session    LeaseSource  // worker锁租约
+}
+
+// NewRecorder 创建执行意图记录器
+func NewRecorder(logger *zap.Logger, etcdClient *etcd.Client, session LeaseSource) *Recorder {
+	return &Recorder{
+		etcdClient: etcdClient,
+		logger:     logger,
+		session:    session,
+	}
+}
+
+// Begin 写入执行意图，返回执行结束时调用的完成函数。手动触发的执行在同一个事务中确认触发，
+// 触发已被其他worker重新抢占或同一个runId已有意图时返回ErrDuplicateRun，本次不应启动命令。
+// 写入失败时只记录日志，任务照常执行
+func (r *Recorder) Begin(info *common.JobExecuteInfo) (func(result *common.JobExecuteResult), error) {
+	noop := func(*common.JobExecuteResult) {}
+
+	leaseID, err := r.session.LeaseID()
+	if err != nil {
+		r.logger.Warn("failed to get lock lease for run intent",
+			zap.String("jobName", info.Job.Name),
+			zap.String("runId", info.RunID),
+			zap.Error(err))
+		return noop, nil
+	}
+
+	intent := &common.RunIntent{
+		RunID:       info.RunID,
+		JobName:     info.Job.Name,
+		WorkerID:    config.GlobalConfig.WorkerID,
+		LeaseID:     int64(leaseID),
+		Command:     info.Job.Command,
+		Namespace:   info.Job.Namespace,
+		Owner:       info.Job.Owner,
+		JobRevision: info.Job.Revision,
+		TriggeredBy: info.TriggeredBy,
+		Canary:      info.Canary,
+		Experiment:  info.Experiment,
+		PlanTime:    info.PlanTime.Unix(),
+		StartTime:   time.Now().Unix(),
+	}
+
+	key := common.RunIntentDir + info.RunID
+	data, err := json.Marshal(intent)
+	if err != nil {
+		r.logger.Warn("failed to marshal run intent", zap.String("runId", info.RunID), zap.Error(err))
+		return noop, nil
+	}
+
+	var revision int64
+	if info.TriggerToken > 0 {
+		revision, err = r.confirmTrigger(info, key, string(data))
+	} else {
+		var resp *clientv3.PutResponse
+		if resp, err = r.etcdClient.Put(key, string(data)); err == nil {
+			revision = resp.Header.Revision
+		}
+	}
+	if errors.Is(err, common.ErrDuplicateRun) {
+		return noop, err
+	}
+	if err != nil {
+		r.logger.Warn("failed to write run intent, crash losses of this run will go undetected",
+			zap.String("jobName", info.Job.Name),
+			zap.String("runId", info.RunID),
+			zap.Error(err))
+		return noop, nil
+	}
+
+	return func(result *common.JobExecuteResult) {
+		intent.Status = result.Status
+		intent.ExitCode = result.ExitCode
+		intent.EndTime = result.EndTime.Unix()
+		r.finish(key, revision, intent)
+	}, nil
+}
+
+// confirmTrigger 确认抢到的手动触发：触发仍是本worker抢占时的版本且runId没有意图时，删除触发并写入意图。
+// 触发在确认前因租约到期被删除时，只要runId还没有意图仍然执行。返回意图写入后的修改版本
+func (r *Recorder) confirmTrigger(info *common.JobExecuteInfo, key, data string) (int64, error) {
+	triggerKey := common.TriggerKey(info.Job.Name, info.RunID)
+	revision, err := r.etcdClient.ApplyIfAllUnchangedAt(
+		map[string]int64{triggerKey: info.TriggerToken, key: 0},
+		clientv3.OpPut(key, data),
+		clientv3.OpDelete(triggerKey),
+	)
+	if err != nil || revision > 0 {
+		return revision, err
+	}
+
+	resp, err := r.etcdClient.Get(triggerKey)
+	if err != nil {
+		return 0, err
+	}
+	if resp.Count > 0 {
+		// 抢占的租约过期后触发已被重新投递给其他worker
+		return 0, common.ErrDuplicateRun
+	}
+
+	revision, err = r.etcdClient.ApplyIfUnchangedAt(key, 0, clientv3.OpPut(key, data))
+	if err != nil {
+		return 0, err
+	}
+	if revision == 0 {
+		return 0, common.ErrDuplicateRun
+	}
+	return revision, nil
+}
+
+// finish 更新意图的结束状态，意图在执行期间被master对账删除时不再写回
+func (r *Recorder) finish(key string, revision int64, intent *common.RunIntent) {
+	data, err := json.Marshal(intent)
+	if err != nil {
+		r.logger.Warn("failed to marshal run intent", zap.String("runId", intent.RunID), zap.Error(err))
+		return
+	}
+
+	applied, err := r.etcdClient.ApplyIfUnchanged(key, revision, clientv3.OpPut(key, string(data)))
+	if err != nil {
+		r.logger.Warn("failed to finalize run intent",
+			zap.String("jobName", intent.JobName),
+			zap.String("runId", intent.RunID),
+			zap.Error(err))
+		return
+	}
+	if !applied {
+		r.logger.Warn("run intent was reconciled as lost while the run was still executing",
+			zap.String("jobName", intent.JobName),
+			zap.String("runId", intent.RunID))
+	}
+}
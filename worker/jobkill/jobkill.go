@@ -0,0 +1,112 @@
+package jobkill
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"go.etcd.io/etcd/client/v3"
+	"go.uber.org/zap"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+	"github.com/fyerfyer/scheduler-refactor/pkg/etcd"
+)
+
+// Watcher 监听kill目录，master写入kill标记时通知回调终止本worker上运行中的任务
+type Watcher struct {
+	etcdClient *etcd.Client         // etcd客户端
+	logger     *zap.Logger          // 日志对象
+	handler    func(jobName string) // 收到kill标记时的回调
+	revision   int64                // 已处理的版本，重新监听时补发之后写入的标记
+	ctx        context.Context      // 上下文，用于控制退出
+	cancelFunc context.CancelFunc   // 取消函数
+}
+
+// NewWatcher 创建kill标记监听器
+func NewWatcher(logger *zap.Logger, etcdClient *etcd.Client, handler func(jobName string)) *Watcher {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &Watcher{
+		etcdClient: etcdClient,
+		logger:     logger,
+		handler:    handler,
+		ctx:        ctx,
+		cancelFunc: cancel,
+	}
+}
+
+// Start 开始监听。kill标记只对写入时运行中的执行有效，启动时不加载已有的标记
+func (w *Watcher) Start() {
+	go w.watchLoop()
+	w.logger.Info("job kill watcher started")
+}
+
+// Stop 停止监听
+func (w *Watcher) Stop() {
+	w.cancelFunc()
+	w.logger.Info("job kill watcher stopped")
+}
+
+// watchLoop 监听kill目录，标记过期产生的删除事件忽略。监听中断时重新监听，并补发中断期间写入的标记
+func (w *Watcher) watchLoop() {
+	watchChan := w.etcdClient.WatchWithPrefix(w.ctx, common.JobKillDir)
+
+	for {
+		select {
+		case <-w.ctx.Done():
+			return
+		case watchResp, ok := <-watchChan:
+			if !ok || watchResp.Err() != nil {
+				w.logger.Warn("job kill watch interrupted, resyncing", zap.Bool("closed", !ok))
+				watchChan = w.resync()
+				continue
+			}
+
+			for _, event := range watchResp.Events {
+				if event.Type == clientv3.EventTypePut && event.Kv.ModRevision > w.revision {
+					w.apply(string(event.Kv.Key))
+				}
+			}
+			w.revision = watchResp.Header.Revision
+		}
+	}
+}
+
+// resync 重新监听并补发已处理版本之后写入的标记，加载失败时每秒重试直到成功或停止。
+// 还没有处理过任何事件时无法区分标记是否已处理，不补发
+func (w *Watcher) resync() clientv3.WatchChan {
+	watchChan := w.etcdClient.WatchWithPrefix(w.ctx, common.JobKillDir)
+
+	for {
+		resp, err := w.etcdClient.GetWithPrefix(common.JobKillDir)
+		if err == nil {
+			if w.revision > 0 {
+				for _, kv := range resp.Kvs {
+					if kv.ModRevision > w.revision {
+						w.apply(string(kv.Key))
+					}
+				}
+			}
+			w.revision = resp.Header.Revision
+			return watchChan
+		}
+
+		w.logger.Error("failed to resync kill markers, retrying", zap.Error(err))
+		select {
+		case <-w.ctx.Done():
+			return watchChan
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// apply 通知回调终止任务
+func (w *Watcher) apply(key string) {
+	jobName := strings.TrimPrefix(key, common.JobKillDir)
+	if jobName == "" {
+		return
+	}
+
+	w.logger.Info("job kill marker received", zap.String("jobName", jobName))
+	w.handler(jobName)
+}
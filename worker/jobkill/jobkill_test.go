@@ -0,0 +1,22 @@
+package jobkill
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+)
+
+func TestWatcher_Apply(t *testing.T) {
+	var killed []string
+	w := NewWatcher(zaptest.NewLogger(t), nil, func(jobName string) {
+		killed = append(killed, jobName)
+	})
+
+	w.apply(common.JobKillDir + "backup")
+	w.apply(common.JobKillDir)
+
+	assert.Equal(t, []string{"backup"}, killed, "Markers without a job name should be ignored")
+}
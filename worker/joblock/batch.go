@@ -0,0 +1,81 @@
+package joblock
+
+import (
+	clientv3 "go.etcd.io/etcd/client/v3"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+	"github.com/fyerfyer/scheduler-refactor/config"
+	"github.com/fyerfyer/scheduler-refactor/pkg/etcd"
+)
+
+// BatchLock 一次批量获取的任务锁，绑定在worker共享的会话租约上
+type BatchLock struct {
+	etcdClient *etcd.Client     // etcd客户端
+	owner      string           // 锁持有者，即WorkerID
+	leaseID    clientv3.LeaseID // 会话租约ID
+	lockKeys   []string         // 获取成功的锁路径
+}
+
+// TryLockBatch 使用会话租约在尽量少的etcd事务中批量尝试获取任务锁，返回的切片与jobNames一一对应
+func TryLockBatch(session *Session, jobNames []string) (*BatchLock, []bool, error) {
+	leaseID, err := session.LeaseID()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	lockKeys := make([]string, len(jobNames))
+	for i, jobName := range jobNames {
+		lockKeys[i] = common.JobLockDir + jobName
+	}
+
+	owner := config.GlobalConfig.WorkerID
+	acquired, err := session.etcdClient.TryAcquireLocks(lockKeys, owner, leaseID)
+	batch := &BatchLock{
+		etcdClient: session.etcdClient,
+		owner:      owner,
+		leaseID:    leaseID,
+	}
+	for i, ok := range acquired {
+		if ok {
+			batch.lockKeys = append(batch.lockKeys, lockKeys[i])
+		}
+	}
+
+	if err != nil {
+		// 释放出错前已经获取的锁
+		batch.Unlock()
+		return nil, nil, err
+	}
+
+	return batch, acquired, nil
+}
+
+// Unlock 删除本批次获取且仍由自己持有的锁，会话租约保持不变
+func (bl *BatchLock) Unlock() {
+	if len(bl.lockKeys) == 0 {
+		return
+	}
+
+	bl.etcdClient.ReleaseLocks(bl.lockKeys, bl.owner, bl.leaseID)
+	bl.lockKeys = nil
+}
+
+// Detach 将任务的锁从本批次中移出，返回只包含该锁的批次，用于单独延迟释放；本批次未持有该锁时返回nil
+func (bl *BatchLock) Detach(jobName string) *BatchLock {
+	lockKey := common.JobLockDir + jobName
+	for i, key := range bl.lockKeys {
+		if key != lockKey {
+			continue
+		}
+
+		bl.lockKeys = append(bl.lockKeys[:i], bl.lockKeys[i+1:]...)
+		return &BatchLock{
+			etcdClient: bl.etcdClient,
+			owner:      bl.owner,
+			leaseID:    bl.leaseID,
+			lockKeys:   []string{lockKey},
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,43 @@
+package joblock
+
+import (
+	clientv3 "go.etcd.io/etcd/client/v3"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+	"github.com/fyerfyer/scheduler-refactor/config"
+)
+
+// TryLockGroups 使用会话租约在一个事务中获取任务所属的全部互斥组锁，任一组锁被占用时一个也不获取，
+// 避免多个任务各持有一部分组锁而互相等待。返回是否获取成功
+func TryLockGroups(session *Session, groups []string) (*BatchLock, bool, error) {
+	leaseID, err := session.LeaseID()
+	if err != nil {
+		return nil, false, err
+	}
+
+	owner := config.GlobalConfig.WorkerID
+	revisions := make(map[string]int64, len(groups))
+	lockKeys := make([]string, 0, len(groups))
+	ops := make([]clientv3.Op, 0, len(groups))
+	for _, group := range groups {
+		key := common.ExclusionLockDir + group
+		if _, ok := revisions[key]; ok {
+			continue
+		}
+		revisions[key] = 0
+		lockKeys = append(lockKeys, key)
+		ops = append(ops, clientv3.OpPut(key, owner, clientv3.WithLease(leaseID)))
+	}
+
+	acquired, err := session.etcdClient.ApplyIfAllUnchanged(revisions, ops...)
+	if err != nil || !acquired {
+		return nil, false, err
+	}
+
+	return &BatchLock{
+		etcdClient: session.etcdClient,
+		owner:      owner,
+		leaseID:    leaseID,
+		lockKeys:   lockKeys,
+	}, true, nil
+}
@@ -0,0 +1,157 @@
+package joblock
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+	"github.com/fyerfyer/scheduler-refactor/pkg/metrics"
+)
+
+// lockAttempts 按任务和结果导出的抢锁次数，结果为acquired、contended、error或throttled
+var lockAttempts = metrics.NewCounter("lock_attempts_total", "Job lock attempts on this worker by result.", "job", "result")
+
+// LockStats 单个任务的抢锁统计
+type LockStats struct {
+	Attempts     int64   `json:"attempts"`     // 抢锁次数
+	Acquired     int64   `json:"acquired"`     // 抢锁成功次数
+	Contended    int64   `json:"contended"`    // 锁被其他worker持有的次数
+	Errors       int64   `json:"errors"`       // etcd出错次数
+	Throttled    int64   `json:"throttled"`    // 超出抢锁预算被限流的次数
+	AvgLatencyMs float64 `json:"avgLatencyMs"` // 平均抢锁耗时(毫秒)
+	MaxLatencyMs float64 `json:"maxLatencyMs"` // 最大抢锁耗时(毫秒)
+
+	totalLatency time.Duration
+}
+
+// GuardSnapshot 抢锁统计和限流状态快照
+type GuardSnapshot struct {
+	Limit int                   `json:"limit"` // 配置的每秒抢锁上限，0表示不限制
+	Rate  float64               `json:"rate"`  // 自适应调整后的当前每秒抢锁上限
+	Jobs  map[string]*LockStats `json:"jobs"`  // 任务名 -> 抢锁统计
+}
+
+// Guard 抢锁守卫，统计每个任务的抢锁情况，并限制worker每秒的抢锁次数以保护etcd集群。
+// etcd出错时当前上限减半，之后每次成功抢锁加1逐步恢复到配置值。
+type Guard struct {
+	limit  float64               // 配置的每秒抢锁上限，0表示不限制
+	rate   float64               // 当前每秒抢锁上限
+	tokens float64               // 当前可用的抢锁次数
+	last   time.Time             // 上次补充令牌的时间
+	stats  map[string]*LockStats // 任务名 -> 抢锁统计
+	lock   sync.Mutex            // 保护以上字段
+	now    func() time.Time      // 当前时间，便于测试
+}
+
+// NewGuard 创建抢锁守卫，limit为每秒抢锁上限，0表示不限制
+func NewGuard(limit int) *Guard {
+	if limit < 0 {
+		limit = 0
+	}
+
+	return &Guard{
+		limit:  float64(limit),
+		rate:   float64(limit),
+		tokens: float64(limit),
+		last:   time.Now(),
+		stats:  make(map[string]*LockStats),
+		now:    time.Now,
+	}
+}
+
+// Allow 判断本次抢锁是否在预算内，nil守卫不限制
+func (g *Guard) Allow(jobName string) bool {
+	if g == nil {
+		return true
+	}
+
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	if g.limit == 0 {
+		return true
+	}
+
+	// 按当前上限补充令牌
+	now := g.now()
+	g.tokens += now.Sub(g.last).Seconds() * g.rate
+	if g.tokens > g.rate {
+		g.tokens = g.rate
+	}
+	g.last = now
+
+	if g.tokens >= 1 {
+		g.tokens--
+		return true
+	}
+
+	g.jobStats(jobName).Throttled++
+	lockAttempts.Inc(jobName, "throttled")
+	return false
+}
+
+// Observe 记录一次抢锁结果并调整抢锁上限
+func (g *Guard) Observe(jobName string, latency time.Duration, err error) {
+	if g == nil {
+		return
+	}
+
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	stats := g.jobStats(jobName)
+	stats.Attempts++
+	stats.totalLatency += latency
+	stats.AvgLatencyMs = float64(stats.totalLatency.Microseconds()) / 1000 / float64(stats.Attempts)
+	if ms := float64(latency.Microseconds()) / 1000; ms > stats.MaxLatencyMs {
+		stats.MaxLatencyMs = ms
+	}
+
+	switch {
+	case err == nil:
+		stats.Acquired++
+		lockAttempts.Inc(jobName, "acquired")
+		if g.limit > 0 && g.rate < g.limit {
+			g.rate = min(g.limit, g.rate+1)
+		}
+	case errors.Is(err, common.ErrLockAlreadyAcquired):
+		// 锁竞争是正常情况，不调整上限
+		stats.Contended++
+		lockAttempts.Inc(jobName, "contended")
+	default:
+		stats.Errors++
+		lockAttempts.Inc(jobName, "error")
+		if g.limit > 0 {
+			g.rate = max(1, g.rate/2)
+		}
+	}
+}
+
+// Snapshot 获取抢锁统计和限流状态
+func (g *Guard) Snapshot() *GuardSnapshot {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	snapshot := &GuardSnapshot{
+		Limit: int(g.limit),
+		Rate:  g.rate,
+		Jobs:  make(map[string]*LockStats, len(g.stats)),
+	}
+	for jobName, stats := range g.stats {
+		copied := *stats
+		snapshot.Jobs[jobName] = &copied
+	}
+
+	return snapshot
+}
+
+// jobStats 获取任务的统计，调用方需持有锁
+func (g *Guard) jobStats(jobName string) *LockStats {
+	stats, ok := g.stats[jobName]
+	if !ok {
+		stats = &LockStats{}
+		g.stats[jobName] = stats
+	}
+	return stats
+}
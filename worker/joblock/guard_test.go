@@ -0,0 +1,54 @@
+package joblock
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+)
+
+func TestGuardThrottle(t *testing.T) {
+	guard := NewGuard(2)
+	now := time.Now()
+	guard.last = now
+	guard.now = func() time.Time { return now }
+
+	assert.True(t, guard.Allow("job"))
+	assert.True(t, guard.Allow("job"))
+	assert.False(t, guard.Allow("job"), "Attempts beyond the budget should be throttled")
+
+	// 半秒后补充一次
+	now = now.Add(500 * time.Millisecond)
+	assert.True(t, guard.Allow("job"))
+	assert.Equal(t, int64(1), guard.Snapshot().Jobs["job"].Throttled)
+
+	var nilGuard *Guard
+	assert.True(t, nilGuard.Allow("job"), "Nil guard should not throttle")
+	assert.True(t, NewGuard(0).Allow("job"), "Zero limit should not throttle")
+}
+
+func TestGuardAdaptiveRate(t *testing.T) {
+	guard := NewGuard(8)
+
+	guard.Observe("job", 10*time.Millisecond, errors.New("etcd unavailable"))
+	guard.Observe("job", 30*time.Millisecond, errors.New("etcd unavailable"))
+	assert.Equal(t, 2.0, guard.Snapshot().Rate, "Errors should halve the rate")
+
+	guard.Observe("job", 20*time.Millisecond, common.ErrLockAlreadyAcquired)
+	assert.Equal(t, 2.0, guard.Snapshot().Rate, "Contention should not change the rate")
+
+	guard.Observe("job", 20*time.Millisecond, nil)
+	snapshot := guard.Snapshot()
+	assert.Equal(t, 3.0, snapshot.Rate, "Success should recover the rate additively")
+
+	stats := snapshot.Jobs["job"]
+	assert.Equal(t, int64(4), stats.Attempts)
+	assert.Equal(t, int64(1), stats.Acquired)
+	assert.Equal(t, int64(1), stats.Contended)
+	assert.Equal(t, int64(2), stats.Errors)
+	assert.InDelta(t, 20.0, stats.AvgLatencyMs, 1e-9)
+	assert.InDelta(t, 30.0, stats.MaxLatencyMs, 1e-9)
+}
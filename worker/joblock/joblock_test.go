@@ -1,13 +1,13 @@
 package joblock
 
 import (
+	"encoding/json"
 	clientv3 "go.etcd.io/etcd/client/v3"
-	"sync"
 	"testing"
-	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
 
 	"github.com/fyerfyer/scheduler-refactor/common"
 	"github.com/fyerfyer/scheduler-refactor/config"
@@ -37,206 +37,171 @@ func cleanupLock(t *testing.T, client *etcd.Client, jobName string) {
 	}
 }
 
-func TestNewJobLock(t *testing.T) {
+func TestTryLockBatch(t *testing.T) {
 	client := setupEtcdClient(t)
 	defer client.Close()
 
-	jobName := "test_job_lock"
-	jobLock := NewJobLock(client, jobName)
-
-	assert.Equal(t, client, jobLock.etcdClient)
-	assert.Equal(t, jobName, jobLock.jobName)
-	assert.Equal(t, common.JobLockDir+jobName, jobLock.lockKey)
-	assert.Equal(t, false, jobLock.isLocked)
-}
-
-func TestJobLock_TryLock(t *testing.T) {
-	client := setupEtcdClient(t)
-	defer client.Close()
-
-	jobName := "test_try_lock"
-	jobLock := NewJobLock(client, jobName)
-
-	// 清理之前可能存在的锁
-	cleanupLock(t, client, jobName)
-
-	// 尝试获取锁
-	err := jobLock.TryLock()
-	assert.NoError(t, err, "Should acquire lock without error")
-	assert.True(t, jobLock.IsLocked(), "Lock should be acquired")
-	assert.NotEqual(t, 0, jobLock.leaseID, "Lease ID should be set")
+	jobNames := []string{"test_batch_a", "test_batch_b", "test_batch_c"}
+	for _, jobName := range jobNames {
+		cleanupLock(t, client, jobName)
+	}
 
-	// 释放锁
-	jobLock.Unlock()
-	assert.False(t, jobLock.IsLocked(), "Lock should be released")
+	// 其他worker已经持有其中一个锁
+	other := NewSession(client, zap.NewNop())
+	defer other.Close()
+	held, _, err := TryLockBatch(other, []string{"test_batch_b"})
+	require.NoError(t, err)
+
+	session := NewSession(client, zap.NewNop())
+	defer session.Close()
+
+	batch, acquired, err := TryLockBatch(session, jobNames)
+	require.NoError(t, err, "Batch lock should not return error")
+	assert.Equal(t, []bool{true, false, true}, acquired, "Only free locks should be acquired")
+
+	// 批量获取的锁同样互斥
+	_, acquired, err = TryLockBatch(other, []string{"test_batch_a"})
+	require.NoError(t, err)
+	assert.Equal(t, []bool{false}, acquired, "Locks held by another session should not be acquired")
+
+	// 释放后锁被删除，会话租约保持不变
+	leaseID, err := session.LeaseID()
+	require.NoError(t, err)
+	batch.Unlock()
+	held.Unlock()
+	_, acquired, err = TryLockBatch(session, jobNames)
+	require.NoError(t, err)
+	assert.Equal(t, []bool{true, true, true}, acquired, "All locks should be free after unlock")
+
+	reused, err := session.LeaseID()
+	require.NoError(t, err)
+	assert.Equal(t, leaseID, reused, "Locks should share the session lease")
+
+	// 关闭会话后所有锁随租约一起释放
+	session.Close()
+	resp, err := client.Get(common.JobLockDir + "test_batch_a")
+	require.NoError(t, err)
+	assert.Empty(t, resp.Kvs, "Locks should expire with the session lease")
 }
 
-func TestJobLock_LockCompetition(t *testing.T) {
-	client := setupEtcdClient(t)
-	defer client.Close()
-
-	jobName := "test_lock_competition"
-
-	// 清理之前可能存在的锁
-	cleanupLock(t, client, jobName)
-
-	// 第一个锁
-	lock1 := NewJobLock(client, jobName)
-	err := lock1.TryLock()
-	assert.NoError(t, err, "First lock should be acquired")
-	assert.True(t, lock1.IsLocked(), "First lock should be acquired")
-
-	// 第二个锁，应该获取失败
-	lock2 := NewJobLock(client, jobName)
-	err = lock2.TryLock()
-	assert.Error(t, err, "Second lock should fail")
-	assert.False(t, lock2.IsLocked(), "Second lock should not be acquired")
-	assert.Equal(t, common.ErrLockAlreadyAcquired, err, "Error should be ErrLockAlreadyAcquired")
-
-	// 释放第一个锁
-	lock1.Unlock()
+func TestBatchLock_Detach(t *testing.T) {
+	batch := &BatchLock{
+		owner:    "worker-a",
+		leaseID:  clientv3.LeaseID(42),
+		lockKeys: []string{common.JobLockDir + "job_a", common.JobLockDir + "job_b"},
+	}
 
-	// 第二个锁现在应该能够获取了
-	err = lock2.TryLock()
-	assert.NoError(t, err, "Second lock should succeed after first is released")
-	assert.True(t, lock2.IsLocked(), "Second lock should be acquired")
+	held := batch.Detach("job_b")
+	require.NotNil(t, held)
+	assert.Equal(t, []string{common.JobLockDir + "job_b"}, held.lockKeys)
+	assert.Equal(t, clientv3.LeaseID(42), held.leaseID, "Detached lock should keep the session lease")
+	assert.Equal(t, []string{common.JobLockDir + "job_a"}, batch.lockKeys, "Detached lock should not be released with the batch")
 
-	// 清理
-	lock2.Unlock()
+	assert.Nil(t, batch.Detach("job_c"), "Lock not held by the batch cannot be detached")
 }
 
-func TestJobLock_Unlock(t *testing.T) {
+func TestTryLockGroups(t *testing.T) {
 	client := setupEtcdClient(t)
 	defer client.Close()
 
-	jobName := "test_unlock"
-	jobLock := NewJobLock(client, jobName)
-
-	// 清理之前可能存在的锁
-	cleanupLock(t, client, jobName)
-
-	// 获取锁
-	err := jobLock.TryLock()
-	require.NoError(t, err, "Should acquire lock without error")
-
-	// 释放锁
-	jobLock.Unlock()
-	assert.False(t, jobLock.IsLocked(), "Lock should be released")
-	assert.Equal(t, clientv3.LeaseID(0), jobLock.leaseID, "Lease ID should be reset")
-
-	// 确认锁确实被释放了，另一把锁应该能够获取
-	anotherLock := NewJobLock(client, jobName)
-	err = anotherLock.TryLock()
-	assert.NoError(t, err, "Another lock should be acquired after unlock")
-
-	// 清理
-	anotherLock.Unlock()
-}
-
-func TestJobLock_LockWithTimeout(t *testing.T) {
-	client := setupEtcdClient(t)
-	defer client.Close()
+	for _, group := range []string{"test_group_db", "test_group_cache"} {
+		_, err := client.Delete(common.ExclusionLockDir + group)
+		require.NoError(t, err)
+	}
 
-	jobName := "test_lock_timeout"
+	session := NewSession(client, zap.NewNop())
+	defer session.Close()
 
-	cleanupLock(t, client, jobName)
+	lock, acquired, err := TryLockGroups(session, []string{"test_group_db"})
+	require.NoError(t, err)
+	require.True(t, acquired)
 
-	lock := NewJobLock(client, jobName)
-	err := lock.LockWithTimeout(2 * time.Second)
-	assert.NoError(t, err, "Should acquire lock with timeout")
-	assert.True(t, lock.IsLocked(), "Lock should be acquired")
+	// 任一组锁被占用时一个也不获取
+	_, acquired, err = TryLockGroups(session, []string{"test_group_cache", "test_group_db"})
+	require.NoError(t, err)
+	assert.False(t, acquired, "Groups should be locked all or nothing")
+	resp, err := client.Get(common.ExclusionLockDir + "test_group_cache")
+	require.NoError(t, err)
+	assert.Empty(t, resp.Kvs, "Free groups should not be locked when another group is busy")
 
 	lock.Unlock()
-
-	lock1 := NewJobLock(client, jobName)
-	err = lock1.TryLock()
-	require.NoError(t, err, "First lock should be acquired")
-
-	lock2 := NewJobLock(client, jobName)
-	err = lock2.LockWithTimeout(500 * time.Millisecond)
-
-	assert.Error(t, err, "Should fail to acquire lock")
-	assert.Equal(t, common.ErrLockAlreadyAcquired, err, "Error should be ErrLockAlreadyAcquired")
-	assert.False(t, lock2.IsLocked(), "Second lock should not be acquired")
-
-	lock1.Unlock()
+	lock, acquired, err = TryLockGroups(session, []string{"test_group_cache", "test_group_db", "test_group_db"})
+	require.NoError(t, err)
+	assert.True(t, acquired, "Groups should be free after unlock")
+	assert.Len(t, lock.lockKeys, 2, "Duplicate groups should be locked once")
+	lock.Unlock()
 }
 
-func TestJobLock_Concurrency(t *testing.T) {
+func TestTryAcquireSemaphores(t *testing.T) {
 	client := setupEtcdClient(t)
 	defer client.Close()
 
-	jobName := "test_concurrency"
-
-	// 清理之前可能存在的锁
-	cleanupLock(t, client, jobName)
-
-	// 并发获取锁的数量
-	concurrency := 10
-	var wg sync.WaitGroup
-	wg.Add(concurrency)
-
-	successCount := 0
-	var mu sync.Mutex
-
-	// 并发获取锁
-	for i := 0; i < concurrency; i++ {
-		go func(index int) {
-			defer wg.Done()
-
-			lock := NewJobLock(client, jobName)
-			err := lock.TryLock()
-
-			if err == nil {
-				mu.Lock()
-				successCount++
-				mu.Unlock()
-
-				// 持有锁一小段时间
-				time.Sleep(100 * time.Millisecond)
-
-				// 释放锁
-				lock.Unlock()
-			}
-		}(i)
+	for _, sem := range []*common.Semaphore{{Name: "test_sem_db", Limit: 2}, {Name: "test_sem_api", Limit: 1}} {
+		data, err := json.Marshal(sem)
+		require.NoError(t, err)
+		_, err = client.Put(common.SemaphoreDir+sem.Name, string(data))
+		require.NoError(t, err)
+		_, err = client.DeleteWithPrefix(common.SemaphoreSlotDir + sem.Name + "/")
+		require.NoError(t, err)
 	}
-
-	// 等待所有goroutine完成
-	wg.Wait()
-
-	// 只有一个goroutine应该成功获取锁
-	assert.Equal(t, 1, successCount, "Only one lock should be acquired")
+	defer func() {
+		_, _ = client.DeleteWithPrefix(common.SemaphoreDir + "test_sem_")
+		_, _ = client.DeleteWithPrefix(common.SemaphoreSlotDir + "test_sem_")
+	}()
+
+	session := NewSession(client, zap.NewNop())
+	defer session.Close()
+
+	first, acquired, err := TryAcquireSemaphores(session, []string{"test_sem_db", "test_sem_db"})
+	require.NoError(t, err)
+	require.True(t, acquired)
+	assert.Len(t, first.lockKeys, 1, "Duplicate semaphores should take one slot")
+
+	second, acquired, err := TryAcquireSemaphores(session, []string{"test_sem_db", "test_sem_api"})
+	require.NoError(t, err)
+	require.True(t, acquired)
+
+	// 任一信号量没有空闲槽位时一个也不占用
+	_, acquired, err = TryAcquireSemaphores(session, []string{"test_sem_db"})
+	require.NoError(t, err)
+	assert.False(t, acquired, "A full semaphore should not hand out slots")
+
+	second.Unlock()
+	_, acquired, err = TryAcquireSemaphores(session, []string{"test_sem_api", "test_sem_db"})
+	require.NoError(t, err)
+	assert.True(t, acquired, "Released slots should be reused")
+	first.Unlock()
+
+	_, _, err = TryAcquireSemaphores(session, []string{"test_sem_missing"})
+	assert.ErrorIs(t, err, common.ErrSemaphoreNotFound)
 }
 
-func TestJobLock_IsLocked(t *testing.T) {
+func TestTryAcquireInstance(t *testing.T) {
 	client := setupEtcdClient(t)
 	defer client.Close()
 
-	jobName := "test_is_locked"
-	jobLock := NewJobLock(client, jobName)
-
-	// 清理之前可能存在的锁
-	cleanupLock(t, client, jobName)
-
-	// 初始状态应该是未上锁
-	assert.False(t, jobLock.IsLocked(), "Initially should not be locked")
-
-	// 获取锁后状态应该是已上锁
-	err := jobLock.TryLock()
-	require.NoError(t, err, "Should acquire lock without error")
-	assert.True(t, jobLock.IsLocked(), "Should be locked after TryLock")
-
-	// 释放锁后状态应该是未上锁
-	jobLock.Unlock()
-	assert.False(t, jobLock.IsLocked(), "Should not be locked after Unlock")
-}
-
-func TestJobLock_JobName(t *testing.T) {
-	client := setupEtcdClient(t)
-	defer client.Close()
-
-	jobName := "test_job_name"
-	jobLock := NewJobLock(client, jobName)
-
-	assert.Equal(t, jobName, jobLock.JobName(), "JobName should return the correct job name")
+	_, err := client.DeleteWithPrefix(common.JobInstanceDir + "test_instances/")
+	require.NoError(t, err)
+
+	session := NewSession(client, zap.NewNop())
+	defer session.Close()
+
+	first, acquired, err := TryAcquireInstance(session, "test_instances", 2)
+	require.NoError(t, err)
+	require.True(t, acquired)
+	second, acquired, err := TryAcquireInstance(session, "test_instances", 2)
+	require.NoError(t, err)
+	require.True(t, acquired)
+	assert.NotEqual(t, first.lockKeys, second.lockKeys, "Instances should take different slots")
+
+	_, acquired, err = TryAcquireInstance(session, "test_instances", 2)
+	require.NoError(t, err)
+	assert.False(t, acquired, "No more instances than maxInstances should run")
+
+	first.Unlock()
+	third, acquired, err := TryAcquireInstance(session, "test_instances", 2)
+	require.NoError(t, err)
+	assert.True(t, acquired, "Released slots should be reused")
+	third.Unlock()
+	second.Unlock()
 }
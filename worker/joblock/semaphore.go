@@ -0,0 +1,117 @@
+package joblock
+
+import (
+	"encoding/json"
+	"fmt"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+	"github.com/fyerfyer/scheduler-refactor/config"
+)
+
+// TryAcquireSemaphores 使用会话租约在一个事务中为每个信号量占用一个空闲槽位，任一信号量没有空闲槽位时
+// 一个也不占用。读取后槽位被其他worker抢先占用时同样视为没有空闲槽位。返回是否占用成功，
+// 信号量不存在时返回ErrSemaphoreNotFound
+func TryAcquireSemaphores(session *Session, names []string) (*BatchLock, bool, error) {
+	leaseID, err := session.LeaseID()
+	if err != nil {
+		return nil, false, err
+	}
+
+	keys := make([]string, 0, len(names))
+	for _, name := range names {
+		keys = append(keys, common.SemaphoreDir+name)
+	}
+	kvs, err := session.etcdClient.GetMany(keys)
+	if err != nil {
+		return nil, false, err
+	}
+
+	owner := config.GlobalConfig.WorkerID
+	revisions := make(map[string]int64, len(names))
+	slotKeys := make([]string, 0, len(names))
+	ops := make([]clientv3.Op, 0, len(names))
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		kv, ok := kvs[common.SemaphoreDir+name]
+		if !ok {
+			return nil, false, fmt.Errorf("%w: %s", common.ErrSemaphoreNotFound, name)
+		}
+		sem := &common.Semaphore{}
+		if err = json.Unmarshal(kv.Value, sem); err != nil {
+			return nil, false, fmt.Errorf("failed to unmarshal semaphore %s: %v", name, err)
+		}
+
+		resp, err := session.etcdClient.GetWithPrefix(common.SemaphoreSlotDir + name + "/")
+		if err != nil {
+			return nil, false, err
+		}
+		held := make(map[string]bool, len(resp.Kvs))
+		for _, slot := range resp.Kvs {
+			held[string(slot.Key)] = true
+		}
+
+		slotKey := sem.FreeSlot(held)
+		if slotKey == "" {
+			return nil, false, nil
+		}
+		revisions[slotKey] = 0
+		slotKeys = append(slotKeys, slotKey)
+		ops = append(ops, clientv3.OpPut(slotKey, owner, clientv3.WithLease(leaseID)))
+	}
+
+	acquired, err := session.etcdClient.ApplyIfAllUnchanged(revisions, ops...)
+	if err != nil || !acquired {
+		return nil, false, err
+	}
+
+	return &BatchLock{
+		etcdClient: session.etcdClient,
+		owner:      owner,
+		leaseID:    leaseID,
+		lockKeys:   slotKeys,
+	}, true, nil
+}
+
+// TryAcquireInstance 使用会话租约为任务占用一个执行实例槽位，maxInstances个槽位都被占用、
+// 或读取后空闲槽位被其他worker抢先占用时返回false
+func TryAcquireInstance(session *Session, jobName string, maxInstances int) (*BatchLock, bool, error) {
+	leaseID, err := session.LeaseID()
+	if err != nil {
+		return nil, false, err
+	}
+
+	resp, err := session.etcdClient.GetWithPrefix(common.JobInstanceDir + jobName + "/")
+	if err != nil {
+		return nil, false, err
+	}
+	held := make(map[string]bool, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		held[string(kv.Key)] = true
+	}
+
+	slotKey := common.FreeInstanceSlot(jobName, maxInstances, held)
+	if slotKey == "" {
+		return nil, false, nil
+	}
+
+	owner := config.GlobalConfig.WorkerID
+	acquired, err := session.etcdClient.ApplyIfUnchanged(slotKey, 0,
+		clientv3.OpPut(slotKey, owner, clientv3.WithLease(leaseID)))
+	if err != nil || !acquired {
+		return nil, false, err
+	}
+
+	return &BatchLock{
+		etcdClient: session.etcdClient,
+		owner:      owner,
+		leaseID:    leaseID,
+		lockKeys:   []string{slotKey},
+	}, true, nil
+}
@@ -0,0 +1,81 @@
+package joblock
+
+import (
+	"sync"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.uber.org/zap"
+
+	"github.com/fyerfyer/scheduler-refactor/config"
+	"github.com/fyerfyer/scheduler-refactor/pkg/etcd"
+)
+
+// Session worker级共享的锁租约，worker持有的所有任务锁都绑定在同一个租约上，
+// 避免每次抢锁都申请新租约；worker宕机后租约过期，所有锁一起失效
+type Session struct {
+	etcdClient *etcd.Client     // etcd客户端
+	logger     *zap.Logger      // 日志对象
+	leaseID    clientv3.LeaseID // 当前租约ID，0表示尚未申请或已失效
+	lock       sync.Mutex       // 保护leaseID
+}
+
+// NewSession 创建锁租约会话，租约在第一次抢锁时申请
+func NewSession(etcdClient *etcd.Client, logger *zap.Logger) *Session {
+	return &Session{
+		etcdClient: etcdClient,
+		logger:     logger,
+	}
+}
+
+// LeaseID 获取当前租约，租约尚未申请或已失效时重新申请并自动续租
+func (s *Session) LeaseID() (clientv3.LeaseID, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.leaseID != 0 {
+		return s.leaseID, nil
+	}
+
+	leaseID, err := s.etcdClient.GrantLease(int64(config.GlobalConfig.JobLockTTL))
+	if err != nil {
+		return 0, err
+	}
+
+	keepAliveChan, err := s.etcdClient.KeepAlive(leaseID)
+	if err != nil {
+		s.etcdClient.RevokeLease(leaseID)
+		return 0, err
+	}
+
+	s.leaseID = leaseID
+	go s.keepAlive(leaseID, keepAliveChan)
+
+	return leaseID, nil
+}
+
+// keepAlive 消费续租应答，续租失败后清空租约，下次抢锁时重新申请
+func (s *Session) keepAlive(leaseID clientv3.LeaseID, keepAliveChan <-chan *clientv3.LeaseKeepAliveResponse) {
+	for range keepAliveChan {
+		// 持续消费应答，避免续租通道阻塞
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.leaseID == leaseID {
+		s.leaseID = 0
+		s.logger.Warn("job lock session lease lost", zap.Int64("leaseId", int64(leaseID)))
+	}
+}
+
+// Close 撤销租约，绑定在租约上的所有锁立即释放
+func (s *Session) Close() {
+	s.lock.Lock()
+	leaseID := s.leaseID
+	s.leaseID = 0
+	s.lock.Unlock()
+
+	if leaseID != 0 {
+		s.etcdClient.RevokeLease(leaseID)
+	}
+}
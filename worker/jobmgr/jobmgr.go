@@ -3,9 +3,12 @@ package jobmgr
 import (
 	"context"
 	"encoding/json"
+	"go.etcd.io/etcd/api/v3/mvccpb"
 	"go.etcd.io/etcd/client/v3"
 	"go.uber.org/zap"
+	"reflect"
 	"sync"
+	"time"
 
 	"github.com/fyerfyer/scheduler-refactor/common"
 	"github.com/fyerfyer/scheduler-refactor/pkg/etcd"
@@ -18,6 +21,8 @@ type JobManager struct {
 	jobsCache  sync.Map              // 任务缓存，使用sync.Map实现线程安全
 	watchChan  clientv3.WatchChan    // 监听任务变化的通道
 	eventChan  chan *common.JobEvent // 任务事件通道
+	queue      *eventQueue           // 合并事件的缓冲队列
+	revision   int64                 // 已同步到的etcd版本
 	ctx        context.Context       // 上下文，用于控制退出
 	cancelFunc context.CancelFunc    // 取消函数
 }
@@ -31,14 +36,17 @@ func NewJobManager(etcdClient *etcd.Client, logger *zap.Logger) *JobManager {
 		logger:     logger,
 		jobsCache:  sync.Map{},
 		eventChan:  make(chan *common.JobEvent, 1000),
+		queue:      newEventQueue(),
 		ctx:        ctx,
 		cancelFunc: cancel,
 	}
 
-	// 任务管理器初始化时，先加载所有任务
+	// 先开始监听再加载所有任务，加载版本之前的事件在处理时忽略，避免遗漏加载和监听之间的修改
+	jobMgr.watchChan = etcdClient.WatchWithPrefix(ctx, common.JobSaveDir)
 	jobMgr.loadJobs()
 
-	// 启动任务变化监听
+	// 启动事件转发和任务变化监听
+	go jobMgr.forwardEvents()
 	jobMgr.watchJobs()
 
 	return jobMgr
@@ -64,39 +72,45 @@ func (jm *JobManager) loadJobs() error {
 				zap.Error(err))
 			continue
 		}
+		job.Revision = kv.ModRevision
 
 		// 缓存任务
 		jm.jobsCache.Store(job.Name, job)
 	}
+	jm.revision = resp.Header.Revision
 
 	jm.logger.Info("jobs loaded", zap.Int("count", len(resp.Kvs)))
 	return nil
 }
 
-// watchJobs 监听任务变化
+// watchJobs 处理/cron/jobs/目录的变化
 func (jm *JobManager) watchJobs() {
-	// 监听/cron/jobs/目录的变化
-	jm.watchChan = jm.etcdClient.WatchWithPrefix(common.JobSaveDir)
-
 	// 处理监听事件
 	go func() {
 		for {
 			select {
 			case <-jm.ctx.Done():
 				return
-			case watchResp := <-jm.watchChan:
+			case watchResp, ok := <-jm.watchChan:
+				// 监听中断（如版本被压缩）时可能已丢失事件，全量重新同步，监听通道关闭时重新监听
+				if !ok || watchResp.Err() != nil {
+					jm.logger.Warn("job watch interrupted, resyncing jobs",
+						zap.Bool("closed", !ok),
+						zap.Error(watchResp.Err()))
+					jm.resync(!ok)
+					continue
+				}
+
 				for _, event := range watchResp.Events {
+					// 已包含在加载结果中的事件
+					if event.Kv.ModRevision <= jm.revision {
+						continue
+					}
+
 					jobEvent := jm.handleWatchEvent(event)
 					if jobEvent != nil {
-						// 推送事件到通道
-						select {
-						case jm.eventChan <- jobEvent:
-							// 写入成功
-						default:
-							// 通道已满，记录日志
-							jm.logger.Warn("event channel is full, dropping event",
-								zap.String("jobName", jobEvent.Job.Name))
-						}
+						// 推送事件到队列，同一任务未处理的事件会被最新事件替换
+						jm.queue.push(jobEvent)
 					}
 				}
 			}
@@ -106,6 +120,85 @@ func (jm *JobManager) watchJobs() {
 	jm.logger.Info("job watcher started")
 }
 
+// forwardEvents 将队列中的事件依次转发到事件通道，通道已满时阻塞等待调度器处理
+func (jm *JobManager) forwardEvents() {
+	for {
+		select {
+		case <-jm.ctx.Done():
+			return
+		case <-jm.queue.notify:
+		}
+
+		for {
+			event, ok := jm.queue.pop()
+			if !ok {
+				break
+			}
+
+			select {
+			case jm.eventChan <- event:
+			case <-jm.ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+// resync 重新加载全部任务，与缓存比较后补发变化的事件，rewatch为true时先重新监听
+func (jm *JobManager) resync(rewatch bool) {
+	if rewatch {
+		jm.watchChan = jm.etcdClient.WatchWithPrefix(jm.ctx, common.JobSaveDir)
+	}
+
+	for {
+		resp, err := jm.etcdClient.GetWithPrefix(common.JobSaveDir)
+		if err == nil {
+			jm.applySnapshot(resp.Kvs)
+			jm.revision = resp.Header.Revision
+			jm.logger.Info("jobs resynced", zap.Int("count", len(resp.Kvs)))
+			return
+		}
+
+		jm.logger.Error("failed to resync jobs, retrying", zap.Error(err))
+		select {
+		case <-jm.ctx.Done():
+			return
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// applySnapshot 用etcd中的全量任务更新缓存，为新增、修改和删除的任务生成事件
+func (jm *JobManager) applySnapshot(kvs []*mvccpb.KeyValue) {
+	current := make(map[string]struct{}, len(kvs))
+	for _, kv := range kvs {
+		job := &common.Job{}
+		if err := json.Unmarshal(kv.Value, job); err != nil {
+			jm.logger.Error("failed to unmarshal job",
+				zap.String("jobKey", string(kv.Key)),
+				zap.Error(err))
+			continue
+		}
+		job.Revision = kv.ModRevision
+		current[job.Name] = struct{}{}
+
+		// 未变化的任务不重新生成调度计划
+		if cached, exists := jm.GetJob(job.Name); exists && reflect.DeepEqual(cached, job) {
+			continue
+		}
+
+		jm.jobsCache.Store(job.Name, job)
+		jm.queue.push(&common.JobEvent{EventType: common.JobEventSave, Job: job})
+	}
+
+	for _, job := range jm.ListJobs() {
+		if _, exists := current[job.Name]; !exists {
+			jm.jobsCache.Delete(job.Name)
+			jm.queue.push(&common.JobEvent{EventType: common.JobEventDelete, Job: job})
+		}
+	}
+}
+
 // handleWatchEvent 处理监听事件
 func (jm *JobManager) handleWatchEvent(event *clientv3.Event) *common.JobEvent {
 	// 提取Job名称
@@ -125,6 +218,7 @@ func (jm *JobManager) handleWatchEvent(event *clientv3.Event) *common.JobEvent {
 				zap.Error(err))
 			return nil
 		}
+		job.Revision = event.Kv.ModRevision
 
 		// 更新缓存
 		jm.jobsCache.Store(job.Name, job)
@@ -0,0 +1,60 @@
+package jobmgr
+
+import (
+	"sync"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+)
+
+// eventQueue 合并任务事件的缓冲队列，同一任务只保留最新的事件，
+// 调度器来不及处理时不会丢失任务的最终状态
+type eventQueue struct {
+	lock      sync.Mutex
+	order     []string                    // 按首次入队顺序排列的任务名
+	events    map[string]*common.JobEvent // 每个任务最新的事件
+	notify    chan struct{}               // 有新事件时通知
+	coalesced int64                       // 被合并掉的事件数
+}
+
+// newEventQueue 创建事件队列
+func newEventQueue() *eventQueue {
+	return &eventQueue{
+		events: make(map[string]*common.JobEvent),
+		notify: make(chan struct{}, 1),
+	}
+}
+
+// push 事件入队，同一任务已有未处理事件时用新事件替换
+func (q *eventQueue) push(event *common.JobEvent) {
+	q.lock.Lock()
+	name := event.Job.Name
+	if _, exists := q.events[name]; exists {
+		q.coalesced++
+	} else {
+		q.order = append(q.order, name)
+	}
+	q.events[name] = event
+	q.lock.Unlock()
+
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+}
+
+// pop 取出最早入队任务的最新事件
+func (q *eventQueue) pop() (*common.JobEvent, bool) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	if len(q.order) == 0 {
+		return nil, false
+	}
+
+	name := q.order[0]
+	q.order = q.order[1:]
+	event := q.events[name]
+	delete(q.events, name)
+
+	return event, true
+}
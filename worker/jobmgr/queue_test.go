@@ -0,0 +1,58 @@
+package jobmgr
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+)
+
+func TestEventQueue_Coalesce(t *testing.T) {
+	q := newEventQueue()
+
+	jobA := &common.Job{Name: "a", Command: "echo 1"}
+	jobA2 := &common.Job{Name: "a", Command: "echo 2"}
+	jobB := &common.Job{Name: "b", Command: "echo b"}
+
+	q.push(&common.JobEvent{EventType: common.JobEventSave, Job: jobA})
+	q.push(&common.JobEvent{EventType: common.JobEventSave, Job: jobB})
+	q.push(&common.JobEvent{EventType: common.JobEventSave, Job: jobA2})
+	q.push(&common.JobEvent{EventType: common.JobEventDelete, Job: jobB})
+
+	assert.Equal(t, int64(2), q.coalesced, "Two events should be coalesced")
+
+	// 保持首次入队顺序，每个任务只返回最新事件
+	event, ok := q.pop()
+	require.True(t, ok)
+	assert.Equal(t, common.JobEventSave, event.EventType)
+	assert.Equal(t, "echo 2", event.Job.Command, "Latest save should win")
+
+	event, ok = q.pop()
+	require.True(t, ok)
+	assert.Equal(t, common.JobEventDelete, event.EventType, "Delete after save should win")
+	assert.Equal(t, "b", event.Job.Name)
+
+	_, ok = q.pop()
+	assert.False(t, ok, "Queue should be empty")
+}
+
+func TestEventQueue_Notify(t *testing.T) {
+	q := newEventQueue()
+
+	q.push(&common.JobEvent{EventType: common.JobEventSave, Job: &common.Job{Name: "a"}})
+	q.push(&common.JobEvent{EventType: common.JobEventSave, Job: &common.Job{Name: "b"}})
+
+	// 多次入队只保留一个通知
+	select {
+	case <-q.notify:
+	default:
+		t.Fatal("Push should notify")
+	}
+	select {
+	case <-q.notify:
+		t.Fatal("Notifications should be collapsed")
+	default:
+	}
+}
@@ -0,0 +1,156 @@
+package killswitch
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"go.etcd.io/etcd/client/v3"
+	"go.uber.org/zap"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+	"github.com/fyerfyer/scheduler-refactor/config"
+	"github.com/fyerfyer/scheduler-refactor/pkg/etcd"
+)
+
+// Watcher 监听当前worker的紧急停机开关，开关变化时通知回调，开关关闭时回调收到nil
+type Watcher struct {
+	etcdClient *etcd.Client                // etcd客户端
+	logger     *zap.Logger                 // 日志对象
+	key        string                      // 当前worker的开关key
+	handler    func(ks *common.KillSwitch) // 开关变化回调
+	applied    int64                       // 已生效的开关版本，为0时开关未开启
+	revision   int64                       // 已加载的版本，不晚于该版本的事件忽略
+	ctx        context.Context             // 上下文，用于控制退出
+	cancelFunc context.CancelFunc          // 取消函数
+}
+
+// NewWatcher 创建紧急停机开关监听器
+func NewWatcher(logger *zap.Logger, etcdClient *etcd.Client, handler func(ks *common.KillSwitch)) *Watcher {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &Watcher{
+		etcdClient: etcdClient,
+		logger:     logger,
+		key:        common.KillSwitchDir + config.GlobalConfig.WorkerID,
+		handler:    handler,
+		ctx:        ctx,
+		cancelFunc: cancel,
+	}
+}
+
+// Start 加载当前开关状态并开始监听，需在调度器启动前调用，保证已开启的开关在重启后仍然生效
+func (w *Watcher) Start() error {
+	// 先监听再加载，加载版本之前的事件在处理时忽略
+	watchChan := w.etcdClient.Watch(w.ctx, w.key)
+	if err := w.load(); err != nil {
+		w.logger.Error("failed to load kill switch", zap.Error(err))
+		return err
+	}
+
+	go w.watchLoop(watchChan)
+
+	w.logger.Info("kill switch watcher started")
+	return nil
+}
+
+// Stop 停止监听
+func (w *Watcher) Stop() {
+	w.cancelFunc()
+	w.logger.Info("kill switch watcher stopped")
+}
+
+// watchLoop 监听开关变化，监听中断时重新监听并重新加载
+func (w *Watcher) watchLoop(watchChan clientv3.WatchChan) {
+	for {
+		select {
+		case <-w.ctx.Done():
+			return
+		case watchResp, ok := <-watchChan:
+			if !ok || watchResp.Err() != nil {
+				w.logger.Warn("kill switch watch interrupted, resyncing", zap.Bool("closed", !ok))
+				watchChan = w.resync()
+				continue
+			}
+
+			for _, event := range watchResp.Events {
+				if event.Kv.ModRevision <= w.revision {
+					continue
+				}
+
+				switch event.Type {
+				case clientv3.EventTypePut:
+					w.apply(event.Kv.Value, event.Kv.ModRevision)
+				case clientv3.EventTypeDelete:
+					w.release()
+				}
+			}
+		}
+	}
+}
+
+// resync 重新监听并重新加载开关，加载失败时每秒重试直到成功或停止
+func (w *Watcher) resync() clientv3.WatchChan {
+	watchChan := w.etcdClient.Watch(w.ctx, w.key)
+
+	for {
+		err := w.load()
+		if err == nil {
+			return watchChan
+		}
+
+		w.logger.Error("failed to resync kill switch, retrying", zap.Error(err))
+		select {
+		case <-w.ctx.Done():
+			return watchChan
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// load 加载当前开关状态，与已生效的开关相同时不重复通知回调
+func (w *Watcher) load() error {
+	resp, err := w.etcdClient.Get(w.key)
+	if err != nil {
+		return err
+	}
+
+	if resp.Count > 0 {
+		if resp.Kvs[0].ModRevision != w.applied {
+			w.apply(resp.Kvs[0].Value, resp.Kvs[0].ModRevision)
+		}
+	} else if w.applied != 0 {
+		w.release()
+	}
+	w.revision = resp.Header.Revision
+
+	return nil
+}
+
+// release 开关关闭，通知回调恢复调度
+func (w *Watcher) release() {
+	w.applied = 0
+	w.logger.Info("kill switch released")
+	w.handler(nil)
+}
+
+// apply 解析开关并通知回调，revision为开关的版本
+func (w *Watcher) apply(value []byte, revision int64) {
+	ks := &common.KillSwitch{}
+	if err := json.Unmarshal(value, ks); err != nil {
+		// 无法解析时按开启处理，宁可停机也不要在事故中继续执行
+		w.logger.Error("failed to unmarshal kill switch, treating as engaged", zap.Error(err))
+		ks = &common.KillSwitch{Reason: "unparseable kill switch"}
+	}
+
+	if ks.GracePeriod <= 0 {
+		ks.GracePeriod = common.DefaultKillGracePeriod
+	}
+
+	w.logger.Warn("kill switch engaged",
+		zap.String("reason", ks.Reason),
+		zap.Int("gracePeriod", ks.GracePeriod),
+		zap.String("createdBy", ks.CreatedBy))
+	w.applied = revision
+	w.handler(ks)
+}
@@ -2,34 +2,50 @@ package logsink
 
 import (
 	"context"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/robfig/cron/v3"
 	"go.uber.org/zap"
 
 	"github.com/fyerfyer/scheduler-refactor/common"
 	"github.com/fyerfyer/scheduler-refactor/config"
-	"github.com/fyerfyer/scheduler-refactor/pkg/mongodb"
+	"github.com/fyerfyer/scheduler-refactor/pkg/logstore"
+	"github.com/fyerfyer/scheduler-refactor/pkg/metrics"
+)
+
+// 日志批量提交的指标
+var (
+	batchSizes     = metrics.NewHistogram("logsink_batch_size", "Logs per batch committed by the log sink.", []float64{1, 10, 50, 100, 500, 1000})
+	commitFailures = metrics.NewCounter("logsink_commit_failures_total", "Log batches the log sink failed to commit.")
 )
 
 // LogSink 日志收集器
 type LogSink struct {
-	client      *mongodb.Client     // MongoDB客户端
-	logChan     chan *common.JobLog // 日志通道
-	logBatch    []*common.JobLog    // 日志批次暂存
-	logger      *zap.Logger         // 日志对象
-	batchSize   int                 // 批处理大小
-	commitTimer *time.Timer         // 自动提交定时器
+	client        logstore.LogStore   // 日志存储
+	logChan       chan *common.JobLog // 日志通道
+	logBatch      []*common.JobLog    // 日志批次暂存
+	logger        *zap.Logger         // 日志对象
+	batchSize     atomic.Int64        // 批处理大小，可热更新
+	commitTimeout atomic.Int64        // 自动提交超时(毫秒)，可热更新
+	retentionDays atomic.Int64        // 日志保留天数，可热更新
+	commitTimer   *time.Timer         // 自动提交定时器
+	stopChan      chan chan struct{}  // 停止请求，收集协程写完剩余日志后关闭应答通道
+	stopOnce      sync.Once
 }
 
 // NewLogSink 创建日志收集器
-func NewLogSink(mongoClient *mongodb.Client, logger *zap.Logger) *LogSink {
+func NewLogSink(logStore logstore.LogStore, logger *zap.Logger) *LogSink {
 	logSink := &LogSink{
-		client:    mongoClient,
-		logChan:   make(chan *common.JobLog, 1000),
-		logBatch:  make([]*common.JobLog, 0, config.GlobalConfig.LogBatchSize),
-		logger:    logger,
-		batchSize: config.GlobalConfig.LogBatchSize,
+		client:   logStore,
+		logChan:  make(chan *common.JobLog, 1000),
+		logBatch: make([]*common.JobLog, 0, config.GlobalConfig.LogBatchSize),
+		logger:   logger,
+		stopChan: make(chan chan struct{}),
 	}
+	logSink.batchSize.Store(int64(config.GlobalConfig.LogBatchSize))
+	logSink.commitTimeout.Store(int64(config.GlobalConfig.LogCommitTimeout))
 
 	// 启动日志收集协程
 	logSink.startWorker()
@@ -41,7 +57,7 @@ func NewLogSink(mongoClient *mongodb.Client, logger *zap.Logger) *LogSink {
 func (l *LogSink) startWorker() {
 	go func() {
 		// 初始化自动提交定时器
-		l.commitTimer = time.NewTimer(time.Duration(config.GlobalConfig.LogCommitTimeout) * time.Millisecond)
+		l.commitTimer = time.NewTimer(l.commitInterval())
 
 		for {
 			select {
@@ -50,24 +66,56 @@ func (l *LogSink) startWorker() {
 				l.logBatch = append(l.logBatch, log)
 
 				// 如果批次已满，立即提交
-				if len(l.logBatch) >= l.batchSize {
+				if len(l.logBatch) >= int(l.batchSize.Load()) {
 					l.commitLogs()
 					// 重置定时器
-					l.commitTimer.Reset(time.Duration(config.GlobalConfig.LogCommitTimeout) * time.Millisecond)
+					l.commitTimer.Reset(l.commitInterval())
 				}
 
+			case done := <-l.stopChan: // 停止前写入通道和批次中的所有日志
+				l.commitTimer.Stop()
+				l.drain()
+				l.commitLogs()
+				close(done)
+				return
 			case <-l.commitTimer.C: // 提交超时
 				// 有日志就提交
 				if len(l.logBatch) > 0 {
 					l.commitLogs()
 				}
 				// 重置定时器
-				l.commitTimer.Reset(time.Duration(config.GlobalConfig.LogCommitTimeout) * time.Millisecond)
+				l.commitTimer.Reset(l.commitInterval())
 			}
 		}
 	}()
 }
 
+// commitInterval 获取自动提交间隔
+func (l *LogSink) commitInterval() time.Duration {
+	return time.Duration(l.commitTimeout.Load()) * time.Millisecond
+}
+
+// SetBatchSize 热更新批处理大小
+func (l *LogSink) SetBatchSize(size int) {
+	if size > 0 {
+		l.batchSize.Store(int64(size))
+	}
+}
+
+// SetCommitTimeout 热更新自动提交超时(毫秒)，下一次定时器重置时生效
+func (l *LogSink) SetCommitTimeout(timeout int) {
+	if timeout > 0 {
+		l.commitTimeout.Store(int64(timeout))
+	}
+}
+
+// SetRetentionDays 热更新日志保留天数，下一次清理时生效
+func (l *LogSink) SetRetentionDays(days int) {
+	if days > 0 {
+		l.retentionDays.Store(int64(days))
+	}
+}
+
 // Append 追加日志
 func (l *LogSink) Append(jobLog *common.JobLog) {
 	select {
@@ -89,15 +137,15 @@ func (l *LogSink) commitLogs() {
 		return
 	}
 
-	// 批量插入mongo
-	logs := make([]interface{}, len(l.logBatch))
-	for i, log := range l.logBatch {
-		logs[i] = log
-	}
+	// 复制当前批次
+	logs := make([]*common.JobLog, len(l.logBatch))
+	copy(logs, l.logBatch)
 
 	// 执行批量插入
-	_, err := l.client.InsertMany(logs)
+	batchSizes.Observe(float64(len(logs)))
+	err := l.client.InsertLogs(logs)
 	if err != nil {
+		commitFailures.Inc()
 		l.logger.Error("failed to commit logs",
 			zap.Int("count", len(logs)),
 			zap.Error(err))
@@ -110,14 +158,30 @@ func (l *LogSink) commitLogs() {
 	l.logBatch = l.logBatch[:0]
 }
 
-// Stop 停止日志收集器
-func (l *LogSink) Stop() {
-	// 立即提交当前批次的日志
-	if len(l.logBatch) > 0 {
-		l.commitLogs()
+// drain 取出通道中剩余的日志，批次满时提交
+func (l *LogSink) drain() {
+	for {
+		select {
+		case log := <-l.logChan:
+			l.logBatch = append(l.logBatch, log)
+			if len(l.logBatch) >= int(l.batchSize.Load()) {
+				l.commitLogs()
+			}
+		default:
+			return
+		}
 	}
 }
 
+// Stop 停止日志收集器，返回前通道和批次中的日志都已提交，重复调用无副作用
+func (l *LogSink) Stop() {
+	l.stopOnce.Do(func() {
+		done := make(chan struct{})
+		l.stopChan <- done
+		<-done
+	})
+}
+
 // CleanExpiredLogs 清理过期日志
 func (l *LogSink) CleanExpiredLogs(retentionDays int) {
 	// 默认保留30天的日志
@@ -143,47 +207,47 @@ func (l *LogSink) CleanExpiredLogs(retentionDays int) {
 	}
 }
 
-// StartLogCleaner 启动定期清理过期日志的协程
-func (l *LogSink) StartLogCleaner(ctx context.Context, retentionDays int) {
-	go func() {
-		// 创建一个每天执行的定时器（凌晨3点执行）
-		ticker := time.NewTicker(24 * time.Hour)
-		defer ticker.Stop()
-
-		// 计算下次执行的时间（今天或明天的凌晨3点）
-		now := time.Now()
-		nextRun := time.Date(now.Year(), now.Month(), now.Day(), 3, 0, 0, 0, now.Location())
-		if now.After(nextRun) {
-			nextRun = nextRun.Add(24 * time.Hour)
-		}
+// StartLogCleaner 启动定期清理过期日志的协程，清理时间由配置的cron表达式决定
+func (l *LogSink) StartLogCleaner(ctx context.Context, retentionDays int) error {
+	schedule := config.GlobalConfig.LogCleanSchedule
+	if schedule == "" {
+		schedule = common.DefaultLogCleanSchedule
+	}
 
-		// 第一次执行的定时器
-		timer := time.NewTimer(nextRun.Sub(now))
-		defer timer.Stop()
+	parser := cron.NewParser(cron.Second | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow)
+	expr, err := parser.Parse(schedule)
+	if err != nil {
+		return err
+	}
 
+	// 远程配置已经设置了保留天数时以远程配置为准
+	l.retentionDays.CompareAndSwap(0, int64(retentionDays))
+
+	go func() {
 		// 先执行一次清理
-		l.CleanExpiredLogs(retentionDays)
+		l.CleanExpiredLogs(int(l.retentionDays.Load()))
 
 		for {
+			timer := time.NewTimer(time.Until(expr.Next(time.Now())))
+
 			select {
 			case <-timer.C:
-				// 第一次到时间后，使用ticker
-				ticker.Reset(24 * time.Hour)
-				l.CleanExpiredLogs(retentionDays)
-
-			case <-ticker.C:
-				// 后续每24小时执行一次
-				l.CleanExpiredLogs(retentionDays)
+				l.CleanExpiredLogs(int(l.retentionDays.Load()))
 
 			case <-ctx.Done():
 				// 上下文取消，退出协程
+				timer.Stop()
 				l.logger.Info("log cleaner stopped")
 				return
 			}
 		}
 	}()
 
-	l.logger.Info("log cleaner started", zap.Int("retentionDays", retentionDays))
+	l.logger.Info("log cleaner started",
+		zap.String("schedule", schedule),
+		zap.Int64("retentionDays", l.retentionDays.Load()))
+
+	return nil
 }
 
 // GetLogChan 获取日志通道，用于测试
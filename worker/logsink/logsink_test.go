@@ -59,7 +59,7 @@ func TestNewLogSink(t *testing.T) {
 
 	logSink := NewLogSink(client, logger)
 	assert.NotNil(t, logSink, "LogSink should not be nil")
-	assert.Equal(t, config.GlobalConfig.LogBatchSize, logSink.batchSize, "Batch size should match config")
+	assert.Equal(t, int64(config.GlobalConfig.LogBatchSize), logSink.batchSize.Load(), "Batch size should match config")
 	assert.NotNil(t, logSink.logChan, "Log channel should be initialized")
 	assert.NotNil(t, logSink.logBatch, "Log batch should be initialized")
 
@@ -169,7 +169,7 @@ func TestLogSink_CleanExpiredLogs(t *testing.T) {
 	time.Sleep(500 * time.Millisecond)
 
 	// 查询旧日志，应该已被删除
-	logs, err := client.FindJobLogs(oldJobLog.JobName, 0, 10)
+	logs, err := client.FindJobLogs(oldJobLog.JobName, nil, 0, 10)
 	require.NoError(t, err, "Query should not fail")
 
 	// 检查是否还能找到旧日志
@@ -216,12 +216,12 @@ func TestLogSink_ChannelOverflow(t *testing.T) {
 
 	// 手动创建LogSink以使用小容量通道
 	logSink := &LogSink{
-		client:    client,
-		logChan:   make(chan *common.JobLog, smallCapacity),
-		logBatch:  make([]*common.JobLog, 0, config.GlobalConfig.LogBatchSize),
-		logger:    logger,
-		batchSize: config.GlobalConfig.LogBatchSize,
+		client:   client,
+		logChan:  make(chan *common.JobLog, smallCapacity),
+		logBatch: make([]*common.JobLog, 0, config.GlobalConfig.LogBatchSize),
+		logger:   logger,
 	}
+	logSink.SetBatchSize(config.GlobalConfig.LogBatchSize)
 
 	// 不启动worker，以测试通道溢出
 
@@ -0,0 +1,165 @@
+package nsconfig
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"go.etcd.io/etcd/client/v3"
+	"go.uber.org/zap"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+	"github.com/fyerfyer/scheduler-refactor/pkg/etcd"
+)
+
+// Watcher 监听etcd中的命名空间设置，供任务继承命名空间的默认值
+type Watcher struct {
+	etcdClient *etcd.Client                         // etcd客户端
+	logger     *zap.Logger                          // 日志对象
+	settings   map[string]*common.NamespaceSettings // 当前设置，key为命名空间
+	lock       sync.RWMutex                         // 保护settings
+	revision   int64                                // 已加载的版本，不晚于该版本的事件忽略
+	ctx        context.Context                      // 上下文，用于控制退出
+	cancelFunc context.CancelFunc                   // 取消函数
+}
+
+// NewWatcher 创建命名空间设置监听器
+func NewWatcher(logger *zap.Logger, etcdClient *etcd.Client) *Watcher {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &Watcher{
+		etcdClient: etcdClient,
+		logger:     logger,
+		settings:   make(map[string]*common.NamespaceSettings),
+		ctx:        ctx,
+		cancelFunc: cancel,
+	}
+}
+
+// Start 加载当前设置并开始监听变化
+func (w *Watcher) Start() error {
+	// 先监听再加载，加载版本之前的事件在处理时忽略
+	watchChan := w.etcdClient.WatchWithPrefix(w.ctx, common.NamespaceDir)
+	count, err := w.load()
+	if err != nil {
+		w.logger.Error("failed to load namespace settings", zap.Error(err))
+		return err
+	}
+
+	go w.watchLoop(watchChan)
+
+	w.logger.Info("namespace settings watcher started", zap.Int("namespaces", count))
+	return nil
+}
+
+// Stop 停止监听
+func (w *Watcher) Stop() {
+	w.cancelFunc()
+	w.logger.Info("namespace settings watcher stopped")
+}
+
+// NotifyRoute 获取命名空间的默认失败通知路由，未设置时返回nil
+func (w *Watcher) NotifyRoute(namespace string) *common.NotifyRoute {
+	w.lock.RLock()
+	defer w.lock.RUnlock()
+
+	if settings, ok := w.settings[common.NamespaceOf(namespace)]; ok {
+		return settings.Notify
+	}
+	return nil
+}
+
+// watchLoop 监听设置目录变化，监听中断时重新监听并重新加载
+func (w *Watcher) watchLoop(watchChan clientv3.WatchChan) {
+	for {
+		select {
+		case <-w.ctx.Done():
+			return
+		case watchResp, ok := <-watchChan:
+			if !ok || watchResp.Err() != nil {
+				w.logger.Warn("namespace settings watch interrupted, resyncing", zap.Bool("closed", !ok))
+				watchChan = w.resync()
+				continue
+			}
+
+			w.lock.Lock()
+			for _, event := range watchResp.Events {
+				if event.Kv.ModRevision <= w.revision {
+					continue
+				}
+
+				key := string(event.Kv.Key)
+				switch event.Type {
+				case clientv3.EventTypePut:
+					w.applyKV(key, event.Kv.Value)
+				case clientv3.EventTypeDelete:
+					delete(w.settings, strings.TrimPrefix(key, common.NamespaceDir))
+				}
+			}
+			w.lock.Unlock()
+		}
+	}
+}
+
+// resync 重新监听并重新加载设置，加载失败时每秒重试直到成功或停止
+func (w *Watcher) resync() clientv3.WatchChan {
+	watchChan := w.etcdClient.WatchWithPrefix(w.ctx, common.NamespaceDir)
+
+	for {
+		count, err := w.load()
+		if err == nil {
+			w.logger.Info("namespace settings resynced", zap.Int("namespaces", count))
+			return watchChan
+		}
+
+		w.logger.Error("failed to resync namespace settings, retrying", zap.Error(err))
+		select {
+		case <-w.ctx.Done():
+			return watchChan
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// load 加载全部设置替换当前设置，返回加载的命名空间数
+func (w *Watcher) load() (int, error) {
+	resp, err := w.etcdClient.GetWithPrefix(common.NamespaceDir)
+	if err != nil {
+		return 0, err
+	}
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	w.settings = make(map[string]*common.NamespaceSettings, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		w.applyKV(string(kv.Key), kv.Value)
+	}
+	w.revision = resp.Header.Revision
+
+	return len(resp.Kvs), nil
+}
+
+// applyKV 解析并保存设置，非法设置会被忽略
+func (w *Watcher) applyKV(key string, value []byte) {
+	settings := &common.NamespaceSettings{}
+	if err := json.Unmarshal(value, settings); err != nil {
+		w.logger.Error("failed to unmarshal namespace settings",
+			zap.String("key", key),
+			zap.Error(err))
+		return
+	}
+
+	if settings.Notify != nil {
+		if err := settings.Notify.Validate(); err != nil {
+			w.logger.Error("ignoring invalid namespace notify route",
+				zap.String("key", key),
+				zap.Error(err))
+			settings.Notify = nil
+		}
+	}
+
+	w.settings[strings.TrimPrefix(key, common.NamespaceDir)] = settings
+}
@@ -0,0 +1,31 @@
+package nsconfig
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+)
+
+func TestWatcher_NotifyRoute(t *testing.T) {
+	w := NewWatcher(zaptest.NewLogger(t), nil)
+
+	assert.Nil(t, w.NotifyRoute("payments"), "Namespaces without settings should have no route")
+
+	w.applyKV(common.NamespaceDir+"payments", []byte(`{"name":"payments","notify":{"webhooks":["https://hooks.example.com/payments"]}}`))
+	w.applyKV(common.NamespaceDir+common.DefaultNamespace, []byte(`{"name":"default","notify":{"severities":{"killed":"none"}}}`))
+	w.applyKV(common.NamespaceDir+"broken", []byte(`{"name":"broken","notify":{"webhooks":["not a url"]}}`))
+
+	route := w.NotifyRoute("payments")
+	require.NotNil(t, route)
+	assert.Equal(t, []string{"https://hooks.example.com/payments"}, route.Webhooks)
+
+	route = w.NotifyRoute("")
+	require.NotNil(t, route, "Jobs without a namespace should use the default namespace settings")
+	assert.Equal(t, common.SeverityNone, route.Severities[common.RunStatusKilled])
+
+	assert.Nil(t, w.NotifyRoute("broken"), "Invalid routes should be ignored")
+}
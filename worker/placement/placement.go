@@ -0,0 +1,122 @@
+package placement
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.uber.org/zap"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+	"github.com/fyerfyer/scheduler-refactor/pkg/etcd"
+)
+
+const (
+	// placementTTL 决策记录绑定的租约时间(秒)，任务不再触发或worker下线后记录自动过期
+	placementTTL = 24 * 3600
+
+	// leaseReuse 同一个租约的复用时间，超过后申请新租约，避免每轮调度都申请租约
+	leaseReuse = time.Hour
+
+	// queueSize 等待写入的批次数，写入跟不上时丢弃新的批次，不阻塞调度
+	queueSize = 64
+)
+
+// Publisher 将调度器每轮的触发归属决策异步写入etcd，master据此说明任务最近一次触发由哪个worker执行
+type Publisher struct {
+	etcdClient *etcd.Client                     // etcd客户端
+	logger     *zap.Logger                      // 日志对象
+	queue      chan []*common.PlacementDecision // 等待写入的决策
+	leaseID    clientv3.LeaseID                 // 当前复用的租约
+	grantedAt  time.Time                        // 当前租约的申请时间
+	ctx        context.Context                  // 上下文，用于控制退出
+	cancelFunc context.CancelFunc               // 取消函数
+}
+
+// NewPublisher 创建决策发布器
+func NewPublisher(logger *zap.Logger, etcdClient *etcd.Client) *Publisher {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &Publisher{
+		etcdClient: etcdClient,
+		logger:     logger,
+		queue:      make(chan []*common.PlacementDecision, queueSize),
+		ctx:        ctx,
+		cancelFunc: cancel,
+	}
+}
+
+// Start 开始写入决策
+func (p *Publisher) Start() {
+	go p.publishLoop()
+	p.logger.Info("placement publisher started")
+}
+
+// Stop 停止写入，尚未写入的决策被丢弃
+func (p *Publisher) Stop() {
+	p.cancelFunc()
+	p.logger.Info("placement publisher stopped")
+}
+
+// Record 提交一轮调度的决策，队列已满时丢弃
+func (p *Publisher) Record(decisions []*common.PlacementDecision) {
+	if len(decisions) == 0 {
+		return
+	}
+
+	select {
+	case p.queue <- decisions:
+	default:
+		p.logger.Debug("placement queue full, dropping decisions", zap.Int("count", len(decisions)))
+	}
+}
+
+// publishLoop 依次写入提交的决策
+func (p *Publisher) publishLoop() {
+	for {
+		select {
+		case <-p.ctx.Done():
+			return
+		case decisions := <-p.queue:
+			if err := p.publish(decisions); err != nil {
+				p.logger.Warn("failed to publish placement decisions",
+					zap.Int("count", len(decisions)),
+					zap.Error(err))
+			}
+		}
+	}
+}
+
+// publish 在一次写入中保存决策，同一个worker对同一个任务只保留最近一次决策
+func (p *Publisher) publish(decisions []*common.PlacementDecision) error {
+	kvs := make(map[string]string, len(decisions))
+	for _, d := range decisions {
+		data, err := json.Marshal(d)
+		if err != nil {
+			return err
+		}
+		kvs[decisionKey(d)] = string(data)
+	}
+
+	if p.leaseID == 0 || time.Since(p.grantedAt) > leaseReuse {
+		leaseID, err := p.etcdClient.GrantLease(placementTTL)
+		if err != nil {
+			return err
+		}
+		p.leaseID = leaseID
+		p.grantedAt = time.Now()
+	}
+
+	if err := p.etcdClient.PutManyWithLease(kvs, p.leaseID); err != nil {
+		// 租约可能已被撤销，下次重新申请
+		p.leaseID = 0
+		return err
+	}
+	return nil
+}
+
+// decisionKey 决策在etcd中的key
+func decisionKey(d *common.PlacementDecision) string {
+	return common.JobPlacementDir + d.JobName + "/" + d.Worker
+}
@@ -0,0 +1,25 @@
+package placement
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+)
+
+func TestPublisher_Record(t *testing.T) {
+	p := NewPublisher(zaptest.NewLogger(t), nil)
+
+	p.Record(nil)
+	assert.Empty(t, p.queue, "Empty rounds should not be queued")
+
+	decisions := []*common.PlacementDecision{{JobName: "backup", Worker: "worker-1", Outcome: common.PlacementWon}}
+	for i := 0; i < queueSize+10; i++ {
+		p.Record(decisions)
+	}
+	assert.Len(t, p.queue, queueSize, "Record should drop decisions instead of blocking the scheduler")
+
+	assert.Equal(t, common.JobPlacementDir+"backup/worker-1", decisionKey(decisions[0]))
+}
@@ -0,0 +1,173 @@
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+)
+
+// 插件协议的子命令
+const (
+	commandDescribe = "describe" // 输出插件声明(JSON)到标准输出
+	commandRun      = "run"      // 从标准输入读取执行请求(JSON)并执行，退出码即执行结果
+)
+
+// 插件发现参数
+const (
+	// describeTimeout 单个插件输出声明的超时时间
+	describeTimeout = 5 * time.Second
+
+	// maxManifestBytes 插件声明的最大字节数
+	maxManifestBytes = 64 * 1024
+)
+
+// Request 通过标准输入传给插件run命令的执行请求
+type Request struct {
+	JobName  string         `json:"jobName"`           // 任务名称
+	RunID    string         `json:"runId"`             // 执行的唯一标识
+	Command  string         `json:"command,omitempty"` // 任务的command，含义由插件决定
+	Config   map[string]any `json:"config,omitempty"`  // 任务的config，已按插件声明校验
+	Timeout  int            `json:"timeout,omitempty"` // 任务超时时间(秒)，超时后worker终止插件进程
+	PlanTime int64          `json:"planTime"`          // 计划执行时间
+	Attempt  int            `json:"attempt"`           // 第几次尝试
+}
+
+// Plugin 一个执行器插件
+type Plugin struct {
+	Path     string                   // 插件可执行文件路径
+	Manifest *common.ExecutorManifest // 插件声明
+}
+
+// Registry 从插件目录发现的执行器插件。插件是目录下的可执行文件，
+// 以describe子命令输出声明，以run子命令从标准输入读取执行请求并执行
+type Registry struct {
+	plugins map[string]*Plugin // 任务类型 -> 插件
+}
+
+// Discover 发现插件目录下的执行器插件，dir为空时不加载任何插件。
+// 单个插件无法加载时记录日志并跳过，不影响其他插件
+func Discover(dir string, logger *zap.Logger) (*Registry, error) {
+	registry := &Registry{plugins: make(map[string]*Plugin)}
+	if dir == "" {
+		return registry, nil
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plugin directory: %v", err)
+	}
+
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		info, err := os.Stat(path)
+		if err != nil || !info.Mode().IsRegular() || info.Mode().Perm()&0o111 == 0 {
+			continue
+		}
+
+		manifest, err := describe(path)
+		if err != nil {
+			logger.Warn("failed to load executor plugin",
+				zap.String("path", path),
+				zap.Error(err))
+			continue
+		}
+		if existing, ok := registry.plugins[manifest.Type]; ok {
+			logger.Warn("duplicate executor plugin type, ignoring",
+				zap.String("type", manifest.Type),
+				zap.String("path", path),
+				zap.String("loaded", existing.Path))
+			continue
+		}
+
+		registry.plugins[manifest.Type] = &Plugin{Path: path, Manifest: manifest}
+		logger.Info("executor plugin loaded",
+			zap.String("type", manifest.Type),
+			zap.String("version", manifest.Version),
+			zap.String("path", path))
+	}
+
+	return registry, nil
+}
+
+// describe 执行插件的describe子命令并校验声明
+func describe(path string) (*common.ExecutorManifest, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), describeTimeout)
+	defer cancel()
+
+	output, err := exec.CommandContext(ctx, path, commandDescribe).Output()
+	if err != nil {
+		return nil, fmt.Errorf("describe failed: %v", err)
+	}
+	if len(output) > maxManifestBytes {
+		return nil, fmt.Errorf("manifest exceeds %d bytes", maxManifestBytes)
+	}
+
+	manifest := &common.ExecutorManifest{}
+	if err = json.Unmarshal(output, manifest); err != nil {
+		return nil, fmt.Errorf("invalid manifest: %v", err)
+	}
+	if err = manifest.Validate(); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// Manifests 返回所有插件的声明，按类型排序
+func (r *Registry) Manifests() []*common.ExecutorManifest {
+	manifests := make([]*common.ExecutorManifest, 0, len(r.plugins))
+	for _, p := range r.plugins {
+		manifests = append(manifests, p.Manifest)
+	}
+	sort.Slice(manifests, func(i, j int) bool {
+		return manifests[i].Type < manifests[j].Type
+	})
+	return manifests
+}
+
+// Supports 判断本worker能否执行该类型的任务，shell任务总是可以执行
+func (r *Registry) Supports(jobType string) bool {
+	if jobType == "" || jobType == common.ExecutorShell {
+		return true
+	}
+	_, ok := r.plugins[jobType]
+	return ok
+}
+
+// Command 为插件任务构建run命令，执行请求通过标准输入传入。
+// 执行前按本worker上的插件声明再次校验任务，防止master校验时使用的声明与本地插件不一致
+func (r *Registry) Command(ctx context.Context, info *common.JobExecuteInfo) (*exec.Cmd, error) {
+	p, ok := r.plugins[info.Job.Type]
+	if !ok {
+		return nil, fmt.Errorf("executor plugin %s is not installed on this worker", info.Job.Type)
+	}
+	if err := p.Manifest.ValidateJob(info.Job); err != nil {
+		return nil, err
+	}
+
+	request, err := json.Marshal(&Request{
+		JobName:  info.Job.Name,
+		RunID:    info.RunID,
+		Command:  info.Job.Command,
+		Config:   info.Job.Config,
+		Timeout:  info.Job.Timeout,
+		PlanTime: info.PlanTime.Unix(),
+		Attempt:  info.Attempt,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := exec.CommandContext(ctx, p.Path, commandRun)
+	cmd.Stdin = bytes.NewReader(request)
+	return cmd, nil
+}
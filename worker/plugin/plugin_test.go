@@ -0,0 +1,92 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+)
+
+// echoPlugin 声明ansible类型的测试插件，run时把执行请求原样输出
+const echoPlugin = `#!/bin/sh
+case "$1" in
+describe) echo '{"type":"ansible","version":"1.0.0","config":{"playbook":{"type":"string","required":true}}}' ;;
+run) cat ;;
+*) exit 2 ;;
+esac
+`
+
+func writePlugin(t *testing.T, dir, name, content string, mode os.FileMode) {
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), mode))
+}
+
+func TestDiscover(t *testing.T) {
+	dir := t.TempDir()
+	writePlugin(t, dir, "ansible", echoPlugin, 0o755)
+	writePlugin(t, dir, "broken", "#!/bin/sh\necho not json\n", 0o755)
+	writePlugin(t, dir, "README", "not a plugin", 0o644)
+	writePlugin(t, dir, "duplicate", echoPlugin, 0o755)
+
+	registry, err := Discover(dir, zap.NewNop())
+	require.NoError(t, err)
+
+	manifests := registry.Manifests()
+	require.Len(t, manifests, 1, "Broken, non-executable and duplicate plugins should be skipped")
+	assert.Equal(t, "ansible", manifests[0].Type)
+	assert.Equal(t, "1.0.0", manifests[0].Version)
+
+	assert.True(t, registry.Supports("ansible"))
+	assert.True(t, registry.Supports(""))
+	assert.True(t, registry.Supports(common.ExecutorShell))
+	assert.False(t, registry.Supports("spark-submit"))
+
+	_, err = Discover(filepath.Join(dir, "missing"), zap.NewNop())
+	assert.Error(t, err)
+
+	empty, err := Discover("", zap.NewNop())
+	require.NoError(t, err)
+	assert.Empty(t, empty.Manifests())
+}
+
+func TestCommand(t *testing.T) {
+	dir := t.TempDir()
+	writePlugin(t, dir, "ansible", echoPlugin, 0o755)
+	registry, err := Discover(dir, zap.NewNop())
+	require.NoError(t, err)
+
+	info := &common.JobExecuteInfo{
+		Job:      &common.Job{Name: "deploy", Type: "ansible", Command: "-l web", Config: map[string]any{"playbook": "site.yml"}},
+		RunID:    "run-1",
+		PlanTime: time.Unix(1700000000, 0),
+		Attempt:  1,
+	}
+	cmd, err := registry.Command(context.Background(), info)
+	require.NoError(t, err)
+	output, err := cmd.Output()
+	require.NoError(t, err)
+
+	request := &Request{}
+	require.NoError(t, json.Unmarshal(output, request))
+	assert.Equal(t, "deploy", request.JobName)
+	assert.Equal(t, "run-1", request.RunID)
+	assert.Equal(t, "-l web", request.Command)
+	assert.Equal(t, "site.yml", request.Config["playbook"])
+	assert.Equal(t, int64(1700000000), request.PlanTime)
+
+	// 本地插件声明不接受的配置在执行前被拒绝
+	info.Job.Config = map[string]any{}
+	_, err = registry.Command(context.Background(), info)
+	assert.ErrorContains(t, err, "playbook")
+
+	info.Job.Type = "spark-submit"
+	_, err = registry.Command(context.Background(), info)
+	assert.ErrorContains(t, err, "not installed")
+}
@@ -0,0 +1,86 @@
+package precondition
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+)
+
+// 默认值
+const (
+	DefaultTimeout    = 5 * time.Second  // 单次检查超时
+	DefaultRetryDelay = 10 * time.Second // 重试间隔
+)
+
+// Check 检查一个前置条件，不满足时返回原因
+func Check(ctx context.Context, p common.Precondition) error {
+	timeout := DefaultTimeout
+	if p.Timeout > 0 {
+		timeout = time.Duration(p.Timeout) * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	switch p.Type {
+	case common.PreconditionTCP:
+		var dialer net.Dialer
+		conn, err := dialer.DialContext(ctx, "tcp", p.Target)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	case common.PreconditionHTTP:
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.Target, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("unexpected status %d", resp.StatusCode)
+		}
+		return nil
+	case common.PreconditionFile:
+		_, err := os.Stat(p.Target)
+		return err
+	default:
+		return fmt.Errorf("unsupported precondition type: %s", p.Type)
+	}
+}
+
+// CheckAll 按顺序检查任务的前置条件，返回第一个不满足的条件
+func CheckAll(ctx context.Context, preconditions []common.Precondition) error {
+	for _, p := range preconditions {
+		if err := Check(ctx, p); err != nil {
+			return fmt.Errorf("%s: %v", p, err)
+		}
+	}
+	return nil
+}
+
+// Wait 检查任务的前置条件，不满足时按任务配置的次数和间隔重试，ctx结束时放弃
+func Wait(ctx context.Context, job *common.Job) error {
+	delay := DefaultRetryDelay
+	if job.PreconditionRetryDelay > 0 {
+		delay = time.Duration(job.PreconditionRetryDelay) * time.Second
+	}
+
+	err := CheckAll(ctx, job.Preconditions)
+	for retry := 0; err != nil && retry < job.PreconditionRetries; retry++ {
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return err
+		}
+		err = CheckAll(ctx, job.Preconditions)
+	}
+	return err
+}
@@ -0,0 +1,63 @@
+package precondition
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+)
+
+func TestCheck(t *testing.T) {
+	ctx := context.Background()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := listener.Addr().String()
+	assert.NoError(t, Check(ctx, common.Precondition{Type: common.PreconditionTCP, Target: addr}))
+	listener.Close()
+	assert.Error(t, Check(ctx, common.Precondition{Type: common.PreconditionTCP, Target: addr}), "Closed port should fail")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/healthz" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+	assert.NoError(t, Check(ctx, common.Precondition{Type: common.PreconditionHTTP, Target: server.URL + "/healthz"}))
+	err = Check(ctx, common.Precondition{Type: common.PreconditionHTTP, Target: server.URL + "/down"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unexpected status 503")
+
+	path := filepath.Join(t.TempDir(), "ready")
+	assert.Error(t, Check(ctx, common.Precondition{Type: common.PreconditionFile, Target: path}))
+	require.NoError(t, os.WriteFile(path, nil, 0o644))
+	assert.NoError(t, Check(ctx, common.Precondition{Type: common.PreconditionFile, Target: path}))
+}
+
+func TestWait(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ready")
+	job := &common.Job{
+		Name:                   "precondition-job",
+		Preconditions:          []common.Precondition{{Type: common.PreconditionFile, Target: path}},
+		PreconditionRetries:    1,
+		PreconditionRetryDelay: 1,
+	}
+
+	err := Wait(context.Background(), job)
+	require.Error(t, err, "Missing file should fail after retries")
+	assert.Contains(t, err.Error(), "file "+path)
+
+	// 重试期间条件满足
+	go func() {
+		os.WriteFile(path, nil, 0o644)
+	}()
+	assert.NoError(t, Wait(context.Background(), job))
+}
@@ -0,0 +1,176 @@
+package progress
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+	"github.com/fyerfyer/scheduler-refactor/config"
+	"github.com/fyerfyer/scheduler-refactor/pkg/etcd"
+)
+
+const (
+	// pollInterval 读取进度文件的间隔
+	pollInterval = 5 * time.Second
+
+	// progressTTL 进度记录的租约时间，worker异常退出后进度记录自动过期
+	progressTTL = 600
+
+	// maxLineLength 只读取进度文件末尾的这些字节，避免任务不断追加导致文件过大
+	maxLineLength = 4096
+)
+
+// Reporter 为每次执行创建进度文件，任务向文件写入进度，worker将最新一行上报到etcd。
+// 进度文件每行格式为"<百分比> <说明>"，百分比可以省略
+type Reporter struct {
+	etcdClient *etcd.Client // etcd客户端
+	logger     *zap.Logger  // 日志对象
+	dir        string       // 进度文件所在目录
+}
+
+// NewReporter 创建进度上报器，dir不存在时自动创建
+func NewReporter(logger *zap.Logger, etcdClient *etcd.Client, dir string) (*Reporter, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	return &Reporter{
+		etcdClient: etcdClient,
+		logger:     logger,
+		dir:        dir,
+	}, nil
+}
+
+// Track 为一次执行创建进度文件并开始上报，返回进度文件路径和执行结束时调用的清理函数
+func (r *Reporter) Track(info *common.JobExecuteInfo) (string, func()) {
+	path := filepath.Join(r.dir, info.RunID)
+	if err := os.WriteFile(path, nil, 0666); err != nil {
+		r.logger.Warn("failed to create progress file",
+			zap.String("jobName", info.Job.Name),
+			zap.String("path", path),
+			zap.Error(err))
+		return "", func() {}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		r.poll(ctx, path, info)
+	}()
+
+	return path, func() {
+		cancel()
+		<-done
+		os.Remove(path)
+		if _, err := r.etcdClient.Delete(common.JobProgressDir + info.Job.Name); err != nil {
+			r.logger.Warn("failed to clear job progress",
+				zap.String("jobName", info.Job.Name),
+				zap.Error(err))
+		}
+	}
+}
+
+// poll 定期读取进度文件，最新一行变化时上报
+func (r *Reporter) poll(ctx context.Context, path string, info *common.JobExecuteInfo) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	var last string
+	var reportedAt time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		line, err := lastLine(path)
+		if err != nil || line == "" {
+			continue
+		}
+
+		// 进度没有变化时只在租约过半时续期
+		if line == last && time.Since(reportedAt) < progressTTL*time.Second/2 {
+			continue
+		}
+
+		percent, message := parseLine(line)
+		progress := &common.JobProgress{
+			JobName:   info.Job.Name,
+			RunID:     info.RunID,
+			Worker:    config.GlobalConfig.WorkerID,
+			Percent:   percent,
+			Message:   message,
+			StartedAt: info.RealTime.Unix(),
+			UpdatedAt: time.Now().Unix(),
+		}
+		data, err := json.Marshal(progress)
+		if err != nil {
+			continue
+		}
+		if err = r.etcdClient.PutWithLease(common.JobProgressDir+info.Job.Name, string(data), progressTTL); err != nil {
+			r.logger.Warn("failed to report job progress",
+				zap.String("jobName", info.Job.Name),
+				zap.Error(err))
+			continue
+		}
+
+		last = line
+		reportedAt = time.Now()
+	}
+}
+
+// lastLine 读取文件最后一个非空行
+func lastLine(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	stat, err := f.Stat()
+	if err != nil {
+		return "", err
+	}
+	if offset := stat.Size() - maxLineLength; offset > 0 {
+		if _, err = f.Seek(offset, io.SeekStart); err != nil {
+			return "", err
+		}
+	}
+
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return "", err
+	}
+
+	data = bytes.TrimRight(data, "\r\n\t ")
+	if i := bytes.LastIndexByte(data, '\n'); i >= 0 {
+		data = data[i+1:]
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// parseLine 解析进度行，第一个字段为0到100之间的数字时视为百分比
+func parseLine(line string) (*float64, string) {
+	fields := strings.SplitN(line, " ", 2)
+	value, err := strconv.ParseFloat(strings.TrimSuffix(fields[0], "%"), 64)
+	if err != nil || value < 0 || value > 100 {
+		return nil, line
+	}
+
+	message := ""
+	if len(fields) > 1 {
+		message = strings.TrimSpace(fields[1])
+	}
+	return &value, message
+}
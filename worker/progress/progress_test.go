@@ -0,0 +1,53 @@
+package progress
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLine(t *testing.T) {
+	percent, message := parseLine("42 copying table users")
+	require.NotNil(t, percent)
+	assert.Equal(t, 42.0, *percent)
+	assert.Equal(t, "copying table users", message)
+
+	percent, message = parseLine("87.5%")
+	require.NotNil(t, percent)
+	assert.Equal(t, 87.5, *percent)
+	assert.Empty(t, message)
+
+	// 不是百分比时整行作为说明
+	percent, message = parseLine("waiting for upstream")
+	assert.Nil(t, percent)
+	assert.Equal(t, "waiting for upstream", message)
+
+	percent, message = parseLine("2024 rows left")
+	assert.Nil(t, percent)
+	assert.Equal(t, "2024 rows left", message)
+}
+
+func TestLastLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "progress")
+
+	require.NoError(t, os.WriteFile(path, nil, 0666))
+	line, err := lastLine(path)
+	require.NoError(t, err)
+	assert.Empty(t, line)
+
+	require.NoError(t, os.WriteFile(path, []byte("10 start\n50 halfway\n\n"), 0666))
+	line, err = lastLine(path)
+	require.NoError(t, err)
+	assert.Equal(t, "50 halfway", line)
+
+	// 只读取文件末尾
+	long := strings.Repeat("x", maxLineLength*2) + "\n99 almost done\n"
+	require.NoError(t, os.WriteFile(path, []byte(long), 0666))
+	line, err = lastLine(path)
+	require.NoError(t, err)
+	assert.Equal(t, "99 almost done", line)
+}
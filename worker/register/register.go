@@ -13,16 +13,19 @@ import (
 	"github.com/fyerfyer/scheduler-refactor/common"
 	"github.com/fyerfyer/scheduler-refactor/config"
 	"github.com/fyerfyer/scheduler-refactor/pkg/etcd"
+	"github.com/fyerfyer/scheduler-refactor/pkg/version"
+	"github.com/fyerfyer/scheduler-refactor/worker/runstats"
 )
 
 // Register 注册器，负责worker节点的注册和心跳
 type Register struct {
-	logger      *zap.Logger        // 日志对象
-	etcdClient  *etcd.Client       // etcd客户端
-	workerInfo  common.WorkerInfo  // 工作节点信息
-	registryKey string             // 注册key
-	ctx         context.Context    // 上下文，用于控制退出
-	cancelFunc  context.CancelFunc // 取消函数
+	logger      *zap.Logger         // 日志对象
+	etcdClient  *etcd.Client        // etcd客户端
+	workerInfo  common.WorkerInfo   // 工作节点信息
+	registryKey string              // 注册key
+	stats       *runstats.Collector // 执行统计，为nil时心跳不携带统计
+	ctx         context.Context     // 上下文，用于控制退出
+	cancelFunc  context.CancelFunc  // 取消函数
 }
 
 // NewRegister 创建注册器
@@ -35,11 +38,17 @@ func NewRegister(logger *zap.Logger, etcdClient *etcd.Client) *Register {
 		hostname = "unknown"
 	}
 
-	// 创建工作节点信息
+	// 创建工作节点信息，心跳中携带构建信息便于确认集群升级进度
+	buildInfo := version.Get()
 	workerInfo := common.WorkerInfo{
-		IP:       config.GlobalConfig.WorkerID,
-		Hostname: hostname,
-		LastSeen: time.Now().Unix(),
+		IP:         config.GlobalConfig.WorkerID,
+		Hostname:   hostname,
+		LastSeen:   time.Now().Unix(),
+		Version:    buildInfo.Version,
+		Commit:     buildInfo.Commit,
+		BuildDate:  buildInfo.BuildDate,
+		Zone:       config.GlobalConfig.Zone,
+		Generation: config.GlobalConfig.Generation,
 	}
 
 	// 创建注册key
@@ -55,6 +64,16 @@ func NewRegister(logger *zap.Logger, etcdClient *etcd.Client) *Register {
 	}
 }
 
+// SetStats 设置执行统计，心跳中携带最新的统计
+func (r *Register) SetStats(stats *runstats.Collector) {
+	r.stats = stats
+}
+
+// SetExecutors 设置本worker的执行器插件，心跳中携带插件声明供master校验任务配置
+func (r *Register) SetExecutors(manifests []*common.ExecutorManifest) {
+	r.workerInfo.Executors = manifests
+}
+
 // Start 开始注册并定期发送心跳
 func (r *Register) Start() error {
 	r.logger.Info("worker register starting...",
@@ -80,6 +99,16 @@ func (r *Register) Stop() {
 	r.logger.Info("worker register stopped")
 }
 
+// Deregister 停止心跳并删除注册信息，master立即感知worker下线，不必等租约过期
+func (r *Register) Deregister() error {
+	r.Stop()
+	if _, err := r.etcdClient.Delete(r.registryKey); err != nil {
+		return err
+	}
+	r.logger.Info("worker deregistered", zap.String("registryKey", r.registryKey))
+	return nil
+}
+
 // doRegister 执行注册
 func (r *Register) doRegister() error {
 	// 更新节点信息
@@ -118,6 +147,11 @@ func (r *Register) updateWorkerInfo() {
 
 	// 这里可以添加更多节点状态收集逻辑，例如CPU和内存使用率
 	r.collectSystemStats()
+
+	// 携带执行统计，master不依赖日志存储也能展示各worker的执行情况
+	if r.stats != nil {
+		r.workerInfo.Stats = r.stats.Snapshot()
+	}
 }
 
 // collectSystemStats 收集系统状态信息
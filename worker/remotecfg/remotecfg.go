@@ -0,0 +1,224 @@
+package remotecfg
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"go.etcd.io/etcd/client/v3"
+	"go.uber.org/zap"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+	"github.com/fyerfyer/scheduler-refactor/config"
+	"github.com/fyerfyer/scheduler-refactor/pkg/etcd"
+)
+
+// Watcher 监听master通过etcd下发的worker配置，并在变化时通知各组件热更新
+type Watcher struct {
+	etcdClient *etcd.Client                            // etcd客户端
+	logger     *zap.Logger                             // 日志对象
+	workerID   string                                  // 当前worker ID
+	global     *common.WorkerSettings                  // 全局配置
+	local      *common.WorkerSettings                  // 当前worker的专属配置
+	handlers   []func(settings *common.WorkerSettings) // 配置变化回调
+	lock       sync.Mutex                              // 保护配置和回调
+	revision   int64                                   // 已加载的版本，不晚于该版本的事件忽略
+	ctx        context.Context                         // 上下文，用于控制退出
+	cancelFunc context.CancelFunc                      // 取消函数
+}
+
+// NewWatcher 创建配置监听器
+func NewWatcher(logger *zap.Logger, etcdClient *etcd.Client) *Watcher {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &Watcher{
+		etcdClient: etcdClient,
+		logger:     logger,
+		workerID:   config.GlobalConfig.WorkerID,
+		ctx:        ctx,
+		cancelFunc: cancel,
+	}
+}
+
+// OnChange 注册配置变化回调，回调收到的是全局配置与专属配置合并后的结果
+func (w *Watcher) OnChange(handler func(settings *common.WorkerSettings)) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	w.handlers = append(w.handlers, handler)
+}
+
+// Start 加载当前配置并开始监听变化
+func (w *Watcher) Start() error {
+	// 先监听再加载，加载版本之前的事件在处理时忽略，避免遗漏加载和监听之间的修改
+	watchChan := w.etcdClient.WatchWithPrefix(w.ctx, common.WorkerConfigDir)
+	if _, err := w.load(); err != nil {
+		w.logger.Error("failed to load worker settings", zap.Error(err))
+		return err
+	}
+
+	w.notify()
+
+	go w.watchLoop(watchChan)
+
+	w.logger.Info("worker settings watcher started")
+	return nil
+}
+
+// Stop 停止监听
+func (w *Watcher) Stop() {
+	w.cancelFunc()
+	w.logger.Info("worker settings watcher stopped")
+}
+
+// Current 获取当前生效的远程配置
+func (w *Watcher) Current() *common.WorkerSettings {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	return w.merged()
+}
+
+// watchLoop 监听配置目录变化，监听中断时重新监听并重新加载
+func (w *Watcher) watchLoop(watchChan clientv3.WatchChan) {
+	for {
+		select {
+		case <-w.ctx.Done():
+			return
+		case watchResp, ok := <-watchChan:
+			if !ok || watchResp.Err() != nil {
+				w.logger.Warn("worker settings watch interrupted, resyncing", zap.Bool("closed", !ok))
+				watchChan = w.resync()
+				continue
+			}
+
+			changed := false
+
+			w.lock.Lock()
+			for _, event := range watchResp.Events {
+				if event.Kv.ModRevision <= w.revision {
+					continue
+				}
+
+				key := string(event.Kv.Key)
+				switch event.Type {
+				case clientv3.EventTypePut:
+					changed = w.applyKV(key, event.Kv.Value) || changed
+				case clientv3.EventTypeDelete:
+					changed = w.removeKey(key) || changed
+				}
+			}
+			w.lock.Unlock()
+
+			if changed {
+				w.notify()
+			}
+		}
+	}
+}
+
+// resync 重新监听并重新加载配置，配置有变化时通知回调，加载失败时每秒重试直到成功或停止
+func (w *Watcher) resync() clientv3.WatchChan {
+	watchChan := w.etcdClient.WatchWithPrefix(w.ctx, common.WorkerConfigDir)
+
+	for {
+		changed, err := w.load()
+		if err == nil {
+			if changed {
+				w.notify()
+			}
+			return watchChan
+		}
+
+		w.logger.Error("failed to resync worker settings, retrying", zap.Error(err))
+		select {
+		case <-w.ctx.Done():
+			return watchChan
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// load 加载全部配置替换当前配置，返回合并后的配置是否变化
+func (w *Watcher) load() (bool, error) {
+	resp, err := w.etcdClient.GetWithPrefix(common.WorkerConfigDir)
+	if err != nil {
+		return false, err
+	}
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	previous := w.merged()
+	w.global, w.local = nil, nil
+	for _, kv := range resp.Kvs {
+		w.applyKV(string(kv.Key), kv.Value)
+	}
+	w.revision = resp.Header.Revision
+
+	return !reflect.DeepEqual(previous, w.merged()), nil
+}
+
+// applyKV 解析并保存配置，返回是否与当前worker相关
+func (w *Watcher) applyKV(key string, value []byte) bool {
+	target := strings.TrimPrefix(key, common.WorkerConfigDir)
+	if target != common.WorkerConfigGlobal && target != w.workerID {
+		return false
+	}
+
+	settings := &common.WorkerSettings{}
+	if err := json.Unmarshal(value, settings); err != nil {
+		w.logger.Error("failed to unmarshal worker settings",
+			zap.String("key", key),
+			zap.Error(err))
+		return false
+	}
+
+	if target == common.WorkerConfigGlobal {
+		w.global = settings
+	} else {
+		w.local = settings
+	}
+
+	return true
+}
+
+// removeKey 删除配置，返回是否与当前worker相关
+func (w *Watcher) removeKey(key string) bool {
+	switch strings.TrimPrefix(key, common.WorkerConfigDir) {
+	case common.WorkerConfigGlobal:
+		w.global = nil
+	case w.workerID:
+		w.local = nil
+	default:
+		return false
+	}
+
+	return true
+}
+
+// merged 合并全局配置和专属配置，专属配置优先
+func (w *Watcher) merged() *common.WorkerSettings {
+	settings := &common.WorkerSettings{}
+	settings.Merge(w.global)
+	settings.Merge(w.local)
+	return settings
+}
+
+// notify 通知所有回调
+func (w *Watcher) notify() {
+	w.lock.Lock()
+	settings := w.merged()
+	handlers := make([]func(settings *common.WorkerSettings), len(w.handlers))
+	copy(handlers, w.handlers)
+	w.lock.Unlock()
+
+	for _, handler := range handlers {
+		handler(settings)
+	}
+
+	w.logger.Info("worker settings applied", zap.Any("settings", settings))
+}
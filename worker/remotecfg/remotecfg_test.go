@@ -0,0 +1,50 @@
+package remotecfg
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+)
+
+func TestWatcher_ApplyAndMerge(t *testing.T) {
+	w := &Watcher{
+		logger:   zaptest.NewLogger(t),
+		workerID: "worker-1",
+	}
+
+	assert.True(t, w.applyKV(common.WorkerConfigDir+common.WorkerConfigGlobal,
+		[]byte(`{"logBatchSize": 200, "maxConcurrentJobs": 4}`)))
+	assert.True(t, w.applyKV(common.WorkerConfigDir+"worker-1", []byte(`{"maxConcurrentJobs": 2}`)))
+	assert.False(t, w.applyKV(common.WorkerConfigDir+"worker-2", []byte(`{"logBatchSize": 1}`)),
+		"Settings for other workers should be ignored")
+
+	settings := w.Current()
+	assert.Equal(t, 200, *settings.LogBatchSize, "Global settings should apply")
+	assert.Equal(t, 2, *settings.MaxConcurrentJobs, "Worker settings should override global")
+	assert.Nil(t, settings.LogRetentionDays)
+
+	assert.True(t, w.removeKey(common.WorkerConfigDir+"worker-1"))
+	assert.Equal(t, 4, *w.Current().MaxConcurrentJobs, "Should fall back to global settings")
+}
+
+func TestWatcher_Notify(t *testing.T) {
+	w := &Watcher{
+		logger:   zaptest.NewLogger(t),
+		workerID: "worker-1",
+	}
+
+	var received *common.WorkerSettings
+	w.OnChange(func(settings *common.WorkerSettings) {
+		received = settings
+	})
+
+	w.applyKV(common.WorkerConfigDir+common.WorkerConfigGlobal, []byte(`{"logRetentionDays": 14}`))
+	w.notify()
+
+	if assert.NotNil(t, received) {
+		assert.Equal(t, 14, *received.LogRetentionDays)
+	}
+}
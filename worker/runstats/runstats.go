@@ -0,0 +1,286 @@
+package runstats
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+	"github.com/fyerfyer/scheduler-refactor/config"
+	"github.com/fyerfyer/scheduler-refactor/pkg/etcd"
+)
+
+// persistInterval 统计写入etcd的间隔，重启时最多丢失这段时间内的计数
+const persistInterval = 30 * time.Second
+
+// errNotRestored 上次保存的统计尚未恢复，此时保存会用从零开始的计数覆盖已持久化的统计
+var errNotRestored = errors.New("run stats not restored yet")
+
+// JobCounts 单个任务在当前worker上的执行计数
+type JobCounts struct {
+	Executed  int64 `json:"executed"`  // 执行次数，不含被跳过的执行
+	Succeeded int64 `json:"succeeded"` // 成功次数
+	Failed    int64 `json:"failed"`    // 失败次数（失败、超时、被终止）
+	Quiet     int64 `json:"quiet"`     // 静默失败次数，只在worker的计数中与Failed分开统计
+	Skipped   int64 `json:"skipped"`   // 执行前被跳过的次数
+}
+
+// record 按执行状态计数，quiet为true时失败计入Quiet而不是Failed
+func (j *JobCounts) record(status common.RunStatus, quiet bool) {
+	if status == common.RunStatusSkipped {
+		j.Skipped++
+		return
+	}
+	j.Executed++
+	if status == common.RunStatusSuccess {
+		j.Succeeded++
+	} else if status.IsFailure() && quiet {
+		j.Quiet++
+	} else if status.IsFailure() {
+		j.Failed++
+	}
+}
+
+// add 累加另一组计数
+func (j *JobCounts) add(other JobCounts) {
+	j.Executed += other.Executed
+	j.Succeeded += other.Succeeded
+	j.Failed += other.Failed
+	j.Quiet += other.Quiet
+	j.Skipped += other.Skipped
+}
+
+// state 持久化到etcd的统计
+type state struct {
+	Since         int64                 `json:"since"`         // 开始统计的时间
+	Worker        JobCounts             `json:"worker"`        // worker的执行计数
+	TotalDuration int64                 `json:"totalDuration"` // 执行时长之和(秒)
+	Jobs          map[string]*JobCounts `json:"jobs"`          // 各任务的执行计数
+}
+
+// Collector worker的执行统计。配置了etcd时定期持久化，重启后从上次保存的值继续累计
+type Collector struct {
+	etcdClient *etcd.Client       // etcd客户端，为nil时只保存在内存中
+	logger     *zap.Logger        // 日志对象
+	key        string             // 统计在etcd中的key
+	lock       sync.Mutex         // 保护state、dirty和restored
+	state      state              // 当前统计
+	dirty      bool               // 上次保存后是否有新的计数
+	restored   bool               // 是否已恢复上次保存的统计，恢复前不写入etcd
+	ctx        context.Context    // 上下文，用于控制退出
+	cancelFunc context.CancelFunc // 取消函数
+}
+
+// NewCollector 创建只保存在内存中的执行统计
+func NewCollector() *Collector {
+	return NewPersistentCollector(zap.NewNop(), nil)
+}
+
+// NewPersistentCollector 创建持久化到etcd的执行统计，key为当前worker ID
+func NewPersistentCollector(logger *zap.Logger, etcdClient *etcd.Client) *Collector {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	key := common.WorkerRunStatsDir
+	if config.GlobalConfig != nil {
+		key += config.GlobalConfig.WorkerID
+	}
+
+	return &Collector{
+		etcdClient: etcdClient,
+		logger:     logger,
+		key:        key,
+		state: state{
+			Since: time.Now().Unix(),
+			Jobs:  make(map[string]*JobCounts),
+		},
+		ctx:        ctx,
+		cancelFunc: cancel,
+	}
+}
+
+// Start 恢复上次保存的统计并开始定期保存，恢复失败时在定期保存前重试，恢复成功前不写入etcd
+func (c *Collector) Start() error {
+	if c.etcdClient == nil {
+		return nil
+	}
+
+	if err := c.restore(); err != nil {
+		c.logger.Warn("failed to restore run stats, will retry before persisting",
+			zap.String("key", c.key),
+			zap.Error(err))
+	}
+
+	go c.persistLoop()
+	return nil
+}
+
+// Stop 停止定期保存并写入最新的统计
+func (c *Collector) Stop() {
+	c.cancelFunc()
+	if c.etcdClient == nil {
+		return
+	}
+	if err := c.save(); errors.Is(err, errNotRestored) {
+		c.logger.Warn("run stats were never restored, not persisting to keep the saved stats", zap.String("key", c.key))
+	} else if err != nil {
+		c.logger.Error("failed to persist run stats", zap.Error(err))
+	}
+}
+
+// restore 从etcd加载上次保存的统计，与启动后已有的计数合并。只有读取失败时返回错误，
+// 无法解析的统计被丢弃，之后的保存会覆盖它
+func (c *Collector) restore() error {
+	resp, err := c.etcdClient.Get(c.key)
+	if err != nil {
+		return err
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.restored = true
+	if resp.Count == 0 {
+		return nil
+	}
+
+	saved := state{}
+	if err = json.Unmarshal(resp.Kvs[0].Value, &saved); err != nil {
+		c.logger.Warn("failed to unmarshal saved run stats, discarding them",
+			zap.String("key", c.key),
+			zap.Error(err))
+		return nil
+	}
+
+	if saved.Since > 0 {
+		c.state.Since = min(c.state.Since, saved.Since)
+	}
+	c.state.TotalDuration += saved.TotalDuration
+	c.state.Worker.add(saved.Worker)
+	for jobName, counts := range saved.Jobs {
+		if counts == nil {
+			continue
+		}
+		if _, exists := c.state.Jobs[jobName]; !exists {
+			c.state.Jobs[jobName] = &JobCounts{}
+		}
+		c.state.Jobs[jobName].add(*counts)
+	}
+
+	c.logger.Info("run stats restored",
+		zap.Int64("executed", c.state.Worker.Executed),
+		zap.Int("jobs", len(c.state.Jobs)))
+	return nil
+}
+
+// persistLoop 定期保存有变化的统计
+func (c *Collector) persistLoop() {
+	ticker := time.NewTicker(persistInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-ticker.C:
+			if !c.isRestored() {
+				if err := c.restore(); err != nil {
+					c.logger.Warn("failed to restore run stats", zap.String("key", c.key), zap.Error(err))
+					continue
+				}
+			}
+			if err := c.save(); err != nil {
+				c.logger.Warn("failed to persist run stats", zap.Error(err))
+			}
+		}
+	}
+}
+
+// isRestored 判断是否已恢复上次保存的统计
+func (c *Collector) isRestored() bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.restored
+}
+
+// save 有新的计数时写入etcd，尚未恢复上次保存的统计时返回errNotRestored
+func (c *Collector) save() error {
+	c.lock.Lock()
+	if !c.restored {
+		c.lock.Unlock()
+		return errNotRestored
+	}
+	if !c.dirty {
+		c.lock.Unlock()
+		return nil
+	}
+	data, err := json.Marshal(&c.state)
+	c.dirty = false
+	c.lock.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if _, err = c.etcdClient.Put(c.key, string(data)); err != nil {
+		// 写入失败时下次重试
+		c.lock.Lock()
+		c.dirty = true
+		c.lock.Unlock()
+		return err
+	}
+	return nil
+}
+
+// Record 记录一次执行的结果
+func (c *Collector) Record(jobLog *common.JobLog) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	// 静默失败不计入worker的失败次数，任务自己的计数照常记录失败
+	c.state.Worker.record(jobLog.Status, jobLog.QuietFailures)
+	counts, exists := c.state.Jobs[jobLog.JobName]
+	if !exists {
+		counts = &JobCounts{}
+		c.state.Jobs[jobLog.JobName] = counts
+	}
+	counts.record(jobLog.Status, false)
+
+	if duration := jobLog.EndTime - jobLog.StartTime; jobLog.Status != common.RunStatusSkipped && duration > 0 {
+		c.state.TotalDuration += duration
+	}
+	c.dirty = true
+}
+
+// Snapshot 获取worker的执行统计
+func (c *Collector) Snapshot() *common.WorkerRunStats {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	worker := c.state.Worker
+	stats := &common.WorkerRunStats{
+		Since:       c.state.Since,
+		Executed:    worker.Executed,
+		Succeeded:   worker.Succeeded,
+		Failed:      worker.Failed,
+		QuietFailed: worker.Quiet,
+		Skipped:     worker.Skipped,
+	}
+	if worker.Executed > 0 {
+		stats.AvgDuration = float64(c.state.TotalDuration) / float64(worker.Executed)
+	}
+	return stats
+}
+
+// Job 获取任务在当前worker上的执行计数
+func (c *Collector) Job(jobName string) JobCounts {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if counts, exists := c.state.Jobs[jobName]; exists {
+		return *counts
+	}
+	return JobCounts{}
+}
@@ -0,0 +1,78 @@
+package runstats
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+	"github.com/fyerfyer/scheduler-refactor/config"
+	"github.com/fyerfyer/scheduler-refactor/pkg/etcd"
+)
+
+func TestCollector(t *testing.T) {
+	collector := NewCollector()
+	assert.Zero(t, collector.Snapshot().AvgDuration, "Empty stats should not divide by zero")
+
+	collector.Record(&common.JobLog{Status: common.RunStatusSuccess, StartTime: 100, EndTime: 102})
+	collector.Record(&common.JobLog{Status: common.RunStatusTimeout, StartTime: 100, EndTime: 104})
+	collector.Record(&common.JobLog{Status: common.RunStatusSkipped, SkipReason: common.SkipReasonPrecondition})
+
+	stats := collector.Snapshot()
+	assert.Equal(t, int64(2), stats.Executed)
+	assert.Equal(t, int64(1), stats.Succeeded)
+	assert.Equal(t, int64(1), stats.Failed)
+	assert.Equal(t, int64(1), stats.Skipped)
+	assert.Equal(t, 3.0, stats.AvgDuration, "Skipped runs should not count towards the average")
+	assert.NotZero(t, stats.Since)
+
+	// 静默失败不计入worker的失败次数，但仍计入任务自己的失败次数
+	collector.Record(&common.JobLog{JobName: "explore", Status: common.RunStatusFailed, QuietFailures: true, StartTime: 100, EndTime: 103})
+	stats = collector.Snapshot()
+	assert.Equal(t, int64(3), stats.Executed)
+	assert.Equal(t, int64(1), stats.Failed)
+	assert.Equal(t, int64(1), stats.QuietFailed)
+	assert.Equal(t, int64(1), collector.Job("explore").Failed)
+}
+
+func TestPersistentCollector(t *testing.T) {
+	config.GlobalConfig = &config.Config{
+		EtcdEndpoints:   []string{"localhost:2379"},
+		EtcdDialTimeout: 5000,
+		WorkerID:        "test-runstats-worker",
+	}
+	etcdClient, err := etcd.NewClient()
+	require.NoError(t, err, "Failed to create etcd client")
+	defer etcdClient.Close()
+	defer etcdClient.Delete(common.WorkerRunStatsDir + "test-runstats-worker")
+
+	collector := NewPersistentCollector(zap.NewNop(), etcdClient)
+	require.NoError(t, collector.Start())
+	collector.Record(&common.JobLog{JobName: "job-a", Status: common.RunStatusSuccess, StartTime: 100, EndTime: 104})
+	collector.Record(&common.JobLog{JobName: "job-a", Status: common.RunStatusFailed, StartTime: 100, EndTime: 102})
+	collector.Stop()
+
+	// 重启后从保存的值继续累计
+	restarted := NewPersistentCollector(zap.NewNop(), etcdClient)
+	require.NoError(t, restarted.Start())
+	defer restarted.Stop()
+	restarted.Record(&common.JobLog{JobName: "job-b", Status: common.RunStatusSuccess, StartTime: 100, EndTime: 103})
+
+	stats := restarted.Snapshot()
+	assert.Equal(t, int64(3), stats.Executed)
+	assert.Equal(t, int64(1), stats.Failed)
+	assert.Equal(t, 3.0, stats.AvgDuration)
+	assert.Equal(t, int64(2), restarted.Job("job-a").Executed)
+	assert.Equal(t, int64(1), restarted.Job("job-b").Succeeded)
+}
+
+func TestSaveBeforeRestore(t *testing.T) {
+	collector := NewCollector()
+	collector.Record(&common.JobLog{JobName: "job-a", Status: common.RunStatusSuccess, StartTime: 100, EndTime: 101})
+
+	// 恢复失败时不能用从零开始的计数覆盖已保存的统计
+	assert.ErrorIs(t, collector.save(), errNotRestored)
+	assert.True(t, collector.dirty, "Unsaved counts should be kept for the next attempt")
+}
@@ -0,0 +1,111 @@
+package schedsource
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+)
+
+// maxResponseBytes 来源响应的最大字节数
+const maxResponseBytes = 64 * 1024
+
+// Provider 查询任务在after之后的下一次执行时间，没有安排时返回零值
+type Provider interface {
+	Next(ctx context.Context, job *common.Job, after time.Time) (time.Time, error)
+}
+
+// providers 内置的来源类型
+var providers = map[string]Provider{
+	common.ScheduleSourceHTTP:    httpProvider{client: &http.Client{}},
+	common.ScheduleSourceCommand: commandProvider{},
+}
+
+// httpProvider 以GET target?job=<任务名>&after=<unix秒>查询，响应为{"next": unix秒}，0表示没有安排
+type httpProvider struct {
+	client *http.Client
+}
+
+// httpResponse http来源的响应
+type httpResponse struct {
+	Next int64 `json:"next"`
+}
+
+// Next 实现Provider接口
+func (p httpProvider) Next(ctx context.Context, job *common.Job, after time.Time) (time.Time, error) {
+	u, err := url.Parse(job.Schedule.Target)
+	if err != nil {
+		return time.Time{}, err
+	}
+	query := u.Query()
+	query.Set("job", job.Name)
+	query.Set("after", strconv.FormatInt(after.Unix(), 10))
+	u.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return time.Time{}, err
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return time.Time{}, fmt.Errorf("schedule source returned status %d", resp.StatusCode)
+	}
+
+	body := &httpResponse{}
+	if err = json.NewDecoder(io.LimitReader(resp.Body, maxResponseBytes)).Decode(body); err != nil {
+		return time.Time{}, fmt.Errorf("failed to decode schedule source response: %v", err)
+	}
+	if body.Next <= 0 {
+		return time.Time{}, nil
+	}
+	return time.Unix(body.Next, 0), nil
+}
+
+// commandProvider 在worker上执行target，通过环境变量CRON_JOB_NAME和CRON_SCHEDULE_AFTER传入参数，
+// 标准输出为unix秒或RFC3339时间，为空表示没有安排
+type commandProvider struct{}
+
+// Next 实现Provider接口
+func (commandProvider) Next(ctx context.Context, job *common.Job, after time.Time) (time.Time, error) {
+	cmd := exec.CommandContext(ctx, "sh", "-c", job.Schedule.Target)
+	cmd.Env = append(os.Environ(),
+		"CRON_JOB_NAME="+job.Name,
+		"CRON_SCHEDULE_AFTER="+strconv.FormatInt(after.Unix(), 10))
+	output, err := cmd.Output()
+	if err != nil {
+		return time.Time{}, fmt.Errorf("schedule command failed: %v", err)
+	}
+	return parseTime(strings.TrimSpace(string(output)))
+}
+
+// parseTime 解析unix秒或RFC3339时间，空串表示没有安排
+func parseTime(text string) (time.Time, error) {
+	if text == "" {
+		return time.Time{}, nil
+	}
+	if unix, err := strconv.ParseInt(text, 10, 64); err == nil {
+		if unix <= 0 {
+			return time.Time{}, nil
+		}
+		return time.Unix(unix, 0), nil
+	}
+	t, err := time.Parse(time.RFC3339, text)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("schedule source returned invalid time %q", text)
+	}
+	return t, nil
+}
@@ -0,0 +1,224 @@
+package schedsource
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"go.uber.org/zap"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+)
+
+// Manager 管理设置了外部调度来源的任务，每个任务一个Source在后台定期查询，
+// 下次执行时间变化时通过Updates通知调度器
+type Manager struct {
+	logger     *zap.Logger        // 日志对象
+	sources    map[string]*Source // 任务名 -> 调度来源
+	lock       sync.Mutex         // 保护sources
+	updates    chan string        // 下次执行时间变化的任务名
+	ctx        context.Context    // 上下文，用于控制退出
+	cancelFunc context.CancelFunc // 取消函数
+}
+
+// NewManager 创建外部调度来源管理器
+func NewManager(logger *zap.Logger) *Manager {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Manager{
+		logger:     logger,
+		sources:    make(map[string]*Source),
+		updates:    make(chan string),
+		ctx:        ctx,
+		cancelFunc: cancel,
+	}
+}
+
+// Schedule 为任务创建调度来源并开始查询，替换任务原有的来源。fallback为任务的cron表达式，
+// 来源不可用且没有可用的缓存时按任务配置使用。来源类型不支持时直接返回fallback
+func (m *Manager) Schedule(job *common.Job, fallback cron.Schedule) cron.Schedule {
+	provider, ok := providers[job.Schedule.Type]
+	if !ok {
+		m.logger.Warn("unsupported schedule source, using cron expression",
+			zap.String("jobName", job.Name),
+			zap.String("type", job.Schedule.Type))
+		m.Remove(job.Name)
+		return fallback
+	}
+	if !job.Schedule.FallbackToCron() {
+		fallback = nil
+	}
+
+	source := newSource(m.ctx, job, provider, fallback, m.logger)
+	source.notify = func() {
+		select {
+		case m.updates <- job.Name:
+		case <-source.ctx.Done():
+		}
+	}
+
+	m.lock.Lock()
+	if old, exists := m.sources[job.Name]; exists {
+		old.stop()
+	}
+	m.sources[job.Name] = source
+	m.lock.Unlock()
+
+	go source.run()
+	return source
+}
+
+// Remove 停止查询任务的调度来源，任务删除、禁用或不再使用外部来源时调用
+func (m *Manager) Remove(jobName string) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if source, exists := m.sources[jobName]; exists {
+		source.stop()
+		delete(m.sources, jobName)
+	}
+}
+
+// Updates 下次执行时间变化的任务名，调度器收到后重新计算该任务的下次执行时间
+func (m *Manager) Updates() <-chan string {
+	return m.updates
+}
+
+// Stop 停止所有调度来源
+func (m *Manager) Stop() {
+	m.cancelFunc()
+}
+
+// Source 一个任务的外部调度来源，实现cron.Schedule。缓存来源返回的下次执行时间，
+// 该时间被触发后重新查询；查询失败时继续使用仍未到期的缓存，没有可用缓存时使用后备的cron表达式
+type Source struct {
+	job      *common.Job        // 任务定义
+	provider Provider           // 来源
+	fallback cron.Schedule      // 后备的cron表达式，为nil时来源不可用期间不触发
+	logger   *zap.Logger        // 日志对象
+	notify   func()             // 下次执行时间变化时调用
+	lock     sync.Mutex         // 保护以下状态
+	after    time.Time          // 已调度到的时间，查询该时间之后的下一次执行
+	next     time.Time          // 来源返回的下次执行时间，零值表示没有安排或尚未查询
+	fetched  bool               // 是否已查询到after之后的安排
+	failing  bool               // 最近一次查询是否失败
+	wake     chan struct{}      // 需要立即重新查询的通知
+	ctx      context.Context    // 上下文，任务的来源被替换或删除时取消
+	cancel   context.CancelFunc // 取消函数
+}
+
+// newSource 创建调度来源，从当前时间之后开始查询
+func newSource(parent context.Context, job *common.Job, provider Provider, fallback cron.Schedule, logger *zap.Logger) *Source {
+	ctx, cancel := context.WithCancel(parent)
+	return &Source{
+		job:      job,
+		provider: provider,
+		fallback: fallback,
+		logger:   logger,
+		notify:   func() {},
+		after:    time.Now(),
+		wake:     make(chan struct{}, 1),
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+}
+
+// Next 实现cron.Schedule接口，返回t之后的下一次执行时间，尚不确定时返回零值。
+// 调度器在触发后以当前时间调用，缓存的时间被触发后立即重新查询
+func (s *Source) Next(t time.Time) time.Time {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if t.After(s.after) {
+		s.after = t
+		if !s.next.IsZero() && !s.next.After(t) {
+			s.next = time.Time{}
+			s.fetched = false
+			select {
+			case s.wake <- struct{}{}:
+			default:
+			}
+		}
+	}
+
+	if s.next.After(t) {
+		return s.next
+	}
+	if s.failing && s.fallback != nil {
+		return s.fallback.Next(t)
+	}
+	return time.Time{}
+}
+
+// run 定期查询来源，被触发后立即重新查询
+func (s *Source) run() {
+	interval := s.job.Schedule.RefreshInterval()
+	for {
+		if s.refresh() {
+			s.notify()
+		}
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-s.wake:
+		case <-time.After(interval):
+		}
+	}
+}
+
+// refresh 查询一次来源，返回下次执行时间是否变化
+func (s *Source) refresh() bool {
+	s.lock.Lock()
+	after := s.after
+	s.lock.Unlock()
+
+	ctx, cancel := context.WithTimeout(s.ctx, s.job.Schedule.QueryTimeout())
+	next, err := s.provider.Next(ctx, s.job, after)
+	cancel()
+	if s.ctx.Err() != nil {
+		return false
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if err != nil {
+		s.logger.Warn("failed to query schedule source",
+			zap.String("jobName", s.job.Name),
+			zap.String("type", s.job.Schedule.Type),
+			zap.Bool("fallbackToCron", s.fallback != nil),
+			zap.Error(err))
+		changed := !s.failing
+		s.failing = true
+		return changed
+	}
+
+	// 查询期间已经调度到更晚的时间，结果作废，重新查询
+	if s.after.After(after) && !next.After(s.after) {
+		select {
+		case s.wake <- struct{}{}:
+		default:
+		}
+		return false
+	}
+	// 来源返回的时间不晚于已调度到的时间视为没有安排
+	if !next.After(s.after) {
+		next = time.Time{}
+	}
+
+	changed := s.failing || !s.fetched || !next.Equal(s.next)
+	if changed {
+		s.logger.Info("schedule source updated",
+			zap.String("jobName", s.job.Name),
+			zap.Time("next", next))
+	}
+	s.next = next
+	s.fetched = true
+	s.failing = false
+	return changed
+}
+
+// stop 停止查询
+func (s *Source) stop() {
+	s.cancel()
+}
@@ -0,0 +1,137 @@
+package schedsource
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+)
+
+// fakeProvider 返回预设结果的测试来源
+type fakeProvider struct {
+	next  time.Time
+	err   error
+	after time.Time // 最近一次查询的after
+}
+
+func (p *fakeProvider) Next(ctx context.Context, job *common.Job, after time.Time) (time.Time, error) {
+	p.after = after
+	return p.next, p.err
+}
+
+// newTestSource 创建不在后台查询的调度来源
+func newTestSource(provider Provider, fallback cron.Schedule, now time.Time) *Source {
+	job := &common.Job{Name: "report", Schedule: &common.ScheduleSource{Type: common.ScheduleSourceHTTP, Target: "http://etl/next"}}
+	source := newSource(context.Background(), job, provider, fallback, zap.NewNop())
+	source.after = now
+	return source
+}
+
+func TestSource_Next(t *testing.T) {
+	now := time.Date(2026, 1, 1, 8, 0, 0, 0, time.UTC)
+	provider := &fakeProvider{next: now.Add(30 * time.Minute)}
+	source := newTestSource(provider, nil, now)
+
+	assert.True(t, source.Next(now).IsZero(), "Next run is unknown before the first query")
+
+	assert.True(t, source.refresh(), "First answer should notify the scheduler")
+	assert.Equal(t, now.Add(30*time.Minute), source.Next(now))
+	assert.False(t, source.refresh(), "Unchanged answer should not notify")
+
+	// 触发后缓存失效，立即重新查询fired之后的安排
+	fired := now.Add(30 * time.Minute)
+	assert.True(t, source.Next(fired).IsZero())
+	select {
+	case <-source.wake:
+	default:
+		t.Fatal("Firing the cached time should wake the refresher")
+	}
+	provider.next = fired.Add(24 * time.Hour)
+	assert.True(t, source.refresh())
+	assert.Equal(t, fired, provider.after)
+	assert.Equal(t, fired.Add(24*time.Hour), source.Next(fired))
+}
+
+func TestSource_NoRunScheduled(t *testing.T) {
+	now := time.Date(2026, 1, 1, 8, 0, 0, 0, time.UTC)
+	provider := &fakeProvider{next: now.Add(-time.Minute)}
+	source := newTestSource(provider, nil, now)
+
+	assert.True(t, source.refresh())
+	assert.True(t, source.Next(now.Add(time.Second)).IsZero(), "Times in the past mean nothing is scheduled")
+	select {
+	case <-source.wake:
+		t.Fatal("Nothing scheduled should not trigger an immediate re-query")
+	default:
+	}
+}
+
+func TestSource_Fallback(t *testing.T) {
+	now := time.Date(2026, 1, 1, 8, 0, 0, 0, time.UTC)
+	fallback, err := cron.NewParser(cron.Second | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow).Parse("0 0 9 * * *")
+	require.NoError(t, err)
+
+	provider := &fakeProvider{next: now.Add(10 * time.Minute)}
+	source := newTestSource(provider, fallback, now)
+	require.True(t, source.refresh())
+
+	// 来源不可用时继续使用未到期的缓存
+	provider.err = errors.New("connection refused")
+	assert.True(t, source.refresh(), "Starting to fail should notify the scheduler")
+	assert.Equal(t, now.Add(10*time.Minute), source.Next(now))
+
+	// 缓存被触发后使用后备的cron表达式
+	assert.Equal(t, time.Date(2026, 1, 1, 9, 0, 0, 0, time.UTC), source.Next(now.Add(10*time.Minute)))
+
+	// 没有后备时不触发
+	source.fallback = nil
+	assert.True(t, source.Next(now.Add(11*time.Minute)).IsZero())
+}
+
+func TestHTTPProvider(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "report", r.URL.Query().Get("job"))
+		assert.Equal(t, "1767254400", r.URL.Query().Get("after"))
+		fmt.Fprint(w, `{"next": 1767256200}`)
+	}))
+	defer server.Close()
+
+	job := &common.Job{Name: "report", Schedule: &common.ScheduleSource{Type: common.ScheduleSourceHTTP, Target: server.URL + "/next?env=prod"}}
+	next, err := providers[common.ScheduleSourceHTTP].Next(context.Background(), job, time.Unix(1767254400, 0))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1767256200), next.Unix())
+}
+
+func TestCommandProvider(t *testing.T) {
+	job := &common.Job{Name: "report", Schedule: &common.ScheduleSource{Type: common.ScheduleSourceCommand, Target: `echo $((CRON_SCHEDULE_AFTER + 60))`}}
+	next, err := providers[common.ScheduleSourceCommand].Next(context.Background(), job, time.Unix(1000, 0))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1060), next.Unix())
+
+	job.Schedule.Target = "exit 1"
+	_, err = providers[common.ScheduleSourceCommand].Next(context.Background(), job, time.Unix(1000, 0))
+	assert.Error(t, err)
+}
+
+func TestParseTime(t *testing.T) {
+	next, err := parseTime("")
+	require.NoError(t, err)
+	assert.True(t, next.IsZero())
+
+	next, err = parseTime("2026-01-01T08:30:00Z")
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2026, 1, 1, 8, 30, 0, 0, time.UTC), next.UTC())
+
+	_, err = parseTime("tomorrow")
+	assert.Error(t, err)
+}
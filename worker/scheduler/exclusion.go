@@ -0,0 +1,45 @@
+package scheduler
+
+import (
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+	"github.com/fyerfyer/scheduler-refactor/worker/joblock"
+	"github.com/fyerfyer/scheduler-refactor/worker/tracer"
+)
+
+// lockExclusion 获取任务所属的全部互斥组锁，执行结束后释放；同组的其他任务正在执行时放弃本次触发
+func (s *Scheduler) lockExclusion(d *dueJob) bool {
+	job := d.plan.Job
+	lock, acquired, err := joblock.TryLockGroups(s.lockSession, job.ExclusionGroups)
+	if err == nil && acquired {
+		s.exclusionLocks[job.Name] = lock
+		return true
+	}
+
+	detail := "exclusion group busy: " + strings.Join(job.ExclusionGroups, ",")
+	reason := common.SkipReasonExclusion
+	if err != nil {
+		s.logger.Warn("failed to acquire exclusion group locks, skipping execution",
+			zap.String("jobName", job.Name),
+			zap.Strings("groups", job.ExclusionGroups),
+			zap.Error(err))
+		detail = err.Error()
+		reason = common.SkipReasonLockError
+	}
+
+	s.tracer.Record(job.Name, tracer.StageLock, false, detail)
+	s.decide(job, d.planTime, common.PlacementExcluded, common.PlacementReasonExclusion, detail)
+	s.recordSkip(d, reason)
+	return false
+}
+
+// releaseExclusion 释放任务持有的互斥组锁
+func (s *Scheduler) releaseExclusion(jobName string) {
+	if lock, ok := s.exclusionLocks[jobName]; ok {
+		delete(s.exclusionLocks, jobName)
+		lock.Unlock()
+	}
+}
@@ -0,0 +1,134 @@
+package scheduler
+
+import (
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+	"github.com/fyerfyer/scheduler-refactor/config"
+	"github.com/fyerfyer/scheduler-refactor/worker/gang"
+	"github.com/fyerfyer/scheduler-refactor/worker/joblock"
+	"github.com/fyerfyer/scheduler-refactor/worker/tracer"
+)
+
+const (
+	// gangPollInterval 检查集合结果的间隔
+	gangPollInterval = 500 * time.Millisecond
+
+	// gangGiveUpDelay 截止时间之后仍无法读取集合结果时，再等待多久后在本地放弃
+	gangGiveUpDelay = time.Minute
+)
+
+// gangMember 抢到任务锁、等待任务组其他成员的触发
+type gangMember struct {
+	due       *dueJob            // 本次触发
+	lock      *joblock.BatchLock // 等待期间持有的任务锁
+	barrier   *gang.Barrier      // 集合点
+	nextCheck time.Time          // 下次检查集合结果的时间
+}
+
+// joinGang 持有抢到的任务锁并在集合点登记，等待同组其他任务
+func (s *Scheduler) joinGang(batch *joblock.BatchLock, d *dueJob) {
+	job := d.plan.Job
+	held := batch.Detach(job.Name)
+	if held == nil {
+		return
+	}
+
+	members := common.GangMembers(s.jobManager.ListJobs(), job.Gang)
+	barrier := gang.NewBarrier(s.etcdClient, job.Gang, d.planTime, members, job.GangWait())
+	if err := barrier.Join(job.Name, config.GlobalConfig.WorkerID); err != nil {
+		s.logger.Warn("failed to join job gang, skipping execution",
+			zap.String("jobName", job.Name),
+			zap.String("gang", job.Gang),
+			zap.Error(err))
+		held.Unlock()
+		s.abortGang(d, err.Error())
+		return
+	}
+
+	s.gangs[job.Name] = &gangMember{due: d, lock: held, barrier: barrier}
+	s.tracer.Record(job.Name, tracer.StageGang, true, "lock acquired, waiting for gang "+job.Gang+" until "+barrier.Deadline().Format(time.RFC3339))
+	s.logger.Debug("job waiting for gang members",
+		zap.String("jobName", job.Name),
+		zap.String("gang", job.Gang),
+		zap.Strings("members", members))
+}
+
+// checkGangs 检查等待中的任务组成员，集合完成时开始执行并释放锁，放弃时直接释放锁
+func (s *Scheduler) checkGangs(now time.Time) {
+	for name, member := range s.gangs {
+		// 等待期间worker开始关闭、紧急停机或任务被修改时不再执行
+		if detail := s.gangBlocked(member); detail != "" {
+			s.dropGang(name, detail)
+			continue
+		}
+
+		if now.Before(member.nextCheck) {
+			continue
+		}
+		member.nextCheck = now.Add(gangPollInterval)
+
+		state, err := member.barrier.Check(now)
+		if err != nil {
+			s.logger.Warn("failed to check job gang",
+				zap.String("jobName", name),
+				zap.Error(err))
+			if now.Before(member.barrier.Deadline().Add(gangGiveUpDelay)) {
+				continue
+			}
+			state = gang.StateAbort
+		}
+
+		switch state {
+		case gang.StateCommit:
+			delete(s.gangs, name)
+			s.startExecution(member.due)
+			if member.due.plan.Job.LockDuringRun {
+				s.runLocks[name] = member.lock
+			} else {
+				member.lock.Unlock()
+			}
+		case gang.StateAbort:
+			delete(s.gangs, name)
+			member.lock.Unlock()
+			s.abortGang(member.due, "gang "+member.due.plan.Job.Gang+" was not fully placed in time")
+		}
+	}
+}
+
+// gangBlocked 检查等待中的成员是否仍能开始执行，不能时返回原因
+func (s *Scheduler) gangBlocked(member *gangMember) string {
+	switch {
+	case s.draining.Load():
+		return "worker shutting down while waiting for gang"
+	case s.halted.Load():
+		return "worker halted by kill switch while waiting for gang"
+	case s.jobPlans[member.due.plan.Job.Name] != member.due.plan:
+		return "job changed while waiting for gang"
+	}
+	return ""
+}
+
+// dropGang 放弃等待中的任务组成员并释放任务锁，成员不存在时什么也不做
+func (s *Scheduler) dropGang(jobName, detail string) {
+	member, ok := s.gangs[jobName]
+	if !ok {
+		return
+	}
+
+	delete(s.gangs, jobName)
+	member.lock.Unlock()
+	s.abortGang(member.due, detail)
+}
+
+// abortGang 放弃任务组成员的本次触发
+func (s *Scheduler) abortGang(d *dueJob, detail string) {
+	s.releaseExclusion(d.plan.Job.Name)
+	s.releaseSemaphores(d.plan.Job.Name)
+	s.releaseInstance(d.plan.Job.Name)
+	s.tracer.Record(d.plan.Job.Name, tracer.StageGang, false, detail)
+	s.decide(d.plan.Job, d.planTime, common.PlacementExcluded, common.PlacementReasonGang, detail)
+	s.recordSkip(d, common.SkipReasonGangAborted)
+}
@@ -0,0 +1,45 @@
+package scheduler
+
+import (
+	"fmt"
+
+	"go.uber.org/zap"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+	"github.com/fyerfyer/scheduler-refactor/worker/joblock"
+	"github.com/fyerfyer/scheduler-refactor/worker/tracer"
+)
+
+// acquireInstance 为任务占用一个执行实例槽位，执行结束后释放；集群内同时执行的实例数达到上限时放弃本次触发
+func (s *Scheduler) acquireInstance(d *dueJob) bool {
+	job := d.plan.Job
+	slot, acquired, err := joblock.TryAcquireInstance(s.lockSession, job.Name, job.MaxInstances)
+	if err == nil && acquired {
+		s.instanceSlots[job.Name] = slot
+		return true
+	}
+
+	detail := fmt.Sprintf("%d instances already running", job.MaxInstances)
+	reason := common.SkipReasonMaxInstances
+	if err != nil {
+		s.logger.Warn("failed to acquire instance slot, skipping execution",
+			zap.String("jobName", job.Name),
+			zap.Int("maxInstances", job.MaxInstances),
+			zap.Error(err))
+		detail = err.Error()
+		reason = common.SkipReasonLockError
+	}
+
+	s.tracer.Record(job.Name, tracer.StageLock, false, detail)
+	s.decide(job, d.planTime, common.PlacementExcluded, common.PlacementReasonInstances, detail)
+	s.recordSkip(d, reason)
+	return false
+}
+
+// releaseInstance 释放任务占用的执行实例槽位
+func (s *Scheduler) releaseInstance(jobName string) {
+	if slot, ok := s.instanceSlots[jobName]; ok {
+		delete(s.instanceSlots, jobName)
+		slot.Unlock()
+	}
+}
@@ -2,24 +2,107 @@ package scheduler
 
 import (
 	"context"
+	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/robfig/cron/v3"
 	"go.uber.org/zap"
 
 	"github.com/fyerfyer/scheduler-refactor/common"
+	"github.com/fyerfyer/scheduler-refactor/config"
 	"github.com/fyerfyer/scheduler-refactor/pkg/etcd"
 	"github.com/fyerfyer/scheduler-refactor/worker/executor"
 	"github.com/fyerfyer/scheduler-refactor/worker/joblock"
 	"github.com/fyerfyer/scheduler-refactor/worker/jobmgr"
+	"github.com/fyerfyer/scheduler-refactor/worker/tracer"
 )
 
 // JobSchedulePlan 任务调度计划
 type JobSchedulePlan struct {
-	Job      *common.Job   // 任务信息
-	Expr     cron.Schedule // cron表达式
-	NextTime time.Time     // 下次调度时间
+	Job      *common.Job    // 任务信息
+	Expr     cron.Schedule  // cron表达式
+	NextTime time.Time      // 下次调度时间
+	Location *time.Location // 任务时区，为nil时使用本地时区
+}
+
+// cronParser 任务cron表达式解析器，带秒字段
+var cronParser = cron.NewParser(cron.Second | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow)
+
+// newSchedulePlan 解析任务的cron表达式和时区，构建从now开始的调度计划
+func newSchedulePlan(job *common.Job, now time.Time) (*JobSchedulePlan, error) {
+	location, err := job.Location()
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone: %v", err)
+	}
+	expr, err := cronParser.Parse(job.CronExpr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cron expression: %v", err)
+	}
+
+	// 按任务时区计算触发时间，不同时区的worker在同一时刻触发
+	if spec, ok := expr.(*cron.SpecSchedule); ok {
+		spec.Location = location
+	}
+
+	return &JobSchedulePlan{
+		Job:      job,
+		Expr:     expr,
+		NextTime: expr.Next(now),
+		Location: location,
+	}, nil
+}
+
+// localTime 将时间转换到任务时区，用于判断允许执行的时间段
+func (p *JobSchedulePlan) localTime(t time.Time) time.Time {
+	if p.Location == nil {
+		return t
+	}
+	return t.In(p.Location)
+}
+
+// zoneLockMargin 首选可用区的worker在故障转移等待时间之外多持有任务锁的时间，避免与其他可用区同时接手
+const zoneLockMargin = 5 * time.Second
+
+// defaultTick 调度循环默认的检查间隔
+const defaultTick = 100 * time.Millisecond
+
+// SkipRecorder 跳过记录的接收者，由日志收集器实现
+type SkipRecorder interface {
+	Append(jobLog *common.JobLog)
+}
+
+// ResultHandler 执行结果的接收者，调度器移除执行记录后把结果连同执行信息交给它
+type ResultHandler interface {
+	HandleResult(result *common.JobExecuteResult, info *common.JobExecuteInfo)
+}
+
+// CanarySource 灰度发布来源，返回当前worker需要试运行的新定义，没有时返回nil
+type CanarySource interface {
+	Lookup(jobName string) *common.Job
+}
+
+// FleetSource 蓝绿切换来源，判断当前worker是否可以执行任务
+type FleetSource interface {
+	Eligible(jobName string) bool
+}
+
+// ExecutorSupport 判断当前worker是否安装了任务类型对应的执行器
+type ExecutorSupport interface {
+	Supports(jobType string) bool
+}
+
+// ScheduleSources 外部调度来源，为设置了schedule的任务提供下次执行时间，结果变化时通过Updates通知
+type ScheduleSources interface {
+	Schedule(job *common.Job, fallback cron.Schedule) cron.Schedule
+	Remove(jobName string)
+	Updates() <-chan string
+}
+
+// PlacementRecorder 触发归属决策的接收者，每轮调度结束后批量提交本轮的决策
+type PlacementRecorder interface {
+	Record(decisions []*common.PlacementDecision)
 }
 
 // Scheduler 任务调度器
@@ -28,7 +111,7 @@ type Scheduler struct {
 	jobManager     *jobmgr.JobManager                // 任务管理器
 	etcdClient     *etcd.Client                      // etcd客户端
 	jobPlans       map[string]*JobSchedulePlan       // 任务调度计划表
-	jobExecuting   map[string]*common.JobExecuteInfo // 正在执行的任务
+	jobExecuting   map[string]*common.JobExecuteInfo // 正在执行的任务，实验命令的key带实验锁后缀
 	jobResultChan  <-chan *common.JobExecuteResult   // 任务执行结果通道
 	jobEventChan   <-chan *common.JobEvent           // 任务事件通道
 	executor       *executor.Executor                // 任务执行器
@@ -37,6 +120,36 @@ type Scheduler struct {
 	cancelFunc     context.CancelFunc                // 取消函数
 	executionCount int
 	countLock      sync.Mutex
+	tick           time.Duration                 // 调度循环检查到期任务的间隔
+	maxConcurrent  atomic.Int64                  // 最大并发执行任务数，0表示不限制
+	halted         atomic.Bool                   // 紧急停机开关是否开启
+	haltTimer      *time.Timer                   // 宽限时间到期后终止运行中任务的定时器
+	haltLock       sync.Mutex                    // 保护haltTimer
+	killAllChan    chan struct{}                 // 宽限时间到期通知
+	killChan       chan string                   // 终止单个任务的请求，由调度循环处理
+	skipRecorder   SkipRecorder                  // 跳过记录的接收者，为nil时不记录
+	tracer         *tracer.Tracer                // 调度决策追踪器，为nil时不追踪
+	lockGuard      *joblock.Guard                // 抢锁统计和限流，为nil时不限制
+	lockSession    *joblock.Session              // worker共享的锁租约
+	failovers      []*dueJob                     // 不在首选可用区、等待故障转移的触发
+	canary         CanarySource                  // 灰度发布来源，为nil时总是执行当前定义
+	fleet          FleetSource                   // 蓝绿切换来源，为nil时可以执行所有任务
+	executors      ExecutorSupport               // 执行器支持判断，为nil时只执行shell任务
+	sources        ScheduleSources               // 外部调度来源，为nil时所有任务按cron表达式调度
+	sourceUpdates  <-chan string                 // 外部调度来源的变化通知，为nil时不接收
+	resultHandler  ResultHandler                 // 执行结果的接收者，为nil时只记录日志
+	draining       atomic.Bool                   // 是否正在关闭，关闭时不再发起新的执行
+	countQuery     chan chan int                 // 查询正在执行的任务数，由调度循环应答
+	placement      PlacementRecorder             // 触发归属决策的接收者，为nil时不记录
+	decisions      []*common.PlacementDecision   // 本轮调度的决策，只在调度循环中访问
+	triggerChan    <-chan *common.JobTrigger     // 手动触发通道，为nil时不处理手动触发
+	gangs          map[string]*gangMember        // 抢到锁、等待任务组其他成员的触发，key为任务名
+	triggerBackoff map[string]time.Time          // 抢到后没能开始执行而撤销的手动触发，key为runId，值为重试时间
+	triggerSkips   map[string]time.Time          // 已记录过跳过的手动触发，key为runId，值为记录时间
+	runLocks       map[string]*joblock.BatchLock // 执行期间持有的任务锁，执行结束后释放，key为任务名
+	exclusionLocks map[string]*joblock.BatchLock // 执行期间持有的互斥组锁，执行结束后释放，key为任务名
+	semaphoreSlots map[string]*joblock.BatchLock // 执行期间占用的信号量槽位，执行结束后释放，key为任务名
+	instanceSlots  map[string]*joblock.BatchLock // 执行期间占用的实例槽位，执行结束后释放，key为任务名
 }
 
 // NewScheduler 创建调度器
@@ -54,14 +167,26 @@ func NewScheduler(
 		etcdClient:     etcdClient,
 		jobPlans:       make(map[string]*JobSchedulePlan),
 		jobExecuting:   make(map[string]*common.JobExecuteInfo),
+		gangs:          make(map[string]*gangMember),
+		triggerBackoff: make(map[string]time.Time),
+		triggerSkips:   make(map[string]time.Time),
+		runLocks:       make(map[string]*joblock.BatchLock),
+		exclusionLocks: make(map[string]*joblock.BatchLock),
+		semaphoreSlots: make(map[string]*joblock.BatchLock),
+		instanceSlots:  make(map[string]*joblock.BatchLock),
 		jobResultChan:  exec.GetResultChan(),
 		jobEventChan:   jobManager.GetEventChan(),
 		executor:       exec,
 		planChan:       make(chan *JobSchedulePlan, 100),
+		killAllChan:    make(chan struct{}, 1),
+		killChan:       make(chan string, 100),
+		countQuery:     make(chan chan int),
+		lockSession:    joblock.NewSession(etcdClient, logger),
 		ctx:            ctx,
 		cancelFunc:     cancel,
 		executionCount: 0,
 		countLock:      sync.Mutex{},
+		tick:           defaultTick,
 	}
 
 	return scheduler
@@ -78,9 +203,73 @@ func (s *Scheduler) Start() {
 	go s.scheduleLoop()
 }
 
+// SetTick 设置调度循环检查到期任务的间隔，间隔越短触发越准时，空闲时的开销也越大。需在Start之前调用
+func (s *Scheduler) SetTick(tick time.Duration) {
+	if tick > 0 {
+		s.tick = tick
+	}
+}
+
+// LockSession 获取worker共享的锁租约
+func (s *Scheduler) LockSession() *joblock.Session {
+	return s.lockSession
+}
+
+// SetSkipRecorder 设置跳过记录的接收者，被跳过的触发会写入一条skipped日志
+func (s *Scheduler) SetSkipRecorder(recorder SkipRecorder) {
+	s.skipRecorder = recorder
+}
+
+// SetResultHandler 设置执行结果的接收者
+func (s *Scheduler) SetResultHandler(handler ResultHandler) {
+	s.resultHandler = handler
+}
+
+// SetTracer 设置调度决策追踪器
+func (s *Scheduler) SetTracer(t *tracer.Tracer) {
+	s.tracer = t
+}
+
+// SetPlacementRecorder 设置触发归属决策的接收者
+func (s *Scheduler) SetPlacementRecorder(recorder PlacementRecorder) {
+	s.placement = recorder
+}
+
+// SetTriggers 设置手动触发通道，需在Start之前调用
+func (s *Scheduler) SetTriggers(triggers <-chan *common.JobTrigger) {
+	s.triggerChan = triggers
+}
+
+// SetLockGuard 设置抢锁守卫
+func (s *Scheduler) SetLockGuard(guard *joblock.Guard) {
+	s.lockGuard = guard
+}
+
+// SetCanary 设置灰度发布来源
+func (s *Scheduler) SetCanary(source CanarySource) {
+	s.canary = source
+}
+
+// SetFleet 设置蓝绿切换来源
+func (s *Scheduler) SetFleet(source FleetSource) {
+	s.fleet = source
+}
+
+// SetExecutors 设置执行器支持判断，没有安装任务类型对应插件的worker不参与抢锁
+func (s *Scheduler) SetExecutors(support ExecutorSupport) {
+	s.executors = support
+}
+
+// SetScheduleSources 设置外部调度来源，需在Start之前调用
+func (s *Scheduler) SetScheduleSources(sources ScheduleSources) {
+	s.sources = sources
+	s.sourceUpdates = sources.Updates()
+}
+
 // Stop 停止调度器
 func (s *Scheduler) Stop() {
 	s.cancelFunc()
+	s.lockSession.Close()
 	s.logger.Info("scheduler stopped")
 }
 
@@ -90,33 +279,29 @@ func (s *Scheduler) loadJobs() {
 	for _, job := range jobs {
 		// 过滤禁用的任务
 		if job.Disabled {
+			s.tracer.Record(job.Name, tracer.StagePlan, false, "job disabled")
 			continue
 		}
 
-		// 解析cron表达式
-		parser := cron.NewParser(cron.Second | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow)
-		expr, err := parser.Parse(job.CronExpr)
+		// 解析cron表达式并计算任务下次执行时间
+		schedPlan, err := s.buildPlan(job, time.Now())
 		if err != nil {
-			s.logger.Error("failed to parse cron expression",
+			s.logger.Error("failed to parse job schedule",
 				zap.String("jobName", job.Name),
 				zap.String("cronExpr", job.CronExpr),
+				zap.String("timezone", job.Timezone),
 				zap.Error(err))
+			s.tracer.Record(job.Name, tracer.StagePlan, false, err.Error())
 			continue
 		}
 
-		// 计算任务下次执行时间
-		schedPlan := &JobSchedulePlan{
-			Job:      job,
-			Expr:     expr,
-			NextTime: expr.Next(time.Now()),
-		}
-
 		// 添加到调度计划表
 		s.jobPlans[job.Name] = schedPlan
 
 		s.logger.Info("job loaded into schedule",
 			zap.String("jobName", job.Name),
 			zap.String("nextTime", schedPlan.NextTime.Format("2006-01-02 15:04:05")))
+		s.tracer.Record(job.Name, tracer.StagePlan, true, "next fire at "+schedPlan.NextTime.Format(time.RFC3339))
 	}
 }
 
@@ -126,9 +311,14 @@ func (s *Scheduler) handleJobEvent(event *common.JobEvent) {
 	case common.JobEventSave: // 保存任务事件
 		job := event.Job
 
+		// 等待任务组的成员按旧定义抢到的锁，任务变更后放弃
+		s.dropGang(job.Name, "job changed while waiting for gang")
+
 		// 跳过禁用的任务
 		if job.Disabled {
+			s.tracer.Record(job.Name, tracer.StagePlan, false, "job disabled")
 			// 如果任务已在调度计划中，则移除它
+			s.removeSource(job.Name)
 			if _, exists := s.jobPlans[job.Name]; exists {
 				delete(s.jobPlans, job.Name)
 				s.logger.Info("job disabled and removed from schedule",
@@ -137,36 +327,35 @@ func (s *Scheduler) handleJobEvent(event *common.JobEvent) {
 			return
 		}
 
-		// 解析cron表达式
-		parser := cron.NewParser(cron.Second | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow)
-		expr, err := parser.Parse(job.CronExpr)
+		// 构建调度计划
+		schedPlan, err := s.buildPlan(job, time.Now())
 		if err != nil {
-			s.logger.Error("failed to parse cron expression",
+			s.logger.Error("failed to parse job schedule",
 				zap.String("jobName", job.Name),
 				zap.String("cronExpr", job.CronExpr),
+				zap.String("timezone", job.Timezone),
 				zap.Error(err))
+			s.tracer.Record(job.Name, tracer.StagePlan, false, err.Error())
 			return
 		}
 
-		// 构建调度计划
-		schedPlan := &JobSchedulePlan{
-			Job:      job,
-			Expr:     expr,
-			NextTime: expr.Next(time.Now()),
-		}
-
 		// 更新调度计划
 		s.jobPlans[job.Name] = schedPlan
 
 		s.logger.Info("job saved and scheduled",
 			zap.String("jobName", job.Name),
 			zap.String("nextTime", schedPlan.NextTime.Format("2006-01-02 15:04:05")))
+		s.tracer.Record(job.Name, tracer.StagePlan, true, "next fire at "+schedPlan.NextTime.Format(time.RFC3339))
 
 	case common.JobEventDelete: // 删除任务事件
+		s.removeSource(event.Job.Name)
+		s.dropGang(event.Job.Name, "job deleted while waiting for gang")
+
 		// 从调度计划表中删除任务
 		if _, exists := s.jobPlans[event.Job.Name]; exists {
 			delete(s.jobPlans, event.Job.Name)
 			s.logger.Info("job removed from schedule", zap.String("jobName", event.Job.Name))
+			s.tracer.Record(event.Job.Name, tracer.StagePlan, false, "job deleted")
 		}
 	}
 }
@@ -177,20 +366,38 @@ func (s *Scheduler) handleJobResult(result *common.JobExecuteResult) {
 	s.countLock.Lock()
 	s.executionCount++
 	s.countLock.Unlock()
-	delete(s.jobExecuting, result.JobName)
+	key := executingKey(result.JobName, result.Experiment)
+	info := s.jobExecuting[key]
+	delete(s.jobExecuting, key)
+
+	// 执行结束后才释放执行期间持有的任务锁，实验命令不持有任务的锁
+	if !result.Experiment {
+		if lock, ok := s.runLocks[result.JobName]; ok {
+			delete(s.runLocks, result.JobName)
+			lock.Unlock()
+		}
+		s.releaseExclusion(result.JobName)
+		s.releaseSemaphores(result.JobName)
+		s.releaseInstance(result.JobName)
+	}
 
 	s.logger.Info("job execution finished",
 		zap.String("jobName", result.JobName),
+		zap.String("status", string(result.Status)),
 		zap.String("startTime", result.StartTime.Format("2006-01-02 15:04:05")),
 		zap.String("endTime", result.EndTime.Format("2006-01-02 15:04:05")),
 		zap.String("output", result.Output),
 		zap.String("error", result.Error))
+
+	if s.resultHandler != nil && info != nil {
+		s.resultHandler.HandleResult(result, info)
+	}
 }
 
 // scheduleLoop 调度循环
 func (s *Scheduler) scheduleLoop() {
-	// 使用ticker进行时间推进，每100ms检查一次
-	scheduleTicker := time.NewTicker(100 * time.Millisecond)
+	// 使用ticker进行时间推进，默认每100ms检查一次
+	scheduleTicker := time.NewTicker(s.tick)
 	defer scheduleTicker.Stop()
 
 	// 调度循环
@@ -204,6 +411,19 @@ func (s *Scheduler) scheduleLoop() {
 			s.handleJobResult(result)
 		case <-scheduleTicker.C: // 定时调度检查
 			s.trySchedule()
+		case trigger := <-s.triggerChan: // 手动触发
+			s.handleTrigger(trigger)
+		case jobName := <-s.sourceUpdates: // 外部调度来源的下次执行时间变化
+			s.handleSourceUpdate(jobName)
+		case <-s.killAllChan: // 紧急停机宽限时间到期或关闭等待超时
+			s.killAll()
+		case jobName := <-s.killChan: // 终止单个任务
+			if err := s.KillJob(jobName); err != nil {
+				s.logger.Debug("job not running on this worker, ignoring kill request",
+					zap.String("jobName", jobName))
+			}
+		case reply := <-s.countQuery: // 查询正在执行和等待任务组集合的任务数
+			reply <- len(s.jobExecuting) + len(s.gangs)
 		}
 	}
 }
@@ -226,6 +446,14 @@ func (s *Scheduler) scheduleLoop() {
 //	fmt.Println("--- END SCHEDULER DEBUG ---")
 //}
 
+// dueJob 本轮到期、通过前置检查等待抢锁的任务
+type dueJob struct {
+	plan      *JobSchedulePlan   // 调度计划
+	planTime  time.Time          // 本次计划执行时间
+	notBefore time.Time          // 等待故障转移时，最早的抢锁时间
+	trigger   *common.JobTrigger // 手动触发，按cron表达式触发时为nil
+}
+
 // trySchedule 尝试执行调度
 func (s *Scheduler) trySchedule() {
 	// Debug 信息
@@ -239,69 +467,518 @@ func (s *Scheduler) trySchedule() {
 	// 有任务需要执行时的最近时间点
 	var nearTime *time.Time
 
+	// 任务组集合完成的成员开始执行，超时的成员放弃
+	s.checkGangs(now)
+
+	// 本轮到期的任务，统一批量抢锁，等待时间已到的故障转移触发一并抢锁
+	due := s.dueFailovers(now)
+
 	// 遍历所有调度计划
 	for _, plan := range s.jobPlans {
 		// 如果任务的调度时间已到
-		if plan.NextTime.Before(now) || plan.NextTime.Equal(now) {
-			// 尝试执行任务
-			s.tryStartJob(plan)
+		// 下次执行时间为零值表示没有安排，例如外部调度来源尚未返回结果
+		if !plan.NextTime.IsZero() && !plan.NextTime.After(now) {
+			s.tracer.Record(plan.Job.Name, tracer.StageDue, true, "planned at "+plan.NextTime.Format(time.RFC3339))
 
-			// 计算任务下次执行时间
-			plan.NextTime = plan.Expr.Next(now)
+			if common.InWindows(plan.Job.AllowedWindows, plan.localTime(now)) {
+				if delay := plan.Job.ZoneDelay(config.GlobalConfig.Zone); delay > 0 {
+					// 不在首选可用区，等待首选可用区的worker先抢锁
+					s.failovers = append(s.failovers, &dueJob{plan: plan, planTime: plan.NextTime, notBefore: now.Add(delay)})
+					s.tracer.Record(plan.Job.Name, tracer.StageZone, false, "preferred zone "+plan.Job.PreferredZone+", failover at "+now.Add(delay).Format(time.RFC3339))
+					s.decide(plan.Job, plan.NextTime, common.PlacementExcluded, common.PlacementReasonZone,
+						"preferred zone "+plan.Job.PreferredZone+", waiting for failover until "+now.Add(delay).Format(time.RFC3339))
+				} else if d := (&dueJob{plan: plan, planTime: plan.NextTime}); s.canStart(d, len(due)) {
+					// 通过前置检查的任务加入本轮抢锁
+					due = append(due, d)
+				}
+
+				// 计算任务下次执行时间
+				plan.NextTime = plan.Expr.Next(now)
+			} else if plan.Job.DeferToWindow {
+				// 推迟到下一个窗口开始时执行，期间的多次触发合并为一次
+				s.decide(plan.Job, plan.NextTime, common.PlacementExcluded, common.PlacementReasonWindow, "outside allowed windows, deferred")
+				plan.NextTime = common.NextWindowStart(plan.Job.AllowedWindows, plan.localTime(now))
+				s.tracer.Record(plan.Job.Name, tracer.StageWindow, false, "outside allowed windows, deferred to "+plan.NextTime.Format(time.RFC3339))
+				s.logger.Debug("job fired outside allowed window, deferred",
+					zap.String("jobName", plan.Job.Name),
+					zap.String("deferredTo", plan.NextTime.Format("2006-01-02 15:04:05")))
+			} else {
+				s.logger.Debug("job fired outside allowed window, skipping schedule",
+					zap.String("jobName", plan.Job.Name))
+				s.tracer.Record(plan.Job.Name, tracer.StageWindow, false, "outside allowed windows, skipped")
+				s.decide(plan.Job, plan.NextTime, common.PlacementExcluded, common.PlacementReasonWindow, "outside allowed windows, skipped")
+				s.recordSkip(&dueJob{plan: plan, planTime: plan.NextTime}, common.SkipReasonOutsideWindow)
+				plan.NextTime = plan.Expr.Next(now)
+			}
 		}
 
 		// 更新最近要执行的任务时间
-		if nearTime == nil || plan.NextTime.Before(*nearTime) {
+		if !plan.NextTime.IsZero() && (nearTime == nil || plan.NextTime.Before(*nearTime)) {
 			nt := plan.NextTime
 			nearTime = &nt
 		}
 	}
+
+	// 同一轮到期的任务在尽量少的etcd事务中抢锁
+	s.startJobs(due)
+	s.flushDecisions()
+}
+
+// handleTrigger 处理手动触发：通过抢锁前的检查后抢占触发记录，抢占成功的worker抢锁执行，
+// 没有通过检查时保留触发记录，留给其他worker；抢占后没能开始执行时撤销抢占，在触发过期前重试
+func (s *Scheduler) handleTrigger(trigger *common.JobTrigger) {
+	defer s.flushDecisions()
+
+	// 已被抢占的触发只在抢占的worker宕机后重新投递
+	if trigger.Claimed() && !s.claimAbandoned(trigger) {
+		return
+	}
+
+	// 本worker刚撤销的触发等待下次扫描再重试
+	if s.triggerDeferred(trigger.RunID, time.Now()) {
+		return
+	}
+
+	plan, ok := s.jobPlans[trigger.JobName]
+	if !ok {
+		s.logger.Debug("triggered job is not scheduled on this worker, ignoring trigger",
+			zap.String("jobName", trigger.JobName),
+			zap.String("runId", trigger.RunID))
+		return
+	}
+
+	// 指定了定义版本的触发按该版本的定义检查和执行，调度计划保持不变
+	if trigger.Job != nil {
+		pinned := *trigger.Job
+		pinned.Revision = trigger.Revision
+		plan = &JobSchedulePlan{Job: &pinned, Expr: plan.Expr, NextTime: plan.NextTime, Location: plan.Location}
+	}
+
+	planTime := time.Unix(trigger.TriggeredAt, 0)
+	d := &dueJob{plan: plan, planTime: planTime, trigger: trigger}
+	s.tracer.Record(plan.Job.Name, tracer.StageDue, true, "triggered manually by "+trigger.TriggeredBy)
+	if !s.canStart(d, 0) {
+		return
+	}
+
+	token, err := s.claimTrigger(trigger)
+	if err != nil {
+		s.logger.Warn("failed to claim job trigger",
+			zap.String("jobName", trigger.JobName),
+			zap.String("runId", trigger.RunID),
+			zap.Error(err))
+		return
+	}
+	if token == 0 {
+		// 已被其他worker抢走或已过期
+		s.tracer.Record(plan.Job.Name, tracer.StageLock, false, "trigger claimed by another worker")
+		s.decide(plan.Job, planTime, common.PlacementLost, "", "trigger claimed by another worker")
+		return
+	}
+	if trigger.Claimed() {
+		s.logger.Warn("redelivering job trigger abandoned by crashed worker",
+			zap.String("jobName", trigger.JobName),
+			zap.String("runId", trigger.RunID),
+			zap.String("claimedBy", trigger.ClaimedBy),
+			zap.Int("attempt", trigger.Attempt+1))
+	}
+	trigger.ClaimRevision = token
+
+	s.startJobs([]*dueJob{d})
+
+	// 任务锁被其他执行持有，或互斥组、信号量、实例数不允许时本次没有开始执行，撤销抢占后重试
+	if info, ok := s.jobExecuting[plan.Job.Name]; !ok || info.RunID != trigger.RunID {
+		s.logger.Info("job trigger claimed but execution not started, releasing for retry",
+			zap.String("jobName", trigger.JobName),
+			zap.String("runId", trigger.RunID))
+		s.releaseTrigger(trigger)
+	}
+}
+
+// dueFailovers 取出等待时间已到的故障转移触发，任务已变更或等待期间已有新的触发时放弃
+func (s *Scheduler) dueFailovers(now time.Time) []*dueJob {
+	due := make([]*dueJob, 0)
+	waiting := s.failovers[:0]
+	for _, d := range s.failovers {
+		if d.notBefore.After(now) {
+			waiting = append(waiting, d)
+			continue
+		}
+
+		if s.jobPlans[d.plan.Job.Name] != d.plan {
+			continue
+		}
+		if next := d.plan.Expr.Next(d.planTime); !next.After(d.notBefore) {
+			s.tracer.Record(d.plan.Job.Name, tracer.StageZone, false, "superseded by a newer trigger during failover delay")
+			s.decide(d.plan.Job, d.planTime, common.PlacementExcluded, common.PlacementReasonZone, "superseded by a newer trigger during failover delay")
+			continue
+		}
+
+		if s.canStart(d, len(due)) {
+			due = append(due, d)
+		}
+	}
+	s.failovers = waiting
+
+	return due
+}
+
+// holdZoneLock 有首选可用区的任务启动后继续持有任务锁，直到其他可用区的故障转移等待结束，
+// 最晚在任务下次触发前释放
+func (s *Scheduler) holdZoneLock(batch *joblock.BatchLock, d *dueJob) {
+	job := d.plan.Job
+	until := d.planTime.Add(job.FailoverDelay() + zoneLockMargin)
+	if next := d.plan.Expr.Next(d.planTime).Add(-time.Second); next.Before(until) {
+		until = next
+	}
+
+	hold := time.Until(until)
+	if hold <= 0 {
+		return
+	}
+
+	if held := batch.Detach(job.Name); held != nil {
+		time.AfterFunc(hold, held.Unlock)
+	}
 }
 
-// tryStartJob 尝试启动任务
+// tryStartJob 尝试启动单个任务
 func (s *Scheduler) tryStartJob(plan *JobSchedulePlan) {
+	if d := (&dueJob{plan: plan, planTime: plan.NextTime}); s.canStart(d, 0) {
+		s.startJobs([]*dueJob{d})
+	}
+	s.flushDecisions()
+}
+
+// canStart 抢锁前的检查，pending为本轮已经通过检查、等待抢锁的任务数
+func (s *Scheduler) canStart(d *dueJob, pending int) bool {
+	plan, planTime := d.plan, d.planTime
+
 	// 如果任务正在执行，跳过本次调度
-	if _, executing := s.jobExecuting[plan.Job.Name]; executing {
-		s.logger.Info("job is already executing, skipping schedule",
+	_, executing := s.jobExecuting[plan.Job.Name]
+	if _, gathering := s.gangs[plan.Job.Name]; executing || gathering {
+		s.logger.Debug("job is already executing, skipping schedule",
 			zap.String("jobName", plan.Job.Name))
+		s.tracer.Record(plan.Job.Name, tracer.StageExecuting, false, "previous run still executing")
+		s.decide(plan.Job, planTime, common.PlacementExcluded, common.PlacementReasonExecuting, "previous run still executing")
+		s.recordSkip(d, common.SkipReasonExecuting)
+		return false
+	}
+
+	// worker正在关闭时不再发起新的执行，由其他worker接手
+	if s.draining.Load() {
+		s.tracer.Record(plan.Job.Name, tracer.StageHalt, false, "worker shutting down")
+		s.decide(plan.Job, planTime, common.PlacementExcluded, common.PlacementReasonDraining, "worker shutting down")
+		return false
+	}
+
+	// 蓝绿切换中属于另一代worker的任务不参与抢锁，由另一代接手
+	if s.fleet != nil && !s.fleet.Eligible(plan.Job.Name) {
+		s.tracer.Record(plan.Job.Name, tracer.StageFleet, false, "job assigned to the other generation by fleet switch")
+		s.decide(plan.Job, planTime, common.PlacementExcluded, common.PlacementReasonFleet, "job assigned to the other generation by fleet switch")
+		return false
+	}
+
+	// 没有安装任务类型对应插件的worker不参与抢锁，由安装了插件的worker执行
+	if !s.supportsExecutor(plan.Job) {
+		detail := "executor plugin " + plan.Job.Type + " is not installed"
+		s.tracer.Record(plan.Job.Name, tracer.StageExecutor, false, detail)
+		s.decide(plan.Job, planTime, common.PlacementExcluded, common.PlacementReasonExecutor, detail)
+		return false
+	}
+
+	// 紧急停机开关开启时拒绝所有新的执行
+	if s.halted.Load() {
+		s.logger.Debug("worker halted by kill switch, skipping schedule",
+			zap.String("jobName", plan.Job.Name))
+		s.tracer.Record(plan.Job.Name, tracer.StageHalt, false, "worker halted by kill switch")
+		s.decide(plan.Job, planTime, common.PlacementExcluded, common.PlacementReasonHalted, "worker halted by kill switch")
+		s.recordSkip(d, common.SkipReasonHalted)
+		return false
+	}
+
+	// 达到并发上限时跳过本次调度
+	if limit := s.maxConcurrent.Load(); limit > 0 && int64(len(s.jobExecuting)+pending) >= limit {
+		s.logger.Debug("max concurrent jobs reached, skipping schedule",
+			zap.String("jobName", plan.Job.Name),
+			zap.Int64("maxConcurrentJobs", limit))
+		detail := fmt.Sprintf("%d jobs executing, %d pending, limit %d", len(s.jobExecuting), pending, limit)
+		s.tracer.Record(plan.Job.Name, tracer.StageConcurrency, false, detail)
+		s.decide(plan.Job, planTime, common.PlacementExcluded, common.PlacementReasonOverload, detail)
+		s.recordSkip(d, common.SkipReasonOverload)
+		return false
+	}
+
+	// 超出抢锁预算时放弃本次抢锁，保护etcd集群
+	if !s.lockGuard.Allow(plan.Job.Name) {
+		s.logger.Debug("lock attempt throttled, skipping execution",
+			zap.String("jobName", plan.Job.Name))
+		s.tracer.Record(plan.Job.Name, tracer.StageLock, false, "lock attempt throttled")
+		s.decide(plan.Job, planTime, common.PlacementThrottled, "", "lock attempt throttled")
+		s.recordSkip(d, common.SkipReasonThrottled)
+		return false
+	}
+
+	return true
+}
+
+// startJobs 批量抢锁并启动抢到锁的任务
+func (s *Scheduler) startJobs(due []*dueJob) {
+	if len(due) == 0 {
 		return
 	}
 
-	// 执行任务前，先获取分布式锁
-	jobLock := joblock.NewJobLock(s.etcdClient, plan.Job.Name)
+	jobNames := make([]string, len(due))
+	for i, d := range due {
+		jobNames[i] = d.plan.Job.Name
+	}
 
-	// 尝试获取锁
-	err := jobLock.TryLock()
+	// 执行任务前，先批量获取分布式锁
+	lockStart := time.Now()
+	batch, acquired, err := joblock.TryLockBatch(s.lockSession, jobNames)
+	latency := time.Since(lockStart)
 	if err != nil {
-		// 获取锁失败，跳过本次调度
-		s.logger.Debug("failed to acquire job lock, skipping execution",
-			zap.String("jobName", plan.Job.Name),
+		// 获取锁出错，跳过本轮调度
+		s.logger.Warn("failed to acquire job locks, skipping execution",
+			zap.Strings("jobNames", jobNames),
 			zap.Error(err))
+		for _, d := range due {
+			s.lockGuard.Observe(d.plan.Job.Name, latency, err)
+			s.tracer.Record(d.plan.Job.Name, tracer.StageLock, false, err.Error())
+			s.decide(d.plan.Job, d.planTime, common.PlacementError, "", err.Error())
+			s.recordSkip(d, common.SkipReasonLockError)
+		}
 		return
 	}
 
+	// 本worker没有抢到锁、配置了实验命令的任务
+	experiments := make([]*dueJob, 0)
+
+	// 任务启动后释放锁，允许其他节点在下一次调度时获取锁；
+	// 开启了lockDuringRun的任务和首选可用区的任务会从批次中移出单独释放
+	defer batch.Unlock()
+
+	for i, d := range due {
+		plan := d.plan
+
+		// 锁被其他worker持有说明本次触发由其他worker执行，不算跳过
+		if !acquired[i] {
+			s.lockGuard.Observe(plan.Job.Name, latency, common.ErrLockAlreadyAcquired)
+			s.logger.Debug("job lock held by another worker, skipping execution",
+				zap.String("jobName", plan.Job.Name))
+			s.tracer.Record(plan.Job.Name, tracer.StageLock, false, common.ErrLockAlreadyAcquired.Error())
+			s.decide(plan.Job, d.planTime, common.PlacementLost, "", common.ErrLockAlreadyAcquired.Error())
+			if plan.Job.ExperimentCommand != "" && d.trigger == nil {
+				experiments = append(experiments, d)
+			}
+			continue
+		}
+		s.lockGuard.Observe(plan.Job.Name, latency, nil)
+
+		// 同一互斥组的其他任务正在执行时放弃本次触发
+		if len(plan.Job.ExclusionGroups) > 0 && !s.lockExclusion(d) {
+			continue
+		}
+
+		// 占用的信号量没有空闲槽位时放弃本次触发
+		if len(plan.Job.Semaphores) > 0 && !s.acquireSemaphores(d) {
+			s.releaseExclusion(plan.Job.Name)
+			continue
+		}
+
+		// 集群内同时执行的实例数达到上限时放弃本次触发
+		if plan.Job.MaxInstances > 0 && !s.acquireInstance(d) {
+			s.releaseExclusion(plan.Job.Name)
+			s.releaseSemaphores(plan.Job.Name)
+			continue
+		}
+
+		// 任务组成员持有锁等待其他成员，手动触发不等待
+		if plan.Job.Gang != "" && d.trigger == nil {
+			s.joinGang(batch, d)
+			continue
+		}
+
+		if plan.Job.LockDuringRun {
+			// 执行期间持有锁，其他worker的触发抢不到锁
+			s.runLocks[plan.Job.Name] = batch.Detach(plan.Job.Name)
+		} else if plan.Job.PreferredZone != "" {
+			// 其他可用区等待故障转移期间不能抢到锁
+			s.holdZoneLock(batch, d)
+		}
+
+		s.startExecution(d)
+	}
+
+	s.startExperiments(experiments)
+}
+
+// startExecution 为抢到锁的触发启动执行
+func (s *Scheduler) startExecution(d *dueJob) {
+	plan := d.plan
+
 	// 构建执行状态信息
 	jobExecuteInfo := &common.JobExecuteInfo{
 		Job:      plan.Job,
-		PlanTime: plan.NextTime,
+		PlanTime: d.planTime,
 		RealTime: time.Now(),
 	}
+	if d.trigger != nil {
+		jobExecuteInfo.RunID = d.trigger.RunID
+		jobExecuteInfo.TriggeredBy = d.trigger.TriggeredBy
+		jobExecuteInfo.ReplayOf = d.trigger.ReplayOf
+		jobExecuteInfo.TriggerToken = d.trigger.ClaimRevision
+	}
+
+	// 当前worker负责灰度发布时执行新定义，调度仍按当前定义；指定了定义版本的触发不受灰度影响
+	if s.canary != nil && (d.trigger == nil || d.trigger.Job == nil) {
+		if canaryJob := s.canary.Lookup(plan.Job.Name); canaryJob != nil {
+			jobExecuteInfo.Job = canaryJob
+			jobExecuteInfo.Canary = true
+		}
+	}
 
 	// 保存执行状态
 	s.jobExecuting[plan.Job.Name] = jobExecuteInfo
+	s.tracer.Record(plan.Job.Name, tracer.StageStart, true, "lock acquired, execution started")
+	s.decide(plan.Job, d.planTime, common.PlacementWon, "", "lock acquired, execution started")
 
 	// 执行任务
 	s.executor.ExecuteJob(jobExecuteInfo)
 
-	s.logger.Info("job scheduled for execution",
+	s.logger.Debug("job scheduled for execution",
 		zap.String("jobName", plan.Job.Name),
-		zap.String("planTime", plan.NextTime.Format("2006-01-02 15:04:05")),
+		zap.String("planTime", d.planTime.Format("2006-01-02 15:04:05")),
 		zap.String("realTime", jobExecuteInfo.RealTime.Format("2006-01-02 15:04:05")))
+}
+
+// startExperiments 为其他worker执行的触发抢实验命令锁并执行实验命令，
+// 抢到任务锁的worker不参与，保证两个版本在不同worker上并行执行
+func (s *Scheduler) startExperiments(due []*dueJob) {
+	if len(due) == 0 {
+		return
+	}
+
+	// 上一次实验命令仍在本worker执行时不再启动
+	pending := due[:0]
+	for _, d := range due {
+		if _, running := s.jobExecuting[executingKey(d.plan.Job.Name, true)]; !running {
+			pending = append(pending, d)
+		}
+	}
+	due = pending
+	if len(due) == 0 {
+		return
+	}
+
+	lockNames := make([]string, len(due))
+	for i, d := range due {
+		lockNames[i] = executingKey(d.plan.Job.Name, true)
+	}
+
+	batch, acquired, err := joblock.TryLockBatch(s.lockSession, lockNames)
+	if err != nil {
+		s.logger.Warn("failed to acquire experiment locks, skipping experiments",
+			zap.Strings("lockNames", lockNames),
+			zap.Error(err))
+		return
+	}
+	defer batch.Unlock()
+
+	for i, d := range due {
+		if !acquired[i] {
+			continue
+		}
+
+		// 实验命令沿用任务的其他配置，只替换命令
+		experiment := *d.plan.Job
+		experiment.Command = d.plan.Job.ExperimentCommand
+
+		jobExecuteInfo := &common.JobExecuteInfo{
+			Job:        &experiment,
+			PlanTime:   d.planTime,
+			RealTime:   time.Now(),
+			Experiment: true,
+		}
+		s.jobExecuting[executingKey(experiment.Name, true)] = jobExecuteInfo
+		s.tracer.Record(experiment.Name, tracer.StageStart, true, "experiment lock acquired, experiment command started")
+
+		s.executor.ExecuteJob(jobExecuteInfo)
+
+		s.logger.Debug("experiment command scheduled for execution",
+			zap.String("jobName", experiment.Name),
+			zap.String("planTime", d.planTime.Format("2006-01-02 15:04:05")))
+	}
+}
 
-	// 任务启动后释放锁
-	// 注意: 这里我们在任务开始后立即释放锁，允许其他节点在下一次调度时获取锁
-	// 真实场景可能需要根据任务特性决定是否在任务结束后释放锁
-	jobLock.Unlock()
+// executingKey 执行记录在jobExecuting中的key，实验命令与任务同名，加上后缀与任务本身的执行区分
+func executingKey(jobName string, experiment bool) string {
+	if experiment {
+		return jobName + common.ExperimentLockSuffix
+	}
+	return jobName
+}
+
+// decide 记录本worker对一次触发的决策，本轮调度结束后由flushDecisions统一提交
+func (s *Scheduler) decide(job *common.Job, planTime time.Time, outcome, reason, detail string) {
+	if s.placement == nil {
+		return
+	}
+
+	s.decisions = append(s.decisions, &common.PlacementDecision{
+		JobName:   job.Name,
+		Worker:    config.GlobalConfig.WorkerID,
+		Zone:      config.GlobalConfig.Zone,
+		PlanTime:  planTime.Unix(),
+		Outcome:   outcome,
+		Reason:    reason,
+		Detail:    detail,
+		DecidedAt: time.Now().Unix(),
+	})
+}
+
+// flushDecisions 提交本轮调度的决策
+func (s *Scheduler) flushDecisions() {
+	if len(s.decisions) == 0 {
+		return
+	}
+
+	s.placement.Record(s.decisions)
+	s.decisions = nil
+}
+
+// recordSkip 记录一次被跳过的触发，计划时间取被跳过的这次触发的时间。
+// 手动触发保留给其他worker时会被重新扫描反复投递，同一个触发只记录一次
+func (s *Scheduler) recordSkip(d *dueJob, reason string) {
+	if s.skipRecorder == nil {
+		return
+	}
+
+	now := time.Now()
+	var triggeredBy string
+	if d.trigger != nil {
+		if s.triggerSkipped(d.trigger.RunID, now) {
+			return
+		}
+		triggeredBy = d.trigger.TriggeredBy
+	}
+
+	plan := d.plan
+	s.skipRecorder.Append(&common.JobLog{
+		JobName:      plan.Job.Name,
+		Command:      plan.Job.Command,
+		PlanTime:     d.planTime.Unix(),
+		ScheduleTime: now.Unix(),
+		StartTime:    now.Unix(),
+		EndTime:      now.Unix(),
+		TriggeredBy:  triggeredBy,
+		Status:       common.RunStatusSkipped,
+		WorkerIP:     config.GlobalConfig.WorkerID,
+		Namespace:    common.NamespaceOf(plan.Job.Namespace),
+		Owner:        plan.Job.Owner,
+		SkipReason:   reason,
+		Annotations:  plan.Job.Annotations,
+	})
 }
 
 // GetExecutingJobs 获取正在执行的任务
@@ -321,9 +998,126 @@ func (s *Scheduler) KillJob(jobName string) error {
 	return common.NewJobError(jobName, common.ErrJobNotFound)
 }
 
+// RequestKill 请求终止运行中的任务，由调度循环处理，可以在任意协程中调用
+func (s *Scheduler) RequestKill(jobName string) {
+	select {
+	case s.killChan <- jobName:
+	default:
+		s.logger.Warn("kill request channel full, dropping request", zap.String("jobName", jobName))
+	}
+}
+
+// SetMaxConcurrentJobs 热更新最大并发执行任务数，0表示不限制
+func (s *Scheduler) SetMaxConcurrentJobs(limit int) {
+	if limit < 0 {
+		limit = 0
+	}
+	s.maxConcurrent.Store(int64(limit))
+}
+
+// Halt 开启紧急停机：立即拒绝新的执行，grace之后终止仍在运行的任务
+func (s *Scheduler) Halt(grace time.Duration) {
+	s.halted.Store(true)
+
+	s.haltLock.Lock()
+	defer s.haltLock.Unlock()
+
+	if s.haltTimer != nil {
+		s.haltTimer.Stop()
+	}
+	s.haltTimer = time.AfterFunc(grace, func() {
+		select {
+		case s.killAllChan <- struct{}{}:
+		default:
+		}
+	})
+
+	s.logger.Warn("scheduler halted by kill switch", zap.Duration("gracePeriod", grace))
+}
+
+// Resume 关闭紧急停机，恢复调度
+func (s *Scheduler) Resume() {
+	s.haltLock.Lock()
+	if s.haltTimer != nil {
+		s.haltTimer.Stop()
+		s.haltTimer = nil
+	}
+	s.haltLock.Unlock()
+
+	if s.halted.Swap(false) {
+		s.logger.Info("scheduler resumed")
+	}
+}
+
+// Halted 紧急停机开关是否开启
+func (s *Scheduler) Halted() bool {
+	return s.halted.Load()
+}
+
+// killAll 终止所有运行中的任务，在调度循环中调用
+func (s *Scheduler) killAll() {
+	// 宽限期内开关已关闭
+	if !s.halted.Load() && !s.draining.Load() {
+		return
+	}
+
+	for jobName, jobInfo := range s.jobExecuting {
+		s.logger.Warn("killing job after grace period", zap.String("jobName", jobName))
+		s.executor.KillJob(jobName, jobInfo)
+	}
+}
+
+// Drain 开始关闭：不再发起新的执行，运行中的任务继续执行，结果照常处理
+func (s *Scheduler) Drain() {
+	s.draining.Store(true)
+	s.logger.Info("scheduler draining, no new executions will start")
+}
+
+// KillRunning 关闭等待超时后终止所有运行中的任务，需先调用Drain
+func (s *Scheduler) KillRunning() {
+	select {
+	case s.killAllChan <- struct{}{}:
+	default:
+	}
+}
+
+// WaitIdle 等待所有运行中的任务结束并处理完结果，等待任务组集合的成员放弃后才算空闲，ctx到期时返回false
+func (s *Scheduler) WaitIdle(ctx context.Context) bool {
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		reply := make(chan int, 1)
+		select {
+		case s.countQuery <- reply:
+			if <-reply == 0 {
+				return true
+			}
+		case <-s.ctx.Done(): // 调度循环已退出，无法再处理结果
+			return false
+		case <-ctx.Done():
+			return false
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return false
+		}
+	}
+}
+
 // GetExecutionCount 获取任务执行计数
 func (s *Scheduler) GetExecutionCount() int {
 	s.countLock.Lock()
 	defer s.countLock.Unlock()
 	return s.executionCount
 }
+
+// supportsExecutor 判断当前worker能否执行任务的类型
+func (s *Scheduler) supportsExecutor(job *common.Job) bool {
+	if job.ExecutorType() == common.ExecutorShell {
+		return true
+	}
+	return s.executors != nil && s.executors.Supports(job.Type)
+}
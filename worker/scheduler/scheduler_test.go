@@ -2,6 +2,7 @@ package scheduler
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"testing"
 	"time"
@@ -15,6 +16,7 @@ import (
 	"github.com/fyerfyer/scheduler-refactor/config"
 	"github.com/fyerfyer/scheduler-refactor/pkg/etcd"
 	"github.com/fyerfyer/scheduler-refactor/worker/executor"
+	"github.com/fyerfyer/scheduler-refactor/worker/joblock"
 	"github.com/fyerfyer/scheduler-refactor/worker/jobmgr"
 )
 
@@ -195,6 +197,32 @@ func TestKillJob(t *testing.T) {
 	assert.NoError(t, err, "Should not return error when killing existing job")
 }
 
+func TestHaltAndResume(t *testing.T) {
+	scheduler := setupTestScheduler(t)
+	defer scheduler.Stop()
+
+	scheduler.Halt(time.Hour)
+	assert.True(t, scheduler.Halted(), "Scheduler should be halted")
+
+	// 停机后不再启动新的执行
+	plan := &JobSchedulePlan{Job: createTestJob("halted-job", "echo test", "*/1 * * * * *", false), NextTime: time.Now()}
+	scheduler.tryStartJob(plan)
+	assert.Empty(t, scheduler.GetExecutingJobs(), "Halted scheduler should refuse new executions")
+
+	// 宽限时间到期后终止运行中的任务
+	ctx, cancel := context.WithCancel(context.Background())
+	scheduler.jobExecuting["running-job"] = &common.JobExecuteInfo{
+		Job:        createTestJob("running-job", "sleep 10", "*/1 * * * * *", false),
+		CancelCtx:  ctx,
+		CancelFunc: cancel,
+	}
+	scheduler.killAll()
+	assert.Error(t, ctx.Err(), "Running job should be killed after grace period")
+
+	scheduler.Resume()
+	assert.False(t, scheduler.Halted(), "Scheduler should resume")
+}
+
 func TestSchedulerCycling(t *testing.T) {
 	scheduler := setupTestScheduler(t)
 	defer scheduler.Stop()
@@ -262,3 +290,380 @@ func setupTestScheduler(t *testing.T) *Scheduler {
 
 	return NewScheduler(logger, jobMan, etcdClient, exec)
 }
+
+// skipCollector 收集跳过记录
+type skipCollector struct {
+	logs []*common.JobLog
+}
+
+func (c *skipCollector) Append(jobLog *common.JobLog) {
+	c.logs = append(c.logs, jobLog)
+}
+
+func TestRecordSkip(t *testing.T) {
+	scheduler := setupTestScheduler(t)
+	defer scheduler.Stop()
+
+	collector := &skipCollector{}
+	scheduler.SetSkipRecorder(collector)
+
+	job := createTestJob("skip-job", "echo test", "*/1 * * * * *", false)
+	job.Namespace = "team-a"
+	plan := &JobSchedulePlan{Job: job, NextTime: time.Now()}
+
+	// 上一次执行尚未结束
+	scheduler.jobExecuting[job.Name] = &common.JobExecuteInfo{Job: job}
+	scheduler.tryStartJob(plan)
+	delete(scheduler.jobExecuting, job.Name)
+
+	// 达到并发上限
+	scheduler.SetMaxConcurrentJobs(1)
+	scheduler.jobExecuting["other-job"] = &common.JobExecuteInfo{Job: createTestJob("other-job", "echo", "* * * * * *", false)}
+	scheduler.tryStartJob(plan)
+	scheduler.SetMaxConcurrentJobs(0)
+	delete(scheduler.jobExecuting, "other-job")
+
+	// 超出抢锁预算
+	guard := joblock.NewGuard(1)
+	require.True(t, guard.Allow("other-job"))
+	scheduler.SetLockGuard(guard)
+	scheduler.tryStartJob(plan)
+
+	require.Len(t, collector.logs, 3)
+	assert.Equal(t, common.SkipReasonExecuting, collector.logs[0].SkipReason)
+	assert.Equal(t, common.SkipReasonOverload, collector.logs[1].SkipReason)
+	assert.Equal(t, common.SkipReasonThrottled, collector.logs[2].SkipReason)
+	assert.Equal(t, common.RunStatusSkipped, collector.logs[0].Status)
+	assert.Equal(t, "team-a", collector.logs[0].Namespace)
+}
+
+// placementCollector 收集触发归属决策
+type placementCollector struct {
+	decisions []*common.PlacementDecision
+}
+
+func (c *placementCollector) Record(decisions []*common.PlacementDecision) {
+	c.decisions = append(c.decisions, decisions...)
+}
+
+func TestPlacementDecisions(t *testing.T) {
+	scheduler := setupTestScheduler(t)
+	defer scheduler.Stop()
+
+	collector := &placementCollector{}
+	scheduler.SetPlacementRecorder(collector)
+
+	job := createTestJob("placement-job", "echo test", "*/1 * * * * *", false)
+	plan := &JobSchedulePlan{Job: job, NextTime: time.Now()}
+
+	// 达到并发上限
+	scheduler.SetMaxConcurrentJobs(1)
+	scheduler.jobExecuting["other-job"] = &common.JobExecuteInfo{Job: createTestJob("other-job", "echo", "* * * * * *", false)}
+	scheduler.tryStartJob(plan)
+
+	// 紧急停机
+	delete(scheduler.jobExecuting, "other-job")
+	scheduler.halted.Store(true)
+	scheduler.tryStartJob(plan)
+
+	require.Len(t, collector.decisions, 2)
+	assert.Equal(t, common.PlacementExcluded, collector.decisions[0].Outcome)
+	assert.Equal(t, common.PlacementReasonOverload, collector.decisions[0].Reason)
+	assert.Equal(t, common.PlacementReasonHalted, collector.decisions[1].Reason)
+	assert.Equal(t, plan.NextTime.Unix(), collector.decisions[0].PlanTime)
+	assert.Equal(t, config.GlobalConfig.WorkerID, collector.decisions[0].Worker)
+	assert.Empty(t, scheduler.decisions, "Decisions should be flushed after each round")
+}
+
+func TestHandleTrigger(t *testing.T) {
+	scheduler := setupTestScheduler(t)
+	defer scheduler.Stop()
+
+	job := createTestJob("trigger-job", "sleep 1", "0 0 0 1 1 *", false)
+	expr, err := cron.ParseStandard("0 0 1 1 *")
+	require.NoError(t, err)
+	scheduler.jobPlans[job.Name] = &JobSchedulePlan{Job: job, Expr: expr, NextTime: expr.Next(time.Now())}
+
+	trigger := &common.JobTrigger{JobName: job.Name, RunID: common.NewRunID(), TriggeredBy: "alice", TriggeredAt: time.Now().Unix()}
+	putResp, err := scheduler.etcdClient.Put(common.TriggerKey(job.Name, trigger.RunID), "{}")
+	require.NoError(t, err)
+	trigger.ModRevision = putResp.Header.Revision
+
+	scheduler.handleTrigger(trigger)
+	info, ok := scheduler.GetExecutingJobs()[job.Name]
+	require.True(t, ok, "Claimed trigger should start an execution")
+	assert.Equal(t, trigger.RunID, info.RunID)
+	assert.Equal(t, "alice", info.TriggeredBy)
+
+	resp, err := scheduler.etcdClient.Get(common.TriggerKey(job.Name, trigger.RunID))
+	require.NoError(t, err)
+	require.Equal(t, int64(1), resp.Count, "Claimed trigger should be kept until the executor confirms it")
+	claimed := &common.JobTrigger{}
+	require.NoError(t, json.Unmarshal(resp.Kvs[0].Value, claimed))
+	assert.Equal(t, config.GlobalConfig.WorkerID, claimed.ClaimedBy)
+	assert.Equal(t, 1, claimed.Attempt)
+	assert.Equal(t, resp.Kvs[0].ModRevision, info.TriggerToken, "The claim revision should be the fencing token")
+
+	// 触发已被抢走时不再执行
+	delete(scheduler.jobExecuting, job.Name)
+	scheduler.handleTrigger(trigger)
+	assert.NotContains(t, scheduler.GetExecutingJobs(), job.Name)
+
+	// 抢占的worker仍然存活时不重新投递
+	claimed.ModRevision = resp.Kvs[0].ModRevision
+	scheduler.handleTrigger(claimed)
+	assert.NotContains(t, scheduler.GetExecutingJobs(), job.Name)
+
+	// 指定版本的触发执行历史定义
+	pinned := *job
+	pinned.Command = "sleep 2"
+	trigger = &common.JobTrigger{JobName: job.Name, RunID: common.NewRunID(), TriggeredBy: "alice",
+		TriggeredAt: time.Now().Unix(), Revision: 42, Job: &pinned, ReplayOf: "original-run"}
+	putResp, err = scheduler.etcdClient.Put(common.TriggerKey(job.Name, trigger.RunID), "{}")
+	require.NoError(t, err)
+	trigger.ModRevision = putResp.Header.Revision
+
+	scheduler.handleTrigger(trigger)
+	info, ok = scheduler.GetExecutingJobs()[job.Name]
+	require.True(t, ok)
+	assert.Equal(t, "sleep 2", info.Job.Command)
+	assert.Equal(t, int64(42), info.Job.Revision)
+	assert.Equal(t, "original-run", info.ReplayOf)
+	assert.Equal(t, "sleep 1", scheduler.jobPlans[job.Name].Job.Command, "The current definition should be kept for scheduling")
+}
+
+func TestReleaseTrigger(t *testing.T) {
+	scheduler := setupTestScheduler(t)
+	defer scheduler.Stop()
+
+	job := createTestJob("held-trigger-job", "sleep 1", "0 0 0 1 1 *", false)
+	expr, err := cron.ParseStandard("0 0 1 1 *")
+	require.NoError(t, err)
+	scheduler.jobPlans[job.Name] = &JobSchedulePlan{Job: job, Expr: expr, NextTime: expr.Next(time.Now())}
+
+	// 任务锁被其他worker的执行持有
+	other := joblock.NewSession(scheduler.etcdClient, zap.NewNop())
+	defer other.Close()
+	held, acquired, err := joblock.TryLockBatch(other, []string{job.Name})
+	require.NoError(t, err)
+	require.Equal(t, []bool{true}, acquired)
+	defer held.Unlock()
+
+	trigger := &common.JobTrigger{JobName: job.Name, RunID: common.NewRunID(), TriggeredBy: "alice", TriggeredAt: time.Now().Unix()}
+	putResp, err := scheduler.etcdClient.Put(common.TriggerKey(job.Name, trigger.RunID), "{}")
+	require.NoError(t, err)
+	trigger.ModRevision = putResp.Header.Revision
+
+	scheduler.handleTrigger(trigger)
+	assert.NotContains(t, scheduler.GetExecutingJobs(), job.Name)
+
+	resp, err := scheduler.etcdClient.Get(common.TriggerKey(job.Name, trigger.RunID))
+	require.NoError(t, err)
+	require.Equal(t, int64(1), resp.Count, "Trigger should be kept for retry")
+	released := &common.JobTrigger{}
+	require.NoError(t, json.Unmarshal(resp.Kvs[0].Value, released))
+	assert.False(t, released.Claimed(), "Claim should be released")
+	assert.Equal(t, trigger.RunID, released.RunID)
+
+	// 撤销后一个扫描间隔内不再处理
+	assert.True(t, scheduler.triggerDeferred(trigger.RunID, time.Now()))
+	assert.False(t, scheduler.triggerDeferred(trigger.RunID, time.Now().Add(common.TriggerRetryInterval*time.Second)))
+}
+
+func TestExperimentResult(t *testing.T) {
+	s := &Scheduler{
+		logger:         zap.NewNop(),
+		jobExecuting:   make(map[string]*common.JobExecuteInfo),
+		runLocks:       make(map[string]*joblock.BatchLock),
+		exclusionLocks: make(map[string]*joblock.BatchLock),
+		semaphoreSlots: make(map[string]*joblock.BatchLock),
+		instanceSlots:  make(map[string]*joblock.BatchLock),
+	}
+	job := createTestJob("experiment-job", "echo v1", "*/5 * * * * *", false)
+	s.jobExecuting[job.Name] = &common.JobExecuteInfo{Job: job}
+	s.jobExecuting[executingKey(job.Name, true)] = &common.JobExecuteInfo{Job: job, Experiment: true}
+
+	// 实验命令结束不影响同名任务的执行记录
+	s.handleJobResult(&common.JobExecuteResult{JobName: job.Name, Experiment: true})
+	assert.Contains(t, s.jobExecuting, job.Name)
+	assert.NotContains(t, s.jobExecuting, executingKey(job.Name, true))
+
+	s.handleJobResult(&common.JobExecuteResult{JobName: job.Name})
+	assert.Empty(t, s.jobExecuting)
+}
+
+func TestGangBlocked(t *testing.T) {
+	s := &Scheduler{
+		logger:         zap.NewNop(),
+		jobPlans:       make(map[string]*JobSchedulePlan),
+		jobExecuting:   make(map[string]*common.JobExecuteInfo),
+		gangs:          make(map[string]*gangMember),
+		exclusionLocks: make(map[string]*joblock.BatchLock),
+		semaphoreSlots: make(map[string]*joblock.BatchLock),
+		instanceSlots:  make(map[string]*joblock.BatchLock),
+	}
+	job := createTestJob("gang-job", "echo hi", "*/5 * * * * *", false)
+	job.Gang = "etl"
+	plan := &JobSchedulePlan{Job: job, NextTime: time.Now()}
+	s.jobPlans[job.Name] = plan
+	wait := func() {
+		s.gangs[job.Name] = &gangMember{due: &dueJob{plan: plan, planTime: plan.NextTime}, lock: &joblock.BatchLock{}}
+	}
+
+	wait()
+	assert.Empty(t, s.gangBlocked(s.gangs[job.Name]))
+
+	// 开始关闭时放弃等待中的成员
+	s.draining.Store(true)
+	s.checkGangs(time.Now())
+	assert.Empty(t, s.gangs)
+	assert.Empty(t, s.jobExecuting)
+	s.draining.Store(false)
+
+	// 任务变更后放弃按旧定义等待的成员
+	wait()
+	s.jobPlans[job.Name] = &JobSchedulePlan{Job: job, NextTime: time.Now()}
+	assert.NotEmpty(t, s.gangBlocked(s.gangs[job.Name]))
+
+	s.dropGang(job.Name, "job deleted while waiting for gang")
+	assert.Empty(t, s.gangs)
+}
+
+func TestRecordSkipPlanTime(t *testing.T) {
+	previous := config.GlobalConfig
+	config.GlobalConfig = &config.Config{WorkerID: "worker-1"}
+	defer func() { config.GlobalConfig = previous }()
+
+	collector := &skipCollector{}
+	s := &Scheduler{skipRecorder: collector, triggerSkips: make(map[string]time.Time)}
+
+	// 调度计划已推进到下次触发，跳过记录使用被跳过的这次触发的时间
+	planTime := time.Now().Add(-time.Minute)
+	plan := &JobSchedulePlan{Job: createTestJob("skip-job", "echo test", "0 * * * * *", false), NextTime: time.Now().Add(time.Minute)}
+	s.recordSkip(&dueJob{plan: plan, planTime: planTime}, common.SkipReasonLockError)
+	require.Len(t, collector.logs, 1)
+	assert.Equal(t, planTime.Unix(), collector.logs[0].PlanTime)
+
+	// 重新扫描反复投递的同一个手动触发只记录一次
+	trigger := &common.JobTrigger{JobName: "skip-job", RunID: common.NewRunID(), TriggeredBy: "alice"}
+	s.recordSkip(&dueJob{plan: plan, planTime: planTime, trigger: trigger}, common.SkipReasonHalted)
+	s.recordSkip(&dueJob{plan: plan, planTime: planTime, trigger: trigger}, common.SkipReasonHalted)
+	require.Len(t, collector.logs, 2)
+	assert.Equal(t, "alice", collector.logs[1].TriggeredBy)
+}
+
+func TestLockDuringRun(t *testing.T) {
+	scheduler := setupTestScheduler(t)
+	defer scheduler.Stop()
+
+	job := createTestJob("lock-during-run-job", "sleep 1", "*/1 * * * * *", false)
+	job.LockDuringRun = true
+	plan := &JobSchedulePlan{Job: job, NextTime: time.Now()}
+
+	scheduler.tryStartJob(plan)
+	require.Contains(t, scheduler.GetExecutingJobs(), job.Name)
+	require.Contains(t, scheduler.runLocks, job.Name, "Lock should be held while the job runs")
+
+	resp, err := scheduler.etcdClient.Get(common.JobLockDir + job.Name)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), resp.Count)
+
+	scheduler.handleJobResult(&common.JobExecuteResult{JobName: job.Name, Status: common.RunStatusSuccess})
+	assert.NotContains(t, scheduler.runLocks, job.Name)
+
+	resp, err = scheduler.etcdClient.Get(common.JobLockDir + job.Name)
+	require.NoError(t, err)
+	assert.Zero(t, resp.Count, "Lock should be released once the result is reported")
+}
+
+func TestDueFailovers(t *testing.T) {
+	scheduler := setupTestScheduler(t)
+	defer scheduler.Stop()
+
+	parser := cron.NewParser(cron.Second | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow)
+	hourly, err := parser.Parse("0 0 * * * *")
+	require.NoError(t, err)
+	everySecond, err := parser.Parse("* * * * * *")
+	require.NoError(t, err)
+
+	now := time.Now()
+	planTime := now.Add(-10 * time.Second)
+
+	ready := &JobSchedulePlan{Job: createTestJob("zone-ready", "echo", "0 0 * * * *", false), Expr: hourly}
+	waiting := &JobSchedulePlan{Job: createTestJob("zone-waiting", "echo", "0 0 * * * *", false), Expr: hourly}
+	superseded := &JobSchedulePlan{Job: createTestJob("zone-superseded", "echo", "* * * * * *", false), Expr: everySecond}
+	removed := &JobSchedulePlan{Job: createTestJob("zone-removed", "echo", "0 0 * * * *", false), Expr: hourly}
+	for _, plan := range []*JobSchedulePlan{ready, waiting, superseded} {
+		scheduler.jobPlans[plan.Job.Name] = plan
+	}
+
+	scheduler.failovers = []*dueJob{
+		{plan: ready, planTime: planTime, notBefore: now.Add(-time.Second)},
+		{plan: waiting, planTime: planTime, notBefore: now.Add(time.Minute)},
+		{plan: superseded, planTime: planTime, notBefore: now.Add(-time.Second)},
+		{plan: removed, planTime: planTime, notBefore: now.Add(-time.Second)},
+	}
+
+	due := scheduler.dueFailovers(now)
+	require.Len(t, due, 1, "Only the ready failover should be due")
+	assert.Equal(t, "zone-ready", due[0].plan.Job.Name)
+	require.Len(t, scheduler.failovers, 1, "Failover still waiting should be kept")
+	assert.Equal(t, "zone-waiting", scheduler.failovers[0].plan.Job.Name)
+}
+
+// resultCollector 记录调度器交出的执行结果
+type resultCollector struct {
+	results []*common.JobExecuteResult
+}
+
+func (c *resultCollector) HandleResult(result *common.JobExecuteResult, info *common.JobExecuteInfo) {
+	c.results = append(c.results, result)
+}
+
+func TestDrainAndWaitIdle(t *testing.T) {
+	scheduler := setupTestScheduler(t)
+	defer scheduler.Stop()
+
+	collector := &resultCollector{}
+	scheduler.SetResultHandler(collector)
+
+	// 关闭时不再发起新的执行
+	scheduler.Drain()
+	plan := &JobSchedulePlan{Job: createTestJob("draining-job", "echo test", "*/1 * * * * *", false), NextTime: time.Now()}
+	scheduler.tryStartJob(plan)
+	assert.Empty(t, scheduler.GetExecutingJobs(), "Draining scheduler should refuse new executions")
+
+	// 运行中的任务结束后结果交给处理器，调度器变为空闲
+	job := createTestJob("running-job", "sleep 10", "*/1 * * * * *", false)
+	scheduler.jobExecuting[job.Name] = &common.JobExecuteInfo{Job: job}
+	scheduler.handleJobResult(&common.JobExecuteResult{JobName: job.Name})
+	require.Len(t, collector.results, 1)
+	assert.Equal(t, job.Name, collector.results[0].JobName)
+
+	scheduler.Start()
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	assert.True(t, scheduler.WaitIdle(ctx), "Scheduler without running jobs should be idle")
+}
+
+func TestNewSchedulePlan(t *testing.T) {
+	now := time.Date(2023, 12, 31, 23, 0, 0, 0, time.UTC)
+
+	job := createTestJob("tz-job", "echo", "0 0 9 * * *", false)
+	job.Timezone = "Asia/Tokyo"
+	plan, err := newSchedulePlan(job, now)
+	require.NoError(t, err)
+	assert.True(t, plan.NextTime.Equal(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)),
+		"9:00 in Tokyo should fire at 00:00 UTC regardless of the worker timezone")
+	assert.True(t, plan.Expr.Next(plan.NextTime).Equal(time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)))
+	assert.Equal(t, 8, plan.localTime(now).Hour(), "Allowed windows should be checked in the job timezone")
+
+	job.Timezone = "Mars/Olympus"
+	_, err = newSchedulePlan(job, now)
+	assert.ErrorContains(t, err, "invalid timezone")
+
+	job.Timezone = ""
+	plan, err = newSchedulePlan(job, now)
+	require.NoError(t, err)
+	assert.Equal(t, time.Local, plan.Location)
+}
@@ -0,0 +1,45 @@
+package scheduler
+
+import (
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+	"github.com/fyerfyer/scheduler-refactor/worker/joblock"
+	"github.com/fyerfyer/scheduler-refactor/worker/tracer"
+)
+
+// acquireSemaphores 为任务占用的每个信号量占用一个槽位，执行结束后释放；任一信号量已满时放弃本次触发
+func (s *Scheduler) acquireSemaphores(d *dueJob) bool {
+	job := d.plan.Job
+	slots, acquired, err := joblock.TryAcquireSemaphores(s.lockSession, job.Semaphores)
+	if err == nil && acquired {
+		s.semaphoreSlots[job.Name] = slots
+		return true
+	}
+
+	detail := "semaphore full: " + strings.Join(job.Semaphores, ",")
+	reason := common.SkipReasonSemaphore
+	if err != nil {
+		s.logger.Warn("failed to acquire semaphore slots, skipping execution",
+			zap.String("jobName", job.Name),
+			zap.Strings("semaphores", job.Semaphores),
+			zap.Error(err))
+		detail = err.Error()
+		reason = common.SkipReasonLockError
+	}
+
+	s.tracer.Record(job.Name, tracer.StageLock, false, detail)
+	s.decide(job, d.planTime, common.PlacementExcluded, common.PlacementReasonSemaphore, detail)
+	s.recordSkip(d, reason)
+	return false
+}
+
+// releaseSemaphores 释放任务占用的信号量槽位
+func (s *Scheduler) releaseSemaphores(jobName string) {
+	if slots, ok := s.semaphoreSlots[jobName]; ok {
+		delete(s.semaphoreSlots, jobName)
+		slots.Unlock()
+	}
+}
@@ -0,0 +1,59 @@
+package scheduler
+
+import (
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+	"github.com/fyerfyer/scheduler-refactor/worker/tracer"
+)
+
+// buildPlan 构建任务的调度计划，设置了外部调度来源的任务由来源决定下次执行时间，cron表达式作为后备
+func (s *Scheduler) buildPlan(job *common.Job, now time.Time) (*JobSchedulePlan, error) {
+	plan, err := newSchedulePlan(job, now)
+	if err != nil {
+		return nil, err
+	}
+	if s.sources == nil {
+		return plan, nil
+	}
+	if job.Schedule == nil {
+		s.sources.Remove(job.Name)
+		return plan, nil
+	}
+
+	plan.Expr = s.sources.Schedule(job, plan.Expr)
+	plan.NextTime = plan.Expr.Next(now)
+	return plan, nil
+}
+
+// removeSource 停止查询任务的外部调度来源
+func (s *Scheduler) removeSource(jobName string) {
+	if s.sources != nil {
+		s.sources.Remove(jobName)
+	}
+}
+
+// handleSourceUpdate 外部调度来源的结果变化后重新计算下次执行时间，已经到期、等待本轮调度的触发不受影响
+func (s *Scheduler) handleSourceUpdate(jobName string) {
+	plan, exists := s.jobPlans[jobName]
+	if !exists || plan.Job.Schedule == nil {
+		return
+	}
+
+	now := time.Now()
+	if !plan.NextTime.IsZero() && !plan.NextTime.After(now) {
+		return
+	}
+
+	plan.NextTime = plan.Expr.Next(now)
+	if plan.NextTime.IsZero() {
+		s.tracer.Record(jobName, tracer.StagePlan, false, "schedule source has no next run")
+		return
+	}
+	s.logger.Debug("job rescheduled by schedule source",
+		zap.String("jobName", jobName),
+		zap.String("nextTime", plan.NextTime.Format("2006-01-02 15:04:05")))
+	s.tracer.Record(jobName, tracer.StagePlan, true, "schedule source: next fire at "+plan.NextTime.Format(time.RFC3339))
+}
@@ -0,0 +1,93 @@
+package scheduler
+
+import (
+	"encoding/json"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.uber.org/zap"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+	"github.com/fyerfyer/scheduler-refactor/config"
+)
+
+// claimTrigger 抢占触发：在触发记录上写入当前worker和锁租约，记录保留原有的租约，
+// 由执行器启动命令前确认删除。返回抢占后的修改版本作为fencing token，已被其他worker抢占时返回0
+func (s *Scheduler) claimTrigger(trigger *common.JobTrigger) (int64, error) {
+	leaseID, err := s.lockSession.LeaseID()
+	if err != nil {
+		return 0, err
+	}
+
+	claimed := *trigger
+	claimed.ClaimedBy = config.GlobalConfig.WorkerID
+	claimed.ClaimLease = int64(leaseID)
+	claimed.Attempt++
+	data, err := json.Marshal(&claimed)
+	if err != nil {
+		return 0, err
+	}
+
+	key := common.TriggerKey(trigger.JobName, trigger.RunID)
+	return s.etcdClient.ApplyIfUnchangedAt(key, trigger.ModRevision, clientv3.OpPut(key, string(data), clientv3.WithIgnoreLease()))
+}
+
+// claimAbandoned 判断已被抢占的触发是否需要重新投递：抢占的worker的锁租约过期说明它在确认前宕机
+func (s *Scheduler) claimAbandoned(trigger *common.JobTrigger) bool {
+	ttl, err := s.etcdClient.LeaseTTL(clientv3.LeaseID(trigger.ClaimLease))
+	if err != nil {
+		s.logger.Warn("failed to check job trigger claim",
+			zap.String("jobName", trigger.JobName),
+			zap.String("runId", trigger.RunID),
+			zap.String("claimedBy", trigger.ClaimedBy),
+			zap.Error(err))
+		return false
+	}
+	return ttl < 0
+}
+
+// releaseTrigger 撤销抢到但没有开始执行的触发，触发记录恢复为未抢占，在租约过期前由重新扫描再次投递。
+// 本worker在一个扫描间隔内不再处理该触发，避免任务锁被长时间持有时反复抢占
+func (s *Scheduler) releaseTrigger(trigger *common.JobTrigger) {
+	s.triggerBackoff[trigger.RunID] = time.Now().Add(common.TriggerRetryInterval * time.Second)
+
+	released := *trigger
+	released.ClaimedBy = ""
+	released.ClaimLease = 0
+	data, err := json.Marshal(&released)
+	if err == nil {
+		key := common.TriggerKey(trigger.JobName, trigger.RunID)
+		_, err = s.etcdClient.ApplyIfUnchanged(key, trigger.ClaimRevision, clientv3.OpPut(key, string(data), clientv3.WithIgnoreLease()))
+	}
+	if err != nil {
+		s.logger.Warn("failed to release job trigger",
+			zap.String("jobName", trigger.JobName),
+			zap.String("runId", trigger.RunID),
+			zap.Error(err))
+	}
+}
+
+// triggerDeferred 本worker撤销过的触发是否仍在等待重试，同时清理已到期的记录
+func (s *Scheduler) triggerDeferred(runID string, now time.Time) bool {
+	for id, until := range s.triggerBackoff {
+		if !now.Before(until) {
+			delete(s.triggerBackoff, id)
+		}
+	}
+	_, deferred := s.triggerBackoff[runID]
+	return deferred
+}
+
+// triggerSkipped 标记手动触发已记录过跳过，返回之前是否已记录；超过触发租约时间的记录已无用，一并清理
+func (s *Scheduler) triggerSkipped(runID string, now time.Time) bool {
+	for id, recorded := range s.triggerSkips {
+		if now.Sub(recorded) > common.JobTriggerTTL*time.Second {
+			delete(s.triggerSkips, id)
+		}
+	}
+	if _, ok := s.triggerSkips[runID]; ok {
+		return true
+	}
+	s.triggerSkips[runID] = now
+	return false
+}
@@ -0,0 +1,98 @@
+package tracer
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 每个任务保留的最大事件数
+const maxEventsPerJob = 200
+
+// 调度决策阶段
+const (
+	StagePlan        = "plan"        // 加载或更新调度计划
+	StageDue         = "due"         // 到期检查
+	StageWindow      = "window"      // 允许执行时间窗口检查
+	StageZone        = "zone"        // 可用区故障转移等待
+	StageExecuting   = "executing"   // 上一次执行是否结束
+	StageHalt        = "halt"        // 紧急停机检查
+	StageFleet       = "fleet"       // 蓝绿切换检查
+	StageExecutor    = "executor"    // 执行器插件检查
+	StageConcurrency = "concurrency" // 并发上限检查
+	StageLock        = "lock"        // 获取任务锁
+	StageGang        = "gang"        // 等待任务组其他成员抢到锁
+	StageStart       = "start"       // 启动执行
+)
+
+// Event 一条调度决策事件
+type Event struct {
+	Time    time.Time `json:"time"`             // 发生时间
+	JobName string    `json:"jobName"`          // 任务名称
+	Stage   string    `json:"stage"`            // 决策阶段
+	Passed  bool      `json:"passed"`           // 是否通过该阶段
+	Detail  string    `json:"detail,omitempty"` // 补充说明
+}
+
+// Tracer 调度决策追踪器，开启后按任务保留最近的决策事件，用于排查任务为什么没有触发
+type Tracer struct {
+	enabled atomic.Bool        // 是否开启追踪
+	events  map[string][]Event // 任务名 -> 最近的事件
+	lock    sync.RWMutex       // 保护events
+}
+
+// NewTracer 创建追踪器
+func NewTracer(enabled bool) *Tracer {
+	t := &Tracer{
+		events: make(map[string][]Event),
+	}
+	t.enabled.Store(enabled)
+	return t
+}
+
+// Enabled 是否开启追踪，nil追踪器视为关闭
+func (t *Tracer) Enabled() bool {
+	return t != nil && t.enabled.Load()
+}
+
+// SetEnabled 开启或关闭追踪，关闭时清空已记录的事件
+func (t *Tracer) SetEnabled(enabled bool) {
+	t.enabled.Store(enabled)
+	if !enabled {
+		t.lock.Lock()
+		t.events = make(map[string][]Event)
+		t.lock.Unlock()
+	}
+}
+
+// Record 记录一条事件，未开启时忽略
+func (t *Tracer) Record(jobName, stage string, passed bool, detail string) {
+	if !t.Enabled() {
+		return
+	}
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	events := append(t.events[jobName], Event{
+		Time:    time.Now(),
+		JobName: jobName,
+		Stage:   stage,
+		Passed:  passed,
+		Detail:  detail,
+	})
+	if len(events) > maxEventsPerJob {
+		events = events[len(events)-maxEventsPerJob:]
+	}
+	t.events[jobName] = events
+}
+
+// Events 获取任务最近的事件，按时间升序
+func (t *Tracer) Events(jobName string) []Event {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	events := make([]Event, len(t.events[jobName]))
+	copy(events, t.events[jobName])
+	return events
+}
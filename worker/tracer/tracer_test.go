@@ -0,0 +1,30 @@
+package tracer
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecord(t *testing.T) {
+	tracer := NewTracer(false)
+	tracer.Record("job", StageDue, true, "")
+	assert.Empty(t, tracer.Events("job"), "Disabled tracer should not record")
+
+	tracer.SetEnabled(true)
+	for i := 0; i < maxEventsPerJob+10; i++ {
+		tracer.Record("job", StageLock, false, fmt.Sprintf("attempt %d", i))
+	}
+
+	events := tracer.Events("job")
+	assert.Len(t, events, maxEventsPerJob, "Events should be capped per job")
+	assert.Equal(t, fmt.Sprintf("attempt %d", maxEventsPerJob+9), events[len(events)-1].Detail, "Newest events should be kept")
+
+	tracer.SetEnabled(false)
+	assert.Empty(t, tracer.Events("job"), "Disabling should clear events")
+
+	var nilTracer *Tracer
+	assert.False(t, nilTracer.Enabled())
+	nilTracer.Record("job", StageDue, true, "")
+}
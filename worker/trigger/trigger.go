@@ -0,0 +1,142 @@
+package trigger
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.uber.org/zap"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+	"github.com/fyerfyer/scheduler-refactor/pkg/etcd"
+)
+
+// triggerChanSize 等待调度器处理的触发数
+const triggerChanSize = 100
+
+// Watcher 监听etcd中的手动触发记录，交给调度器抢占执行
+type Watcher struct {
+	etcdClient  *etcd.Client            // etcd客户端
+	logger      *zap.Logger             // 日志对象
+	triggerChan chan *common.JobTrigger // 新的触发
+	ctx         context.Context         // 上下文，用于控制退出
+	cancelFunc  context.CancelFunc      // 取消函数
+}
+
+// NewWatcher 创建手动触发监听器
+func NewWatcher(logger *zap.Logger, etcdClient *etcd.Client) *Watcher {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &Watcher{
+		etcdClient:  etcdClient,
+		logger:      logger,
+		triggerChan: make(chan *common.JobTrigger, triggerChanSize),
+		ctx:         ctx,
+		cancelFunc:  cancel,
+	}
+}
+
+// Start 加载尚未被执行的触发并开始监听
+func (w *Watcher) Start() error {
+	resp, err := w.etcdClient.GetWithPrefix(common.JobTriggerDir)
+	if err != nil {
+		w.logger.Error("failed to load job triggers", zap.Error(err))
+		return err
+	}
+
+	for _, kv := range resp.Kvs {
+		w.applyKV(string(kv.Key), kv.Value, kv.ModRevision)
+	}
+
+	go w.watchLoop()
+	go w.retryLoop()
+
+	w.logger.Info("job trigger watcher started", zap.Int("pending", len(resp.Kvs)))
+	return nil
+}
+
+// Stop 停止监听
+func (w *Watcher) Stop() {
+	w.cancelFunc()
+	w.logger.Info("job trigger watcher stopped")
+}
+
+// Triggers 获取新触发的通道
+func (w *Watcher) Triggers() <-chan *common.JobTrigger {
+	return w.triggerChan
+}
+
+// watchLoop 监听触发目录，只关心新写入的触发，被抢走或过期的删除事件忽略。
+// 监听中断时重新监听并立即扫描一次，补发中断期间写入的触发
+func (w *Watcher) watchLoop() {
+	watchChan := w.etcdClient.WatchWithPrefix(w.ctx, common.JobTriggerDir)
+
+	for {
+		select {
+		case <-w.ctx.Done():
+			return
+		case watchResp, ok := <-watchChan:
+			if !ok || watchResp.Err() != nil {
+				w.logger.Warn("job trigger watch interrupted, rescanning", zap.Bool("closed", !ok))
+				watchChan = w.etcdClient.WatchWithPrefix(w.ctx, common.JobTriggerDir)
+				w.rescan()
+				continue
+			}
+
+			for _, event := range watchResp.Events {
+				if event.Type == clientv3.EventTypePut {
+					w.applyKV(string(event.Kv.Key), event.Kv.Value, event.Kv.ModRevision)
+				}
+			}
+		}
+	}
+}
+
+// retryLoop 定期重新扫描触发目录：抢占后没有确认的触发在抢占的worker宕机后不会再产生事件，
+// 由扫描重新投递，是否已过期由调度器按抢占租约判断
+func (w *Watcher) retryLoop() {
+	ticker := time.NewTicker(common.TriggerRetryInterval * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.ctx.Done():
+			return
+		case <-ticker.C:
+			w.rescan()
+		}
+	}
+}
+
+// rescan 扫描触发目录，重新投递所有触发
+func (w *Watcher) rescan() {
+	resp, err := w.etcdClient.GetWithPrefix(common.JobTriggerDir)
+	if err != nil {
+		w.logger.Warn("failed to rescan job triggers", zap.Error(err))
+		return
+	}
+	for _, kv := range resp.Kvs {
+		w.applyKV(string(kv.Key), kv.Value, kv.ModRevision)
+	}
+}
+
+// applyKV 解析触发并交给调度器，通道已满时丢弃，由其他worker执行
+func (w *Watcher) applyKV(key string, value []byte, modRevision int64) {
+	trigger := &common.JobTrigger{}
+	if err := json.Unmarshal(value, trigger); err != nil || trigger.JobName == "" || trigger.RunID == "" {
+		w.logger.Error("failed to unmarshal job trigger",
+			zap.String("key", key),
+			zap.Error(err))
+		return
+	}
+	trigger.ModRevision = modRevision
+
+	select {
+	case w.triggerChan <- trigger:
+	default:
+		w.logger.Warn("job trigger channel full, leaving trigger to other workers",
+			zap.String("jobName", trigger.JobName),
+			zap.String("runId", trigger.RunID))
+	}
+}
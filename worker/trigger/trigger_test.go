@@ -0,0 +1,33 @@
+package trigger
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/fyerfyer/scheduler-refactor/common"
+)
+
+func TestWatcher_ApplyKV(t *testing.T) {
+	w := NewWatcher(zaptest.NewLogger(t), nil)
+
+	w.applyKV(common.TriggerKey("backup", "run-1"), []byte(`{"jobName":"backup","runId":"run-1","triggeredBy":"alice"}`), 7)
+	w.applyKV(common.TriggerKey("backup", "broken"), []byte(`not json`), 8)
+	w.applyKV(common.TriggerKey("backup", ""), []byte(`{"jobName":"backup"}`), 9)
+
+	require.Len(t, w.Triggers(), 1, "Invalid triggers should be ignored")
+	trigger := <-w.Triggers()
+	assert.Equal(t, "backup", trigger.JobName)
+	assert.Equal(t, "run-1", trigger.RunID)
+	assert.Equal(t, "alice", trigger.TriggeredBy)
+	assert.Equal(t, int64(7), trigger.ModRevision)
+	assert.False(t, trigger.Claimed())
+
+	w.applyKV(common.TriggerKey("backup", "run-2"), []byte(`{"jobName":"backup","runId":"run-2","claimedBy":"worker-1","claimLease":42,"attempt":1}`), 10)
+	trigger = <-w.Triggers()
+	assert.True(t, trigger.Claimed(), "Claimed triggers should still be delivered for retry")
+	assert.Equal(t, int64(42), trigger.ClaimLease)
+	assert.Equal(t, 1, trigger.Attempt)
+}